@@ -0,0 +1,13 @@
+package awskms_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAWSKMS(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "AWS KMS Suite")
+}
@@ -0,0 +1,6 @@
+package awskms
+
+//go:generate counterfeiter -o fakes/fake_key_resolver.go . KeyResolver
+type KeyResolver interface {
+	ResolveAlias(alias string) (string, error)
+}
@@ -0,0 +1,100 @@
+package awskms
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol"
+	"github.com/aws/aws-sdk-go/private/protocol/jsonrpc"
+)
+
+// KMS is a client for the one KMS operation the broker needs. The SDK's
+// own KMS client is large and the broker only ever looks keys up, so this
+// is built the same way from the SDK's JSON RPC protocol instead.
+type KMS struct {
+	*client.Client
+}
+
+const (
+	kmsServiceName = "kms"
+	kmsServiceID   = "KMS"
+)
+
+func NewKMS(p client.ConfigProvider, cfgs ...*aws.Config) *KMS {
+	c := p.ClientConfig(kmsServiceName, cfgs...)
+	if c.SigningNameDerived || len(c.SigningName) == 0 {
+		c.SigningName = kmsServiceName
+	}
+
+	svc := &KMS{
+		Client: client.New(
+			*c.Config,
+			metadata.ClientInfo{
+				ServiceName:    kmsServiceName,
+				ServiceID:      kmsServiceID,
+				SigningName:    c.SigningName,
+				SigningRegion:  c.SigningRegion,
+				PartitionID:    c.PartitionID,
+				Endpoint:       c.Endpoint,
+				APIVersion:     "2014-11-01",
+				ResolvedRegion: c.ResolvedRegion,
+				JSONVersion:    "1.1",
+				TargetPrefix:   "TrentService",
+			},
+			c.Handlers,
+		),
+	}
+	svc.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	svc.Handlers.Build.PushBackNamed(jsonrpc.BuildHandler)
+	svc.Handlers.Unmarshal.PushBackNamed(jsonrpc.UnmarshalHandler)
+	svc.Handlers.UnmarshalMeta.PushBackNamed(jsonrpc.UnmarshalMetaHandler)
+	svc.Handlers.UnmarshalError.PushBackNamed(
+		protocol.NewUnmarshalErrorHandler(jsonrpc.NewUnmarshalTypedError(nil)).NamedHandler(),
+	)
+	return svc
+}
+
+// ErrCodeNotFoundException is the code of the error KMS returns when there
+// is no key with an ID or alias.
+const ErrCodeNotFoundException = "NotFoundException"
+
+type DescribeKeyInput struct {
+	_ struct{} `type:"structure"`
+
+	KeyId *string `min:"1" type:"string" required:"true"`
+}
+
+type DescribeKeyOutput struct {
+	_ struct{} `type:"structure"`
+
+	KeyMetadata *KeyMetadata `type:"structure"`
+}
+
+type KeyMetadata struct {
+	_ struct{} `type:"structure"`
+
+	Arn        *string `min:"20" type:"string"`
+	KeyId      *string `min:"1" type:"string"`
+	KeyManager *string `type:"string"`
+	KeyState   *string `type:"string"`
+}
+
+// The states and managers of keys which the broker checks for.
+const (
+	KeyStateEnabled    = "Enabled"
+	KeyManagerCustomer = "CUSTOMER"
+)
+
+// DescribeKey returns the details of a key, given its ID, ARN or alias.
+func (c *KMS) DescribeKey(input *DescribeKeyInput) (*DescribeKeyOutput, error) {
+	op := &request.Operation{
+		Name:       "DescribeKey",
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+	output := &DescribeKeyOutput{}
+	req := c.NewRequest(op, input, output)
+	return output, req.Send()
+}
@@ -0,0 +1,56 @@
+package awskms
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// KMSKeyResolver looks up the customer managed keys which tenants choose by
+// alias to encrypt their instances with.
+type KMSKeyResolver struct {
+	kmssvc *KMS
+	logger lager.Logger
+}
+
+func NewKMSKeyResolver(kmssvc *KMS, logger lager.Logger) *KMSKeyResolver {
+	return &KMSKeyResolver{
+		kmssvc: kmssvc,
+		logger: logger.Session("key-resolver"),
+	}
+}
+
+// ResolveAlias returns the ARN of the key an alias refers to. Keys which
+// aren't customer managed or can't currently be used are refused, as RDS
+// would only fail later on when creating the instance.
+func (r *KMSKeyResolver) ResolveAlias(alias string) (string, error) {
+	describeKeyInput := &DescribeKeyInput{
+		KeyId: aws.String(alias),
+	}
+	r.logger.Debug("describe-key", lager.Data{"input": describeKeyInput})
+
+	describeKeyOutput, err := r.kmssvc.DescribeKey(describeKeyInput)
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == ErrCodeNotFoundException {
+		return "", fmt.Errorf("KMS key '%s' does not exist", alias)
+	}
+	if err != nil {
+		r.logger.Error("aws-kms-error", err)
+		return "", err
+	}
+
+	keyMetadata := describeKeyOutput.KeyMetadata
+	if keyMetadata == nil {
+		return "", fmt.Errorf("KMS key '%s' does not exist", alias)
+	}
+	if aws.StringValue(keyMetadata.KeyManager) != KeyManagerCustomer {
+		return "", fmt.Errorf("KMS key '%s' is not a customer managed key", alias)
+	}
+	if aws.StringValue(keyMetadata.KeyState) != KeyStateEnabled {
+		return "", fmt.Errorf("KMS key '%s' is %s", alias, aws.StringValue(keyMetadata.KeyState))
+	}
+
+	r.logger.Debug("describe-key", lager.Data{"arn": keyMetadata.Arn})
+	return aws.StringValue(keyMetadata.Arn), nil
+}
@@ -0,0 +1,120 @@
+package awskms_test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/alphagov/paas-rds-broker/awskms"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+var _ = Describe("KMS Key Resolver", func() {
+	var (
+		receivedTarget string
+		receivedBody   string
+		responseStatus int
+		responseBody   string
+
+		keyResolver KeyResolver
+	)
+
+	BeforeEach(func() {
+		receivedTarget = ""
+		receivedBody = ""
+		responseStatus = http.StatusOK
+		responseBody = `{"KeyMetadata": {
+			"Arn": "arn:aws:kms:eu-west-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab",
+			"KeyId": "1234abcd-12ab-34cd-56ef-1234567890ab",
+			"KeyManager": "CUSTOMER",
+			"KeyState": "Enabled"
+		}}`
+	})
+
+	JustBeforeEach(func() {
+		awsSession, _ := session.NewSession(&aws.Config{
+			Region:      aws.String("eu-west-1"),
+			Credentials: credentials.NewStaticCredentials("access-key-id", "secret-access-key", ""),
+		})
+		kmssvc := NewKMS(awsSession)
+
+		kmssvc.Handlers.Send.Clear()
+		kmssvc.Handlers.Send.PushBack(func(r *request.Request) {
+			receivedTarget = r.HTTPRequest.Header.Get("X-Amz-Target")
+			body, err := io.ReadAll(r.HTTPRequest.Body)
+			Expect(err).NotTo(HaveOccurred())
+			receivedBody = string(body)
+
+			r.HTTPResponse = &http.Response{
+				StatusCode: responseStatus,
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader(responseBody)),
+			}
+		})
+
+		keyResolver = NewKMSKeyResolver(kmssvc, lagertest.NewTestLogger("kmskeyresolver_test"))
+	})
+
+	It("returns the ARN of the key the alias refers to", func() {
+		arn, err := keyResolver.ResolveAlias("alias/tenant-key")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(arn).To(Equal("arn:aws:kms:eu-west-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"))
+
+		Expect(receivedTarget).To(Equal("TrentService.DescribeKey"))
+		Expect(receivedBody).To(MatchJSON(`{"KeyId": "alias/tenant-key"}`))
+	})
+
+	Context("when the alias doesn't exist", func() {
+		BeforeEach(func() {
+			responseStatus = http.StatusBadRequest
+			responseBody = `{"__type": "NotFoundException", "message": "Alias is not found."}`
+		})
+
+		It("says so", func() {
+			_, err := keyResolver.ResolveAlias("alias/tenant-key")
+			Expect(err).To(MatchError("KMS key 'alias/tenant-key' does not exist"))
+		})
+	})
+
+	Context("when KMS fails", func() {
+		BeforeEach(func() {
+			responseStatus = http.StatusBadRequest
+			responseBody = `{"__type": "AccessDeniedException", "message": "Not authorized."}`
+		})
+
+		It("returns the error", func() {
+			_, err := keyResolver.ResolveAlias("alias/tenant-key")
+			Expect(err).To(MatchError(ContainSubstring("AccessDeniedException")))
+		})
+	})
+
+	Context("when the key is managed by AWS", func() {
+		BeforeEach(func() {
+			responseBody = `{"KeyMetadata": {"Arn": "arn:aws:kms:eu-west-1:123456789012:key/aws", "KeyManager": "AWS", "KeyState": "Enabled"}}`
+		})
+
+		It("refuses it", func() {
+			_, err := keyResolver.ResolveAlias("alias/aws/rds")
+			Expect(err).To(MatchError("KMS key 'alias/aws/rds' is not a customer managed key"))
+		})
+	})
+
+	Context("when the key is disabled", func() {
+		BeforeEach(func() {
+			responseBody = `{"KeyMetadata": {"Arn": "arn:aws:kms:eu-west-1:123456789012:key/disabled", "KeyManager": "CUSTOMER", "KeyState": "Disabled"}}`
+		})
+
+		It("refuses it", func() {
+			_, err := keyResolver.ResolveAlias("alias/tenant-key")
+			Expect(err).To(MatchError("KMS key 'alias/tenant-key' is Disabled"))
+		})
+	})
+})
@@ -0,0 +1,133 @@
+package awsrds
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+var (
+	ErrCodeAWSUnavailable = "AWSUnavailable"
+
+	ErrAWSUnavailable = NewError(
+		errors.New("AWS is temporarily unavailable, please try again later"),
+		ErrCodeAWSUnavailable,
+	)
+)
+
+type healthPingKey struct{}
+
+// CircuitBreaker stops the broker calling the RDS APIs once they have been
+// throttling or failing for a while, so that retries and polls don't pile
+// up on an API which is already struggling. Once open, the breaker fails
+// every call with ErrAWSUnavailable, and pings RDS until it answers again.
+type CircuitBreaker struct {
+	failureThreshold int
+	pingInterval     time.Duration
+	ping             func() error
+	logger           lager.Logger
+
+	lock     sync.Mutex
+	failures int
+	open     bool
+}
+
+func NewCircuitBreaker(failureThreshold int, pingInterval time.Duration, logger lager.Logger) *CircuitBreaker {
+	return &CircuitBreaker{
+		failureThreshold: failureThreshold,
+		pingInterval:     pingInterval,
+		logger:           logger.Session("circuit-breaker"),
+	}
+}
+
+// Install adds the circuit breaker to the request handlers of the RDS
+// client, and pings RDS with it while the breaker is open.
+func (c *CircuitBreaker) Install(rdssvc *rds.RDS) {
+	c.ping = func() error {
+		ctx := context.WithValue(context.Background(), healthPingKey{}, true)
+		_, err := rdssvc.DescribeAccountAttributesWithContext(ctx, &rds.DescribeAccountAttributesInput{})
+		return err
+	}
+	rdssvc.Handlers.Validate.PushFrontNamed(request.NamedHandler{
+		Name: "awsrds.CircuitBreaker.Check",
+		Fn:   c.check,
+	})
+	rdssvc.Handlers.Complete.PushBackNamed(request.NamedHandler{
+		Name: "awsrds.CircuitBreaker.Record",
+		Fn:   c.record,
+	})
+}
+
+// Available is whether the breaker is closed, letting calls through to RDS.
+func (c *CircuitBreaker) Available() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return !c.open
+}
+
+func (c *CircuitBreaker) check(r *request.Request) {
+	if r.Context().Value(healthPingKey{}) != nil {
+		return
+	}
+	if !c.Available() {
+		r.Error = ErrAWSUnavailable
+	}
+}
+
+func (c *CircuitBreaker) record(r *request.Request) {
+	if r.Error == ErrAWSUnavailable {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !isUnhealthyResponse(r) {
+		if c.open {
+			c.logger.Info("closed", lager.Data{"operation": r.Operation.Name})
+		}
+		c.failures = 0
+		c.open = false
+		return
+	}
+
+	c.failures++
+	if !c.open && c.failures >= c.failureThreshold {
+		c.logger.Error("opened", r.Error, lager.Data{
+			"operation": r.Operation.Name,
+			"failures":  c.failures,
+		})
+		c.open = true
+		go c.pingUntilClosed()
+	}
+}
+
+func (c *CircuitBreaker) pingUntilClosed() {
+	for {
+		time.Sleep(c.pingInterval)
+		if c.Available() {
+			return
+		}
+		if err := c.ping(); err != nil {
+			c.logger.Info("health-ping-failed", lager.Data{"error": err.Error()})
+		}
+	}
+}
+
+// isUnhealthyResponse is whether a call failed because RDS throttled it or
+// failed on its side, rather than because of the call itself.
+func isUnhealthyResponse(r *request.Request) bool {
+	if r.Error == nil {
+		return false
+	}
+	if request.IsErrorThrottle(r.Error) {
+		return true
+	}
+	return r.HTTPResponse != nil && r.HTTPResponse.StatusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,131 @@
+package awsrds_test
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/alphagov/paas-rds-broker/awsrds"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+var _ = Describe("CircuitBreaker", func() {
+	var (
+		rdssvc         *rds.RDS
+		circuitBreaker *CircuitBreaker
+
+		describeError  error
+		describeStatus int
+		describeCalls  int
+		pingError      error
+		pingCalls      int
+	)
+
+	BeforeEach(func() {
+		describeError = nil
+		describeStatus = http.StatusOK
+		describeCalls = 0
+		pingError = errors.New("still down")
+		pingCalls = 0
+
+		awsSession, _ := session.NewSession(nil)
+		rdssvc = rds.New(awsSession)
+		rdssvc.Handlers.Clear()
+		rdssvc.Handlers.Send.PushBack(func(r *request.Request) {
+			switch r.Operation.Name {
+			case "DescribeDBInstances":
+				describeCalls++
+				r.HTTPResponse = &http.Response{StatusCode: describeStatus}
+				r.Error = describeError
+			case "DescribeAccountAttributes":
+				pingCalls++
+				r.Error = pingError
+			}
+		})
+
+		circuitBreaker = NewCircuitBreaker(3, 10*time.Millisecond, lagertest.NewTestLogger("circuit-breaker"))
+		circuitBreaker.Install(rdssvc)
+	})
+
+	describe := func() error {
+		_, err := rdssvc.DescribeDBInstances(&rds.DescribeDBInstancesInput{DBInstanceIdentifier: aws.String("cf-instance-id")})
+		return err
+	}
+
+	It("lets calls through while RDS is healthy", func() {
+		Expect(describe()).To(Succeed())
+		Expect(circuitBreaker.Available()).To(BeTrue())
+		Expect(describeCalls).To(Equal(1))
+	})
+
+	It("doesn't open on errors which aren't throttling or server errors", func() {
+		describeError = awserr.New("InvalidParameterValue", "bad", nil)
+		describeStatus = http.StatusBadRequest
+		for i := 0; i < 5; i++ {
+			Expect(describe()).ToNot(Succeed())
+		}
+		Expect(circuitBreaker.Available()).To(BeTrue())
+	})
+
+	It("doesn't open until the failures are sustained", func() {
+		describeError = awserr.New("Throttling", "Rate exceeded", nil)
+		Expect(describe()).ToNot(Succeed())
+		Expect(describe()).ToNot(Succeed())
+
+		describeError = nil
+		Expect(describe()).To(Succeed())
+
+		describeError = awserr.New("Throttling", "Rate exceeded", nil)
+		Expect(describe()).ToNot(Succeed())
+		Expect(describe()).ToNot(Succeed())
+		Expect(circuitBreaker.Available()).To(BeTrue())
+	})
+
+	Context("when RDS keeps throttling", func() {
+		BeforeEach(func() {
+			describeError = awserr.New("Throttling", "Rate exceeded", nil)
+		})
+
+		It("opens and fails calls without making them", func() {
+			for i := 0; i < 3; i++ {
+				Expect(describe()).ToNot(Succeed())
+			}
+			Expect(circuitBreaker.Available()).To(BeFalse())
+
+			Expect(describe()).To(MatchError(ErrAWSUnavailable))
+			Expect(describeCalls).To(Equal(3))
+		})
+
+		It("closes once a health ping succeeds", func() {
+			for i := 0; i < 3; i++ {
+				Expect(describe()).ToNot(Succeed())
+			}
+			Eventually(func() int { return pingCalls }).Should(BeNumerically(">=", 1))
+			Expect(circuitBreaker.Available()).To(BeFalse())
+
+			pingError = nil
+			Eventually(circuitBreaker.Available).Should(BeTrue())
+
+			describeError = nil
+			Expect(describe()).To(Succeed())
+		})
+	})
+
+	It("opens when RDS keeps failing with server errors", func() {
+		describeError = awserr.New("InternalFailure", "oops", nil)
+		describeStatus = http.StatusInternalServerError
+		for i := 0; i < 3; i++ {
+			Expect(describe()).ToNot(Succeed())
+		}
+		Expect(circuitBreaker.Available()).To(BeFalse())
+	})
+})
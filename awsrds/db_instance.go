@@ -2,6 +2,8 @@ package awsrds
 
 import (
 	"errors"
+	"io"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/rds"
@@ -19,20 +21,40 @@ type RDSInstance interface {
 	GetResourceTags(resourceArn string, opts ...DescribeOption) ([]*rds.Tag, error)
 	DescribeByTag(TagName, TagValue string, opts ...DescribeOption) ([]*rds.DBInstance, error)
 	DescribeSnapshots(DBInstanceID string) ([]*rds.DBSnapshot, error)
+	DescribeSnapshot(DBSnapshotID string) (*rds.DBSnapshot, error)
+	CopySnapshot(copyDBSnapshotInput *rds.CopyDBSnapshotInput) error
+	DeleteSnapshot(DBSnapshotID string) error
+	DescribeEvents(DBInstanceID string) ([]*rds.Event, error)
+	DescribeEventsBetween(DBInstanceID string, startTime time.Time, endTime time.Time) ([]*rds.Event, error)
+	DescribeLogFiles(DBInstanceID string, filenameContains string) ([]*rds.DescribeDBLogFilesDetails, error)
+	DownloadLogFile(DBInstanceID string, logFileName string, w io.Writer) error
 	DeleteSnapshots(brokerName string, keepForDays int) error
+	DescribeManualSnapshots(brokerName string, createdAfter time.Time) ([]*rds.DBSnapshot, error)
+	ShareSnapshot(DBSnapshotID string, accountID string) error
 	Create(createDBInstanceInput *rds.CreateDBInstanceInput) error
 	Restore(restoreRBInstanceInput *rds.RestoreDBInstanceFromDBSnapshotInput) error
 	RestoreToPointInTime(restoreRBInstanceInput *rds.RestoreDBInstanceToPointInTimeInput) error
 	Modify(modifyDBInstanceInput *rds.ModifyDBInstanceInput) (*rds.DBInstance, error)
 	AddTagsToResource(resourceArn string, tags []*rds.Tag) error
 	Reboot(rebootDBInstanceInput *rds.RebootDBInstanceInput) error
+	Stop(ID string) error
+	Start(ID string) error
 	RemoveTag(ID, tagKey string) error
 	Delete(ID string, skipFinalSnapshot bool) error
 	GetTag(ID, tagKey string) (string, error)
 	GetParameterGroup(groupId string) (*rds.DBParameterGroup, error)
 	CreateParameterGroup(input *rds.CreateDBParameterGroupInput) error
 	ModifyParameterGroup(input *rds.ModifyDBParameterGroupInput) error
+	DescribeParameters(groupId string) ([]*rds.Parameter, error)
+	DescribePendingMaintenanceActions() (map[string][]*rds.PendingMaintenanceAction, error)
+	DescribeOrderableOptions(engine string, dbInstanceClass string) ([]*rds.OrderableDBInstanceOption, error)
+	CreateDBProxy(input *rds.CreateDBProxyInput) error
+	DescribeDBProxy(proxyName string) (*rds.DBProxy, error)
+	RegisterDBProxyTargets(proxyName string, dbInstanceIdentifier string) error
+	SetDBProxyAuth(proxyName string, auth []*rds.UserAuthConfig) error
+	DeleteDBProxy(proxyName string) error
 	GetLatestMinorVersion(engine string, version string) (*string, error)
+	GetParameterGroupFamily(engine string, version string) (string, error)
 	GetFullValidTargetVersion(engine string, currentVersion string, targetVersion string) (string, error)
 }
 
@@ -44,6 +66,22 @@ func (ct ByCreateTime) Less(i, j int) bool {
 	return aws.TimeValue(ct[i].SnapshotCreateTime).After(aws.TimeValue(ct[j].SnapshotCreateTime))
 }
 
+type ByLastWritten []*rds.DescribeDBLogFilesDetails
+
+func (lw ByLastWritten) Len() int      { return len(lw) }
+func (lw ByLastWritten) Swap(i, j int) { lw[i], lw[j] = lw[j], lw[i] }
+func (lw ByLastWritten) Less(i, j int) bool {
+	return aws.Int64Value(lw[i].LastWritten) > aws.Int64Value(lw[j].LastWritten)
+}
+
+type ByEventDate []*rds.Event
+
+func (ed ByEventDate) Len() int      { return len(ed) }
+func (ed ByEventDate) Swap(i, j int) { ed[i], ed[j] = ed[j], ed[i] }
+func (ed ByEventDate) Less(i, j int) bool {
+	return aws.TimeValue(ed[i].Date).After(aws.TimeValue(ed[j].Date))
+}
+
 type awsRdsErr struct {
 	orig error
 	code string
@@ -81,10 +119,21 @@ type Error interface {
 
 var (
 	ErrCodeDBInstanceDoesNotExist      = "DBInstanceDoesNotExist"
+	ErrCodeDBInstanceAlreadyExists     = "DBInstanceAlreadyExists"
 	ErrCodeInvalidParameterCombination = "InvalidParameterCombination"
+	ErrCodeDBSnapshotDoesNotExist      = "DBSnapshotDoesNotExist"
+	ErrCodeDBProxyDoesNotExist         = "DBProxyDoesNotExist"
 
 	ErrDBInstanceDoesNotExist = NewError(
 		errors.New("rds db instance does not exist"),
 		ErrCodeDBInstanceDoesNotExist,
 	)
+	ErrDBSnapshotDoesNotExist = NewError(
+		errors.New("rds db snapshot does not exist"),
+		ErrCodeDBSnapshotDoesNotExist,
+	)
+	ErrDBProxyDoesNotExist = NewError(
+		errors.New("rds db proxy does not exist"),
+		ErrCodeDBProxyDoesNotExist,
+	)
 )
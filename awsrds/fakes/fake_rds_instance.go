@@ -2,7 +2,9 @@
 package fakes
 
 import (
+	"io"
 	"sync"
+	"time"
 
 	"github.com/alphagov/paas-rds-broker/awsrds"
 	"github.com/aws/aws-sdk-go/service/rds"
@@ -21,6 +23,17 @@ type FakeRDSInstance struct {
 	addTagsToResourceReturnsOnCall map[int]struct {
 		result1 error
 	}
+	CopySnapshotStub        func(*rds.CopyDBSnapshotInput) error
+	copySnapshotMutex       sync.RWMutex
+	copySnapshotArgsForCall []struct {
+		arg1 *rds.CopyDBSnapshotInput
+	}
+	copySnapshotReturns struct {
+		result1 error
+	}
+	copySnapshotReturnsOnCall map[int]struct {
+		result1 error
+	}
 	CreateStub        func(*rds.CreateDBInstanceInput) error
 	createMutex       sync.RWMutex
 	createArgsForCall []struct {
@@ -32,6 +45,17 @@ type FakeRDSInstance struct {
 	createReturnsOnCall map[int]struct {
 		result1 error
 	}
+	CreateDBProxyStub        func(*rds.CreateDBProxyInput) error
+	createDBProxyMutex       sync.RWMutex
+	createDBProxyArgsForCall []struct {
+		arg1 *rds.CreateDBProxyInput
+	}
+	createDBProxyReturns struct {
+		result1 error
+	}
+	createDBProxyReturnsOnCall map[int]struct {
+		result1 error
+	}
 	CreateParameterGroupStub        func(*rds.CreateDBParameterGroupInput) error
 	createParameterGroupMutex       sync.RWMutex
 	createParameterGroupArgsForCall []struct {
@@ -55,6 +79,28 @@ type FakeRDSInstance struct {
 	deleteReturnsOnCall map[int]struct {
 		result1 error
 	}
+	DeleteDBProxyStub        func(string) error
+	deleteDBProxyMutex       sync.RWMutex
+	deleteDBProxyArgsForCall []struct {
+		arg1 string
+	}
+	deleteDBProxyReturns struct {
+		result1 error
+	}
+	deleteDBProxyReturnsOnCall map[int]struct {
+		result1 error
+	}
+	DeleteSnapshotStub        func(string) error
+	deleteSnapshotMutex       sync.RWMutex
+	deleteSnapshotArgsForCall []struct {
+		arg1 string
+	}
+	deleteSnapshotReturns struct {
+		result1 error
+	}
+	deleteSnapshotReturnsOnCall map[int]struct {
+		result1 error
+	}
 	DeleteSnapshotsStub        func(string, int) error
 	deleteSnapshotsMutex       sync.RWMutex
 	deleteSnapshotsArgsForCall []struct {
@@ -95,6 +141,127 @@ type FakeRDSInstance struct {
 		result1 []*rds.DBInstance
 		result2 error
 	}
+	DescribeDBProxyStub        func(string) (*rds.DBProxy, error)
+	describeDBProxyMutex       sync.RWMutex
+	describeDBProxyArgsForCall []struct {
+		arg1 string
+	}
+	describeDBProxyReturns struct {
+		result1 *rds.DBProxy
+		result2 error
+	}
+	describeDBProxyReturnsOnCall map[int]struct {
+		result1 *rds.DBProxy
+		result2 error
+	}
+	DescribeEventsStub        func(string) ([]*rds.Event, error)
+	describeEventsMutex       sync.RWMutex
+	describeEventsArgsForCall []struct {
+		arg1 string
+	}
+	describeEventsReturns struct {
+		result1 []*rds.Event
+		result2 error
+	}
+	describeEventsReturnsOnCall map[int]struct {
+		result1 []*rds.Event
+		result2 error
+	}
+	DescribeEventsBetweenStub        func(string, time.Time, time.Time) ([]*rds.Event, error)
+	describeEventsBetweenMutex       sync.RWMutex
+	describeEventsBetweenArgsForCall []struct {
+		arg1 string
+		arg2 time.Time
+		arg3 time.Time
+	}
+	describeEventsBetweenReturns struct {
+		result1 []*rds.Event
+		result2 error
+	}
+	describeEventsBetweenReturnsOnCall map[int]struct {
+		result1 []*rds.Event
+		result2 error
+	}
+	DescribeLogFilesStub        func(string, string) ([]*rds.DescribeDBLogFilesDetails, error)
+	describeLogFilesMutex       sync.RWMutex
+	describeLogFilesArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	describeLogFilesReturns struct {
+		result1 []*rds.DescribeDBLogFilesDetails
+		result2 error
+	}
+	describeLogFilesReturnsOnCall map[int]struct {
+		result1 []*rds.DescribeDBLogFilesDetails
+		result2 error
+	}
+	DescribeManualSnapshotsStub        func(string, time.Time) ([]*rds.DBSnapshot, error)
+	describeManualSnapshotsMutex       sync.RWMutex
+	describeManualSnapshotsArgsForCall []struct {
+		arg1 string
+		arg2 time.Time
+	}
+	describeManualSnapshotsReturns struct {
+		result1 []*rds.DBSnapshot
+		result2 error
+	}
+	describeManualSnapshotsReturnsOnCall map[int]struct {
+		result1 []*rds.DBSnapshot
+		result2 error
+	}
+	DescribeOrderableOptionsStub        func(string, string) ([]*rds.OrderableDBInstanceOption, error)
+	describeOrderableOptionsMutex       sync.RWMutex
+	describeOrderableOptionsArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	describeOrderableOptionsReturns struct {
+		result1 []*rds.OrderableDBInstanceOption
+		result2 error
+	}
+	describeOrderableOptionsReturnsOnCall map[int]struct {
+		result1 []*rds.OrderableDBInstanceOption
+		result2 error
+	}
+	DescribeParametersStub        func(string) ([]*rds.Parameter, error)
+	describeParametersMutex       sync.RWMutex
+	describeParametersArgsForCall []struct {
+		arg1 string
+	}
+	describeParametersReturns struct {
+		result1 []*rds.Parameter
+		result2 error
+	}
+	describeParametersReturnsOnCall map[int]struct {
+		result1 []*rds.Parameter
+		result2 error
+	}
+	DescribePendingMaintenanceActionsStub        func() (map[string][]*rds.PendingMaintenanceAction, error)
+	describePendingMaintenanceActionsMutex       sync.RWMutex
+	describePendingMaintenanceActionsArgsForCall []struct {
+	}
+	describePendingMaintenanceActionsReturns struct {
+		result1 map[string][]*rds.PendingMaintenanceAction
+		result2 error
+	}
+	describePendingMaintenanceActionsReturnsOnCall map[int]struct {
+		result1 map[string][]*rds.PendingMaintenanceAction
+		result2 error
+	}
+	DescribeSnapshotStub        func(string) (*rds.DBSnapshot, error)
+	describeSnapshotMutex       sync.RWMutex
+	describeSnapshotArgsForCall []struct {
+		arg1 string
+	}
+	describeSnapshotReturns struct {
+		result1 *rds.DBSnapshot
+		result2 error
+	}
+	describeSnapshotReturnsOnCall map[int]struct {
+		result1 *rds.DBSnapshot
+		result2 error
+	}
 	DescribeSnapshotsStub        func(string) ([]*rds.DBSnapshot, error)
 	describeSnapshotsMutex       sync.RWMutex
 	describeSnapshotsArgsForCall []struct {
@@ -108,6 +275,19 @@ type FakeRDSInstance struct {
 		result1 []*rds.DBSnapshot
 		result2 error
 	}
+	DownloadLogFileStub        func(string, string, io.Writer) error
+	downloadLogFileMutex       sync.RWMutex
+	downloadLogFileArgsForCall []struct {
+		arg1 string
+		arg2 string
+		arg3 io.Writer
+	}
+	downloadLogFileReturns struct {
+		result1 error
+	}
+	downloadLogFileReturnsOnCall map[int]struct {
+		result1 error
+	}
 	GetFullValidTargetVersionStub        func(string, string, string) (string, error)
 	getFullValidTargetVersionMutex       sync.RWMutex
 	getFullValidTargetVersionArgsForCall []struct {
@@ -150,6 +330,20 @@ type FakeRDSInstance struct {
 		result1 *rds.DBParameterGroup
 		result2 error
 	}
+	GetParameterGroupFamilyStub        func(string, string) (string, error)
+	getParameterGroupFamilyMutex       sync.RWMutex
+	getParameterGroupFamilyArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	getParameterGroupFamilyReturns struct {
+		result1 string
+		result2 error
+	}
+	getParameterGroupFamilyReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
 	GetResourceTagsStub        func(string, ...awsrds.DescribeOption) ([]*rds.Tag, error)
 	getResourceTagsMutex       sync.RWMutex
 	getResourceTagsArgsForCall []struct {
@@ -213,6 +407,18 @@ type FakeRDSInstance struct {
 	rebootReturnsOnCall map[int]struct {
 		result1 error
 	}
+	RegisterDBProxyTargetsStub        func(string, string) error
+	registerDBProxyTargetsMutex       sync.RWMutex
+	registerDBProxyTargetsArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	registerDBProxyTargetsReturns struct {
+		result1 error
+	}
+	registerDBProxyTargetsReturnsOnCall map[int]struct {
+		result1 error
+	}
 	RemoveTagStub        func(string, string) error
 	removeTagMutex       sync.RWMutex
 	removeTagArgsForCall []struct {
@@ -247,6 +453,52 @@ type FakeRDSInstance struct {
 	restoreToPointInTimeReturnsOnCall map[int]struct {
 		result1 error
 	}
+	SetDBProxyAuthStub        func(string, []*rds.UserAuthConfig) error
+	setDBProxyAuthMutex       sync.RWMutex
+	setDBProxyAuthArgsForCall []struct {
+		arg1 string
+		arg2 []*rds.UserAuthConfig
+	}
+	setDBProxyAuthReturns struct {
+		result1 error
+	}
+	setDBProxyAuthReturnsOnCall map[int]struct {
+		result1 error
+	}
+	ShareSnapshotStub        func(string, string) error
+	shareSnapshotMutex       sync.RWMutex
+	shareSnapshotArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	shareSnapshotReturns struct {
+		result1 error
+	}
+	shareSnapshotReturnsOnCall map[int]struct {
+		result1 error
+	}
+	StartStub        func(string) error
+	startMutex       sync.RWMutex
+	startArgsForCall []struct {
+		arg1 string
+	}
+	startReturns struct {
+		result1 error
+	}
+	startReturnsOnCall map[int]struct {
+		result1 error
+	}
+	StopStub        func(string) error
+	stopMutex       sync.RWMutex
+	stopArgsForCall []struct {
+		arg1 string
+	}
+	stopReturns struct {
+		result1 error
+	}
+	stopReturnsOnCall map[int]struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -318,6 +570,67 @@ func (fake *FakeRDSInstance) AddTagsToResourceReturnsOnCall(i int, result1 error
 	}{result1}
 }
 
+func (fake *FakeRDSInstance) CopySnapshot(arg1 *rds.CopyDBSnapshotInput) error {
+	fake.copySnapshotMutex.Lock()
+	ret, specificReturn := fake.copySnapshotReturnsOnCall[len(fake.copySnapshotArgsForCall)]
+	fake.copySnapshotArgsForCall = append(fake.copySnapshotArgsForCall, struct {
+		arg1 *rds.CopyDBSnapshotInput
+	}{arg1})
+	stub := fake.CopySnapshotStub
+	fakeReturns := fake.copySnapshotReturns
+	fake.recordInvocation("CopySnapshot", []interface{}{arg1})
+	fake.copySnapshotMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRDSInstance) CopySnapshotCallCount() int {
+	fake.copySnapshotMutex.RLock()
+	defer fake.copySnapshotMutex.RUnlock()
+	return len(fake.copySnapshotArgsForCall)
+}
+
+func (fake *FakeRDSInstance) CopySnapshotCalls(stub func(*rds.CopyDBSnapshotInput) error) {
+	fake.copySnapshotMutex.Lock()
+	defer fake.copySnapshotMutex.Unlock()
+	fake.CopySnapshotStub = stub
+}
+
+func (fake *FakeRDSInstance) CopySnapshotArgsForCall(i int) *rds.CopyDBSnapshotInput {
+	fake.copySnapshotMutex.RLock()
+	defer fake.copySnapshotMutex.RUnlock()
+	argsForCall := fake.copySnapshotArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeRDSInstance) CopySnapshotReturns(result1 error) {
+	fake.copySnapshotMutex.Lock()
+	defer fake.copySnapshotMutex.Unlock()
+	fake.CopySnapshotStub = nil
+	fake.copySnapshotReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRDSInstance) CopySnapshotReturnsOnCall(i int, result1 error) {
+	fake.copySnapshotMutex.Lock()
+	defer fake.copySnapshotMutex.Unlock()
+	fake.CopySnapshotStub = nil
+	if fake.copySnapshotReturnsOnCall == nil {
+		fake.copySnapshotReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.copySnapshotReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeRDSInstance) Create(arg1 *rds.CreateDBInstanceInput) error {
 	fake.createMutex.Lock()
 	ret, specificReturn := fake.createReturnsOnCall[len(fake.createArgsForCall)]
@@ -379,6 +692,67 @@ func (fake *FakeRDSInstance) CreateReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeRDSInstance) CreateDBProxy(arg1 *rds.CreateDBProxyInput) error {
+	fake.createDBProxyMutex.Lock()
+	ret, specificReturn := fake.createDBProxyReturnsOnCall[len(fake.createDBProxyArgsForCall)]
+	fake.createDBProxyArgsForCall = append(fake.createDBProxyArgsForCall, struct {
+		arg1 *rds.CreateDBProxyInput
+	}{arg1})
+	stub := fake.CreateDBProxyStub
+	fakeReturns := fake.createDBProxyReturns
+	fake.recordInvocation("CreateDBProxy", []interface{}{arg1})
+	fake.createDBProxyMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRDSInstance) CreateDBProxyCallCount() int {
+	fake.createDBProxyMutex.RLock()
+	defer fake.createDBProxyMutex.RUnlock()
+	return len(fake.createDBProxyArgsForCall)
+}
+
+func (fake *FakeRDSInstance) CreateDBProxyCalls(stub func(*rds.CreateDBProxyInput) error) {
+	fake.createDBProxyMutex.Lock()
+	defer fake.createDBProxyMutex.Unlock()
+	fake.CreateDBProxyStub = stub
+}
+
+func (fake *FakeRDSInstance) CreateDBProxyArgsForCall(i int) *rds.CreateDBProxyInput {
+	fake.createDBProxyMutex.RLock()
+	defer fake.createDBProxyMutex.RUnlock()
+	argsForCall := fake.createDBProxyArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeRDSInstance) CreateDBProxyReturns(result1 error) {
+	fake.createDBProxyMutex.Lock()
+	defer fake.createDBProxyMutex.Unlock()
+	fake.CreateDBProxyStub = nil
+	fake.createDBProxyReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRDSInstance) CreateDBProxyReturnsOnCall(i int, result1 error) {
+	fake.createDBProxyMutex.Lock()
+	defer fake.createDBProxyMutex.Unlock()
+	fake.CreateDBProxyStub = nil
+	if fake.createDBProxyReturnsOnCall == nil {
+		fake.createDBProxyReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.createDBProxyReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeRDSInstance) CreateParameterGroup(arg1 *rds.CreateDBParameterGroupInput) error {
 	fake.createParameterGroupMutex.Lock()
 	ret, specificReturn := fake.createParameterGroupReturnsOnCall[len(fake.createParameterGroupArgsForCall)]
@@ -502,6 +876,128 @@ func (fake *FakeRDSInstance) DeleteReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeRDSInstance) DeleteDBProxy(arg1 string) error {
+	fake.deleteDBProxyMutex.Lock()
+	ret, specificReturn := fake.deleteDBProxyReturnsOnCall[len(fake.deleteDBProxyArgsForCall)]
+	fake.deleteDBProxyArgsForCall = append(fake.deleteDBProxyArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.DeleteDBProxyStub
+	fakeReturns := fake.deleteDBProxyReturns
+	fake.recordInvocation("DeleteDBProxy", []interface{}{arg1})
+	fake.deleteDBProxyMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRDSInstance) DeleteDBProxyCallCount() int {
+	fake.deleteDBProxyMutex.RLock()
+	defer fake.deleteDBProxyMutex.RUnlock()
+	return len(fake.deleteDBProxyArgsForCall)
+}
+
+func (fake *FakeRDSInstance) DeleteDBProxyCalls(stub func(string) error) {
+	fake.deleteDBProxyMutex.Lock()
+	defer fake.deleteDBProxyMutex.Unlock()
+	fake.DeleteDBProxyStub = stub
+}
+
+func (fake *FakeRDSInstance) DeleteDBProxyArgsForCall(i int) string {
+	fake.deleteDBProxyMutex.RLock()
+	defer fake.deleteDBProxyMutex.RUnlock()
+	argsForCall := fake.deleteDBProxyArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeRDSInstance) DeleteDBProxyReturns(result1 error) {
+	fake.deleteDBProxyMutex.Lock()
+	defer fake.deleteDBProxyMutex.Unlock()
+	fake.DeleteDBProxyStub = nil
+	fake.deleteDBProxyReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRDSInstance) DeleteDBProxyReturnsOnCall(i int, result1 error) {
+	fake.deleteDBProxyMutex.Lock()
+	defer fake.deleteDBProxyMutex.Unlock()
+	fake.DeleteDBProxyStub = nil
+	if fake.deleteDBProxyReturnsOnCall == nil {
+		fake.deleteDBProxyReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteDBProxyReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRDSInstance) DeleteSnapshot(arg1 string) error {
+	fake.deleteSnapshotMutex.Lock()
+	ret, specificReturn := fake.deleteSnapshotReturnsOnCall[len(fake.deleteSnapshotArgsForCall)]
+	fake.deleteSnapshotArgsForCall = append(fake.deleteSnapshotArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.DeleteSnapshotStub
+	fakeReturns := fake.deleteSnapshotReturns
+	fake.recordInvocation("DeleteSnapshot", []interface{}{arg1})
+	fake.deleteSnapshotMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRDSInstance) DeleteSnapshotCallCount() int {
+	fake.deleteSnapshotMutex.RLock()
+	defer fake.deleteSnapshotMutex.RUnlock()
+	return len(fake.deleteSnapshotArgsForCall)
+}
+
+func (fake *FakeRDSInstance) DeleteSnapshotCalls(stub func(string) error) {
+	fake.deleteSnapshotMutex.Lock()
+	defer fake.deleteSnapshotMutex.Unlock()
+	fake.DeleteSnapshotStub = stub
+}
+
+func (fake *FakeRDSInstance) DeleteSnapshotArgsForCall(i int) string {
+	fake.deleteSnapshotMutex.RLock()
+	defer fake.deleteSnapshotMutex.RUnlock()
+	argsForCall := fake.deleteSnapshotArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeRDSInstance) DeleteSnapshotReturns(result1 error) {
+	fake.deleteSnapshotMutex.Lock()
+	defer fake.deleteSnapshotMutex.Unlock()
+	fake.DeleteSnapshotStub = nil
+	fake.deleteSnapshotReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRDSInstance) DeleteSnapshotReturnsOnCall(i int, result1 error) {
+	fake.deleteSnapshotMutex.Lock()
+	defer fake.deleteSnapshotMutex.Unlock()
+	fake.DeleteSnapshotStub = nil
+	if fake.deleteSnapshotReturnsOnCall == nil {
+		fake.deleteSnapshotReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteSnapshotReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeRDSInstance) DeleteSnapshots(arg1 string, arg2 int) error {
 	fake.deleteSnapshotsMutex.Lock()
 	ret, specificReturn := fake.deleteSnapshotsReturnsOnCall[len(fake.deleteSnapshotsArgsForCall)]
@@ -628,20 +1124,593 @@ func (fake *FakeRDSInstance) DescribeReturnsOnCall(i int, result1 *rds.DBInstanc
 	}{result1, result2}
 }
 
-func (fake *FakeRDSInstance) DescribeByTag(arg1 string, arg2 string, arg3 ...awsrds.DescribeOption) ([]*rds.DBInstance, error) {
-	fake.describeByTagMutex.Lock()
-	ret, specificReturn := fake.describeByTagReturnsOnCall[len(fake.describeByTagArgsForCall)]
-	fake.describeByTagArgsForCall = append(fake.describeByTagArgsForCall, struct {
+func (fake *FakeRDSInstance) DescribeByTag(arg1 string, arg2 string, arg3 ...awsrds.DescribeOption) ([]*rds.DBInstance, error) {
+	fake.describeByTagMutex.Lock()
+	ret, specificReturn := fake.describeByTagReturnsOnCall[len(fake.describeByTagArgsForCall)]
+	fake.describeByTagArgsForCall = append(fake.describeByTagArgsForCall, struct {
+		arg1 string
+		arg2 string
+		arg3 []awsrds.DescribeOption
+	}{arg1, arg2, arg3})
+	stub := fake.DescribeByTagStub
+	fakeReturns := fake.describeByTagReturns
+	fake.recordInvocation("DescribeByTag", []interface{}{arg1, arg2, arg3})
+	fake.describeByTagMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRDSInstance) DescribeByTagCallCount() int {
+	fake.describeByTagMutex.RLock()
+	defer fake.describeByTagMutex.RUnlock()
+	return len(fake.describeByTagArgsForCall)
+}
+
+func (fake *FakeRDSInstance) DescribeByTagCalls(stub func(string, string, ...awsrds.DescribeOption) ([]*rds.DBInstance, error)) {
+	fake.describeByTagMutex.Lock()
+	defer fake.describeByTagMutex.Unlock()
+	fake.DescribeByTagStub = stub
+}
+
+func (fake *FakeRDSInstance) DescribeByTagArgsForCall(i int) (string, string, []awsrds.DescribeOption) {
+	fake.describeByTagMutex.RLock()
+	defer fake.describeByTagMutex.RUnlock()
+	argsForCall := fake.describeByTagArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeRDSInstance) DescribeByTagReturns(result1 []*rds.DBInstance, result2 error) {
+	fake.describeByTagMutex.Lock()
+	defer fake.describeByTagMutex.Unlock()
+	fake.DescribeByTagStub = nil
+	fake.describeByTagReturns = struct {
+		result1 []*rds.DBInstance
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRDSInstance) DescribeByTagReturnsOnCall(i int, result1 []*rds.DBInstance, result2 error) {
+	fake.describeByTagMutex.Lock()
+	defer fake.describeByTagMutex.Unlock()
+	fake.DescribeByTagStub = nil
+	if fake.describeByTagReturnsOnCall == nil {
+		fake.describeByTagReturnsOnCall = make(map[int]struct {
+			result1 []*rds.DBInstance
+			result2 error
+		})
+	}
+	fake.describeByTagReturnsOnCall[i] = struct {
+		result1 []*rds.DBInstance
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRDSInstance) DescribeDBProxy(arg1 string) (*rds.DBProxy, error) {
+	fake.describeDBProxyMutex.Lock()
+	ret, specificReturn := fake.describeDBProxyReturnsOnCall[len(fake.describeDBProxyArgsForCall)]
+	fake.describeDBProxyArgsForCall = append(fake.describeDBProxyArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.DescribeDBProxyStub
+	fakeReturns := fake.describeDBProxyReturns
+	fake.recordInvocation("DescribeDBProxy", []interface{}{arg1})
+	fake.describeDBProxyMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRDSInstance) DescribeDBProxyCallCount() int {
+	fake.describeDBProxyMutex.RLock()
+	defer fake.describeDBProxyMutex.RUnlock()
+	return len(fake.describeDBProxyArgsForCall)
+}
+
+func (fake *FakeRDSInstance) DescribeDBProxyCalls(stub func(string) (*rds.DBProxy, error)) {
+	fake.describeDBProxyMutex.Lock()
+	defer fake.describeDBProxyMutex.Unlock()
+	fake.DescribeDBProxyStub = stub
+}
+
+func (fake *FakeRDSInstance) DescribeDBProxyArgsForCall(i int) string {
+	fake.describeDBProxyMutex.RLock()
+	defer fake.describeDBProxyMutex.RUnlock()
+	argsForCall := fake.describeDBProxyArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeRDSInstance) DescribeDBProxyReturns(result1 *rds.DBProxy, result2 error) {
+	fake.describeDBProxyMutex.Lock()
+	defer fake.describeDBProxyMutex.Unlock()
+	fake.DescribeDBProxyStub = nil
+	fake.describeDBProxyReturns = struct {
+		result1 *rds.DBProxy
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRDSInstance) DescribeDBProxyReturnsOnCall(i int, result1 *rds.DBProxy, result2 error) {
+	fake.describeDBProxyMutex.Lock()
+	defer fake.describeDBProxyMutex.Unlock()
+	fake.DescribeDBProxyStub = nil
+	if fake.describeDBProxyReturnsOnCall == nil {
+		fake.describeDBProxyReturnsOnCall = make(map[int]struct {
+			result1 *rds.DBProxy
+			result2 error
+		})
+	}
+	fake.describeDBProxyReturnsOnCall[i] = struct {
+		result1 *rds.DBProxy
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRDSInstance) DescribeEvents(arg1 string) ([]*rds.Event, error) {
+	fake.describeEventsMutex.Lock()
+	ret, specificReturn := fake.describeEventsReturnsOnCall[len(fake.describeEventsArgsForCall)]
+	fake.describeEventsArgsForCall = append(fake.describeEventsArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.DescribeEventsStub
+	fakeReturns := fake.describeEventsReturns
+	fake.recordInvocation("DescribeEvents", []interface{}{arg1})
+	fake.describeEventsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRDSInstance) DescribeEventsCallCount() int {
+	fake.describeEventsMutex.RLock()
+	defer fake.describeEventsMutex.RUnlock()
+	return len(fake.describeEventsArgsForCall)
+}
+
+func (fake *FakeRDSInstance) DescribeEventsCalls(stub func(string) ([]*rds.Event, error)) {
+	fake.describeEventsMutex.Lock()
+	defer fake.describeEventsMutex.Unlock()
+	fake.DescribeEventsStub = stub
+}
+
+func (fake *FakeRDSInstance) DescribeEventsArgsForCall(i int) string {
+	fake.describeEventsMutex.RLock()
+	defer fake.describeEventsMutex.RUnlock()
+	argsForCall := fake.describeEventsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeRDSInstance) DescribeEventsReturns(result1 []*rds.Event, result2 error) {
+	fake.describeEventsMutex.Lock()
+	defer fake.describeEventsMutex.Unlock()
+	fake.DescribeEventsStub = nil
+	fake.describeEventsReturns = struct {
+		result1 []*rds.Event
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRDSInstance) DescribeEventsReturnsOnCall(i int, result1 []*rds.Event, result2 error) {
+	fake.describeEventsMutex.Lock()
+	defer fake.describeEventsMutex.Unlock()
+	fake.DescribeEventsStub = nil
+	if fake.describeEventsReturnsOnCall == nil {
+		fake.describeEventsReturnsOnCall = make(map[int]struct {
+			result1 []*rds.Event
+			result2 error
+		})
+	}
+	fake.describeEventsReturnsOnCall[i] = struct {
+		result1 []*rds.Event
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRDSInstance) DescribeEventsBetween(arg1 string, arg2 time.Time, arg3 time.Time) ([]*rds.Event, error) {
+	fake.describeEventsBetweenMutex.Lock()
+	ret, specificReturn := fake.describeEventsBetweenReturnsOnCall[len(fake.describeEventsBetweenArgsForCall)]
+	fake.describeEventsBetweenArgsForCall = append(fake.describeEventsBetweenArgsForCall, struct {
+		arg1 string
+		arg2 time.Time
+		arg3 time.Time
+	}{arg1, arg2, arg3})
+	stub := fake.DescribeEventsBetweenStub
+	fakeReturns := fake.describeEventsBetweenReturns
+	fake.recordInvocation("DescribeEventsBetween", []interface{}{arg1, arg2, arg3})
+	fake.describeEventsBetweenMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRDSInstance) DescribeEventsBetweenCallCount() int {
+	fake.describeEventsBetweenMutex.RLock()
+	defer fake.describeEventsBetweenMutex.RUnlock()
+	return len(fake.describeEventsBetweenArgsForCall)
+}
+
+func (fake *FakeRDSInstance) DescribeEventsBetweenCalls(stub func(string, time.Time, time.Time) ([]*rds.Event, error)) {
+	fake.describeEventsBetweenMutex.Lock()
+	defer fake.describeEventsBetweenMutex.Unlock()
+	fake.DescribeEventsBetweenStub = stub
+}
+
+func (fake *FakeRDSInstance) DescribeEventsBetweenArgsForCall(i int) (string, time.Time, time.Time) {
+	fake.describeEventsBetweenMutex.RLock()
+	defer fake.describeEventsBetweenMutex.RUnlock()
+	argsForCall := fake.describeEventsBetweenArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeRDSInstance) DescribeEventsBetweenReturns(result1 []*rds.Event, result2 error) {
+	fake.describeEventsBetweenMutex.Lock()
+	defer fake.describeEventsBetweenMutex.Unlock()
+	fake.DescribeEventsBetweenStub = nil
+	fake.describeEventsBetweenReturns = struct {
+		result1 []*rds.Event
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRDSInstance) DescribeEventsBetweenReturnsOnCall(i int, result1 []*rds.Event, result2 error) {
+	fake.describeEventsBetweenMutex.Lock()
+	defer fake.describeEventsBetweenMutex.Unlock()
+	fake.DescribeEventsBetweenStub = nil
+	if fake.describeEventsBetweenReturnsOnCall == nil {
+		fake.describeEventsBetweenReturnsOnCall = make(map[int]struct {
+			result1 []*rds.Event
+			result2 error
+		})
+	}
+	fake.describeEventsBetweenReturnsOnCall[i] = struct {
+		result1 []*rds.Event
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRDSInstance) DescribeLogFiles(arg1 string, arg2 string) ([]*rds.DescribeDBLogFilesDetails, error) {
+	fake.describeLogFilesMutex.Lock()
+	ret, specificReturn := fake.describeLogFilesReturnsOnCall[len(fake.describeLogFilesArgsForCall)]
+	fake.describeLogFilesArgsForCall = append(fake.describeLogFilesArgsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.DescribeLogFilesStub
+	fakeReturns := fake.describeLogFilesReturns
+	fake.recordInvocation("DescribeLogFiles", []interface{}{arg1, arg2})
+	fake.describeLogFilesMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRDSInstance) DescribeLogFilesCallCount() int {
+	fake.describeLogFilesMutex.RLock()
+	defer fake.describeLogFilesMutex.RUnlock()
+	return len(fake.describeLogFilesArgsForCall)
+}
+
+func (fake *FakeRDSInstance) DescribeLogFilesCalls(stub func(string, string) ([]*rds.DescribeDBLogFilesDetails, error)) {
+	fake.describeLogFilesMutex.Lock()
+	defer fake.describeLogFilesMutex.Unlock()
+	fake.DescribeLogFilesStub = stub
+}
+
+func (fake *FakeRDSInstance) DescribeLogFilesArgsForCall(i int) (string, string) {
+	fake.describeLogFilesMutex.RLock()
+	defer fake.describeLogFilesMutex.RUnlock()
+	argsForCall := fake.describeLogFilesArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRDSInstance) DescribeLogFilesReturns(result1 []*rds.DescribeDBLogFilesDetails, result2 error) {
+	fake.describeLogFilesMutex.Lock()
+	defer fake.describeLogFilesMutex.Unlock()
+	fake.DescribeLogFilesStub = nil
+	fake.describeLogFilesReturns = struct {
+		result1 []*rds.DescribeDBLogFilesDetails
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRDSInstance) DescribeLogFilesReturnsOnCall(i int, result1 []*rds.DescribeDBLogFilesDetails, result2 error) {
+	fake.describeLogFilesMutex.Lock()
+	defer fake.describeLogFilesMutex.Unlock()
+	fake.DescribeLogFilesStub = nil
+	if fake.describeLogFilesReturnsOnCall == nil {
+		fake.describeLogFilesReturnsOnCall = make(map[int]struct {
+			result1 []*rds.DescribeDBLogFilesDetails
+			result2 error
+		})
+	}
+	fake.describeLogFilesReturnsOnCall[i] = struct {
+		result1 []*rds.DescribeDBLogFilesDetails
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRDSInstance) DescribeManualSnapshots(arg1 string, arg2 time.Time) ([]*rds.DBSnapshot, error) {
+	fake.describeManualSnapshotsMutex.Lock()
+	ret, specificReturn := fake.describeManualSnapshotsReturnsOnCall[len(fake.describeManualSnapshotsArgsForCall)]
+	fake.describeManualSnapshotsArgsForCall = append(fake.describeManualSnapshotsArgsForCall, struct {
+		arg1 string
+		arg2 time.Time
+	}{arg1, arg2})
+	stub := fake.DescribeManualSnapshotsStub
+	fakeReturns := fake.describeManualSnapshotsReturns
+	fake.recordInvocation("DescribeManualSnapshots", []interface{}{arg1, arg2})
+	fake.describeManualSnapshotsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRDSInstance) DescribeManualSnapshotsCallCount() int {
+	fake.describeManualSnapshotsMutex.RLock()
+	defer fake.describeManualSnapshotsMutex.RUnlock()
+	return len(fake.describeManualSnapshotsArgsForCall)
+}
+
+func (fake *FakeRDSInstance) DescribeManualSnapshotsCalls(stub func(string, time.Time) ([]*rds.DBSnapshot, error)) {
+	fake.describeManualSnapshotsMutex.Lock()
+	defer fake.describeManualSnapshotsMutex.Unlock()
+	fake.DescribeManualSnapshotsStub = stub
+}
+
+func (fake *FakeRDSInstance) DescribeManualSnapshotsArgsForCall(i int) (string, time.Time) {
+	fake.describeManualSnapshotsMutex.RLock()
+	defer fake.describeManualSnapshotsMutex.RUnlock()
+	argsForCall := fake.describeManualSnapshotsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRDSInstance) DescribeManualSnapshotsReturns(result1 []*rds.DBSnapshot, result2 error) {
+	fake.describeManualSnapshotsMutex.Lock()
+	defer fake.describeManualSnapshotsMutex.Unlock()
+	fake.DescribeManualSnapshotsStub = nil
+	fake.describeManualSnapshotsReturns = struct {
+		result1 []*rds.DBSnapshot
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRDSInstance) DescribeManualSnapshotsReturnsOnCall(i int, result1 []*rds.DBSnapshot, result2 error) {
+	fake.describeManualSnapshotsMutex.Lock()
+	defer fake.describeManualSnapshotsMutex.Unlock()
+	fake.DescribeManualSnapshotsStub = nil
+	if fake.describeManualSnapshotsReturnsOnCall == nil {
+		fake.describeManualSnapshotsReturnsOnCall = make(map[int]struct {
+			result1 []*rds.DBSnapshot
+			result2 error
+		})
+	}
+	fake.describeManualSnapshotsReturnsOnCall[i] = struct {
+		result1 []*rds.DBSnapshot
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRDSInstance) DescribeOrderableOptions(arg1 string, arg2 string) ([]*rds.OrderableDBInstanceOption, error) {
+	fake.describeOrderableOptionsMutex.Lock()
+	ret, specificReturn := fake.describeOrderableOptionsReturnsOnCall[len(fake.describeOrderableOptionsArgsForCall)]
+	fake.describeOrderableOptionsArgsForCall = append(fake.describeOrderableOptionsArgsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.DescribeOrderableOptionsStub
+	fakeReturns := fake.describeOrderableOptionsReturns
+	fake.recordInvocation("DescribeOrderableOptions", []interface{}{arg1, arg2})
+	fake.describeOrderableOptionsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRDSInstance) DescribeOrderableOptionsCallCount() int {
+	fake.describeOrderableOptionsMutex.RLock()
+	defer fake.describeOrderableOptionsMutex.RUnlock()
+	return len(fake.describeOrderableOptionsArgsForCall)
+}
+
+func (fake *FakeRDSInstance) DescribeOrderableOptionsCalls(stub func(string, string) ([]*rds.OrderableDBInstanceOption, error)) {
+	fake.describeOrderableOptionsMutex.Lock()
+	defer fake.describeOrderableOptionsMutex.Unlock()
+	fake.DescribeOrderableOptionsStub = stub
+}
+
+func (fake *FakeRDSInstance) DescribeOrderableOptionsArgsForCall(i int) (string, string) {
+	fake.describeOrderableOptionsMutex.RLock()
+	defer fake.describeOrderableOptionsMutex.RUnlock()
+	argsForCall := fake.describeOrderableOptionsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRDSInstance) DescribeOrderableOptionsReturns(result1 []*rds.OrderableDBInstanceOption, result2 error) {
+	fake.describeOrderableOptionsMutex.Lock()
+	defer fake.describeOrderableOptionsMutex.Unlock()
+	fake.DescribeOrderableOptionsStub = nil
+	fake.describeOrderableOptionsReturns = struct {
+		result1 []*rds.OrderableDBInstanceOption
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRDSInstance) DescribeOrderableOptionsReturnsOnCall(i int, result1 []*rds.OrderableDBInstanceOption, result2 error) {
+	fake.describeOrderableOptionsMutex.Lock()
+	defer fake.describeOrderableOptionsMutex.Unlock()
+	fake.DescribeOrderableOptionsStub = nil
+	if fake.describeOrderableOptionsReturnsOnCall == nil {
+		fake.describeOrderableOptionsReturnsOnCall = make(map[int]struct {
+			result1 []*rds.OrderableDBInstanceOption
+			result2 error
+		})
+	}
+	fake.describeOrderableOptionsReturnsOnCall[i] = struct {
+		result1 []*rds.OrderableDBInstanceOption
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRDSInstance) DescribeParameters(arg1 string) ([]*rds.Parameter, error) {
+	fake.describeParametersMutex.Lock()
+	ret, specificReturn := fake.describeParametersReturnsOnCall[len(fake.describeParametersArgsForCall)]
+	fake.describeParametersArgsForCall = append(fake.describeParametersArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.DescribeParametersStub
+	fakeReturns := fake.describeParametersReturns
+	fake.recordInvocation("DescribeParameters", []interface{}{arg1})
+	fake.describeParametersMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRDSInstance) DescribeParametersCallCount() int {
+	fake.describeParametersMutex.RLock()
+	defer fake.describeParametersMutex.RUnlock()
+	return len(fake.describeParametersArgsForCall)
+}
+
+func (fake *FakeRDSInstance) DescribeParametersCalls(stub func(string) ([]*rds.Parameter, error)) {
+	fake.describeParametersMutex.Lock()
+	defer fake.describeParametersMutex.Unlock()
+	fake.DescribeParametersStub = stub
+}
+
+func (fake *FakeRDSInstance) DescribeParametersArgsForCall(i int) string {
+	fake.describeParametersMutex.RLock()
+	defer fake.describeParametersMutex.RUnlock()
+	argsForCall := fake.describeParametersArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeRDSInstance) DescribeParametersReturns(result1 []*rds.Parameter, result2 error) {
+	fake.describeParametersMutex.Lock()
+	defer fake.describeParametersMutex.Unlock()
+	fake.DescribeParametersStub = nil
+	fake.describeParametersReturns = struct {
+		result1 []*rds.Parameter
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRDSInstance) DescribeParametersReturnsOnCall(i int, result1 []*rds.Parameter, result2 error) {
+	fake.describeParametersMutex.Lock()
+	defer fake.describeParametersMutex.Unlock()
+	fake.DescribeParametersStub = nil
+	if fake.describeParametersReturnsOnCall == nil {
+		fake.describeParametersReturnsOnCall = make(map[int]struct {
+			result1 []*rds.Parameter
+			result2 error
+		})
+	}
+	fake.describeParametersReturnsOnCall[i] = struct {
+		result1 []*rds.Parameter
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRDSInstance) DescribePendingMaintenanceActions() (map[string][]*rds.PendingMaintenanceAction, error) {
+	fake.describePendingMaintenanceActionsMutex.Lock()
+	ret, specificReturn := fake.describePendingMaintenanceActionsReturnsOnCall[len(fake.describePendingMaintenanceActionsArgsForCall)]
+	fake.describePendingMaintenanceActionsArgsForCall = append(fake.describePendingMaintenanceActionsArgsForCall, struct {
+	}{})
+	stub := fake.DescribePendingMaintenanceActionsStub
+	fakeReturns := fake.describePendingMaintenanceActionsReturns
+	fake.recordInvocation("DescribePendingMaintenanceActions", []interface{}{})
+	fake.describePendingMaintenanceActionsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRDSInstance) DescribePendingMaintenanceActionsCallCount() int {
+	fake.describePendingMaintenanceActionsMutex.RLock()
+	defer fake.describePendingMaintenanceActionsMutex.RUnlock()
+	return len(fake.describePendingMaintenanceActionsArgsForCall)
+}
+
+func (fake *FakeRDSInstance) DescribePendingMaintenanceActionsCalls(stub func() (map[string][]*rds.PendingMaintenanceAction, error)) {
+	fake.describePendingMaintenanceActionsMutex.Lock()
+	defer fake.describePendingMaintenanceActionsMutex.Unlock()
+	fake.DescribePendingMaintenanceActionsStub = stub
+}
+
+func (fake *FakeRDSInstance) DescribePendingMaintenanceActionsReturns(result1 map[string][]*rds.PendingMaintenanceAction, result2 error) {
+	fake.describePendingMaintenanceActionsMutex.Lock()
+	defer fake.describePendingMaintenanceActionsMutex.Unlock()
+	fake.DescribePendingMaintenanceActionsStub = nil
+	fake.describePendingMaintenanceActionsReturns = struct {
+		result1 map[string][]*rds.PendingMaintenanceAction
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRDSInstance) DescribePendingMaintenanceActionsReturnsOnCall(i int, result1 map[string][]*rds.PendingMaintenanceAction, result2 error) {
+	fake.describePendingMaintenanceActionsMutex.Lock()
+	defer fake.describePendingMaintenanceActionsMutex.Unlock()
+	fake.DescribePendingMaintenanceActionsStub = nil
+	if fake.describePendingMaintenanceActionsReturnsOnCall == nil {
+		fake.describePendingMaintenanceActionsReturnsOnCall = make(map[int]struct {
+			result1 map[string][]*rds.PendingMaintenanceAction
+			result2 error
+		})
+	}
+	fake.describePendingMaintenanceActionsReturnsOnCall[i] = struct {
+		result1 map[string][]*rds.PendingMaintenanceAction
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRDSInstance) DescribeSnapshot(arg1 string) (*rds.DBSnapshot, error) {
+	fake.describeSnapshotMutex.Lock()
+	ret, specificReturn := fake.describeSnapshotReturnsOnCall[len(fake.describeSnapshotArgsForCall)]
+	fake.describeSnapshotArgsForCall = append(fake.describeSnapshotArgsForCall, struct {
 		arg1 string
-		arg2 string
-		arg3 []awsrds.DescribeOption
-	}{arg1, arg2, arg3})
-	stub := fake.DescribeByTagStub
-	fakeReturns := fake.describeByTagReturns
-	fake.recordInvocation("DescribeByTag", []interface{}{arg1, arg2, arg3})
-	fake.describeByTagMutex.Unlock()
+	}{arg1})
+	stub := fake.DescribeSnapshotStub
+	fakeReturns := fake.describeSnapshotReturns
+	fake.recordInvocation("DescribeSnapshot", []interface{}{arg1})
+	fake.describeSnapshotMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2, arg3...)
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -649,47 +1718,47 @@ func (fake *FakeRDSInstance) DescribeByTag(arg1 string, arg2 string, arg3 ...aws
 	return fakeReturns.result1, fakeReturns.result2
 }
 
-func (fake *FakeRDSInstance) DescribeByTagCallCount() int {
-	fake.describeByTagMutex.RLock()
-	defer fake.describeByTagMutex.RUnlock()
-	return len(fake.describeByTagArgsForCall)
+func (fake *FakeRDSInstance) DescribeSnapshotCallCount() int {
+	fake.describeSnapshotMutex.RLock()
+	defer fake.describeSnapshotMutex.RUnlock()
+	return len(fake.describeSnapshotArgsForCall)
 }
 
-func (fake *FakeRDSInstance) DescribeByTagCalls(stub func(string, string, ...awsrds.DescribeOption) ([]*rds.DBInstance, error)) {
-	fake.describeByTagMutex.Lock()
-	defer fake.describeByTagMutex.Unlock()
-	fake.DescribeByTagStub = stub
+func (fake *FakeRDSInstance) DescribeSnapshotCalls(stub func(string) (*rds.DBSnapshot, error)) {
+	fake.describeSnapshotMutex.Lock()
+	defer fake.describeSnapshotMutex.Unlock()
+	fake.DescribeSnapshotStub = stub
 }
 
-func (fake *FakeRDSInstance) DescribeByTagArgsForCall(i int) (string, string, []awsrds.DescribeOption) {
-	fake.describeByTagMutex.RLock()
-	defer fake.describeByTagMutex.RUnlock()
-	argsForCall := fake.describeByTagArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+func (fake *FakeRDSInstance) DescribeSnapshotArgsForCall(i int) string {
+	fake.describeSnapshotMutex.RLock()
+	defer fake.describeSnapshotMutex.RUnlock()
+	argsForCall := fake.describeSnapshotArgsForCall[i]
+	return argsForCall.arg1
 }
 
-func (fake *FakeRDSInstance) DescribeByTagReturns(result1 []*rds.DBInstance, result2 error) {
-	fake.describeByTagMutex.Lock()
-	defer fake.describeByTagMutex.Unlock()
-	fake.DescribeByTagStub = nil
-	fake.describeByTagReturns = struct {
-		result1 []*rds.DBInstance
+func (fake *FakeRDSInstance) DescribeSnapshotReturns(result1 *rds.DBSnapshot, result2 error) {
+	fake.describeSnapshotMutex.Lock()
+	defer fake.describeSnapshotMutex.Unlock()
+	fake.DescribeSnapshotStub = nil
+	fake.describeSnapshotReturns = struct {
+		result1 *rds.DBSnapshot
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeRDSInstance) DescribeByTagReturnsOnCall(i int, result1 []*rds.DBInstance, result2 error) {
-	fake.describeByTagMutex.Lock()
-	defer fake.describeByTagMutex.Unlock()
-	fake.DescribeByTagStub = nil
-	if fake.describeByTagReturnsOnCall == nil {
-		fake.describeByTagReturnsOnCall = make(map[int]struct {
-			result1 []*rds.DBInstance
+func (fake *FakeRDSInstance) DescribeSnapshotReturnsOnCall(i int, result1 *rds.DBSnapshot, result2 error) {
+	fake.describeSnapshotMutex.Lock()
+	defer fake.describeSnapshotMutex.Unlock()
+	fake.DescribeSnapshotStub = nil
+	if fake.describeSnapshotReturnsOnCall == nil {
+		fake.describeSnapshotReturnsOnCall = make(map[int]struct {
+			result1 *rds.DBSnapshot
 			result2 error
 		})
 	}
-	fake.describeByTagReturnsOnCall[i] = struct {
-		result1 []*rds.DBInstance
+	fake.describeSnapshotReturnsOnCall[i] = struct {
+		result1 *rds.DBSnapshot
 		result2 error
 	}{result1, result2}
 }
@@ -758,6 +1827,69 @@ func (fake *FakeRDSInstance) DescribeSnapshotsReturnsOnCall(i int, result1 []*rd
 	}{result1, result2}
 }
 
+func (fake *FakeRDSInstance) DownloadLogFile(arg1 string, arg2 string, arg3 io.Writer) error {
+	fake.downloadLogFileMutex.Lock()
+	ret, specificReturn := fake.downloadLogFileReturnsOnCall[len(fake.downloadLogFileArgsForCall)]
+	fake.downloadLogFileArgsForCall = append(fake.downloadLogFileArgsForCall, struct {
+		arg1 string
+		arg2 string
+		arg3 io.Writer
+	}{arg1, arg2, arg3})
+	stub := fake.DownloadLogFileStub
+	fakeReturns := fake.downloadLogFileReturns
+	fake.recordInvocation("DownloadLogFile", []interface{}{arg1, arg2, arg3})
+	fake.downloadLogFileMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRDSInstance) DownloadLogFileCallCount() int {
+	fake.downloadLogFileMutex.RLock()
+	defer fake.downloadLogFileMutex.RUnlock()
+	return len(fake.downloadLogFileArgsForCall)
+}
+
+func (fake *FakeRDSInstance) DownloadLogFileCalls(stub func(string, string, io.Writer) error) {
+	fake.downloadLogFileMutex.Lock()
+	defer fake.downloadLogFileMutex.Unlock()
+	fake.DownloadLogFileStub = stub
+}
+
+func (fake *FakeRDSInstance) DownloadLogFileArgsForCall(i int) (string, string, io.Writer) {
+	fake.downloadLogFileMutex.RLock()
+	defer fake.downloadLogFileMutex.RUnlock()
+	argsForCall := fake.downloadLogFileArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeRDSInstance) DownloadLogFileReturns(result1 error) {
+	fake.downloadLogFileMutex.Lock()
+	defer fake.downloadLogFileMutex.Unlock()
+	fake.DownloadLogFileStub = nil
+	fake.downloadLogFileReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRDSInstance) DownloadLogFileReturnsOnCall(i int, result1 error) {
+	fake.downloadLogFileMutex.Lock()
+	defer fake.downloadLogFileMutex.Unlock()
+	fake.DownloadLogFileStub = nil
+	if fake.downloadLogFileReturnsOnCall == nil {
+		fake.downloadLogFileReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.downloadLogFileReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeRDSInstance) GetFullValidTargetVersion(arg1 string, arg2 string, arg3 string) (string, error) {
 	fake.getFullValidTargetVersionMutex.Lock()
 	ret, specificReturn := fake.getFullValidTargetVersionReturnsOnCall[len(fake.getFullValidTargetVersionArgsForCall)]
@@ -953,6 +2085,71 @@ func (fake *FakeRDSInstance) GetParameterGroupReturnsOnCall(i int, result1 *rds.
 	}{result1, result2}
 }
 
+func (fake *FakeRDSInstance) GetParameterGroupFamily(arg1 string, arg2 string) (string, error) {
+	fake.getParameterGroupFamilyMutex.Lock()
+	ret, specificReturn := fake.getParameterGroupFamilyReturnsOnCall[len(fake.getParameterGroupFamilyArgsForCall)]
+	fake.getParameterGroupFamilyArgsForCall = append(fake.getParameterGroupFamilyArgsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.GetParameterGroupFamilyStub
+	fakeReturns := fake.getParameterGroupFamilyReturns
+	fake.recordInvocation("GetParameterGroupFamily", []interface{}{arg1, arg2})
+	fake.getParameterGroupFamilyMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRDSInstance) GetParameterGroupFamilyCallCount() int {
+	fake.getParameterGroupFamilyMutex.RLock()
+	defer fake.getParameterGroupFamilyMutex.RUnlock()
+	return len(fake.getParameterGroupFamilyArgsForCall)
+}
+
+func (fake *FakeRDSInstance) GetParameterGroupFamilyCalls(stub func(string, string) (string, error)) {
+	fake.getParameterGroupFamilyMutex.Lock()
+	defer fake.getParameterGroupFamilyMutex.Unlock()
+	fake.GetParameterGroupFamilyStub = stub
+}
+
+func (fake *FakeRDSInstance) GetParameterGroupFamilyArgsForCall(i int) (string, string) {
+	fake.getParameterGroupFamilyMutex.RLock()
+	defer fake.getParameterGroupFamilyMutex.RUnlock()
+	argsForCall := fake.getParameterGroupFamilyArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRDSInstance) GetParameterGroupFamilyReturns(result1 string, result2 error) {
+	fake.getParameterGroupFamilyMutex.Lock()
+	defer fake.getParameterGroupFamilyMutex.Unlock()
+	fake.GetParameterGroupFamilyStub = nil
+	fake.getParameterGroupFamilyReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRDSInstance) GetParameterGroupFamilyReturnsOnCall(i int, result1 string, result2 error) {
+	fake.getParameterGroupFamilyMutex.Lock()
+	defer fake.getParameterGroupFamilyMutex.Unlock()
+	fake.GetParameterGroupFamilyStub = nil
+	if fake.getParameterGroupFamilyReturnsOnCall == nil {
+		fake.getParameterGroupFamilyReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.getParameterGroupFamilyReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeRDSInstance) GetResourceTags(arg1 string, arg2 ...awsrds.DescribeOption) ([]*rds.Tag, error) {
 	fake.getResourceTagsMutex.Lock()
 	ret, specificReturn := fake.getResourceTagsReturnsOnCall[len(fake.getResourceTagsArgsForCall)]
@@ -1269,6 +2466,68 @@ func (fake *FakeRDSInstance) RebootReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeRDSInstance) RegisterDBProxyTargets(arg1 string, arg2 string) error {
+	fake.registerDBProxyTargetsMutex.Lock()
+	ret, specificReturn := fake.registerDBProxyTargetsReturnsOnCall[len(fake.registerDBProxyTargetsArgsForCall)]
+	fake.registerDBProxyTargetsArgsForCall = append(fake.registerDBProxyTargetsArgsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.RegisterDBProxyTargetsStub
+	fakeReturns := fake.registerDBProxyTargetsReturns
+	fake.recordInvocation("RegisterDBProxyTargets", []interface{}{arg1, arg2})
+	fake.registerDBProxyTargetsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRDSInstance) RegisterDBProxyTargetsCallCount() int {
+	fake.registerDBProxyTargetsMutex.RLock()
+	defer fake.registerDBProxyTargetsMutex.RUnlock()
+	return len(fake.registerDBProxyTargetsArgsForCall)
+}
+
+func (fake *FakeRDSInstance) RegisterDBProxyTargetsCalls(stub func(string, string) error) {
+	fake.registerDBProxyTargetsMutex.Lock()
+	defer fake.registerDBProxyTargetsMutex.Unlock()
+	fake.RegisterDBProxyTargetsStub = stub
+}
+
+func (fake *FakeRDSInstance) RegisterDBProxyTargetsArgsForCall(i int) (string, string) {
+	fake.registerDBProxyTargetsMutex.RLock()
+	defer fake.registerDBProxyTargetsMutex.RUnlock()
+	argsForCall := fake.registerDBProxyTargetsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRDSInstance) RegisterDBProxyTargetsReturns(result1 error) {
+	fake.registerDBProxyTargetsMutex.Lock()
+	defer fake.registerDBProxyTargetsMutex.Unlock()
+	fake.RegisterDBProxyTargetsStub = nil
+	fake.registerDBProxyTargetsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRDSInstance) RegisterDBProxyTargetsReturnsOnCall(i int, result1 error) {
+	fake.registerDBProxyTargetsMutex.Lock()
+	defer fake.registerDBProxyTargetsMutex.Unlock()
+	fake.RegisterDBProxyTargetsStub = nil
+	if fake.registerDBProxyTargetsReturnsOnCall == nil {
+		fake.registerDBProxyTargetsReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.registerDBProxyTargetsReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeRDSInstance) RemoveTag(arg1 string, arg2 string) error {
 	fake.removeTagMutex.Lock()
 	ret, specificReturn := fake.removeTagReturnsOnCall[len(fake.removeTagArgsForCall)]
@@ -1453,31 +2712,312 @@ func (fake *FakeRDSInstance) RestoreToPointInTimeReturnsOnCall(i int, result1 er
 	}{result1}
 }
 
+func (fake *FakeRDSInstance) SetDBProxyAuth(arg1 string, arg2 []*rds.UserAuthConfig) error {
+	var arg2Copy []*rds.UserAuthConfig
+	if arg2 != nil {
+		arg2Copy = make([]*rds.UserAuthConfig, len(arg2))
+		copy(arg2Copy, arg2)
+	}
+	fake.setDBProxyAuthMutex.Lock()
+	ret, specificReturn := fake.setDBProxyAuthReturnsOnCall[len(fake.setDBProxyAuthArgsForCall)]
+	fake.setDBProxyAuthArgsForCall = append(fake.setDBProxyAuthArgsForCall, struct {
+		arg1 string
+		arg2 []*rds.UserAuthConfig
+	}{arg1, arg2Copy})
+	stub := fake.SetDBProxyAuthStub
+	fakeReturns := fake.setDBProxyAuthReturns
+	fake.recordInvocation("SetDBProxyAuth", []interface{}{arg1, arg2Copy})
+	fake.setDBProxyAuthMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRDSInstance) SetDBProxyAuthCallCount() int {
+	fake.setDBProxyAuthMutex.RLock()
+	defer fake.setDBProxyAuthMutex.RUnlock()
+	return len(fake.setDBProxyAuthArgsForCall)
+}
+
+func (fake *FakeRDSInstance) SetDBProxyAuthCalls(stub func(string, []*rds.UserAuthConfig) error) {
+	fake.setDBProxyAuthMutex.Lock()
+	defer fake.setDBProxyAuthMutex.Unlock()
+	fake.SetDBProxyAuthStub = stub
+}
+
+func (fake *FakeRDSInstance) SetDBProxyAuthArgsForCall(i int) (string, []*rds.UserAuthConfig) {
+	fake.setDBProxyAuthMutex.RLock()
+	defer fake.setDBProxyAuthMutex.RUnlock()
+	argsForCall := fake.setDBProxyAuthArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRDSInstance) SetDBProxyAuthReturns(result1 error) {
+	fake.setDBProxyAuthMutex.Lock()
+	defer fake.setDBProxyAuthMutex.Unlock()
+	fake.SetDBProxyAuthStub = nil
+	fake.setDBProxyAuthReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRDSInstance) SetDBProxyAuthReturnsOnCall(i int, result1 error) {
+	fake.setDBProxyAuthMutex.Lock()
+	defer fake.setDBProxyAuthMutex.Unlock()
+	fake.SetDBProxyAuthStub = nil
+	if fake.setDBProxyAuthReturnsOnCall == nil {
+		fake.setDBProxyAuthReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.setDBProxyAuthReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRDSInstance) ShareSnapshot(arg1 string, arg2 string) error {
+	fake.shareSnapshotMutex.Lock()
+	ret, specificReturn := fake.shareSnapshotReturnsOnCall[len(fake.shareSnapshotArgsForCall)]
+	fake.shareSnapshotArgsForCall = append(fake.shareSnapshotArgsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.ShareSnapshotStub
+	fakeReturns := fake.shareSnapshotReturns
+	fake.recordInvocation("ShareSnapshot", []interface{}{arg1, arg2})
+	fake.shareSnapshotMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRDSInstance) ShareSnapshotCallCount() int {
+	fake.shareSnapshotMutex.RLock()
+	defer fake.shareSnapshotMutex.RUnlock()
+	return len(fake.shareSnapshotArgsForCall)
+}
+
+func (fake *FakeRDSInstance) ShareSnapshotCalls(stub func(string, string) error) {
+	fake.shareSnapshotMutex.Lock()
+	defer fake.shareSnapshotMutex.Unlock()
+	fake.ShareSnapshotStub = stub
+}
+
+func (fake *FakeRDSInstance) ShareSnapshotArgsForCall(i int) (string, string) {
+	fake.shareSnapshotMutex.RLock()
+	defer fake.shareSnapshotMutex.RUnlock()
+	argsForCall := fake.shareSnapshotArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRDSInstance) ShareSnapshotReturns(result1 error) {
+	fake.shareSnapshotMutex.Lock()
+	defer fake.shareSnapshotMutex.Unlock()
+	fake.ShareSnapshotStub = nil
+	fake.shareSnapshotReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRDSInstance) ShareSnapshotReturnsOnCall(i int, result1 error) {
+	fake.shareSnapshotMutex.Lock()
+	defer fake.shareSnapshotMutex.Unlock()
+	fake.ShareSnapshotStub = nil
+	if fake.shareSnapshotReturnsOnCall == nil {
+		fake.shareSnapshotReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.shareSnapshotReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRDSInstance) Start(arg1 string) error {
+	fake.startMutex.Lock()
+	ret, specificReturn := fake.startReturnsOnCall[len(fake.startArgsForCall)]
+	fake.startArgsForCall = append(fake.startArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.StartStub
+	fakeReturns := fake.startReturns
+	fake.recordInvocation("Start", []interface{}{arg1})
+	fake.startMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRDSInstance) StartCallCount() int {
+	fake.startMutex.RLock()
+	defer fake.startMutex.RUnlock()
+	return len(fake.startArgsForCall)
+}
+
+func (fake *FakeRDSInstance) StartCalls(stub func(string) error) {
+	fake.startMutex.Lock()
+	defer fake.startMutex.Unlock()
+	fake.StartStub = stub
+}
+
+func (fake *FakeRDSInstance) StartArgsForCall(i int) string {
+	fake.startMutex.RLock()
+	defer fake.startMutex.RUnlock()
+	argsForCall := fake.startArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeRDSInstance) StartReturns(result1 error) {
+	fake.startMutex.Lock()
+	defer fake.startMutex.Unlock()
+	fake.StartStub = nil
+	fake.startReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRDSInstance) StartReturnsOnCall(i int, result1 error) {
+	fake.startMutex.Lock()
+	defer fake.startMutex.Unlock()
+	fake.StartStub = nil
+	if fake.startReturnsOnCall == nil {
+		fake.startReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.startReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRDSInstance) Stop(arg1 string) error {
+	fake.stopMutex.Lock()
+	ret, specificReturn := fake.stopReturnsOnCall[len(fake.stopArgsForCall)]
+	fake.stopArgsForCall = append(fake.stopArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.StopStub
+	fakeReturns := fake.stopReturns
+	fake.recordInvocation("Stop", []interface{}{arg1})
+	fake.stopMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRDSInstance) StopCallCount() int {
+	fake.stopMutex.RLock()
+	defer fake.stopMutex.RUnlock()
+	return len(fake.stopArgsForCall)
+}
+
+func (fake *FakeRDSInstance) StopCalls(stub func(string) error) {
+	fake.stopMutex.Lock()
+	defer fake.stopMutex.Unlock()
+	fake.StopStub = stub
+}
+
+func (fake *FakeRDSInstance) StopArgsForCall(i int) string {
+	fake.stopMutex.RLock()
+	defer fake.stopMutex.RUnlock()
+	argsForCall := fake.stopArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeRDSInstance) StopReturns(result1 error) {
+	fake.stopMutex.Lock()
+	defer fake.stopMutex.Unlock()
+	fake.StopStub = nil
+	fake.stopReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRDSInstance) StopReturnsOnCall(i int, result1 error) {
+	fake.stopMutex.Lock()
+	defer fake.stopMutex.Unlock()
+	fake.StopStub = nil
+	if fake.stopReturnsOnCall == nil {
+		fake.stopReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.stopReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeRDSInstance) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
 	fake.addTagsToResourceMutex.RLock()
 	defer fake.addTagsToResourceMutex.RUnlock()
+	fake.copySnapshotMutex.RLock()
+	defer fake.copySnapshotMutex.RUnlock()
 	fake.createMutex.RLock()
 	defer fake.createMutex.RUnlock()
+	fake.createDBProxyMutex.RLock()
+	defer fake.createDBProxyMutex.RUnlock()
 	fake.createParameterGroupMutex.RLock()
 	defer fake.createParameterGroupMutex.RUnlock()
 	fake.deleteMutex.RLock()
 	defer fake.deleteMutex.RUnlock()
+	fake.deleteDBProxyMutex.RLock()
+	defer fake.deleteDBProxyMutex.RUnlock()
+	fake.deleteSnapshotMutex.RLock()
+	defer fake.deleteSnapshotMutex.RUnlock()
 	fake.deleteSnapshotsMutex.RLock()
 	defer fake.deleteSnapshotsMutex.RUnlock()
 	fake.describeMutex.RLock()
 	defer fake.describeMutex.RUnlock()
 	fake.describeByTagMutex.RLock()
 	defer fake.describeByTagMutex.RUnlock()
+	fake.describeDBProxyMutex.RLock()
+	defer fake.describeDBProxyMutex.RUnlock()
+	fake.describeEventsMutex.RLock()
+	defer fake.describeEventsMutex.RUnlock()
+	fake.describeEventsBetweenMutex.RLock()
+	defer fake.describeEventsBetweenMutex.RUnlock()
+	fake.describeLogFilesMutex.RLock()
+	defer fake.describeLogFilesMutex.RUnlock()
+	fake.describeManualSnapshotsMutex.RLock()
+	defer fake.describeManualSnapshotsMutex.RUnlock()
+	fake.describeOrderableOptionsMutex.RLock()
+	defer fake.describeOrderableOptionsMutex.RUnlock()
+	fake.describeParametersMutex.RLock()
+	defer fake.describeParametersMutex.RUnlock()
+	fake.describePendingMaintenanceActionsMutex.RLock()
+	defer fake.describePendingMaintenanceActionsMutex.RUnlock()
+	fake.describeSnapshotMutex.RLock()
+	defer fake.describeSnapshotMutex.RUnlock()
 	fake.describeSnapshotsMutex.RLock()
 	defer fake.describeSnapshotsMutex.RUnlock()
+	fake.downloadLogFileMutex.RLock()
+	defer fake.downloadLogFileMutex.RUnlock()
 	fake.getFullValidTargetVersionMutex.RLock()
 	defer fake.getFullValidTargetVersionMutex.RUnlock()
 	fake.getLatestMinorVersionMutex.RLock()
 	defer fake.getLatestMinorVersionMutex.RUnlock()
 	fake.getParameterGroupMutex.RLock()
 	defer fake.getParameterGroupMutex.RUnlock()
+	fake.getParameterGroupFamilyMutex.RLock()
+	defer fake.getParameterGroupFamilyMutex.RUnlock()
 	fake.getResourceTagsMutex.RLock()
 	defer fake.getResourceTagsMutex.RUnlock()
 	fake.getTagMutex.RLock()
@@ -1488,12 +3028,22 @@ func (fake *FakeRDSInstance) Invocations() map[string][][]interface{} {
 	defer fake.modifyParameterGroupMutex.RUnlock()
 	fake.rebootMutex.RLock()
 	defer fake.rebootMutex.RUnlock()
+	fake.registerDBProxyTargetsMutex.RLock()
+	defer fake.registerDBProxyTargetsMutex.RUnlock()
 	fake.removeTagMutex.RLock()
 	defer fake.removeTagMutex.RUnlock()
 	fake.restoreMutex.RLock()
 	defer fake.restoreMutex.RUnlock()
 	fake.restoreToPointInTimeMutex.RLock()
 	defer fake.restoreToPointInTimeMutex.RUnlock()
+	fake.setDBProxyAuthMutex.RLock()
+	defer fake.setDBProxyAuthMutex.RUnlock()
+	fake.shareSnapshotMutex.RLock()
+	defer fake.shareSnapshotMutex.RUnlock()
+	fake.startMutex.RLock()
+	defer fake.startMutex.RUnlock()
+	fake.stopMutex.RLock()
+	defer fake.stopMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
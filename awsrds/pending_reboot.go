@@ -0,0 +1,58 @@
+package awsrds
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+const (
+	parameterApplyStatusApplying      = "applying"
+	parameterApplyStatusPendingReboot = "pending-reboot"
+)
+
+// PendingReboot is whether a DB instance has to be rebooted for changes to
+// its parameter groups to take effect.
+type PendingReboot struct {
+	// Applying is set while RDS is still applying a parameter group change,
+	// after which it may need a reboot.
+	Applying bool
+	// Required is set when a parameter group change only takes effect once
+	// the instance is rebooted.
+	Required bool
+	// ParameterGroups are the names of the parameter groups waiting for a
+	// reboot.
+	ParameterGroups []string
+}
+
+// GetPendingReboot reports whether the DB instance is waiting for a reboot,
+// from the apply status of its parameter groups.
+func GetPendingReboot(dbInstance *rds.DBInstance) PendingReboot {
+	pendingReboot := PendingReboot{ParameterGroups: []string{}}
+	for _, parameterGroup := range dbInstance.DBParameterGroups {
+		switch aws.StringValue(parameterGroup.ParameterApplyStatus) {
+		case parameterApplyStatusApplying:
+			pendingReboot.Applying = true
+		case parameterApplyStatusPendingReboot:
+			pendingReboot.Required = true
+			pendingReboot.ParameterGroups = append(pendingReboot.ParameterGroups, aws.StringValue(parameterGroup.DBParameterGroupName))
+		}
+	}
+	return pendingReboot
+}
+
+// PendingRebootParameters picks the names of the parameters of a group which
+// only take effect after a reboot. RDS doesn't tell which of them have changed
+// since the instance was last rebooted, so these are all the static
+// parameters the group sets.
+func PendingRebootParameters(parameters []*rds.Parameter) []string {
+	names := []string{}
+	for _, parameter := range parameters {
+		if aws.StringValue(parameter.Source) != "user" {
+			continue
+		}
+		if aws.StringValue(parameter.ApplyType) == "static" || aws.StringValue(parameter.ApplyMethod) == rds.ApplyMethodPendingReboot {
+			names = append(names, aws.StringValue(parameter.ParameterName))
+		}
+	}
+	return names
+}
@@ -0,0 +1,62 @@
+package awsrds_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/alphagov/paas-rds-broker/awsrds"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+var _ = Describe("Pending Reboot", func() {
+	Describe("GetPendingReboot", func() {
+		It("reports no reboot when the parameter groups are in sync", func() {
+			pendingReboot := GetPendingReboot(&rds.DBInstance{
+				DBParameterGroups: []*rds.DBParameterGroupStatus{
+					{DBParameterGroupName: aws.String("group-1"), ParameterApplyStatus: aws.String("in-sync")},
+				},
+			})
+			Expect(pendingReboot).To(Equal(PendingReboot{ParameterGroups: []string{}}))
+		})
+
+		It("reports the parameter groups waiting for a reboot", func() {
+			pendingReboot := GetPendingReboot(&rds.DBInstance{
+				DBParameterGroups: []*rds.DBParameterGroupStatus{
+					{DBParameterGroupName: aws.String("group-1"), ParameterApplyStatus: aws.String("pending-reboot")},
+					{DBParameterGroupName: aws.String("group-2"), ParameterApplyStatus: aws.String("in-sync")},
+				},
+			})
+			Expect(pendingReboot.Required).To(BeTrue())
+			Expect(pendingReboot.Applying).To(BeFalse())
+			Expect(pendingReboot.ParameterGroups).To(Equal([]string{"group-1"}))
+		})
+
+		It("reports changes still being applied", func() {
+			pendingReboot := GetPendingReboot(&rds.DBInstance{
+				DBParameterGroups: []*rds.DBParameterGroupStatus{
+					{DBParameterGroupName: aws.String("group-1"), ParameterApplyStatus: aws.String("applying")},
+				},
+			})
+			Expect(pendingReboot.Applying).To(BeTrue())
+			Expect(pendingReboot.Required).To(BeFalse())
+		})
+
+		It("copes with an instance without parameter groups", func() {
+			Expect(GetPendingReboot(&rds.DBInstance{}).Required).To(BeFalse())
+		})
+	})
+
+	Describe("PendingRebootParameters", func() {
+		It("picks the static parameters set by the group", func() {
+			names := PendingRebootParameters([]*rds.Parameter{
+				{ParameterName: aws.String("shared_preload_libraries"), Source: aws.String("user"), ApplyType: aws.String("static")},
+				{ParameterName: aws.String("rds.force_ssl"), Source: aws.String("user"), ApplyType: aws.String("dynamic"), ApplyMethod: aws.String("pending-reboot")},
+				{ParameterName: aws.String("log_min_duration_statement"), Source: aws.String("user"), ApplyType: aws.String("dynamic"), ApplyMethod: aws.String("immediate")},
+				{ParameterName: aws.String("max_connections"), Source: aws.String("system"), ApplyType: aws.String("static")},
+			})
+			Expect(names).To(Equal([]string{"shared_preload_libraries", "rds.force_ssl"}))
+		})
+	})
+})
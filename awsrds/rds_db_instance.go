@@ -2,6 +2,7 @@ package awsrds
 
 import (
 	"fmt"
+	"io"
 	"sort"
 	"strings"
 	"sync"
@@ -14,27 +15,84 @@ import (
 )
 
 const (
-	TagServiceID            = "Service ID"
-	TagPlanID               = "Plan ID"
-	TagOrganizationID       = "Organization ID"
-	TagSpaceID              = "Space ID"
-	TagSkipFinalSnapshot    = "SkipFinalSnapshot"
-	TagRestoredFromSnapshot = "Restored From Snapshot"
-	TagBrokerName           = "Broker Name"
-	TagExtensions           = "Extensions"
-	TagOriginDatabase       = "Restored From Database"
-	TagOriginPointInTime    = "Restored From Time"
+	TagServiceID               = "Service ID"
+	TagPlanID                  = "Plan ID"
+	TagOrganizationID          = "Organization ID"
+	TagSpaceID                 = "Space ID"
+	TagSkipFinalSnapshot       = "SkipFinalSnapshot"
+	TagRestoredFromSnapshot    = "Restored From Snapshot"
+	TagBrokerName              = "Broker Name"
+	TagExtensions              = "Extensions"
+	TagOriginDatabase          = "Restored From Database"
+	TagOriginPointInTime       = "Restored From Time"
+	TagChargeableEntity        = "chargeable_entity"
+	TagAdoptedFrom             = "Adopted From Database"
+	TagOperationLease          = "Operation Lease"
+	TagUpdatedByUser           = "Updated by user"
+	TagSecurityGroupSet        = "Security Group Set"
+	TagInstanceName            = "Instance Name"
+	TagPreviousInstanceNames   = "Previous Instance Names"
+	TagExtensionUpdateFor      = "Pending Extension Update For"
+	TagDatabasesToPurge        = "Databases To Purge"
+	TagPgauditLog              = "Pgaudit Log"
+	TagDBProxy                 = "DB Proxy"
+	TagPurgeAfter              = "Purge After"
+	TagUndeleteRequested       = "Undelete Requested"
+	TagTrialExpires            = "Trial Expires"
+	TagTrialExpiryWarned       = "Trial Expiry Warned"
+	TagRestoreTestOf           = "Restore Test Of"
+	TagRestoreTestedAt         = "Restore Tested At"
+	TagRestoreTestResult       = "Restore Test Result"
+	TagFailoverRequestedAt     = "Failover Requested At"
+	TagPlatform                = "Platform"
+	TagKubernetesNamespace     = "Kubernetes Namespace"
+	TagDNSName                 = "DNS Name"
+	TagDNSTarget               = "DNS Target"
+	TagAdditionalDatabases     = "Additional Databases"
+	TagExpiringUsersUntil      = "Expiring Users Until"
+	TagNetworkTier             = "Network Tier"
+	TagOrphaned                = "Orphaned"
+	TagBinlogRetentionHours    = "Binlog Retention Hours"
+	TagUnprotectedUntil        = "Deprovision Protection Lifted Until"
+	TagPubliclyAccessible      = "Publicly Accessible"
+	TagAutoMinorUpgrade        = "Auto Minor Version Upgrade"
+	TagMinorUpgrade            = "Minor Upgrade"
+	TagMinorUpgradeTarget      = "Minor Upgrade Target"
+	TagScheduleAtMaintenance   = "Schedule At Maintenance Window"
+	TagScheduledMaintenance    = "Scheduled Maintenance"
+	TagRebootReason            = "Reboot Reason"
+	TagMasterPasswordVersion   = "Master Password Version"
+	TagMasterPasswordRotated   = "Master Password Rotated At"
+	TagMasterPasswordPolicy    = "Master Password Policy"
+	TagKmsKeyAlias             = "KMS Key Alias"
+	TagSharedWithAccount       = "Shared With Backup Account"
+	TagCopiedToAccount         = "Copied To Backup Account"
+	TagSLATier                 = "SLA Tier"
+	TagSupportLevel            = "Support Level"
+	TagSchemaCopyFrom          = "Pending Schema Copy From"
+	TagSchemaCopyProgress      = "Schema Copy Progress"
+	TagDataImport              = "Pending Data Import"
+	TagDataImportProgress      = "Data Import Progress"
+	TagDataExportOnDeprovision = "Data Export On Deprovision"
+	TagDataExport              = "Pending Data Export"
+
+	// TagBindingPrefix is followed by the binding ID in the key of the tag
+	// recording each binding of an instance.
+	TagBindingPrefix = "Binding "
 )
 
 type RDSDBInstance struct {
-	region           string
-	partition        string
-	rdssvc           *rds.RDS
-	cachedTags       map[string]tagCacheEntry
-	cachedTagsLock   sync.RWMutex
-	logger           lager.Logger
-	timeNowFunc      func() time.Time
-	tagCacheDuration time.Duration
+	region                     string
+	partition                  string
+	rdssvc                     *rds.RDS
+	cachedTags                 map[string]tagCacheEntry
+	cachedTagsLock             sync.RWMutex
+	cachedEngineVersions       map[string]engineVersionCacheEntry
+	cachedEngineVersionsLock   sync.RWMutex
+	logger                     lager.Logger
+	timeNowFunc                func() time.Time
+	tagCacheDuration           time.Duration
+	engineVersionCacheDuration time.Duration
 }
 
 type tagCacheEntry struct {
@@ -46,12 +104,22 @@ func (e *tagCacheEntry) HasExpired(now time.Time, duration time.Duration) bool {
 	return now.After(e.requestTime.Add(duration))
 }
 
+type engineVersionCacheEntry struct {
+	engineVersions []*rds.DBEngineVersion
+	requestTime    time.Time
+}
+
+func (e *engineVersionCacheEntry) HasExpired(now time.Time, duration time.Duration) bool {
+	return now.After(e.requestTime.Add(duration))
+}
+
 func NewRDSDBInstance(
 	region string,
 	partition string,
 	rdssvc *rds.RDS,
 	logger lager.Logger,
 	tagCacheDuration time.Duration,
+	engineVersionCacheDuration time.Duration,
 	timeNowFunc func() time.Time,
 ) *RDSDBInstance {
 	if timeNowFunc == nil {
@@ -61,13 +129,15 @@ func NewRDSDBInstance(
 	}
 
 	return &RDSDBInstance{
-		region:           region,
-		partition:        partition,
-		rdssvc:           rdssvc,
-		cachedTags:       map[string]tagCacheEntry{},
-		logger:           logger.Session("db-instance"),
-		tagCacheDuration: tagCacheDuration,
-		timeNowFunc:      timeNowFunc,
+		region:                     region,
+		partition:                  partition,
+		rdssvc:                     rdssvc,
+		cachedTags:                 map[string]tagCacheEntry{},
+		cachedEngineVersions:       map[string]engineVersionCacheEntry{},
+		logger:                     logger.Session("db-instance"),
+		tagCacheDuration:           tagCacheDuration,
+		engineVersionCacheDuration: engineVersionCacheDuration,
+		timeNowFunc:                timeNowFunc,
 	}
 }
 
@@ -168,6 +238,160 @@ func (r *RDSDBInstance) DescribeSnapshots(DBInstanceID string) ([]*rds.DBSnapsho
 	return describeDBSnapshotsOutput.DBSnapshots, nil
 }
 
+func (r *RDSDBInstance) DescribeSnapshot(DBSnapshotID string) (*rds.DBSnapshot, error) {
+	describeDBSnapshotsInput := &rds.DescribeDBSnapshotsInput{
+		DBSnapshotIdentifier: aws.String(DBSnapshotID),
+	}
+
+	r.logger.Debug("describe-db-snapshot", lager.Data{"input": describeDBSnapshotsInput})
+
+	describeDBSnapshotsOutput, err := r.rdssvc.DescribeDBSnapshots(describeDBSnapshotsInput)
+	if err != nil {
+		return nil, HandleAWSError(err, r.logger)
+	}
+
+	for _, snapshot := range describeDBSnapshotsOutput.DBSnapshots {
+		if aws.StringValue(snapshot.DBSnapshotIdentifier) == DBSnapshotID {
+			return snapshot, nil
+		}
+	}
+	return nil, ErrDBSnapshotDoesNotExist
+}
+
+func (r *RDSDBInstance) CopySnapshot(copyDBSnapshotInput *rds.CopyDBSnapshotInput) error {
+	r.logger.Debug("copy-db-snapshot", lager.Data{"input": copyDBSnapshotInput})
+
+	copyDBSnapshotOutput, err := r.rdssvc.CopyDBSnapshot(copyDBSnapshotInput)
+	if err != nil {
+		return HandleAWSError(err, r.logger)
+	}
+	r.logger.Debug("copy-db-snapshot", lager.Data{"output": copyDBSnapshotOutput})
+
+	return nil
+}
+
+func (r *RDSDBInstance) DeleteSnapshot(DBSnapshotID string) error {
+	deleteDBSnapshotInput := &rds.DeleteDBSnapshotInput{
+		DBSnapshotIdentifier: aws.String(DBSnapshotID),
+	}
+
+	r.logger.Debug("delete-db-snapshot", lager.Data{"input": deleteDBSnapshotInput})
+
+	deleteDBSnapshotOutput, err := r.rdssvc.DeleteDBSnapshot(deleteDBSnapshotInput)
+	if err != nil {
+		return HandleAWSError(err, r.logger)
+	}
+	r.logger.Debug("delete-db-snapshot", lager.Data{"output": deleteDBSnapshotOutput})
+
+	return nil
+}
+
+// DescribeEvents returns the events RDS has recorded for the DB instance in
+// the last day, most recent first.
+func (r *RDSDBInstance) DescribeEvents(DBInstanceID string) ([]*rds.Event, error) {
+	describeEventsInput := &rds.DescribeEventsInput{
+		SourceIdentifier: aws.String(DBInstanceID),
+		SourceType:       aws.String(rds.SourceTypeDbInstance),
+		Duration:         aws.Int64(24 * 60),
+	}
+
+	r.logger.Debug("describe-events", lager.Data{"input": describeEventsInput})
+
+	describeEventsOutput, err := r.rdssvc.DescribeEvents(describeEventsInput)
+	if err != nil {
+		return nil, HandleAWSError(err, r.logger)
+	}
+
+	sort.Sort(ByEventDate(describeEventsOutput.Events))
+
+	return describeEventsOutput.Events, nil
+}
+
+// DescribeEventsBetween returns the events RDS has recorded for the DB
+// instance between startTime and endTime, most recent first. RDS only keeps
+// events for 14 days.
+func (r *RDSDBInstance) DescribeEventsBetween(DBInstanceID string, startTime time.Time, endTime time.Time) ([]*rds.Event, error) {
+	describeEventsInput := &rds.DescribeEventsInput{
+		SourceIdentifier: aws.String(DBInstanceID),
+		SourceType:       aws.String(rds.SourceTypeDbInstance),
+		StartTime:        aws.Time(startTime),
+		EndTime:          aws.Time(endTime),
+	}
+
+	r.logger.Debug("describe-events-between", lager.Data{"input": describeEventsInput})
+
+	events := []*rds.Event{}
+	err := r.rdssvc.DescribeEventsPages(
+		describeEventsInput,
+		func(page *rds.DescribeEventsOutput, lastPage bool) bool {
+			events = append(events, page.Events...)
+			return true
+		},
+	)
+	if err != nil {
+		return nil, HandleAWSError(err, r.logger)
+	}
+
+	sort.Sort(ByEventDate(events))
+
+	return events, nil
+}
+
+// DescribeLogFiles returns the log files of the DB instance whose names
+// contain filenameContains, most recently written first.
+func (r *RDSDBInstance) DescribeLogFiles(DBInstanceID string, filenameContains string) ([]*rds.DescribeDBLogFilesDetails, error) {
+	describeDBLogFilesInput := &rds.DescribeDBLogFilesInput{
+		DBInstanceIdentifier: aws.String(DBInstanceID),
+	}
+	if filenameContains != "" {
+		describeDBLogFilesInput.FilenameContains = aws.String(filenameContains)
+	}
+
+	r.logger.Debug("describe-db-log-files", lager.Data{"input": describeDBLogFilesInput})
+
+	logFiles := []*rds.DescribeDBLogFilesDetails{}
+	err := r.rdssvc.DescribeDBLogFilesPages(
+		describeDBLogFilesInput,
+		func(page *rds.DescribeDBLogFilesOutput, lastPage bool) bool {
+			logFiles = append(logFiles, page.DescribeDBLogFiles...)
+			return true
+		},
+	)
+	if err != nil {
+		return nil, HandleAWSError(err, r.logger)
+	}
+
+	sort.Sort(ByLastWritten(logFiles))
+
+	return logFiles, nil
+}
+
+// DownloadLogFile writes a log file of the DB instance to w from its start,
+// a portion at a time as RDS returns them, so that large files don't have to
+// be held in memory.
+func (r *RDSDBInstance) DownloadLogFile(DBInstanceID string, logFileName string, w io.Writer) error {
+	downloadDBLogFilePortionInput := &rds.DownloadDBLogFilePortionInput{
+		DBInstanceIdentifier: aws.String(DBInstanceID),
+		LogFileName:          aws.String(logFileName),
+		Marker:               aws.String("0"),
+	}
+
+	r.logger.Debug("download-db-log-file-portion", lager.Data{"input": downloadDBLogFilePortionInput})
+
+	var writeErr error
+	err := r.rdssvc.DownloadDBLogFilePortionPages(
+		downloadDBLogFilePortionInput,
+		func(page *rds.DownloadDBLogFilePortionOutput, lastPage bool) bool {
+			_, writeErr = io.WriteString(w, aws.StringValue(page.LogFileData))
+			return writeErr == nil && aws.BoolValue(page.AdditionalDataPending)
+		},
+	)
+	if err != nil {
+		return HandleAWSError(err, r.logger)
+	}
+	return writeErr
+}
+
 func (r *RDSDBInstance) DeleteSnapshots(brokerName string, keepForDays int) error {
 	r.logger.Info("delete-snapshots", lager.Data{"broker_name": brokerName, "keep_for_days": keepForDays})
 
@@ -235,6 +459,64 @@ func (r *RDSDBInstance) DeleteSnapshots(brokerName string, keepForDays int) erro
 	return nil
 }
 
+// DescribeManualSnapshots returns the manual snapshots tagged with the
+// broker's name which were created after a time, most recent first.
+func (r *RDSDBInstance) DescribeManualSnapshots(brokerName string, createdAfter time.Time) ([]*rds.DBSnapshot, error) {
+	describeDBSnapshotsInput := &rds.DescribeDBSnapshotsInput{
+		SnapshotType: aws.String("manual"),
+	}
+
+	r.logger.Debug("describe-db-snapshots", lager.Data{"input": describeDBSnapshotsInput})
+
+	recentSnapshots := []*rds.DBSnapshot{}
+	err := r.rdssvc.DescribeDBSnapshotsPages(
+		describeDBSnapshotsInput,
+		func(page *rds.DescribeDBSnapshotsOutput, lastPage bool) bool {
+			for _, snapshot := range page.DBSnapshots {
+				if snapshot.SnapshotCreateTime != nil && snapshot.SnapshotCreateTime.After(createdAfter) {
+					recentSnapshots = append(recentSnapshots, snapshot)
+				}
+			}
+			return true
+		},
+	)
+	if err != nil {
+		return nil, HandleAWSError(err, r.logger)
+	}
+
+	snapshots := []*rds.DBSnapshot{}
+	for _, snapshot := range recentSnapshots {
+		tags, err := r.cachedListTagsForResource(aws.StringValue(snapshot.DBSnapshotArn), true)
+		if err != nil {
+			return nil, err
+		}
+		if RDSTagsValues(tags)[TagBrokerName] == brokerName {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+
+	sort.Sort(ByCreateTime(snapshots))
+
+	return snapshots, nil
+}
+
+// ShareSnapshot lets another AWS account restore or copy a manual snapshot.
+func (r *RDSDBInstance) ShareSnapshot(DBSnapshotID string, accountID string) error {
+	modifyDBSnapshotAttributeInput := &rds.ModifyDBSnapshotAttributeInput{
+		DBSnapshotIdentifier: aws.String(DBSnapshotID),
+		AttributeName:        aws.String("restore"),
+		ValuesToAdd:          aws.StringSlice([]string{accountID}),
+	}
+
+	r.logger.Debug("modify-db-snapshot-attribute", lager.Data{"input": modifyDBSnapshotAttributeInput})
+
+	_, err := r.rdssvc.ModifyDBSnapshotAttribute(modifyDBSnapshotAttributeInput)
+	if err != nil {
+		return HandleAWSError(err, r.logger)
+	}
+	return nil
+}
+
 func (r *RDSDBInstance) GetTag(ID, tagKey string) (string, error) {
 
 	describeDBInstancesInput := &rds.DescribeDBInstancesInput{
@@ -398,6 +680,36 @@ func (r *RDSDBInstance) Reboot(rebootDBInstanceInput *rds.RebootDBInstanceInput)
 	return nil
 }
 
+func (r *RDSDBInstance) Stop(ID string) error {
+	stopDBInstanceInput := &rds.StopDBInstanceInput{
+		DBInstanceIdentifier: aws.String(ID),
+	}
+	r.logger.Debug("stop-db-instance", lager.Data{"input": stopDBInstanceInput})
+
+	stopDBInstanceOutput, err := r.rdssvc.StopDBInstance(stopDBInstanceInput)
+	if err != nil {
+		return HandleAWSError(err, r.logger)
+	}
+
+	r.logger.Debug("stop-db-instance", lager.Data{"output": stopDBInstanceOutput})
+	return nil
+}
+
+func (r *RDSDBInstance) Start(ID string) error {
+	startDBInstanceInput := &rds.StartDBInstanceInput{
+		DBInstanceIdentifier: aws.String(ID),
+	}
+	r.logger.Debug("start-db-instance", lager.Data{"input": startDBInstanceInput})
+
+	startDBInstanceOutput, err := r.rdssvc.StartDBInstance(startDBInstanceInput)
+	if err != nil {
+		return HandleAWSError(err, r.logger)
+	}
+
+	r.logger.Debug("start-db-instance", lager.Data{"output": startDBInstanceOutput})
+	return nil
+}
+
 func (r *RDSDBInstance) RemoveTag(ID, tagKey string) error {
 	dbInstance, err := r.Describe(ID)
 	if err != nil {
@@ -467,6 +779,89 @@ func (r *RDSDBInstance) ModifyParameterGroup(input *rds.ModifyDBParameterGroupIn
 	return nil
 }
 
+func (r *RDSDBInstance) CreateDBProxy(input *rds.CreateDBProxyInput) error {
+	r.logger.Debug("create-db-proxy", lager.Data{"input": input})
+
+	createDBProxyOutput, err := r.rdssvc.CreateDBProxy(input)
+	if err != nil {
+		return HandleAWSError(err, r.logger)
+	}
+
+	r.logger.Debug("create-db-proxy", lager.Data{"output": createDBProxyOutput})
+	return nil
+}
+
+func (r *RDSDBInstance) DescribeDBProxy(proxyName string) (*rds.DBProxy, error) {
+	describeDBProxiesInput := &rds.DescribeDBProxiesInput{
+		DBProxyName: aws.String(proxyName),
+	}
+	r.logger.Debug("describe-db-proxy", lager.Data{"input": describeDBProxiesInput})
+
+	describeDBProxiesOutput, err := r.rdssvc.DescribeDBProxies(describeDBProxiesInput)
+	if err != nil {
+		return nil, HandleAWSError(err, r.logger)
+	}
+
+	for _, dbProxy := range describeDBProxiesOutput.DBProxies {
+		if aws.StringValue(dbProxy.DBProxyName) == proxyName {
+			r.logger.Debug("describe-db-proxy", lager.Data{"db-proxy": dbProxy})
+			return dbProxy, nil
+		}
+	}
+	return nil, ErrDBProxyDoesNotExist
+}
+
+// RegisterDBProxyTargets points the default target group of the proxy at the
+// DB instance.
+func (r *RDSDBInstance) RegisterDBProxyTargets(proxyName string, dbInstanceIdentifier string) error {
+	registerDBProxyTargetsInput := &rds.RegisterDBProxyTargetsInput{
+		DBProxyName:           aws.String(proxyName),
+		DBInstanceIdentifiers: []*string{aws.String(dbInstanceIdentifier)},
+	}
+	r.logger.Debug("register-db-proxy-targets", lager.Data{"input": registerDBProxyTargetsInput})
+
+	registerDBProxyTargetsOutput, err := r.rdssvc.RegisterDBProxyTargets(registerDBProxyTargetsInput)
+	if err != nil {
+		return HandleAWSError(err, r.logger)
+	}
+
+	r.logger.Debug("register-db-proxy-targets", lager.Data{"output": registerDBProxyTargetsOutput})
+	return nil
+}
+
+// SetDBProxyAuth replaces the list of secrets holding the credentials the
+// proxy lets through.
+func (r *RDSDBInstance) SetDBProxyAuth(proxyName string, auth []*rds.UserAuthConfig) error {
+	modifyDBProxyInput := &rds.ModifyDBProxyInput{
+		DBProxyName: aws.String(proxyName),
+		Auth:        auth,
+	}
+	r.logger.Debug("modify-db-proxy", lager.Data{"input": modifyDBProxyInput})
+
+	modifyDBProxyOutput, err := r.rdssvc.ModifyDBProxy(modifyDBProxyInput)
+	if err != nil {
+		return HandleAWSError(err, r.logger)
+	}
+
+	r.logger.Debug("modify-db-proxy", lager.Data{"output": modifyDBProxyOutput})
+	return nil
+}
+
+func (r *RDSDBInstance) DeleteDBProxy(proxyName string) error {
+	deleteDBProxyInput := &rds.DeleteDBProxyInput{
+		DBProxyName: aws.String(proxyName),
+	}
+	r.logger.Debug("delete-db-proxy", lager.Data{"input": deleteDBProxyInput})
+
+	deleteDBProxyOutput, err := r.rdssvc.DeleteDBProxy(deleteDBProxyInput)
+	if err != nil {
+		return HandleAWSError(err, r.logger)
+	}
+
+	r.logger.Debug("delete-db-proxy", lager.Data{"output": deleteDBProxyOutput})
+	return nil
+}
+
 func (r *RDSDBInstance) buildDeleteDBInstanceInput(ID string, skipFinalSnapshot bool) *rds.DeleteDBInstanceInput {
 	deleteDBInstanceInput := &rds.DeleteDBInstanceInput{
 		DBInstanceIdentifier: aws.String(ID),
@@ -474,13 +869,15 @@ func (r *RDSDBInstance) buildDeleteDBInstanceInput(ID string, skipFinalSnapshot
 	}
 
 	if !skipFinalSnapshot {
-		deleteDBInstanceInput.FinalDBSnapshotIdentifier = aws.String(r.dbSnapshotName(ID))
+		deleteDBInstanceInput.FinalDBSnapshotIdentifier = aws.String(FinalSnapshotIdentifier(ID))
 	}
 
 	return deleteDBInstanceInput
 }
 
-func (r *RDSDBInstance) dbSnapshotName(ID string) string {
+// FinalSnapshotIdentifier is the identifier of the snapshot taken of a DB
+// instance when it is deleted.
+func FinalSnapshotIdentifier(ID string) string {
 	return fmt.Sprintf("%s-final-snapshot", ID)
 }
 
@@ -507,6 +904,41 @@ func (r *RDSDBInstance) cachedListTagsForResource(arn string, useCached bool) ([
 	return tags, err
 }
 
+// cachedDescribeDBEngineVersions describes an engine version, reusing the
+// answer for the same engine and version until engineVersionCacheDuration
+// has passed, as updates in a batch of upgrades ask about the same versions
+// over and over. A duration of 0 disables the cache.
+func (r *RDSDBInstance) cachedDescribeDBEngineVersions(engine string, version string) ([]*rds.DBEngineVersion, error) {
+	key := engine + "/" + version
+	if r.engineVersionCacheDuration > 0 {
+		r.cachedEngineVersionsLock.RLock()
+		entry, ok := r.cachedEngineVersions[key]
+		r.cachedEngineVersionsLock.RUnlock()
+		if ok && !entry.HasExpired(r.timeNowFunc(), r.engineVersionCacheDuration) {
+			return entry.engineVersions, nil
+		}
+	}
+
+	resp, err := r.rdssvc.DescribeDBEngineVersions(&rds.DescribeDBEngineVersionsInput{
+		Engine:        aws.String(engine),
+		EngineVersion: aws.String(version),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if r.engineVersionCacheDuration > 0 {
+		entry := engineVersionCacheEntry{
+			engineVersions: resp.DBEngineVersions,
+			requestTime:    r.timeNowFunc(),
+		}
+		r.cachedEngineVersionsLock.Lock()
+		r.cachedEngineVersions[key] = entry
+		r.cachedEngineVersionsLock.Unlock()
+	}
+	return resp.DBEngineVersions, nil
+}
+
 func (r *RDSDBInstance) selectEngineVersion(engine *string, oldEngineVersion *string, planEngineVersion *string) (newEngineVersion *string, err error) {
 	keepEngineVersion := false
 
@@ -541,26 +973,94 @@ func (r *RDSDBInstance) selectEngineVersion(engine *string, oldEngineVersion *st
 	return newEngineVersion, err
 }
 
+// DescribeParameters lists every parameter of the parameter group, with the
+// values the group gives them.
+func (r *RDSDBInstance) DescribeParameters(groupId string) ([]*rds.Parameter, error) {
+	describeDBParametersInput := &rds.DescribeDBParametersInput{
+		DBParameterGroupName: aws.String(groupId),
+	}
+	r.logger.Debug("describe-parameters", lager.Data{"input": describeDBParametersInput})
+
+	parameters := []*rds.Parameter{}
+	err := r.rdssvc.DescribeDBParametersPages(
+		describeDBParametersInput,
+		func(page *rds.DescribeDBParametersOutput, lastPage bool) bool {
+			parameters = append(parameters, page.Parameters...)
+			return true
+		},
+	)
+	if err != nil {
+		return nil, HandleAWSError(err, r.logger)
+	}
+
+	return parameters, nil
+}
+
+// DescribePendingMaintenanceActions lists the maintenance actions pending
+// for every resource in the region, by resource ARN.
+func (r *RDSDBInstance) DescribePendingMaintenanceActions() (map[string][]*rds.PendingMaintenanceAction, error) {
+	describePendingMaintenanceActionsInput := &rds.DescribePendingMaintenanceActionsInput{}
+	r.logger.Debug("describe-pending-maintenance-actions", lager.Data{"input": describePendingMaintenanceActionsInput})
+
+	pendingMaintenanceActions := map[string][]*rds.PendingMaintenanceAction{}
+	err := r.rdssvc.DescribePendingMaintenanceActionsPages(
+		describePendingMaintenanceActionsInput,
+		func(page *rds.DescribePendingMaintenanceActionsOutput, lastPage bool) bool {
+			for _, resource := range page.PendingMaintenanceActions {
+				resourceArn := aws.StringValue(resource.ResourceIdentifier)
+				pendingMaintenanceActions[resourceArn] = append(pendingMaintenanceActions[resourceArn], resource.PendingMaintenanceActionDetails...)
+			}
+			return true
+		},
+	)
+	if err != nil {
+		return nil, HandleAWSError(err, r.logger)
+	}
+
+	return pendingMaintenanceActions, nil
+}
+
+// DescribeOrderableOptions lists the combinations of engine version, storage
+// and features which can be ordered for the engine and instance class.
+func (r *RDSDBInstance) DescribeOrderableOptions(engine string, dbInstanceClass string) ([]*rds.OrderableDBInstanceOption, error) {
+	describeOrderableDBInstanceOptionsInput := &rds.DescribeOrderableDBInstanceOptionsInput{
+		Engine:          aws.String(engine),
+		DBInstanceClass: aws.String(dbInstanceClass),
+	}
+	r.logger.Debug("describe-orderable-options", lager.Data{"input": describeOrderableDBInstanceOptionsInput})
+
+	options := []*rds.OrderableDBInstanceOption{}
+	err := r.rdssvc.DescribeOrderableDBInstanceOptionsPages(
+		describeOrderableDBInstanceOptionsInput,
+		func(page *rds.DescribeOrderableDBInstanceOptionsOutput, lastPage bool) bool {
+			options = append(options, page.OrderableDBInstanceOptions...)
+			return true
+		},
+	)
+	if err != nil {
+		return nil, HandleAWSError(err, r.logger)
+	}
+
+	return options, nil
+}
+
 func (r *RDSDBInstance) GetLatestMinorVersion(engine string, version string) (*string, error) {
-	resp, err := r.rdssvc.DescribeDBEngineVersions(&rds.DescribeDBEngineVersionsInput{
-		Engine:        aws.String(engine),
-		EngineVersion: aws.String(version),
-	})
+	engineVersions, err := r.cachedDescribeDBEngineVersions(engine, version)
 	if err != nil {
 		return nil, err
 	}
 
 	r.logger.Info(
 		"get-latest-minor-version.describe",
-		lager.Data{"version-count": len(resp.DBEngineVersions)},
+		lager.Data{"version-count": len(engineVersions)},
 	)
 
-	if len(resp.DBEngineVersions) != 1 {
+	if len(engineVersions) != 1 {
 		return nil, fmt.Errorf("Did not find a single version for %s/%s", engine, version)
 	}
 
 	validUpgradeTargets := []rds.UpgradeTarget{}
-	for _, target := range resp.DBEngineVersions[0].ValidUpgradeTarget {
+	for _, target := range engineVersions[0].ValidUpgradeTarget {
 		if target.IsMajorVersionUpgrade != nil && *target.IsMajorVersionUpgrade == false {
 			validUpgradeTargets = append(validUpgradeTargets, *target)
 		}
@@ -575,6 +1075,33 @@ func (r *RDSDBInstance) GetLatestMinorVersion(engine string, version string) (*s
 	return latestUpgradeTarget.EngineVersion, nil
 }
 
+// GetParameterGroupFamily finds the parameter group family RDS uses for a
+// version of a database engine. version can be a major version moniker
+// (e.g. 16 for postgres), in which case the default version for it is used.
+func (r *RDSDBInstance) GetParameterGroupFamily(engine string, version string) (string, error) {
+	resp, err := r.rdssvc.DescribeDBEngineVersions(&rds.DescribeDBEngineVersionsInput{
+		Engine:        aws.String(engine),
+		EngineVersion: aws.String(version),
+		DefaultOnly:   aws.Bool(true),
+	})
+	if err != nil {
+		return "", HandleAWSError(err, r.logger)
+	}
+
+	if len(resp.DBEngineVersions) == 0 || aws.StringValue(resp.DBEngineVersions[0].DBParameterGroupFamily) == "" {
+		return "", fmt.Errorf("Did not find a parameter group family for %s/%s", engine, version)
+	}
+
+	family := aws.StringValue(resp.DBEngineVersions[0].DBParameterGroupFamily)
+	r.logger.Info("get-parameter-group-family", lager.Data{
+		"engine":  engine,
+		"version": version,
+		"family":  family,
+	})
+
+	return family, nil
+}
+
 // GetFullValidTargetVersion finds the full version specifier for the newest release of the target version.
 // engine is the name of the database engine in AWS RDS (e.g. postgres).
 // currentVersion is current, exact version of a database engine
@@ -601,30 +1128,27 @@ func (r *RDSDBInstance) GetFullValidTargetVersion(engine string, currentVersion
 	}
 
 	logSess.Info("describe-db-engine-versions")
-	engineVersionsOut, err := r.rdssvc.DescribeDBEngineVersions(&rds.DescribeDBEngineVersionsInput{
-		Engine:        aws.String(engine),
-		EngineVersion: aws.String(currentVersion),
-	})
+	engineVersions, err := r.cachedDescribeDBEngineVersions(engine, currentVersion)
 
 	if err != nil {
 		logSess.Error("describe-db-engine-versions", err)
 		return "", err
 	}
 
-	if len(engineVersionsOut.DBEngineVersions) == 0 {
+	if len(engineVersions) == 0 {
 		err = fmt.Errorf("describe-db-engines did not describe a version engine matching the engine and current version")
 		logSess.Error("no-matching-engine-version", err)
 		return "", err
 	}
 
-	if len(engineVersionsOut.DBEngineVersions) > 1 {
+	if len(engineVersions) > 1 {
 		err = fmt.Errorf("given version '%s' was too broad. Current version must specify an exact version", currentVersion)
 		logSess.Error("ambiguous-version", err)
 		return "", err
 	}
 
 	var targetVersions []string
-	for _, target := range engineVersionsOut.DBEngineVersions[0].ValidUpgradeTarget {
+	for _, target := range engineVersions[0].ValidUpgradeTarget {
 		targetVersions = append(targetVersions, *target.EngineVersion)
 	}
 
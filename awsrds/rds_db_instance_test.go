@@ -1,6 +1,7 @@
 package awsrds_test
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"time"
@@ -60,7 +61,7 @@ var _ = Describe("RDS DB Instance", func() {
 		testSink = lagertest.NewTestSink()
 		logger.RegisterSink(testSink)
 
-		rdsDBInstance = NewRDSDBInstance(region, partition, rdssvc, logger, time.Hour, func() time.Time {
+		rdsDBInstance = NewRDSDBInstance(region, partition, rdssvc, logger, time.Hour, time.Hour, func() time.Time {
 			return dummyTimeNow
 		})
 	})
@@ -551,6 +552,448 @@ var _ = Describe("RDS DB Instance", func() {
 		})
 	})
 
+	var _ = Describe("DescribeEvents", func() {
+		var (
+			receivedDescribeEventsInput *rds.DescribeEventsInput
+
+			describeEventsError error
+
+			eventOneHourOld *rds.Event
+			eventTwoHourOld *rds.Event
+		)
+
+		BeforeEach(func() {
+			describeEventsError = nil
+			eventOneHourOld = &rds.Event{
+				SourceIdentifier: aws.String(dbInstanceIdentifier),
+				Message:          aws.String("newer"),
+				Date:             aws.Time(dummyTimeNow.Add(-1 * time.Hour)),
+			}
+			eventTwoHourOld = &rds.Event{
+				SourceIdentifier: aws.String(dbInstanceIdentifier),
+				Message:          aws.String("older"),
+				Date:             aws.Time(dummyTimeNow.Add(-2 * time.Hour)),
+			}
+		})
+
+		JustBeforeEach(func() {
+			rdssvc.Handlers.Clear()
+
+			rdsCall = func(r *request.Request) {
+				Expect(r.Operation.Name).To(Equal("DescribeEvents"))
+				Expect(r.Params).To(BeAssignableToTypeOf(&rds.DescribeEventsInput{}))
+				receivedDescribeEventsInput = r.Params.(*rds.DescribeEventsInput)
+				data := r.Data.(*rds.DescribeEventsOutput)
+				data.Events = []*rds.Event{eventTwoHourOld, eventOneHourOld}
+				r.Error = describeEventsError
+			}
+			rdssvc.Handlers.Send.PushBack(rdsCall)
+		})
+
+		It("returns the events for the DB instance, most recent first", func() {
+			events, err := rdsDBInstance.DescribeEvents(dbInstanceIdentifier)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(aws.StringValue(receivedDescribeEventsInput.SourceIdentifier)).To(Equal(dbInstanceIdentifier))
+			Expect(aws.StringValue(receivedDescribeEventsInput.SourceType)).To(Equal("db-instance"))
+			Expect(events).To(Equal([]*rds.Event{eventOneHourOld, eventTwoHourOld}))
+		})
+
+		It("returns the events for the DB instance within the window, most recent first", func() {
+			startTime := dummyTimeNow.Add(-3 * time.Hour)
+			events, err := rdsDBInstance.DescribeEventsBetween(dbInstanceIdentifier, startTime, dummyTimeNow)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(aws.StringValue(receivedDescribeEventsInput.SourceIdentifier)).To(Equal(dbInstanceIdentifier))
+			Expect(aws.StringValue(receivedDescribeEventsInput.SourceType)).To(Equal("db-instance"))
+			Expect(aws.TimeValue(receivedDescribeEventsInput.StartTime)).To(Equal(startTime))
+			Expect(aws.TimeValue(receivedDescribeEventsInput.EndTime)).To(Equal(dummyTimeNow))
+			Expect(receivedDescribeEventsInput.Duration).To(BeNil())
+			Expect(events).To(Equal([]*rds.Event{eventOneHourOld, eventTwoHourOld}))
+		})
+
+		Context("when describing the events fails", func() {
+			BeforeEach(func() {
+				describeEventsError = awserr.New("code", "message", errors.New("operation failed"))
+			})
+
+			It("returns the proper AWS error", func() {
+				_, err := rdsDBInstance.DescribeEvents(dbInstanceIdentifier)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("code: message"))
+			})
+
+			It("returns the proper AWS error for a window", func() {
+				_, err := rdsDBInstance.DescribeEventsBetween(dbInstanceIdentifier, dummyTimeNow.Add(-time.Hour), dummyTimeNow)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("code: message"))
+			})
+		})
+	})
+
+	var _ = Describe("DescribeLogFiles", func() {
+		var (
+			receivedDescribeDBLogFilesInput *rds.DescribeDBLogFilesInput
+
+			describeDBLogFilesError error
+
+			olderLogFile *rds.DescribeDBLogFilesDetails
+			newerLogFile *rds.DescribeDBLogFilesDetails
+		)
+
+		BeforeEach(func() {
+			describeDBLogFilesError = nil
+			olderLogFile = &rds.DescribeDBLogFilesDetails{
+				LogFileName: aws.String("slowquery/mysql-slowquery.log.1"),
+				LastWritten: aws.Int64(1000),
+			}
+			newerLogFile = &rds.DescribeDBLogFilesDetails{
+				LogFileName: aws.String("slowquery/mysql-slowquery.log"),
+				LastWritten: aws.Int64(2000),
+			}
+		})
+
+		JustBeforeEach(func() {
+			rdssvc.Handlers.Clear()
+
+			rdsCall = func(r *request.Request) {
+				Expect(r.Operation.Name).To(Equal("DescribeDBLogFiles"))
+				Expect(r.Params).To(BeAssignableToTypeOf(&rds.DescribeDBLogFilesInput{}))
+				receivedDescribeDBLogFilesInput = r.Params.(*rds.DescribeDBLogFilesInput)
+				data := r.Data.(*rds.DescribeDBLogFilesOutput)
+				data.DescribeDBLogFiles = []*rds.DescribeDBLogFilesDetails{olderLogFile, newerLogFile}
+				r.Error = describeDBLogFilesError
+			}
+			rdssvc.Handlers.Send.PushBack(rdsCall)
+		})
+
+		It("returns the matching log files, most recently written first", func() {
+			logFiles, err := rdsDBInstance.DescribeLogFiles(dbInstanceIdentifier, "slowquery/")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(aws.StringValue(receivedDescribeDBLogFilesInput.DBInstanceIdentifier)).To(Equal(dbInstanceIdentifier))
+			Expect(aws.StringValue(receivedDescribeDBLogFilesInput.FilenameContains)).To(Equal("slowquery/"))
+			Expect(logFiles).To(Equal([]*rds.DescribeDBLogFilesDetails{newerLogFile, olderLogFile}))
+		})
+
+		Context("when describing the log files fails", func() {
+			BeforeEach(func() {
+				describeDBLogFilesError = awserr.New("code", "message", errors.New("operation failed"))
+			})
+
+			It("returns the proper AWS error", func() {
+				_, err := rdsDBInstance.DescribeLogFiles(dbInstanceIdentifier, "")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("code: message"))
+			})
+		})
+	})
+
+	var _ = Describe("DownloadLogFile", func() {
+		var (
+			receivedMarkers []string
+
+			downloadError error
+		)
+
+		BeforeEach(func() {
+			receivedMarkers = []string{}
+			downloadError = nil
+		})
+
+		JustBeforeEach(func() {
+			rdssvc.Handlers.Clear()
+
+			rdsCall = func(r *request.Request) {
+				Expect(r.Operation.Name).To(Equal("DownloadDBLogFilePortion"))
+				Expect(r.Params).To(BeAssignableToTypeOf(&rds.DownloadDBLogFilePortionInput{}))
+				input := r.Params.(*rds.DownloadDBLogFilePortionInput)
+				Expect(aws.StringValue(input.DBInstanceIdentifier)).To(Equal(dbInstanceIdentifier))
+				Expect(aws.StringValue(input.LogFileName)).To(Equal("error/postgresql.log"))
+				receivedMarkers = append(receivedMarkers, aws.StringValue(input.Marker))
+				data := r.Data.(*rds.DownloadDBLogFilePortionOutput)
+				if aws.StringValue(input.Marker) == "0" {
+					data.LogFileData = aws.String("first portion\n")
+					data.Marker = aws.String("1:100")
+					data.AdditionalDataPending = aws.Bool(true)
+				} else {
+					data.LogFileData = aws.String("second portion\n")
+					data.Marker = aws.String("1:200")
+					data.AdditionalDataPending = aws.Bool(false)
+				}
+				r.Error = downloadError
+			}
+			rdssvc.Handlers.Send.PushBack(rdsCall)
+		})
+
+		It("writes every portion of the log file from its start", func() {
+			var buffer bytes.Buffer
+			err := rdsDBInstance.DownloadLogFile(dbInstanceIdentifier, "error/postgresql.log", &buffer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(buffer.String()).To(Equal("first portion\nsecond portion\n"))
+			Expect(receivedMarkers).To(Equal([]string{"0", "1:100"}))
+		})
+
+		Context("when downloading the log file fails", func() {
+			BeforeEach(func() {
+				downloadError = awserr.New("code", "message", errors.New("operation failed"))
+			})
+
+			It("returns the proper AWS error", func() {
+				var buffer bytes.Buffer
+				err := rdsDBInstance.DownloadLogFile(dbInstanceIdentifier, "error/postgresql.log", &buffer)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("code: message"))
+			})
+		})
+	})
+
+	var _ = Describe("DescribeParameters", func() {
+		var (
+			receivedDescribeDBParametersInput *rds.DescribeDBParametersInput
+
+			describeDBParametersError error
+		)
+
+		BeforeEach(func() {
+			describeDBParametersError = nil
+		})
+
+		JustBeforeEach(func() {
+			rdssvc.Handlers.Clear()
+
+			rdsCall = func(r *request.Request) {
+				Expect(r.Operation.Name).To(Equal("DescribeDBParameters"))
+				Expect(r.Params).To(BeAssignableToTypeOf(&rds.DescribeDBParametersInput{}))
+				receivedDescribeDBParametersInput = r.Params.(*rds.DescribeDBParametersInput)
+				data := r.Data.(*rds.DescribeDBParametersOutput)
+				data.Parameters = []*rds.Parameter{
+					{ParameterName: aws.String("max_connections"), ParameterValue: aws.String("100"), Source: aws.String("user")},
+					{ParameterName: aws.String("work_mem"), Source: aws.String("engine-default")},
+				}
+				r.Error = describeDBParametersError
+			}
+			rdssvc.Handlers.Send.PushBack(rdsCall)
+		})
+
+		It("returns the parameters of the parameter group", func() {
+			parameters, err := rdsDBInstance.DescribeParameters("test-parameter-group")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(aws.StringValue(receivedDescribeDBParametersInput.DBParameterGroupName)).To(Equal("test-parameter-group"))
+			Expect(parameters).To(HaveLen(2))
+			Expect(aws.StringValue(parameters[0].ParameterName)).To(Equal("max_connections"))
+			Expect(aws.StringValue(parameters[0].ParameterValue)).To(Equal("100"))
+			Expect(aws.StringValue(parameters[1].ParameterName)).To(Equal("work_mem"))
+		})
+
+		Context("when describing the parameters fails", func() {
+			BeforeEach(func() {
+				describeDBParametersError = awserr.New("code", "message", errors.New("operation failed"))
+			})
+
+			It("returns the proper AWS error", func() {
+				_, err := rdsDBInstance.DescribeParameters("test-parameter-group")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("code: message"))
+			})
+		})
+	})
+
+	var _ = Describe("DescribePendingMaintenanceActions", func() {
+		var (
+			describePendingMaintenanceActionsError error
+		)
+
+		BeforeEach(func() {
+			describePendingMaintenanceActionsError = nil
+		})
+
+		JustBeforeEach(func() {
+			rdssvc.Handlers.Clear()
+
+			rdsCall = func(r *request.Request) {
+				Expect(r.Operation.Name).To(Equal("DescribePendingMaintenanceActions"))
+				Expect(r.Params).To(BeAssignableToTypeOf(&rds.DescribePendingMaintenanceActionsInput{}))
+				data := r.Data.(*rds.DescribePendingMaintenanceActionsOutput)
+				data.PendingMaintenanceActions = []*rds.ResourcePendingMaintenanceActions{
+					{
+						ResourceIdentifier: aws.String("arn:aws:rds:rds-region:1234567890:db:cf-instance-1"),
+						PendingMaintenanceActionDetails: []*rds.PendingMaintenanceAction{
+							{Action: aws.String("system-update"), Description: aws.String("New Operating System update is available")},
+						},
+					},
+				}
+				r.Error = describePendingMaintenanceActionsError
+			}
+			rdssvc.Handlers.Send.PushBack(rdsCall)
+		})
+
+		It("returns the pending maintenance actions by resource", func() {
+			pendingMaintenanceActions, err := rdsDBInstance.DescribePendingMaintenanceActions()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pendingMaintenanceActions).To(HaveLen(1))
+			actions := pendingMaintenanceActions["arn:aws:rds:rds-region:1234567890:db:cf-instance-1"]
+			Expect(actions).To(HaveLen(1))
+			Expect(aws.StringValue(actions[0].Action)).To(Equal("system-update"))
+		})
+
+		Context("when describing the pending maintenance actions fails", func() {
+			BeforeEach(func() {
+				describePendingMaintenanceActionsError = awserr.New("code", "message", errors.New("operation failed"))
+			})
+
+			It("returns the proper AWS error", func() {
+				_, err := rdsDBInstance.DescribePendingMaintenanceActions()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("code: message"))
+			})
+		})
+	})
+
+	var _ = Describe("DescribeOrderableOptions", func() {
+		var (
+			receivedDescribeOrderableDBInstanceOptionsInput *rds.DescribeOrderableDBInstanceOptionsInput
+
+			describeOrderableDBInstanceOptionsError error
+		)
+
+		BeforeEach(func() {
+			describeOrderableDBInstanceOptionsError = nil
+		})
+
+		JustBeforeEach(func() {
+			rdssvc.Handlers.Clear()
+
+			rdsCall = func(r *request.Request) {
+				Expect(r.Operation.Name).To(Equal("DescribeOrderableDBInstanceOptions"))
+				Expect(r.Params).To(BeAssignableToTypeOf(&rds.DescribeOrderableDBInstanceOptionsInput{}))
+				receivedDescribeOrderableDBInstanceOptionsInput = r.Params.(*rds.DescribeOrderableDBInstanceOptionsInput)
+				data := r.Data.(*rds.DescribeOrderableDBInstanceOptionsOutput)
+				data.OrderableDBInstanceOptions = []*rds.OrderableDBInstanceOption{
+					{EngineVersion: aws.String("12.7"), StorageType: aws.String("gp2"), MultiAZCapable: aws.Bool(true)},
+				}
+				r.Error = describeOrderableDBInstanceOptionsError
+			}
+			rdssvc.Handlers.Send.PushBack(rdsCall)
+		})
+
+		It("returns the orderable options for the engine and instance class", func() {
+			options, err := rdsDBInstance.DescribeOrderableOptions("postgres", "db.t3.micro")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(aws.StringValue(receivedDescribeOrderableDBInstanceOptionsInput.Engine)).To(Equal("postgres"))
+			Expect(aws.StringValue(receivedDescribeOrderableDBInstanceOptionsInput.DBInstanceClass)).To(Equal("db.t3.micro"))
+			Expect(options).To(HaveLen(1))
+			Expect(aws.StringValue(options[0].EngineVersion)).To(Equal("12.7"))
+		})
+
+		Context("when describing the orderable options fails", func() {
+			BeforeEach(func() {
+				describeOrderableDBInstanceOptionsError = awserr.New("code", "message", errors.New("operation failed"))
+			})
+
+			It("returns the proper AWS error", func() {
+				_, err := rdsDBInstance.DescribeOrderableOptions("postgres", "db.t3.micro")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("code: message"))
+			})
+		})
+	})
+
+	var _ = Describe("DescribeDBProxy", func() {
+		var (
+			describeDBProxiesError error
+		)
+
+		BeforeEach(func() {
+			describeDBProxiesError = nil
+		})
+
+		JustBeforeEach(func() {
+			rdssvc.Handlers.Clear()
+
+			rdsCall = func(r *request.Request) {
+				Expect(r.Operation.Name).To(Equal("DescribeDBProxies"))
+				Expect(r.Params).To(BeAssignableToTypeOf(&rds.DescribeDBProxiesInput{}))
+				input := r.Params.(*rds.DescribeDBProxiesInput)
+				Expect(aws.StringValue(input.DBProxyName)).To(Equal(dbInstanceIdentifier))
+				data := r.Data.(*rds.DescribeDBProxiesOutput)
+				data.DBProxies = []*rds.DBProxy{
+					{
+						DBProxyName: aws.String(dbInstanceIdentifier),
+						Endpoint:    aws.String("proxy-endpoint"),
+						Status:      aws.String("available"),
+					},
+				}
+				r.Error = describeDBProxiesError
+			}
+			rdssvc.Handlers.Send.PushBack(rdsCall)
+		})
+
+		It("returns the proxy", func() {
+			dbProxy, err := rdsDBInstance.DescribeDBProxy(dbInstanceIdentifier)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(aws.StringValue(dbProxy.Endpoint)).To(Equal("proxy-endpoint"))
+			Expect(aws.StringValue(dbProxy.Status)).To(Equal("available"))
+		})
+
+		Context("when the proxy does not exist", func() {
+			BeforeEach(func() {
+				describeDBProxiesError = awserr.New(rds.ErrCodeDBProxyNotFoundFault, "message", errors.New("operation failed"))
+			})
+
+			It("returns the proper error", func() {
+				_, err := rdsDBInstance.DescribeDBProxy(dbInstanceIdentifier)
+				Expect(err).To(Equal(ErrDBProxyDoesNotExist))
+			})
+		})
+	})
+
+	var _ = Describe("RegisterDBProxyTargets", func() {
+		var (
+			receivedRegisterDBProxyTargetsInput *rds.RegisterDBProxyTargetsInput
+		)
+
+		JustBeforeEach(func() {
+			rdssvc.Handlers.Clear()
+
+			rdsCall = func(r *request.Request) {
+				Expect(r.Operation.Name).To(Equal("RegisterDBProxyTargets"))
+				Expect(r.Params).To(BeAssignableToTypeOf(&rds.RegisterDBProxyTargetsInput{}))
+				receivedRegisterDBProxyTargetsInput = r.Params.(*rds.RegisterDBProxyTargetsInput)
+			}
+			rdssvc.Handlers.Send.PushBack(rdsCall)
+		})
+
+		It("registers the DB instance with the proxy", func() {
+			err := rdsDBInstance.RegisterDBProxyTargets("proxy-name", dbInstanceIdentifier)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(aws.StringValue(receivedRegisterDBProxyTargetsInput.DBProxyName)).To(Equal("proxy-name"))
+			Expect(aws.StringValueSlice(receivedRegisterDBProxyTargetsInput.DBInstanceIdentifiers)).To(Equal([]string{dbInstanceIdentifier}))
+		})
+	})
+
+	var _ = Describe("SetDBProxyAuth", func() {
+		var (
+			receivedModifyDBProxyInput *rds.ModifyDBProxyInput
+		)
+
+		JustBeforeEach(func() {
+			rdssvc.Handlers.Clear()
+
+			rdsCall = func(r *request.Request) {
+				Expect(r.Operation.Name).To(Equal("ModifyDBProxy"))
+				Expect(r.Params).To(BeAssignableToTypeOf(&rds.ModifyDBProxyInput{}))
+				receivedModifyDBProxyInput = r.Params.(*rds.ModifyDBProxyInput)
+			}
+			rdssvc.Handlers.Send.PushBack(rdsCall)
+		})
+
+		It("replaces the proxy's secrets", func() {
+			auth := []*rds.UserAuthConfig{{SecretArn: aws.String("secret-arn")}}
+			err := rdsDBInstance.SetDBProxyAuth("proxy-name", auth)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(aws.StringValue(receivedModifyDBProxyInput.DBProxyName)).To(Equal("proxy-name"))
+			Expect(receivedModifyDBProxyInput.Auth).To(Equal(auth))
+		})
+	})
+
 	var _ = Describe("Create", func() {
 		var (
 			createDBInstanceInput *rds.CreateDBInstanceInput
@@ -1065,6 +1508,112 @@ var _ = Describe("RDS DB Instance", func() {
 		})
 	})
 
+	var _ = Describe("Stop", func() {
+		var (
+			stopDBInstanceError error
+		)
+
+		BeforeEach(func() {
+			stopDBInstanceError = nil
+		})
+
+		JustBeforeEach(func() {
+			rdssvc.Handlers.Clear()
+
+			rdsCall = func(r *request.Request) {
+				Expect(r.Operation.Name).To(Equal("StopDBInstance"))
+				Expect(r.Params).To(BeAssignableToTypeOf(&rds.StopDBInstanceInput{}))
+				params := r.Params.(*rds.StopDBInstanceInput)
+				Expect(params.DBInstanceIdentifier).To(Equal(aws.String(dbInstanceIdentifier)))
+				r.Error = stopDBInstanceError
+			}
+			rdssvc.Handlers.Send.PushBack(rdsCall)
+		})
+
+		It("does not return error", func() {
+			err := rdsDBInstance.Stop(dbInstanceIdentifier)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		Context("when stopping the DB instance fails", func() {
+			BeforeEach(func() {
+				stopDBInstanceError = errors.New("operation failed")
+			})
+
+			It("returns the proper error", func() {
+				err := rdsDBInstance.Stop(dbInstanceIdentifier)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("operation failed"))
+			})
+
+			Context("and it is a 404 error", func() {
+				BeforeEach(func() {
+					awsError := awserr.New(rds.ErrCodeDBInstanceNotFoundFault, "message", errors.New("operation failed"))
+					stopDBInstanceError = awserr.NewRequestFailure(awsError, 404, "request-id")
+				})
+
+				It("returns the proper error", func() {
+					err := rdsDBInstance.Stop(dbInstanceIdentifier)
+					Expect(err).To(HaveOccurred())
+					Expect(err).To(Equal(ErrDBInstanceDoesNotExist))
+				})
+			})
+		})
+	})
+
+	var _ = Describe("Start", func() {
+		var (
+			startDBInstanceError error
+		)
+
+		BeforeEach(func() {
+			startDBInstanceError = nil
+		})
+
+		JustBeforeEach(func() {
+			rdssvc.Handlers.Clear()
+
+			rdsCall = func(r *request.Request) {
+				Expect(r.Operation.Name).To(Equal("StartDBInstance"))
+				Expect(r.Params).To(BeAssignableToTypeOf(&rds.StartDBInstanceInput{}))
+				params := r.Params.(*rds.StartDBInstanceInput)
+				Expect(params.DBInstanceIdentifier).To(Equal(aws.String(dbInstanceIdentifier)))
+				r.Error = startDBInstanceError
+			}
+			rdssvc.Handlers.Send.PushBack(rdsCall)
+		})
+
+		It("does not return error", func() {
+			err := rdsDBInstance.Start(dbInstanceIdentifier)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		Context("when starting the DB instance fails", func() {
+			BeforeEach(func() {
+				startDBInstanceError = errors.New("operation failed")
+			})
+
+			It("returns the proper error", func() {
+				err := rdsDBInstance.Start(dbInstanceIdentifier)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("operation failed"))
+			})
+
+			Context("and it is a 404 error", func() {
+				BeforeEach(func() {
+					awsError := awserr.New(rds.ErrCodeDBInstanceNotFoundFault, "message", errors.New("operation failed"))
+					startDBInstanceError = awserr.NewRequestFailure(awsError, 404, "request-id")
+				})
+
+				It("returns the proper error", func() {
+					err := rdsDBInstance.Start(dbInstanceIdentifier)
+					Expect(err).To(HaveOccurred())
+					Expect(err).To(Equal(ErrDBInstanceDoesNotExist))
+				})
+			})
+		})
+	})
+
 	var _ = Describe("Delete", func() {
 		var (
 			skipFinalSnapshot         bool
@@ -1153,6 +1702,106 @@ var _ = Describe("RDS DB Instance", func() {
 		})
 	})
 
+	var _ = Describe("DescribeManualSnapshots", func() {
+		var (
+			receivedDescribeDBSnapshotsInput *rds.DescribeDBSnapshotsInput
+			snapshotTags                     map[string]map[string]string
+		)
+
+		buildSnapshot := func(name string, age time.Duration) *rds.DBSnapshot {
+			return &rds.DBSnapshot{
+				DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
+				DBSnapshotIdentifier: aws.String(name),
+				DBSnapshotArn:        aws.String(dbSnapshotArn + "-" + name),
+				SnapshotCreateTime:   aws.Time(dummyTimeNow.Add(-age)),
+			}
+		}
+
+		BeforeEach(func() {
+			snapshotTags = map[string]map[string]string{
+				dbSnapshotArn + "-recent":       {"Broker Name": "test-broker"},
+				dbSnapshotArn + "-older":        {"Broker Name": "test-broker"},
+				dbSnapshotArn + "-other-broker": {"Broker Name": "other-broker"},
+			}
+		})
+
+		JustBeforeEach(func() {
+			rdssvc.Handlers.Clear()
+
+			rdsCall = func(r *request.Request) {
+				Expect(r.Operation.Name).To(MatchRegexp("DescribeDBSnapshots|ListTagsForResource"))
+				switch r.Operation.Name {
+				case "DescribeDBSnapshots":
+					receivedDescribeDBSnapshotsInput = r.Params.(*rds.DescribeDBSnapshotsInput)
+					data := r.Data.(*rds.DescribeDBSnapshotsOutput)
+					data.DBSnapshots = []*rds.DBSnapshot{
+						buildSnapshot("too-old", 3*24*time.Hour),
+						buildSnapshot("older", 12*time.Hour),
+						buildSnapshot("recent", time.Hour),
+						buildSnapshot("other-broker", time.Hour),
+					}
+				case "ListTagsForResource":
+					input := r.Params.(*rds.ListTagsForResourceInput)
+					data := r.Data.(*rds.ListTagsForResourceOutput)
+					data.TagList = BuildRDSTags(snapshotTags[aws.StringValue(input.ResourceName)])
+				}
+			}
+			rdssvc.Handlers.Send.PushBack(rdsCall)
+		})
+
+		It("returns the broker's manual snapshots created after the time, most recent first", func() {
+			snapshots, err := rdsDBInstance.DescribeManualSnapshots("test-broker", dummyTimeNow.Add(-24*time.Hour))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(aws.StringValue(receivedDescribeDBSnapshotsInput.SnapshotType)).To(Equal("manual"))
+			Expect(snapshots).To(HaveLen(2))
+			Expect(aws.StringValue(snapshots[0].DBSnapshotIdentifier)).To(Equal("recent"))
+			Expect(aws.StringValue(snapshots[1].DBSnapshotIdentifier)).To(Equal("older"))
+		})
+	})
+
+	var _ = Describe("ShareSnapshot", func() {
+		var (
+			receivedModifyDBSnapshotAttributeInput *rds.ModifyDBSnapshotAttributeInput
+			modifyDBSnapshotAttributeError         error
+		)
+
+		BeforeEach(func() {
+			modifyDBSnapshotAttributeError = nil
+		})
+
+		JustBeforeEach(func() {
+			rdssvc.Handlers.Clear()
+
+			rdsCall = func(r *request.Request) {
+				Expect(r.Operation.Name).To(Equal("ModifyDBSnapshotAttribute"))
+				receivedModifyDBSnapshotAttributeInput = r.Params.(*rds.ModifyDBSnapshotAttributeInput)
+				r.Error = modifyDBSnapshotAttributeError
+			}
+			rdssvc.Handlers.Send.PushBack(rdsCall)
+		})
+
+		It("lets the account restore the snapshot", func() {
+			err := rdsDBInstance.ShareSnapshot("snapshot-id", "210987654321")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(receivedModifyDBSnapshotAttributeInput).To(Equal(&rds.ModifyDBSnapshotAttributeInput{
+				DBSnapshotIdentifier: aws.String("snapshot-id"),
+				AttributeName:        aws.String("restore"),
+				ValuesToAdd:          aws.StringSlice([]string{"210987654321"}),
+			}))
+		})
+
+		Context("when sharing the snapshot fails", func() {
+			BeforeEach(func() {
+				modifyDBSnapshotAttributeError = awserr.New("code", "message", errors.New("operation failed"))
+			})
+
+			It("returns the proper AWS error", func() {
+				err := rdsDBInstance.ShareSnapshot("snapshot-id", "210987654321")
+				Expect(err).To(MatchError("code: message"))
+			})
+		})
+	})
+
 	var _ = Describe("DeleteSnapshots", func() {
 		var (
 			describeDBSnapshotsInput       *rds.DescribeDBSnapshotsInput
@@ -1370,14 +2019,17 @@ var _ = Describe("RDS DB Instance", func() {
 
 	Describe("GetLatestMinorVersion", func() {
 		var (
-			engineVersions []*rds.DBEngineVersion
+			engineVersions                    []*rds.DBEngineVersion
+			describeDBEngineVersionsCallCount int
 		)
 
 		JustBeforeEach(func() {
 			rdssvc.Handlers.Clear()
+			describeDBEngineVersionsCallCount = 0
 
 			rdsCall = func(r *request.Request) {
 				Expect(r.Operation.Name).To(Equal("DescribeDBEngineVersions"))
+				describeDBEngineVersionsCallCount++
 				data := r.Data.(*rds.DescribeDBEngineVersionsOutput)
 				data.DBEngineVersions = engineVersions
 			}
@@ -1468,6 +2120,78 @@ var _ = Describe("RDS DB Instance", func() {
 					Expect(err).NotTo(HaveOccurred())
 					Expect(version).To(Equal(aws.String("7")))
 				})
+
+				It("only describes each version once within the engineVersionCacheDuration", func() {
+					_, err := rdsDBInstance.GetLatestMinorVersion("not-postgres", "5")
+					Expect(err).NotTo(HaveOccurred())
+					version, err := rdsDBInstance.GetLatestMinorVersion("not-postgres", "5")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(version).To(Equal(aws.String("7")))
+					Expect(describeDBEngineVersionsCallCount).To(Equal(1))
+
+					_, err = rdsDBInstance.GetLatestMinorVersion("not-postgres", "5.1")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(describeDBEngineVersionsCallCount).To(Equal(2))
+
+					// advance time beyond engineVersionCacheDuration
+					dummyTimeNow = dummyTimeNow.Add(time.Hour * 2)
+
+					_, err = rdsDBInstance.GetLatestMinorVersion("not-postgres", "5")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(describeDBEngineVersionsCallCount).To(Equal(3))
+				})
+			})
+		})
+	})
+
+	Describe("GetParameterGroupFamily", func() {
+		var (
+			receivedDescribeDBEngineVersionsInput *rds.DescribeDBEngineVersionsInput
+			engineVersions                        []*rds.DBEngineVersion
+		)
+
+		JustBeforeEach(func() {
+			rdssvc.Handlers.Clear()
+
+			rdsCall = func(r *request.Request) {
+				Expect(r.Operation.Name).To(Equal("DescribeDBEngineVersions"))
+				receivedDescribeDBEngineVersionsInput = r.Params.(*rds.DescribeDBEngineVersionsInput)
+				data := r.Data.(*rds.DescribeDBEngineVersionsOutput)
+				data.DBEngineVersions = engineVersions
+			}
+			rdssvc.Handlers.Send.PushBack(rdsCall)
+		})
+
+		Context("When the default version is found", func() {
+			BeforeEach(func() {
+				engineVersions = []*rds.DBEngineVersion{
+					{
+						Engine:                 aws.String("postgres"),
+						EngineVersion:          aws.String("16.3"),
+						DBParameterGroupFamily: aws.String("postgres16"),
+					},
+				}
+			})
+
+			It("returns its parameter group family", func() {
+				family, err := rdsDBInstance.GetParameterGroupFamily("postgres", "16")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(family).To(Equal("postgres16"))
+
+				Expect(aws.StringValue(receivedDescribeDBEngineVersionsInput.Engine)).To(Equal("postgres"))
+				Expect(aws.StringValue(receivedDescribeDBEngineVersionsInput.EngineVersion)).To(Equal("16"))
+				Expect(aws.BoolValue(receivedDescribeDBEngineVersionsInput.DefaultOnly)).To(BeTrue())
+			})
+		})
+
+		Context("When no versions are found", func() {
+			BeforeEach(func() {
+				engineVersions = []*rds.DBEngineVersion{}
+			})
+
+			It("returns an error", func() {
+				_, err := rdsDBInstance.GetParameterGroupFamily("postgres", "99")
+				Expect(err).To(MatchError("Did not find a parameter group family for postgres/99"))
 			})
 		})
 	})
@@ -0,0 +1,950 @@
+package awsrds
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// simulatedAccountID is the AWS account ID in the ARNs of simulated
+// resources.
+const simulatedAccountID = "000000000000"
+
+// simulatedStorageTypes are the storage types every simulated engine
+// version can be ordered with.
+var simulatedStorageTypes = []string{"gp2", "gp3", "io1", "standard"}
+
+// SimulatedRDSInstance is an RDSInstance held in memory, for running the
+// broker in dry run mode without touching AWS. Changes are applied straight
+// away, but DB instances go through the status RDS would give them, such as
+// "creating" or "modifying", until they are next described. Every change is
+// logged and recorded as an event of the DB instance it was made to, so it
+// can be seen through the admin events endpoint.
+type SimulatedRDSInstance struct {
+	region         string
+	partition      string
+	engineVersions map[string][]string
+	logger         lager.Logger
+
+	lock              sync.Mutex
+	dbInstances       map[string]*rds.DBInstance
+	snapshots         map[string]*rds.DBSnapshot
+	snapshotInstances map[string]*rds.DBInstance
+	tags              map[string]map[string]string
+	parameterGroups   map[string]*rds.DBParameterGroup
+	parameters        map[string]map[string]*rds.Parameter
+	proxies           map[string]*rds.DBProxy
+	events            []*rds.Event
+}
+
+// NewSimulatedRDSInstance returns an empty simulation. engineVersions are
+// the versions of each engine which can be ordered, usually those of the
+// plans in the catalog.
+func NewSimulatedRDSInstance(region string, partition string, engineVersions map[string][]string, logger lager.Logger) *SimulatedRDSInstance {
+	return &SimulatedRDSInstance{
+		region:            region,
+		partition:         partition,
+		engineVersions:    engineVersions,
+		logger:            logger.Session("simulated-db-instance"),
+		dbInstances:       map[string]*rds.DBInstance{},
+		snapshots:         map[string]*rds.DBSnapshot{},
+		snapshotInstances: map[string]*rds.DBInstance{},
+		tags:              map[string]map[string]string{},
+		parameterGroups:   map[string]*rds.DBParameterGroup{},
+		parameters:        map[string]map[string]*rds.Parameter{},
+		proxies:           map[string]*rds.DBProxy{},
+	}
+}
+
+func (s *SimulatedRDSInstance) Describe(ID string) (*rds.DBInstance, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	dbInstance, ok := s.describe(ID)
+	if !ok {
+		return nil, ErrDBInstanceDoesNotExist
+	}
+	return copyDBInstance(dbInstance), nil
+}
+
+func (s *SimulatedRDSInstance) GetResourceTags(resourceArn string, opts ...DescribeOption) ([]*rds.Tag, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	tags, ok := s.tags[resourceArn]
+	if !ok {
+		return nil, ErrDBInstanceDoesNotExist
+	}
+	return BuildRDSTags(tags), nil
+}
+
+func (s *SimulatedRDSInstance) DescribeByTag(tagKey, tagValue string, opts ...DescribeOption) ([]*rds.DBInstance, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ids := []string{}
+	for id := range s.dbInstances {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	dbInstances := []*rds.DBInstance{}
+	for _, id := range ids {
+		dbInstance, ok := s.describe(id)
+		if !ok {
+			continue
+		}
+		if value, ok := s.tags[aws.StringValue(dbInstance.DBInstanceArn)][tagKey]; ok && value == tagValue {
+			dbInstances = append(dbInstances, copyDBInstance(dbInstance))
+		}
+	}
+	return dbInstances, nil
+}
+
+func (s *SimulatedRDSInstance) DescribeSnapshots(DBInstanceID string) ([]*rds.DBSnapshot, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	snapshots := []*rds.DBSnapshot{}
+	for id := range s.snapshots {
+		snapshot, _ := s.describeSnapshot(id)
+		if aws.StringValue(snapshot.DBInstanceIdentifier) == DBInstanceID {
+			snapshots = append(snapshots, copyDBSnapshot(snapshot))
+		}
+	}
+	sort.Sort(ByCreateTime(snapshots))
+	return snapshots, nil
+}
+
+func (s *SimulatedRDSInstance) DescribeSnapshot(DBSnapshotID string) (*rds.DBSnapshot, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	snapshot, ok := s.describeSnapshot(DBSnapshotID)
+	if !ok {
+		return nil, ErrDBSnapshotDoesNotExist
+	}
+	return copyDBSnapshot(snapshot), nil
+}
+
+func (s *SimulatedRDSInstance) CopySnapshot(copyDBSnapshotInput *rds.CopyDBSnapshotInput) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	sourceID := aws.StringValue(copyDBSnapshotInput.SourceDBSnapshotIdentifier)
+	if i := strings.LastIndex(sourceID, ":snapshot:"); i >= 0 {
+		sourceID = sourceID[i+len(":snapshot:"):]
+	}
+	source, ok := s.snapshots[sourceID]
+	if !ok {
+		return ErrDBSnapshotDoesNotExist
+	}
+
+	targetID := aws.StringValue(copyDBSnapshotInput.TargetDBSnapshotIdentifier)
+	snapshot := copyDBSnapshot(source)
+	snapshot.DBSnapshotIdentifier = aws.String(targetID)
+	snapshot.DBSnapshotArn = aws.String(s.arn("snapshot", targetID))
+	snapshot.SnapshotCreateTime = aws.Time(time.Now())
+	snapshot.SnapshotType = aws.String("manual")
+	snapshot.Status = aws.String("creating")
+	if copyDBSnapshotInput.KmsKeyId != nil {
+		snapshot.KmsKeyId = copyDBSnapshotInput.KmsKeyId
+		snapshot.Encrypted = aws.Bool(true)
+	}
+	tags := map[string]string{}
+	if aws.BoolValue(copyDBSnapshotInput.CopyTags) {
+		for key, value := range s.tags[aws.StringValue(source.DBSnapshotArn)] {
+			tags[key] = value
+		}
+	}
+	for key, value := range RDSTagsValues(copyDBSnapshotInput.Tags) {
+		tags[key] = value
+	}
+
+	s.snapshots[targetID] = snapshot
+	s.snapshotInstances[targetID] = s.snapshotInstances[sourceID]
+	s.tags[aws.StringValue(snapshot.DBSnapshotArn)] = tags
+	s.record("copy-db-snapshot", aws.StringValue(source.DBInstanceIdentifier), fmt.Sprintf("Copied snapshot %s to %s", sourceID, targetID))
+	return nil
+}
+
+func (s *SimulatedRDSInstance) DeleteSnapshot(DBSnapshotID string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	snapshot, ok := s.snapshots[DBSnapshotID]
+	if !ok {
+		return ErrDBSnapshotDoesNotExist
+	}
+	s.deleteSnapshot(snapshot)
+	return nil
+}
+
+func (s *SimulatedRDSInstance) DescribeEvents(DBInstanceID string) ([]*rds.Event, error) {
+	return s.DescribeEventsBetween(DBInstanceID, time.Now().Add(-24*time.Hour), time.Now())
+}
+
+func (s *SimulatedRDSInstance) DescribeEventsBetween(DBInstanceID string, startTime time.Time, endTime time.Time) ([]*rds.Event, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	events := []*rds.Event{}
+	for _, event := range s.events {
+		if aws.StringValue(event.SourceIdentifier) != DBInstanceID {
+			continue
+		}
+		if event.Date.Before(startTime) || event.Date.After(endTime) {
+			continue
+		}
+		events = append(events, event)
+	}
+	sort.Sort(ByEventDate(events))
+	return events, nil
+}
+
+func (s *SimulatedRDSInstance) DescribeLogFiles(DBInstanceID string, filenameContains string) ([]*rds.DescribeDBLogFilesDetails, error) {
+	if _, err := s.Describe(DBInstanceID); err != nil {
+		return nil, err
+	}
+	return []*rds.DescribeDBLogFilesDetails{}, nil
+}
+
+func (s *SimulatedRDSInstance) DownloadLogFile(DBInstanceID string, logFileName string, w io.Writer) error {
+	return fmt.Errorf("log files of simulated DB instances can't be downloaded")
+}
+
+func (s *SimulatedRDSInstance) DeleteSnapshots(brokerName string, keepForDays int) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	deleteBefore := time.Now().Add(-1 * time.Duration(keepForDays) * 24 * time.Hour)
+	for _, snapshot := range s.snapshots {
+		if aws.StringValue(snapshot.SnapshotType) != "manual" || !snapshot.SnapshotCreateTime.Before(deleteBefore) {
+			continue
+		}
+		if s.tags[aws.StringValue(snapshot.DBSnapshotArn)][TagBrokerName] == brokerName {
+			s.deleteSnapshot(snapshot)
+		}
+	}
+	return nil
+}
+
+func (s *SimulatedRDSInstance) DescribeManualSnapshots(brokerName string, createdAfter time.Time) ([]*rds.DBSnapshot, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	snapshots := []*rds.DBSnapshot{}
+	for _, snapshot := range s.snapshots {
+		if aws.StringValue(snapshot.SnapshotType) != "manual" || !snapshot.SnapshotCreateTime.After(createdAfter) {
+			continue
+		}
+		if s.tags[aws.StringValue(snapshot.DBSnapshotArn)][TagBrokerName] == brokerName {
+			snapshots = append(snapshots, copyDBSnapshot(snapshot))
+		}
+	}
+	sort.Sort(ByCreateTime(snapshots))
+	return snapshots, nil
+}
+
+func (s *SimulatedRDSInstance) ShareSnapshot(DBSnapshotID string, accountID string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	snapshot, ok := s.snapshots[DBSnapshotID]
+	if !ok {
+		return ErrDBSnapshotDoesNotExist
+	}
+	s.record("modify-db-snapshot-attribute", aws.StringValue(snapshot.DBInstanceIdentifier), fmt.Sprintf("Shared snapshot %s with account %s", DBSnapshotID, accountID))
+	return nil
+}
+
+func (s *SimulatedRDSInstance) GetTag(ID, tagKey string) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	dbInstance, ok := s.describe(ID)
+	if !ok {
+		return "", ErrDBInstanceDoesNotExist
+	}
+	return s.tags[aws.StringValue(dbInstance.DBInstanceArn)][tagKey], nil
+}
+
+func (s *SimulatedRDSInstance) Create(createDBInstanceInput *rds.CreateDBInstanceInput) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	id := aws.StringValue(createDBInstanceInput.DBInstanceIdentifier)
+	if _, ok := s.dbInstances[id]; ok {
+		return s.alreadyExistsError(id)
+	}
+
+	dbInstance := s.newDBInstance(id, aws.StringValue(createDBInstanceInput.Engine), createDBInstanceInput.Port)
+	dbInstance.DBInstanceClass = createDBInstanceInput.DBInstanceClass
+	dbInstance.EngineVersion = createDBInstanceInput.EngineVersion
+	dbInstance.AllocatedStorage = createDBInstanceInput.AllocatedStorage
+	dbInstance.MultiAZ = createDBInstanceInput.MultiAZ
+	dbInstance.StorageType = createDBInstanceInput.StorageType
+	dbInstance.Iops = createDBInstanceInput.Iops
+	dbInstance.StorageEncrypted = createDBInstanceInput.StorageEncrypted
+	dbInstance.KmsKeyId = createDBInstanceInput.KmsKeyId
+	dbInstance.DBName = createDBInstanceInput.DBName
+	dbInstance.MasterUsername = createDBInstanceInput.MasterUsername
+	dbInstance.BackupRetentionPeriod = createDBInstanceInput.BackupRetentionPeriod
+	dbInstance.PreferredBackupWindow = createDBInstanceInput.PreferredBackupWindow
+	dbInstance.PreferredMaintenanceWindow = createDBInstanceInput.PreferredMaintenanceWindow
+	dbInstance.AutoMinorVersionUpgrade = createDBInstanceInput.AutoMinorVersionUpgrade
+	dbInstance.CopyTagsToSnapshot = createDBInstanceInput.CopyTagsToSnapshot
+	dbInstance.PubliclyAccessible = createDBInstanceInput.PubliclyAccessible
+	s.setParameterGroup(dbInstance, createDBInstanceInput.DBParameterGroupName)
+	s.setNetwork(dbInstance, createDBInstanceInput.DBSubnetGroupName, createDBInstanceInput.VpcSecurityGroupIds)
+
+	s.addDBInstance(dbInstance, createDBInstanceInput.Tags)
+	s.record("create-db-instance", id, fmt.Sprintf("Created %s %s DB instance %s", aws.StringValue(dbInstance.Engine), aws.StringValue(dbInstance.EngineVersion), aws.StringValue(dbInstance.DBInstanceClass)))
+	return nil
+}
+
+func (s *SimulatedRDSInstance) Restore(restoreDBInstanceInput *rds.RestoreDBInstanceFromDBSnapshotInput) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	id := aws.StringValue(restoreDBInstanceInput.DBInstanceIdentifier)
+	if _, ok := s.dbInstances[id]; ok {
+		return s.alreadyExistsError(id)
+	}
+	snapshotID := aws.StringValue(restoreDBInstanceInput.DBSnapshotIdentifier)
+	snapshot, ok := s.snapshots[snapshotID]
+	if !ok {
+		return ErrDBSnapshotDoesNotExist
+	}
+
+	dbInstance := s.restoredDBInstance(id, s.snapshotInstances[snapshotID], restoreDBInstanceInput.Port)
+	dbInstance.EngineVersion = snapshot.EngineVersion
+	dbInstance.AllocatedStorage = snapshot.AllocatedStorage
+	dbInstance.StorageEncrypted = snapshot.Encrypted
+	dbInstance.KmsKeyId = snapshot.KmsKeyId
+	if restoreDBInstanceInput.DBInstanceClass != nil {
+		dbInstance.DBInstanceClass = restoreDBInstanceInput.DBInstanceClass
+	}
+	if restoreDBInstanceInput.MultiAZ != nil {
+		dbInstance.MultiAZ = restoreDBInstanceInput.MultiAZ
+	}
+	if restoreDBInstanceInput.StorageType != nil {
+		dbInstance.StorageType = restoreDBInstanceInput.StorageType
+	}
+	if restoreDBInstanceInput.Iops != nil {
+		dbInstance.Iops = restoreDBInstanceInput.Iops
+	}
+	if restoreDBInstanceInput.AutoMinorVersionUpgrade != nil {
+		dbInstance.AutoMinorVersionUpgrade = restoreDBInstanceInput.AutoMinorVersionUpgrade
+	}
+	if restoreDBInstanceInput.CopyTagsToSnapshot != nil {
+		dbInstance.CopyTagsToSnapshot = restoreDBInstanceInput.CopyTagsToSnapshot
+	}
+	if restoreDBInstanceInput.PubliclyAccessible != nil {
+		dbInstance.PubliclyAccessible = restoreDBInstanceInput.PubliclyAccessible
+	}
+	s.setParameterGroup(dbInstance, restoreDBInstanceInput.DBParameterGroupName)
+	s.setNetwork(dbInstance, restoreDBInstanceInput.DBSubnetGroupName, restoreDBInstanceInput.VpcSecurityGroupIds)
+
+	s.addDBInstance(dbInstance, restoreDBInstanceInput.Tags)
+	s.record("restore-db-instance", id, fmt.Sprintf("Restored DB instance from snapshot %s", snapshotID))
+	return nil
+}
+
+func (s *SimulatedRDSInstance) RestoreToPointInTime(restoreDBInstanceInput *rds.RestoreDBInstanceToPointInTimeInput) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	id := aws.StringValue(restoreDBInstanceInput.TargetDBInstanceIdentifier)
+	if _, ok := s.dbInstances[id]; ok {
+		return s.alreadyExistsError(id)
+	}
+	sourceID := aws.StringValue(restoreDBInstanceInput.SourceDBInstanceIdentifier)
+	source, ok := s.dbInstances[sourceID]
+	if !ok {
+		return ErrDBInstanceDoesNotExist
+	}
+
+	dbInstance := s.restoredDBInstance(id, source, restoreDBInstanceInput.Port)
+	if restoreDBInstanceInput.DBInstanceClass != nil {
+		dbInstance.DBInstanceClass = restoreDBInstanceInput.DBInstanceClass
+	}
+	if restoreDBInstanceInput.MultiAZ != nil {
+		dbInstance.MultiAZ = restoreDBInstanceInput.MultiAZ
+	}
+	if restoreDBInstanceInput.StorageType != nil {
+		dbInstance.StorageType = restoreDBInstanceInput.StorageType
+	}
+	if restoreDBInstanceInput.Iops != nil {
+		dbInstance.Iops = restoreDBInstanceInput.Iops
+	}
+	if restoreDBInstanceInput.AutoMinorVersionUpgrade != nil {
+		dbInstance.AutoMinorVersionUpgrade = restoreDBInstanceInput.AutoMinorVersionUpgrade
+	}
+	if restoreDBInstanceInput.CopyTagsToSnapshot != nil {
+		dbInstance.CopyTagsToSnapshot = restoreDBInstanceInput.CopyTagsToSnapshot
+	}
+	if restoreDBInstanceInput.PubliclyAccessible != nil {
+		dbInstance.PubliclyAccessible = restoreDBInstanceInput.PubliclyAccessible
+	}
+	s.setParameterGroup(dbInstance, restoreDBInstanceInput.DBParameterGroupName)
+	s.setNetwork(dbInstance, restoreDBInstanceInput.DBSubnetGroupName, restoreDBInstanceInput.VpcSecurityGroupIds)
+
+	s.addDBInstance(dbInstance, restoreDBInstanceInput.Tags)
+	s.record("restore-db-instance-to-point-in-time", id, fmt.Sprintf("Restored DB instance from %s", sourceID))
+	return nil
+}
+
+func (s *SimulatedRDSInstance) Modify(modifyDBInstanceInput *rds.ModifyDBInstanceInput) (*rds.DBInstance, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	id := aws.StringValue(modifyDBInstanceInput.DBInstanceIdentifier)
+	dbInstance, ok := s.describe(id)
+	if !ok {
+		return nil, ErrDBInstanceDoesNotExist
+	}
+
+	changes := []string{}
+	if modifyDBInstanceInput.DBInstanceClass != nil {
+		dbInstance.DBInstanceClass = modifyDBInstanceInput.DBInstanceClass
+		changes = append(changes, "instance class "+aws.StringValue(dbInstance.DBInstanceClass))
+	}
+	if modifyDBInstanceInput.EngineVersion != nil {
+		dbInstance.EngineVersion = modifyDBInstanceInput.EngineVersion
+		changes = append(changes, "engine version "+aws.StringValue(dbInstance.EngineVersion))
+	}
+	if aws.Int64Value(modifyDBInstanceInput.AllocatedStorage) > aws.Int64Value(dbInstance.AllocatedStorage) {
+		dbInstance.AllocatedStorage = modifyDBInstanceInput.AllocatedStorage
+		changes = append(changes, fmt.Sprintf("allocated storage %d", aws.Int64Value(dbInstance.AllocatedStorage)))
+	}
+	if modifyDBInstanceInput.MultiAZ != nil {
+		dbInstance.MultiAZ = modifyDBInstanceInput.MultiAZ
+		changes = append(changes, fmt.Sprintf("multi-az %t", aws.BoolValue(dbInstance.MultiAZ)))
+	}
+	if modifyDBInstanceInput.StorageType != nil {
+		dbInstance.StorageType = modifyDBInstanceInput.StorageType
+		changes = append(changes, "storage type "+aws.StringValue(dbInstance.StorageType))
+	}
+	if modifyDBInstanceInput.Iops != nil {
+		dbInstance.Iops = modifyDBInstanceInput.Iops
+		changes = append(changes, fmt.Sprintf("iops %d", aws.Int64Value(dbInstance.Iops)))
+	}
+	if modifyDBInstanceInput.BackupRetentionPeriod != nil {
+		dbInstance.BackupRetentionPeriod = modifyDBInstanceInput.BackupRetentionPeriod
+		changes = append(changes, fmt.Sprintf("backup retention period %d", aws.Int64Value(dbInstance.BackupRetentionPeriod)))
+	}
+	if modifyDBInstanceInput.PreferredBackupWindow != nil {
+		dbInstance.PreferredBackupWindow = modifyDBInstanceInput.PreferredBackupWindow
+		changes = append(changes, "backup window "+aws.StringValue(dbInstance.PreferredBackupWindow))
+	}
+	if modifyDBInstanceInput.PreferredMaintenanceWindow != nil {
+		dbInstance.PreferredMaintenanceWindow = modifyDBInstanceInput.PreferredMaintenanceWindow
+		changes = append(changes, "maintenance window "+aws.StringValue(dbInstance.PreferredMaintenanceWindow))
+	}
+	if modifyDBInstanceInput.AutoMinorVersionUpgrade != nil {
+		dbInstance.AutoMinorVersionUpgrade = modifyDBInstanceInput.AutoMinorVersionUpgrade
+	}
+	if modifyDBInstanceInput.CopyTagsToSnapshot != nil {
+		dbInstance.CopyTagsToSnapshot = modifyDBInstanceInput.CopyTagsToSnapshot
+	}
+	if modifyDBInstanceInput.PubliclyAccessible != nil {
+		dbInstance.PubliclyAccessible = modifyDBInstanceInput.PubliclyAccessible
+		changes = append(changes, fmt.Sprintf("publicly accessible %t", aws.BoolValue(dbInstance.PubliclyAccessible)))
+	}
+	if modifyDBInstanceInput.DBParameterGroupName != nil {
+		s.setParameterGroup(dbInstance, modifyDBInstanceInput.DBParameterGroupName)
+		changes = append(changes, "parameter group "+aws.StringValue(modifyDBInstanceInput.DBParameterGroupName))
+	}
+	if modifyDBInstanceInput.DBSubnetGroupName != nil || modifyDBInstanceInput.VpcSecurityGroupIds != nil {
+		s.setNetwork(dbInstance, modifyDBInstanceInput.DBSubnetGroupName, modifyDBInstanceInput.VpcSecurityGroupIds)
+		changes = append(changes, "network")
+	}
+	if modifyDBInstanceInput.MasterUserPassword != nil {
+		changes = append(changes, "master password")
+	}
+	dbInstance.DBInstanceStatus = aws.String("modifying")
+
+	if newID := aws.StringValue(modifyDBInstanceInput.NewDBInstanceIdentifier); newID != "" && newID != id {
+		if _, ok := s.dbInstances[newID]; ok {
+			return nil, s.alreadyExistsError(newID)
+		}
+		oldArn := aws.StringValue(dbInstance.DBInstanceArn)
+		delete(s.dbInstances, id)
+		dbInstance.DBInstanceIdentifier = aws.String(newID)
+		dbInstance.DBInstanceArn = aws.String(s.arn("db", newID))
+		dbInstance.Endpoint.Address = aws.String(s.endpointAddress(newID))
+		dbInstance.DBInstanceStatus = aws.String("renaming")
+		s.dbInstances[newID] = dbInstance
+		s.tags[aws.StringValue(dbInstance.DBInstanceArn)] = s.tags[oldArn]
+		delete(s.tags, oldArn)
+		changes = append(changes, "identifier "+newID)
+		id = newID
+	}
+
+	s.record("modify-db-instance", id, "Modified "+strings.Join(changes, ", "))
+	return copyDBInstance(dbInstance), nil
+}
+
+func (s *SimulatedRDSInstance) AddTagsToResource(resourceARN string, tags []*rds.Tag) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	resourceTags, ok := s.tags[resourceARN]
+	if !ok {
+		return ErrDBInstanceDoesNotExist
+	}
+	for key, value := range RDSTagsValues(tags) {
+		resourceTags[key] = value
+	}
+	return nil
+}
+
+func (s *SimulatedRDSInstance) Reboot(rebootDBInstanceInput *rds.RebootDBInstanceInput) error {
+	return s.setStatus(aws.StringValue(rebootDBInstanceInput.DBInstanceIdentifier), "available", "rebooting", "reboot-db-instance")
+}
+
+func (s *SimulatedRDSInstance) Stop(ID string) error {
+	return s.setStatus(ID, "available", "stopping", "stop-db-instance")
+}
+
+func (s *SimulatedRDSInstance) Start(ID string) error {
+	return s.setStatus(ID, "stopped", "starting", "start-db-instance")
+}
+
+func (s *SimulatedRDSInstance) RemoveTag(ID, tagKey string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	dbInstance, ok := s.describe(ID)
+	if !ok {
+		return ErrDBInstanceDoesNotExist
+	}
+	delete(s.tags[aws.StringValue(dbInstance.DBInstanceArn)], tagKey)
+	return nil
+}
+
+func (s *SimulatedRDSInstance) Delete(ID string, skipFinalSnapshot bool) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	dbInstance, ok := s.describe(ID)
+	if !ok {
+		return ErrDBInstanceDoesNotExist
+	}
+
+	if !skipFinalSnapshot {
+		snapshotID := FinalSnapshotIdentifier(ID)
+		snapshot := &rds.DBSnapshot{
+			DBSnapshotIdentifier: aws.String(snapshotID),
+			DBSnapshotArn:        aws.String(s.arn("snapshot", snapshotID)),
+			DBInstanceIdentifier: aws.String(ID),
+			Engine:               dbInstance.Engine,
+			EngineVersion:        dbInstance.EngineVersion,
+			AllocatedStorage:     dbInstance.AllocatedStorage,
+			MasterUsername:       dbInstance.MasterUsername,
+			Encrypted:            dbInstance.StorageEncrypted,
+			KmsKeyId:             dbInstance.KmsKeyId,
+			SnapshotCreateTime:   aws.Time(time.Now()),
+			SnapshotType:         aws.String("manual"),
+			Status:               aws.String("available"),
+		}
+		tags := map[string]string{}
+		if aws.BoolValue(dbInstance.CopyTagsToSnapshot) {
+			for key, value := range s.tags[aws.StringValue(dbInstance.DBInstanceArn)] {
+				tags[key] = value
+			}
+		}
+		s.snapshots[snapshotID] = snapshot
+		s.snapshotInstances[snapshotID] = copyDBInstance(dbInstance)
+		s.tags[aws.StringValue(snapshot.DBSnapshotArn)] = tags
+	}
+
+	dbInstance.DBInstanceStatus = aws.String("deleting")
+	s.record("delete-db-instance", ID, fmt.Sprintf("Deleted DB instance, skipping the final snapshot: %t", skipFinalSnapshot))
+	return nil
+}
+
+func (s *SimulatedRDSInstance) GetParameterGroup(groupId string) (*rds.DBParameterGroup, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	parameterGroup, ok := s.parameterGroups[groupId]
+	if !ok {
+		return nil, parameterGroupNotFoundError(groupId)
+	}
+	return awsutil.CopyOf(parameterGroup).(*rds.DBParameterGroup), nil
+}
+
+func (s *SimulatedRDSInstance) CreateParameterGroup(input *rds.CreateDBParameterGroupInput) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	name := aws.StringValue(input.DBParameterGroupName)
+	if _, ok := s.parameterGroups[name]; ok {
+		return NewError(errors.New(rds.ErrCodeDBParameterGroupAlreadyExistsFault+": Parameter group "+name+" already exists"), "")
+	}
+	s.parameterGroups[name] = &rds.DBParameterGroup{
+		DBParameterGroupName:   input.DBParameterGroupName,
+		DBParameterGroupFamily: input.DBParameterGroupFamily,
+		Description:            input.Description,
+		DBParameterGroupArn:    aws.String(s.arn("pg", name)),
+	}
+	s.parameters[name] = map[string]*rds.Parameter{}
+	s.tags[s.arn("pg", name)] = RDSTagsValues(input.Tags)
+	s.logger.Info("create-parameter-group", lager.Data{"name": name, "family": aws.StringValue(input.DBParameterGroupFamily)})
+	return nil
+}
+
+func (s *SimulatedRDSInstance) ModifyParameterGroup(input *rds.ModifyDBParameterGroupInput) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	name := aws.StringValue(input.DBParameterGroupName)
+	parameters, ok := s.parameters[name]
+	if !ok {
+		return parameterGroupNotFoundError(name)
+	}
+	for _, parameter := range input.Parameters {
+		parameter := awsutil.CopyOf(parameter).(*rds.Parameter)
+		parameter.Source = aws.String("user")
+		parameters[aws.StringValue(parameter.ParameterName)] = parameter
+	}
+	s.logger.Info("modify-parameter-group", lager.Data{"name": name, "parameters": input.Parameters})
+	return nil
+}
+
+func (s *SimulatedRDSInstance) DescribeParameters(groupId string) ([]*rds.Parameter, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	parameters, ok := s.parameters[groupId]
+	if !ok {
+		return nil, parameterGroupNotFoundError(groupId)
+	}
+	names := []string{}
+	for name := range parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	described := []*rds.Parameter{}
+	for _, name := range names {
+		described = append(described, awsutil.CopyOf(parameters[name]).(*rds.Parameter))
+	}
+	return described, nil
+}
+
+func (s *SimulatedRDSInstance) DescribePendingMaintenanceActions() (map[string][]*rds.PendingMaintenanceAction, error) {
+	return map[string][]*rds.PendingMaintenanceAction{}, nil
+}
+
+// DescribeOrderableOptions offers every simulated engine version with every
+// instance class and storage type, with every feature.
+func (s *SimulatedRDSInstance) DescribeOrderableOptions(engine string, dbInstanceClass string) ([]*rds.OrderableDBInstanceOption, error) {
+	options := []*rds.OrderableDBInstanceOption{}
+	for _, version := range s.engineVersions[engine] {
+		for _, storageType := range simulatedStorageTypes {
+			options = append(options, &rds.OrderableDBInstanceOption{
+				Engine:                    aws.String(engine),
+				EngineVersion:             aws.String(version),
+				DBInstanceClass:           aws.String(dbInstanceClass),
+				StorageType:               aws.String(storageType),
+				MinStorageSize:            aws.Int64(20),
+				MaxStorageSize:            aws.Int64(65536),
+				MultiAZCapable:            aws.Bool(true),
+				SupportsStorageEncryption: aws.Bool(true),
+				SupportsIops:              aws.Bool(true),
+			})
+		}
+	}
+	return options, nil
+}
+
+func (s *SimulatedRDSInstance) CreateDBProxy(input *rds.CreateDBProxyInput) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	name := aws.StringValue(input.DBProxyName)
+	s.proxies[name] = &rds.DBProxy{
+		DBProxyName:  input.DBProxyName,
+		DBProxyArn:   aws.String(s.arn("db-proxy", name)),
+		EngineFamily: input.EngineFamily,
+		Endpoint:     aws.String(name + ".proxy." + s.endpointDomain()),
+		Status:       aws.String("available"),
+	}
+	s.logger.Info("create-db-proxy", lager.Data{"name": name})
+	return nil
+}
+
+func (s *SimulatedRDSInstance) DescribeDBProxy(proxyName string) (*rds.DBProxy, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	proxy, ok := s.proxies[proxyName]
+	if !ok {
+		return nil, ErrDBProxyDoesNotExist
+	}
+	return awsutil.CopyOf(proxy).(*rds.DBProxy), nil
+}
+
+func (s *SimulatedRDSInstance) RegisterDBProxyTargets(proxyName string, dbInstanceIdentifier string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.proxies[proxyName]; !ok {
+		return ErrDBProxyDoesNotExist
+	}
+	if _, ok := s.dbInstances[dbInstanceIdentifier]; !ok {
+		return ErrDBInstanceDoesNotExist
+	}
+	s.record("register-db-proxy-targets", dbInstanceIdentifier, "Registered with DB proxy "+proxyName)
+	return nil
+}
+
+func (s *SimulatedRDSInstance) SetDBProxyAuth(proxyName string, auth []*rds.UserAuthConfig) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.proxies[proxyName]; !ok {
+		return ErrDBProxyDoesNotExist
+	}
+	s.logger.Info("set-db-proxy-auth", lager.Data{"name": proxyName, "secrets": len(auth)})
+	return nil
+}
+
+func (s *SimulatedRDSInstance) DeleteDBProxy(proxyName string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.proxies[proxyName]; !ok {
+		return ErrDBProxyDoesNotExist
+	}
+	delete(s.proxies, proxyName)
+	s.logger.Info("delete-db-proxy", lager.Data{"name": proxyName})
+	return nil
+}
+
+// GetLatestMinorVersion returns the version it is given, as the simulation
+// has no newer releases.
+func (s *SimulatedRDSInstance) GetLatestMinorVersion(engine string, version string) (*string, error) {
+	return aws.String(version), nil
+}
+
+// GetParameterGroupFamily names the family after the major version, the way
+// RDS does: postgres13 for Postgres and mysql8.0 for MySQL.
+func (s *SimulatedRDSInstance) GetParameterGroupFamily(engine string, version string) (string, error) {
+	parts := strings.Split(version, ".")
+	if version == "" {
+		return "", fmt.Errorf("Did not find a parameter group family for %s/%s", engine, version)
+	}
+	if engine == "postgres" {
+		if parts[0] == "9" && len(parts) > 1 {
+			return "postgres9." + parts[1], nil
+		}
+		return "postgres" + parts[0], nil
+	}
+	if len(parts) > 1 {
+		return engine + parts[0] + "." + parts[1], nil
+	}
+	return engine + parts[0], nil
+}
+
+// GetFullValidTargetVersion returns the newest simulated version of the
+// engine which starts with the target version, or the target itself.
+func (s *SimulatedRDSInstance) GetFullValidTargetVersion(engine string, currentVersion string, targetVersion string) (string, error) {
+	fullVersion := targetVersion
+	for _, version := range s.engineVersions[engine] {
+		if strings.HasPrefix(version, targetVersion+".") && version > fullVersion {
+			fullVersion = version
+		}
+	}
+	return fullVersion, nil
+}
+
+// describe returns the DB instance after moving it on from the status of
+// its last change, forgetting it if it was being deleted.
+func (s *SimulatedRDSInstance) describe(ID string) (*rds.DBInstance, bool) {
+	dbInstance, ok := s.dbInstances[ID]
+	if !ok {
+		return nil, false
+	}
+
+	switch aws.StringValue(dbInstance.DBInstanceStatus) {
+	case "creating", "modifying", "renaming", "rebooting", "starting":
+		dbInstance.DBInstanceStatus = aws.String("available")
+	case "stopping":
+		dbInstance.DBInstanceStatus = aws.String("stopped")
+	case "deleting":
+		delete(s.dbInstances, ID)
+		delete(s.tags, aws.StringValue(dbInstance.DBInstanceArn))
+		return nil, false
+	}
+	return dbInstance, true
+}
+
+func (s *SimulatedRDSInstance) describeSnapshot(ID string) (*rds.DBSnapshot, bool) {
+	snapshot, ok := s.snapshots[ID]
+	if !ok {
+		return nil, false
+	}
+	if aws.StringValue(snapshot.Status) == "creating" {
+		snapshot.Status = aws.String("available")
+	}
+	return snapshot, true
+}
+
+func (s *SimulatedRDSInstance) setStatus(ID string, fromStatus string, toStatus string, action string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	dbInstance, ok := s.describe(ID)
+	if !ok {
+		return ErrDBInstanceDoesNotExist
+	}
+	if status := aws.StringValue(dbInstance.DBInstanceStatus); status != fromStatus {
+		return NewError(fmt.Errorf("InvalidDBInstanceState: DB instance %s is %s, not %s", ID, status, fromStatus), "")
+	}
+	dbInstance.DBInstanceStatus = aws.String(toStatus)
+	s.record(action, ID, fmt.Sprintf("DB instance is %s", toStatus))
+	return nil
+}
+
+func (s *SimulatedRDSInstance) newDBInstance(ID string, engine string, port *int64) *rds.DBInstance {
+	if port == nil {
+		port = aws.Int64(5432)
+		if engine != "postgres" {
+			port = aws.Int64(3306)
+		}
+	}
+	return &rds.DBInstance{
+		DBInstanceIdentifier: aws.String(ID),
+		DBInstanceArn:        aws.String(s.arn("db", ID)),
+		DBInstanceStatus:     aws.String("creating"),
+		Engine:               aws.String(engine),
+		InstanceCreateTime:   aws.Time(time.Now()),
+		AvailabilityZone:     aws.String(s.region + "a"),
+		Endpoint: &rds.Endpoint{
+			Address: aws.String(s.endpointAddress(ID)),
+			Port:    port,
+		},
+	}
+}
+
+// restoredDBInstance starts a DB instance off with the settings of the one
+// it is restored from.
+func (s *SimulatedRDSInstance) restoredDBInstance(ID string, source *rds.DBInstance, port *int64) *rds.DBInstance {
+	if source == nil {
+		source = &rds.DBInstance{}
+	}
+	dbInstance := s.newDBInstance(ID, aws.StringValue(source.Engine), port)
+	dbInstance.DBInstanceClass = source.DBInstanceClass
+	dbInstance.EngineVersion = source.EngineVersion
+	dbInstance.AllocatedStorage = source.AllocatedStorage
+	dbInstance.MultiAZ = source.MultiAZ
+	dbInstance.StorageType = source.StorageType
+	dbInstance.Iops = source.Iops
+	dbInstance.StorageEncrypted = source.StorageEncrypted
+	dbInstance.KmsKeyId = source.KmsKeyId
+	dbInstance.DBName = source.DBName
+	dbInstance.MasterUsername = source.MasterUsername
+	dbInstance.BackupRetentionPeriod = source.BackupRetentionPeriod
+	dbInstance.PreferredBackupWindow = source.PreferredBackupWindow
+	dbInstance.PreferredMaintenanceWindow = source.PreferredMaintenanceWindow
+	dbInstance.AutoMinorVersionUpgrade = source.AutoMinorVersionUpgrade
+	dbInstance.CopyTagsToSnapshot = source.CopyTagsToSnapshot
+	dbInstance.DBInstanceStatus = aws.String("creating")
+	return dbInstance
+}
+
+func (s *SimulatedRDSInstance) setParameterGroup(dbInstance *rds.DBInstance, name *string) {
+	if name == nil {
+		return
+	}
+	dbInstance.DBParameterGroups = []*rds.DBParameterGroupStatus{
+		{DBParameterGroupName: name, ParameterApplyStatus: aws.String("in-sync")},
+	}
+}
+
+func (s *SimulatedRDSInstance) setNetwork(dbInstance *rds.DBInstance, subnetGroupName *string, securityGroupIDs []*string) {
+	if subnetGroupName != nil {
+		dbInstance.DBSubnetGroup = &rds.DBSubnetGroup{DBSubnetGroupName: subnetGroupName}
+	}
+	if securityGroupIDs != nil {
+		dbInstance.VpcSecurityGroups = []*rds.VpcSecurityGroupMembership{}
+		for _, securityGroupID := range securityGroupIDs {
+			dbInstance.VpcSecurityGroups = append(dbInstance.VpcSecurityGroups, &rds.VpcSecurityGroupMembership{
+				VpcSecurityGroupId: securityGroupID,
+				Status:             aws.String("active"),
+			})
+		}
+	}
+}
+
+func (s *SimulatedRDSInstance) addDBInstance(dbInstance *rds.DBInstance, tags []*rds.Tag) {
+	s.dbInstances[aws.StringValue(dbInstance.DBInstanceIdentifier)] = dbInstance
+	s.tags[aws.StringValue(dbInstance.DBInstanceArn)] = RDSTagsValues(tags)
+}
+
+func (s *SimulatedRDSInstance) deleteSnapshot(snapshot *rds.DBSnapshot) {
+	id := aws.StringValue(snapshot.DBSnapshotIdentifier)
+	delete(s.snapshots, id)
+	delete(s.snapshotInstances, id)
+	delete(s.tags, aws.StringValue(snapshot.DBSnapshotArn))
+	s.record("delete-db-snapshot", aws.StringValue(snapshot.DBInstanceIdentifier), "Deleted snapshot "+id)
+}
+
+// record logs a change to a DB instance and keeps it as one of its events.
+func (s *SimulatedRDSInstance) record(action string, dbInstanceIdentifier string, message string) {
+	s.logger.Info(action, lager.Data{"db-instance": dbInstanceIdentifier, "message": message})
+	s.events = append(s.events, &rds.Event{
+		Date:             aws.Time(time.Now()),
+		SourceIdentifier: aws.String(dbInstanceIdentifier),
+		SourceArn:        aws.String(s.arn("db", dbInstanceIdentifier)),
+		SourceType:       aws.String(rds.SourceTypeDbInstance),
+		EventCategories:  []*string{aws.String("simulation")},
+		Message:          aws.String(message),
+	})
+}
+
+func (s *SimulatedRDSInstance) alreadyExistsError(ID string) error {
+	return NewError(
+		errors.New(rds.ErrCodeDBInstanceAlreadyExistsFault+": DB instance "+ID+" already exists"),
+		ErrCodeDBInstanceAlreadyExists,
+	)
+}
+
+func (s *SimulatedRDSInstance) arn(resourceType string, name string) string {
+	return fmt.Sprintf("arn:%s:rds:%s:%s:%s:%s", s.partition, s.region, simulatedAccountID, resourceType, name)
+}
+
+// endpointDomain is under .invalid, which is reserved so that it never
+// resolves.
+func (s *SimulatedRDSInstance) endpointDomain() string {
+	return s.region + ".rds.simulated.invalid"
+}
+
+func (s *SimulatedRDSInstance) endpointAddress(ID string) string {
+	return ID + "." + s.endpointDomain()
+}
+
+func parameterGroupNotFoundError(name string) error {
+	return NewError(errors.New(rds.ErrCodeDBParameterGroupNotFoundFault+": DBParameterGroup not found: "+name), "")
+}
+
+func copyDBInstance(dbInstance *rds.DBInstance) *rds.DBInstance {
+	return awsutil.CopyOf(dbInstance).(*rds.DBInstance)
+}
+
+func copyDBSnapshot(snapshot *rds.DBSnapshot) *rds.DBSnapshot {
+	return awsutil.CopyOf(snapshot).(*rds.DBSnapshot)
+}
@@ -0,0 +1,172 @@
+package awsrds_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/alphagov/paas-rds-broker/awsrds"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+var _ = Describe("SimulatedRDSInstance", func() {
+	var (
+		simulated *SimulatedRDSInstance
+	)
+
+	BeforeEach(func() {
+		simulated = NewSimulatedRDSInstance(
+			"eu-west-1",
+			"aws",
+			map[string][]string{"postgres": {"13.4", "13.7"}},
+			lagertest.NewTestLogger("simulated-db-instance"),
+		)
+
+		err := simulated.Create(&rds.CreateDBInstanceInput{
+			DBInstanceIdentifier: aws.String("cf-instance-1"),
+			DBInstanceClass:      aws.String("db.t3.micro"),
+			Engine:               aws.String("postgres"),
+			EngineVersion:        aws.String("13.7"),
+			AllocatedStorage:     aws.Int64(20),
+			DBName:               aws.String("mydb"),
+			CopyTagsToSnapshot:   aws.Bool(true),
+			Tags: []*rds.Tag{
+				{Key: aws.String(TagBrokerName), Value: aws.String("mybroker")},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("creates DB instances which become available once described", func() {
+		dbInstance, err := simulated.Describe("cf-instance-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(aws.StringValue(dbInstance.DBInstanceStatus)).To(Equal("available"))
+		Expect(aws.StringValue(dbInstance.DBInstanceArn)).To(Equal("arn:aws:rds:eu-west-1:000000000000:db:cf-instance-1"))
+		Expect(aws.Int64Value(dbInstance.Endpoint.Port)).To(Equal(int64(5432)))
+		Expect(aws.StringValue(dbInstance.DBName)).To(Equal("mydb"))
+
+		err = simulated.Create(&rds.CreateDBInstanceInput{DBInstanceIdentifier: aws.String("cf-instance-1")})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("finds DB instances by their tags", func() {
+		dbInstances, err := simulated.DescribeByTag(TagBrokerName, "mybroker")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dbInstances).To(HaveLen(1))
+
+		dbInstances, err = simulated.DescribeByTag(TagBrokerName, "otherbroker")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dbInstances).To(BeEmpty())
+	})
+
+	It("applies modifications and records them as events", func() {
+		dbInstance, err := simulated.Modify(&rds.ModifyDBInstanceInput{
+			DBInstanceIdentifier: aws.String("cf-instance-1"),
+			DBInstanceClass:      aws.String("db.m5.large"),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(aws.StringValue(dbInstance.DBInstanceStatus)).To(Equal("modifying"))
+		Expect(aws.StringValue(dbInstance.DBInstanceClass)).To(Equal("db.m5.large"))
+
+		dbInstance, err = simulated.Describe("cf-instance-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(aws.StringValue(dbInstance.DBInstanceStatus)).To(Equal("available"))
+
+		events, err := simulated.DescribeEvents("cf-instance-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(events).To(HaveLen(2))
+		Expect(aws.StringValue(events[0].Message)).To(Equal("Modified instance class db.m5.large"))
+	})
+
+	It("moves the tags of renamed DB instances", func() {
+		_, err := simulated.Modify(&rds.ModifyDBInstanceInput{
+			DBInstanceIdentifier:    aws.String("cf-instance-1"),
+			NewDBInstanceIdentifier: aws.String("cf-instance-2"),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = simulated.Describe("cf-instance-1")
+		Expect(err).To(Equal(ErrDBInstanceDoesNotExist))
+
+		tag, err := simulated.GetTag("cf-instance-2", TagBrokerName)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tag).To(Equal("mybroker"))
+	})
+
+	It("deletes DB instances, keeping a final snapshot which can be restored", func() {
+		err := simulated.Delete("cf-instance-1", false)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = simulated.Describe("cf-instance-1")
+		Expect(err).To(Equal(ErrDBInstanceDoesNotExist))
+
+		snapshot, err := simulated.DescribeSnapshot(FinalSnapshotIdentifier("cf-instance-1"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(aws.StringValue(snapshot.DBInstanceIdentifier)).To(Equal("cf-instance-1"))
+
+		tags, err := simulated.GetResourceTags(aws.StringValue(snapshot.DBSnapshotArn))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(RDSTagsValues(tags)).To(HaveKeyWithValue(TagBrokerName, "mybroker"))
+
+		err = simulated.Restore(&rds.RestoreDBInstanceFromDBSnapshotInput{
+			DBInstanceIdentifier: aws.String("cf-instance-3"),
+			DBSnapshotIdentifier: snapshot.DBSnapshotIdentifier,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		dbInstance, err := simulated.Describe("cf-instance-3")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(aws.StringValue(dbInstance.DBName)).To(Equal("mydb"))
+		Expect(aws.StringValue(dbInstance.EngineVersion)).To(Equal("13.7"))
+	})
+
+	It("stops and starts DB instances only from the right status", func() {
+		Expect(simulated.Start("cf-instance-1")).To(HaveOccurred())
+
+		Expect(simulated.Stop("cf-instance-1")).To(Succeed())
+		dbInstance, err := simulated.Describe("cf-instance-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(aws.StringValue(dbInstance.DBInstanceStatus)).To(Equal("stopped"))
+
+		Expect(simulated.Start("cf-instance-1")).To(Succeed())
+	})
+
+	It("keeps parameter groups", func() {
+		_, err := simulated.GetParameterGroup("rdsbroker-postgres13")
+		Expect(err).To(MatchError(HavePrefix(rds.ErrCodeDBParameterGroupNotFoundFault)))
+
+		err = simulated.CreateParameterGroup(&rds.CreateDBParameterGroupInput{
+			DBParameterGroupName:   aws.String("rdsbroker-postgres13"),
+			DBParameterGroupFamily: aws.String("postgres13"),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		err = simulated.ModifyParameterGroup(&rds.ModifyDBParameterGroupInput{
+			DBParameterGroupName: aws.String("rdsbroker-postgres13"),
+			Parameters: []*rds.Parameter{
+				{ParameterName: aws.String("rds.force_ssl"), ParameterValue: aws.String("1")},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		parameters, err := simulated.DescribeParameters("rdsbroker-postgres13")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parameters).To(HaveLen(1))
+		Expect(aws.StringValue(parameters[0].ParameterValue)).To(Equal("1"))
+	})
+
+	It("offers the engine versions it was given", func() {
+		options, err := simulated.DescribeOrderableOptions("postgres", "db.t3.micro")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(options).To(HaveLen(8))
+
+		family, err := simulated.GetParameterGroupFamily("postgres", "13.7")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(family).To(Equal("postgres13"))
+
+		version, err := simulated.GetFullValidTargetVersion("postgres", "12.8", "13")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(version).To(Equal("13.7"))
+	})
+})
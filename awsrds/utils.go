@@ -70,6 +70,18 @@ func HandleAWSError(err error, logger lager.Logger) error {
 		if awsErr.Code() == rds.ErrCodeDBInstanceNotFoundFault {
 			return ErrDBInstanceDoesNotExist
 		}
+		if awsErr.Code() == rds.ErrCodeDBSnapshotNotFoundFault {
+			return ErrDBSnapshotDoesNotExist
+		}
+		if awsErr.Code() == rds.ErrCodeDBProxyNotFoundFault {
+			return ErrDBProxyDoesNotExist
+		}
+		if awsErr.Code() == rds.ErrCodeDBInstanceAlreadyExistsFault {
+			return NewError(
+				errors.New(awsErr.Code()+": "+awsErr.Message()),
+				ErrCodeDBInstanceAlreadyExists,
+			)
+		}
 		if awsErr.Code() == "InvalidParameterCombination" {
 			return NewError(
 				errors.New(awsErr.Code()+": "+awsErr.Message()),
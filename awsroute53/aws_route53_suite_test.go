@@ -0,0 +1,13 @@
+package awsroute53_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAWSRoute53(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "AWS Route53 Suite")
+}
@@ -0,0 +1,7 @@
+package awsroute53
+
+//go:generate counterfeiter -o fakes/fake_dns_zone.go . DNSZone
+type DNSZone interface {
+	UpsertCNAME(name string, target string) error
+	DeleteCNAME(name string, target string) error
+}
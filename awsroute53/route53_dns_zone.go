@@ -0,0 +1,82 @@
+package awsroute53
+
+import (
+	"strings"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+// Route53DNSZone manages CNAME records in a Route53 hosted zone.
+type Route53DNSZone struct {
+	hostedZoneID string
+	ttl          int64
+	route53svc   *route53.Route53
+	logger       lager.Logger
+}
+
+func NewRoute53DNSZone(
+	hostedZoneID string,
+	ttl int64,
+	route53svc *route53.Route53,
+	logger lager.Logger,
+) *Route53DNSZone {
+	return &Route53DNSZone{
+		hostedZoneID: hostedZoneID,
+		ttl:          ttl,
+		route53svc:   route53svc,
+		logger:       logger.Session("dns-zone"),
+	}
+}
+
+// UpsertCNAME creates the CNAME record, or points it at the new target if it
+// already exists.
+func (r *Route53DNSZone) UpsertCNAME(name string, target string) error {
+	return r.changeCNAME(route53.ChangeActionUpsert, name, target)
+}
+
+// DeleteCNAME deletes the CNAME record. Route53 only deletes a record which
+// matches exactly, so the target must be the one it currently points at. A
+// record which doesn't exist is not an error.
+func (r *Route53DNSZone) DeleteCNAME(name string, target string) error {
+	err := r.changeCNAME(route53.ChangeActionDelete, name, target)
+	if awsErr, ok := err.(awserr.Error); ok {
+		if awsErr.Code() == route53.ErrCodeInvalidChangeBatch && strings.Contains(awsErr.Message(), "not found") {
+			return nil
+		}
+	}
+	return err
+}
+
+func (r *Route53DNSZone) changeCNAME(action string, name string, target string) error {
+	changeResourceRecordSetsInput := &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(r.hostedZoneID),
+		ChangeBatch: &route53.ChangeBatch{
+			Changes: []*route53.Change{
+				{
+					Action: aws.String(action),
+					ResourceRecordSet: &route53.ResourceRecordSet{
+						Name: aws.String(name),
+						Type: aws.String(route53.RRTypeCname),
+						TTL:  aws.Int64(r.ttl),
+						ResourceRecords: []*route53.ResourceRecord{
+							{Value: aws.String(target)},
+						},
+					},
+				},
+			},
+		},
+	}
+	r.logger.Debug("change-resource-record-sets", lager.Data{"input": changeResourceRecordSetsInput})
+
+	changeResourceRecordSetsOutput, err := r.route53svc.ChangeResourceRecordSets(changeResourceRecordSetsInput)
+	if err != nil {
+		r.logger.Error("aws-route53-error", err)
+		return err
+	}
+
+	r.logger.Debug("change-resource-record-sets", lager.Data{"output": changeResourceRecordSetsOutput})
+	return nil
+}
@@ -0,0 +1,109 @@
+package awsroute53_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/alphagov/paas-rds-broker/awsroute53"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+var _ = Describe("Route53 DNS Zone", func() {
+	var (
+		route53svc *route53.Route53
+
+		receivedInput *route53.ChangeResourceRecordSetsInput
+		changeError   error
+
+		dnsZone DNSZone
+	)
+
+	BeforeEach(func() {
+		receivedInput = nil
+		changeError = nil
+	})
+
+	JustBeforeEach(func() {
+		awsSession, _ := session.NewSession(nil)
+		route53svc = route53.New(awsSession)
+
+		route53svc.Handlers.Clear()
+		route53svc.Handlers.Send.PushBack(func(r *request.Request) {
+			Expect(r.Operation.Name).To(Equal("ChangeResourceRecordSets"))
+			Expect(r.Params).To(BeAssignableToTypeOf(&route53.ChangeResourceRecordSetsInput{}))
+			receivedInput = r.Params.(*route53.ChangeResourceRecordSetsInput)
+			r.Error = changeError
+		})
+
+		dnsZone = NewRoute53DNSZone("ZONE123", 60, route53svc, lagertest.NewTestLogger("route53dnszone_test"))
+	})
+
+	Describe("UpsertCNAME", func() {
+		It("upserts the CNAME record", func() {
+			err := dnsZone.UpsertCNAME("instance-id.db.example.com", "cf-instance-id.rds.amazonaws.com")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(aws.StringValue(receivedInput.HostedZoneId)).To(Equal("ZONE123"))
+			Expect(receivedInput.ChangeBatch.Changes).To(HaveLen(1))
+			change := receivedInput.ChangeBatch.Changes[0]
+			Expect(aws.StringValue(change.Action)).To(Equal("UPSERT"))
+			Expect(aws.StringValue(change.ResourceRecordSet.Name)).To(Equal("instance-id.db.example.com"))
+			Expect(aws.StringValue(change.ResourceRecordSet.Type)).To(Equal("CNAME"))
+			Expect(aws.Int64Value(change.ResourceRecordSet.TTL)).To(Equal(int64(60)))
+			Expect(change.ResourceRecordSet.ResourceRecords).To(HaveLen(1))
+			Expect(aws.StringValue(change.ResourceRecordSet.ResourceRecords[0].Value)).To(Equal("cf-instance-id.rds.amazonaws.com"))
+		})
+
+		Context("when the change fails", func() {
+			BeforeEach(func() {
+				changeError = errors.New("operation failed")
+			})
+
+			It("returns the error", func() {
+				err := dnsZone.UpsertCNAME("instance-id.db.example.com", "cf-instance-id.rds.amazonaws.com")
+				Expect(err).To(MatchError("operation failed"))
+			})
+		})
+	})
+
+	Describe("DeleteCNAME", func() {
+		It("deletes the CNAME record", func() {
+			err := dnsZone.DeleteCNAME("instance-id.db.example.com", "cf-instance-id.rds.amazonaws.com")
+			Expect(err).ToNot(HaveOccurred())
+
+			change := receivedInput.ChangeBatch.Changes[0]
+			Expect(aws.StringValue(change.Action)).To(Equal("DELETE"))
+			Expect(aws.StringValue(change.ResourceRecordSet.Name)).To(Equal("instance-id.db.example.com"))
+		})
+
+		Context("when the record doesn't exist", func() {
+			BeforeEach(func() {
+				changeError = awserr.New(route53.ErrCodeInvalidChangeBatch, "Tried to delete resource record set but it was not found", nil)
+			})
+
+			It("does not return an error", func() {
+				err := dnsZone.DeleteCNAME("instance-id.db.example.com", "cf-instance-id.rds.amazonaws.com")
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("when the change fails", func() {
+			BeforeEach(func() {
+				changeError = awserr.New(route53.ErrCodeInvalidChangeBatch, "something else", nil)
+			})
+
+			It("returns the error", func() {
+				err := dnsZone.DeleteCNAME("instance-id.db.example.com", "cf-instance-id.rds.amazonaws.com")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package awssecrets_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAWSSecrets(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "AWS Secrets Suite")
+}
@@ -0,0 +1,7 @@
+package awssecrets
+
+//go:generate counterfeiter -o fakes/fake_secret_store.go . SecretStore
+type SecretStore interface {
+	CreateDBCredentials(name string, username string, password string, tags map[string]string) (string, error)
+	DeleteSecret(name string) error
+}
@@ -0,0 +1,86 @@
+package awssecrets
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+)
+
+// SecretsManagerSecretStore keeps database credentials in Secrets Manager, in
+// the format RDS Proxy reads them in.
+type SecretsManagerSecretStore struct {
+	secretsmanagersvc *secretsmanager.SecretsManager
+	logger            lager.Logger
+}
+
+func NewSecretsManagerSecretStore(
+	secretsmanagersvc *secretsmanager.SecretsManager,
+	logger lager.Logger,
+) *SecretsManagerSecretStore {
+	return &SecretsManagerSecretStore{
+		secretsmanagersvc: secretsmanagersvc,
+		logger:            logger.Session("secret-store"),
+	}
+}
+
+type dbCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// CreateDBCredentials stores a username and password in a new secret, and
+// returns the secret's ARN.
+func (s *SecretsManagerSecretStore) CreateDBCredentials(name string, username string, password string, tags map[string]string) (string, error) {
+	secretString, err := json.Marshal(dbCredentials{Username: username, Password: password})
+	if err != nil {
+		return "", err
+	}
+
+	secretTags := []*secretsmanager.Tag{}
+	for key, value := range tags {
+		secretTags = append(secretTags, &secretsmanager.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(value),
+		})
+	}
+
+	createSecretInput := &secretsmanager.CreateSecretInput{
+		Name:         aws.String(name),
+		SecretString: aws.String(string(secretString)),
+		Tags:         secretTags,
+	}
+	s.logger.Debug("create-secret", lager.Data{"name": name})
+
+	createSecretOutput, err := s.secretsmanagersvc.CreateSecret(createSecretInput)
+	if err != nil {
+		s.logger.Error("aws-secretsmanager-error", err)
+		return "", err
+	}
+
+	s.logger.Debug("create-secret", lager.Data{"arn": createSecretOutput.ARN})
+	return aws.StringValue(createSecretOutput.ARN), nil
+}
+
+// DeleteSecret deletes the secret straight away, without the usual recovery
+// window, as the credentials it holds have already been revoked. A secret
+// which doesn't exist is not an error.
+func (s *SecretsManagerSecretStore) DeleteSecret(name string) error {
+	deleteSecretInput := &secretsmanager.DeleteSecretInput{
+		SecretId:                   aws.String(name),
+		ForceDeleteWithoutRecovery: aws.Bool(true),
+	}
+	s.logger.Debug("delete-secret", lager.Data{"input": deleteSecretInput})
+
+	_, err := s.secretsmanagersvc.DeleteSecret(deleteSecretInput)
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == secretsmanager.ErrCodeResourceNotFoundException {
+		return nil
+	}
+	if err != nil {
+		s.logger.Error("aws-secretsmanager-error", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package awssecrets_test
+
+import (
+	"encoding/json"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/alphagov/paas-rds-broker/awssecrets"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+)
+
+var _ = Describe("Secrets Manager Secret Store", func() {
+	var (
+		secretsmanagersvc *secretsmanager.SecretsManager
+
+		receivedCreateInput *secretsmanager.CreateSecretInput
+		receivedDeleteInput *secretsmanager.DeleteSecretInput
+		callError           error
+
+		secretStore SecretStore
+	)
+
+	BeforeEach(func() {
+		receivedCreateInput = nil
+		receivedDeleteInput = nil
+		callError = nil
+	})
+
+	JustBeforeEach(func() {
+		awsSession, _ := session.NewSession(nil)
+		secretsmanagersvc = secretsmanager.New(awsSession)
+
+		secretsmanagersvc.Handlers.Clear()
+		secretsmanagersvc.Handlers.Send.PushBack(func(r *request.Request) {
+			switch input := r.Params.(type) {
+			case *secretsmanager.CreateSecretInput:
+				receivedCreateInput = input
+				r.Data.(*secretsmanager.CreateSecretOutput).ARN = aws.String("secret-arn")
+			case *secretsmanager.DeleteSecretInput:
+				receivedDeleteInput = input
+			default:
+				Fail("unexpected call to " + r.Operation.Name)
+			}
+			r.Error = callError
+		})
+
+		secretStore = NewSecretsManagerSecretStore(secretsmanagersvc, lagertest.NewTestLogger("secretsmanagersecretstore_test"))
+	})
+
+	Describe("CreateDBCredentials", func() {
+		It("stores the credentials in the format RDS Proxy reads", func() {
+			arn, err := secretStore.CreateDBCredentials("secret-name", "user", "pass", map[string]string{"Owner": "Cloud Foundry"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(arn).To(Equal("secret-arn"))
+
+			Expect(aws.StringValue(receivedCreateInput.Name)).To(Equal("secret-name"))
+			credentials := map[string]string{}
+			Expect(json.Unmarshal([]byte(aws.StringValue(receivedCreateInput.SecretString)), &credentials)).To(Succeed())
+			Expect(credentials).To(Equal(map[string]string{"username": "user", "password": "pass"}))
+			Expect(receivedCreateInput.Tags).To(HaveLen(1))
+			Expect(aws.StringValue(receivedCreateInput.Tags[0].Key)).To(Equal("Owner"))
+		})
+
+		Context("when creating the secret fails", func() {
+			BeforeEach(func() {
+				callError = errors.New("operation failed")
+			})
+
+			It("returns the error", func() {
+				_, err := secretStore.CreateDBCredentials("secret-name", "user", "pass", nil)
+				Expect(err).To(MatchError("operation failed"))
+			})
+		})
+	})
+
+	Describe("DeleteSecret", func() {
+		It("deletes the secret without a recovery window", func() {
+			err := secretStore.DeleteSecret("secret-name")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(aws.StringValue(receivedDeleteInput.SecretId)).To(Equal("secret-name"))
+			Expect(aws.BoolValue(receivedDeleteInput.ForceDeleteWithoutRecovery)).To(BeTrue())
+		})
+
+		Context("when the secret doesn't exist", func() {
+			BeforeEach(func() {
+				callError = awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "not found", nil)
+			})
+
+			It("does not return an error", func() {
+				Expect(secretStore.DeleteSecret("secret-name")).To(Succeed())
+			})
+		})
+
+		Context("when deleting the secret fails", func() {
+			BeforeEach(func() {
+				callError = errors.New("operation failed")
+			})
+
+			It("returns the error", func() {
+				Expect(secretStore.DeleteSecret("secret-name")).To(MatchError("operation failed"))
+			})
+		})
+	})
+})
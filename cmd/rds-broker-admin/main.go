@@ -0,0 +1,257 @@
+// rds-broker-admin runs the common operator tasks against a running broker,
+// through its admin endpoints and, for adoption, its broker API.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alphagov/paas-rds-broker/config"
+	"github.com/alphagov/paas-rds-broker/rdsbroker"
+)
+
+// brokerAPIVersion is the Open Service Broker API version sent with
+// adoption requests.
+const brokerAPIVersion = "2.14"
+
+const usage = `Usage: rds-broker-admin -config=<path> -url=<broker-url> [-admin-url=<admin-url>] <command> [arguments]
+
+Commands:
+  instances             list the service instances owned by the broker
+  status [instance-id]  show the status of every instance, or of the given ones
+  check-credentials     check, and reset if needed, the master credentials of every instance
+  reconcile-tags        write every queued tag write straight away
+  adopt                 adopt an existing DB instance as a service instance, see adopt -h
+`
+
+// client makes requests to the broker with the credentials from its config.
+type client struct {
+	httpClient    *http.Client
+	brokerURL     string
+	adminURL      string
+	username      string
+	password      string
+	adminUsername string
+	adminPassword string
+}
+
+func main() {
+	flags := flag.NewFlagSet("rds-broker-admin", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	configFilePath := flags.String("config", "", "Location of the broker's config file")
+	brokerURL := flags.String("url", "", "URL of the broker, including any base path")
+	adminURL := flags.String("admin-url", "", "URL of the broker's admin listener, when the config has an admin section")
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 || *brokerURL == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadConfig(*configFilePath)
+	if err != nil {
+		fatalf("Error loading config file: %s", err)
+	}
+
+	c := &client{
+		httpClient:    &http.Client{Timeout: 5 * time.Minute},
+		brokerURL:     strings.TrimSuffix(*brokerURL, "/"),
+		adminURL:      strings.TrimSuffix(*brokerURL, "/"),
+		username:      cfg.Username,
+		password:      cfg.Password,
+		adminUsername: cfg.Username,
+		adminPassword: cfg.Password,
+	}
+	if cfg.Admin != nil {
+		if *adminURL == "" {
+			fatalf("The config has an admin section, so -admin-url is required")
+		}
+		c.adminURL = strings.TrimSuffix(*adminURL, "/")
+		c.adminUsername = cfg.Admin.Username
+		c.adminPassword = cfg.Admin.Password
+	}
+
+	args := flags.Args()
+	switch args[0] {
+	case "instances":
+		err = c.listInstances()
+	case "status":
+		err = c.showStatus(args[1:])
+	case "check-credentials":
+		err = c.adminPost("/admin/credentials/check")
+		if err == nil {
+			fmt.Println("Started checking credentials, progress is in the broker's logs")
+		}
+	case "reconcile-tags":
+		err = c.adminPost("/admin/tags/reconcile")
+		if err == nil {
+			fmt.Println("Wrote the queued tags")
+		}
+	case "adopt":
+		err = c.adopt(args[1:])
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fatalf("%s", err)
+	}
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
+
+func (c *client) listInstances() error {
+	definitions := []rdsbroker.InstanceDefinition{}
+	if err := c.adminGet("/admin/instances", &definitions); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "INSTANCE ID\tDB INSTANCE\tPLAN ID\tORGANIZATION ID\tSPACE ID")
+	for _, definition := range definitions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			definition.InstanceID,
+			definition.DBInstanceIdentifier,
+			definition.PlanID,
+			definition.OrganizationID,
+			definition.SpaceID,
+		)
+	}
+	return w.Flush()
+}
+
+func (c *client) showStatus(instanceIDs []string) error {
+	statuses := []rdsbroker.InstanceStatus{}
+	if err := c.adminGet("/admin/status", &statuses); err != nil {
+		return err
+	}
+
+	if len(instanceIDs) > 0 {
+		selected := []rdsbroker.InstanceStatus{}
+		for _, status := range statuses {
+			for _, instanceID := range instanceIDs {
+				if status.InstanceID == instanceID {
+					selected = append(selected, status)
+				}
+			}
+		}
+		if len(selected) < len(instanceIDs) {
+			return fmt.Errorf("Only found %d of the %d instances", len(selected), len(instanceIDs))
+		}
+		statuses = selected
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(statuses)
+}
+
+// adopt asks the broker to provision a service instance which adopts an
+// existing DB instance, as the platform would with the adopt_db_instance
+// parameter, for instances which the platform will be told about later.
+func (c *client) adopt(args []string) error {
+	flags := flag.NewFlagSet("adopt", flag.ExitOnError)
+	instanceID := flags.String("instance-id", "", "GUID of the service instance to create")
+	serviceID := flags.String("service-id", "", "ID of the service in the broker's catalog")
+	planID := flags.String("plan-id", "", "ID of the plan in the broker's catalog")
+	organizationGUID := flags.String("organization-guid", "", "GUID of the organization the service instance belongs to")
+	spaceGUID := flags.String("space-guid", "", "GUID of the space the service instance belongs to")
+	dbInstance := flags.String("db-instance", "", "Identifier of the DB instance to adopt")
+	flags.Parse(args)
+
+	for name, value := range map[string]string{
+		"instance-id":       *instanceID,
+		"service-id":        *serviceID,
+		"plan-id":           *planID,
+		"organization-guid": *organizationGUID,
+		"space-guid":        *spaceGUID,
+		"db-instance":       *dbInstance,
+	} {
+		if value == "" {
+			return fmt.Errorf("adopt: -%s is required", name)
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"service_id":        *serviceID,
+		"plan_id":           *planID,
+		"organization_guid": *organizationGUID,
+		"space_guid":        *spaceGUID,
+		"parameters":        map[string]string{"adopt_db_instance": *dbInstance},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, c.brokerURL+"/v2/service_instances/"+url.PathEscape(*instanceID)+"?accepts_incomplete=true", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Broker-API-Version", brokerAPIVersion)
+	req.SetBasicAuth(c.username, c.password)
+
+	if _, err := c.do(req, http.StatusAccepted); err != nil {
+		return err
+	}
+	fmt.Printf("Adopting %s as service instance %s, its progress is in the broker's admin status\n", *dbInstance, *instanceID)
+	return nil
+}
+
+func (c *client) adminGet(path string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, c.adminURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.adminUsername, c.adminPassword)
+
+	body, err := c.do(req, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+func (c *client) adminPost(path string) error {
+	req, err := http.NewRequest(http.MethodPost, c.adminURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.adminUsername, c.adminPassword)
+
+	_, err = c.do(req, http.StatusAccepted, http.StatusNoContent)
+	return err
+}
+
+// do sends the request and returns the response body, or an error with the
+// body when the status isn't one of those expected.
+func (c *client) do(req *http.Request, expectedStatuses ...int) ([]byte, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range expectedStatuses {
+		if resp.StatusCode == status {
+			return body, nil
+		}
+	}
+	return nil, fmt.Errorf("%s %s returned %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(body)))
+}
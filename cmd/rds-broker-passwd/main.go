@@ -0,0 +1,105 @@
+// rds-broker-passwd derives the master credentials and identifiers the
+// broker uses for a service instance, from the broker's own config so that
+// the seed and lengths always match those of the broker.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	"github.com/alphagov/paas-rds-broker/config"
+	"github.com/alphagov/paas-rds-broker/rdsbroker"
+	"github.com/alphagov/paas-rds-broker/sqlengine"
+)
+
+const usage = `Usage: rds-broker-passwd -config=<path> [-engine=<engine>] [-password-version=<version>] <command> <instance-id>
+
+Commands:
+  derive-master-password  print the master password the broker gives the service instance's DB
+                          instance, following the master password policy of -engine if it has
+                          one, with the broker's master_password_version unless -password-version
+                          is given. Leave out -engine for DB instances without a Master Password
+                          Policy tag
+  derive-db-identifier    print the identifier of the service instance's DB instance
+  verify-connection       connect to the service instance's database with its master credentials
+`
+
+func main() {
+	flags := flag.NewFlagSet("rds-broker-passwd", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	configFilePath := flags.String("config", "", "Location of the broker's config file")
+	engine := flags.String("engine", "", "Engine of the service instance's DB instance")
+	passwordVersion := flags.Int("password-version", 0, "Version of the derivation of the master password")
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	command, instanceID := flags.Arg(0), flags.Arg(1)
+
+	cfg, err := config.LoadConfig(*configFilePath)
+	if err != nil {
+		fatalf("Error loading config file: %s", err)
+	}
+
+	switch command {
+	case "derive-master-password":
+		if *passwordVersion == 0 {
+			*passwordVersion = cfg.RDSConfig.MasterPasswordVersion
+		}
+		fmt.Println(buildBroker(cfg, nil).MasterPassword(instanceID, *engine, *passwordVersion))
+	case "derive-db-identifier":
+		fmt.Println(buildBroker(cfg, nil).DBInstanceIdentifier(instanceID))
+	case "verify-connection":
+		if err := buildBroker(cfg, buildDBInstance(cfg)).VerifyConnection(instanceID); err != nil {
+			fatalf("Failed to connect with the master credentials: %s", err)
+		}
+		fmt.Println("Connected with the master credentials")
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
+
+// buildBroker builds a broker for deriving credentials with. The commands
+// which don't call AWS pass no DB instance.
+func buildBroker(cfg *config.Config, dbInstance awsrds.RDSInstance) *rdsbroker.RDSBroker {
+	logger := lager.NewLogger("rds-broker-passwd")
+	logger.RegisterSink(lager.NewWriterSink(os.Stderr, lager.ERROR))
+
+	return rdsbroker.New(
+		*cfg.RDSConfig,
+		dbInstance,
+		sqlengine.NewProviderService(logger),
+		rdsbroker.NewParameterGroupSource(*cfg.RDSConfig, dbInstance, rdsbroker.SupportedPreloadExtensions, logger),
+		logger,
+	)
+}
+
+func buildDBInstance(cfg *config.Config) awsrds.RDSInstance {
+	awsConfig := cfg.RDSConfig.AWSConfig()
+	awsSession, _ := session.NewSession(awsConfig)
+	logger := lager.NewLogger("rds-broker-passwd")
+	return awsrds.NewRDSDBInstance(
+		cfg.RDSConfig.Region,
+		cfg.RDSConfig.AWSPartition,
+		rds.New(awsSession),
+		logger,
+		0,
+		time.Second*time.Duration(cfg.RDSConfig.AWSEngineVersionCacheSeconds),
+		nil,
+	)
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"errors"
+)
+
+// AdminConfig moves the admin endpoints off the broker API listener onto
+// listeners of their own, with separate credentials.
+type AdminConfig struct {
+	Listen   []string   `json:"listen"`
+	Username string     `json:"username"`
+	Password string     `json:"password"`
+	TLS      *TLSConfig `json:"tls"`
+}
+
+// ListenAddresses returns the addresses the admin endpoints should be
+// served on.
+func (a *AdminConfig) ListenAddresses() ([]ListenAddress, error) {
+	listenAddresses := []ListenAddress{}
+	for _, address := range a.Listen {
+		listenAddress, err := ParseListenAddress(address)
+		if err != nil {
+			return nil, err
+		}
+		listenAddresses = append(listenAddresses, listenAddress)
+	}
+	return listenAddresses, nil
+}
+
+func (a *AdminConfig) validate() error {
+	if len(a.Listen) == 0 {
+		return errors.New("Config error: admin listen addresses required")
+	}
+	if _, err := a.ListenAddresses(); err != nil {
+		return err
+	}
+	if a.Username == "" {
+		return errors.New("Config error: admin username required")
+	}
+	if a.Password == "" {
+		return errors.New("Config error: admin password required")
+	}
+	if a.TLS != nil {
+		return a.TLS.validate()
+	}
+	return nil
+}
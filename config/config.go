@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/alphagov/paas-rds-broker/rdsbroker"
 )
@@ -20,11 +21,15 @@ type Config struct {
 	Username             string            `json:"username"`
 	Password             string            `json:"password"`
 	Host                 string            `json:"host"`
+	Listen               []string          `json:"listen"`
+	BasePath             string            `json:"base_path"`
 	RunHousekeeping      bool              `json:"run_housekeeping"`
 	KeepSnapshotsForDays int               `json:"keep_snapshots_for_days"`
 	CronSchedule         string            `json:"cron_schedule"`
 	RDSConfig            *rdsbroker.Config `json:"rds_config"`
 	TLS                  *TLSConfig        `json:"tls"`
+	HTTPServer           *HTTPServerConfig `json:"http_server"`
+	Admin                *AdminConfig      `json:"admin"`
 }
 
 func LoadConfig(configFile string) (config *Config, err error) {
@@ -58,6 +63,15 @@ func (c *Config) FillDefaults() {
 	if c.Host == "" {
 		c.Host = DefaultHost
 	}
+	if basePath := strings.Trim(c.BasePath, "/"); basePath != "" {
+		c.BasePath = "/" + basePath
+	} else {
+		c.BasePath = ""
+	}
+	if c.HTTPServer == nil {
+		c.HTTPServer = &HTTPServerConfig{}
+	}
+	c.HTTPServer.fillDefaults()
 	c.RDSConfig.FillDefaults()
 }
 
@@ -97,5 +111,21 @@ func (c Config) Validate() error {
 		}
 	}
 
+	if _, err := c.ListenAddresses(); err != nil {
+		return err
+	}
+
+	if c.Admin != nil {
+		if err := c.Admin.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.HTTPServer != nil {
+		if err := c.HTTPServer.validate(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
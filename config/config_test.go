@@ -1,6 +1,8 @@
 package config_test
 
 import (
+	"time"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -55,6 +57,51 @@ var _ = Describe("Config", func() {
 			})
 		})
 
+		Describe("BasePath", func() {
+			It("adds a leading slash and removes any trailing slash", func() {
+				config.BasePath = "brokers/rds/"
+				config.FillDefaults()
+				Expect(config.BasePath).To(Equal("/brokers/rds"))
+			})
+
+			It("treats the root as no base path", func() {
+				config.BasePath = "/"
+				config.FillDefaults()
+				Expect(config.BasePath).To(Equal(""))
+			})
+		})
+
+		Describe("HTTPServer", func() {
+			It("sets default timeouts and header size", func() {
+				config.HTTPServer = nil
+				config.FillDefaults()
+				Expect(config.HTTPServer).To(Equal(&HTTPServerConfig{
+					ReadHeaderTimeoutSeconds: 10,
+					ReadTimeoutSeconds:       60,
+					WriteTimeoutSeconds:      120,
+					IdleTimeoutSeconds:       120,
+					MaxHeaderBytes:           1 << 20,
+				}))
+			})
+
+			It("does not override existing values", func() {
+				config.HTTPServer = &HTTPServerConfig{WriteTimeoutSeconds: 300, MaxConnections: 50}
+				config.FillDefaults()
+				Expect(config.HTTPServer.WriteTimeoutSeconds).To(Equal(300))
+				Expect(config.HTTPServer.MaxConnections).To(Equal(50))
+				Expect(config.HTTPServer.ReadTimeoutSeconds).To(Equal(60))
+			})
+
+			It("builds a server with the timeouts", func() {
+				config.HTTPServer = nil
+				config.FillDefaults()
+				server := config.HTTPServer.NewServer(nil)
+				Expect(server.ReadHeaderTimeout).To(Equal(10 * time.Second))
+				Expect(server.WriteTimeout).To(Equal(120 * time.Second))
+				Expect(server.MaxHeaderBytes).To(Equal(1 << 20))
+			})
+		})
+
 		It("fills defaults in the RDSConfig", func() {
 			config.RDSConfig.AWSPartition = ""
 			config.FillDefaults()
@@ -128,6 +175,34 @@ var _ = Describe("Config", func() {
 			Expect(err).To(MatchError("must provide a valid number for keep_snapshots_for_days"))
 		})
 
+		It("returns error if a listen address is not valid", func() {
+			config.Listen = []string{"localhost"}
+
+			err := config.Validate()
+			Expect(err).To(MatchError(ContainSubstring("Config error: listen address 'localhost' is invalid")))
+		})
+
+		It("returns error if the admin configuration has no listen addresses", func() {
+			config.Admin = &AdminConfig{Username: "admin", Password: "adminpass"}
+
+			err := config.Validate()
+			Expect(err).To(MatchError("Config error: admin listen addresses required"))
+		})
+
+		It("returns error if the admin configuration has no credentials", func() {
+			config.Admin = &AdminConfig{Listen: []string{"127.0.0.1:3001"}}
+
+			err := config.Validate()
+			Expect(err).To(MatchError("Config error: admin username required"))
+		})
+
+		It("returns error if the HTTP server configuration is not valid", func() {
+			config.HTTPServer = &HTTPServerConfig{MaxConnections: -1}
+
+			err := config.Validate()
+			Expect(err).To(MatchError("Config error: http_server max_connections must not be negative"))
+		})
+
 		It("returns error if RDS configuration is not valid", func() {
 			config.RDSConfig = &rdsbroker.Config{}
 
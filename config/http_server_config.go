@@ -0,0 +1,67 @@
+package config
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultReadHeaderTimeoutSeconds = 10
+	DefaultReadTimeoutSeconds       = 60
+	DefaultWriteTimeoutSeconds      = 120
+	DefaultIdleTimeoutSeconds       = 120
+)
+
+type HTTPServerConfig struct {
+	ReadHeaderTimeoutSeconds int `json:"read_header_timeout_seconds"`
+	ReadTimeoutSeconds       int `json:"read_timeout_seconds"`
+	WriteTimeoutSeconds      int `json:"write_timeout_seconds"`
+	IdleTimeoutSeconds       int `json:"idle_timeout_seconds"`
+	MaxHeaderBytes           int `json:"max_header_bytes"`
+	MaxConnections           int `json:"max_connections"`
+}
+
+func (h *HTTPServerConfig) fillDefaults() {
+	if h.ReadHeaderTimeoutSeconds == 0 {
+		h.ReadHeaderTimeoutSeconds = DefaultReadHeaderTimeoutSeconds
+	}
+	if h.ReadTimeoutSeconds == 0 {
+		h.ReadTimeoutSeconds = DefaultReadTimeoutSeconds
+	}
+	if h.WriteTimeoutSeconds == 0 {
+		h.WriteTimeoutSeconds = DefaultWriteTimeoutSeconds
+	}
+	if h.IdleTimeoutSeconds == 0 {
+		h.IdleTimeoutSeconds = DefaultIdleTimeoutSeconds
+	}
+	if h.MaxHeaderBytes == 0 {
+		h.MaxHeaderBytes = http.DefaultMaxHeaderBytes
+	}
+}
+
+func (h *HTTPServerConfig) validate() error {
+	if h.ReadHeaderTimeoutSeconds < 0 || h.ReadTimeoutSeconds < 0 || h.WriteTimeoutSeconds < 0 || h.IdleTimeoutSeconds < 0 {
+		return errors.New("Config error: http_server timeouts must not be negative")
+	}
+	if h.MaxHeaderBytes < 0 {
+		return errors.New("Config error: http_server max_header_bytes must not be negative")
+	}
+	if h.MaxConnections < 0 {
+		return errors.New("Config error: http_server max_connections must not be negative")
+	}
+	return nil
+}
+
+// NewServer builds an http.Server for handler with the configured timeouts
+// and header size limit.
+func (h *HTTPServerConfig) NewServer(handler http.Handler) *http.Server {
+	return &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: time.Duration(h.ReadHeaderTimeoutSeconds) * time.Second,
+		ReadTimeout:       time.Duration(h.ReadTimeoutSeconds) * time.Second,
+		WriteTimeout:      time.Duration(h.WriteTimeoutSeconds) * time.Second,
+		IdleTimeout:       time.Duration(h.IdleTimeoutSeconds) * time.Second,
+		MaxHeaderBytes:    h.MaxHeaderBytes,
+	}
+}
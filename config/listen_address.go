@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const unixSocketPrefix = "unix:"
+
+// ListenAddress is an address for the broker to accept connections on,
+// either a TCP host and port or the path of a unix socket.
+type ListenAddress struct {
+	Network string
+	Address string
+}
+
+// ParseListenAddress parses "host:port" or "[ipv6-host]:port" as a TCP
+// address, and "unix:/path/to/socket" as a unix socket.
+func ParseListenAddress(address string) (ListenAddress, error) {
+	if strings.HasPrefix(address, unixSocketPrefix) {
+		path := strings.TrimPrefix(address, unixSocketPrefix)
+		if path == "" {
+			return ListenAddress{}, fmt.Errorf("Config error: listen address '%s' must have a socket path", address)
+		}
+		return ListenAddress{Network: "unix", Address: path}, nil
+	}
+
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return ListenAddress{}, fmt.Errorf("Config error: listen address '%s' is invalid: %s", address, err)
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return ListenAddress{}, fmt.Errorf("Config error: listen address '%s' has an invalid port", address)
+	}
+	return ListenAddress{Network: "tcp", Address: address}, nil
+}
+
+// ListenAddresses returns the addresses the broker should listen on. These
+// are the configured listen addresses, or else host and port.
+func (c Config) ListenAddresses() ([]ListenAddress, error) {
+	if len(c.Listen) == 0 {
+		return []ListenAddress{
+			{Network: "tcp", Address: net.JoinHostPort(c.Host, strconv.Itoa(c.Port))},
+		}, nil
+	}
+
+	listenAddresses := []ListenAddress{}
+	for _, address := range c.Listen {
+		listenAddress, err := ParseListenAddress(address)
+		if err != nil {
+			return nil, err
+		}
+		listenAddresses = append(listenAddresses, listenAddress)
+	}
+	return listenAddresses, nil
+}
@@ -0,0 +1,64 @@
+package config_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/alphagov/paas-rds-broker/config"
+)
+
+var _ = Describe("ListenAddress", func() {
+	Describe("ParseListenAddress", func() {
+		It("parses a TCP address", func() {
+			listenAddress, err := ParseListenAddress("127.0.0.1:3000")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(listenAddress).To(Equal(ListenAddress{Network: "tcp", Address: "127.0.0.1:3000"}))
+		})
+
+		It("parses an IPv6 TCP address", func() {
+			listenAddress, err := ParseListenAddress("[::]:3000")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(listenAddress).To(Equal(ListenAddress{Network: "tcp", Address: "[::]:3000"}))
+		})
+
+		It("parses a unix socket", func() {
+			listenAddress, err := ParseListenAddress("unix:/var/vcap/sys/run/rds-broker.sock")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(listenAddress).To(Equal(ListenAddress{Network: "unix", Address: "/var/vcap/sys/run/rds-broker.sock"}))
+		})
+
+		It("rejects a unix socket without a path", func() {
+			_, err := ParseListenAddress("unix:")
+			Expect(err).To(MatchError("Config error: listen address 'unix:' must have a socket path"))
+		})
+
+		It("rejects an address without a port", func() {
+			_, err := ParseListenAddress("127.0.0.1")
+			Expect(err).To(MatchError(ContainSubstring("Config error: listen address '127.0.0.1' is invalid")))
+		})
+
+		It("rejects an invalid port", func() {
+			_, err := ParseListenAddress("127.0.0.1:http")
+			Expect(err).To(MatchError("Config error: listen address '127.0.0.1:http' has an invalid port"))
+		})
+	})
+
+	Describe("ListenAddresses", func() {
+		It("defaults to the host and port", func() {
+			config := Config{Host: "::1", Port: 3000}
+			listenAddresses, err := config.ListenAddresses()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(listenAddresses).To(Equal([]ListenAddress{{Network: "tcp", Address: "[::1]:3000"}}))
+		})
+
+		It("uses the listen addresses when they are set", func() {
+			config := Config{Host: "0.0.0.0", Port: 3000, Listen: []string{"127.0.0.1:3000", "unix:/tmp/rds-broker.sock"}}
+			listenAddresses, err := config.ListenAddresses()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(listenAddresses).To(Equal([]ListenAddress{
+				{Network: "tcp", Address: "127.0.0.1:3000"},
+				{Network: "unix", Address: "/tmp/rds-broker.sock"},
+			}))
+		})
+	})
+})
@@ -9,18 +9,35 @@ import (
 	robfig_cron "github.com/robfig/cron"
 )
 
+// ParameterGroupDriftCorrector puts back parameters of the broker's
+// parameter groups which have been changed outside of the broker.
+type ParameterGroupDriftCorrector interface {
+	CorrectParameterGroupDrift() (int, error)
+}
+
+// Housekeeper runs the broker's periodic jobs over its DB instances, such
+// as purging soft deleted instances and checking backups, sharing one
+// listing of the instances between them.
+type Housekeeper interface {
+	RunHousekeeping() error
+}
+
 type Process struct {
-	cron       *robfig_cron.Cron
-	config     *config.Config
-	dbInstance awsrds.RDSInstance
-	logger     lager.Logger
+	cron                *robfig_cron.Cron
+	config              *config.Config
+	dbInstance          awsrds.RDSInstance
+	parameterGroupDrift ParameterGroupDriftCorrector
+	housekeeper         Housekeeper
+	logger              lager.Logger
 }
 
-func NewProcess(config *config.Config, dbInstance awsrds.RDSInstance, logger lager.Logger) *Process {
+func NewProcess(config *config.Config, dbInstance awsrds.RDSInstance, parameterGroupDrift ParameterGroupDriftCorrector, housekeeper Housekeeper, logger lager.Logger) *Process {
 	return &Process{
-		config:     config,
-		dbInstance: dbInstance,
-		logger:     logger,
+		config:              config,
+		dbInstance:          dbInstance,
+		parameterGroupDrift: parameterGroupDrift,
+		housekeeper:         housekeeper,
+		logger:              logger,
 	}
 }
 
@@ -31,11 +48,17 @@ func (p *Process) Start() error {
 		if err != nil {
 			p.logger.Error("delete-snapshots", err)
 		}
+		p.correctParameterGroupDrift()
+		if err := p.housekeeper.RunHousekeeping(); err != nil {
+			p.logger.Error("housekeeping", err)
+		}
 	})
 	if err != nil {
 		return fmt.Errorf("cron_schedule is invalid: %s", err)
 	}
 
+	p.correctParameterGroupDrift()
+
 	p.logger.Info("cron-start")
 	p.cron.Run()
 	p.logger.Info("cron-stop")
@@ -43,6 +66,13 @@ func (p *Process) Start() error {
 	return nil
 }
 
+func (p *Process) correctParameterGroupDrift() {
+	_, err := p.parameterGroupDrift.CorrectParameterGroupDrift()
+	if err != nil {
+		p.logger.Error("correct-parameter-group-drift", err)
+	}
+}
+
 func (p *Process) Stop() {
 	if p.cron != nil {
 		p.cron.Stop()
@@ -2,6 +2,7 @@ package cron_test
 
 import (
 	"errors"
+	"sync/atomic"
 
 	"code.cloudfoundry.org/lager/v3"
 	. "github.com/onsi/ginkgo/v2"
@@ -13,11 +14,25 @@ import (
 	"github.com/alphagov/paas-rds-broker/rdsbroker"
 )
 
+type fakeHousekeeper struct {
+	calls int32
+}
+
+func (f *fakeHousekeeper) RunHousekeeping() error {
+	atomic.AddInt32(&f.calls, 1)
+	return nil
+}
+
+func (f *fakeHousekeeper) CallCount() int {
+	return int(atomic.LoadInt32(&f.calls))
+}
+
 var _ = Describe("Process", func() {
 
 	var cfg *config.Config
 	var rdsInstance *fakes.FakeRDSInstance
 	var logger lager.Logger
+	var housekeeper *fakeHousekeeper
 	var process *Process
 
 	BeforeEach(func() {
@@ -31,7 +46,9 @@ var _ = Describe("Process", func() {
 		}
 		rdsInstance = &fakes.FakeRDSInstance{}
 		logger = lager.NewLogger("main.test")
-		process = NewProcess(cfg, rdsInstance, logger)
+		parameterGroupSource := rdsbroker.NewParameterGroupSource(*cfg.RDSConfig, rdsInstance, rdsbroker.SupportedPreloadExtensions, logger)
+		housekeeper = &fakeHousekeeper{}
+		process = NewProcess(cfg, rdsInstance, parameterGroupSource, housekeeper, logger)
 	})
 
 	AfterEach(func() {
@@ -73,6 +90,34 @@ var _ = Describe("Process", func() {
 		Expect(err).ToNot(HaveOccurred())
 	})
 
+	It("should check the parameter groups for drift on start and regularly", func() {
+		var err error
+		go func() {
+			err = process.Start()
+		}()
+
+		Eventually(func() int {
+			return rdsInstance.DescribeByTagCallCount()
+		}, "5s").Should(BeNumerically(">=", 2))
+
+		tagKey, tagValue, _ := rdsInstance.DescribeByTagArgsForCall(0)
+		Expect(tagKey).To(Equal("Broker Name"))
+		Expect(tagValue).To(Equal("test-broker"))
+
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should run the housekeeping jobs regularly", func() {
+		var err error
+		go func() {
+			err = process.Start()
+		}()
+
+		Eventually(housekeeper.CallCount, "5s").Should(BeNumerically(">=", 2))
+
+		Expect(err).ToNot(HaveOccurred())
+	})
+
 	Context("the schedule is invalid", func() {
 		It("should exit with error", func() {
 			cfg.CronSchedule = "invalid"
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net"
+	"sync"
+)
+
+// limitListener accepts at most max simultaneous connections from the
+// wrapped listener. Further connections wait in the kernel's accept queue
+// until an open one is closed.
+type limitListener struct {
+	net.Listener
+	sem chan struct{}
+}
+
+func newLimitListener(listener net.Listener, max int) net.Listener {
+	return &limitListener{
+		Listener: listener,
+		sem:      make(chan struct{}, max),
+	}
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	l.sem <- struct{}{}
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		<-l.sem
+		return nil, err
+	}
+	return &limitListenerConn{Conn: conn, release: func() { <-l.sem }}, nil
+}
+
+type limitListenerConn struct {
+	net.Conn
+	releaseOnce sync.Once
+	release     func()
+}
+
+func (c *limitListenerConn) Close() error {
+	err := c.Conn.Close()
+	c.releaseOnce.Do(c.release)
+	return err
+}
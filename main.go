@@ -9,24 +9,40 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"time"
 
 	"code.cloudfoundry.org/lager/v3"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
 	"github.com/pivotal-cf/brokerapi/v9"
+	"github.com/pivotal-cf/brokerapi/v9/auth"
 
+	"github.com/alphagov/paas-rds-broker/awskms"
 	"github.com/alphagov/paas-rds-broker/awsrds"
+	"github.com/alphagov/paas-rds-broker/awsroute53"
+	"github.com/alphagov/paas-rds-broker/awssecrets"
 	"github.com/alphagov/paas-rds-broker/config"
 	"github.com/alphagov/paas-rds-broker/cron"
 	"github.com/alphagov/paas-rds-broker/rdsbroker"
 	"github.com/alphagov/paas-rds-broker/sqlengine"
+	"github.com/alphagov/paas-rds-broker/statestore"
 )
 
+// tagWriteRetryInterval is how often to look for failed tag writes which
+// are due to be retried.
+const tagWriteRetryInterval = 30 * time.Second
+
 func main() {
 	configFilePath := flag.String("config", "", "Location of the config file")
+	checkCatalog := flag.Bool("check-catalog", false, "Check the catalog's plans can be ordered from RDS, then exit")
 	flag.Parse()
 
 	cfg, err := config.LoadConfig(*configFilePath)
@@ -34,14 +50,64 @@ func main() {
 		log.Fatalf("Error loading config file: %s", err)
 	}
 	logger := buildLogger(cfg.LogLevel)
-	dbInstance := buildDBInstance(*cfg.RDSConfig, logger)
-	sqlProvider := sqlengine.NewProviderService(logger)
+
+	var dbInstance awsrds.RDSInstance
+	var circuitBreaker *awsrds.CircuitBreaker
+	var sqlProvider sqlengine.Provider
+	if cfg.RDSConfig.DryRun {
+		logger.Info("dry-run", lager.Data{"message": "simulating RDS in memory, no changes will be made in AWS"})
+		dbInstance = buildSimulatedDBInstance(*cfg.RDSConfig, logger)
+		sqlProvider = sqlengine.NewSimulatedProvider(logger)
+	} else {
+		dbInstance, circuitBreaker = buildDBInstance(*cfg.RDSConfig, logger)
+		sqlProvider = buildSQLProvider(*cfg.RDSConfig, logger)
+	}
 	parameterGroupSource := rdsbroker.NewParameterGroupSource(*cfg.RDSConfig, dbInstance, rdsbroker.SupportedPreloadExtensions, logger.Session("parameter_group_source"))
 	broker := rdsbroker.New(*cfg.RDSConfig, dbInstance, sqlProvider, parameterGroupSource, logger)
+	if circuitBreaker != nil {
+		broker.SetAWSAvailability(circuitBreaker)
+	}
+	if !cfg.RDSConfig.DryRun {
+		if cfg.RDSConfig.InventoryBucket != "" {
+			broker.SetInventoryStore(buildInventoryStore(*cfg.RDSConfig))
+		}
+		if len(cfg.RDSConfig.OrganizationDataImportBuckets) > 0 {
+			broker.SetDataImportPresigner(buildDataImportPresigner(*cfg.RDSConfig))
+		}
+		if len(cfg.RDSConfig.OrganizationDataExportBuckets) > 0 {
+			broker.SetDataExportStore(buildDataExportStore(*cfg.RDSConfig))
+		}
+		if cfg.RDSConfig.DNS != nil {
+			broker.SetDNSZone(buildDNSZone(*cfg.RDSConfig, logger))
+		}
+		broker.SetSecretStore(buildSecretStore(*cfg.RDSConfig, logger))
+		if cfg.RDSConfig.BackupAccount != nil && cfg.RDSConfig.BackupAccount.CopyRoleARN != "" {
+			broker.SetBackupAccountDBInstance(buildBackupAccountDBInstance(*cfg.RDSConfig, logger))
+		}
+		if len(cfg.RDSConfig.KmsKeyAliases) > 0 {
+			broker.SetKeyResolver(buildKeyResolver(*cfg.RDSConfig, logger))
+		}
+		if cfg.RDSConfig.DeprovisionProtectionHours > 0 {
+			broker.SetMetricStatistics(buildMetricStatistics(*cfg.RDSConfig))
+		}
+	}
+	if cfg.RDSConfig.StateStore != nil {
+		stateStore, err := statestore.NewPostgresStateStore(cfg.RDSConfig.StateStore.URL, logger.Session("state_store"))
+		if err != nil {
+			log.Fatalf("Failed to open state store: %s", err)
+		}
+		broker.SetStateStore(stateStore)
+	}
+
+	if *checkCatalog {
+		os.Exit(runCatalogCheck(broker))
+	}
+
+	go broker.RunTagWriteRetries(tagWriteRetryInterval)
 
 	if cfg.RunHousekeeping {
 		go broker.CheckAndRotateCredentials()
-		go startCronProcess(cfg, dbInstance, logger)
+		go startCronProcess(cfg, dbInstance, parameterGroupSource, broker, logger)
 	}
 
 	err = startHTTPServer(cfg, broker, logger)
@@ -50,6 +116,24 @@ func main() {
 	}
 }
 
+// runCatalogCheck prints any plans which can't be ordered from RDS, and
+// returns the exit status for the check.
+func runCatalogCheck(broker *rdsbroker.RDSBroker) int {
+	problems, err := broker.CheckCatalogOrderability()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to check the catalog: %s\n", err)
+		return 2
+	}
+
+	for _, problem := range problems {
+		fmt.Printf("%s/%s (%s): %s\n", problem.ServiceName, problem.PlanName, problem.PlanID, problem.Problem)
+	}
+	if len(problems) > 0 {
+		return 1
+	}
+	return 0
+}
+
 func buildLogger(logLevel string) lager.Logger {
 	lagerLogLevel, err := lager.LogLevelFromString(strings.ToLower(logLevel))
 	if err != nil {
@@ -70,62 +154,247 @@ func buildHTTPHandler(serviceBroker *rdsbroker.RDSBroker, logger lager.Logger, c
 
 	brokerAPI := brokerapi.New(serviceBroker, logger, credentials)
 	mux := http.NewServeMux()
-	mux.Handle("/", brokerAPI)
+	mux.Handle("/", rdsbroker.RetryAfterHandler(brokerAPI))
 	mux.HandleFunc("/healthcheck", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
-	return mux
+	mux.Handle("/healthcheck/deep", serviceBroker.DeepHealthcheckHandler())
+	if config.Admin == nil {
+		mux.Handle("/admin/", auth.NewWrapper(config.Username, config.Password).Wrap(serviceBroker.AdminHandler()))
+	}
+
+	return withBasePath(mux, config.BasePath)
 }
 
-func buildDBInstance(rdsCfg rdsbroker.Config, logger lager.Logger) awsrds.RDSInstance {
-	awsConfig := aws.NewConfig().WithRegion(rdsCfg.Region).WithMaxRetries(3)
+// withBasePath serves handler's routes under basePath, if it isn't empty.
+func withBasePath(handler http.Handler, basePath string) http.Handler {
+	if basePath == "" {
+		return handler
+	}
+
+	prefixedMux := http.NewServeMux()
+	prefixedMux.Handle(basePath+"/", http.StripPrefix(basePath, handler))
+	return prefixedMux
+}
+
+// buildAdminHTTPHandler serves the admin endpoints on their own listeners,
+// when the config has an admin section, under the same base path as they
+// would have on the broker's.
+func buildAdminHTTPHandler(serviceBroker *rdsbroker.RDSBroker, config *config.Config) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthcheck", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.Handle("/healthcheck/deep", serviceBroker.DeepHealthcheckHandler())
+	mux.Handle("/admin/", auth.NewWrapper(config.Admin.Username, config.Admin.Password).Wrap(serviceBroker.AdminHandler()))
+	return withBasePath(mux, config.BasePath)
+}
+
+// buildSQLProvider provides the SQL engines, pinging them by connecting to
+// their canary databases if the config has any.
+func buildSQLProvider(rdsCfg rdsbroker.Config, logger lager.Logger) sqlengine.Provider {
+	sqlProvider := sqlengine.NewProviderService(logger)
+	for engine, canary := range rdsCfg.SQLEngineCanaries {
+		sqlProvider.SetCanary(engine, sqlengine.Canary(canary))
+	}
+	return sqlProvider
+}
+
+func buildDBInstance(rdsCfg rdsbroker.Config, logger lager.Logger) (awsrds.RDSInstance, *awsrds.CircuitBreaker) {
+	awsConfig := rdsCfg.AWSConfig()
 	awsSession, _ := session.NewSession(awsConfig)
 	rdssvc := rds.New(awsSession)
+	var circuitBreaker *awsrds.CircuitBreaker
+	if rdsCfg.AWSCircuitBreaker != nil {
+		circuitBreaker = awsrds.NewCircuitBreaker(
+			int(rdsCfg.AWSCircuitBreaker.FailureThreshold),
+			time.Second*time.Duration(rdsCfg.AWSCircuitBreaker.HealthPingSeconds),
+			logger,
+		)
+		circuitBreaker.Install(rdssvc)
+	}
 	return awsrds.NewRDSDBInstance(
 		rdsCfg.Region,
-		"aws",
+		rdsCfg.AWSPartition,
 		rdssvc,
 		logger,
 		time.Second*time.Duration(rdsCfg.AWSTagCacheSeconds),
+		time.Second*time.Duration(rdsCfg.AWSEngineVersionCacheSeconds),
+		nil,
+	), circuitBreaker
+}
+
+// buildBackupAccountDBInstance calls RDS in the backup account, with the
+// role the broker assumes there to copy snapshots.
+func buildBackupAccountDBInstance(rdsCfg rdsbroker.Config, logger lager.Logger) awsrds.RDSInstance {
+	awsConfig := rdsCfg.AWSConfig()
+	awsSession, _ := session.NewSession(awsConfig)
+	backupAccountConfig := rdsCfg.AWSConfig().WithCredentials(stscreds.NewCredentials(awsSession, rdsCfg.BackupAccount.CopyRoleARN))
+	backupAccountSession, _ := session.NewSession(backupAccountConfig)
+	return awsrds.NewRDSDBInstance(
+		rdsCfg.Region,
+		rdsCfg.AWSPartition,
+		rds.New(backupAccountSession),
+		logger.Session("backup-account"),
+		time.Second*time.Duration(rdsCfg.AWSTagCacheSeconds),
+		time.Second*time.Duration(rdsCfg.AWSEngineVersionCacheSeconds),
 		nil,
 	)
 }
 
+// buildSimulatedDBInstance simulates RDS for dry run mode, offering the
+// engine versions of the plans in the catalog.
+func buildSimulatedDBInstance(rdsCfg rdsbroker.Config, logger lager.Logger) awsrds.RDSInstance {
+	engineVersions := map[string][]string{}
+	for _, service := range rdsCfg.Catalog.Services {
+		for _, plan := range service.Plans {
+			engine := aws.StringValue(plan.RDSProperties.Engine)
+			engineVersions[engine] = append(engineVersions[engine], aws.StringValue(plan.RDSProperties.EngineVersion))
+		}
+	}
+	return awsrds.NewSimulatedRDSInstance(rdsCfg.Region, rdsCfg.AWSPartition, engineVersions, logger)
+}
+
+func buildInventoryStore(rdsCfg rdsbroker.Config) rdsbroker.InventoryStore {
+	awsConfig := rdsCfg.AWSConfig()
+	awsSession, _ := session.NewSession(awsConfig)
+	return s3.New(awsSession)
+}
+
+func buildDataImportPresigner(rdsCfg rdsbroker.Config) rdsbroker.DataImportPresigner {
+	awsConfig := rdsCfg.AWSConfig()
+	awsSession, _ := session.NewSession(awsConfig)
+	return rdsbroker.NewS3Presigner(s3.New(awsSession))
+}
+
+func buildDataExportStore(rdsCfg rdsbroker.Config) rdsbroker.DataExportStore {
+	awsConfig := rdsCfg.AWSConfig()
+	awsSession, _ := session.NewSession(awsConfig)
+	return s3.New(awsSession)
+}
+
+func buildMetricStatistics(rdsCfg rdsbroker.Config) rdsbroker.MetricStatistics {
+	awsConfig := rdsCfg.AWSConfig()
+	awsSession, _ := session.NewSession(awsConfig)
+	return cloudwatch.New(awsSession)
+}
+
+func buildDNSZone(rdsCfg rdsbroker.Config, logger lager.Logger) awsroute53.DNSZone {
+	awsConfig := rdsCfg.AWSConfig()
+	awsSession, _ := session.NewSession(awsConfig)
+	return awsroute53.NewRoute53DNSZone(
+		rdsCfg.DNS.HostedZoneID,
+		rdsCfg.DNS.TTL,
+		route53.New(awsSession),
+		logger,
+	)
+}
+
+func buildSecretStore(rdsCfg rdsbroker.Config, logger lager.Logger) awssecrets.SecretStore {
+	awsConfig := rdsCfg.AWSConfig()
+	awsSession, _ := session.NewSession(awsConfig)
+	return awssecrets.NewSecretsManagerSecretStore(secretsmanager.New(awsSession), logger)
+}
+
+func buildKeyResolver(rdsCfg rdsbroker.Config, logger lager.Logger) awskms.KeyResolver {
+	awsConfig := rdsCfg.AWSConfig()
+	awsSession, _ := session.NewSession(awsConfig)
+	return awskms.NewKMSKeyResolver(awskms.NewKMS(awsSession), logger)
+}
+
 func startHTTPServer(
 	cfg *config.Config,
 	serviceBroker *rdsbroker.RDSBroker,
 	logger lager.Logger,
 ) error {
-	server := buildHTTPHandler(serviceBroker, logger, cfg)
+	server := cfg.HTTPServer.NewServer(buildHTTPHandler(serviceBroker, logger, cfg))
+
+	listenAddresses, err := cfg.ListenAddresses()
+	if err != nil {
+		return err
+	}
 
-	listenAddress := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
 	// We don't use http.ListenAndServe here so that the "start" log message is
 	// logged after the socket is listening. This log message is used by the
 	// tests to wait until the broker is ready.
-	listener, err := net.Listen("tcp", listenAddress)
+	listeners := map[net.Listener]*http.Server{}
+	for _, listenAddress := range listenAddresses {
+		listener, err := listen(listenAddress, cfg.TLS, cfg.HTTPServer.MaxConnections, logger)
+		if err != nil {
+			return err
+		}
+		listeners[listener] = server
+	}
+
+	if cfg.Admin != nil {
+		adminServer := cfg.HTTPServer.NewServer(buildAdminHTTPHandler(serviceBroker, cfg))
+		adminListenAddresses, err := cfg.Admin.ListenAddresses()
+		if err != nil {
+			return err
+		}
+		for _, listenAddress := range adminListenAddresses {
+			listener, err := listen(listenAddress, cfg.Admin.TLS, cfg.HTTPServer.MaxConnections, logger.Session("admin"))
+			if err != nil {
+				return err
+			}
+			listeners[listener] = adminServer
+		}
+	}
+
+	errs := make(chan error, len(listeners))
+	for listener, server := range listeners {
+		go func(listener net.Listener, server *http.Server) {
+			errs <- server.Serve(listener)
+		}(listener, server)
+	}
+	return <-errs
+}
+
+func listen(listenAddress config.ListenAddress, tlsCfg *config.TLSConfig, maxConnections int, logger lager.Logger) (net.Listener, error) {
+	if listenAddress.Network == "unix" {
+		// Remove a socket left behind by a previous run.
+		if err := os.Remove(listenAddress.Address); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove socket %s: %s", listenAddress.Address, err)
+		}
+	}
+
+	listener, err := net.Listen(listenAddress.Network, listenAddress.Address)
 	if err != nil {
-		return fmt.Errorf("failed to listen on address %s: %s", listenAddress, err)
+		return nil, fmt.Errorf("failed to listen on address %s: %s", listenAddress.Address, err)
+	}
+	if maxConnections > 0 {
+		listener = newLimitListener(listener, maxConnections)
 	}
-	if cfg.TLSEnabled() {
-		tlsConfig, err := cfg.TLS.GenerateTLSConfig()
+
+	if listenAddress.Network == "unix" {
+		logger.Info("start", lager.Data{"socket": listenAddress.Address})
+		return listener, nil
+	}
+
+	host, portString, _ := net.SplitHostPort(listenAddress.Address)
+	port, _ := strconv.Atoi(portString)
+	if tlsCfg != nil {
+		tlsConfig, err := tlsCfg.GenerateTLSConfig()
 		if err != nil {
 			log.Fatalf("Error configuring TLS: %s", err)
 		}
 		listener = tls.NewListener(listener, tlsConfig)
-		logger.Info("start", lager.Data{"port": cfg.Port, "tls": true, "host": cfg.Host, "address": listenAddress})
+		logger.Info("start", lager.Data{"port": port, "tls": true, "host": host, "address": listenAddress.Address})
 	} else {
-		logger.Info("start", lager.Data{"port": cfg.Port, "tls": false, "host": cfg.Host, "address": listenAddress})
+		logger.Info("start", lager.Data{"port": port, "tls": false, "host": host, "address": listenAddress.Address})
 	}
 
-	return http.Serve(listener, server)
+	return listener, nil
 }
 
 func startCronProcess(
 	cfg *config.Config,
 	dbInstance awsrds.RDSInstance,
+	parameterGroupSource *rdsbroker.ParameterGroupSource,
+	broker *rdsbroker.RDSBroker,
 	logger lager.Logger,
 ) {
-	cronProcess := cron.NewProcess(cfg, dbInstance, logger)
+	cronProcess := cron.NewProcess(cfg, dbInstance, parameterGroupSource, broker, logger)
 	go stopOnSignal(cronProcess)
 
 	logger.Info("cron.starting")
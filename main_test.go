@@ -1,8 +1,10 @@
 package main
 
 import (
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"time"
 
 	"code.cloudfoundry.org/lager/v3"
 	"github.com/alphagov/paas-rds-broker/config"
@@ -30,6 +32,204 @@ var _ = Describe("Main", func() {
 
 			Expect(w.Code).To(Equal(200))
 		})
+
+		It("has a deep healthcheck endpoint that returns 200 when the SQL engines are healthy", func() {
+			handler := buildHTTPHandler(
+				&rdsbroker.RDSBroker{},
+				lager.NewLogger("main.test"),
+				&config.Config{},
+			)
+			req, err := http.NewRequest("GET", "http://example.com/healthcheck/deep", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			Expect(w.Code).To(Equal(200))
+			Expect(w.Body.String()).To(MatchJSON(`{"sql_engines": []}`))
+		})
+
+		It("requires broker credentials for the admin endpoints", func() {
+			handler := buildHTTPHandler(
+				&rdsbroker.RDSBroker{},
+				lager.NewLogger("main.test"),
+				&config.Config{Username: "user", Password: "pass"},
+			)
+			req, err := http.NewRequest("GET", "http://example.com/admin/instances", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			Expect(w.Code).To(Equal(401))
+		})
+
+		Context("when a separate admin listener is configured", func() {
+			var cfg *config.Config
+
+			BeforeEach(func() {
+				cfg = &config.Config{
+					Username: "user",
+					Password: "pass",
+					Admin: &config.AdminConfig{
+						Listen:   []string{"127.0.0.1:3001"},
+						Username: "admin",
+						Password: "adminpass",
+					},
+				}
+			})
+
+			It("does not serve the admin endpoints on the broker API handler", func() {
+				handler := buildHTTPHandler(&rdsbroker.RDSBroker{}, lager.NewLogger("main.test"), cfg)
+				req, err := http.NewRequest("GET", "http://example.com/admin/instances", nil)
+				Expect(err).NotTo(HaveOccurred())
+				req.SetBasicAuth("user", "pass")
+
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+
+				Expect(w.Code).To(Equal(404))
+			})
+
+			It("requires the admin credentials for the admin endpoints", func() {
+				handler := buildAdminHTTPHandler(&rdsbroker.RDSBroker{}, cfg)
+				req, err := http.NewRequest("GET", "http://example.com/admin/instances", nil)
+				Expect(err).NotTo(HaveOccurred())
+				req.SetBasicAuth("user", "pass")
+
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+
+				Expect(w.Code).To(Equal(401))
+			})
+
+			It("does not serve the broker API on the admin handler", func() {
+				handler := buildAdminHTTPHandler(&rdsbroker.RDSBroker{}, cfg)
+				req, err := http.NewRequest("GET", "http://example.com/v2/catalog", nil)
+				Expect(err).NotTo(HaveOccurred())
+				req.SetBasicAuth("admin", "adminpass")
+
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+
+				Expect(w.Code).To(Equal(404))
+			})
+		})
+
+		Context("when a base path is configured", func() {
+			var handler http.Handler
+
+			BeforeEach(func() {
+				handler = buildHTTPHandler(
+					&rdsbroker.RDSBroker{},
+					lager.NewLogger("main.test"),
+					&config.Config{Username: "user", Password: "pass", BasePath: "/brokers/rds"},
+				)
+			})
+
+			It("serves the endpoints under the base path", func() {
+				req, err := http.NewRequest("GET", "http://example.com/brokers/rds/healthcheck", nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+				Expect(w.Code).To(Equal(200))
+
+				req, err = http.NewRequest("GET", "http://example.com/brokers/rds/admin/instances", nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				w = httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+				Expect(w.Code).To(Equal(401))
+
+				req, err = http.NewRequest("GET", "http://example.com/brokers/rds/v2/catalog", nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				w = httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+				Expect(w.Code).To(Equal(401))
+			})
+
+			It("serves the admin endpoints under the base path on a separate admin listener", func() {
+				adminHandler := buildAdminHTTPHandler(&rdsbroker.RDSBroker{}, &config.Config{
+					BasePath: "/brokers/rds",
+					Admin:    &config.AdminConfig{Username: "admin", Password: "adminpass"},
+				})
+
+				req, err := http.NewRequest("GET", "http://example.com/brokers/rds/healthcheck", nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				w := httptest.NewRecorder()
+				adminHandler.ServeHTTP(w, req)
+				Expect(w.Code).To(Equal(200))
+
+				req, err = http.NewRequest("GET", "http://example.com/brokers/rds/admin/instances", nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				w = httptest.NewRecorder()
+				adminHandler.ServeHTTP(w, req)
+				Expect(w.Code).To(Equal(401))
+
+				req, err = http.NewRequest("GET", "http://example.com/admin/instances", nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				w = httptest.NewRecorder()
+				adminHandler.ServeHTTP(w, req)
+				Expect(w.Code).To(Equal(404))
+			})
+
+			It("does not serve the endpoints at the root", func() {
+				req, err := http.NewRequest("GET", "http://example.com/healthcheck", nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+				Expect(w.Code).To(Equal(404))
+			})
+		})
+	})
+
+	Describe("limiting concurrent connections", func() {
+		var (
+			listener net.Listener
+			conns    chan net.Conn
+		)
+
+		BeforeEach(func() {
+			tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			listener = newLimitListener(tcpListener, 1)
+
+			conns = make(chan net.Conn, 2)
+			go func() {
+				for {
+					conn, err := listener.Accept()
+					if err != nil {
+						return
+					}
+					conns <- conn
+				}
+			}()
+		})
+
+		AfterEach(func() {
+			listener.Close()
+		})
+
+		It("accepts another connection only once an open one is closed", func() {
+			for i := 0; i < 2; i++ {
+				client, err := net.Dial("tcp", listener.Addr().String())
+				Expect(err).NotTo(HaveOccurred())
+				defer client.Close()
+			}
+
+			var first net.Conn
+			Eventually(conns).Should(Receive(&first))
+			Consistently(conns, 200*time.Millisecond).ShouldNot(Receive())
+
+			first.Close()
+			Eventually(conns).Should(Receive())
+		})
 	})
 
 })
@@ -0,0 +1,110 @@
+package rdsbroker
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+const additionalDatabasesSeparator = ":"
+
+var additionalDatabaseNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// reservedDatabaseNames are the databases which postgres and RDS keep for
+// themselves.
+var reservedDatabaseNames = []string{"postgres", "template0", "template1", "rdsadmin"}
+
+// validateAdditionalDatabases checks the names of the additional databases
+// requested by users, which have to be plain identifiers so that they can be
+// recorded in a tag.
+func validateAdditionalDatabases(names []string) []error {
+	problems := []error{}
+	for i, name := range names {
+		if !additionalDatabaseNamePattern.MatchString(name) {
+			problems = append(problems, fmt.Errorf("additional_databases: '%s' must start with a lowercase letter and contain only lowercase letters, digits and underscores, up to 63 characters", name))
+		} else if containsString(reservedDatabaseNames, name) {
+			problems = append(problems, fmt.Errorf("additional_databases: '%s' is reserved", name))
+		} else if containsString(names[:i], name) {
+			problems = append(problems, fmt.Errorf("additional_databases: '%s' is listed more than once", name))
+		}
+	}
+	return problems
+}
+
+// mergeAdditionalDatabases adds the requested databases to those the
+// instance already has. Databases are never dropped by an update, as that
+// would lose their data.
+func mergeAdditionalDatabases(servicePlan ServicePlan, dbName string, existing []string, requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return existing, nil
+	}
+	if aws.StringValue(servicePlan.RDSProperties.Engine) != "postgres" {
+		return nil, newUserError(ErrCodeUnsupportedByEngine, "additional_databases is only supported for postgres")
+	}
+	if containsString(requested, dbName) {
+		return nil, newUserError(ErrCodeInvalidParameters, "additional_databases: '%s' is the instance's main database", dbName)
+	}
+
+	merged := mergeStrings(existing, requested)
+	if len(packAdditionalDatabases(merged)) > maxRDSTagValueLength {
+		return nil, newUserError(ErrCodeInvalidParameters, "additional_databases: there are too many databases for the broker to record")
+	}
+	return merged, nil
+}
+
+func packAdditionalDatabases(databases []string) string {
+	return strings.Join(databases, additionalDatabasesSeparator)
+}
+
+func unpackAdditionalDatabases(packedDatabases string) []string {
+	if packedDatabases == "" {
+		return []string{}
+	}
+	return strings.Split(packedDatabases, additionalDatabasesSeparator)
+}
+
+// ensureAdditionalDatabases creates the additional databases recorded on an
+// instance which don't exist yet.
+func (b *RDSBroker) ensureAdditionalDatabases(instanceID string, dbInstance *rds.DBInstance, tagsByName map[string]string) error {
+	additionalDatabases := unpackAdditionalDatabases(tagsByName[awsrds.TagAdditionalDatabases])
+	if len(additionalDatabases) == 0 || aws.StringValue(dbInstance.Engine) != "postgres" {
+		return nil
+	}
+
+	b.logger.Debug("ensure-additional-databases", lager.Data{
+		instanceIDLogKey: instanceID,
+		"databases":      additionalDatabases,
+	})
+
+	dbName := b.dbNameFromDBInstance(instanceID, dbInstance)
+	sqlEngine, err := b.openSQLEngineForDBInstance(instanceID, dbName, dbInstance)
+	if err != nil {
+		return err
+	}
+	defer sqlEngine.Close()
+
+	for _, additionalDatabase := range additionalDatabases {
+		if err := sqlEngine.CreateDatabase(additionalDatabase); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bindingDatabase is the database a binding is made for: the instance's main
+// database, unless one of its additional databases is asked for.
+func bindingDatabase(dbName string, requested string, tagsByName map[string]string) (string, error) {
+	if requested == "" || requested == dbName {
+		return dbName, nil
+	}
+	if !containsString(unpackAdditionalDatabases(tagsByName[awsrds.TagAdditionalDatabases]), requested) {
+		return "", fmt.Errorf("Database '%s' is not one of the instance's databases", requested)
+	}
+	return requested, nil
+}
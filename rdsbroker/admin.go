@@ -0,0 +1,694 @@
+package rdsbroker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// InstanceDefinition is everything the broker knows about one of its
+// service instances, in a form which can be used to recreate the service
+// instance in a rebuilt platform.
+type InstanceDefinition struct {
+	InstanceID           string                 `json:"instance_id"`
+	ServiceID            string                 `json:"service_id"`
+	PlanID               string                 `json:"plan_id"`
+	OrganizationID       string                 `json:"organization_id"`
+	SpaceID              string                 `json:"space_id"`
+	DBInstanceIdentifier string                 `json:"db_instance_identifier"`
+	Parameters           map[string]interface{} `json:"parameters"`
+	Tags                 map[string]string      `json:"tags"`
+	Bindings             []BindingMetadata      `json:"bindings"`
+}
+
+// InstanceParameterGroup is the parameter group a service instance's DB
+// instance is using, with the parameters it sets.
+type InstanceParameterGroup struct {
+	InstanceID         string              `json:"instance_id"`
+	ParameterGroupName string              `json:"parameter_group_name"`
+	Parameters         []InstanceParameter `json:"parameters"`
+}
+
+// InstanceParameter is a single parameter of a parameter group. Modified is
+// true when the value differs from the engine default.
+type InstanceParameter struct {
+	Name         string `json:"name"`
+	Value        string `json:"value"`
+	Source       string `json:"source"`
+	ApplyType    string `json:"apply_type"`
+	IsModifiable bool   `json:"is_modifiable"`
+	Modified     bool   `json:"modified"`
+}
+
+// DeprecatedPlanInstance is a service instance still on a deprecated plan.
+type DeprecatedPlanInstance struct {
+	InstanceID           string `json:"instance_id"`
+	DBInstanceIdentifier string `json:"db_instance_identifier"`
+	PlanID               string `json:"plan_id"`
+	PlanName             string `json:"plan_name"`
+	OrganizationID       string `json:"organization_id"`
+	SpaceID              string `json:"space_id"`
+}
+
+// InstanceStatus is the state of a service instance's DB instance, with any
+// work the broker or RDS still has to do on it.
+type InstanceStatus struct {
+	InstanceID              string                       `json:"instance_id"`
+	DBInstanceIdentifier    string                       `json:"db_instance_identifier"`
+	DBInstanceStatus        string                       `json:"db_instance_status"`
+	State                   string                       `json:"state"`
+	PendingTags             map[string]string            `json:"pending_tags"`
+	ParameterApplyStatus    string                       `json:"parameter_apply_status"`
+	RebootRequired          bool                         `json:"reboot_required"`
+	PendingRebootParameters []string                     `json:"pending_reboot_parameters"`
+	PendingMaintenance      []InstancePendingMaintenance `json:"pending_maintenance"`
+	MaxAgeExceeded          bool                         `json:"max_age_exceeded"`
+}
+
+// InstancePendingMaintenance is a maintenance action RDS has scheduled for a
+// DB instance.
+type InstancePendingMaintenance struct {
+	Action           string     `json:"action"`
+	Description      string     `json:"description"`
+	AutoAppliedAfter *time.Time `json:"auto_applied_after,omitempty"`
+	ForcedApplyDate  *time.Time `json:"forced_apply_date,omitempty"`
+	CurrentApplyDate *time.Time `json:"current_apply_date,omitempty"`
+}
+
+// pendingTags are the tags the broker sets on a DB instance while it has
+// work left to do on it.
+var pendingTags = []string{
+	StateUpdateSettings,
+	StateReboot,
+	StateResetUserPassword,
+	awsrds.TagExtensionUpdateFor,
+	awsrds.TagDatabasesToPurge,
+	awsrds.TagOperationLease,
+	awsrds.TagPurgeAfter,
+	awsrds.TagUndeleteRequested,
+}
+
+// AdminHandler serves the operator-only endpoints of the broker. It does no
+// authentication of its own.
+func (b *RDSBroker) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/instances", b.handleExportInstances)
+	mux.HandleFunc("/admin/instances/", b.handleInstance)
+	mux.HandleFunc("/admin/status", b.handleInstanceStatuses)
+	mux.HandleFunc("/admin/catalog/orderability", b.handleCatalogOrderability)
+	mux.HandleFunc("/admin/deprecated-plans", b.handleDeprecatedPlanInstances)
+	mux.HandleFunc("/admin/backups", b.handleBackupStatuses)
+	mux.HandleFunc("/admin/metrics", b.handleMetrics)
+	mux.HandleFunc("/admin/credentials/check", b.handleCheckCredentials)
+	mux.HandleFunc("/admin/tags/reconcile", b.handleReconcileTags)
+	mux.HandleFunc("/admin/minor-upgrades", b.handleMinorUpgrades)
+	return mux
+}
+
+func (b *RDSBroker) handleExportInstances(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	definitions, err := b.ExportInstances()
+	if err != nil {
+		b.logger.Error("admin.export-instances", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(definitions)
+}
+
+func (b *RDSBroker) handleInstanceStatuses(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	statuses, err := b.InstanceStatuses()
+	if err != nil {
+		b.logger.Error("admin.instance-statuses", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
+
+func (b *RDSBroker) handleCatalogOrderability(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	problems, err := b.CheckCatalogOrderability()
+	if err != nil {
+		b.logger.Error("admin.catalog-orderability", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(problems)
+}
+
+func (b *RDSBroker) handleDeprecatedPlanInstances(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	instances, err := b.DeprecatedPlanInstances()
+	if err != nil {
+		b.logger.Error("admin.deprecated-plan-instances", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(instances)
+}
+
+func (b *RDSBroker) handleBackupStatuses(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	statuses, err := b.BackupStatuses()
+	if err != nil {
+		b.logger.Error("admin.backup-statuses", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
+
+func (b *RDSBroker) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	tagWrites, err := b.queuedTagWrites()
+	if err != nil {
+		b.logger.Error("admin.metrics", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	b.writeInstanceMetrics(w)
+	if driftMetrics, ok := b.parameterGroupsSelector.(driftMetricsWriter); ok {
+		driftMetrics.writeDriftMetrics(w)
+	}
+	writeTagWriteMetrics(w, tagWrites)
+}
+
+// handleCheckCredentials starts a check of the master credentials of every
+// instance in the background, as it connects to each of them in turn.
+func (b *RDSBroker) handleCheckCredentials(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	go b.CheckAndRotateCredentials()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (b *RDSBroker) handleReconcileTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := b.ForceTagWrites(); err != nil {
+		b.logger.Error("admin.reconcile-tags", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (b *RDSBroker) handleInstance(w http.ResponseWriter, r *http.Request) {
+	pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/admin/instances/"), "/")
+	if len(pathParts) != 2 || pathParts[0] == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch pathParts[1] {
+	case "parameters":
+		b.handleInstanceParameters(w, r, pathParts[0])
+	case "undelete":
+		b.handleUndeleteInstance(w, r, pathParts[0])
+	case "failover":
+		b.handleFailover(w, r, pathParts[0])
+	case "events":
+		b.handleInstanceEvents(w, r, pathParts[0])
+	case "logs":
+		b.handleInstanceLogs(w, r, pathParts[0])
+	case "log-files":
+		b.handleInstanceLogFiles(w, r, pathParts[0])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (b *RDSBroker) handleInstanceParameters(w http.ResponseWriter, r *http.Request, instanceID string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	parameterGroup, err := b.InstanceParameters(instanceID)
+	if err == awsrds.ErrDBInstanceDoesNotExist {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		b.logger.Error("admin.instance-parameters", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(parameterGroup)
+}
+
+func (b *RDSBroker) handleUndeleteInstance(w http.ResponseWriter, r *http.Request, instanceID string) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := b.UndeleteInstance(instanceID)
+	if err == awsrds.ErrDBInstanceDoesNotExist || err == ErrNotSoftDeleted {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		b.logger.Error("admin.undelete-instance", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleInstanceEvents serves the events of the last day, or of the window
+// given by the RFC 3339 `since` and `until` query parameters.
+func (b *RDSBroker) handleInstanceEvents(w http.ResponseWriter, r *http.Request, instanceID string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	until := time.Now()
+	if value := r.URL.Query().Get("until"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, "Invalid until: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		until = parsed
+	}
+	since := until.Add(-24 * time.Hour)
+	if value := r.URL.Query().Get("since"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, "Invalid since: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		since = parsed
+	}
+	if !since.Before(until) {
+		http.Error(w, "since must be before until", http.StatusBadRequest)
+		return
+	}
+
+	events, err := b.InstanceEvents(instanceID, since, until)
+	if err == awsrds.ErrDBInstanceDoesNotExist {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		b.logger.Error("admin.instance-events", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+func (b *RDSBroker) handleInstanceLogFiles(w http.ResponseWriter, r *http.Request, instanceID string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	logFiles, err := b.ListInstanceLogFiles(instanceID)
+	if err == awsrds.ErrDBInstanceDoesNotExist {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		b.logger.Error("admin.instance-log-files", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(logFiles)
+}
+
+// handleInstanceLogs streams the latest log file of the type given by the
+// `type` query parameter, `slowquery` by default, as plain text.
+func (b *RDSBroker) handleInstanceLogs(w http.ResponseWriter, r *http.Request, instanceID string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	logType := r.URL.Query().Get("type")
+	if logType == "" {
+		logType = "slowquery"
+	}
+	if logType != "slowquery" && logType != "general" {
+		http.Error(w, "Invalid type: must be slowquery or general", http.StatusBadRequest)
+		return
+	}
+
+	logFile, err := b.LatestInstanceLogFile(instanceID, logType)
+	if err == awsrds.ErrDBInstanceDoesNotExist || err == ErrNoLogFiles {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		b.logger.Error("admin.instance-logs", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// log files can take longer to stream than the server's write timeout
+	// allows for a whole response
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		b.logger.Error("admin.instance-logs.clear-write-deadline", err, lager.Data{instanceIDLogKey: instanceID})
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Log-File", logFile.Name)
+	w.Header().Set("Last-Modified", logFile.LastWritten.Format(http.TimeFormat))
+	if err := b.DownloadInstanceLogFile(logFile, w); err != nil {
+		// the status has already been sent, so all that can be done is to
+		// stop writing and record why
+		b.logger.Error("admin.instance-logs", err, lager.Data{instanceIDLogKey: instanceID})
+	}
+}
+
+func (b *RDSBroker) handleFailover(w http.ResponseWriter, r *http.Request, instanceID string) {
+	var (
+		failoverTest *FailoverTest
+		err          error
+		status       = http.StatusOK
+	)
+	switch r.Method {
+	case http.MethodGet:
+		failoverTest, err = b.FailoverStatus(instanceID)
+	case http.MethodPost:
+		failoverTest, err = b.FailoverInstance(instanceID)
+		status = http.StatusAccepted
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err == awsrds.ErrDBInstanceDoesNotExist {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err == ErrNotMultiAZ || err == ErrFailoverUnavailable {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		b.logger.Error("admin.failover", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(failoverTest)
+}
+
+// InstanceParameters lists the parameters set by the parameter group of a
+// service instance's DB instance. Parameters without a value are left out.
+func (b *RDSBroker) InstanceParameters(instanceID string) (*InstanceParameterGroup, error) {
+	dbInstance, err := b.dbInstance.Describe(b.dbInstanceIdentifier(instanceID))
+	if err != nil {
+		return nil, err
+	}
+	if len(dbInstance.DBParameterGroups) == 0 {
+		return nil, fmt.Errorf("DB instance for service instance '%s' has no parameter group", instanceID)
+	}
+	parameterGroupName := aws.StringValue(dbInstance.DBParameterGroups[0].DBParameterGroupName)
+
+	rdsParameters, err := b.dbInstance.DescribeParameters(parameterGroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	parameters := []InstanceParameter{}
+	for _, rdsParameter := range rdsParameters {
+		if rdsParameter.ParameterValue == nil {
+			continue
+		}
+		source := aws.StringValue(rdsParameter.Source)
+		parameters = append(parameters, InstanceParameter{
+			Name:         aws.StringValue(rdsParameter.ParameterName),
+			Value:        aws.StringValue(rdsParameter.ParameterValue),
+			Source:       source,
+			ApplyType:    aws.StringValue(rdsParameter.ApplyType),
+			IsModifiable: aws.BoolValue(rdsParameter.IsModifiable),
+			Modified:     source == "user",
+		})
+	}
+
+	b.logger.Info("admin.instance-parameters", lager.Data{
+		instanceIDLogKey:     instanceID,
+		"parameterGroupName": parameterGroupName,
+		"count":              len(parameters),
+	})
+
+	return &InstanceParameterGroup{
+		InstanceID:         instanceID,
+		ParameterGroupName: parameterGroupName,
+		Parameters:         parameters,
+	}, nil
+}
+
+// InstanceStatuses gives the status of every DB instance owned by this
+// broker, so that it doesn't have to be polled one instance at a time.
+func (b *RDSBroker) InstanceStatuses() ([]InstanceStatus, error) {
+	dbInstances, err := b.dbInstance.DescribeByTag(awsrds.TagBrokerName, b.brokerName)
+	if err != nil {
+		return nil, err
+	}
+
+	pendingMaintenanceActions, err := b.dbInstance.DescribePendingMaintenanceActions()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []InstanceStatus{}
+	for _, dbInstance := range dbInstances {
+		dbInstanceIdentifier := aws.StringValue(dbInstance.DBInstanceIdentifier)
+		tags, err := b.dbInstance.GetResourceTags(aws.StringValue(dbInstance.DBInstanceArn))
+		if err != nil {
+			return nil, err
+		}
+		tagsByName := awsrds.RDSTagsValues(tags)
+
+		instanceID := tagsByName[awsrds.TagChargeableEntity]
+		if instanceID == "" {
+			instanceID = b.dbInstanceIdentifierToServiceInstanceID(dbInstanceIdentifier)
+		}
+
+		instancePendingTags := map[string]string{}
+		for _, tagName := range pendingTags {
+			if value, ok := tagsByName[tagName]; ok {
+				instancePendingTags[tagName] = value
+			}
+		}
+		if tagsByName[awsrds.TagDBProxy] == dbProxyCreating {
+			instancePendingTags[awsrds.TagDBProxy] = dbProxyCreating
+		}
+
+		parameterApplyStatus := ""
+		if len(dbInstance.DBParameterGroups) > 0 {
+			parameterApplyStatus = aws.StringValue(dbInstance.DBParameterGroups[0].ParameterApplyStatus)
+		}
+		pendingReboot := awsrds.GetPendingReboot(dbInstance)
+		pendingRebootParams := []string{}
+		for _, parameterGroup := range pendingReboot.ParameterGroups {
+			parameters, err := b.dbInstance.DescribeParameters(parameterGroup)
+			if err != nil {
+				return nil, err
+			}
+			pendingRebootParams = append(pendingRebootParams, awsrds.PendingRebootParameters(parameters)...)
+		}
+
+		pendingMaintenance := []InstancePendingMaintenance{}
+		for _, action := range pendingMaintenanceActions[aws.StringValue(dbInstance.DBInstanceArn)] {
+			pendingMaintenance = append(pendingMaintenance, InstancePendingMaintenance{
+				Action:           aws.StringValue(action.Action),
+				Description:      aws.StringValue(action.Description),
+				AutoAppliedAfter: action.AutoAppliedAfterDate,
+				ForcedApplyDate:  action.ForcedApplyDate,
+				CurrentApplyDate: action.CurrentApplyDate,
+			})
+		}
+
+		servicePlan, _ := b.catalog.FindServicePlan(tagsByName[awsrds.TagPlanID])
+
+		dbInstanceStatus := aws.StringValue(dbInstance.DBInstanceStatus)
+		statuses = append(statuses, InstanceStatus{
+			InstanceID:              instanceID,
+			DBInstanceIdentifier:    dbInstanceIdentifier,
+			DBInstanceStatus:        dbInstanceStatus,
+			State:                   string(rdsStatus2State[dbInstanceStatus]),
+			PendingTags:             instancePendingTags,
+			ParameterApplyStatus:    parameterApplyStatus,
+			RebootRequired:          pendingReboot.Required,
+			PendingRebootParameters: pendingRebootParams,
+			PendingMaintenance:      pendingMaintenance,
+			MaxAgeExceeded:          instanceAgeWarning(dbInstance, servicePlan, time.Now()) != "",
+		})
+	}
+
+	b.logger.Info("admin.instance-statuses", lager.Data{"count": len(statuses)})
+
+	return statuses, nil
+}
+
+// DeprecatedPlanInstances lists the service instances which are still on
+// deprecated plans, and so need moving before the plans can be removed.
+func (b *RDSBroker) DeprecatedPlanInstances() ([]DeprecatedPlanInstance, error) {
+	dbInstances, err := b.dbInstance.DescribeByTag(awsrds.TagBrokerName, b.brokerName)
+	if err != nil {
+		return nil, err
+	}
+
+	instances := []DeprecatedPlanInstance{}
+	for _, dbInstance := range dbInstances {
+		dbInstanceIdentifier := aws.StringValue(dbInstance.DBInstanceIdentifier)
+		tags, err := b.dbInstance.GetResourceTags(aws.StringValue(dbInstance.DBInstanceArn))
+		if err != nil {
+			return nil, err
+		}
+		tagsByName := awsrds.RDSTagsValues(tags)
+
+		servicePlan, ok := b.catalog.FindServicePlan(tagsByName[awsrds.TagPlanID])
+		if !ok || !servicePlan.Deprecated {
+			continue
+		}
+
+		instanceID := tagsByName[awsrds.TagChargeableEntity]
+		if instanceID == "" {
+			instanceID = b.dbInstanceIdentifierToServiceInstanceID(dbInstanceIdentifier)
+		}
+
+		instances = append(instances, DeprecatedPlanInstance{
+			InstanceID:           instanceID,
+			DBInstanceIdentifier: dbInstanceIdentifier,
+			PlanID:               servicePlan.ID,
+			PlanName:             servicePlan.Name,
+			OrganizationID:       tagsByName[awsrds.TagOrganizationID],
+			SpaceID:              tagsByName[awsrds.TagSpaceID],
+		})
+	}
+
+	b.logger.Info("admin.deprecated-plan-instances", lager.Data{"count": len(instances)})
+
+	return instances, nil
+}
+
+// ExportInstances describes every DB instance owned by this broker.
+func (b *RDSBroker) ExportInstances() ([]InstanceDefinition, error) {
+	dbInstances, err := b.dbInstance.DescribeByTag(awsrds.TagBrokerName, b.brokerName)
+	if err != nil {
+		return nil, err
+	}
+
+	definitions := []InstanceDefinition{}
+	for _, dbInstance := range dbInstances {
+		dbInstanceIdentifier := aws.StringValue(dbInstance.DBInstanceIdentifier)
+		tags, err := b.dbInstance.GetResourceTags(aws.StringValue(dbInstance.DBInstanceArn))
+		if err != nil {
+			return nil, err
+		}
+		tagsByName := awsrds.RDSTagsValues(tags)
+
+		instanceID := tagsByName[awsrds.TagChargeableEntity]
+		if instanceID == "" {
+			instanceID = b.dbInstanceIdentifierToServiceInstanceID(dbInstanceIdentifier)
+		}
+
+		bindings, err := b.instanceBindings(instanceID, tagsByName)
+		if err != nil {
+			return nil, err
+		}
+
+		parameters := map[string]interface{}{
+			"backup_retention_period":      aws.Int64Value(dbInstance.BackupRetentionPeriod),
+			"preferred_backup_window":      aws.StringValue(dbInstance.PreferredBackupWindow),
+			"preferred_maintenance_window": aws.StringValue(dbInstance.PreferredMaintenanceWindow),
+		}
+		if dbName := aws.StringValue(dbInstance.DBName); dbName != "" {
+			parameters["dbname"] = dbName
+		}
+		if extensions, ok := tagsByName[awsrds.TagExtensions]; ok && extensions != "" {
+			parameters["enable_extensions"] = unpackExtensions(extensions)
+		}
+		if skipFinalSnapshot, ok := tagsByName[awsrds.TagSkipFinalSnapshot]; ok {
+			parameters["skip_final_snapshot"] = skipFinalSnapshot == "true"
+		}
+		if additionalDatabases := tagsByName[awsrds.TagAdditionalDatabases]; additionalDatabases != "" {
+			parameters["additional_databases"] = unpackAdditionalDatabases(additionalDatabases)
+		}
+
+		definitions = append(definitions, InstanceDefinition{
+			InstanceID:           instanceID,
+			ServiceID:            tagsByName[awsrds.TagServiceID],
+			PlanID:               tagsByName[awsrds.TagPlanID],
+			OrganizationID:       tagsByName[awsrds.TagOrganizationID],
+			SpaceID:              tagsByName[awsrds.TagSpaceID],
+			DBInstanceIdentifier: dbInstanceIdentifier,
+			Parameters:           parameters,
+			Tags:                 tagsByName,
+			Bindings:             bindings,
+		})
+	}
+
+	b.logger.Info("admin.export-instances", lager.Data{"count": len(definitions)})
+
+	return definitions, nil
+}
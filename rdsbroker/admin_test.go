@@ -0,0 +1,960 @@
+package rdsbroker_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+	"github.com/alphagov/paas-rds-broker/statestore"
+	statestorefake "github.com/alphagov/paas-rds-broker/statestore/fakes"
+)
+
+var _ = Describe("Admin", func() {
+	var (
+		rdsInstance *rdsfake.FakeRDSInstance
+		rdsBroker   *RDSBroker
+		logger      lager.Logger
+	)
+
+	BeforeEach(func() {
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		logger = lagertest.NewTestLogger("admin_test")
+		rdsBroker = newTestBroker(testConfig(), rdsInstance, nil, logger)
+
+		rdsInstance.DescribeByTagReturns([]*rds.DBInstance{
+			{
+				DBInstanceIdentifier:       aws.String("cf-instance-1"),
+				DBInstanceArn:              aws.String("arn:aws:rds:rds-region:1234567890:db:cf-instance-1"),
+				DBName:                     aws.String("cf_instance_1"),
+				BackupRetentionPeriod:      aws.Int64(7),
+				PreferredBackupWindow:      aws.String("01:00-02:00"),
+				PreferredMaintenanceWindow: aws.String("sun:03:00-sun:04:00"),
+			},
+		}, nil)
+		rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+			"Broker Name":       "mybroker",
+			"chargeable_entity": "instance-1",
+			"Service ID":        "Service-1",
+			"Plan ID":           "Plan-1",
+			"Organization ID":   "organization-id",
+			"Space ID":          "space-id",
+			"SkipFinalSnapshot": "true",
+			"Extensions":        "postgis:pg_stat_statements",
+		}), nil)
+	})
+
+	Describe("ExportInstances", func() {
+		It("describes the instances owned by this broker", func() {
+			definitions, err := rdsBroker.ExportInstances()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rdsInstance.DescribeByTagCallCount()).To(Equal(1))
+			tagKey, tagValue, _ := rdsInstance.DescribeByTagArgsForCall(0)
+			Expect(tagKey).To(Equal("Broker Name"))
+			Expect(tagValue).To(Equal("mybroker"))
+
+			Expect(definitions).To(HaveLen(1))
+			definition := definitions[0]
+			Expect(definition.InstanceID).To(Equal("instance-1"))
+			Expect(definition.ServiceID).To(Equal("Service-1"))
+			Expect(definition.PlanID).To(Equal("Plan-1"))
+			Expect(definition.OrganizationID).To(Equal("organization-id"))
+			Expect(definition.SpaceID).To(Equal("space-id"))
+			Expect(definition.DBInstanceIdentifier).To(Equal("cf-instance-1"))
+			Expect(definition.Parameters).To(Equal(map[string]interface{}{
+				"backup_retention_period":      int64(7),
+				"preferred_backup_window":      "01:00-02:00",
+				"preferred_maintenance_window": "sun:03:00-sun:04:00",
+				"dbname":                       "cf_instance_1",
+				"enable_extensions":            []string{"postgis", "pg_stat_statements"},
+				"skip_final_snapshot":          true,
+			}))
+			Expect(definition.Tags).To(HaveKeyWithValue("Broker Name", "mybroker"))
+		})
+
+		It("falls back to the DB instance identifier for the instance ID", func() {
+			rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+				"Broker Name": "mybroker",
+			}), nil)
+
+			definitions, err := rdsBroker.ExportInstances()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(definitions[0].InstanceID).To(Equal("instance-1"))
+		})
+
+		It("lists the recorded bindings of the instances, oldest first", func() {
+			rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+				"Broker Name":       "mybroker",
+				"Binding binding-2": "app= created=2024-02-01T00:00:00Z read_only=true",
+				"Binding binding-1": "app=app-1 created=2024-01-01T00:00:00Z read_only=false",
+			}), nil)
+
+			definitions, err := rdsBroker.ExportInstances()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(definitions[0].Bindings).To(Equal([]BindingMetadata{
+				{
+					BindingID: "binding-1",
+					AppGUID:   "app-1",
+					CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				},
+				{
+					BindingID: "binding-2",
+					CreatedAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+					ReadOnly:  true,
+				},
+			}))
+		})
+
+		It("adds the bindings recorded in the state store", func() {
+			rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+				"Broker Name":       "mybroker",
+				"chargeable_entity": "instance-1",
+				"Binding binding-1": "app=app-1 created=2024-01-01T00:00:00Z read_only=false",
+			}), nil)
+			stateStore := &statestorefake.FakeStateStore{}
+			stateStore.ListBindingsReturns([]statestore.Binding{
+				{
+					InstanceID: "instance-1",
+					BindingID:  "binding-2",
+					AppGUID:    "app-2",
+					CreatedAt:  time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+				},
+			}, nil)
+			rdsBroker.SetStateStore(stateStore)
+
+			definitions, err := rdsBroker.ExportInstances()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stateStore.ListBindingsArgsForCall(0)).To(Equal("instance-1"))
+			Expect(definitions[0].Bindings).To(Equal([]BindingMetadata{
+				{
+					BindingID: "binding-1",
+					AppGUID:   "app-1",
+					CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				},
+				{
+					BindingID: "binding-2",
+					AppGUID:   "app-2",
+					CreatedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+				},
+			}))
+		})
+
+		It("returns an error if the instances can't be listed", func() {
+			rdsInstance.DescribeByTagReturns(nil, errors.New("boom"))
+
+			_, err := rdsBroker.ExportInstances()
+			Expect(err).To(MatchError("boom"))
+		})
+	})
+
+	Describe("DeprecatedPlanInstances", func() {
+		BeforeEach(func() {
+			config := testConfig()
+			config.Catalog.Services[0].Plans[0].Deprecated = true
+			rdsBroker = newTestBroker(config, rdsInstance, nil, logger)
+		})
+
+		It("lists the instances on deprecated plans", func() {
+			instances, err := rdsBroker.DeprecatedPlanInstances()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instances).To(Equal([]DeprecatedPlanInstance{{
+				InstanceID:           "instance-1",
+				DBInstanceIdentifier: "cf-instance-1",
+				PlanID:               "Plan-1",
+				PlanName:             "small",
+				OrganizationID:       "organization-id",
+				SpaceID:              "space-id",
+			}}))
+		})
+
+		It("leaves out instances on current plans", func() {
+			rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+				"Broker Name":       "mybroker",
+				"chargeable_entity": "instance-1",
+				"Plan ID":           "Plan-2",
+			}), nil)
+
+			instances, err := rdsBroker.DeprecatedPlanInstances()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instances).To(BeEmpty())
+		})
+	})
+
+	Describe("InstanceStatuses", func() {
+		BeforeEach(func() {
+			rdsInstance.DescribeByTagReturns([]*rds.DBInstance{
+				{
+					DBInstanceIdentifier: aws.String("cf-instance-1"),
+					DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-instance-1"),
+					DBInstanceStatus:     aws.String("modifying"),
+					DBParameterGroups: []*rds.DBParameterGroupStatus{
+						{
+							DBParameterGroupName: aws.String("rdsbroker-postgres12-mybroker"),
+							ParameterApplyStatus: aws.String("pending-reboot"),
+						},
+					},
+				},
+			}, nil)
+			rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+				"Broker Name":        "mybroker",
+				"chargeable_entity":  "instance-1",
+				"PendingReboot":      "true",
+				"Databases To Purge": "olddb",
+				"DB Proxy":           "creating",
+				"Plan ID":            "Plan-1",
+			}), nil)
+			rdsInstance.DescribeParametersReturns([]*rds.Parameter{
+				{ParameterName: aws.String("shared_preload_libraries"), Source: aws.String("user"), ApplyType: aws.String("static")},
+				{ParameterName: aws.String("work_mem"), Source: aws.String("user"), ApplyType: aws.String("dynamic")},
+			}, nil)
+			rdsInstance.DescribePendingMaintenanceActionsReturns(map[string][]*rds.PendingMaintenanceAction{
+				"arn:aws:rds:rds-region:1234567890:db:cf-instance-1": {
+					{Action: aws.String("system-update"), Description: aws.String("New Operating System update is available")},
+				},
+			}, nil)
+		})
+
+		It("composes the status of the instances owned by this broker", func() {
+			statuses, err := rdsBroker.InstanceStatuses()
+			Expect(err).ToNot(HaveOccurred())
+
+			tagKey, tagValue, _ := rdsInstance.DescribeByTagArgsForCall(0)
+			Expect(tagKey).To(Equal("Broker Name"))
+			Expect(tagValue).To(Equal("mybroker"))
+
+			Expect(statuses).To(HaveLen(1))
+			status := statuses[0]
+			Expect(status.InstanceID).To(Equal("instance-1"))
+			Expect(status.DBInstanceIdentifier).To(Equal("cf-instance-1"))
+			Expect(status.DBInstanceStatus).To(Equal("modifying"))
+			Expect(status.State).To(Equal("in progress"))
+			Expect(status.PendingTags).To(Equal(map[string]string{
+				"PendingReboot":      "true",
+				"Databases To Purge": "olddb",
+				"DB Proxy":           "creating",
+			}))
+			Expect(status.ParameterApplyStatus).To(Equal("pending-reboot"))
+			Expect(status.RebootRequired).To(BeTrue())
+			Expect(status.PendingRebootParameters).To(Equal([]string{"shared_preload_libraries"}))
+			Expect(rdsInstance.DescribeParametersArgsForCall(0)).To(Equal("rdsbroker-postgres12-mybroker"))
+			Expect(status.PendingMaintenance).To(Equal([]InstancePendingMaintenance{
+				{Action: "system-update", Description: "New Operating System update is available"},
+			}))
+		})
+
+		It("returns an error if the pending maintenance can't be described", func() {
+			rdsInstance.DescribePendingMaintenanceActionsReturns(nil, errors.New("boom"))
+
+			_, err := rdsBroker.InstanceStatuses()
+			Expect(err).To(MatchError("boom"))
+		})
+	})
+
+	Describe("InstanceParameters", func() {
+		BeforeEach(func() {
+			rdsInstance.DescribeReturns(&rds.DBInstance{
+				DBInstanceIdentifier: aws.String("cf-instance-1"),
+				DBParameterGroups: []*rds.DBParameterGroupStatus{
+					{DBParameterGroupName: aws.String("rdsbroker-postgres12-mybroker")},
+				},
+			}, nil)
+			rdsInstance.DescribeParametersReturns([]*rds.Parameter{
+				{
+					ParameterName:  aws.String("max_connections"),
+					ParameterValue: aws.String("500"),
+					Source:         aws.String("user"),
+					ApplyType:      aws.String("static"),
+					IsModifiable:   aws.Bool(true),
+				},
+				{
+					ParameterName:  aws.String("work_mem"),
+					ParameterValue: aws.String("4096"),
+					Source:         aws.String("engine-default"),
+					ApplyType:      aws.String("dynamic"),
+					IsModifiable:   aws.Bool(true),
+				},
+				{
+					ParameterName: aws.String("unset_parameter"),
+					Source:        aws.String("engine-default"),
+				},
+			}, nil)
+		})
+
+		It("lists the parameters of the instance's parameter group", func() {
+			parameterGroup, err := rdsBroker.InstanceParameters("instance-1")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rdsInstance.DescribeArgsForCall(0)).To(Equal("cf-instance-1"))
+			Expect(rdsInstance.DescribeParametersArgsForCall(0)).To(Equal("rdsbroker-postgres12-mybroker"))
+
+			Expect(parameterGroup.InstanceID).To(Equal("instance-1"))
+			Expect(parameterGroup.ParameterGroupName).To(Equal("rdsbroker-postgres12-mybroker"))
+			Expect(parameterGroup.Parameters).To(Equal([]InstanceParameter{
+				{Name: "max_connections", Value: "500", Source: "user", ApplyType: "static", IsModifiable: true, Modified: true},
+				{Name: "work_mem", Value: "4096", Source: "engine-default", ApplyType: "dynamic", IsModifiable: true, Modified: false},
+			}))
+		})
+
+		It("returns an error if the parameters can't be described", func() {
+			rdsInstance.DescribeParametersReturns(nil, errors.New("boom"))
+
+			_, err := rdsBroker.InstanceParameters("instance-1")
+			Expect(err).To(MatchError("boom"))
+		})
+
+		It("returns an error if the DB instance has no parameter group", func() {
+			rdsInstance.DescribeReturns(&rds.DBInstance{DBInstanceIdentifier: aws.String("cf-instance-1")}, nil)
+
+			_, err := rdsBroker.InstanceParameters("instance-1")
+			Expect(err).To(HaveOccurred())
+			Expect(rdsInstance.DescribeParametersCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("FailoverInstance", func() {
+		BeforeEach(func() {
+			rdsInstance.DescribeReturns(&rds.DBInstance{
+				DBInstanceIdentifier: aws.String("cf-instance-1"),
+				DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-instance-1"),
+				DBInstanceStatus:     aws.String("available"),
+				MultiAZ:              aws.Bool(true),
+			}, nil)
+		})
+
+		It("reboots the instance with a forced failover and tags the request time", func() {
+			failoverTest, err := rdsBroker.FailoverInstance("instance-1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(failoverTest.DBInstanceIdentifier).To(Equal("cf-instance-1"))
+			Expect(failoverTest.RequestedAt).ToNot(BeNil())
+
+			Expect(rdsInstance.DescribeArgsForCall(0)).To(Equal("cf-instance-1"))
+			Expect(rdsInstance.RebootCallCount()).To(Equal(1))
+			input := rdsInstance.RebootArgsForCall(0)
+			Expect(aws.StringValue(input.DBInstanceIdentifier)).To(Equal("cf-instance-1"))
+			Expect(aws.BoolValue(input.ForceFailover)).To(BeTrue())
+
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+			arn, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+			Expect(arn).To(Equal("arn:aws:rds:rds-region:1234567890:db:cf-instance-1"))
+			Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue("Failover Requested At", failoverTest.RequestedAt.Format(time.RFC3339)))
+		})
+
+		It("refuses instances which aren't Multi-AZ", func() {
+			rdsInstance.DescribeReturns(&rds.DBInstance{
+				DBInstanceIdentifier: aws.String("cf-instance-1"),
+				DBInstanceStatus:     aws.String("available"),
+				MultiAZ:              aws.Bool(false),
+			}, nil)
+
+			_, err := rdsBroker.FailoverInstance("instance-1")
+			Expect(err).To(Equal(ErrNotMultiAZ))
+			Expect(rdsInstance.RebootCallCount()).To(Equal(0))
+		})
+
+		It("refuses instances which aren't available", func() {
+			rdsInstance.DescribeReturns(&rds.DBInstance{
+				DBInstanceIdentifier: aws.String("cf-instance-1"),
+				DBInstanceStatus:     aws.String("modifying"),
+				MultiAZ:              aws.Bool(true),
+			}, nil)
+
+			_, err := rdsBroker.FailoverInstance("instance-1")
+			Expect(err).To(Equal(ErrFailoverUnavailable))
+			Expect(rdsInstance.RebootCallCount()).To(Equal(0))
+		})
+
+		It("refuses instances owned by another broker", func() {
+			rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+				"Broker Name": "otherbroker",
+			}), nil)
+
+			_, err := rdsBroker.FailoverInstance("instance-1")
+			Expect(err).To(Equal(awsrds.ErrDBInstanceDoesNotExist))
+			Expect(rdsInstance.RebootCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("FailoverStatus", func() {
+		var requestedAt time.Time
+
+		BeforeEach(func() {
+			requestedAt = time.Date(2023, 11, 14, 10, 0, 0, 0, time.UTC)
+			rdsInstance.DescribeReturns(&rds.DBInstance{
+				DBInstanceIdentifier: aws.String("cf-instance-1"),
+				DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-instance-1"),
+				DBInstanceStatus:     aws.String("available"),
+				MultiAZ:              aws.Bool(true),
+			}, nil)
+			rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+				"Broker Name":           "mybroker",
+				"Failover Requested At": requestedAt.Format(time.RFC3339),
+			}), nil)
+			rdsInstance.DescribeEventsReturns([]*rds.Event{
+				{
+					Date:            aws.Time(requestedAt.Add(50 * time.Second)),
+					EventCategories: aws.StringSlice([]string{"failover"}),
+					Message:         aws.String("Multi-AZ instance failover completed."),
+				},
+				{
+					Date:            aws.Time(requestedAt.Add(20 * time.Second)),
+					EventCategories: aws.StringSlice([]string{"availability"}),
+					Message:         aws.String("DB instance restarted"),
+				},
+				{
+					Date:            aws.Time(requestedAt.Add(5 * time.Second)),
+					EventCategories: aws.StringSlice([]string{"failover"}),
+					Message:         aws.String("Multi-AZ instance failover started."),
+				},
+				{
+					Date:            aws.Time(requestedAt.Add(-time.Hour)),
+					EventCategories: aws.StringSlice([]string{"failover"}),
+					Message:         aws.String("Multi-AZ instance failover completed."),
+				},
+			}, nil)
+		})
+
+		It("times the failover from the events since it was requested", func() {
+			failoverTest, err := rdsBroker.FailoverStatus("instance-1")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rdsInstance.DescribeEventsArgsForCall(0)).To(Equal("cf-instance-1"))
+			Expect(failoverTest.DBInstanceStatus).To(Equal("available"))
+			Expect(*failoverTest.RequestedAt).To(BeTemporally("==", requestedAt))
+			Expect(*failoverTest.FailoverStartedAt).To(BeTemporally("==", requestedAt.Add(5*time.Second)))
+			Expect(*failoverTest.FailoverCompletedAt).To(BeTemporally("==", requestedAt.Add(50*time.Second)))
+			Expect(*failoverTest.DurationSeconds).To(Equal(45.0))
+		})
+
+		It("leaves the failover times empty until the events appear", func() {
+			rdsInstance.DescribeEventsReturns([]*rds.Event{}, nil)
+
+			failoverTest, err := rdsBroker.FailoverStatus("instance-1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(failoverTest.RequestedAt).ToNot(BeNil())
+			Expect(failoverTest.FailoverStartedAt).To(BeNil())
+			Expect(failoverTest.FailoverCompletedAt).To(BeNil())
+			Expect(failoverTest.DurationSeconds).To(BeNil())
+		})
+
+		It("doesn't look for events when no failover has been requested", func() {
+			rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+				"Broker Name": "mybroker",
+			}), nil)
+
+			failoverTest, err := rdsBroker.FailoverStatus("instance-1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(failoverTest.RequestedAt).To(BeNil())
+			Expect(rdsInstance.DescribeEventsCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("ListInstanceLogFiles", func() {
+		BeforeEach(func() {
+			rdsInstance.DescribeReturns(&rds.DBInstance{
+				DBInstanceIdentifier: aws.String("cf-instance-1"),
+				Engine:               aws.String("postgres"),
+				DBParameterGroups: []*rds.DBParameterGroupStatus{
+					{DBParameterGroupName: aws.String("cf-postgres13-mybroker")},
+				},
+			}, nil)
+			rdsInstance.DescribeLogFilesReturns([]*rds.DescribeDBLogFilesDetails{
+				{
+					LogFileName: aws.String("error/postgresql.log.2023-11-14-22"),
+					LastWritten: aws.Int64(1700000000000),
+					Size:        aws.Int64(2048),
+				},
+				{
+					LogFileName: aws.String("error/postgresql.log.2023-11-14-21"),
+					LastWritten: aws.Int64(1699996400000),
+					Size:        aws.Int64(1024),
+				},
+			}, nil)
+			rdsInstance.DescribeParametersReturns([]*rds.Parameter{
+				{ParameterName: aws.String("rds.log_retention_period"), ParameterValue: aws.String("4320")},
+				{ParameterName: aws.String("log_min_duration_statement"), ParameterValue: aws.String("1000")},
+				{ParameterName: aws.String("log_statement")},
+				{ParameterName: aws.String("max_connections"), ParameterValue: aws.String("100")},
+			}, nil)
+		})
+
+		It("lists every log file with its size and when it was last written", func() {
+			logFiles, err := rdsBroker.ListInstanceLogFiles("instance-1")
+			Expect(err).ToNot(HaveOccurred())
+
+			id, filenameContains := rdsInstance.DescribeLogFilesArgsForCall(0)
+			Expect(id).To(Equal("cf-instance-1"))
+			Expect(filenameContains).To(BeEmpty())
+
+			Expect(logFiles.InstanceID).To(Equal("instance-1"))
+			Expect(logFiles.LogFiles).To(Equal([]InstanceLogFile{
+				{
+					DBInstanceIdentifier: "cf-instance-1",
+					Name:                 "error/postgresql.log.2023-11-14-22",
+					Size:                 2048,
+					LastWritten:          time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC),
+				},
+				{
+					DBInstanceIdentifier: "cf-instance-1",
+					Name:                 "error/postgresql.log.2023-11-14-21",
+					Size:                 1024,
+					LastWritten:          time.Date(2023, 11, 14, 21, 13, 20, 0, time.UTC),
+				},
+			}))
+			Expect(logFiles.TotalSize).To(Equal(int64(3072)))
+		})
+
+		It("includes the parameters which decide what is logged and for how long", func() {
+			logFiles, err := rdsBroker.ListInstanceLogFiles("instance-1")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rdsInstance.DescribeParametersArgsForCall(0)).To(Equal("cf-postgres13-mybroker"))
+			Expect(logFiles.LoggingParameters).To(Equal(map[string]string{
+				"rds.log_retention_period":   "4320",
+				"log_min_duration_statement": "1000",
+			}))
+		})
+
+		It("returns an error for instances owned by another broker", func() {
+			rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+				"Broker Name": "otherbroker",
+			}), nil)
+
+			_, err := rdsBroker.ListInstanceLogFiles("instance-1")
+			Expect(err).To(Equal(awsrds.ErrDBInstanceDoesNotExist))
+			Expect(rdsInstance.DescribeLogFilesCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("AdminHandler", func() {
+		It("serves the instance definitions as JSON", func() {
+			req := httptest.NewRequest("GET", "/admin/instances", nil)
+			w := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Header().Get("Content-Type")).To(Equal("application/json"))
+
+			var definitions []map[string]interface{}
+			Expect(json.Unmarshal(w.Body.Bytes(), &definitions)).To(Succeed())
+			Expect(definitions).To(HaveLen(1))
+			Expect(definitions[0]).To(HaveKeyWithValue("instance_id", "instance-1"))
+			Expect(definitions[0]).To(HaveKeyWithValue("plan_id", "Plan-1"))
+		})
+
+		It("serves the instance statuses as JSON", func() {
+			req := httptest.NewRequest("GET", "/admin/status", nil)
+			w := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Header().Get("Content-Type")).To(Equal("application/json"))
+
+			var statuses []map[string]interface{}
+			Expect(json.Unmarshal(w.Body.Bytes(), &statuses)).To(Succeed())
+			Expect(statuses).To(HaveLen(1))
+			Expect(statuses[0]).To(HaveKeyWithValue("instance_id", "instance-1"))
+		})
+
+		It("serves the catalog orderability problems as JSON", func() {
+			req := httptest.NewRequest("GET", "/admin/catalog/orderability", nil)
+			w := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Header().Get("Content-Type")).To(Equal("application/json"))
+
+			var problems []map[string]interface{}
+			Expect(json.Unmarshal(w.Body.Bytes(), &problems)).To(Succeed())
+			Expect(problems).To(HaveLen(2))
+			Expect(problems[0]).To(HaveKeyWithValue("plan_id", "Plan-1"))
+			Expect(problems[0]).To(HaveKeyWithValue("problem", "DB instance class db.t3.small can't be ordered for postgres 13"))
+		})
+
+		It("starts a check of the instances' credentials", func() {
+			rdsInstance.DescribeByTagReturns([]*rds.DBInstance{}, nil)
+			describeByTagCalls := rdsInstance.DescribeByTagCallCount()
+
+			req := httptest.NewRequest("POST", "/admin/credentials/check", nil)
+			w := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusAccepted))
+			Eventually(rdsInstance.DescribeByTagCallCount).Should(BeNumerically(">", describeByTagCalls))
+		})
+
+		It("only checks credentials on a POST", func() {
+			req := httptest.NewRequest("GET", "/admin/credentials/check", nil)
+			w := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+
+		It("writes the queued tags", func() {
+			req := httptest.NewRequest("POST", "/admin/tags/reconcile", nil)
+			w := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusNoContent))
+		})
+
+		It("serves an instance's parameters as JSON", func() {
+			rdsInstance.DescribeReturns(&rds.DBInstance{
+				DBInstanceIdentifier: aws.String("cf-instance-1"),
+				DBParameterGroups: []*rds.DBParameterGroupStatus{
+					{DBParameterGroupName: aws.String("rdsbroker-postgres12-mybroker")},
+				},
+			}, nil)
+			rdsInstance.DescribeParametersReturns([]*rds.Parameter{
+				{ParameterName: aws.String("max_connections"), ParameterValue: aws.String("500"), Source: aws.String("user")},
+			}, nil)
+
+			req := httptest.NewRequest("GET", "/admin/instances/instance-1/parameters", nil)
+			w := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Header().Get("Content-Type")).To(Equal("application/json"))
+
+			var parameterGroup map[string]interface{}
+			Expect(json.Unmarshal(w.Body.Bytes(), &parameterGroup)).To(Succeed())
+			Expect(parameterGroup).To(HaveKeyWithValue("parameter_group_name", "rdsbroker-postgres12-mybroker"))
+			Expect(parameterGroup["parameters"]).To(HaveLen(1))
+		})
+
+		It("returns 404 for the parameters of an unknown instance", func() {
+			rdsInstance.DescribeReturns(nil, awsrds.ErrDBInstanceDoesNotExist)
+
+			req := httptest.NewRequest("GET", "/admin/instances/unknown/parameters", nil)
+			w := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("accepts a request to undelete an instance", func() {
+			rdsInstance.DescribeReturns(&rds.DBInstance{
+				DBInstanceIdentifier: aws.String("cf-instance-1-deleted"),
+				DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-instance-1-deleted"),
+			}, nil)
+			rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+				"Broker Name": "mybroker",
+				"Purge After": "2030-01-01T00:00:00Z",
+			}), nil)
+
+			req := httptest.NewRequest("POST", "/admin/instances/instance-1/undelete", nil)
+			w := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusAccepted))
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+		})
+
+		It("returns 404 when undeleting an instance which has not been soft deleted", func() {
+			rdsInstance.DescribeReturns(nil, awsrds.ErrDBInstanceDoesNotExist)
+
+			req := httptest.NewRequest("POST", "/admin/instances/instance-1/undelete", nil)
+			w := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("accepts a request to fail over an instance", func() {
+			rdsInstance.DescribeReturns(&rds.DBInstance{
+				DBInstanceIdentifier: aws.String("cf-instance-1"),
+				DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-instance-1"),
+				DBInstanceStatus:     aws.String("available"),
+				MultiAZ:              aws.Bool(true),
+			}, nil)
+
+			req := httptest.NewRequest("POST", "/admin/instances/instance-1/failover", nil)
+			w := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusAccepted))
+			Expect(w.Header().Get("Content-Type")).To(Equal("application/json"))
+			Expect(rdsInstance.RebootCallCount()).To(Equal(1))
+		})
+
+		It("returns 409 when failing over an instance which isn't Multi-AZ", func() {
+			rdsInstance.DescribeReturns(&rds.DBInstance{
+				DBInstanceIdentifier: aws.String("cf-instance-1"),
+				DBInstanceStatus:     aws.String("available"),
+			}, nil)
+
+			req := httptest.NewRequest("POST", "/admin/instances/instance-1/failover", nil)
+			w := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusConflict))
+		})
+
+		It("serves the failover status as JSON", func() {
+			rdsInstance.DescribeReturns(&rds.DBInstance{
+				DBInstanceIdentifier: aws.String("cf-instance-1"),
+				DBInstanceStatus:     aws.String("rebooting"),
+			}, nil)
+
+			req := httptest.NewRequest("GET", "/admin/instances/instance-1/failover", nil)
+			w := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			var failoverTest map[string]interface{}
+			Expect(json.Unmarshal(w.Body.Bytes(), &failoverTest)).To(Succeed())
+			Expect(failoverTest).To(HaveKeyWithValue("db_instance_status", "rebooting"))
+		})
+
+		It("serves the events of an instance within the window as JSON", func() {
+			rdsInstance.DescribeReturns(&rds.DBInstance{
+				DBInstanceIdentifier: aws.String("cf-instance-1"),
+			}, nil)
+			rdsInstance.DescribeEventsBetweenReturns([]*rds.Event{
+				{
+					Date:            aws.Time(time.Date(2023, 11, 14, 10, 0, 0, 0, time.UTC)),
+					EventCategories: aws.StringSlice([]string{"failure"}),
+					Message:         aws.String("DB instance could not be created because of insufficient capacity in the availability zone"),
+				},
+			}, nil)
+
+			req := httptest.NewRequest("GET", "/admin/instances/instance-1/events?since=2023-11-14T00:00:00Z&until=2023-11-15T00:00:00Z", nil)
+			w := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Header().Get("Content-Type")).To(Equal("application/json"))
+
+			id, startTime, endTime := rdsInstance.DescribeEventsBetweenArgsForCall(0)
+			Expect(id).To(Equal("cf-instance-1"))
+			Expect(startTime).To(BeTemporally("==", time.Date(2023, 11, 14, 0, 0, 0, 0, time.UTC)))
+			Expect(endTime).To(BeTemporally("==", time.Date(2023, 11, 15, 0, 0, 0, 0, time.UTC)))
+
+			var events []map[string]interface{}
+			Expect(json.Unmarshal(w.Body.Bytes(), &events)).To(Succeed())
+			Expect(events).To(HaveLen(1))
+			Expect(events[0]).To(HaveKeyWithValue("message", "DB instance could not be created because of insufficient capacity in the availability zone"))
+			Expect(events[0]).To(HaveKeyWithValue("date", "2023-11-14T10:00:00Z"))
+		})
+
+		It("serves the events of the last day by default", func() {
+			rdsInstance.DescribeReturns(&rds.DBInstance{
+				DBInstanceIdentifier: aws.String("cf-instance-1"),
+			}, nil)
+
+			req := httptest.NewRequest("GET", "/admin/instances/instance-1/events", nil)
+			w := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			_, startTime, endTime := rdsInstance.DescribeEventsBetweenArgsForCall(0)
+			Expect(endTime.Sub(startTime)).To(Equal(24 * time.Hour))
+		})
+
+		It("returns 400 for an invalid events window", func() {
+			req := httptest.NewRequest("GET", "/admin/instances/instance-1/events?since=yesterday", nil)
+			w := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+			Expect(rdsInstance.DescribeEventsBetweenCallCount()).To(Equal(0))
+		})
+
+		It("returns 404 for the events of an unknown instance", func() {
+			rdsInstance.DescribeReturns(nil, awsrds.ErrDBInstanceDoesNotExist)
+
+			req := httptest.NewRequest("GET", "/admin/instances/unknown/events", nil)
+			w := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("serves the log files of an instance as JSON", func() {
+			rdsInstance.DescribeReturns(&rds.DBInstance{
+				DBInstanceIdentifier: aws.String("cf-instance-1"),
+				Engine:               aws.String("mysql"),
+			}, nil)
+			rdsInstance.DescribeLogFilesReturns([]*rds.DescribeDBLogFilesDetails{
+				{
+					LogFileName: aws.String("slowquery/mysql-slowquery.log"),
+					LastWritten: aws.Int64(1700000000000),
+					Size:        aws.Int64(42),
+				},
+			}, nil)
+
+			req := httptest.NewRequest("GET", "/admin/instances/instance-1/log-files", nil)
+			w := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Header().Get("Content-Type")).To(Equal("application/json"))
+
+			var logFiles map[string]interface{}
+			Expect(json.Unmarshal(w.Body.Bytes(), &logFiles)).To(Succeed())
+			Expect(logFiles).To(HaveKeyWithValue("total_size", 42.0))
+			Expect(logFiles["log_files"]).To(ConsistOf(HaveKeyWithValue("name", "slowquery/mysql-slowquery.log")))
+		})
+
+		It("returns 404 for the log files of an unknown instance", func() {
+			rdsInstance.DescribeReturns(nil, awsrds.ErrDBInstanceDoesNotExist)
+
+			req := httptest.NewRequest("GET", "/admin/instances/unknown/log-files", nil)
+			w := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusNotFound))
+		})
+
+		Context("serving the logs of an instance", func() {
+			BeforeEach(func() {
+				rdsInstance.DescribeReturns(&rds.DBInstance{
+					DBInstanceIdentifier: aws.String("cf-instance-1"),
+					Engine:               aws.String("mysql"),
+				}, nil)
+				rdsInstance.DescribeLogFilesReturns([]*rds.DescribeDBLogFilesDetails{
+					{
+						LogFileName: aws.String("slowquery/mysql-slowquery.log"),
+						LastWritten: aws.Int64(1700000000000),
+						Size:        aws.Int64(42),
+					},
+					{
+						LogFileName: aws.String("slowquery/mysql-slowquery.log.3"),
+						LastWritten: aws.Int64(1699990000000),
+					},
+				}, nil)
+				rdsInstance.DownloadLogFileStub = func(id string, logFileName string, w io.Writer) error {
+					_, err := io.WriteString(w, "# Query_time: 12.5\nSELECT SLEEP(12.5);\n")
+					return err
+				}
+			})
+
+			It("streams the latest slow query log as plain text", func() {
+				req := httptest.NewRequest("GET", "/admin/instances/instance-1/logs", nil)
+				w := httptest.NewRecorder()
+				rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+				Expect(w.Code).To(Equal(http.StatusOK))
+				Expect(w.Header().Get("Content-Type")).To(Equal("text/plain; charset=utf-8"))
+				Expect(w.Header().Get("X-Log-File")).To(Equal("slowquery/mysql-slowquery.log"))
+				Expect(w.Header().Get("Last-Modified")).To(Equal("Tue, 14 Nov 2023 22:13:20 GMT"))
+				Expect(w.Body.String()).To(Equal("# Query_time: 12.5\nSELECT SLEEP(12.5);\n"))
+
+				id, filenameContains := rdsInstance.DescribeLogFilesArgsForCall(0)
+				Expect(id).To(Equal("cf-instance-1"))
+				Expect(filenameContains).To(Equal("slowquery/"))
+				id, logFileName, _ := rdsInstance.DownloadLogFileArgsForCall(0)
+				Expect(id).To(Equal("cf-instance-1"))
+				Expect(logFileName).To(Equal("slowquery/mysql-slowquery.log"))
+			})
+
+			It("keeps streaming past the server's write timeout", func() {
+				rdsInstance.DownloadLogFileStub = func(id string, logFileName string, w io.Writer) error {
+					for i := 0; i < 3; i++ {
+						time.Sleep(100 * time.Millisecond)
+						if _, err := io.WriteString(w, "SELECT 1;\n"); err != nil {
+							return err
+						}
+						w.(http.Flusher).Flush()
+					}
+					return nil
+				}
+				server := httptest.NewUnstartedServer(rdsBroker.AdminHandler())
+				server.Config.WriteTimeout = 150 * time.Millisecond
+				server.Start()
+				defer server.Close()
+
+				resp, err := http.Get(server.URL + "/admin/instances/instance-1/logs")
+				Expect(err).ToNot(HaveOccurred())
+				defer resp.Body.Close()
+				body, err := io.ReadAll(resp.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(body)).To(Equal("SELECT 1;\nSELECT 1;\nSELECT 1;\n"))
+			})
+
+			It("looks for the general log when asked", func() {
+				req := httptest.NewRequest("GET", "/admin/instances/instance-1/logs?type=general", nil)
+				w := httptest.NewRecorder()
+				rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+				Expect(w.Code).To(Equal(http.StatusOK))
+				_, filenameContains := rdsInstance.DescribeLogFilesArgsForCall(0)
+				Expect(filenameContains).To(Equal("general/"))
+			})
+
+			It("reads the slow queries of postgres instances from their main log", func() {
+				rdsInstance.DescribeReturns(&rds.DBInstance{
+					DBInstanceIdentifier: aws.String("cf-instance-1"),
+					Engine:               aws.String("postgres"),
+				}, nil)
+
+				req := httptest.NewRequest("GET", "/admin/instances/instance-1/logs?type=slowquery", nil)
+				w := httptest.NewRecorder()
+				rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+				Expect(w.Code).To(Equal(http.StatusOK))
+				_, filenameContains := rdsInstance.DescribeLogFilesArgsForCall(0)
+				Expect(filenameContains).To(Equal("error/"))
+			})
+
+			It("returns 400 for an unknown type of log", func() {
+				req := httptest.NewRequest("GET", "/admin/instances/instance-1/logs?type=audit", nil)
+				w := httptest.NewRecorder()
+				rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+				Expect(w.Code).To(Equal(http.StatusBadRequest))
+				Expect(rdsInstance.DescribeLogFilesCallCount()).To(Equal(0))
+			})
+
+			It("returns 404 when the instance has no log files of that type", func() {
+				rdsInstance.DescribeLogFilesReturns([]*rds.DescribeDBLogFilesDetails{}, nil)
+
+				req := httptest.NewRequest("GET", "/admin/instances/instance-1/logs", nil)
+				w := httptest.NewRecorder()
+				rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+				Expect(w.Code).To(Equal(http.StatusNotFound))
+				Expect(rdsInstance.DownloadLogFileCallCount()).To(Equal(0))
+			})
+
+			It("returns 404 for the logs of an unknown instance", func() {
+				rdsInstance.DescribeReturns(nil, awsrds.ErrDBInstanceDoesNotExist)
+
+				req := httptest.NewRequest("GET", "/admin/instances/unknown/logs", nil)
+				w := httptest.NewRecorder()
+				rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+				Expect(w.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		It("returns 404 for unknown paths under an instance", func() {
+			req := httptest.NewRequest("GET", "/admin/instances/instance-1/other", nil)
+			w := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("rejects other methods", func() {
+			req := httptest.NewRequest("POST", "/admin/instances", nil)
+			w := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
+})
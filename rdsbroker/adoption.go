@@ -0,0 +1,187 @@
+package rdsbroker
+
+import (
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// adoptDBInstance brings an RDS instance which was not created by this broker
+// under its management as the service instance instanceID. The instance is
+// tagged and renamed to the identifier the broker expects, and its master
+// password is reset to the one derived from the broker seed. The remaining
+// plan settings are applied, and the database bindings are made in is
+// created if it's missing, by LastOperation once the rename has completed.
+//
+// Existing users and data are left untouched.
+func (b *RDSBroker) adoptDBInstance(
+	instanceID string,
+	details domain.ProvisionDetails,
+	provisionParameters ProvisionParameters,
+	servicePlan ServicePlan,
+) error {
+	if !b.allowDBInstanceAdoption {
+		return newUserError(ErrCodeAdoptionNotAllowed, "Adopting existing DB instances is not enabled in this broker")
+	}
+
+	adoptedDBInstanceIdentifier := aws.StringValue(provisionParameters.AdoptDBInstance)
+	if adoptedDBInstanceIdentifier == "" {
+		return newUserError(ErrCodeInvalidParameters, "Parameter adopt_db_instance must not be empty")
+	}
+	if !b.isAdoptableBy(details.OrganizationGUID, adoptedDBInstanceIdentifier) {
+		return newUserError(ErrCodeAdoptionNotAllowed, "Instance %s may not be adopted into this organization", adoptedDBInstanceIdentifier)
+	}
+
+	existingInstance, err := b.dbInstance.Describe(adoptedDBInstanceIdentifier)
+	if err != nil {
+		if err == awsrds.ErrDBInstanceDoesNotExist {
+			return newUserError(ErrCodeAdoptionNotAllowed, "Cannot find instance %s", adoptedDBInstanceIdentifier)
+		}
+		return err
+	}
+
+	tags, err := b.dbInstance.GetResourceTags(aws.StringValue(existingInstance.DBInstanceArn))
+	if err != nil {
+		return err
+	}
+	tagsByName := awsrds.RDSTagsValues(tags)
+	if brokerName, ok := tagsByName[awsrds.TagBrokerName]; ok {
+		return newUserError(ErrCodeAdoptionNotAllowed, "Instance %s is already managed by broker '%s'", adoptedDBInstanceIdentifier, brokerName)
+	}
+
+	if err := checkAdoptionCompatibility(existingInstance, servicePlan); err != nil {
+		return err
+	}
+	if dbName := aws.StringValue(existingInstance.DBName); containsString(reservedDatabaseNames, dbName) {
+		return newUserError(ErrCodeAdoptionNotAllowed, "Cannot adopt instance whose database is '%s', as bindings would be made in it", dbName)
+	}
+
+	b.logger.Info("adopt-db-instance", lager.Data{
+		instanceIDLogKey:      instanceID,
+		detailsLogKey:         details,
+		"adoptedDBInstanceID": adoptedDBInstanceIdentifier,
+	})
+
+	// Tag before renaming, so that if the rename fails we can still tell the
+	// instance was claimed and by whom.
+	platform := platformContextFrom(details.RawContext)
+	instanceTags := b.dbTags(RDSInstanceTags{
+		Action:                "Adopted",
+		ServiceID:             details.ServiceID,
+		PlanID:                details.PlanID,
+		OrganizationID:        details.OrganizationGUID,
+		SpaceID:               details.SpaceGUID,
+		SkipFinalSnapshot:     fmt.Sprintf("%t", b.defaultSkipFinalSnapshot(servicePlan, false)),
+		Extensions:            provisionParameters.Extensions,
+		ChargeableEntity:      instanceID,
+		AdoptedFrom:           adoptedDBInstanceIdentifier,
+		InstanceName:          instanceNameFromContext(details.RawContext),
+		Platform:              platform.Platform,
+		KubernetesNamespace:   platform.kubernetesNamespace(),
+		AdditionalDatabases:   provisionParameters.AdditionalDatabases,
+		MasterPasswordVersion: masterPasswordVersionTag(b.masterPasswordVersion),
+		MasterPasswordPolicy:  packMasterPasswordPolicy(b.newMasterPasswordDerivation(aws.StringValue(existingInstance.Engine)).policy),
+	})
+	err = b.dbInstance.AddTagsToResource(aws.StringValue(existingInstance.DBInstanceArn), awsrds.BuildRDSTags(instanceTags))
+	if err != nil {
+		return err
+	}
+
+	_, err = b.dbInstance.Modify(&rds.ModifyDBInstanceInput{
+		DBInstanceIdentifier:    aws.String(adoptedDBInstanceIdentifier),
+		NewDBInstanceIdentifier: aws.String(b.dbInstanceIdentifier(instanceID)),
+		MasterUserPassword:      aws.String(b.generateMasterPassword(instanceID, b.newMasterPasswordDerivation(aws.StringValue(existingInstance.Engine)))),
+		ApplyImmediately:        aws.Bool(true),
+	})
+	return err
+}
+
+// createAdoptedDatabase creates the database bindings of an adopted instance
+// are made in: the database it was created with, or the one the broker would
+// have created if it has none. Until it exists the broker can't connect to
+// the instance as anything but the maintenance database, so this runs before
+// the master user's state is reset.
+func (b *RDSBroker) createAdoptedDatabase(instanceID string, dbInstance *rds.DBInstance, tagsByName map[string]string) (asyncOperationTriggered bool, err error) {
+	dbName := b.dbNameFromDBInstance(instanceID, dbInstance)
+	b.logger.Info("create-adopted-database", lager.Data{
+		instanceIDLogKey: instanceID,
+		"dbName":         dbName,
+	})
+
+	sqlEngine, err := b.openSQLEngineForDBInstance(instanceID, maintenanceDatabase(aws.StringValue(dbInstance.Engine)), dbInstance)
+	if err != nil {
+		return false, err
+	}
+	defer sqlEngine.Close()
+
+	if err := sqlEngine.CreateDatabase(dbName); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// maintenanceDatabase is a database every instance of the engine has, to
+// connect to when its other databases may not exist.
+func maintenanceDatabase(engine string) string {
+	if engine == "postgres" {
+		return "postgres"
+	}
+	return ""
+}
+
+// isAdoptableBy checks that the operator has allowed the organization to
+// adopt the DB instance, by giving it a prefix of the instance's identifier.
+// Without this any tenant could claim any instance in the account which no
+// broker manages.
+func (b *RDSBroker) isAdoptableBy(organizationGUID, dbInstanceIdentifier string) bool {
+	for _, prefix := range b.dbInstanceAdoptionPrefixes[organizationGUID] {
+		if strings.HasPrefix(dbInstanceIdentifier, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAdoptionCompatibility returns an error if the plan cannot be applied
+// to dbInstance without a major version upgrade or loss of data.
+func checkAdoptionCompatibility(dbInstance *rds.DBInstance, servicePlan ServicePlan) error {
+	planEngine := aws.StringValue(servicePlan.RDSProperties.Engine)
+	if engine := aws.StringValue(dbInstance.Engine); engine != planEngine {
+		return newUserError(ErrCodeAdoptionNotAllowed, "Cannot adopt instance with engine '%s' into a plan with engine '%s'", engine, planEngine)
+	}
+
+	planMajorVersion := majorEngineVersion(planEngine, aws.StringValue(servicePlan.RDSProperties.EngineVersion))
+	if majorVersion := majorEngineVersion(planEngine, aws.StringValue(dbInstance.EngineVersion)); majorVersion != planMajorVersion {
+		return newUserError(ErrCodeAdoptionNotAllowed, "Cannot adopt instance with engine version '%s' into a plan with engine version '%s'", majorVersion, planMajorVersion)
+	}
+
+	if aws.Int64Value(servicePlan.RDSProperties.AllocatedStorage) < aws.Int64Value(dbInstance.AllocatedStorage) {
+		return newUserError(ErrCodeAdoptionNotAllowed, "Cannot adopt instance with %dGB of storage into a plan with %dGB", aws.Int64Value(dbInstance.AllocatedStorage), aws.Int64Value(servicePlan.RDSProperties.AllocatedStorage))
+	}
+
+	if aws.BoolValue(servicePlan.RDSProperties.StorageEncrypted) != aws.BoolValue(dbInstance.StorageEncrypted) {
+		return newUserError(ErrCodeAdoptionNotAllowed, "Cannot adopt instance: storage encryption does not match the plan")
+	}
+
+	return nil
+}
+
+// majorEngineVersion returns the part of the version which RDS treats as the
+// major version: the first component for postgres 10 and later, the first two
+// otherwise.
+func majorEngineVersion(engine, version string) string {
+	parts := strings.Split(version, ".")
+	if engine == "postgres" && len(parts[0]) > 1 {
+		return parts[0]
+	}
+	if len(parts) < 2 {
+		return version
+	}
+	return parts[0] + "." + parts[1]
+}
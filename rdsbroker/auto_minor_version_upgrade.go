@@ -0,0 +1,26 @@
+package rdsbroker
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// checkAutoMinorVersionUpgradeAllowed refuses an auto_minor_version_upgrade
+// parameter opting out of automatic minor version upgrades on a plan which
+// doesn't allow it. Opting in is always allowed.
+func checkAutoMinorVersionUpgradeAllowed(servicePlan ServicePlan, autoMinorVersionUpgrade *bool) error {
+	if autoMinorVersionUpgrade != nil && !*autoMinorVersionUpgrade && !servicePlan.AllowAutoMinorVersionUpgradeOptOut {
+		return newUserError(ErrCodePlanNotAllowed, "Service Plan '%s' does not allow auto_minor_version_upgrade to be set to false", servicePlan.Name)
+	}
+	return nil
+}
+
+// autoMinorVersionUpgrade is whether RDS upgrades an instance of the plan to
+// new minor versions in its maintenance window. The user's choice is only
+// kept while the plan allows opting out, so moving to a plan which doesn't
+// puts the instance back on the plan's setting.
+func autoMinorVersionUpgrade(servicePlan ServicePlan, choice string) *bool {
+	if servicePlan.AllowAutoMinorVersionUpgradeOptOut && choice != "" {
+		return aws.Bool(choice == "true")
+	}
+	return servicePlan.RDSProperties.AutoMinorVersionUpgrade
+}
@@ -0,0 +1,62 @@
+package rdsbroker
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// AWSCircuitBreakerConfig is when to stop calling the RDS APIs after they
+// have been throttling or failing, and how often to check whether they have
+// recovered.
+type AWSCircuitBreakerConfig struct {
+	FailureThreshold  uint `json:"failure_threshold"`
+	HealthPingSeconds uint `json:"health_ping_seconds"`
+}
+
+func (c *AWSCircuitBreakerConfig) FillDefaults() {
+	if c.FailureThreshold == 0 {
+		c.FailureThreshold = 10
+	}
+	if c.HealthPingSeconds == 0 {
+		c.HealthPingSeconds = 30
+	}
+}
+
+// AWSAvailability reports whether the AWS APIs are currently being called,
+// such as an awsrds.CircuitBreaker.
+type AWSAvailability interface {
+	Available() bool
+}
+
+var ErrAWSUnavailable = newCodedError(
+	http.StatusServiceUnavailable,
+	ErrCodeAWSUnavailable,
+	errors.New(awsrds.ErrAWSUnavailable.Error()),
+)
+
+// SetAWSAvailability makes the broker refuse requests which would call AWS
+// while it is unavailable, rather than waiting for the calls to fail.
+func (b *RDSBroker) SetAWSAvailability(awsAvailability AWSAvailability) {
+	b.awsAvailability = awsAvailability
+}
+
+func (b *RDSBroker) checkAWSAvailable() error {
+	if b.awsAvailability != nil && !b.awsAvailability.Available() {
+		// outages which trip the breaker are what service advisories
+		// are for, so one which isn't limited to some engines is given
+		// along with the error
+		if advisory := b.serviceAdvisoryFor("", time.Now()); advisory != "" {
+			return newCodedError(
+				http.StatusServiceUnavailable,
+				ErrCodeAWSUnavailable,
+				fmt.Errorf("%s. Service advisory: %s", awsrds.ErrAWSUnavailable, advisory),
+			)
+		}
+		return ErrAWSUnavailable
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package rdsbroker_test
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+	"github.com/pivotal-cf/brokerapi/v9/domain/apiresponses"
+
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+)
+
+type fakeAWSAvailability struct {
+	available bool
+}
+
+func (f *fakeAWSAvailability) Available() bool {
+	return f.available
+}
+
+var _ = Describe("AWS circuit breaker", func() {
+	var (
+		rdsInstance     *rdsfake.FakeRDSInstance
+		awsAvailability *fakeAWSAvailability
+		rdsBroker       *RDSBroker
+	)
+
+	BeforeEach(func() {
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		rdsInstance.DescribeReturns(&rds.DBInstance{
+			DBInstanceArn:    aws.String("arn:aws:rds:rds-region:123456789012:db:cf-instance-id"),
+			DBInstanceStatus: aws.String("available"),
+		}, nil)
+		awsAvailability = &fakeAWSAvailability{available: true}
+
+		rdsBroker = newTestBroker(testConfig(), rdsInstance, nil, lagertest.NewTestLogger("aws_circuit_breaker_test"))
+		rdsBroker.SetAWSAvailability(awsAvailability)
+	})
+
+	It("calls RDS while AWS is available", func() {
+		_, err := rdsBroker.LastOperation(context.Background(), "instance-id", domain.PollDetails{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rdsInstance.DescribeCallCount()).To(Equal(1))
+	})
+
+	Context("while AWS is unavailable", func() {
+		BeforeEach(func() {
+			awsAvailability.available = false
+		})
+
+		It("fails last operation polls without calling RDS", func() {
+			_, err := rdsBroker.LastOperation(context.Background(), "instance-id", domain.PollDetails{})
+			Expect(err).To(Equal(ErrAWSUnavailable))
+			Expect(err.(*apiresponses.FailureResponse).ValidatedStatusCode(nil)).To(Equal(503))
+			Expect(rdsInstance.DescribeCallCount()).To(Equal(0))
+		})
+
+		It("fails provisions without calling RDS", func() {
+			_, err := rdsBroker.Provision(context.Background(), "instance-id", domain.ProvisionDetails{
+				ServiceID: "Service-1",
+				PlanID:    "Plan-1",
+			}, true)
+			Expect(err).To(Equal(ErrAWSUnavailable))
+			Expect(rdsInstance.CreateCallCount()).To(Equal(0))
+		})
+
+		It("fails deprovisions without calling RDS", func() {
+			_, err := rdsBroker.Deprovision(context.Background(), "instance-id", domain.DeprovisionDetails{
+				ServiceID: "Service-1",
+				PlanID:    "Plan-1",
+			}, true)
+			Expect(err).To(Equal(ErrAWSUnavailable))
+			Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,55 @@
+package rdsbroker
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+// AWSEndpointConfig points the broker's AWS clients at an endpoint other
+// than AWS's own, such as LocalStack or moto, for running it locally.
+type AWSEndpointConfig struct {
+	URL                string `json:"url"`
+	AccessKeyID        string `json:"access_key_id"`
+	SecretAccessKey    string `json:"secret_access_key"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+}
+
+func (c AWSEndpointConfig) Validate() error {
+	if c.URL == "" {
+		return errors.New("Must provide a non-empty URL")
+	}
+	if u, err := url.Parse(c.URL); err != nil || u.Scheme == "" || u.Host == "" {
+		return errors.New("URL must be an absolute URL, such as http://localhost:4566")
+	}
+	if (c.AccessKeyID == "") != (c.SecretAccessKey == "") {
+		return errors.New("Must provide both AccessKeyID and SecretAccessKey, or neither")
+	}
+	return nil
+}
+
+// AWSConfig returns the config for the broker's AWS clients, with the
+// endpoint override applied if there is one.
+func (c Config) AWSConfig() *aws.Config {
+	awsConfig := aws.NewConfig().WithRegion(c.Region).WithMaxRetries(3)
+	if c.AWSEndpoint == nil {
+		return awsConfig
+	}
+
+	// Path style S3 requests are needed as the emulators don't serve a host
+	// per bucket.
+	awsConfig = awsConfig.WithEndpoint(c.AWSEndpoint.URL).WithS3ForcePathStyle(true)
+	if c.AWSEndpoint.AccessKeyID != "" {
+		awsConfig = awsConfig.WithCredentials(credentials.NewStaticCredentials(c.AWSEndpoint.AccessKeyID, c.AWSEndpoint.SecretAccessKey, ""))
+	}
+	if c.AWSEndpoint.InsecureSkipVerify {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		awsConfig = awsConfig.WithHTTPClient(&http.Client{Transport: transport})
+	}
+	return awsConfig
+}
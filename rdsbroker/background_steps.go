@@ -0,0 +1,95 @@
+package rdsbroker
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// backgroundStepAbandonedAfter is how long after a background step started
+// it is taken to have been abandoned if it still hasn't finished, such as
+// when the broker process running it was restarted. It is well past the
+// time the SQL engines give any of the steps.
+const backgroundStepAbandonedAfter = time.Hour
+
+// The progress of a background step is recorded in a tag while it runs, or
+// once it has failed.
+const (
+	backgroundStepRunning = "running since "
+	backgroundStepFailed  = "failed: "
+)
+
+// ensureBackgroundStep runs a step of the last operation which takes too
+// long to run within a poll, such as copying data into the database,
+// returning true while it runs. Its progress is recorded in progressTag, so
+// that every broker process reports the same state: the step is started if
+// the tag isn't set, and the tag is removed once it has succeeded. The error
+// of a failed step is kept in the tag, so that later polls fail too. The
+// step must remove the tag asking for it before it returns successfully.
+func (b *RDSBroker) ensureBackgroundStep(instanceID string, dbInstance *rds.DBInstance, tagsByName map[string]string, progressTag string, step func() error) (bool, error) {
+	dbInstanceIdentifier := b.dbInstanceIdentifier(instanceID)
+	dbInstanceARN := aws.StringValue(dbInstance.DBInstanceArn)
+	stepKey := instanceID + ":" + progressTag
+	logData := lager.Data{instanceIDLogKey: instanceID, "step": progressTag}
+
+	if progress, exists := tagsByName[progressTag]; exists {
+		switch {
+		case strings.HasPrefix(progress, backgroundStepFailed):
+			return false, errors.New(strings.TrimPrefix(progress, backgroundStepFailed))
+		case strings.HasPrefix(progress, backgroundStepRunning):
+			if b.backgroundSteps.isLocked(stepKey) {
+				return true, nil
+			}
+			startedAt, err := time.Parse(time.RFC3339, strings.TrimPrefix(progress, backgroundStepRunning))
+			if err == nil && time.Now().Before(startedAt.Add(backgroundStepAbandonedAfter)) {
+				// another broker process may be running it
+				return true, nil
+			}
+			err = fmt.Errorf("abandoned as it had not finished within %s", backgroundStepAbandonedAfter)
+			b.logger.Error("background-step.abandoned", err, logData)
+			b.writeTags(instanceID, dbInstanceARN, map[string]string{
+				progressTag: sanitizeTagValue(backgroundStepFailed + err.Error()),
+			})
+			return false, err
+		default:
+			return false, fmt.Errorf("Invalid tag '%s' value '%s'", progressTag, progress)
+		}
+	}
+
+	if !b.backgroundSteps.tryLock(stepKey) {
+		return true, nil
+	}
+	err := b.dbInstance.AddTagsToResource(dbInstanceARN, awsrds.BuildRDSTags(map[string]string{
+		progressTag: backgroundStepRunning + time.Now().UTC().Format(time.RFC3339),
+	}))
+	if err != nil {
+		b.backgroundSteps.unlock(stepKey)
+		return false, err
+	}
+
+	b.logger.Info("background-step.start", logData)
+	go func() {
+		defer b.backgroundSteps.unlock(stepKey)
+
+		if err := step(); err != nil {
+			b.logger.Error("background-step.failed", err, logData)
+			b.writeTags(instanceID, dbInstanceARN, map[string]string{
+				progressTag: sanitizeTagValue(backgroundStepFailed + err.Error()),
+			})
+			return
+		}
+		b.logger.Info("background-step.done", logData)
+		if err := b.removeTag(dbInstanceIdentifier, progressTag); err != nil {
+			b.logger.Error("background-step.remove-progress", err, logData)
+		}
+	}()
+
+	return true, nil
+}
@@ -0,0 +1,200 @@
+package rdsbroker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+var ErrBackupOverdue = errors.New("DB instance has gone too long without a successful backup")
+
+// BackupStatus is when a DB instance was last backed up: the latest time it
+// can be restored to from its transaction logs, and the time of its latest
+// automated snapshot.
+type BackupStatus struct {
+	InstanceID           string     `json:"instance_id"`
+	DBInstanceIdentifier string     `json:"db_instance_identifier"`
+	LatestRestorableTime *time.Time `json:"latest_restorable_time"`
+	LatestSnapshotTime   *time.Time `json:"latest_snapshot_time"`
+	RestoreTestedAt      *time.Time `json:"restore_tested_at,omitempty"`
+	RestoreTestPassed    *bool      `json:"restore_test_passed,omitempty"`
+}
+
+// latestAutomatedSnapshot returns the newest automated snapshot which has
+// finished, or nil if there is none. Snapshots are described newest first.
+func latestAutomatedSnapshot(snapshots []*rds.DBSnapshot) *rds.DBSnapshot {
+	for _, snapshot := range snapshots {
+		if aws.StringValue(snapshot.SnapshotType) == "automated" && aws.StringValue(snapshot.Status) == "available" {
+			return snapshot
+		}
+	}
+	return nil
+}
+
+func (b *RDSBroker) backupStatus(dbInstance *rds.DBInstance, tagsByName map[string]string) (BackupStatus, error) {
+	dbInstanceIdentifier := aws.StringValue(dbInstance.DBInstanceIdentifier)
+	snapshots, err := b.dbInstance.DescribeSnapshots(dbInstanceIdentifier)
+	if err != nil {
+		return BackupStatus{}, err
+	}
+
+	status := BackupStatus{
+		InstanceID:           b.dbInstanceIdentifierToServiceInstanceID(dbInstanceIdentifier),
+		DBInstanceIdentifier: dbInstanceIdentifier,
+		LatestRestorableTime: dbInstance.LatestRestorableTime,
+	}
+	if snapshot := latestAutomatedSnapshot(snapshots); snapshot != nil {
+		status.LatestSnapshotTime = snapshot.SnapshotCreateTime
+	}
+	if restoreTestedAt, err := time.Parse(time.RFC3339, tagsByName[awsrds.TagRestoreTestedAt]); err == nil {
+		status.RestoreTestedAt = &restoreTestedAt
+		status.RestoreTestPassed = aws.Bool(tagsByName[awsrds.TagRestoreTestResult] == restoreTestPassed)
+	}
+
+	return status, nil
+}
+
+// BackupStatuses gives when every DB instance owned by this broker was last
+// backed up. Soft deleted instances are left out.
+func (b *RDSBroker) BackupStatuses() ([]BackupStatus, error) {
+	dbInstances, err := b.listManagedDBInstances()
+	if err != nil {
+		return nil, err
+	}
+	return b.backupStatuses(dbInstances)
+}
+
+func (b *RDSBroker) backupStatuses(dbInstances []managedDBInstance) ([]BackupStatus, error) {
+	statuses := []BackupStatus{}
+	for _, instance := range dbInstances {
+		if instance.tagsByName[awsrds.TagPurgeAfter] != "" {
+			continue
+		}
+
+		status, err := b.backupStatus(instance.dbInstance, instance.tagsByName)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// CheckBackups logs an error for every DB instance which has gone longer
+// than the configured backup alert period without an automated snapshot,
+// and returns how many there are. Instances younger than the period are
+// not expected to have one yet.
+func (b *RDSBroker) CheckBackups() (int, error) {
+	if b.backupAlertDuration == 0 {
+		return 0, nil
+	}
+
+	dbInstances, err := b.listManagedDBInstances()
+	if err != nil {
+		return 0, err
+	}
+	return b.checkBackups(dbInstances), nil
+}
+
+func (b *RDSBroker) checkBackups(dbInstances []managedDBInstance) int {
+	if b.backupAlertDuration == 0 {
+		return 0
+	}
+
+	now := time.Now()
+	checkedInstances := 0
+	overdueInstances := 0
+	for _, instance := range dbInstances {
+		dbInstance := instance.dbInstance
+		if now.Sub(aws.TimeValue(dbInstance.InstanceCreateTime)) < b.backupAlertDuration {
+			continue
+		}
+		if instance.tagsByName[awsrds.TagPurgeAfter] != "" {
+			continue
+		}
+
+		status, err := b.backupStatus(dbInstance, instance.tagsByName)
+		if err != nil {
+			b.logger.Error("backup-check.describe-snapshots", err, lager.Data{dbInstanceLogKey: dbInstance.DBInstanceIdentifier})
+			continue
+		}
+		checkedInstances++
+
+		if status.LatestSnapshotTime == nil || now.Sub(*status.LatestSnapshotTime) > b.backupAlertDuration {
+			overdueInstances++
+			b.logger.Error("backup-check.overdue", ErrBackupOverdue, lager.Data{
+				instanceIDLogKey:       status.InstanceID,
+				"latestSnapshotTime":   status.LatestSnapshotTime,
+				"latestRestorableTime": status.LatestRestorableTime,
+			})
+		}
+	}
+
+	b.logger.Info("backup-check.checked", lager.Data{
+		"checkedInstances": checkedInstances,
+		"overdueInstances": overdueInstances,
+	})
+
+	return overdueInstances
+}
+
+// writeBackupMetrics writes the backup statuses in the Prometheus text
+// exposition format, with times as Unix timestamps. Instances without a
+// backup or restore test yet are left out of the metrics they have no value
+// for.
+func writeBackupMetrics(w io.Writer, statuses []BackupStatus) {
+	metrics := []struct {
+		name string
+		help string
+		time func(BackupStatus) *time.Time
+	}{
+		{
+			name: "rds_broker_instance_latest_restorable_timestamp_seconds",
+			help: "Latest time the DB instance can be restored to.",
+			time: func(s BackupStatus) *time.Time { return s.LatestRestorableTime },
+		},
+		{
+			name: "rds_broker_instance_latest_snapshot_timestamp_seconds",
+			help: "Creation time of the latest automated snapshot of the DB instance.",
+			time: func(s BackupStatus) *time.Time { return s.LatestSnapshotTime },
+		},
+		{
+			name: "rds_broker_instance_restore_test_timestamp_seconds",
+			help: "Time the latest restore test of the DB instance finished.",
+			time: func(s BackupStatus) *time.Time { return s.RestoreTestedAt },
+		},
+	}
+
+	for _, metric := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n", metric.name, metric.help)
+		fmt.Fprintf(w, "# TYPE %s gauge\n", metric.name)
+		for _, status := range statuses {
+			if t := metric.time(status); t != nil {
+				fmt.Fprintf(w, "%s{instance_id=%q,db_instance_identifier=%q} %d\n",
+					metric.name, status.InstanceID, status.DBInstanceIdentifier, t.Unix())
+			}
+		}
+	}
+
+	const restoreTestSuccess = "rds_broker_instance_restore_test_success"
+	fmt.Fprintf(w, "# HELP %s Whether the latest restore test of the DB instance passed.\n", restoreTestSuccess)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", restoreTestSuccess)
+	for _, status := range statuses {
+		if status.RestoreTestPassed != nil {
+			success := 0
+			if *status.RestoreTestPassed {
+				success = 1
+			}
+			fmt.Fprintf(w, "%s{instance_id=%q,db_instance_identifier=%q} %d\n",
+				restoreTestSuccess, status.InstanceID, status.DBInstanceIdentifier, success)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package rdsbroker_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+)
+
+var _ = Describe("Backups", func() {
+	var (
+		rdsInstance          *rdsfake.FakeRDSInstance
+		rdsBroker            *RDSBroker
+		logger               *lagertest.TestLogger
+		backupAlertHours     uint
+		latestRestorableTime time.Time
+		latestSnapshotTime   time.Time
+	)
+
+	BeforeEach(func() {
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		logger = lagertest.NewTestLogger("backups_test")
+		backupAlertHours = 36
+
+		latestRestorableTime = time.Unix(1700000300, 0)
+		latestSnapshotTime = time.Now().Add(-12 * time.Hour)
+
+		rdsInstance.DescribeByTagReturns([]*rds.DBInstance{
+			{
+				DBInstanceIdentifier: aws.String("cf-instance-1"),
+				DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-instance-1"),
+				InstanceCreateTime:   aws.Time(time.Now().Add(-30 * 24 * time.Hour)),
+				LatestRestorableTime: aws.Time(latestRestorableTime),
+			},
+		}, nil)
+		rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+			"Broker Name": "mybroker",
+		}), nil)
+	})
+
+	JustBeforeEach(func() {
+		rdsInstance.DescribeSnapshotsReturns([]*rds.DBSnapshot{
+			{
+				SnapshotType:       aws.String("manual"),
+				Status:             aws.String("available"),
+				SnapshotCreateTime: aws.Time(time.Now().Add(-time.Hour)),
+			},
+			{
+				SnapshotType:       aws.String("automated"),
+				Status:             aws.String("available"),
+				SnapshotCreateTime: aws.Time(latestSnapshotTime),
+			},
+		}, nil)
+
+		config := testConfig()
+		config.BackupAlertHours = backupAlertHours
+		rdsBroker = newTestBroker(config, rdsInstance, nil, logger)
+	})
+
+	Describe("BackupStatuses", func() {
+		It("returns the latest restorable time and automated snapshot of each instance", func() {
+			statuses, err := rdsBroker.BackupStatuses()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(statuses).To(HaveLen(1))
+			Expect(statuses[0].InstanceID).To(Equal("instance-1"))
+			Expect(*statuses[0].LatestRestorableTime).To(BeTemporally("==", latestRestorableTime))
+			Expect(*statuses[0].LatestSnapshotTime).To(BeTemporally("==", latestSnapshotTime))
+
+			Expect(rdsInstance.DescribeSnapshotsArgsForCall(0)).To(Equal("cf-instance-1"))
+		})
+
+		Context("when the snapshots can't be described", func() {
+			JustBeforeEach(func() {
+				rdsInstance.DescribeSnapshotsReturns(nil, errors.New("boom"))
+			})
+
+			It("returns the error", func() {
+				_, err := rdsBroker.BackupStatuses()
+				Expect(err).To(MatchError("boom"))
+			})
+		})
+	})
+
+	Describe("CheckBackups", func() {
+		It("doesn't report instances with a recent snapshot", func() {
+			overdueInstances, err := rdsBroker.CheckBackups()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(overdueInstances).To(Equal(0))
+			Expect(logger.LogMessages()).To(ContainElement("backups_test.broker.backup-check.checked"))
+		})
+
+		Context("when the latest snapshot is older than the alert period", func() {
+			BeforeEach(func() {
+				latestSnapshotTime = time.Now().Add(-48 * time.Hour)
+			})
+
+			It("logs an error for the instance", func() {
+				overdueInstances, err := rdsBroker.CheckBackups()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(overdueInstances).To(Equal(1))
+				Expect(logger.LogMessages()).To(ContainElement("backups_test.broker.backup-check.overdue"))
+			})
+
+			Context("and the alert is disabled", func() {
+				BeforeEach(func() {
+					backupAlertHours = 0
+				})
+
+				It("doesn't check anything", func() {
+					overdueInstances, err := rdsBroker.CheckBackups()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(overdueInstances).To(Equal(0))
+					Expect(rdsInstance.DescribeByTagCallCount()).To(Equal(0))
+				})
+			})
+		})
+	})
+
+	Describe("the metrics admin endpoint", func() {
+		JustBeforeEach(func() {
+			Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+		})
+
+		It("serves the backup times in the Prometheus format", func() {
+			recorder := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/metrics", nil))
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Header().Get("Content-Type")).To(HavePrefix("text/plain"))
+			Expect(recorder.Body.String()).To(ContainSubstring(
+				"# TYPE rds_broker_instance_latest_restorable_timestamp_seconds gauge\n" +
+					`rds_broker_instance_latest_restorable_timestamp_seconds{instance_id="instance-1",db_instance_identifier="cf-instance-1"} 1700000300` + "\n",
+			))
+			Expect(recorder.Body.String()).To(ContainSubstring("# TYPE rds_broker_instance_latest_snapshot_timestamp_seconds gauge\n"))
+		})
+
+		It("serves the times gathered by housekeeping without calling RDS", func() {
+			describeByTagCalls := rdsInstance.DescribeByTagCallCount()
+			describeSnapshotsCalls := rdsInstance.DescribeSnapshotsCallCount()
+
+			recorder := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/metrics", nil))
+
+			Expect(recorder.Body.String()).To(ContainSubstring("rds_broker_instance_metrics_gathered_timestamp_seconds "))
+			Expect(rdsInstance.DescribeByTagCallCount()).To(Equal(describeByTagCalls))
+			Expect(rdsInstance.DescribeSnapshotsCallCount()).To(Equal(describeSnapshotsCalls))
+		})
+
+		Context("when the instance has had a restore test", func() {
+			BeforeEach(func() {
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+					"Broker Name":         "mybroker",
+					"Restore Tested At":   "2023-11-14T22:13:20Z",
+					"Restore Test Result": "failed",
+				}), nil)
+			})
+
+			It("serves its time and outcome", func() {
+				recorder := httptest.NewRecorder()
+				rdsBroker.AdminHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/metrics", nil))
+
+				Expect(recorder.Body.String()).To(ContainSubstring(
+					`rds_broker_instance_restore_test_timestamp_seconds{instance_id="instance-1",db_instance_identifier="cf-instance-1"} 1700000000` + "\n",
+				))
+				Expect(recorder.Body.String()).To(ContainSubstring(
+					`rds_broker_instance_restore_test_success{instance_id="instance-1",db_instance_identifier="cf-instance-1"} 0` + "\n",
+				))
+			})
+		})
+	})
+})
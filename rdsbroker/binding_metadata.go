@@ -0,0 +1,249 @@
+package rdsbroker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	"github.com/alphagov/paas-rds-broker/statestore"
+)
+
+// BindingMetadata is what the broker records about a binding of an
+// instance, in its state store or otherwise in a tag of the instance keyed
+// by the binding ID.
+type BindingMetadata struct {
+	BindingID string    `json:"binding_id"`
+	AppGUID   string    `json:"app_guid,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ReadOnly  bool      `json:"read_only"`
+}
+
+// bindingAppGUID returns the app a binding is for, which is empty for
+// service keys. The top level app_guid is deprecated in favour of the one
+// in bind_resource.
+func bindingAppGUID(details domain.BindDetails) string {
+	if details.BindResource != nil && details.BindResource.AppGuid != "" {
+		return details.BindResource.AppGuid
+	}
+	return details.AppGUID
+}
+
+// maxBindingTags is the most bindings recorded in the tags of an instance
+// without a state store, however much room its tags leave.
+const maxBindingTags = 10
+
+// maxResourceTags is the most tags RDS allows on a DB instance. Adding tags
+// beyond it fails, so binding tags must never take the room of the ones the
+// broker relies on.
+const maxResourceTags = 50
+
+// laterTagKeys are the tags the broker may add to an instance after it was
+// created, for updates and for operations in progress, which binding tags
+// have to leave room for.
+var laterTagKeys = []string{
+	awsrds.TagExtensions,
+	awsrds.TagOperationLease,
+	awsrds.TagUpdatedByUser,
+	awsrds.TagPreviousInstanceNames,
+	awsrds.TagExtensionUpdateFor,
+	awsrds.TagDatabasesToPurge,
+	awsrds.TagPgauditLog,
+	awsrds.TagDBProxy,
+	awsrds.TagPurgeAfter,
+	awsrds.TagUndeleteRequested,
+	awsrds.TagTrialExpiryWarned,
+	awsrds.TagFailoverRequestedAt,
+	awsrds.TagDNSName,
+	awsrds.TagDNSTarget,
+	awsrds.TagAdditionalDatabases,
+	awsrds.TagExpiringUsersUntil,
+	awsrds.TagOrphaned,
+	awsrds.TagBinlogRetentionHours,
+	awsrds.TagUnprotectedUntil,
+	awsrds.TagPubliclyAccessible,
+	awsrds.TagAutoMinorUpgrade,
+	awsrds.TagMinorUpgrade,
+	awsrds.TagMinorUpgradeTarget,
+	awsrds.TagScheduleAtMaintenance,
+	awsrds.TagScheduledMaintenance,
+	awsrds.TagRebootReason,
+	awsrds.TagMasterPasswordVersion,
+	awsrds.TagMasterPasswordRotated,
+	awsrds.TagMasterPasswordPolicy,
+	awsrds.TagSharedWithAccount,
+	awsrds.TagCopiedToAccount,
+	awsrds.TagSLATier,
+	awsrds.TagSupportLevel,
+	awsrds.TagSchemaCopyProgress,
+	awsrds.TagDataImportProgress,
+	awsrds.TagDataExportOnDeprovision,
+	awsrds.TagDataExport,
+	StateUpdateSettings,
+	StateReboot,
+	StateResetUserPassword,
+}
+
+// bindingTagsHeadroom is how many more binding tags an instance has room
+// for: what the RDS limit leaves of its current tags, less the later tags
+// it doesn't have yet.
+func bindingTagsHeadroom(tagsByName map[string]string) int {
+	headroom := maxResourceTags - len(tagsByName)
+	for _, key := range laterTagKeys {
+		if _, ok := tagsByName[key]; !ok {
+			headroom--
+		}
+	}
+	if bindings := len(bindingsFromTags(tagsByName)); headroom > maxBindingTags-bindings {
+		headroom = maxBindingTags - bindings
+	}
+	return headroom
+}
+
+func bindingMetadataTagKey(bindingID string) string {
+	return awsrds.TagBindingPrefix + bindingID
+}
+
+// packBindingMetadata writes the metadata as a tag value. RDS tag values
+// can't contain commas or semicolons, so fields are separated by spaces.
+func packBindingMetadata(metadata BindingMetadata) string {
+	return fmt.Sprintf(
+		"app=%s created=%s read_only=%t",
+		metadata.AppGUID,
+		metadata.CreatedAt.UTC().Format(time.RFC3339),
+		metadata.ReadOnly,
+	)
+}
+
+func unpackBindingMetadata(bindingID, value string) BindingMetadata {
+	metadata := BindingMetadata{BindingID: bindingID}
+	for _, field := range strings.Fields(value) {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		switch parts[0] {
+		case "app":
+			metadata.AppGUID = parts[1]
+		case "created":
+			metadata.CreatedAt, _ = time.Parse(time.RFC3339, parts[1])
+		case "read_only":
+			metadata.ReadOnly = parts[1] == "true"
+		}
+	}
+	return metadata
+}
+
+// bindingsFromTags returns the bindings of an instance recorded in its
+// tags.
+func bindingsFromTags(tagsByName map[string]string) []BindingMetadata {
+	bindings := []BindingMetadata{}
+	for key, value := range tagsByName {
+		if !strings.HasPrefix(key, awsrds.TagBindingPrefix) {
+			continue
+		}
+		bindings = append(bindings, unpackBindingMetadata(strings.TrimPrefix(key, awsrds.TagBindingPrefix), value))
+	}
+	return bindings
+}
+
+// instanceBindings returns the recorded bindings of an instance, oldest
+// first. Bindings made before the broker had a state store are still in the
+// instance's tags.
+func (b *RDSBroker) instanceBindings(instanceID string, tagsByName map[string]string) ([]BindingMetadata, error) {
+	bindings := bindingsFromTags(tagsByName)
+
+	if b.stateStore != nil {
+		storedBindings, err := b.stateStore.ListBindings(instanceID)
+		if err != nil {
+			return nil, err
+		}
+		inTags := map[string]bool{}
+		for _, binding := range bindings {
+			inTags[binding.BindingID] = true
+		}
+		for _, storedBinding := range storedBindings {
+			if !inTags[storedBinding.BindingID] {
+				bindings = append(bindings, BindingMetadata{
+					BindingID: storedBinding.BindingID,
+					AppGUID:   storedBinding.AppGUID,
+					CreatedAt: storedBinding.CreatedAt,
+					ReadOnly:  storedBinding.ReadOnly,
+				})
+			}
+		}
+	}
+
+	sort.Slice(bindings, func(i, j int) bool {
+		if bindings[i].CreatedAt.Equal(bindings[j].CreatedAt) {
+			return bindings[i].BindingID < bindings[j].BindingID
+		}
+		return bindings[i].CreatedAt.Before(bindings[j].CreatedAt)
+	})
+	return bindings, nil
+}
+
+// recordBinding records the metadata of a new binding in the state store,
+// or otherwise in a tag of the instance, unless its tags have no room left
+// for it. The binding works without it, so failures are only
+// logged.
+func (b *RDSBroker) recordBinding(instanceID string, dbInstance *rds.DBInstance, metadata BindingMetadata) {
+	logData := lager.Data{
+		instanceIDLogKey: instanceID,
+		bindingIDLogKey:  metadata.BindingID,
+	}
+	if b.stateStore != nil {
+		err := b.stateStore.RecordBinding(statestore.Binding{
+			InstanceID: instanceID,
+			BindingID:  metadata.BindingID,
+			AppGUID:    metadata.AppGUID,
+			CreatedAt:  metadata.CreatedAt,
+			ReadOnly:   metadata.ReadOnly,
+		})
+		if err != nil {
+			b.logger.Error("record-binding", err, logData)
+		}
+		return
+	}
+
+	tags, err := b.dbInstance.GetResourceTags(aws.StringValue(dbInstance.DBInstanceArn))
+	if err != nil {
+		b.logger.Error("record-binding", err, logData)
+		return
+	}
+	if bindingTagsHeadroom(awsrds.RDSTagsValues(tags)) < 1 {
+		b.logger.Info("record-binding.no-room-for-binding-tag", logData)
+		return
+	}
+
+	err = b.dbInstance.AddTagsToResource(
+		aws.StringValue(dbInstance.DBInstanceArn),
+		awsrds.BuildRDSTags(map[string]string{bindingMetadataTagKey(metadata.BindingID): packBindingMetadata(metadata)}),
+	)
+	if err != nil {
+		b.logger.Error("record-binding", err, logData)
+	}
+}
+
+// forgetBinding removes the record of a binding. The tag is removed even
+// with a state store, as the binding may have been made before it was set.
+func (b *RDSBroker) forgetBinding(instanceID, bindingID string) {
+	logData := lager.Data{
+		instanceIDLogKey: instanceID,
+		bindingIDLogKey:  bindingID,
+	}
+	if b.stateStore != nil {
+		if err := b.stateStore.ForgetBinding(instanceID, bindingID); err != nil {
+			b.logger.Error("forget-binding", err, logData)
+		}
+	}
+	if err := b.removeTag(b.dbInstanceIdentifier(instanceID), bindingMetadataTagKey(bindingID)); err != nil {
+		b.logger.Error("forget-binding", err, logData)
+	}
+}
@@ -1,7 +1,6 @@
 package rdsbroker
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -10,6 +9,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Masterminds/semver"
@@ -18,8 +18,12 @@ import (
 	"code.cloudfoundry.org/lager/v3"
 	"github.com/pivotal-cf/brokerapi/v9/domain"
 
+	"github.com/alphagov/paas-rds-broker/awskms"
 	"github.com/alphagov/paas-rds-broker/awsrds"
+	"github.com/alphagov/paas-rds-broker/awsroute53"
+	"github.com/alphagov/paas-rds-broker/awssecrets"
 	"github.com/alphagov/paas-rds-broker/sqlengine"
+	"github.com/alphagov/paas-rds-broker/statestore"
 	"github.com/alphagov/paas-rds-broker/utils"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/rds"
@@ -49,7 +53,7 @@ const disagreementMultiAZ = "MultiAZ"
 const disagreementDBInstanceClass = "DBInstanceClass"
 
 var (
-	ErrEncryptionNotUpdateable = errors.New("instance can not be updated to a plan with different encryption settings")
+	ErrEncryptionNotUpdateable = newCodedError(http.StatusBadRequest, ErrCodeInvalidPlanChange, errors.New("instance can not be updated to a plan with different encryption settings, restore a snapshot into a new instance on that plan instead"))
 	ErrCannotSkipMajorVersion  = errors.New("cannot skip major Postgres versions. Please upgrade one major version at a time (e.g. 10, to 11, to 12)")
 	ErrCannotDowngradeVersion  = errors.New("cannot downgrade major versions")
 	ErrCannotDowngradeStorage  = errors.New("cannot downgrade storage")
@@ -82,24 +86,98 @@ var rdsStatus2State = map[string]domain.LastOperationState{
 	"inaccessible-encryption-credentials": domain.Failed,
 }
 
+// operationDataProvision is returned as the operation data of a provision
+// which creates, restores or adopts a DB instance, so that its polls can tell that
+// a failed instance was never handed over and can be cleaned up.
+const operationDataProvision = "provision"
+
+// operationDataReboot and operationDataDeprovision are returned as the
+// operation data of reboots and deprovisions, so that their polls can be
+// given their own Retry-After hints.
+const operationDataReboot = "reboot"
+const operationDataDeprovision = "deprovision"
+
+func isProvisionOperation(operationData string) bool {
+	return operationData == operationDataProvision || operationData == operationDataReencryptedRestore
+}
+
 const StateUpdateSettings = "PendingUpdateSettings"
 const StateReboot = "PendingReboot"
 const StateResetUserPassword = "PendingResetUserPassword"
+const StateCreateDatabase = "PendingCreateDatabase"
 
-var restoreStateSequence = []string{StateUpdateSettings, StateReboot, StateResetUserPassword}
+var restoreStateSequence = []string{StateUpdateSettings, StateReboot, StateCreateDatabase, StateResetUserPassword}
 
 type RDSBroker struct {
-	dbPrefix                     string
-	masterPasswordSeed           string
-	allowUserProvisionParameters bool
-	allowUserUpdateParameters    bool
-	allowUserBindParameters      bool
-	catalog                      Catalog
-	dbInstance                   awsrds.RDSInstance
-	sqlProvider                  sqlengine.Provider
-	logger                       lager.Logger
-	brokerName                   string
-	parameterGroupsSelector      ParameterGroupSelector
+	dbPrefix                       string
+	masterPasswordSeed             string
+	masterPasswordPolicies         map[string]MasterPasswordPolicy
+	masterPasswordVersion          int
+	allowUserProvisionParameters   bool
+	allowUserUpdateParameters      bool
+	allowUserBindParameters        bool
+	allowDBInstanceAdoption        bool
+	dbInstanceAdoptionPrefixes     map[string][]string
+	catalog                        Catalog
+	dbInstance                     awsrds.RDSInstance
+	sqlProvider                    sqlengine.Provider
+	logger                         lager.Logger
+	brokerName                     string
+	parameterGroupsSelector        ParameterGroupSelector
+	lastOperationCache             *lastOperationCache
+	databaseUsageCache             *databaseUsageCache
+	instanceLocks                  instanceLocks
+	backgroundSteps                instanceLocks
+	operationLeaseDuration         time.Duration
+	brokerID                       string
+	securityGroupSets              map[string][]string
+	organizationSecurityGroupSets  map[string][]string
+	networkTiers                   map[string]NetworkTier
+	organizationNetworkTiers       map[string]string
+	subnetCIDRBlocks               map[string]string
+	checkBindingConnections        bool
+	softDeleteDuration             time.Duration
+	trialWarningDuration           time.Duration
+	trialGraceDuration             time.Duration
+	trialExpiryWebhookURL          string
+	trialExpiryWebhookClient       *http.Client
+	backupAlertDuration            time.Duration
+	restoreTestInterval            time.Duration
+	minorUpgradeConcurrency        int
+	retryAfterSeconds              map[string]uint
+	warmUpAttempts                 uint
+	inventoryBucket                string
+	inventoryPrefix                string
+	inventoryStore                 InventoryStore
+	inventoryExportedOn            string
+	versionDriftExportedOn         string
+	region                         string
+	priceTable                     *PriceTable
+	dnsZone                        awsroute53.DNSZone
+	secretStore                    awssecrets.SecretStore
+	keyResolver                    awskms.KeyResolver
+	kmsKeyAliases                  []string
+	backupAccount                  *BackupAccountConfig
+	backupDBInstance               awsrds.RDSInstance
+	dbProxyAuthLock                sync.Mutex
+	dnsDomain                      string
+	deprovisionProtectionWindow    time.Duration
+	metricStatistics               MetricStatistics
+	revokeBindingsOnDeprovision    bool
+	stateStore                     statestore.StateStore
+	awsAvailability                AWSAvailability
+	pendingTagWrites               *pendingTagWrites
+	instanceMetrics                *instanceMetrics
+	skipFinalSnapshotDefault       *bool
+	tolerateRemovedPlans           bool
+	confirmInstanceClassDowngrades bool
+	transactionIDAgeWarning        int64
+	connectionUsageWarningPercent  uint
+	organizationDataImportBuckets  map[string][]DataImportBucket
+	dataImportPresigner            DataImportPresigner
+	organizationDataExportBuckets  map[string][]string
+	dataExportStore                DataExportStore
+	serviceAdvisory                *ServiceAdvisoryConfig
 }
 
 type Credentials struct {
@@ -110,10 +188,25 @@ type Credentials struct {
 	Password string `json:"password"`
 	URI      string `json:"uri"`
 	JDBCURI  string `json:"jdbcuri"`
+
+	// The servicebinding.io well-known entries missing from the above, only
+	// set when the binding asks for that layout.
+	Type     string `json:"type,omitempty"`
+	Provider string `json:"provider,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+	Database string `json:"database,omitempty"`
+
+	// When the credentials stop working, for bindings made with ttl_hours.
+	ExpiresAt string `json:"expires_at,omitempty"`
+
+	// Where the DB instance sits in its VPC, for firewall rules outside the
+	// platform.
+	Network *Network `json:"network,omitempty"`
 }
 
 type RDSInstanceTags struct {
 	Action                   string
+	AdoptedFrom              string
 	ServiceID                string
 	PlanID                   string
 	OrganizationID           string
@@ -124,6 +217,29 @@ type RDSInstanceTags struct {
 	OriginPointInTime        string
 	Extensions               []string
 	ChargeableEntity         string
+	UpdatedByUser            string
+	SecurityGroupSet         string
+	NetworkTier              string
+	InstanceName             string
+	PreviousInstanceNames    string
+	ExtensionUpdateFor       string
+	DatabasesToPurge         string
+	PgauditLog               []string
+	TrialExpires             string
+	Platform                 string
+	KubernetesNamespace      string
+	AdditionalDatabases      []string
+	UnprotectedUntil         string
+	PubliclyAccessible       string
+	AutoMinorVersionUpgrade  string
+	ScheduleAtMaintenance    string
+	ScheduledMaintenance     string
+	MasterPasswordVersion    string
+	MasterPasswordPolicy     string
+	KmsKeyAlias              string
+	SchemaCopyFrom           string
+	DataImport               string
+	DataExportOnDeprovision  string
 }
 
 func New(
@@ -133,23 +249,77 @@ func New(
 	parameterGroupSelector ParameterGroupSelector,
 	logger lager.Logger,
 ) *RDSBroker {
-	return &RDSBroker{
-		dbPrefix:                     config.DBPrefix,
-		masterPasswordSeed:           config.MasterPasswordSeed,
-		allowUserProvisionParameters: config.AllowUserProvisionParameters,
-		allowUserUpdateParameters:    config.AllowUserUpdateParameters,
-		allowUserBindParameters:      config.AllowUserBindParameters,
-		catalog:                      config.Catalog,
-		brokerName:                   config.BrokerName,
-		dbInstance:                   dbInstance,
-		sqlProvider:                  sqlProvider,
-		logger:                       logger.Session("broker"),
-		parameterGroupsSelector:      parameterGroupSelector,
-	}
+	broker := &RDSBroker{
+		dbPrefix:                       config.DBPrefix,
+		masterPasswordSeed:             config.MasterPasswordSeed,
+		masterPasswordPolicies:         config.MasterPasswordPolicies,
+		allowUserProvisionParameters:   config.AllowUserProvisionParameters,
+		allowUserUpdateParameters:      config.AllowUserUpdateParameters,
+		allowUserBindParameters:        config.AllowUserBindParameters,
+		allowDBInstanceAdoption:        config.AllowDBInstanceAdoption,
+		dbInstanceAdoptionPrefixes:     config.DBInstanceAdoptionPrefixes,
+		catalog:                        config.Catalog,
+		brokerName:                     config.BrokerName,
+		dbInstance:                     dbInstance,
+		sqlProvider:                    sqlProvider,
+		logger:                         logger.Session("broker"),
+		parameterGroupsSelector:        parameterGroupSelector,
+		lastOperationCache:             newLastOperationCache(time.Second * time.Duration(config.LastOperationCacheSeconds)),
+		databaseUsageCache:             newDatabaseUsageCache(time.Second * time.Duration(config.DatabaseUsageCacheSeconds)),
+		operationLeaseDuration:         time.Second * time.Duration(config.OperationLeaseSeconds),
+		brokerID:                       config.BrokerName + "-" + utils.RandomLowerAlphaNum(8),
+		securityGroupSets:              config.SecurityGroupSets,
+		organizationSecurityGroupSets:  config.OrganizationSecurityGroupSets,
+		networkTiers:                   config.NetworkTiers,
+		organizationNetworkTiers:       config.OrganizationNetworkTiers,
+		subnetCIDRBlocks:               config.SubnetCIDRBlocks,
+		kmsKeyAliases:                  config.KmsKeyAliases,
+		checkBindingConnections:        config.CheckBindingConnections,
+		softDeleteDuration:             24 * time.Hour * time.Duration(config.SoftDeleteDays),
+		trialWarningDuration:           24 * time.Hour * time.Duration(config.TrialExpiryWarningDays),
+		trialGraceDuration:             24 * time.Hour * time.Duration(config.TrialGraceDays),
+		trialExpiryWebhookURL:          config.TrialExpiryWebhookURL,
+		trialExpiryWebhookClient:       &http.Client{Timeout: 10 * time.Second},
+		backupAlertDuration:            time.Hour * time.Duration(config.BackupAlertHours),
+		restoreTestInterval:            24 * time.Hour * time.Duration(config.RestoreTestIntervalDays),
+		minorUpgradeConcurrency:        config.MinorUpgradeConcurrency,
+		retryAfterSeconds:              config.RetryAfterSeconds,
+		warmUpAttempts:                 config.ProvisionWarmUpAttempts,
+		inventoryBucket:                config.InventoryBucket,
+		inventoryPrefix:                config.InventoryPrefix,
+		region:                         config.Region,
+		priceTable:                     config.PriceTable,
+		deprovisionProtectionWindow:    time.Hour * time.Duration(config.DeprovisionProtectionHours),
+		revokeBindingsOnDeprovision:    config.RevokeBindingsOnDeprovision,
+		pendingTagWrites:               newPendingTagWrites(),
+		instanceMetrics:                &instanceMetrics{},
+		skipFinalSnapshotDefault:       config.SkipFinalSnapshotDefault,
+		tolerateRemovedPlans:           config.TolerateRemovedPlans,
+		confirmInstanceClassDowngrades: config.ConfirmInstanceClassDowngrades,
+		transactionIDAgeWarning:        int64(config.TransactionIDAgeWarning),
+		connectionUsageWarningPercent:  config.ConnectionUsageWarningPercent,
+		organizationDataImportBuckets:  config.OrganizationDataImportBuckets,
+		organizationDataExportBuckets:  config.OrganizationDataExportBuckets,
+		serviceAdvisory:                config.ServiceAdvisory,
+	}
+	if config.BackupAccount != nil {
+		backupAccount := *config.BackupAccount
+		backupAccount.FillDefaults()
+		broker.backupAccount = &backupAccount
+	}
+
+	if config.DNS != nil {
+		broker.dnsDomain = strings.Trim(config.DNS.Domain, ".")
+	}
+	broker.masterPasswordVersion = config.MasterPasswordVersion
+	if broker.masterPasswordVersion == 0 {
+		broker.masterPasswordVersion = MasterPasswordVersionSHA256
+	}
+	return broker
 }
 
 func (b *RDSBroker) Services(ctx context.Context) ([]domain.Service, error) {
-	brokerCatalog, err := json.Marshal(b.catalog)
+	brokerCatalog, err := json.Marshal(b.catalog.withoutDeprecatedPlans())
 	if err != nil {
 		b.logger.Error("marshal-error", err)
 		return []domain.Service{}, err
@@ -161,6 +331,8 @@ func (b *RDSBroker) Services(ctx context.Context) ([]domain.Service, error) {
 		return []domain.Service{}, err
 	}
 
+	b.catalog.withServiceLevelMetadata(apiCatalog.Services)
+
 	for i := range apiCatalog.Services {
 		apiCatalog.Services[i].Bindable = true
 		apiCatalog.Services[i].InstancesRetrievable = true
@@ -181,55 +353,87 @@ func (b *RDSBroker) Provision(
 		asyncAllowedLogKey: asyncAllowed,
 	})
 
+	b.auditLog(ctx, "provision", instanceID)
+
 	if !asyncAllowed {
 		return domain.ProvisionedServiceSpec{}, apiresponses.ErrAsyncRequired
 	}
 
+	if err := b.checkAWSAvailable(); err != nil {
+		return domain.ProvisionedServiceSpec{}, err
+	}
+
 	provisionParameters := ProvisionParameters{}
 	if b.allowUserProvisionParameters && len(details.RawParameters) > 0 {
-		decoder := json.NewDecoder(bytes.NewReader(details.RawParameters))
-		decoder.DisallowUnknownFields()
-		if err := decoder.Decode(&provisionParameters); err != nil {
-			return domain.ProvisionedServiceSpec{}, err
-		}
-		if err := provisionParameters.Validate(); err != nil {
+		if err := decodeParameters(details.RawParameters, &provisionParameters); err != nil {
 			return domain.ProvisionedServiceSpec{}, err
 		}
 	}
 
 	servicePlan, ok := b.catalog.FindServicePlan(details.PlanID)
 	if !ok {
-		return domain.ProvisionedServiceSpec{}, fmt.Errorf("Service Plan '%s' not found", details.PlanID)
+		return domain.ProvisionedServiceSpec{}, newUserError(ErrCodePlanNotFound, "Service Plan '%s' not found", details.PlanID)
+	}
+	if servicePlan.Deprecated {
+		return domain.ProvisionedServiceSpec{}, newUserError(ErrCodePlanDeprecated, "Service Plan '%s' is deprecated", details.PlanID)
+	}
+	if err := checkSkipFinalSnapshotAllowed(servicePlan, provisionParameters.SkipFinalSnapshot); err != nil {
+		return domain.ProvisionedServiceSpec{}, err
+	}
+	if err := checkPublicAccessAllowed(servicePlan, provisionParameters.PubliclyAccessible); err != nil {
+		return domain.ProvisionedServiceSpec{}, err
+	}
+	if err := checkAutoMinorVersionUpgradeAllowed(servicePlan, provisionParameters.AutoMinorVersionUpgrade); err != nil {
+		return domain.ProvisionedServiceSpec{}, err
+	}
+	if err := b.checkKmsKeyAliasAllowed(servicePlan, provisionParameters); err != nil {
+		return domain.ProvisionedServiceSpec{}, err
 	}
 
 	if aws.StringValue(servicePlan.RDSProperties.Engine) == "postgres" {
-		provisionParameters.Extensions = mergeExtensions(aws.StringValueSlice(servicePlan.RDSProperties.DefaultExtensions), provisionParameters.Extensions)
-		ok, unsupportedExtensions := extensionsAreSupported(servicePlan, provisionParameters.Extensions)
+		extensionLists := servicePlan.RDSProperties.extensionLists(aws.StringValue(servicePlan.RDSProperties.EngineVersion))
+		provisionParameters.Extensions = mergeStrings(aws.StringValueSlice(extensionLists.DefaultExtensions), provisionParameters.Extensions)
+		ok, unsupportedExtensions := extensionsAreSupported(extensionLists, provisionParameters.Extensions)
 		if !ok {
-			return domain.ProvisionedServiceSpec{}, fmt.Errorf("%s is not supported", unsupportedExtensions)
+			return domain.ProvisionedServiceSpec{}, newUserError(ErrCodeExtensionNotAllowed, "%s is not supported", unsupportedExtensions)
+		}
+		if err := extensionsAreCompatible(servicePlan, provisionParameters.Extensions); err != nil {
+			return domain.ProvisionedServiceSpec{}, err
 		}
 	}
 
-	if provisionParameters.RestoreFromLatestSnapshotOf != nil && provisionParameters.RestoreFromPointInTimeOf != nil {
-		return domain.ProvisionedServiceSpec{}, fmt.Errorf("Cannot use both restore_from_latest_snapshot_of and restore_from_point_in_time_of at the same time")
-	}
-
-	if provisionParameters.RestoreFromLatestSnapshotOf == nil && provisionParameters.RestoreFromLatestSnapshotBefore != nil {
-		return domain.ProvisionedServiceSpec{}, fmt.Errorf("Parameter restore_from_latest_snapshot_before should be used with restore_from_latest_snapshot_of")
+	additionalDatabases, err := mergeAdditionalDatabases(servicePlan, b.dbName(instanceID), nil, provisionParameters.AdditionalDatabases)
+	if err != nil {
+		return domain.ProvisionedServiceSpec{}, err
 	}
+	provisionParameters.AdditionalDatabases = additionalDatabases
 
-	if provisionParameters.RestoreFromPointInTimeOf == nil && provisionParameters.RestoreFromPointInTimeBefore != nil {
-		return domain.ProvisionedServiceSpec{}, fmt.Errorf("Parameter restore_from_point_in_time_before should be used with restore_from_point_in_time_of")
-	}
+	if provisionParameters.AdoptDBInstance != nil {
+		err := b.adoptDBInstance(instanceID, details, provisionParameters, servicePlan)
+		if err != nil {
+			return domain.ProvisionedServiceSpec{}, err
+		}
+		b.hintRetryAfter(ctx, RetryAfterProvision)
+		return domain.ProvisionedServiceSpec{IsAsync: true, OperationData: operationDataProvision}, nil
 
-	if provisionParameters.RestoreFromLatestSnapshotOf != nil {
-		err := b.restoreFromSnapshot(
+	} else if provisionParameters.RestoreFromLatestSnapshotOf != nil {
+		operationData, err := b.restoreFromSnapshot(
 			ctx, instanceID, details, asyncAllowed,
 			provisionParameters, servicePlan,
 		)
 		if err != nil {
 			return domain.ProvisionedServiceSpec{}, err
 		}
+		b.hintRetryAfter(ctx, RetryAfterProvision)
+		return domain.ProvisionedServiceSpec{IsAsync: true, OperationData: operationData}, nil
+
+	} else if provisionParameters.RestorePrevious {
+		operationData, err := b.restoreFromPreviousInstance(instanceID, details, provisionParameters, servicePlan)
+		if err != nil {
+			return domain.ProvisionedServiceSpec{}, err
+		}
+		b.hintRetryAfter(ctx, RetryAfterProvision)
+		return domain.ProvisionedServiceSpec{IsAsync: true, OperationData: operationData}, nil
 
 	} else if provisionParameters.RestoreFromPointInTimeOf != nil {
 		err := b.restoreFromPointInTime(
@@ -241,16 +445,28 @@ func (b *RDSBroker) Provision(
 		}
 
 	} else {
+		if provisionParameters.TemplateDatabaseInstance != nil {
+			if err := b.checkTemplateDatabaseInstance(details, servicePlan, *provisionParameters.TemplateDatabaseInstance); err != nil {
+				return domain.ProvisionedServiceSpec{}, err
+			}
+		}
+		if provisionParameters.ImportData != nil {
+			if err := b.checkDataImportAllowed(details.OrganizationGUID, servicePlan, *provisionParameters.ImportData); err != nil {
+				return domain.ProvisionedServiceSpec{}, err
+			}
+		}
 		createDBInstance, err := b.newCreateDBInstanceInput(instanceID, servicePlan, provisionParameters, details)
 		if err != nil {
 			return domain.ProvisionedServiceSpec{}, err
 		}
 		if err := b.dbInstance.Create(createDBInstance); err != nil {
+			b.cleanUpOrphanedDBInstance(instanceID, err)
 			return domain.ProvisionedServiceSpec{}, err
 		}
 	}
 
-	return domain.ProvisionedServiceSpec{IsAsync: true}, nil
+	b.hintRetryAfter(ctx, RetryAfterProvision)
+	return domain.ProvisionedServiceSpec{IsAsync: true, OperationData: operationDataProvision}, nil
 }
 
 func (b *RDSBroker) checkPermissionsFromTags(
@@ -258,10 +474,13 @@ func (b *RDSBroker) checkPermissionsFromTags(
 	tagsByName map[string]string,
 ) error {
 	if tagsByName[awsrds.TagSpaceID] != details.SpaceGUID || tagsByName[awsrds.TagOrganizationID] != details.OrganizationGUID {
-		return fmt.Errorf("The service instance you are getting a snapshot from is not in the same org or space")
+		return newUserError(ErrCodeRestoreNotPermitted, "The service instance you are getting a snapshot from is not in the same org or space")
 	}
-	if tagsByName[awsrds.TagPlanID] != details.PlanID {
-		return fmt.Errorf("You must use the same plan as the service instance you are restoring from")
+	if tagsByName[awsrds.TagKubernetesNamespace] != platformContextFrom(details.RawContext).kubernetesNamespace() {
+		return newUserError(ErrCodeRestoreNotPermitted, "The service instance you are getting a snapshot from is not in the same namespace")
+	}
+	if tagsByName[awsrds.TagPlanID] != details.PlanID && !b.isKmsKeyOnlyPlanChange(tagsByName[awsrds.TagPlanID], details.PlanID) {
+		return newUserError(ErrCodeRestoreNotPermitted, "You must use the same plan as the service instance you are restoring from")
 	}
 
 	return nil
@@ -277,11 +496,11 @@ func (b *RDSBroker) restoreFromPointInTime(
 ) error {
 	if engine := servicePlan.RDSProperties.Engine; engine != nil {
 		if *engine != "postgres" && *engine != "mysql" {
-			return fmt.Errorf("Restore from point in time not supported for engine '%s'", *engine)
+			return newUserError(ErrCodeUnsupportedByEngine, "Restore from point in time not supported for engine '%s'", *engine)
 		}
 	}
 	if *provisionParameters.RestoreFromPointInTimeOf == "" {
-		return fmt.Errorf("Invalid guid: '%s'", *provisionParameters.RestoreFromPointInTimeOf)
+		return newUserError(ErrCodeInvalidParameters, "Invalid guid: '%s'", *provisionParameters.RestoreFromPointInTimeOf)
 	}
 
 	var restoreTime *time.Time
@@ -293,7 +512,7 @@ func (b *RDSBroker) restoreFromPointInTime(
 				time.UTC,
 			)
 			if err != nil {
-				return fmt.Errorf("Parameter restore_from_point_in_time_before should be a date and a time: %s", err)
+				return newUserError(ErrCodeInvalidParameters, "Parameter restore_from_point_in_time_before should be a date and a time: %s", err)
 			}
 			restoreTime = &parsedTime
 		}
@@ -303,13 +522,13 @@ func (b *RDSBroker) restoreFromPointInTime(
 
 	existingInstance, err := b.dbInstance.Describe(b.dbInstanceIdentifier(restoreFromDBInstanceID))
 	if err != nil {
-		return fmt.Errorf("Cannot find instance %s", b.dbInstanceIdentifier(restoreFromDBInstanceID))
+		return newUserError(ErrCodeRestoreSourceNotFound, "Cannot find instance %s", b.dbInstanceIdentifier(restoreFromDBInstanceID))
 	}
 
 	dbARN := *(existingInstance.DBInstanceArn)
 	tags, err := b.dbInstance.GetResourceTags(dbARN)
 	if err != nil {
-		return fmt.Errorf("Cannot find instance %s", dbARN)
+		return newUserError(ErrCodeRestoreSourceNotFound, "Cannot find instance %s", dbARN)
 	}
 
 	tagsByName := awsrds.RDSTagsValues(tags)
@@ -317,19 +536,77 @@ func (b *RDSBroker) restoreFromPointInTime(
 		return err
 	}
 
+	if tagsByName[awsrds.TagPlanID] != details.PlanID {
+		return newUserError(ErrCodeRestoreNotPermitted, "Cannot restore from a point in time into a plan with a different KMS key, restore from a snapshot instead")
+	}
+
+	if err := checkPointInTimeRestorable(existingInstance, tagsByName, restoreTime, time.Now()); err != nil {
+		return err
+	}
+
 	if extensionsTag, ok := tagsByName[awsrds.TagExtensions]; ok {
 		if extensionsTag != "" {
 			existingExts := unpackExtensions(extensionsTag)
-			provisionParameters.Extensions = mergeExtensions(provisionParameters.Extensions, existingExts)
+			provisionParameters.Extensions = mergeStrings(provisionParameters.Extensions, existingExts)
 		}
 	}
+	// the restored instance has the source's additional databases already
+	provisionParameters.AdditionalDatabases = mergeStrings(provisionParameters.AdditionalDatabases, unpackAdditionalDatabases(tagsByName[awsrds.TagAdditionalDatabases]))
 
 	restoreInput, err := b.restoreDBInstancePointInTimeInput(instanceID, restoreFromDBInstanceID, restoreTime, servicePlan, provisionParameters, details)
 	if err != nil {
 		return err
 	}
 
-	return b.dbInstance.RestoreToPointInTime(restoreInput)
+	if err := b.dbInstance.RestoreToPointInTime(restoreInput); err != nil {
+		b.cleanUpOrphanedDBInstance(instanceID, err)
+		return err
+	}
+
+	return nil
+}
+
+// checkPointInTimeRestorable makes sure RDS will be able to restore the
+// source instance to restoreTime, so that we can reject the request rather
+// than have the restore fail asynchronously. A nil restoreTime means the
+// latest restorable time. MySQL instances can only be restored as far back
+// as the binlog retention which ensureBinlogRetention has applied to them.
+func checkPointInTimeRestorable(dbInstance *rds.DBInstance, tagsByName map[string]string, restoreTime *time.Time, now time.Time) error {
+	dbInstanceIdentifier := aws.StringValue(dbInstance.DBInstanceIdentifier)
+
+	backupRetentionPeriod := aws.Int64Value(dbInstance.BackupRetentionPeriod)
+	if backupRetentionPeriod == 0 {
+		return newUserError(ErrCodeInvalidRestoreWindow, "Cannot restore instance %s from a point in time as it has no automated backups", dbInstanceIdentifier)
+	}
+
+	if restoreTime == nil {
+		return nil
+	}
+
+	retention := time.Duration(backupRetentionPeriod) * 24 * time.Hour
+	switch aws.StringValue(dbInstance.Engine) {
+	case "mariadb", "mysql":
+		binlogRetentionHours, err := strconv.ParseInt(tagsByName[awsrds.TagBinlogRetentionHours], 10, 64)
+		if err != nil {
+			return newUserError(ErrCodeInvalidRestoreWindow, "Cannot restore instance %s to a point in time before its latest restorable time as its plan doesn't retain binary logs", dbInstanceIdentifier)
+		}
+		retention = time.Duration(binlogRetentionHours) * time.Hour
+	}
+
+	earliestRestorableTime := now.Add(-retention)
+	if dbInstance.InstanceCreateTime != nil && dbInstance.InstanceCreateTime.After(earliestRestorableTime) {
+		earliestRestorableTime = *dbInstance.InstanceCreateTime
+	}
+	if restoreTime.Before(earliestRestorableTime) {
+		return newUserError(
+			ErrCodeInvalidRestoreWindow,
+			"Cannot restore instance %s to a point in time before %s, which is the earliest time its backups cover",
+			dbInstanceIdentifier,
+			earliestRestorableTime.UTC().Format(RestoreFromPointInTimeBeforeTimeFormat),
+		)
+	}
+
+	return nil
 }
 
 func (b *RDSBroker) restoreFromSnapshot(
@@ -339,24 +616,24 @@ func (b *RDSBroker) restoreFromSnapshot(
 	asyncAllowed bool,
 	provisionParameters ProvisionParameters,
 	servicePlan ServicePlan,
-) error {
+) (string, error) {
 	if *provisionParameters.RestoreFromLatestSnapshotOf == "" {
-		return fmt.Errorf("Invalid guid: '%s'", *provisionParameters.RestoreFromLatestSnapshotOf)
+		return "", newUserError(ErrCodeInvalidParameters, "Invalid guid: '%s'", *provisionParameters.RestoreFromLatestSnapshotOf)
 	}
 	if engine := servicePlan.RDSProperties.Engine; engine != nil {
 		if *engine != "postgres" && *engine != "mysql" {
-			return fmt.Errorf("Restore from snapshot not supported for engine '%s'", *engine)
+			return "", newUserError(ErrCodeUnsupportedByEngine, "Restore from snapshot not supported for engine '%s'", *engine)
 		}
 	}
 	restoreFromDBInstanceID := b.dbInstanceIdentifier(*provisionParameters.RestoreFromLatestSnapshotOf)
 	snapshots, err := b.dbInstance.DescribeSnapshots(restoreFromDBInstanceID)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	if provisionParameters.RestoreFromLatestSnapshotBefore != nil {
 		if *provisionParameters.RestoreFromLatestSnapshotBefore == "" {
-			return fmt.Errorf("Parameter restore_from_latest_snapshot_before must not be empty")
+			return "", newUserError(ErrCodeInvalidParameters, "Parameter restore_from_latest_snapshot_before must not be empty")
 		}
 
 		restoreFromLatestSnapshotBeforeTime, err := time.ParseInLocation(
@@ -365,7 +642,7 @@ func (b *RDSBroker) restoreFromSnapshot(
 			time.UTC,
 		)
 		if err != nil {
-			return fmt.Errorf("Parameter restore_from_latest_snapshot_before should be a date and a time: %s", err)
+			return "", newUserError(ErrCodeInvalidParameters, "Parameter restore_from_latest_snapshot_before should be a date and a time: %s", err)
 		}
 
 		prunedSnapshots := make([]*rds.DBSnapshot, 0)
@@ -386,7 +663,7 @@ func (b *RDSBroker) restoreFromSnapshot(
 	}
 
 	if len(snapshots) == 0 {
-		return fmt.Errorf("No snapshots found for guid '%s'", *provisionParameters.RestoreFromLatestSnapshotOf)
+		return "", newUserError(ErrCodeRestoreSourceNotFound, "No snapshots found for guid '%s'", *provisionParameters.RestoreFromLatestSnapshotOf)
 	}
 
 	snapshot := snapshots[0]
@@ -397,29 +674,154 @@ func (b *RDSBroker) restoreFromSnapshot(
 		"snapshotIdentifier": snapshot.DBSnapshotIdentifier,
 	})
 
+	return b.restoreFromChosenSnapshot(instanceID, details, provisionParameters, servicePlan, snapshot)
+}
+
+// restoreFromPreviousInstance restores the final snapshot which was taken
+// when an earlier service instance with the same GUID was deprovisioned.
+func (b *RDSBroker) restoreFromPreviousInstance(
+	instanceID string,
+	details domain.ProvisionDetails,
+	provisionParameters ProvisionParameters,
+	servicePlan ServicePlan,
+) (string, error) {
+	if engine := servicePlan.RDSProperties.Engine; engine != nil {
+		if *engine != "postgres" && *engine != "mysql" {
+			return "", newUserError(ErrCodeUnsupportedByEngine, "Restore from snapshot not supported for engine '%s'", *engine)
+		}
+	}
+
+	snapshot, err := b.dbInstance.DescribeSnapshot(awsrds.FinalSnapshotIdentifier(b.dbInstanceIdentifier(instanceID)))
+	if err != nil {
+		if err == awsrds.ErrDBSnapshotDoesNotExist {
+			return "", newUserError(ErrCodeRestoreSourceNotFound, "No final snapshot found for a previous service instance with guid '%s'", instanceID)
+		}
+		return "", err
+	}
+
+	b.logger.Info("chose-final-snapshot", lager.Data{
+		instanceIDLogKey:     instanceID,
+		detailsLogKey:        details,
+		"snapshotIdentifier": snapshot.DBSnapshotIdentifier,
+	})
+
+	return b.restoreFromChosenSnapshot(instanceID, details, provisionParameters, servicePlan, snapshot)
+}
+
+// restoreFromChosenSnapshot checks that the snapshot may be restored for the
+// service instance, from the tags it was given by the instance it was taken
+// of, and restores it.
+func (b *RDSBroker) restoreFromChosenSnapshot(
+	instanceID string,
+	details domain.ProvisionDetails,
+	provisionParameters ProvisionParameters,
+	servicePlan ServicePlan,
+	snapshot *rds.DBSnapshot,
+) (string, error) {
 	tags, err := b.dbInstance.GetResourceTags(aws.StringValue(snapshot.DBSnapshotArn))
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	tagsByName := awsrds.RDSTagsValues(tags)
 	if err := b.checkPermissionsFromTags(details, tagsByName); err != nil {
-		return err
+		return "", err
 	}
 
 	if extensionsTag, ok := tagsByName[awsrds.TagExtensions]; ok {
 		if extensionsTag != "" {
 			snapshotExts := unpackExtensions(extensionsTag)
-			provisionParameters.Extensions = mergeExtensions(provisionParameters.Extensions, snapshotExts)
+			provisionParameters.Extensions = mergeStrings(provisionParameters.Extensions, snapshotExts)
 		}
 	}
+	provisionParameters.AdditionalDatabases = mergeStrings(provisionParameters.AdditionalDatabases, unpackAdditionalDatabases(tagsByName[awsrds.TagAdditionalDatabases]))
 
 	restoreDBInstanceInput, err := b.restoreDBInstanceInput(instanceID, snapshot, servicePlan, provisionParameters, details)
 	if err != nil {
-		return err
+		return "", err
+	}
+
+	if tagsByName[awsrds.TagPlanID] != details.PlanID {
+		if err := b.copySnapshotForRestore(instanceID, snapshot, servicePlan, restoreDBInstanceInput); err != nil {
+			return "", err
+		}
+		return operationDataReencryptedRestore, nil
+	}
+
+	if err := b.dbInstance.Restore(restoreDBInstanceInput); err != nil {
+		b.cleanUpOrphanedDBInstance(instanceID, err)
+		return "", err
+	}
+
+	return operationDataProvision, nil
+}
+
+// cleanUpOrphanedDBInstance is called when a create or restore call has
+// failed, or when the DB instance it created has failed. The instance may
+// nonetheless exist (e.g. the request timed out after AWS accepted it), in
+// which case the platform will believe there is no service instance while we
+// keep paying for the database. RDS won't delete an instance while it is
+// being created, so such an instance is tagged for housekeeping to delete
+// later. Any error here is logged rather than returned so the
+// original failure is what the user sees.
+func (b *RDSBroker) cleanUpOrphanedDBInstance(instanceID string, provisionErr error) {
+	if awsRdsErr, ok := provisionErr.(awsrds.Error); ok && awsRdsErr.Code() == awsrds.ErrCodeDBInstanceAlreadyExists {
+		// the instance predates this request so it isn't ours to remove
+		return
+	}
+
+	dbInstanceIdentifier := b.dbInstanceIdentifier(instanceID)
+	dbInstance, err := b.dbInstance.Describe(dbInstanceIdentifier)
+	if err != nil || dbInstance == nil {
+		if err != nil && err != awsrds.ErrDBInstanceDoesNotExist {
+			b.logger.Error("orphan-mitigation.describe", err, lager.Data{instanceIDLogKey: instanceID})
+		}
+		return
+	}
+
+	owned, err := b.isOwnedDBInstance(instanceID, dbInstance)
+	if err != nil {
+		b.logger.Error("orphan-mitigation.get-tags", err, lager.Data{instanceIDLogKey: instanceID})
+		return
+	}
+	if !owned {
+		return
+	}
+
+	if aws.StringValue(dbInstance.DBInstanceStatus) != "creating" {
+		b.logger.Info("orphan-mitigation.delete", lager.Data{instanceIDLogKey: instanceID})
+		err := b.dbInstance.Delete(dbInstanceIdentifier, true)
+		if err == nil {
+			return
+		}
+		b.logger.Error("orphan-mitigation.delete", err, lager.Data{instanceIDLogKey: instanceID})
+	}
+
+	b.logger.Info("orphan-mitigation.mark", lager.Data{instanceIDLogKey: instanceID})
+	err = b.dbInstance.AddTagsToResource(
+		aws.StringValue(dbInstance.DBInstanceArn),
+		awsrds.BuildRDSTags(map[string]string{
+			awsrds.TagOrphaned: time.Now().UTC().Format(time.RFC3339),
+		}),
+	)
+	if err != nil {
+		b.logger.Error("orphan-mitigation.mark", err, lager.Data{instanceIDLogKey: instanceID})
+	}
+}
+
+// isOwnedDBInstance checks that dbInstance was created by this broker on
+// behalf of the service instance instanceID. Adopted instances predate their
+// service instance and hold data it didn't put there, so they never are.
+func (b *RDSBroker) isOwnedDBInstance(instanceID string, dbInstance *rds.DBInstance) (bool, error) {
+	tags, err := b.dbInstance.GetResourceTags(aws.StringValue(dbInstance.DBInstanceArn))
+	if err != nil {
+		return false, err
 	}
+	tagsByName := awsrds.RDSTagsValues(tags)
 
-	return b.dbInstance.Restore(restoreDBInstanceInput)
+	return tagsByName[awsrds.TagBrokerName] == b.brokerName &&
+		tagsByName[awsrds.TagChargeableEntity] == instanceID &&
+		tagsByName[awsrds.TagAdoptedFrom] == "", nil
 }
 
 func (b *RDSBroker) GetBinding(ctx context.Context, instanceID, bindingID string, details domain.FetchBindingDetails) (domain.GetBindingSpec, error) {
@@ -435,6 +837,10 @@ func (b *RDSBroker) GetInstance(
 		instanceIDLogKey: instanceID,
 	})
 
+	if err := b.checkAWSAvailable(); err != nil {
+		return domain.GetInstanceDetailsSpec{}, err
+	}
+
 	dbInstance, err := b.dbInstance.Describe(b.dbInstanceIdentifier(instanceID))
 	if err != nil {
 		b.logger.Error("describe-instance", err)
@@ -459,38 +865,137 @@ func (b *RDSBroker) GetInstance(
 		extensions = unpackExtensions(extensionsTag)
 	}
 
-	var ok bool
-	planID := details.PlanID
+	// the tags record what the DB instance actually is, which the platform
+	// can disagree with after a failed update, so they are what we report
+	var warnings []string
+	planID := tagsByName[awsrds.TagPlanID]
 	if planID == "" {
-		// supplying the plan id as a GET parameter doesn't appear to be
-		// a mandatory part of the OSB spec but we prefer it if it's
-		// present as it doesn't have the same (small) possibility of
-		// being incorrect that the aws tag has, which we fall back to
-		// here
-		planID, ok = tagsByName[awsrds.TagPlanID]
-		if !ok {
-			err = fmt.Errorf("Can't find plan id for this service instance")
-			b.logger.Error("cant-find-plan-id", err)
-			return domain.GetInstanceDetailsSpec{}, err
-		}
+		planID = details.PlanID
+	} else if details.PlanID != "" && details.PlanID != planID {
+		b.logger.Info("get-instance.plan-mismatch", lager.Data{
+			instanceIDLogKey:  instanceID,
+			servicePlanLogKey: details.PlanID,
+			"awsTagsPlanID":   planID,
+		})
+		warnings = append(warnings, fmt.Sprintf("The platform records plan '%s' for this service instance, but its DB instance is on plan '%s'", details.PlanID, planID))
+	}
+	if planID == "" {
+		err = fmt.Errorf("Can't find plan id for this service instance")
+		b.logger.Error("cant-find-plan-id", err)
+		return domain.GetInstanceDetailsSpec{}, err
+	}
+	serviceID := tagsByName[awsrds.TagServiceID]
+	if serviceID == "" {
+		serviceID = details.ServiceID
+	} else if details.ServiceID != "" && details.ServiceID != serviceID {
+		b.logger.Info("get-instance.service-mismatch", lager.Data{
+			instanceIDLogKey:   instanceID,
+			"serviceID":        details.ServiceID,
+			"awsTagsServiceID": serviceID,
+		})
+		warnings = append(warnings, fmt.Sprintf("The platform records service '%s' for this service instance, but its DB instance is of service '%s'", details.ServiceID, serviceID))
 	}
+
 	servicePlan, ok := b.catalog.FindServicePlan(planID)
 	if !ok {
-		return domain.GetInstanceDetailsSpec{}, fmt.Errorf("Service Plan '%s' not found", planID)
+		if !b.tolerateRemovedPlans {
+			return domain.GetInstanceDetailsSpec{}, newUserError(ErrCodePlanNotFound, "Service Plan '%s' not found", planID)
+		}
+		b.logger.Info("get-instance.plan-removed", lager.Data{
+			instanceIDLogKey:  instanceID,
+			servicePlanLogKey: planID,
+		})
+		servicePlan = removedServicePlan(planID, dbInstance)
+		warnings = append(warnings, removedPlanWarning(planID))
 	}
 
-	skipFinalSnapshot, err := resolveSkipFinalSnapshot(servicePlan, tagsByName[awsrds.TagSkipFinalSnapshot])
+	skipFinalSnapshot, err := b.resolveSkipFinalSnapshot(servicePlan, tagsByName[awsrds.TagSkipFinalSnapshot])
 	if err != nil {
 		b.logger.Error("resolve-skip-final-snapshot", err)
 		return domain.GetInstanceDetailsSpec{}, err
 	}
 
 	instanceParams := map[string]interface{}{
-		"backup_retention_period":      dbInstance.BackupRetentionPeriod,
-		"extensions":                   extensions,
-		"preferred_backup_window":      dbInstance.PreferredBackupWindow,
-		"preferred_maintenance_window": dbInstance.PreferredMaintenanceWindow,
-		"skip_final_snapshot":          skipFinalSnapshot,
+		"backup_retention_period":        dbInstance.BackupRetentionPeriod,
+		"extensions":                     extensions,
+		"preferred_backup_window":        dbInstance.PreferredBackupWindow,
+		"preferred_maintenance_window":   dbInstance.PreferredMaintenanceWindow,
+		"skip_final_snapshot":            skipFinalSnapshot,
+		"engine_version":                 dbInstance.EngineVersion,
+		"db_instance_class":              dbInstance.DBInstanceClass,
+		"allocated_storage":              dbInstance.AllocatedStorage,
+		"max_allocated_storage":          dbInstance.MaxAllocatedStorage,
+		"multi_az":                       dbInstance.MultiAZ,
+		"storage_type":                   dbInstance.StorageType,
+		"pending_modifications":          dbInstance.PendingModifiedValues,
+		"publicly_accessible":            dbInstance.PubliclyAccessible,
+		"endpoint_type":                  endpointType(dbInstance),
+		"auto_minor_version_upgrade":     dbInstance.AutoMinorVersionUpgrade,
+		"schedule_at_maintenance_window": tagsByName[awsrds.TagScheduleAtMaintenance] == "true",
+		"availability_zone":              dbInstance.AvailabilityZone,
+		"secondary_availability_zone":    dbInstance.SecondaryAvailabilityZone,
+	}
+
+	if aws.BoolValue(dbInstance.MultiAZ) {
+		// the instance is still described without them, as events are
+		// only for information
+		failovers, err := b.recentFailovers(dbInstance, time.Now())
+		if err != nil {
+			b.logger.Error("describe-failovers", err, lager.Data{instanceIDLogKey: instanceID})
+		} else {
+			instanceParams["recent_failovers"] = failovers
+		}
+	}
+
+	if aws.StringValue(dbInstance.Engine) == "postgres" {
+		// the extensions of the instance's current version, which can be
+		// ahead of its plan's
+		extensionLists := servicePlan.RDSProperties.extensionLists(aws.StringValue(dbInstance.EngineVersion))
+		instanceParams["allowed_extensions"] = aws.StringValueSlice(extensionLists.AllowedExtensions)
+	}
+
+	if kmsKeyAlias := tagsByName[awsrds.TagKmsKeyAlias]; kmsKeyAlias != "" {
+		instanceParams["kms_key_alias"] = kmsKeyAlias
+	}
+
+	if dataExportBucket := tagsByName[awsrds.TagDataExportOnDeprovision]; dataExportBucket != "" {
+		instanceParams["export_data_on_deprovision"] = dataExportBucket
+	}
+
+	if advisory := b.serviceAdvisoryFor(aws.StringValue(dbInstance.Engine), time.Now()); advisory != "" {
+		instanceParams["service_advisory"] = advisory
+	}
+
+	if rotatedAt, ok := masterPasswordRotatedAt(tagsByName); ok {
+		instanceParams["master_password_rotated_at"] = rotatedAt.Format(time.RFC3339)
+	}
+
+	if scheduledSteps := unpackScheduledSteps(tagsByName[awsrds.TagScheduledMaintenance]); len(scheduledSteps) > 0 {
+		instanceParams["scheduled_maintenance"] = scheduledSteps
+	}
+
+	if securityGroupSet, ok := tagsByName[awsrds.TagSecurityGroupSet]; ok {
+		instanceParams["security_group_set"] = securityGroupSet
+	}
+
+	if networkTier, ok := tagsByName[awsrds.TagNetworkTier]; ok {
+		instanceParams["network_tier"] = networkTier
+	}
+
+	if instanceName, ok := tagsByName[awsrds.TagInstanceName]; ok {
+		instanceParams["instance_name"] = instanceName
+	}
+
+	if previousNames, ok := tagsByName[awsrds.TagPreviousInstanceNames]; ok {
+		instanceParams["previous_instance_names"] = unpackInstanceNames(previousNames)
+	}
+
+	if databasesToPurge, ok := tagsByName[awsrds.TagDatabasesToPurge]; ok {
+		instanceParams["databases_to_purge"] = unpackDatabasesToPurge(databasesToPurge)
+	}
+
+	if pgauditLog, ok := tagsByName[awsrds.TagPgauditLog]; ok {
+		instanceParams["pgaudit_log"] = strings.Split(pgauditLog, ":")
 	}
 
 	if tagsByName[awsrds.TagOriginDatabase] != "" {
@@ -506,11 +1011,63 @@ func (b *RDSBroker) GetInstance(
 		}
 	}
 
+	instanceParams["latest_restorable_time"] = dbInstance.LatestRestorableTime
+	if backupStatus, err := b.backupStatus(dbInstance, tagsByName); err != nil {
+		b.logger.Error("get-instance-backup-status", err)
+	} else {
+		instanceParams["latest_snapshot_time"] = backupStatus.LatestSnapshotTime
+	}
+
+	if warning := instanceAgeWarning(dbInstance, servicePlan, time.Now()); warning != "" {
+		warnings = append(warnings, warning)
+	}
+	if warning := b.oldTransactionIDWarning(instanceID); warning != "" {
+		warnings = append(warnings, warning)
+	}
+	if len(warnings) > 0 {
+		instanceParams["warnings"] = warnings
+	}
+
+	if b.databaseUsageCache.enabled() && aws.StringValue(dbInstance.DBInstanceStatus) == "available" {
+		if usage, err := b.databaseUsage(instanceID, dbInstance); err != nil {
+			b.logger.Error("get-instance-database-usage", err, lager.Data{instanceIDLogKey: instanceID})
+		} else {
+			instanceParams["database_usage"] = usage
+		}
+	}
+
 	return domain.GetInstanceDetailsSpec{
+		ServiceID:  serviceID,
+		PlanID:     planID,
 		Parameters: instanceParams,
 	}, nil
 }
 
+// taggedPlanID returns the plan ID the DB instance of a service instance was
+// tagged with when it was last provisioned or updated.
+func (b *RDSBroker) taggedPlanID(instanceID string) (string, error) {
+	dbInstance, err := b.dbInstance.Describe(b.dbInstanceIdentifier(instanceID))
+	if err != nil {
+		if err == awsrds.ErrDBInstanceDoesNotExist {
+			return "", apiresponses.ErrInstanceDoesNotExist
+		}
+		return "", err
+	}
+
+	tags, err := b.dbInstance.GetResourceTags(aws.StringValue(dbInstance.DBInstanceArn))
+	if err != nil {
+		return "", err
+	}
+
+	planID := awsrds.RDSTagsValues(tags)[awsrds.TagPlanID]
+	if planID == "" {
+		err = fmt.Errorf("Can't find plan id for this service instance")
+		b.logger.Error("cant-find-plan-id", err, lager.Data{instanceIDLogKey: instanceID})
+		return "", err
+	}
+	return planID, nil
+}
+
 func (b *RDSBroker) LastBindingOperation(ctx context.Context, first, second string, pollDetails domain.PollDetails) (domain.LastOperation, error) {
 	return domain.LastOperation{}, fmt.Errorf("LastBindingOperation method not implemented")
 }
@@ -521,26 +1078,35 @@ func (b *RDSBroker) Update(
 	details domain.UpdateDetails,
 	asyncAllowed bool,
 ) (domain.UpdateServiceSpec, error) {
+	b.lastOperationCache.invalidate(instanceID)
+
 	b.logger.Debug("update", lager.Data{
 		instanceIDLogKey:   instanceID,
 		detailsLogKey:      details,
 		asyncAllowedLogKey: asyncAllowed,
 	})
 
+	b.auditLog(ctx, "update", instanceID)
+
 	b.logger.Info("update", lager.Data{instanceIDLogKey: instanceID, detailsLogKey: details})
 
 	if !asyncAllowed {
 		return domain.UpdateServiceSpec{}, apiresponses.ErrAsyncRequired
 	}
 
+	if err := b.checkAWSAvailable(); err != nil {
+		return domain.UpdateServiceSpec{}, err
+	}
+
+	releaseOperationLock, err := b.acquireOperationLock(instanceID)
+	if err != nil {
+		return domain.UpdateServiceSpec{}, err
+	}
+	defer releaseOperationLock()
+
 	updateParameters := UpdateParameters{}
 	if b.allowUserUpdateParameters && len(details.RawParameters) > 0 {
-		decoder := json.NewDecoder(bytes.NewReader(details.RawParameters))
-		decoder.DisallowUnknownFields()
-		if err := decoder.Decode(&updateParameters); err != nil {
-			return domain.UpdateServiceSpec{}, err
-		}
-		if err := updateParameters.Validate(); err != nil {
+		if err := decodeParameters(details.RawParameters, &updateParameters); err != nil {
 			return domain.UpdateServiceSpec{}, err
 		}
 		b.logger.Debug("update-parsed-params", lager.Data{updateParametersLogKey: updateParameters})
@@ -548,7 +1114,17 @@ func (b *RDSBroker) Update(
 
 	service, ok := b.catalog.FindService(details.ServiceID)
 	if !ok {
-		return domain.UpdateServiceSpec{}, fmt.Errorf("Service '%s' not found", details.ServiceID)
+		return domain.UpdateServiceSpec{}, newUserError(ErrCodeServiceNotFound, "Service '%s' not found", details.ServiceID)
+	}
+
+	if details.PreviousValues.PlanID == "" {
+		// previous_values is optional in the OSB spec and some platforms
+		// leave it out, so fall back to the plan the instance is tagged with
+		previousPlanID, err := b.taggedPlanID(instanceID)
+		if err != nil {
+			return domain.UpdateServiceSpec{}, err
+		}
+		details.PreviousValues.PlanID = previousPlanID
 	}
 
 	if details.PlanID != details.PreviousValues.PlanID {
@@ -557,18 +1133,30 @@ func (b *RDSBroker) Update(
 		}
 		err := updateParameters.CheckForCompatibilityWithPlanChange()
 		if err != nil {
-			return domain.UpdateServiceSpec{}, err
+			return domain.UpdateServiceSpec{}, newInvalidParametersError(err)
 		}
 	}
 
 	servicePlan, ok := b.catalog.FindServicePlan(details.PlanID)
 	if !ok {
-		return domain.UpdateServiceSpec{}, fmt.Errorf("Service Plan '%s' not found", details.PlanID)
+		return domain.UpdateServiceSpec{}, newUserError(ErrCodePlanNotFound, "Service Plan '%s' not found", details.PlanID)
+	}
+	if servicePlan.Deprecated && details.PlanID != details.PreviousValues.PlanID {
+		return domain.UpdateServiceSpec{}, newUserError(ErrCodePlanDeprecated, "Service Plan '%s' is deprecated", details.PlanID)
+	}
+	if err := checkSkipFinalSnapshotAllowed(servicePlan, updateParameters.SkipFinalSnapshot); err != nil {
+		return domain.UpdateServiceSpec{}, err
+	}
+	if err := checkPublicAccessAllowed(servicePlan, updateParameters.PubliclyAccessible); err != nil {
+		return domain.UpdateServiceSpec{}, err
+	}
+	if err := checkAutoMinorVersionUpgradeAllowed(servicePlan, updateParameters.AutoMinorVersionUpgrade); err != nil {
+		return domain.UpdateServiceSpec{}, err
 	}
 
 	previousServicePlan, ok := b.catalog.FindServicePlan(details.PreviousValues.PlanID)
 	if !ok {
-		return domain.UpdateServiceSpec{}, fmt.Errorf("Service Plan '%s' not found", details.PreviousValues.PlanID)
+		return domain.UpdateServiceSpec{}, newUserError(ErrCodePlanNotFound, "Service Plan '%s' not found", details.PreviousValues.PlanID)
 	}
 
 	isPlanUpgrade, err := servicePlan.IsUpgradeFrom(previousServicePlan)
@@ -590,22 +1178,23 @@ func (b *RDSBroker) Update(
 		err := ErrCannotDowngradeVersion
 		b.logger.Error("version-downgrade-attempted", err)
 		return domain.UpdateServiceSpec{},
-			apiresponses.NewFailureResponse(
-				err,
-				http.StatusBadRequest,
-				"upgrade",
-			)
+			newCodedError(http.StatusBadRequest, ErrCodeInvalidPlanChange, err)
 	}
 
 	if *servicePlan.RDSProperties.AllocatedStorage < *previousServicePlan.RDSProperties.AllocatedStorage {
 		err := ErrCannotDowngradeStorage
 		b.logger.Error("storage-downgrade-attempted", err)
 		return domain.UpdateServiceSpec{},
-			apiresponses.NewFailureResponse(
-				err,
-				http.StatusBadRequest,
-				"upgrade",
-			)
+			newCodedError(http.StatusBadRequest, ErrCodeInvalidPlanChange, err)
+	}
+
+	downgradeWarning := b.instanceClassDowngradeWarning(servicePlan, previousServicePlan)
+	if err := b.checkInstanceClassDowngrade(downgradeWarning, updateParameters); err != nil {
+		b.logger.Error("instance-class-downgrade-attempted", err)
+		return domain.UpdateServiceSpec{}, err
+	}
+	if downgradeWarning != "" {
+		b.logger.Info("instance-class-downgrade", lager.Data{instanceIDLogKey: instanceID, "warning": downgradeWarning})
 	}
 
 	if aws.StringValue(servicePlan.RDSProperties.Engine) == "postgres" {
@@ -614,11 +1203,7 @@ func (b *RDSBroker) Update(
 			err := ErrCannotSkipMajorVersion
 			b.logger.Error("invalid-upgrade-path", err)
 			return domain.UpdateServiceSpec{},
-				apiresponses.NewFailureResponse(
-					err,
-					http.StatusBadRequest,
-					"upgrade",
-				)
+				newCodedError(http.StatusBadRequest, ErrCodeInvalidPlanChange, err)
 		}
 	}
 
@@ -636,27 +1221,50 @@ func (b *RDSBroker) Update(
 		return domain.UpdateServiceSpec{}, fmt.Errorf("cannot find instance %s", b.dbInstanceIdentifier(instanceID))
 	}
 
+	if aws.StringValue(existingInstance.DBInstanceStatus) == "stopped" && !updateParameters.Preview {
+		// RDS can't modify stopped instances, such as expired trials, so the
+		// instance is started for the update to be made once it is running
+		b.logger.Info("update.start-stopped-instance", lager.Data{instanceIDLogKey: instanceID})
+		if err := b.dbInstance.Start(b.dbInstanceIdentifier(instanceID)); err != nil {
+			return domain.UpdateServiceSpec{}, err
+		}
+		return domain.UpdateServiceSpec{}, newCodedError(
+			http.StatusUnprocessableEntity,
+			ErrCodeInstanceStopped,
+			fmt.Errorf("DB Instance '%s' was stopped and is being started. Try the update again once it is available.", b.dbInstanceIdentifier(instanceID)),
+		)
+	}
+
 	if aws.StringValue(existingInstance.DBInstanceStatus) == "storage-full" {
 		return domain.UpdateServiceSpec{},
-			fmt.Errorf("Cannot update instance %s because it is in state \"storage-full\". You will need to contact support to resolve this issue.",
-				b.dbInstanceIdentifier(instanceID))
+			newCodedError(http.StatusUnprocessableEntity, ErrCodeInstanceStorageFull, fmt.Errorf("Cannot update instance %s because it is in state \"storage-full\". You will need to contact support to resolve this issue.",
+				b.dbInstanceIdentifier(instanceID)))
+	}
+
+	if !updateParameters.Preview {
+		if err := b.checkNoModificationInProgress(instanceID, existingInstance); err != nil {
+			b.logger.Error("update.modification-in-progress", err, lager.Data{instanceIDLogKey: instanceID})
+			return domain.UpdateServiceSpec{}, err
+		}
 	}
 
 	previousDbParamGroup := *existingInstance.DBParameterGroups[0].DBParameterGroupName
 
 	newDbParamGroup := previousDbParamGroup
 
-	ok, unsupportedExtension := extensionsAreSupported(servicePlan, mergeExtensions(updateParameters.EnableExtensions, updateParameters.DisableExtensions))
+	extensionLists := servicePlan.RDSProperties.extensionLists(extensionsEngineVersion(servicePlan, existingInstance))
+
+	ok, unsupportedExtension := extensionsAreSupported(extensionLists, mergeStrings(updateParameters.EnableExtensions, updateParameters.DisableExtensions))
 	if !ok {
-		return domain.UpdateServiceSpec{}, fmt.Errorf("%s is not supported", unsupportedExtension)
+		return domain.UpdateServiceSpec{}, newUserError(ErrCodeExtensionNotAllowed, "%s is not supported", unsupportedExtension)
 	}
 
-	ok, defaultExtension := containsDefaultExtension(servicePlan, updateParameters.DisableExtensions)
+	ok, defaultExtension := containsDefaultExtension(extensionLists, updateParameters.DisableExtensions)
 	if ok {
-		return domain.UpdateServiceSpec{}, fmt.Errorf("%s cannot be disabled", defaultExtension)
+		return domain.UpdateServiceSpec{}, newUserError(ErrCodeExtensionNotAllowed, "%s cannot be disabled", defaultExtension)
 	}
 
-	extensions := mergeExtensions(aws.StringValueSlice(servicePlan.RDSProperties.DefaultExtensions), updateParameters.EnableExtensions)
+	extensions := mergeStrings(aws.StringValueSlice(extensionLists.DefaultExtensions), updateParameters.EnableExtensions)
 
 	tags, err := b.dbInstance.GetResourceTags(aws.StringValue(existingInstance.DBInstanceArn))
 	if err != nil {
@@ -666,48 +1274,115 @@ func (b *RDSBroker) Update(
 
 	if extensionsTag, ok := tagsByName[awsrds.TagExtensions]; ok {
 		if extensionsTag != "" {
-			extensions = mergeExtensions(extensions, unpackExtensions(extensionsTag))
+			extensions = mergeStrings(extensions, unpackExtensions(extensionsTag))
 		}
 	}
 
-	extensions = removeExtensions(extensions, updateParameters.DisableExtensions)
-	err = b.ensureDropExtensions(instanceID, existingInstance, updateParameters.DisableExtensions)
+	additionalDatabases, err := mergeAdditionalDatabases(
+		servicePlan,
+		b.dbNameFromDBInstance(instanceID, existingInstance),
+		unpackAdditionalDatabases(tagsByName[awsrds.TagAdditionalDatabases]),
+		updateParameters.AdditionalDatabases,
+	)
 	if err != nil {
 		return domain.UpdateServiceSpec{}, err
 	}
 
-	deferReboot := false
-
-	newDbParamGroup, err = b.parameterGroupsSelector.SelectParameterGroup(servicePlan, extensions)
-	if err != nil {
-		return domain.UpdateServiceSpec{}, err
+	securityGroupSet := tagsByName[awsrds.TagSecurityGroupSet]
+	if updateParameters.SecurityGroupSet != nil && *updateParameters.SecurityGroupSet != securityGroupSet {
+		if _, ok := b.securityGroupSets[*updateParameters.SecurityGroupSet]; !ok {
+			return domain.UpdateServiceSpec{}, newUserError(ErrCodeSecurityGroupSetDenied, "Unknown security group set '%s'", *updateParameters.SecurityGroupSet)
+		}
+		// the sets change who can reach the instance, so the operator
+		// decides which organizations may use them
+		if !containsString(b.organizationSecurityGroupSets[tagsByName[awsrds.TagOrganizationID]], *updateParameters.SecurityGroupSet) {
+			return domain.UpdateServiceSpec{}, newUserError(ErrCodeSecurityGroupSetDenied, "Security group set '%s' is not available to this service instance's organization", *updateParameters.SecurityGroupSet)
+		}
+		securityGroupSet = *updateParameters.SecurityGroupSet
 	}
 
-	if (len(updateParameters.EnableExtensions) > 0 || len(updateParameters.DisableExtensions) > 0) && newDbParamGroup != previousDbParamGroup {
-		if updateParameters.Reboot == nil || !*updateParameters.Reboot {
-			return domain.UpdateServiceSpec{}, errors.New("The requested extensions require the instance to be manually rebooted. Please re-run update service with reboot set to true")
+	databasesToPurge := ""
+	if updateParameters.PurgeOtherDatabases != nil {
+		if err := checkPurgeOtherDatabases(tagsByName, *updateParameters.PurgeOtherDatabases); err != nil {
+			return domain.UpdateServiceSpec{}, err
+		}
+		if *updateParameters.PurgeOtherDatabases == PurgeOtherDatabasesDryRun {
+			databasesToPurge, err = b.findDatabasesToPurge(instanceID, existingInstance, tagsByName)
+			if err != nil {
+				return domain.UpdateServiceSpec{}, err
+			}
 		}
-		// When updating the parameter group, the instance will be in a modifying state
-		// for a couple of mins. So we have to defer the reboot to the last operation call.
-		deferReboot = true
 	}
 
-	modifyDBInstanceInput := b.newModifyDBInstanceInput(instanceID, servicePlan, updateParameters, newDbParamGroup)
-
-	if updateParameters.UpgradeMinorVersionToLatest != nil && *updateParameters.UpgradeMinorVersionToLatest {
-		b.logger.Info("is-minor-version-upgrade")
-		if updateParameters.Reboot != nil && *updateParameters.Reboot {
-			return domain.UpdateServiceSpec{}, fmt.Errorf(
-				"Cannot reboot and upgrade minor version to latest at the same time",
-			)
+	extensions = removeExtensions(extensions, updateParameters.DisableExtensions)
+	if aws.StringValue(servicePlan.RDSProperties.Engine) == "postgres" {
+		if err := extensionsAreCompatible(servicePlan, extensions); err != nil {
+			return domain.UpdateServiceSpec{}, err
 		}
+	}
 
-		if details.PlanID != details.PreviousValues.PlanID {
-			return domain.UpdateServiceSpec{}, fmt.Errorf(
-				"Cannot specify a version and upgrade minor version to latest at the same time",
-			)
+	var pgauditLog []string
+	if pgauditLogTag := tagsByName[awsrds.TagPgauditLog]; pgauditLogTag != "" {
+		pgauditLog = strings.Split(pgauditLogTag, ":")
+	}
+	if len(updateParameters.PgauditLog) > 0 {
+		if !containsString(extensions, "pgaudit") {
+			return domain.UpdateServiceSpec{}, newUserError(ErrCodeExtensionNotAllowed, "pgaudit_log can only be set when the pgaudit extension is enabled")
+		}
+		pgauditLog = updateParameters.PgauditLog
+	}
+
+	if bucket := aws.StringValue(updateParameters.ExportDataOnDeprovision); bucket != "" {
+		if err := b.checkDataExportAllowed(tagsByName[awsrds.TagOrganizationID], servicePlan, bucket); err != nil {
+			return domain.UpdateServiceSpec{}, err
+		}
+	}
+
+	if !updateParameters.Preview {
+		err = b.ensureDropExtensions(instanceID, existingInstance, updateParameters.DisableExtensions, updateParameters.ForceDropExtensions)
+		if err != nil {
+			return domain.UpdateServiceSpec{}, err
+		}
+	}
+
+	deferReboot := false
+	// changes which would take the instance down are held back for its
+	// maintenance window, where a reboot is scheduled anyway
+	scheduleAtMaintenance := userChoice(updateParameters.ScheduleAtMaintenanceWindow, tagsByName[awsrds.TagScheduleAtMaintenance])
+
+	if updateParameters.Preview {
+		// previews mustn't create the parameter group
+		newDbParamGroup, err = b.parameterGroupsSelector.ParameterGroupName(servicePlan, extensions, pgauditLog)
+	} else {
+		newDbParamGroup, err = b.parameterGroupsSelector.SelectParameterGroup(servicePlan, extensions, pgauditLog)
+	}
+	if err != nil {
+		return domain.UpdateServiceSpec{}, err
+	}
+
+	if (len(updateParameters.EnableExtensions) > 0 || len(updateParameters.DisableExtensions) > 0) && newDbParamGroup != previousDbParamGroup {
+		if (updateParameters.Reboot == nil || !*updateParameters.Reboot) && scheduleAtMaintenance != "true" {
+			return domain.UpdateServiceSpec{}, newCodedError(http.StatusUnprocessableEntity, ErrCodeRebootRequired, errors.New("The requested extensions require the instance to be manually rebooted. Please re-run update service with reboot set to true"))
 		}
+		// When updating the parameter group, the instance will be in a modifying state
+		// for a couple of mins. So we have to defer the reboot to the last operation call.
+		deferReboot = true
+	}
+
+	if len(updateParameters.PgauditLog) > 0 && newDbParamGroup != previousDbParamGroup {
+		if (updateParameters.Reboot == nil || !*updateParameters.Reboot) && scheduleAtMaintenance != "true" {
+			return domain.UpdateServiceSpec{}, newCodedError(http.StatusUnprocessableEntity, ErrCodeRebootRequired, errors.New("Changing pgaudit_log requires the instance to be manually rebooted. Please re-run update service with reboot set to true"))
+		}
+		deferReboot = true
+	}
+
+	publicAccess := userChoice(updateParameters.PubliclyAccessible, tagsByName[awsrds.TagPubliclyAccessible])
+	autoMinorUpgrade := userChoice(updateParameters.AutoMinorVersionUpgrade, tagsByName[awsrds.TagAutoMinorUpgrade])
+
+	modifyDBInstanceInput := b.newModifyDBInstanceInput(instanceID, servicePlan, updateParameters, newDbParamGroup, securityGroupSet, tagsByName[awsrds.TagNetworkTier], publicAccess, autoMinorUpgrade)
 
+	if updateParameters.UpgradeMinorVersionToLatest != nil && *updateParameters.UpgradeMinorVersionToLatest {
+		b.logger.Info("is-minor-version-upgrade")
 		availableEngineVersion, err := b.dbInstance.GetLatestMinorVersion(
 			*existingInstance.Engine,
 			*existingInstance.EngineVersion,
@@ -743,6 +1418,34 @@ func (b *RDSBroker) Update(
 		}
 	}
 
+	upgradeMinorVersion := updateParameters.UpgradeMinorVersionToLatest != nil && *updateParameters.UpgradeMinorVersionToLatest
+	if scheduleAtMaintenance == "true" && (isPlanUpgrade || upgradeMinorVersion) {
+		return domain.UpdateServiceSpec{}, newInvalidParametersError(errors.New("Engine version upgrades can't be scheduled for the maintenance window. Please re-run update service with schedule_at_maintenance_window set to false"))
+	}
+
+	if updateParameters.Preview {
+		reboot := updateParameters.Reboot != nil && *updateParameters.Reboot
+		preview, err := b.previewUpdate(existingInstance, servicePlan, modifyDBInstanceInput, reboot)
+		if err != nil {
+			return domain.UpdateServiceSpec{}, err
+		}
+		preview.PlanChange = details.PlanID != details.PreviousValues.PlanID
+		if downgradeWarning != "" {
+			preview.Warnings = append(preview.Warnings, downgradeWarning)
+		}
+		b.logger.Info("update-preview", lager.Data{instanceIDLogKey: instanceID, "preview": preview})
+		operationData, err := preview.operationData()
+		if err != nil {
+			return domain.UpdateServiceSpec{}, err
+		}
+		return domain.UpdateServiceSpec{IsAsync: true, OperationData: operationData}, nil
+	}
+
+	scheduledSteps := []string{}
+	if scheduleAtMaintenance == "true" {
+		scheduledSteps = holdBackDisruptiveChanges(modifyDBInstanceInput, existingInstance, updateParameters)
+	}
+
 	updatedDBInstance, err := b.dbInstance.Modify(modifyDBInstanceInput)
 	if err != nil {
 		if awsRdsErr, ok := err.(awsrds.Error); ok {
@@ -763,50 +1466,102 @@ func (b *RDSBroker) Update(
 	}
 
 	instanceTags := RDSInstanceTags{
-		Action:           "Updated",
-		ServiceID:        details.ServiceID,
-		PlanID:           details.PlanID,
-		Extensions:       extensions,
-		ChargeableEntity: instanceID,
+		Action:                  "Updated",
+		ServiceID:               details.ServiceID,
+		PlanID:                  details.PlanID,
+		Extensions:              extensions,
+		ChargeableEntity:        instanceID,
+		SecurityGroupSet:        securityGroupSet,
+		DatabasesToPurge:        databasesToPurge,
+		PgauditLog:              pgauditLog,
+		AdditionalDatabases:     additionalDatabases,
+		PubliclyAccessible:      publicAccess,
+		AutoMinorVersionUpgrade: autoMinorUpgrade,
+		ScheduleAtMaintenance:   scheduleAtMaintenance,
+	}
+
+	if len(scheduledSteps) > 0 {
+		instanceTags.ScheduledMaintenance = packScheduledSteps(append(unpackScheduledSteps(tagsByName[awsrds.TagScheduledMaintenance]), scheduledSteps...))
+		b.logger.Info("update.scheduled-for-maintenance-window", lager.Data{
+			instanceIDLogKey: instanceID,
+			"steps":          instanceTags.ScheduledMaintenance,
+		})
+	}
+
+	if identity, ok := originatingIdentityFromContext(ctx); ok {
+		instanceTags.UpdatedByUser = identity.UserID
+	}
+
+	if instanceName := instanceNameFromContext(details.RawContext); instanceName != "" {
+		instanceTags.InstanceName = instanceName
+		if previousName := tagsByName[awsrds.TagInstanceName]; previousName != "" && previousName != instanceName {
+			instanceTags.PreviousInstanceNames = appendPreviousInstanceName(tagsByName[awsrds.TagPreviousInstanceNames], previousName)
+		}
 	}
 
 	if updateParameters.SkipFinalSnapshot != nil {
 		instanceTags.SkipFinalSnapshot = strconv.FormatBool(*updateParameters.SkipFinalSnapshot)
 	}
 
-	builtTags := awsrds.BuildRDSTags(b.dbTags(instanceTags))
-	b.dbInstance.AddTagsToResource(aws.StringValue(updatedDBInstance.DBInstanceArn), builtTags)
-
-	if updateParameters.Reboot != nil && *updateParameters.Reboot && !deferReboot {
-		rebootDBInstanceInput := &rds.RebootDBInstanceInput{
-			DBInstanceIdentifier: aws.String(b.dbInstanceIdentifier(instanceID)),
-			ForceFailover:        updateParameters.ForceFailover,
+	if updateParameters.ExportDataOnDeprovision != nil {
+		if bucket := *updateParameters.ExportDataOnDeprovision; bucket != "" {
+			instanceTags.DataExportOnDeprovision = bucket
+		} else if tagsByName[awsrds.TagDataExportOnDeprovision] != "" {
+			if err := b.removeTag(b.dbInstanceIdentifier(instanceID), awsrds.TagDataExportOnDeprovision); err != nil {
+				return domain.UpdateServiceSpec{}, err
+			}
 		}
+	}
 
-		err := b.dbInstance.Reboot(rebootDBInstanceInput)
-		if err != nil {
-			return domain.UpdateServiceSpec{}, err
+	if updateParameters.LiftDeprovisionProtection {
+		instanceTags.UnprotectedUntil = time.Now().Add(deprovisionProtectionLiftDuration).UTC().Format(time.RFC3339)
+	}
+
+	if aws.StringValue(servicePlan.RDSProperties.Engine) == "postgres" && newVersion.Major() > oldVersion.Major() {
+		// extensions have to be updated once the new version is running
+		instanceTags.ExtensionUpdateFor = strconv.FormatInt(newVersion.Major(), 10)
+	}
+
+	// Moving between trial plans keeps the original expiry, while moving to
+	// a plan without a trial lifts it.
+	if servicePlan.TrialDays > 0 {
+		instanceTags.TrialExpires = tagsByName[awsrds.TagTrialExpires]
+		if instanceTags.TrialExpires == "" {
+			instanceTags.TrialExpires = trialExpiresAt(servicePlan, time.Now())
+		}
+	} else if tagsByName[awsrds.TagTrialExpires] != "" {
+		for _, tagKey := range []string{awsrds.TagTrialExpires, awsrds.TagTrialExpiryWarned} {
+			if err := b.removeTag(b.dbInstanceIdentifier(instanceID), tagKey); err != nil {
+				return domain.UpdateServiceSpec{}, err
+			}
 		}
-		return domain.UpdateServiceSpec{IsAsync: true}, nil
 	}
 
-	return domain.UpdateServiceSpec{IsAsync: true}, nil
-}
+	if err := b.removeStaleServiceLevelTags(instanceID, servicePlan, tagsByName); err != nil {
+		return domain.UpdateServiceSpec{}, err
+	}
 
-// determine whether we actually want to skip final snapshot given
-// servicePlan and tagValue
-func resolveSkipFinalSnapshot(servicePlan ServicePlan, tagValue string) (bool, error) {
-	var err error
-	skipDBInstanceFinalSnapshot := servicePlan.RDSProperties.SkipFinalSnapshot == nil || *servicePlan.RDSProperties.SkipFinalSnapshot
+	b.writeTags(instanceID, aws.StringValue(updatedDBInstance.DBInstanceArn), b.dbTags(instanceTags))
 
-	if tagValue != "" {
-		skipDBInstanceFinalSnapshot, err = strconv.ParseBool(tagValue)
+	// databases are only dropped once RDS has accepted the rest of the
+	// update, as they can't be brought back if it is refused
+	if aws.StringValue(updateParameters.PurgeOtherDatabases) == PurgeOtherDatabasesConfirm {
+		if err := b.dropDatabasesToPurge(instanceID, existingInstance, tagsByName); err != nil {
+			return domain.UpdateServiceSpec{}, err
+		}
+	}
+
+	if updateParameters.Reboot != nil && *updateParameters.Reboot && !deferReboot && len(scheduledSteps) == 0 {
+		err := b.rebootForReason(ctx, instanceID, aws.StringValue(updatedDBInstance.DBInstanceArn), updateParameters.ForceFailover, rebootReasonUserRequested)
 		if err != nil {
-			return false, err
+			return domain.UpdateServiceSpec{}, err
 		}
+		b.hintRetryAfter(ctx, RetryAfterReboot)
+		return domain.UpdateServiceSpec{IsAsync: true, OperationData: operationDataReboot}, nil
 	}
 
-	return skipDBInstanceFinalSnapshot, nil
+	b.hintRetryAfter(ctx, RetryAfterUpdate)
+	return domain.UpdateServiceSpec{IsAsync: true}, nil
 }
 
 func (b *RDSBroker) Deprovision(
@@ -815,39 +1570,141 @@ func (b *RDSBroker) Deprovision(
 	details domain.DeprovisionDetails,
 	asyncAllowed bool,
 ) (domain.DeprovisionServiceSpec, error) {
+	b.lastOperationCache.invalidate(instanceID)
+
 	b.logger.Debug("deprovision", lager.Data{
 		instanceIDLogKey:   instanceID,
 		detailsLogKey:      details,
 		asyncAllowedLogKey: asyncAllowed,
 	})
 
+	b.auditLog(ctx, "deprovision", instanceID)
+
 	if !asyncAllowed {
 		return domain.DeprovisionServiceSpec{}, apiresponses.ErrAsyncRequired
 	}
 
+	if err := b.checkAWSAvailable(); err != nil {
+		return domain.DeprovisionServiceSpec{}, err
+	}
+
+	releaseOperationLock, err := b.acquireOperationLock(instanceID)
+	if err != nil {
+		return domain.DeprovisionServiceSpec{}, err
+	}
+	defer releaseOperationLock()
+
 	servicePlan, ok := b.catalog.FindServicePlan(details.PlanID)
 	if !ok {
-		return domain.DeprovisionServiceSpec{}, fmt.Errorf("Service Plan '%s' not found", details.PlanID)
+		return domain.DeprovisionServiceSpec{}, newUserError(ErrCodePlanNotFound, "Service Plan '%s' not found", details.PlanID)
 	}
 
 	skipFinalSnapshot, err := b.dbInstance.GetTag(b.dbInstanceIdentifier(instanceID), awsrds.TagSkipFinalSnapshot)
 	if err != nil {
+		if err == awsrds.ErrDBInstanceDoesNotExist {
+			spec, err := b.deprovisionOrphanedDBInstances(instanceID, servicePlan)
+			if err == nil {
+				b.hintRetryAfter(ctx, RetryAfterDeprovision)
+			}
+			return spec, err
+		}
+		return domain.DeprovisionServiceSpec{}, err
+	}
+
+	skipDBInstanceFinalSnapshot, err := b.resolveSkipFinalSnapshot(servicePlan, skipFinalSnapshot)
+	if err != nil {
+		return domain.DeprovisionServiceSpec{}, err
+	}
+
+	if err := b.checkNotInUse(instanceID, details.Force); err != nil {
 		return domain.DeprovisionServiceSpec{}, err
 	}
 
-	skipDBInstanceFinalSnapshot, err := resolveSkipFinalSnapshot(servicePlan, skipFinalSnapshot)
+	dataExportBucket, err := b.dataExportOnDeprovisionBucket(instanceID)
 	if err != nil {
 		return domain.DeprovisionServiceSpec{}, err
 	}
 
+	// the bindings of soft deleted instances are kept, so that they still
+	// work if the instance is undeleted, and revoked when it is purged.
+	// Instances whose data is to be exported are soft deleted too, so that
+	// housekeeping can export it once the platform has let go of them.
+	if b.softDeleteDuration > 0 || dataExportBucket != "" {
+		if err := b.softDeleteDBInstance(instanceID, dataExportBucket); err != nil {
+			if err == awsrds.ErrDBInstanceDoesNotExist {
+				return domain.DeprovisionServiceSpec{}, apiresponses.ErrInstanceDoesNotExist
+			}
+			return domain.DeprovisionServiceSpec{}, err
+		}
+		b.hintRetryAfter(ctx, RetryAfterDeprovision)
+		return domain.DeprovisionServiceSpec{IsAsync: true, OperationData: operationDataDeprovision}, nil
+	}
+
+	if err := b.deleteDBProxy(instanceID); err != nil {
+		return domain.DeprovisionServiceSpec{}, err
+	}
+
+	if err := b.deleteDNSName(instanceID); err != nil {
+		return domain.DeprovisionServiceSpec{}, err
+	}
+
 	if err := b.dbInstance.Delete(b.dbInstanceIdentifier(instanceID), skipDBInstanceFinalSnapshot); err != nil {
 		if err == awsrds.ErrDBInstanceDoesNotExist {
 			return domain.DeprovisionServiceSpec{}, apiresponses.ErrInstanceDoesNotExist
 		}
 		return domain.DeprovisionServiceSpec{}, err
 	}
+	b.revokeBindings(instanceID, b.dbInstanceIdentifier(instanceID))
+
+	b.hintRetryAfter(ctx, RetryAfterDeprovision)
+	return domain.DeprovisionServiceSpec{IsAsync: true, OperationData: operationDataDeprovision}, nil
+}
+
+// deprovisionOrphanedDBInstances handles a deprovision for a service instance
+// whose DB instance can't be found under the expected identifier. Any DB
+// instance created by this broker for the service instance is deleted so that
+// nothing is left behind once the platform forgets about it.
+func (b *RDSBroker) deprovisionOrphanedDBInstances(instanceID string, servicePlan ServicePlan) (domain.DeprovisionServiceSpec, error) {
+	dbInstances, err := b.dbInstance.DescribeByTag(awsrds.TagChargeableEntity, instanceID)
+	if err != nil {
+		return domain.DeprovisionServiceSpec{}, err
+	}
+
+	deleted := 0
+	for _, dbInstance := range dbInstances {
+		tags, err := b.dbInstance.GetResourceTags(aws.StringValue(dbInstance.DBInstanceArn))
+		if err != nil {
+			return domain.DeprovisionServiceSpec{}, err
+		}
+		tagsByName := awsrds.RDSTagsValues(tags)
+		if tagsByName[awsrds.TagBrokerName] != b.brokerName || tagsByName[awsrds.TagPurgeAfter] != "" {
+			continue
+		}
+
+		skipFinalSnapshot, err := b.resolveSkipFinalSnapshot(servicePlan, tagsByName[awsrds.TagSkipFinalSnapshot])
+		if err != nil {
+			return domain.DeprovisionServiceSpec{}, err
+		}
+
+		dbInstanceIdentifier := aws.StringValue(dbInstance.DBInstanceIdentifier)
+		b.logger.Info("deprovision.delete-orphan", lager.Data{
+			instanceIDLogKey:       instanceID,
+			"dbInstanceIdentifier": dbInstanceIdentifier,
+		})
+		if err := b.dbInstance.Delete(dbInstanceIdentifier, skipFinalSnapshot); err != nil {
+			if err == awsrds.ErrDBInstanceDoesNotExist {
+				continue
+			}
+			return domain.DeprovisionServiceSpec{}, err
+		}
+		deleted++
+	}
 
-	return domain.DeprovisionServiceSpec{IsAsync: true}, nil
+	if deleted == 0 {
+		return domain.DeprovisionServiceSpec{}, apiresponses.ErrInstanceDoesNotExist
+	}
+
+	return domain.DeprovisionServiceSpec{IsAsync: true, OperationData: operationDataDeprovision}, nil
 }
 
 func (b *RDSBroker) Bind(
@@ -862,25 +1719,29 @@ func (b *RDSBroker) Bind(
 		detailsLogKey:    details,
 	})
 
+	b.auditLog(ctx, "bind", instanceID)
+
 	bindingResponse := domain.Binding{}
 
+	if err := b.checkAWSAvailable(); err != nil {
+		return bindingResponse, err
+	}
+
 	bindParameters := BindParameters{}
 	if b.allowUserBindParameters && len(details.RawParameters) > 0 {
-		decoder := json.NewDecoder(bytes.NewReader(details.RawParameters))
-		decoder.DisallowUnknownFields()
-		if err := decoder.Decode(&bindParameters); err != nil {
+		if err := decodeParameters(details.RawParameters, &bindParameters); err != nil {
 			return bindingResponse, err
 		}
 	}
 
 	_, ok := b.catalog.FindService(details.ServiceID)
 	if !ok {
-		return bindingResponse, fmt.Errorf("Service '%s' not found", details.ServiceID)
+		return bindingResponse, newUserError(ErrCodeServiceNotFound, "Service '%s' not found", details.ServiceID)
 	}
 
 	servicePlan, ok := b.catalog.FindServicePlan(details.PlanID)
 	if !ok {
-		return bindingResponse, fmt.Errorf("Service Plan '%s' not found", details.PlanID)
+		return bindingResponse, newUserError(ErrCodePlanNotFound, "Service Plan '%s' not found", details.PlanID)
 	}
 
 	dbInstance, err := b.dbInstance.Describe(b.dbInstanceIdentifier(instanceID))
@@ -891,14 +1752,67 @@ func (b *RDSBroker) Bind(
 		return bindingResponse, err
 	}
 
+	if masterPasswordChanging(dbInstance) {
+		b.logger.Info("bind.master-password-changing", lager.Data{instanceIDLogKey: instanceID})
+		return bindingResponse, apiresponses.ErrConcurrentInstanceAccess
+	}
+
 	if aws.StringValue(dbInstance.Engine) != "postgres" && bindParameters.ReadOnly {
-		return bindingResponse, fmt.Errorf("Read only bindings are only supported for postgres")
+		return bindingResponse, newUserError(ErrCodeUnsupportedByEngine, "Read only bindings are only supported for postgres")
+	}
+
+	if aws.StringValue(dbInstance.Engine) != "postgres" && len(bindParameters.Schemas) > 0 {
+		return bindingResponse, newUserError(ErrCodeUnsupportedByEngine, "schemas are only supported for postgres")
+	}
+
+	if bindParameters.TTLHours > 0 {
+		if err := checkUserExpirySupported(dbInstance); err != nil {
+			return bindingResponse, err
+		}
+	}
+
+	statementTimeout := bindingStatementTimeout(servicePlan, bindParameters)
+	if statementTimeout > 0 {
+		if err := checkStatementTimeoutSupported(dbInstance); err != nil {
+			return bindingResponse, err
+		}
+	}
+
+	if bindParameters.UseConnectionPool && servicePlan.ConnectionPool == nil {
+		return bindingResponse, newUserError(ErrCodePlanNotAllowed, "Service Plan '%s' has no connection pool", servicePlan.Name)
+	}
+
+	if bindParameters.UseRDSProxy && b.secretStore == nil {
+		return bindingResponse, newUserError(ErrCodeInvalidParameters, "This broker can't make bindings through an RDS Proxy")
+	}
+
+	dbName := b.dbNameFromDBInstance(instanceID, dbInstance)
+
+	var dbProxyEndpoint, dnsName, expiringUsersUntil string
+	tagsByName := map[string]string{}
+	if bindParameters.UseRDSProxy || b.dnsZone != nil || bindParameters.Database != "" || bindParameters.TTLHours > 0 {
+		tags, err := b.dbInstance.GetResourceTags(aws.StringValue(dbInstance.DBInstanceArn))
+		if err != nil {
+			return bindingResponse, err
+		}
+		tagsByName = awsrds.RDSTagsValues(tags)
+		if bindParameters.UseRDSProxy {
+			dbProxyEndpoint, err = b.dbProxyEndpoint(instanceID, tagsByName)
+			if err != nil {
+				return bindingResponse, err
+			}
+		}
+		dnsName = tagsByName[awsrds.TagDNSName]
+		expiringUsersUntil = tagsByName[awsrds.TagExpiringUsersUntil]
+		dbName, err = bindingDatabase(dbName, bindParameters.Database, tagsByName)
+		if err != nil {
+			return bindingResponse, err
+		}
 	}
 
 	dbAddress := awsrds.GetDBAddress(dbInstance.Endpoint)
 	dbPort := awsrds.GetDBPort(dbInstance.Endpoint)
 	masterUsername := aws.StringValue(dbInstance.MasterUsername)
-	dbName := b.dbNameFromDBInstance(instanceID, dbInstance)
 
 	var engine string
 	if servicePlan.RDSProperties.Engine != nil {
@@ -909,17 +1823,89 @@ func (b *RDSBroker) Bind(
 		return bindingResponse, err
 	}
 
-	if err = sqlEngine.Open(dbAddress, dbPort, dbName, masterUsername, b.generateMasterPassword(instanceID)); err != nil {
+	masterPassword, err := b.masterPasswordForDBInstance(instanceID, dbInstance)
+	if err != nil {
+		return bindingResponse, err
+	}
+	if err = sqlEngine.Open(dbAddress, dbPort, dbName, masterUsername, masterPassword); err != nil {
 		return bindingResponse, err
 	}
 	defer sqlEngine.Close()
 
+	for _, schema := range bindParameters.Schemas {
+		if err := sqlEngine.CreateSchema(dbName, schema); err != nil {
+			return bindingResponse, err
+		}
+	}
+
 	dbUsername, dbPassword, err := sqlEngine.CreateUser(bindingID, dbName, bindParameters.ReadOnly)
 	if err != nil {
 		return bindingResponse, err
 	}
 
-	bindingResponse.Credentials = Credentials{
+	if b.checkBindingConnections {
+		if err := b.checkBindingConnection(engine, dbAddress, dbPort, dbName, dbUsername, dbPassword); err != nil {
+			b.logger.Error("check-binding-connection", err, lager.Data{
+				instanceIDLogKey: instanceID,
+				bindingIDLogKey:  bindingID,
+			})
+			if dropErr := sqlEngine.DropUser(bindingID); dropErr != nil {
+				b.logger.Error("drop-unusable-binding-user", dropErr, lager.Data{bindingIDLogKey: bindingID})
+			}
+			return bindingResponse, fmt.Errorf("The new user could not connect to the database: %s", err)
+		}
+	}
+
+	if statementTimeout > 0 {
+		if err := sqlEngine.SetUserStatementTimeout(bindingID, statementTimeout); err != nil {
+			if dropErr := sqlEngine.DropUser(bindingID); dropErr != nil {
+				b.logger.Error("drop-untimed-binding-user", dropErr, lager.Data{bindingIDLogKey: bindingID})
+			}
+			return bindingResponse, err
+		}
+	}
+
+	var expiresAt time.Time
+	if bindParameters.TTLHours > 0 {
+		expiresAt = time.Now().Add(time.Duration(bindParameters.TTLHours) * time.Hour).UTC().Truncate(time.Second)
+		if err := b.expireBindingUser(sqlEngine, dbInstance, bindingID, dbName, expiresAt, expiringUsersUntil); err != nil {
+			if dropErr := sqlEngine.DropUser(bindingID); dropErr != nil {
+				b.logger.Error("drop-unexpirable-binding-user", dropErr, lager.Data{bindingIDLogKey: bindingID})
+			}
+			return bindingResponse, err
+		}
+	}
+
+	if bindParameters.UseRDSProxy {
+		if err := b.allowThroughDBProxy(instanceID, bindingID, dbUsername, dbPassword, tagsByName); err != nil {
+			if dropErr := sqlEngine.DropUser(bindingID); dropErr != nil {
+				b.logger.Error("drop-unproxied-binding-user", dropErr, lager.Data{bindingIDLogKey: bindingID})
+			}
+			return bindingResponse, err
+		}
+	}
+
+	b.recordBinding(instanceID, dbInstance, BindingMetadata{
+		BindingID: bindingID,
+		AppGUID:   bindingAppGUID(details),
+		CreatedAt: time.Now(),
+		ReadOnly:  bindParameters.ReadOnly,
+	})
+
+	// the user is created on the DB instance, but the application connects
+	// through its CNAME, or the pool or proxy
+	if dnsName != "" {
+		dbAddress = dnsName
+	}
+	if bindParameters.UseConnectionPool {
+		dbAddress = servicePlan.ConnectionPool.Host
+		dbPort = servicePlan.ConnectionPool.Port
+	}
+	if bindParameters.UseRDSProxy {
+		dbAddress = dbProxyEndpoint
+	}
+
+	credentials := Credentials{
 		Host:     dbAddress,
 		Port:     dbPort,
 		Name:     dbName,
@@ -927,11 +1913,52 @@ func (b *RDSBroker) Bind(
 		Password: dbPassword,
 		URI:      sqlEngine.URI(dbAddress, dbPort, dbName, dbUsername, dbPassword),
 		JDBCURI:  sqlEngine.JDBCURI(dbAddress, dbPort, dbName, dbUsername, dbPassword),
+		Network:  b.networkFromDBInstance(dbInstance),
+	}
+	if !expiresAt.IsZero() {
+		credentials.ExpiresAt = expiresAt.Format(time.RFC3339)
 	}
+	// Kubernetes bindings are read by servicebinding.io libraries
+	if bindParameters.ServiceBindingLayout || platformContextFrom(details.RawContext).isKubernetes() {
+		credentials.Type = serviceBindingType(engine)
+		credentials.Provider = serviceBindingProvider
+		credentials.Hostname = dbAddress
+		credentials.Database = dbName
+	}
+	bindingResponse.Credentials = credentials
 
 	return bindingResponse, nil
 }
 
+// serviceBindingProvider is the servicebinding.io `provider` of the
+// bindings made by this broker.
+const serviceBindingProvider = "aws-rds"
+
+// serviceBindingType is the servicebinding.io `type` of a binding to an
+// engine, which is what Kubernetes binding libraries look bindings up by.
+func serviceBindingType(engine string) string {
+	if engine == "postgres" {
+		return "postgresql"
+	}
+	return engine
+}
+
+// checkBindingConnection connects as a newly created binding user and runs a
+// trivial query, so that bindings which can't be used fail straight away.
+func (b *RDSBroker) checkBindingConnection(engine, address string, port int64, dbName, username, password string) error {
+	sqlEngine, err := b.sqlProvider.GetSQLEngine(engine)
+	if err != nil {
+		return err
+	}
+
+	if err := sqlEngine.Open(address, port, dbName, username, password); err != nil {
+		return err
+	}
+	defer sqlEngine.Close()
+
+	return sqlEngine.CheckConnection()
+}
+
 func (b *RDSBroker) Unbind(
 	ctx context.Context,
 	instanceID, bindingID string,
@@ -944,9 +1971,15 @@ func (b *RDSBroker) Unbind(
 		detailsLogKey:    details,
 	})
 
-	_, ok := b.catalog.FindServicePlan(details.PlanID)
+	b.auditLog(ctx, "unbind", instanceID)
+
+	if err := b.checkAWSAvailable(); err != nil {
+		return domain.UnbindSpec{}, err
+	}
+
+	servicePlan, ok := b.catalog.FindServicePlan(details.PlanID)
 	if !ok {
-		return domain.UnbindSpec{}, fmt.Errorf("Service Plan '%s' not found", details.PlanID)
+		return domain.UnbindSpec{}, newUserError(ErrCodePlanNotFound, "Service Plan '%s' not found", details.PlanID)
 	}
 
 	dbInstance, err := b.dbInstance.Describe(b.dbInstanceIdentifier(instanceID))
@@ -957,6 +1990,17 @@ func (b *RDSBroker) Unbind(
 		return domain.UnbindSpec{}, err
 	}
 
+	if servicePlan.RDSProxy != nil {
+		if err := b.denyThroughDBProxy(instanceID, bindingID); err != nil {
+			return domain.UnbindSpec{}, err
+		}
+	}
+
+	if masterPasswordChanging(dbInstance) {
+		b.logger.Info("unbind.master-password-changing", lager.Data{instanceIDLogKey: instanceID})
+		return domain.UnbindSpec{}, apiresponses.ErrConcurrentInstanceAccess
+	}
+
 	dbName := b.dbNameFromDBInstance(instanceID, dbInstance)
 	sqlEngine, err := b.openSQLEngineForDBInstance(instanceID, dbName, dbInstance)
 	if err != nil {
@@ -968,6 +2012,8 @@ func (b *RDSBroker) Unbind(
 		return domain.UnbindSpec{}, err
 	}
 
+	b.forgetBinding(instanceID, bindingID)
+
 	return domain.UnbindSpec{}, nil
 }
 
@@ -975,6 +2021,46 @@ func (b *RDSBroker) LastOperation(
 	ctx context.Context,
 	instanceID string,
 	pollDetails domain.PollDetails,
+) (domain.LastOperation, error) {
+	if preview, ok := updatePreviewFromOperationData(pollDetails.OperationData); ok {
+		return preview.lastOperation(), nil
+	}
+
+	if lastOperation, ok := b.lastOperationCache.get(instanceID, pollDetails); ok {
+		b.logger.Debug("last-operation.cached", lager.Data{
+			instanceIDLogKey:            instanceID,
+			lastOperationResponseLogKey: lastOperation,
+		})
+		b.hintRetryAfter(ctx, retryAfterOperation(pollDetails.OperationData))
+		return b.withServiceAdvisory(lastOperation, pollDetails.PlanID), nil
+	}
+
+	if err := b.checkAWSAvailable(); err != nil {
+		return domain.LastOperation{}, err
+	}
+
+	lastOperation, err := b.lastOperation(ctx, instanceID, pollDetails)
+	if err == nil && lastOperation.State == domain.Failed && isProvisionOperation(pollDetails.OperationData) {
+		// the platform won't hand over an instance whose provision failed, so
+		// nobody would ever use or delete it
+		b.cleanUpOrphanedDBInstance(instanceID, nil)
+	}
+	if err == nil && lastOperation.State == domain.InProgress {
+		// only in-progress responses are cached: the poll which sees an
+		// operation finish may also kick off post restore tasks
+		b.lastOperationCache.set(instanceID, pollDetails, lastOperation)
+		b.hintRetryAfter(ctx, retryAfterOperation(pollDetails.OperationData))
+	}
+	if err != nil {
+		return lastOperation, err
+	}
+	return b.withServiceAdvisory(lastOperation, pollDetails.PlanID), nil
+}
+
+func (b *RDSBroker) lastOperation(
+	ctx context.Context,
+	instanceID string,
+	pollDetails domain.PollDetails,
 ) (domain.LastOperation, error) {
 	b.logger.Debug("last-operation", lager.Data{
 		instanceIDLogKey: instanceID,
@@ -992,6 +2078,11 @@ func (b *RDSBroker) LastOperation(
 	dbInstance, err := b.dbInstance.Describe(b.dbInstanceIdentifier(instanceID))
 	if err != nil {
 		if err == awsrds.ErrDBInstanceDoesNotExist {
+			if pollDetails.OperationData == operationDataReencryptedRestore {
+				// the restore may be waiting for its snapshot to be
+				// re-encrypted
+				return b.restoreFromReencryptedSnapshot(instanceID)
+			}
 			err = apiresponses.ErrInstanceDoesNotExist
 		}
 		return domain.LastOperation{State: domain.Failed}, err
@@ -1020,15 +2111,29 @@ func (b *RDSBroker) LastOperation(
 		Description: fmt.Sprintf("DB Instance '%s' status is '%s'", b.dbInstanceIdentifier(instanceID), status),
 	}
 
+	if rebootReason := tagsByName[awsrds.TagRebootReason]; rebootReason != "" && status == "rebooting" {
+		lastOperationResponse.Description = b.rebootingDescription(instanceID, rebootReason)
+	}
+
+	if lastOperationResponse.State == domain.Failed {
+		if message := b.latestEventMessage(instanceID); message != "" {
+			lastOperationResponse.Description += ": " + message
+		}
+	}
+
 	if lastOperationResponse.State == domain.Succeeded {
-		hasPendingModifications := false
-		if dbInstance.PendingModifiedValues != nil {
-			emptyPendingModifiedValues := rds.PendingModifiedValues{}
-			if !reflect.DeepEqual(*dbInstance.PendingModifiedValues, emptyPendingModifiedValues) {
-				hasPendingModifications = true
+		// the tasks below may modify the instance, so they mustn't race an
+		// update or another poll
+		if b.leaseHeldByOther(tagsByName) || !b.instanceLocks.tryLock(instanceID) {
+			lastOperationResponse = domain.LastOperation{
+				State:       domain.InProgress,
+				Description: fmt.Sprintf("DB Instance '%s' has another operation in progress", b.dbInstanceIdentifier(instanceID)),
 			}
+			return lastOperationResponse, nil
 		}
-		if hasPendingModifications {
+		defer b.instanceLocks.unlock(instanceID)
+
+		if len(pendingModifications(dbInstance)) > 0 {
 			lastOperationResponse = domain.LastOperation{
 				State:       domain.InProgress,
 				Description: fmt.Sprintf("DB Instance '%s' has pending modifications", b.dbInstanceIdentifier(instanceID)),
@@ -1039,7 +2144,7 @@ func (b *RDSBroker) LastOperation(
 		awsTagsPlanID, _ := tagsByName[awsrds.TagPlanID]
 		if pollDetails.PlanID != awsTagsPlanID {
 			// this was presumably a plan change
-			awsTagsPlan, ok := b.catalog.FindServicePlan(awsTagsPlanID)
+			awsTagsPlan, ok := b.findServicePlanForRead(awsTagsPlanID, dbInstance)
 			if !ok {
 				return domain.LastOperation{State: domain.Failed}, fmt.Errorf(
 					"Service Plan '%s' in aws tag '%s' not found",
@@ -1091,76 +2196,182 @@ func (b *RDSBroker) LastOperation(
 					})
 				}
 
-				if len(currentPlanDisagreements) == 0 {
-					// we can tell the cloud controller the operation has failed
-					// and simply roll back the plan id in the aws tags
-					b.logger.Info("rolling-back-failed-plan-change", lager.Data{
-						instanceIDLogKey:   instanceID,
-						servicePlanLogKey:  pollDetails.PlanID,
-						"awsTagsPlanID":    awsTagsPlanID,
-						"rdsEngineVersion": *dbInstance.EngineVersion,
-					})
-					tagsByName[awsrds.TagPlanID] = pollDetails.PlanID
-					b.dbInstance.AddTagsToResource(
-						aws.StringValue(dbInstance.DBInstanceArn),
-						awsrds.BuildRDSTags(tagsByName),
-					)
-					lastOperationResponse = domain.LastOperation{
-						State:       domain.Failed,
-						Description: "Plan upgrade failed. Refer to database logs for more information.",
-					}
-					return lastOperationResponse, nil
-				}
+				if len(currentPlanDisagreements) == 0 {
+					// we can tell the cloud controller the operation has failed
+					// and simply roll back the plan id in the aws tags
+					b.logger.Info("rolling-back-failed-plan-change", lager.Data{
+						instanceIDLogKey:   instanceID,
+						servicePlanLogKey:  pollDetails.PlanID,
+						"awsTagsPlanID":    awsTagsPlanID,
+						"rdsEngineVersion": *dbInstance.EngineVersion,
+					})
+					b.writeTags(instanceID, aws.StringValue(dbInstance.DBInstanceArn), map[string]string{
+						awsrds.TagPlanID: pollDetails.PlanID,
+					})
+					lastOperationResponse = domain.LastOperation{
+						State:       domain.Failed,
+						Description: "Plan upgrade failed. Refer to database logs for more information.",
+					}
+					return lastOperationResponse, nil
+				}
+
+				// the current state of the instance matches neither plan, so
+				// we can't safely leave it or roll it back
+				b.logger.Info("current-plan-properties-mismatch", lager.Data{
+					instanceIDLogKey:  instanceID,
+					servicePlanLogKey: pollDetails.PlanID,
+					"disagreements":   currentPlanDisagreements,
+				})
+				lastOperationResponse = domain.LastOperation{
+					State:       domain.Failed,
+					Description: "Operation failed and will need manual intervention to resolve. Please contact support.",
+				}
+				return lastOperationResponse, nil
+			}
+		}
+
+		if tagsByName[awsrds.TagRebootReason] != "" {
+			// the reboot has finished
+			if err := b.removeTag(b.dbInstanceIdentifier(instanceID), awsrds.TagRebootReason); err != nil {
+				return domain.LastOperation{State: domain.Failed}, err
+			}
+		}
+
+		restoreState := nextRestoreState(tagsByName)
+		asyncOperationTriggered, err := b.PostRestoreTasks(instanceID, dbInstance, tagsByName)
+		if err != nil {
+			return domain.LastOperation{State: domain.Failed}, err
+		}
+		if asyncOperationTriggered {
+			lastOperationResponse = domain.LastOperation{
+				State:       domain.InProgress,
+				Description: fmt.Sprintf("DB Instance '%s' has pending post restore modifications", b.dbInstanceIdentifier(instanceID)),
+			}
+			if restoreState == StateReboot {
+				lastOperationResponse.Description = b.rebootingDescription(instanceID, rebootReasonRestore)
+			}
+			return lastOperationResponse, nil
+		}
+
+		asyncOperationTriggered, err = b.RebootIfRequired(instanceID, dbInstance)
+		if err != nil {
+			return domain.LastOperation{State: domain.Failed}, err
+		}
+		if asyncOperationTriggered {
+			lastOperationResponse = domain.LastOperation{
+				State:       domain.InProgress,
+				Description: b.rebootingDescription(instanceID, rebootReasonParameterChange),
+			}
+			return lastOperationResponse, nil
+		}
+
+		asyncOperationTriggered, err = b.ensureDBProxy(instanceID, dbInstance, tagsByName)
+		if err != nil {
+			return domain.LastOperation{State: domain.Failed}, err
+		}
+		if asyncOperationTriggered {
+			lastOperationResponse = domain.LastOperation{
+				State:       domain.InProgress,
+				Description: fmt.Sprintf("RDS Proxy for DB Instance '%s' is being created", b.dbInstanceIdentifier(instanceID)),
+			}
+			return lastOperationResponse, nil
+		}
+
+		err = b.ensureDNSName(instanceID, dbInstance, tagsByName)
+		if err != nil {
+			return domain.LastOperation{State: domain.Failed}, err
+		}
 
-				// the current state of the instance matches neither plan, so
-				// we can't safely leave it or roll it back
-				b.logger.Info("current-plan-properties-mismatch", lager.Data{
-					instanceIDLogKey:  instanceID,
-					servicePlanLogKey: pollDetails.PlanID,
-					"disagreements":   currentPlanDisagreements,
-				})
-				lastOperationResponse = domain.LastOperation{
-					State:       domain.Failed,
-					Description: "Operation failed and will need manual intervention to resolve. Please contact support.",
-				}
-				return lastOperationResponse, nil
+		if isProvisionOperation(pollDetails.OperationData) && !b.warmedUp(instanceID, dbInstance) {
+			lastOperationResponse = domain.LastOperation{
+				State:       domain.InProgress,
+				Description: fmt.Sprintf("DB Instance '%s' is available but not yet accepting connections", b.dbInstanceIdentifier(instanceID)),
 			}
+			return lastOperationResponse, nil
 		}
 
-		asyncOperationTriggered, err := b.PostRestoreTasks(instanceID, dbInstance, tagsByName)
+		// extensions waiting on a parameter group swapped in the maintenance
+		// window are created by housekeeping after it
+		if !containsString(unpackScheduledSteps(tagsByName[awsrds.TagScheduledMaintenance]), scheduledStepCreateExtensions) {
+			err = b.ensureCreateExtensions(instanceID, dbInstance, tagsByName)
+			if err != nil {
+				return domain.LastOperation{State: domain.Failed}, err
+			}
+		}
+
+		err = b.ensureAdditionalDatabases(instanceID, dbInstance, tagsByName)
+		if err != nil {
+			return domain.LastOperation{State: domain.Failed}, err
+		}
+
+		asyncOperationTriggered, err = b.ensureSchemaCopied(instanceID, dbInstance, tagsByName)
 		if err != nil {
 			return domain.LastOperation{State: domain.Failed}, err
 		}
 		if asyncOperationTriggered {
 			lastOperationResponse = domain.LastOperation{
 				State:       domain.InProgress,
-				Description: fmt.Sprintf("DB Instance '%s' has pending post restore modifications", b.dbInstanceIdentifier(instanceID)),
+				Description: fmt.Sprintf("DB Instance '%s' is having the schema of its template database instance copied into it", b.dbInstanceIdentifier(instanceID)),
 			}
 			return lastOperationResponse, nil
 		}
 
-		asyncOperationTriggered, err = b.RebootIfRequired(instanceID, dbInstance)
+		asyncOperationTriggered, err = b.ensureDataImported(instanceID, dbInstance, tagsByName)
 		if err != nil {
 			return domain.LastOperation{State: domain.Failed}, err
 		}
 		if asyncOperationTriggered {
 			lastOperationResponse = domain.LastOperation{
 				State:       domain.InProgress,
-				Description: fmt.Sprintf("DB Instance '%s' is rebooting", b.dbInstanceIdentifier(instanceID)),
+				Description: fmt.Sprintf("DB Instance '%s' is having its data imported", b.dbInstanceIdentifier(instanceID)),
 			}
 			return lastOperationResponse, nil
 		}
 
-		err = b.ensureCreateExtensions(instanceID, dbInstance, tagsByName)
+		err = b.ensureUpdateExtensions(instanceID, dbInstance, tagsByName)
+		if err != nil {
+			return domain.LastOperation{State: domain.Failed}, err
+		}
+
+		err = b.ensureBinlogRetention(instanceID, dbInstance, tagsByName)
 		if err != nil {
 			return domain.LastOperation{State: domain.Failed}, err
 		}
+
+		if warning := b.connectionUsageWarning(instanceID); warning != "" {
+			lastOperationResponse.Description += ". " + warning
+		}
 	}
 
 	return lastOperationResponse, nil
 }
 
-func searchExtension(slice []string, element string) bool {
+// latestEventMessage returns the message of the event of the last day which
+// most likely explains why the DB instance has failed: the most recent
+// failure event if there is one, otherwise the most recent event of any kind.
+func (b *RDSBroker) latestEventMessage(instanceID string) string {
+	now := time.Now()
+	events, err := b.dbInstance.DescribeEventsBetween(b.dbInstanceIdentifier(instanceID), now.Add(-24*time.Hour), now)
+	if err != nil {
+		b.logger.Error("describe-events", err, lager.Data{instanceIDLogKey: instanceID})
+		return ""
+	}
+	if len(events) == 0 {
+		return ""
+	}
+
+	for _, event := range events {
+		for _, category := range event.EventCategories {
+			if aws.StringValue(category) == "failure" {
+				return aws.StringValue(event.Message)
+			}
+		}
+	}
+	return aws.StringValue(events[0].Message)
+}
+
+// containsString reports whether element is in slice.
+func containsString(slice []string, element string) bool {
 	for _, e := range slice {
 		if e == element {
 			return true
@@ -1170,14 +2381,16 @@ func searchExtension(slice []string, element string) bool {
 	return false
 }
 
-func mergeExtensions(l1 []string, l2 []string) []string {
+// mergeStrings returns the elements of l1 followed by those of l2 which
+// aren't already in it.
+func mergeStrings(l1 []string, l2 []string) []string {
 	var result []string
 	for _, e := range l1 {
 		result = append(result, e)
 	}
 
 	for _, e := range l2 {
-		if !searchExtension(result, e) {
+		if !containsString(result, e) {
 			result = append(result, e)
 		}
 	}
@@ -1188,7 +2401,7 @@ func mergeExtensions(l1 []string, l2 []string) []string {
 func removeExtensions(extensions []string, exclude []string) []string {
 	var result []string
 	for _, e := range extensions {
-		if !searchExtension(exclude, e) {
+		if !containsString(exclude, e) {
 			result = append(result, e)
 		}
 	}
@@ -1196,26 +2409,44 @@ func removeExtensions(extensions []string, exclude []string) []string {
 	return result
 }
 
-func extensionsAreSupported(plan ServicePlan, extensions []string) (bool, string) {
-	supported := aws.StringValueSlice(plan.RDSProperties.AllowedExtensions)
+func extensionsAreSupported(extensionLists ExtensionLists, extensions []string) (bool, string) {
+	supported := aws.StringValueSlice(extensionLists.AllowedExtensions)
 	for _, e := range extensions {
-		if !searchExtension(supported, e) {
+		if !containsString(supported, e) {
 			return false, e
 		}
 	}
 	return true, ""
 }
 
-func containsDefaultExtension(plan ServicePlan, extensions []string) (bool, string) {
-	defaultExtensions := aws.StringValueSlice(plan.RDSProperties.DefaultExtensions)
+func containsDefaultExtension(extensionLists ExtensionLists, extensions []string) (bool, string) {
+	defaultExtensions := aws.StringValueSlice(extensionLists.DefaultExtensions)
 	for _, e := range extensions {
-		if searchExtension(defaultExtensions, e) {
+		if containsString(defaultExtensions, e) {
 			return true, e
 		}
 	}
 	return false, ""
 }
 
+// extensionsEngineVersion is the engine version whose extensions apply to an
+// instance being updated to the plan: its own version, which can be ahead of
+// the plan's after upgrades, unless the plan upgrades it.
+func extensionsEngineVersion(servicePlan ServicePlan, dbInstance *rds.DBInstance) string {
+	planVersion := aws.StringValue(servicePlan.RDSProperties.EngineVersion)
+	instanceVersion := aws.StringValue(dbInstance.EngineVersion)
+
+	planSemVer, err := semver.NewVersion(planVersion)
+	if err != nil {
+		return planVersion
+	}
+	instanceSemVer, err := semver.NewVersion(instanceVersion)
+	if err != nil || planSemVer.GreaterThan(instanceSemVer) {
+		return planVersion
+	}
+	return instanceVersion
+}
+
 func (b *RDSBroker) ensureCreateExtensions(instanceID string, dbInstance *rds.DBInstance, tagsByName map[string]string) error {
 	b.logger.Debug("ensure-create-extensions", lager.Data{
 		instanceIDLogKey: instanceID,
@@ -1241,7 +2472,102 @@ func (b *RDSBroker) ensureCreateExtensions(instanceID string, dbInstance *rds.DB
 	return nil
 }
 
-func (b *RDSBroker) ensureDropExtensions(instanceID string, dbInstance *rds.DBInstance, extensions []string) error {
+// ensureUpdateExtensions updates the instance's extensions once a major
+// version upgrade has completed, as postgres leaves installed extensions at
+// their old versions. An extension which fails to update is only logged: it
+// still works at its old version, and failing the operation would leave the
+// instance looking broken after a successful upgrade.
+func (b *RDSBroker) ensureUpdateExtensions(instanceID string, dbInstance *rds.DBInstance, tagsByName map[string]string) error {
+	updateFor, exists := tagsByName[awsrds.TagExtensionUpdateFor]
+	if !exists || aws.StringValue(dbInstance.Engine) != "postgres" {
+		return nil
+	}
+
+	targetMajorVersion, err := strconv.ParseInt(updateFor, 10, 64)
+	if err != nil {
+		return err
+	}
+	engineVersion, err := semver.NewVersion(aws.StringValue(dbInstance.EngineVersion))
+	if err != nil {
+		return err
+	}
+	if engineVersion.Major() < targetMajorVersion {
+		// the upgrade hasn't happened, so there's nothing to update yet
+		return nil
+	}
+
+	b.logger.Debug("ensure-update-extensions", lager.Data{
+		instanceIDLogKey: instanceID,
+		"engineVersion":  aws.StringValue(dbInstance.EngineVersion),
+	})
+
+	if extensions := tagsByName[awsrds.TagExtensions]; extensions != "" {
+		dbName := b.dbNameFromDBInstance(instanceID, dbInstance)
+		sqlEngine, err := b.openSQLEngineForDBInstance(instanceID, dbName, dbInstance)
+		if err != nil {
+			return err
+		}
+		defer sqlEngine.Close()
+
+		for _, extension := range unpackExtensions(extensions) {
+			if err := sqlEngine.UpdateExtension(extension); err != nil {
+				b.logger.Error("update-extension", err, lager.Data{
+					instanceIDLogKey: instanceID,
+					"extension":      extension,
+				})
+			}
+		}
+	}
+
+	return b.removeTag(b.dbInstanceIdentifier(instanceID), awsrds.TagExtensionUpdateFor)
+}
+
+// ensureBinlogRetention applies the plan's binlog retention to MySQL
+// instances. RDS doesn't expose this setting through its API so it has to be
+// set from inside the database. The retention applied is recorded in a tag,
+// so that it is only set again when the plan's changes, and so that point in
+// time restores can tell how far back the instance can be restored.
+func (b *RDSBroker) ensureBinlogRetention(instanceID string, dbInstance *rds.DBInstance, tagsByName map[string]string) error {
+	servicePlan, ok := b.catalog.FindServicePlan(tagsByName[awsrds.TagPlanID])
+	if !ok || servicePlan.RDSProperties.BinlogRetentionHours == nil {
+		return nil
+	}
+	switch aws.StringValue(dbInstance.Engine) {
+	case "mariadb", "mysql":
+	default:
+		return nil
+	}
+	hours := strconv.FormatInt(*servicePlan.RDSProperties.BinlogRetentionHours, 10)
+	if tagsByName[awsrds.TagBinlogRetentionHours] == hours {
+		return nil
+	}
+
+	b.logger.Debug("ensure-binlog-retention", lager.Data{
+		instanceIDLogKey: instanceID,
+		"hours":          *servicePlan.RDSProperties.BinlogRetentionHours,
+	})
+
+	dbName := b.dbNameFromDBInstance(instanceID, dbInstance)
+	sqlEngine, err := b.openSQLEngineForDBInstance(instanceID, dbName, dbInstance)
+	if err != nil {
+		return err
+	}
+	defer sqlEngine.Close()
+
+	if err := sqlEngine.SetBinlogRetentionHours(*servicePlan.RDSProperties.BinlogRetentionHours); err != nil {
+		return err
+	}
+
+	return b.dbInstance.AddTagsToResource(
+		aws.StringValue(dbInstance.DBInstanceArn),
+		awsrds.BuildRDSTags(map[string]string{awsrds.TagBinlogRetentionHours: hours}),
+	)
+}
+
+// ensureDropExtensions drops the given extensions from a postgres instance.
+// Extensions which other objects depend on are only dropped when force is
+// set, in which case those objects are dropped with them.
+func (b *RDSBroker) ensureDropExtensions(instanceID string, dbInstance *rds.DBInstance, extensions []string, force bool) error {
 	b.logger.Debug("ensure-drop-extensions", lager.Data{
 		instanceIDLogKey: instanceID,
 	})
@@ -1254,7 +2580,24 @@ func (b *RDSBroker) ensureDropExtensions(instanceID string, dbInstance *rds.DBIn
 		}
 		defer sqlEngine.Close()
 
-		if err = sqlEngine.DropExtensions(extensions); err != nil {
+		dependents, err := sqlEngine.ExtensionDependents(extensions)
+		if err != nil {
+			return err
+		}
+		if len(dependents) > 0 {
+			if !force {
+				return fmt.Errorf(
+					"The extensions to disable have dependent objects, which would be dropped along with them: %s. Set force_drop_extensions to true to drop them anyway",
+					describeExtensionDependents(extensions, dependents),
+				)
+			}
+			b.logger.Info("drop-extensions-cascade", lager.Data{
+				instanceIDLogKey: instanceID,
+				"dependents":     dependents,
+			})
+		}
+
+		if err = sqlEngine.DropExtensions(extensions, force); err != nil {
 			return err
 		}
 	}
@@ -1262,6 +2605,18 @@ func (b *RDSBroker) ensureDropExtensions(instanceID string, dbInstance *rds.DBIn
 	return nil
 }
 
+// describeExtensionDependents lists the objects depending on each extension,
+// in the order the extensions were asked for.
+func describeExtensionDependents(extensions []string, dependents map[string][]string) string {
+	descriptions := []string{}
+	for _, extension := range extensions {
+		if objects := dependents[extension]; len(objects) > 0 {
+			descriptions = append(descriptions, fmt.Sprintf("%s (%s)", extension, strings.Join(objects, ", ")))
+		}
+	}
+	return strings.Join(descriptions, "; ")
+}
+
 // pack array of extensions to their tag-stored format
 func packExtensions(unpackedExtensions []string) string {
 	return strings.Join(unpackedExtensions, ":")
@@ -1285,8 +2640,9 @@ func (b *RDSBroker) updateDBSettings(instanceID string, dbInstance *rds.DBInstan
 
 	existingParameterGroup := aws.StringValue(dbInstance.DBParameterGroups[0].DBParameterGroupName)
 
-	modifyDBInstanceInput := b.newModifyDBInstanceInput(instanceID, servicePlan, UpdateParameters{}, existingParameterGroup)
-	modifyDBInstanceInput.MasterUserPassword = aws.String(b.generateMasterPassword(instanceID))
+	modifyDBInstanceInput := b.newModifyDBInstanceInput(instanceID, servicePlan, UpdateParameters{}, existingParameterGroup, tagsByName[awsrds.TagSecurityGroupSet], tagsByName[awsrds.TagNetworkTier], tagsByName[awsrds.TagPubliclyAccessible], tagsByName[awsrds.TagAutoMinorUpgrade])
+	derivation := b.newMasterPasswordDerivation(aws.StringValue(dbInstance.Engine))
+	modifyDBInstanceInput.MasterUserPassword = aws.String(b.generateMasterPassword(instanceID, derivation))
 	updatedDBInstance, err := b.dbInstance.Modify(modifyDBInstanceInput)
 	if err != nil {
 		if err == awsrds.ErrDBInstanceDoesNotExist {
@@ -1310,19 +2666,18 @@ func (b *RDSBroker) updateDBSettings(instanceID string, dbInstance *rds.DBInstan
 		ChargeableEntity: instanceID,
 	})
 
-	rdsTags := awsrds.BuildRDSTags(tags)
-	b.dbInstance.AddTagsToResource(aws.StringValue(updatedDBInstance.DBInstanceArn), rdsTags)
-	// AddTagsToResource error intentionally ignored - it's logged inside the method
+	// the restored instance has the tags of the one its snapshot was taken
+	// of, whose password may have had another derivation
+	err = b.recordMasterPasswordRotation(instanceID, aws.StringValue(updatedDBInstance.DBInstanceArn), tags, masterPasswordDerivationFromTags(tagsByName), derivation)
+	if err != nil {
+		return false, err
+	}
 
 	return true, nil
 }
 
 func (b *RDSBroker) rebootInstance(instanceID string, dbInstance *rds.DBInstance, tagsByName map[string]string) (asyncOperationTriggered bool, err error) {
-	rebootDBInstanceInput := &rds.RebootDBInstanceInput{
-		DBInstanceIdentifier: aws.String(b.dbInstanceIdentifier(instanceID)),
-	}
-
-	err = b.dbInstance.Reboot(rebootDBInstanceInput)
+	err = b.rebootForReason(context.Background(), instanceID, aws.StringValue(dbInstance.DBInstanceArn), nil, rebootReasonRestore)
 	if err != nil {
 		return false, err
 	}
@@ -1330,7 +2685,25 @@ func (b *RDSBroker) rebootInstance(instanceID string, dbInstance *rds.DBInstance
 	return true, nil
 }
 
+// masterPasswordChanging is true while RDS is still applying a new master
+// password, during which the broker can't log in with either password
+// reliably. Bindings should be retried once it has been applied.
+func masterPasswordChanging(dbInstance *rds.DBInstance) bool {
+	if aws.StringValue(dbInstance.DBInstanceStatus) == "resetting-master-credentials" {
+		return true
+	}
+	return dbInstance.PendingModifiedValues != nil && dbInstance.PendingModifiedValues.MasterUserPassword != nil
+}
+
 func (b *RDSBroker) openSQLEngineForDBInstance(instanceID string, dbName string, dbInstance *rds.DBInstance) (sqlengine.SQLEngine, error) {
+	masterPassword, err := b.masterPasswordForDBInstance(instanceID, dbInstance)
+	if err != nil {
+		return nil, err
+	}
+	return b.openSQLEngineWithMasterPassword(dbName, dbInstance, masterPassword)
+}
+
+func (b *RDSBroker) openSQLEngineWithMasterPassword(dbName string, dbInstance *rds.DBInstance, masterPassword string) (sqlengine.SQLEngine, error) {
 	dbAddress := awsrds.GetDBAddress(dbInstance.Endpoint)
 	dbPort := awsrds.GetDBPort(dbInstance.Endpoint)
 	masterUsername := aws.StringValue(dbInstance.MasterUsername)
@@ -1345,7 +2718,7 @@ func (b *RDSBroker) openSQLEngineForDBInstance(instanceID string, dbName string,
 		return nil, err
 	}
 
-	err = sqlEngine.Open(dbAddress, dbPort, dbName, masterUsername, b.generateMasterPassword(instanceID))
+	err = sqlEngine.Open(dbAddress, dbPort, dbName, masterUsername, masterPassword)
 	if err != nil {
 		sqlEngine.Close()
 		return nil, err
@@ -1372,38 +2745,49 @@ func (b *RDSBroker) PostRestoreTasks(instanceID string, dbInstance *rds.DBInstan
 	restoreStateFuncs := map[string]func(instanceID string, instance *rds.DBInstance, tagsByName map[string]string) (bool, error){
 		StateUpdateSettings:    b.updateDBSettings,
 		StateReboot:            b.rebootInstance,
+		StateCreateDatabase:    b.createAdoptedDatabase,
 		StateResetUserPassword: b.changeUserPassword,
 	}
 
-	for _, state := range restoreStateSequence {
-		_, tag := tagsByName[state]
-		if tag {
-			b.logger.Debug(fmt.Sprintf("last-operation.%s", state))
-			var success, err = restoreStateFuncs[state](instanceID, dbInstance, tagsByName)
-			if success {
-				var err = b.dbInstance.RemoveTag(b.dbInstanceIdentifier(instanceID), state)
-				if err != nil {
-					return false, err
-				}
-			}
-			return success, err
+	state := nextRestoreState(tagsByName)
+	if state == "" {
+		return false, nil
+	}
+
+	b.logger.Debug(fmt.Sprintf("last-operation.%s", state))
+	success, err := restoreStateFuncs[state](instanceID, dbInstance, tagsByName)
+	if success {
+		err := b.removeTag(b.dbInstanceIdentifier(instanceID), state)
+		if err != nil {
+			return false, err
 		}
 	}
+	return success, err
+}
 
-	return false, nil
+// nextRestoreState is the first of the post restore tasks still tagged on
+// the instance, or "" once they have all been done.
+func nextRestoreState(tagsByName map[string]string) string {
+	for _, state := range restoreStateSequence {
+		if _, tag := tagsByName[state]; tag {
+			return state
+		}
+	}
+	return ""
 }
 
 func (b *RDSBroker) RebootIfRequired(instanceID string, dbInstance *rds.DBInstance) (asyncOperationTriggered bool, err error) {
-	if aws.StringValue(dbInstance.DBParameterGroups[0].ParameterApplyStatus) == "applying" {
+	pendingReboot := awsrds.GetPendingReboot(dbInstance)
+	if pendingReboot.Applying {
 		return true, nil
 	}
 
-	if aws.StringValue(dbInstance.DBParameterGroups[0].ParameterApplyStatus) == "pending-reboot" {
-		rebootDBInstanceInput := &rds.RebootDBInstanceInput{
-			DBInstanceIdentifier: aws.String(b.dbInstanceIdentifier(instanceID)),
-		}
-
-		err := b.dbInstance.Reboot(rebootDBInstanceInput)
+	if pendingReboot.Required {
+		b.logger.Info("reboot-if-required", lager.Data{
+			instanceIDLogKey:  instanceID,
+			"parameterGroups": pendingReboot.ParameterGroups,
+		})
+		err := b.rebootForReason(context.Background(), instanceID, aws.StringValue(dbInstance.DBInstanceArn), nil, rebootReasonParameterChange)
 		if err != nil {
 			return false, err
 		}
@@ -1430,32 +2814,56 @@ func (b *RDSBroker) CheckAndRotateCredentials() {
 	for _, dbInstance := range dbInstances {
 		dbInstanceIdentifier := aws.StringValue(dbInstance.DBInstanceIdentifier)
 		b.logger.Debug(fmt.Sprintf("Checking credentials for instance %v", dbInstanceIdentifier))
+		if masterPasswordChanging(dbInstance) {
+			continue
+		}
 		serviceInstanceID := b.dbInstanceIdentifierToServiceInstanceID(dbInstanceIdentifier)
-		masterPassword := b.generateMasterPassword(serviceInstanceID)
+		derivation, err := b.masterPasswordDerivationOf(dbInstance)
+		if err != nil {
+			b.logger.Error(fmt.Sprintf("Could not get the master password version of instance %v", dbInstanceIdentifier), err)
+			continue
+		}
+		newDerivation := b.newMasterPasswordDerivation(aws.StringValue(dbInstance.Engine))
 
 		// Hey, this is wrong:
 		dbName := b.dbNameFromDBInstance(dbInstanceIdentifier, dbInstance)
 
-		sqlEngine, err := b.openSQLEngineForDBInstance(serviceInstanceID, dbName, dbInstance)
+		sqlEngine, err := b.openSQLEngineWithMasterPassword(dbName, dbInstance, b.generateMasterPassword(serviceInstanceID, derivation))
 		if sqlEngine != nil {
 			sqlEngine.Close()
 		}
+		if err != nil && err != sqlengine.LoginFailedError {
+			b.logger.Error(fmt.Sprintf("Unknown error when connecting to DB"), err, lager.Data{"id": dbInstanceIdentifier, "endpoint": dbInstance.Endpoint})
+			continue
+		}
+		if err == sqlengine.LoginFailedError {
+			b.logger.Info(
+				"Login failed when connecting to DB. Will attempt to reset the password.",
+				lager.Data{"engine": sqlEngine, "endpoint": dbInstance.Endpoint})
+		} else if !derivation.equal(newDerivation) {
+			// passwords are moved to the configured version and policy while
+			// the old one still works, so that nothing is left using it
+			b.logger.Info("Rotating the master password to the configured version.", lager.Data{
+				"id":          dbInstanceIdentifier,
+				"fromVersion": derivation.version,
+				"toVersion":   newDerivation.version,
+			})
+		} else {
+			continue
+		}
+
+		changePasswordInput := &rds.ModifyDBInstanceInput{
+			DBInstanceIdentifier: dbInstance.DBInstanceIdentifier,
+			MasterUserPassword:   aws.String(b.generateMasterPassword(serviceInstanceID, newDerivation)),
+		}
+		_, err = b.dbInstance.Modify(changePasswordInput)
 		if err != nil {
-			if err == sqlengine.LoginFailedError {
-				b.logger.Info(
-					"Login failed when connecting to DB. Will attempt to reset the password.",
-					lager.Data{"engine": sqlEngine, "endpoint": dbInstance.Endpoint})
-				changePasswordInput := &rds.ModifyDBInstanceInput{
-					DBInstanceIdentifier: dbInstance.DBInstanceIdentifier,
-					MasterUserPassword:   aws.String(masterPassword),
-				}
-				_, err = b.dbInstance.Modify(changePasswordInput)
-				if err != nil {
-					b.logger.Error(fmt.Sprintf("Could not reset the master password of instance %v", dbInstanceIdentifier), err)
-				}
-			} else {
-				b.logger.Error(fmt.Sprintf("Unknown error when connecting to DB"), err, lager.Data{"id": dbInstanceIdentifier, "endpoint": dbInstance.Endpoint})
-			}
+			b.logger.Error(fmt.Sprintf("Could not reset the master password of instance %v", dbInstanceIdentifier), err)
+			continue
+		}
+		err = b.recordMasterPasswordRotation(serviceInstanceID, aws.StringValue(dbInstance.DBInstanceArn), map[string]string{}, derivation, newDerivation)
+		if err != nil {
+			b.logger.Error(fmt.Sprintf("Could not record the master password rotation of instance %v", dbInstanceIdentifier), err)
 		}
 	}
 	b.logger.Info(fmt.Sprintf("Instances credentials check has ended"))
@@ -1466,6 +2874,7 @@ func (b *RDSBroker) dbInstanceIdentifier(instanceID string) string {
 }
 
 func (b *RDSBroker) dbInstanceIdentifierToServiceInstanceID(serviceInstanceID string) string {
+	serviceInstanceID = strings.TrimSuffix(serviceInstanceID, softDeletedIdentifierSuffix)
 	return strings.TrimPrefix(serviceInstanceID, strings.Replace(b.dbPrefix, "_", "-", -1)+"-")
 }
 
@@ -1473,8 +2882,24 @@ func (b *RDSBroker) generateMasterUsername() string {
 	return utils.RandomAlphaNum(MasterUsernameLength)
 }
 
-func (b *RDSBroker) generateMasterPassword(instanceID string) string {
-	return utils.GenerateHash(b.masterPasswordSeed+instanceID, MasterPasswordLength)
+// generateMasterPassword derives the master password of a service
+// instance's DB instance from the broker's seed with a derivation.
+func (b *RDSBroker) generateMasterPassword(instanceID string, derivation masterPasswordDerivation) string {
+	length, characters := MasterPasswordLength, ""
+	if derivation.policy != nil {
+		length, characters = derivation.policy.Length, derivation.policy.Characters
+	}
+
+	if derivation.version >= MasterPasswordVersionHKDF {
+		if characters == "" {
+			characters = base64URLCharacters
+		}
+		return utils.DeriveKeyString(b.masterPasswordSeed, instanceID, masterPasswordKeyInfo, length, characters)
+	}
+	if characters == "" {
+		return utils.GenerateHash(b.masterPasswordSeed+instanceID, length)
+	}
+	return utils.DeriveString(b.masterPasswordSeed+instanceID, length, characters)
 }
 
 func (b *RDSBroker) dbName(instanceID string) string {
@@ -1493,25 +2918,53 @@ func (b *RDSBroker) dbNameFromDBInstance(instanceID string, dbInstance *rds.DBIn
 }
 
 func (b *RDSBroker) newCreateDBInstanceInput(instanceID string, servicePlan ServicePlan, provisionParameters ProvisionParameters, details domain.ProvisionDetails) (*rds.CreateDBInstanceInput, error) {
-	skipFinalSnapshot := false
-	if provisionParameters.SkipFinalSnapshot != nil {
-		skipFinalSnapshot = *provisionParameters.SkipFinalSnapshot
-	} else if servicePlan.RDSProperties.SkipFinalSnapshot != nil {
-		skipFinalSnapshot = *servicePlan.RDSProperties.SkipFinalSnapshot
+	skipFinalSnapshot := b.provisionSkipFinalSnapshot(servicePlan, provisionParameters)
+
+	networkTier, err := b.networkTierForProvision(details.OrganizationGUID, provisionParameters.NetworkTier)
+	if err != nil {
+		return nil, err
+	}
+
+	dataExportBucket, err := b.dataExportBucketForProvision(details.OrganizationGUID, servicePlan, provisionParameters.ExportDataOnDeprovision)
+	if err != nil {
+		return nil, err
 	}
 
+	platform := platformContextFrom(details.RawContext)
 	tags := RDSInstanceTags{
-		Action:            "Created",
-		ServiceID:         details.ServiceID,
-		PlanID:            details.PlanID,
-		OrganizationID:    details.OrganizationGUID,
-		SpaceID:           details.SpaceGUID,
-		SkipFinalSnapshot: strconv.FormatBool(skipFinalSnapshot),
-		Extensions:        provisionParameters.Extensions,
-		ChargeableEntity:  instanceID,
+		Action:                  "Created",
+		ServiceID:               details.ServiceID,
+		PlanID:                  details.PlanID,
+		OrganizationID:          details.OrganizationGUID,
+		SpaceID:                 details.SpaceGUID,
+		SkipFinalSnapshot:       strconv.FormatBool(skipFinalSnapshot),
+		Extensions:              provisionParameters.Extensions,
+		ChargeableEntity:        instanceID,
+		InstanceName:            instanceNameFromContext(details.RawContext),
+		TrialExpires:            trialExpiresAt(servicePlan, time.Now()),
+		Platform:                platform.Platform,
+		KubernetesNamespace:     platform.kubernetesNamespace(),
+		AdditionalDatabases:     provisionParameters.AdditionalDatabases,
+		NetworkTier:             networkTier,
+		PubliclyAccessible:      userChoice(provisionParameters.PubliclyAccessible, ""),
+		AutoMinorVersionUpgrade: userChoice(provisionParameters.AutoMinorVersionUpgrade, ""),
+		ScheduleAtMaintenance:   userChoice(provisionParameters.ScheduleAtMaintenanceWindow, ""),
+		MasterPasswordVersion:   masterPasswordVersionTag(b.masterPasswordVersion),
+		MasterPasswordPolicy:    packMasterPasswordPolicy(b.newMasterPasswordDerivation(aws.StringValue(servicePlan.RDSProperties.Engine)).policy),
+		KmsKeyAlias:             provisionParameters.KmsKeyAlias,
+		SchemaCopyFrom:          aws.StringValue(provisionParameters.TemplateDatabaseInstance),
+		DataExportOnDeprovision: dataExportBucket,
+	}
+	if provisionParameters.ImportData != nil {
+		tags.DataImport = packDataImport(*provisionParameters.ImportData)
+	}
+
+	kmsKeyID, err := b.kmsKeyIDForProvision(servicePlan, provisionParameters.KmsKeyAlias)
+	if err != nil {
+		return nil, err
 	}
 
-	parameterGroupName, err := b.parameterGroupsSelector.SelectParameterGroup(servicePlan, provisionParameters.Extensions)
+	parameterGroupName, err := b.parameterGroupsSelector.SelectParameterGroup(servicePlan, provisionParameters.Extensions, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1520,31 +2973,31 @@ func (b *RDSBroker) newCreateDBInstanceInput(instanceID string, servicePlan Serv
 		DBInstanceIdentifier:       aws.String(b.dbInstanceIdentifier(instanceID)),
 		DBName:                     aws.String(b.dbName(instanceID)),
 		MasterUsername:             aws.String(b.generateMasterUsername()),
-		MasterUserPassword:         aws.String(b.generateMasterPassword(instanceID)),
+		MasterUserPassword:         aws.String(b.generateMasterPassword(instanceID, b.newMasterPasswordDerivation(aws.StringValue(servicePlan.RDSProperties.Engine)))),
 		DBInstanceClass:            servicePlan.RDSProperties.DBInstanceClass,
 		Engine:                     servicePlan.RDSProperties.Engine,
-		AutoMinorVersionUpgrade:    servicePlan.RDSProperties.AutoMinorVersionUpgrade,
+		AutoMinorVersionUpgrade:    autoMinorVersionUpgrade(servicePlan, tags.AutoMinorVersionUpgrade),
 		AvailabilityZone:           servicePlan.RDSProperties.AvailabilityZone,
 		CopyTagsToSnapshot:         servicePlan.RDSProperties.CopyTagsToSnapshot,
 		DBParameterGroupName:       aws.String(parameterGroupName),
-		DBSubnetGroupName:          servicePlan.RDSProperties.DBSubnetGroupName,
+		DBSubnetGroupName:          b.networkTierSubnetGroup(servicePlan, networkTier),
 		EngineVersion:              servicePlan.RDSProperties.EngineVersion,
 		OptionGroupName:            servicePlan.RDSProperties.OptionGroupName,
 		PreferredMaintenanceWindow: servicePlan.RDSProperties.PreferredMaintenanceWindow,
-		PubliclyAccessible:         servicePlan.RDSProperties.PubliclyAccessible,
+		PubliclyAccessible:         publiclyAccessible(servicePlan, tags.PubliclyAccessible),
 		BackupRetentionPeriod:      servicePlan.RDSProperties.BackupRetentionPeriod,
 		AllocatedStorage:           servicePlan.RDSProperties.AllocatedStorage,
 		CharacterSetName:           servicePlan.RDSProperties.CharacterSetName,
 		DBSecurityGroups:           servicePlan.RDSProperties.DBSecurityGroups,
 		Iops:                       servicePlan.RDSProperties.Iops,
-		KmsKeyId:                   servicePlan.RDSProperties.KmsKeyID,
+		KmsKeyId:                   kmsKeyID,
 		LicenseModel:               servicePlan.RDSProperties.LicenseModel,
 		MultiAZ:                    servicePlan.RDSProperties.MultiAZ,
 		Port:                       servicePlan.RDSProperties.Port,
 		PreferredBackupWindow:      servicePlan.RDSProperties.PreferredBackupWindow,
 		StorageEncrypted:           servicePlan.RDSProperties.StorageEncrypted,
 		StorageType:                servicePlan.RDSProperties.StorageType,
-		VpcSecurityGroupIds:        servicePlan.RDSProperties.VpcSecurityGroupIds,
+		VpcSecurityGroupIds:        b.networkTierSecurityGroups(servicePlan, networkTier),
 		Tags:                       awsrds.BuildRDSTags(b.dbTags(tags)),
 	}
 	if provisionParameters.PreferredBackupWindow != "" {
@@ -1557,20 +3010,26 @@ func (b *RDSBroker) newCreateDBInstanceInput(instanceID string, servicePlan Serv
 }
 
 func (b *RDSBroker) restoreDBInstanceInput(instanceID string, snapshot *rds.DBSnapshot, servicePlan ServicePlan, provisionParameters ProvisionParameters, details domain.ProvisionDetails) (*rds.RestoreDBInstanceFromDBSnapshotInput, error) {
-	skipFinalSnapshot := false
-	if provisionParameters.SkipFinalSnapshot != nil {
-		skipFinalSnapshot = *provisionParameters.SkipFinalSnapshot
-	} else if servicePlan.RDSProperties.SkipFinalSnapshot != nil {
-		skipFinalSnapshot = *servicePlan.RDSProperties.SkipFinalSnapshot
-	}
+	skipFinalSnapshot := b.provisionSkipFinalSnapshot(servicePlan, provisionParameters)
 	skipFinalSnapshotStr := strconv.FormatBool(skipFinalSnapshot)
 
-	parameterGroupName, err := b.parameterGroupsSelector.SelectParameterGroup(servicePlan, provisionParameters.Extensions)
+	parameterGroupName, err := b.parameterGroupsSelector.SelectParameterGroup(servicePlan, provisionParameters.Extensions, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	networkTier, err := b.networkTierForProvision(details.OrganizationGUID, provisionParameters.NetworkTier)
+	if err != nil {
+		return nil, err
+	}
+
+	dataExportBucket, err := b.dataExportBucketForProvision(details.OrganizationGUID, servicePlan, provisionParameters.ExportDataOnDeprovision)
 	if err != nil {
 		return nil, err
 	}
 
 	//"Restored", details.ServiceID, details.PlanID, details.OrganizationGUID, details.SpaceGUID, skipFinalSnapshotStr, snapshot.DBSnapshotIdentifier, provisionParameters.Extensions
+	platform := platformContextFrom(details.RawContext)
 	tags := RDSInstanceTags{
 		Action:                   "Restored",
 		ServiceID:                details.ServiceID,
@@ -1582,43 +3041,65 @@ func (b *RDSBroker) restoreDBInstanceInput(instanceID string, snapshot *rds.DBSn
 		OriginDatabaseIdentifier: aws.StringValue(snapshot.DBInstanceIdentifier),
 		Extensions:               provisionParameters.Extensions,
 		ChargeableEntity:         instanceID,
-	}
-
-	return &rds.RestoreDBInstanceFromDBSnapshotInput{
+		InstanceName:             instanceNameFromContext(details.RawContext),
+		TrialExpires:             trialExpiresAt(servicePlan, time.Now()),
+		Platform:                 platform.Platform,
+		KubernetesNamespace:      platform.kubernetesNamespace(),
+		AdditionalDatabases:      provisionParameters.AdditionalDatabases,
+		NetworkTier:              networkTier,
+		PubliclyAccessible:       userChoice(provisionParameters.PubliclyAccessible, ""),
+		AutoMinorVersionUpgrade:  userChoice(provisionParameters.AutoMinorVersionUpgrade, ""),
+		ScheduleAtMaintenance:    userChoice(provisionParameters.ScheduleAtMaintenanceWindow, ""),
+		DataExportOnDeprovision:  dataExportBucket,
+	}
+
+	input := &rds.RestoreDBInstanceFromDBSnapshotInput{
 		DBSnapshotIdentifier:    snapshot.DBSnapshotIdentifier,
 		DBInstanceIdentifier:    aws.String(b.dbInstanceIdentifier(instanceID)),
 		DBInstanceClass:         servicePlan.RDSProperties.DBInstanceClass,
 		Engine:                  servicePlan.RDSProperties.Engine,
-		AutoMinorVersionUpgrade: servicePlan.RDSProperties.AutoMinorVersionUpgrade,
+		AutoMinorVersionUpgrade: autoMinorVersionUpgrade(servicePlan, tags.AutoMinorVersionUpgrade),
 		AvailabilityZone:        servicePlan.RDSProperties.AvailabilityZone,
 		CopyTagsToSnapshot:      servicePlan.RDSProperties.CopyTagsToSnapshot,
 		DBParameterGroupName:    aws.String(parameterGroupName),
-		DBSubnetGroupName:       servicePlan.RDSProperties.DBSubnetGroupName,
+		DBSubnetGroupName:       b.networkTierSubnetGroup(servicePlan, networkTier),
 		OptionGroupName:         servicePlan.RDSProperties.OptionGroupName,
-		PubliclyAccessible:      servicePlan.RDSProperties.PubliclyAccessible,
+		PubliclyAccessible:      publiclyAccessible(servicePlan, tags.PubliclyAccessible),
 		Iops:                    servicePlan.RDSProperties.Iops,
 		LicenseModel:            servicePlan.RDSProperties.LicenseModel,
 		MultiAZ:                 servicePlan.RDSProperties.MultiAZ,
 		Port:                    servicePlan.RDSProperties.Port,
 		StorageType:             servicePlan.RDSProperties.StorageType,
 		Tags:                    awsrds.BuildRDSTags(b.dbTags(tags)),
-	}, nil
+	}
+	if networkTier != "" {
+		// otherwise the security groups are set once the restore has
+		// finished
+		input.VpcSecurityGroupIds = b.networkTierSecurityGroups(servicePlan, networkTier)
+	}
+	return input, nil
 }
 
 func (b *RDSBroker) restoreDBInstancePointInTimeInput(instanceID, originDBIdentifier string, originTime *time.Time, servicePlan ServicePlan, provisionParameters ProvisionParameters, details domain.ProvisionDetails) (*rds.RestoreDBInstanceToPointInTimeInput, error) {
-	skipFinalSnapshot := false
-	if provisionParameters.SkipFinalSnapshot != nil {
-		skipFinalSnapshot = *provisionParameters.SkipFinalSnapshot
-	} else if servicePlan.RDSProperties.SkipFinalSnapshot != nil {
-		skipFinalSnapshot = *servicePlan.RDSProperties.SkipFinalSnapshot
-	}
+	skipFinalSnapshot := b.provisionSkipFinalSnapshot(servicePlan, provisionParameters)
 	skipFinalSnapshotStr := strconv.FormatBool(skipFinalSnapshot)
 
-	parameterGroupName, err := b.parameterGroupsSelector.SelectParameterGroup(servicePlan, provisionParameters.Extensions)
+	parameterGroupName, err := b.parameterGroupsSelector.SelectParameterGroup(servicePlan, provisionParameters.Extensions, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	networkTier, err := b.networkTierForProvision(details.OrganizationGUID, provisionParameters.NetworkTier)
+	if err != nil {
+		return nil, err
+	}
+
+	dataExportBucket, err := b.dataExportBucketForProvision(details.OrganizationGUID, servicePlan, provisionParameters.ExportDataOnDeprovision)
 	if err != nil {
 		return nil, err
 	}
 
+	platform := platformContextFrom(details.RawContext)
 	tags := RDSInstanceTags{
 		Action:                   "Restored",
 		ServiceID:                details.ServiceID,
@@ -1629,6 +3110,16 @@ func (b *RDSBroker) restoreDBInstancePointInTimeInput(instanceID, originDBIdenti
 		OriginDatabaseIdentifier: b.dbInstanceIdentifier(originDBIdentifier),
 		Extensions:               provisionParameters.Extensions,
 		ChargeableEntity:         instanceID,
+		InstanceName:             instanceNameFromContext(details.RawContext),
+		TrialExpires:             trialExpiresAt(servicePlan, time.Now()),
+		Platform:                 platform.Platform,
+		KubernetesNamespace:      platform.kubernetesNamespace(),
+		AdditionalDatabases:      provisionParameters.AdditionalDatabases,
+		NetworkTier:              networkTier,
+		PubliclyAccessible:       userChoice(provisionParameters.PubliclyAccessible, ""),
+		AutoMinorVersionUpgrade:  userChoice(provisionParameters.AutoMinorVersionUpgrade, ""),
+		ScheduleAtMaintenance:    userChoice(provisionParameters.ScheduleAtMaintenanceWindow, ""),
+		DataExportOnDeprovision:  dataExportBucket,
 	}
 
 	if originTime != nil {
@@ -1641,13 +3132,13 @@ func (b *RDSBroker) restoreDBInstancePointInTimeInput(instanceID, originDBIdenti
 		RestoreTime:                originTime,
 		DBInstanceClass:            servicePlan.RDSProperties.DBInstanceClass,
 		Engine:                     servicePlan.RDSProperties.Engine,
-		AutoMinorVersionUpgrade:    servicePlan.RDSProperties.AutoMinorVersionUpgrade,
+		AutoMinorVersionUpgrade:    autoMinorVersionUpgrade(servicePlan, tags.AutoMinorVersionUpgrade),
 		AvailabilityZone:           servicePlan.RDSProperties.AvailabilityZone,
 		CopyTagsToSnapshot:         servicePlan.RDSProperties.CopyTagsToSnapshot,
 		DBParameterGroupName:       aws.String(parameterGroupName),
-		DBSubnetGroupName:          servicePlan.RDSProperties.DBSubnetGroupName,
+		DBSubnetGroupName:          b.networkTierSubnetGroup(servicePlan, networkTier),
 		OptionGroupName:            servicePlan.RDSProperties.OptionGroupName,
-		PubliclyAccessible:         servicePlan.RDSProperties.PubliclyAccessible,
+		PubliclyAccessible:         publiclyAccessible(servicePlan, tags.PubliclyAccessible),
 		Iops:                       servicePlan.RDSProperties.Iops,
 		LicenseModel:               servicePlan.RDSProperties.LicenseModel,
 		MultiAZ:                    servicePlan.RDSProperties.MultiAZ,
@@ -1656,6 +3147,10 @@ func (b *RDSBroker) restoreDBInstancePointInTimeInput(instanceID, originDBIdenti
 		Tags:                       awsrds.BuildRDSTags(b.dbTags(tags)),
 	}
 
+	if networkTier != "" {
+		input.VpcSecurityGroupIds = b.networkTierSecurityGroups(servicePlan, networkTier)
+	}
+
 	if originTime != nil {
 		input.RestoreTime = originTime
 	} else {
@@ -1665,18 +3160,25 @@ func (b *RDSBroker) restoreDBInstancePointInTimeInput(instanceID, originDBIdenti
 	return input, nil
 }
 
-func (b *RDSBroker) newModifyDBInstanceInput(instanceID string, servicePlan ServicePlan, updateParameters UpdateParameters, parameterGroupName string) *rds.ModifyDBInstanceInput {
+// newModifyDBInstanceInput builds the modification which brings the instance
+// in line with servicePlan. An instance in a network tier keeps the tier's
+// subnet group and security groups. If the instance has been moved to one of
+// the configured security group sets that takes the place of the plan's, or
+// the tier's, VpcSecurityGroupIds. publicAccess and autoMinorUpgrade are the
+// user's recorded publicly_accessible and auto_minor_version_upgrade
+// choices, if any.
+func (b *RDSBroker) newModifyDBInstanceInput(instanceID string, servicePlan ServicePlan, updateParameters UpdateParameters, parameterGroupName string, securityGroupSet string, networkTier string, publicAccess string, autoMinorUpgrade string) *rds.ModifyDBInstanceInput {
 	modifyDBInstanceInput := &rds.ModifyDBInstanceInput{
 		DBInstanceIdentifier:       aws.String(b.dbInstanceIdentifier(instanceID)),
 		DBInstanceClass:            servicePlan.RDSProperties.DBInstanceClass,
-		AutoMinorVersionUpgrade:    servicePlan.RDSProperties.AutoMinorVersionUpgrade,
+		AutoMinorVersionUpgrade:    autoMinorVersionUpgrade(servicePlan, autoMinorUpgrade),
 		CopyTagsToSnapshot:         servicePlan.RDSProperties.CopyTagsToSnapshot,
 		DBParameterGroupName:       aws.String(parameterGroupName),
-		DBSubnetGroupName:          servicePlan.RDSProperties.DBSubnetGroupName,
+		DBSubnetGroupName:          b.networkTierSubnetGroup(servicePlan, networkTier),
 		EngineVersion:              servicePlan.RDSProperties.EngineVersion,
 		OptionGroupName:            servicePlan.RDSProperties.OptionGroupName,
 		PreferredMaintenanceWindow: servicePlan.RDSProperties.PreferredMaintenanceWindow,
-		PubliclyAccessible:         servicePlan.RDSProperties.PubliclyAccessible,
+		PubliclyAccessible:         publiclyAccessible(servicePlan, publicAccess),
 		BackupRetentionPeriod:      servicePlan.RDSProperties.BackupRetentionPeriod,
 		AllocatedStorage:           servicePlan.RDSProperties.AllocatedStorage,
 		DBSecurityGroups:           servicePlan.RDSProperties.DBSecurityGroups,
@@ -1685,7 +3187,7 @@ func (b *RDSBroker) newModifyDBInstanceInput(instanceID string, servicePlan Serv
 		MultiAZ:                    servicePlan.RDSProperties.MultiAZ,
 		PreferredBackupWindow:      servicePlan.RDSProperties.PreferredBackupWindow,
 		StorageType:                servicePlan.RDSProperties.StorageType,
-		VpcSecurityGroupIds:        servicePlan.RDSProperties.VpcSecurityGroupIds,
+		VpcSecurityGroupIds:        b.networkTierSecurityGroups(servicePlan, networkTier),
 		ApplyImmediately:           aws.Bool(!updateParameters.ApplyAtMaintenanceWindow),
 	}
 	if updateParameters.PreferredBackupWindow != "" {
@@ -1694,6 +3196,9 @@ func (b *RDSBroker) newModifyDBInstanceInput(instanceID string, servicePlan Serv
 	if updateParameters.PreferredMaintenanceWindow != "" {
 		modifyDBInstanceInput.PreferredMaintenanceWindow = aws.String(updateParameters.PreferredMaintenanceWindow)
 	}
+	if securityGroupIDs, ok := b.securityGroupSets[securityGroupSet]; ok && securityGroupSet != "" {
+		modifyDBInstanceInput.VpcSecurityGroupIds = aws.StringSlice(securityGroupIDs)
+	}
 
 	b.logger.Debug("newModifyDBInstanceInputAndTags", lager.Data{
 		instanceIDLogKey:  instanceID,
@@ -1748,7 +3253,7 @@ func (b *RDSBroker) dbTags(instanceTags RDSInstanceTags) map[string]string {
 
 	tags["Owner"] = "Cloud Foundry"
 
-	tags["chargeable_entity"] = instanceTags.ChargeableEntity
+	tags[awsrds.TagChargeableEntity] = instanceTags.ChargeableEntity
 
 	tags[awsrds.TagBrokerName] = b.brokerName
 
@@ -1762,6 +3267,11 @@ func (b *RDSBroker) dbTags(instanceTags RDSInstanceTags) map[string]string {
 
 	if instanceTags.PlanID != "" {
 		tags[awsrds.TagPlanID] = instanceTags.PlanID
+		if servicePlan, found := b.catalog.FindServicePlan(instanceTags.PlanID); found {
+			for tagKey, tagValue := range servicePlan.serviceLevelTags() {
+				tags[tagKey] = tagValue
+			}
+		}
 	}
 
 	if instanceTags.OrganizationID != "" {
@@ -1795,5 +3305,103 @@ func (b *RDSBroker) dbTags(instanceTags RDSInstanceTags) map[string]string {
 		tags[awsrds.TagExtensions] = packExtensions(instanceTags.Extensions)
 	}
 
+	if instanceTags.UpdatedByUser != "" {
+		tags[awsrds.TagUpdatedByUser] = instanceTags.UpdatedByUser
+	}
+
+	if instanceTags.InstanceName != "" {
+		tags[awsrds.TagInstanceName] = instanceTags.InstanceName
+	}
+
+	if instanceTags.PreviousInstanceNames != "" {
+		tags[awsrds.TagPreviousInstanceNames] = instanceTags.PreviousInstanceNames
+	}
+
+	if instanceTags.SecurityGroupSet != "" {
+		tags[awsrds.TagSecurityGroupSet] = instanceTags.SecurityGroupSet
+	}
+
+	if instanceTags.NetworkTier != "" {
+		tags[awsrds.TagNetworkTier] = instanceTags.NetworkTier
+	}
+
+	if instanceTags.PubliclyAccessible != "" {
+		tags[awsrds.TagPubliclyAccessible] = instanceTags.PubliclyAccessible
+	}
+
+	if instanceTags.AutoMinorVersionUpgrade != "" {
+		tags[awsrds.TagAutoMinorUpgrade] = instanceTags.AutoMinorVersionUpgrade
+	}
+
+	if instanceTags.ScheduleAtMaintenance != "" {
+		tags[awsrds.TagScheduleAtMaintenance] = instanceTags.ScheduleAtMaintenance
+	}
+
+	if instanceTags.ScheduledMaintenance != "" {
+		tags[awsrds.TagScheduledMaintenance] = instanceTags.ScheduledMaintenance
+	}
+
+	if instanceTags.MasterPasswordVersion != "" {
+		tags[awsrds.TagMasterPasswordVersion] = instanceTags.MasterPasswordVersion
+	}
+
+	if instanceTags.MasterPasswordPolicy != "" {
+		tags[awsrds.TagMasterPasswordPolicy] = instanceTags.MasterPasswordPolicy
+	}
+
+	if instanceTags.KmsKeyAlias != "" {
+		tags[awsrds.TagKmsKeyAlias] = instanceTags.KmsKeyAlias
+	}
+
+	if instanceTags.SchemaCopyFrom != "" {
+		tags[awsrds.TagSchemaCopyFrom] = instanceTags.SchemaCopyFrom
+	}
+
+	if instanceTags.DataImport != "" {
+		tags[awsrds.TagDataImport] = instanceTags.DataImport
+	}
+
+	if instanceTags.DataExportOnDeprovision != "" {
+		tags[awsrds.TagDataExportOnDeprovision] = instanceTags.DataExportOnDeprovision
+	}
+
+	if instanceTags.ExtensionUpdateFor != "" {
+		tags[awsrds.TagExtensionUpdateFor] = instanceTags.ExtensionUpdateFor
+	}
+
+	if instanceTags.DatabasesToPurge != "" {
+		tags[awsrds.TagDatabasesToPurge] = instanceTags.DatabasesToPurge
+	}
+
+	if len(instanceTags.PgauditLog) > 0 {
+		tags[awsrds.TagPgauditLog] = strings.Join(instanceTags.PgauditLog, ":")
+	}
+
+	if instanceTags.TrialExpires != "" {
+		tags[awsrds.TagTrialExpires] = instanceTags.TrialExpires
+	}
+
+	if instanceTags.Platform != "" {
+		tags[awsrds.TagPlatform] = instanceTags.Platform
+	}
+
+	if instanceTags.KubernetesNamespace != "" {
+		tags[awsrds.TagKubernetesNamespace] = instanceTags.KubernetesNamespace
+	}
+
+	if len(instanceTags.AdditionalDatabases) > 0 {
+		tags[awsrds.TagAdditionalDatabases] = packAdditionalDatabases(instanceTags.AdditionalDatabases)
+	}
+
+	if instanceTags.UnprotectedUntil != "" {
+		tags[awsrds.TagUnprotectedUntil] = instanceTags.UnprotectedUntil
+	}
+
+	if instanceTags.AdoptedFrom != "" {
+		tags[awsrds.TagAdoptedFrom] = instanceTags.AdoptedFrom
+		tags[StateUpdateSettings] = "true"
+		tags[StateCreateDatabase] = "true"
+	}
+
 	return tags
 }
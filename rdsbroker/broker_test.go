@@ -31,27 +31,48 @@ import (
 	"github.com/pivotal-cf/brokerapi/v9"
 
 	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	dnsfake "github.com/alphagov/paas-rds-broker/awsroute53/fakes"
+	secretsfake "github.com/alphagov/paas-rds-broker/awssecrets/fakes"
 	sqlfake "github.com/alphagov/paas-rds-broker/sqlengine/fakes"
+	statestorefake "github.com/alphagov/paas-rds-broker/statestore/fakes"
 )
 
 var _ = Describe("RDS Broker", func() {
 	var (
 		ctx context.Context
 
-		rdsProperties1 RDSProperties
-		rdsProperties2 RDSProperties
-		rdsProperties3 RDSProperties
-		rdsProperties4 RDSProperties
-		rdsProperties5 RDSProperties
-		plan1          ServicePlan
-		plan2          ServicePlan
-		plan3          ServicePlan
-		plan4          ServicePlan
-		plan5          ServicePlan
-		service1       Service
-		service2       Service
-		service3       Service
-		catalog        Catalog
+		rdsProperties1  RDSProperties
+		rdsProperties2  RDSProperties
+		rdsProperties3  RDSProperties
+		rdsProperties4  RDSProperties
+		rdsProperties5  RDSProperties
+		connectionPool  *ConnectionPool
+		rdsProxy        *RDSProxy
+		dnsConfig       *DNSConfig
+
+		networkTiers             map[string]NetworkTier
+		organizationNetworkTiers map[string]string
+		subnetCIDRBlocks         map[string]string
+		dnsZone         *dnsfake.FakeDNSZone
+		secretStore     *secretsfake.FakeSecretStore
+		plan4Deprecated bool
+		plan1TrialDays  uint
+		plan1MaxAgeDays uint
+
+		plan1AllowUserSkipFinalSnapshot *bool
+		plan1AllowPublicAccess          bool
+		plan1AllowAutoMinorOptOut       bool
+		skipFinalSnapshotDefault        *bool
+
+		plan1           ServicePlan
+		plan2           ServicePlan
+		plan3           ServicePlan
+		plan4           ServicePlan
+		plan5           ServicePlan
+		service1        Service
+		service2        Service
+		service3        Service
+		catalog         Catalog
 
 		config Config
 
@@ -69,6 +90,13 @@ var _ = Describe("RDS Broker", func() {
 		allowUserProvisionParameters bool
 		allowUserUpdateParameters    bool
 		allowUserBindParameters      bool
+		allowDBInstanceAdoption      bool
+		dbInstanceAdoptionPrefixes   map[string][]string
+		checkBindingConnections      bool
+		softDeleteDays               uint
+		lastOperationCacheSeconds    uint
+		databaseUsageCacheSeconds    uint
+		operationLeaseSeconds        uint
 		serviceBindable              bool
 		instancesRetrievable         bool
 		planUpdateable               bool
@@ -99,18 +127,40 @@ var _ = Describe("RDS Broker", func() {
 		allowUserProvisionParameters = true
 		allowUserUpdateParameters = true
 		allowUserBindParameters = true
+		allowDBInstanceAdoption = false
+		dbInstanceAdoptionPrefixes = nil
+		checkBindingConnections = false
+		softDeleteDays = 0
+		lastOperationCacheSeconds = 0
+		databaseUsageCacheSeconds = 0
+		operationLeaseSeconds = 0
 		serviceBindable = true
 		instancesRetrievable = true
 		planUpdateable = true
 		skipFinalSnapshot = true
 		dbPrefix = "cf"
 		brokerName = "mybroker"
+		connectionPool = nil
+		rdsProxy = nil
+		dnsConfig = nil
+		networkTiers = nil
+		organizationNetworkTiers = nil
+		subnetCIDRBlocks = nil
+		dnsZone = &dnsfake.FakeDNSZone{}
+		secretStore = &secretsfake.FakeSecretStore{}
+		plan4Deprecated = false
+		plan1TrialDays = 0
+		plan1MaxAgeDays = 0
+		plan1AllowUserSkipFinalSnapshot = nil
+		plan1AllowPublicAccess = false
+		plan1AllowAutoMinorOptOut = false
+		skipFinalSnapshotDefault = nil
 
 		rdsInstance = &rdsfake.FakeRDSInstance{}
 
 		sqlProvider = &sqlfake.FakeProvider{}
 		sqlEngine = &sqlfake.FakeSQLEngine{}
-		sqlProvider.GetSQLEngineSQLEngine = sqlEngine
+		sqlProvider.GetSQLEngineReturns(sqlEngine, nil)
 
 		rdsProperties1 = RDSProperties{
 			DBInstanceClass:   stringPointer("db.m1.test"),
@@ -205,10 +255,18 @@ var _ = Describe("RDS Broker", func() {
 
 	JustBeforeEach(func() {
 		plan1 = ServicePlan{
-			ID:            "Plan-1",
-			Name:          "Plan 1",
-			Description:   "This is the Plan 1",
-			RDSProperties: rdsProperties1,
+			ID:                 "Plan-1",
+			Name:               "Plan 1",
+			Description:        "This is the Plan 1",
+			RDSProperties:      rdsProperties1,
+			ConnectionPool:     connectionPool,
+			TrialDays:          plan1TrialDays,
+			MaxInstanceAgeDays: plan1MaxAgeDays,
+
+			AllowUserSkipFinalSnapshot: plan1AllowUserSkipFinalSnapshot,
+			AllowPublicAccess:          plan1AllowPublicAccess,
+
+			AllowAutoMinorVersionUpgradeOptOut: plan1AllowAutoMinorOptOut,
 		}
 		plan2 = ServicePlan{
 			ID:            "Plan-2",
@@ -221,12 +279,14 @@ var _ = Describe("RDS Broker", func() {
 			Name:          "Plan 3",
 			Description:   "This is the Plan 3",
 			RDSProperties: rdsProperties3,
+			RDSProxy:      rdsProxy,
 		}
 		plan4 = ServicePlan{
 			ID:            "Plan-4",
 			Name:          "Plan 4",
 			Description:   "This is the Plan 4",
 			RDSProperties: rdsProperties4,
+			Deprecated:    plan4Deprecated,
 		}
 		plan5 = ServicePlan{
 			ID:            "Plan-5",
@@ -269,6 +329,18 @@ var _ = Describe("RDS Broker", func() {
 			AllowUserProvisionParameters: allowUserProvisionParameters,
 			AllowUserUpdateParameters:    allowUserUpdateParameters,
 			AllowUserBindParameters:      allowUserBindParameters,
+			AllowDBInstanceAdoption:      allowDBInstanceAdoption,
+			DBInstanceAdoptionPrefixes:   dbInstanceAdoptionPrefixes,
+			LastOperationCacheSeconds:    lastOperationCacheSeconds,
+			DatabaseUsageCacheSeconds:    databaseUsageCacheSeconds,
+			OperationLeaseSeconds:        operationLeaseSeconds,
+			CheckBindingConnections:      checkBindingConnections,
+			SoftDeleteDays:               softDeleteDays,
+			DNS:                          dnsConfig,
+			NetworkTiers:                 networkTiers,
+			OrganizationNetworkTiers:     organizationNetworkTiers,
+			SubnetCIDRBlocks:             subnetCIDRBlocks,
+			SkipFinalSnapshotDefault:     skipFinalSnapshotDefault,
 			Catalog:                      catalog,
 		}
 
@@ -282,6 +354,10 @@ var _ = Describe("RDS Broker", func() {
 		paramGroupSelector.SelectParameterGroupReturns(dbPrefix+"-postgres10-"+brokerName, nil)
 
 		rdsBroker = New(config, rdsInstance, sqlProvider, &paramGroupSelector, logger)
+		if dnsConfig != nil {
+			rdsBroker.SetDNSZone(dnsZone)
+		}
+		rdsBroker.SetSecretStore(secretStore)
 
 		brokeruser = "brokeruser"
 		brokerpass = "brokerpass"
@@ -365,6 +441,20 @@ var _ = Describe("RDS Broker", func() {
 			Expect(brokerCatalog).To(Equal(properCatalogResponse))
 		})
 
+		Context("when a plan is deprecated", func() {
+			BeforeEach(func() {
+				plan4Deprecated = true
+			})
+
+			It("does not advertise it", func() {
+				brokerCatalog, err := rdsBroker.Services(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(brokerCatalog[2].Plans).To(HaveLen(2))
+				Expect(brokerCatalog[2].Plans[0].ID).To(Equal("Plan-3"))
+				Expect(brokerCatalog[2].Plans[1].ID).To(Equal("Plan-5"))
+			})
+		})
+
 		It("brokerapi integration returns the proper CatalogResponse", func() {
 			var err error
 
@@ -428,7 +518,8 @@ var _ = Describe("RDS Broker", func() {
 			acceptsIncomplete = true
 
 			properProvisionedServiceSpec = domain.ProvisionedServiceSpec{
-				IsAsync: true,
+				IsAsync:       true,
+				OperationData: "provision",
 			}
 		})
 
@@ -466,11 +557,57 @@ var _ = Describe("RDS Broker", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 
+		Context("when the request context has an instance name", func() {
+			BeforeEach(func() {
+				provisionDetails.RawContext = json.RawMessage(`{"platform": "cloudfoundry", "instance_name": "orders-db"}`)
+			})
+
+			It("records the name in the tags", func() {
+				_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(rdsInstance.CreateCallCount()).To(Equal(1))
+				input := rdsInstance.CreateArgsForCall(0)
+				Expect(awsrds.RDSTagsValues(input.Tags)).To(HaveKeyWithValue("Instance Name", "orders-db"))
+			})
+		})
+
+		Context("when the request comes from Kubernetes", func() {
+			BeforeEach(func() {
+				provisionDetails.RawContext = json.RawMessage(`{"platform": "kubernetes", "namespace": "orders", "clusterid": "cluster-1"}`)
+			})
+
+			It("records the platform and namespace in the tags", func() {
+				_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+
+				input := rdsInstance.CreateArgsForCall(0)
+				Expect(awsrds.RDSTagsValues(input.Tags)).To(HaveKeyWithValue("Platform", "kubernetes"))
+				Expect(awsrds.RDSTagsValues(input.Tags)).To(HaveKeyWithValue("Kubernetes Namespace", "orders"))
+			})
+		})
+
+		Context("when the request comes from Cloud Foundry", func() {
+			BeforeEach(func() {
+				provisionDetails.RawContext = json.RawMessage(`{"platform": "cloudfoundry", "namespace": "ignored"}`)
+			})
+
+			It("records the platform but no namespace", func() {
+				_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+
+				input := rdsInstance.CreateArgsForCall(0)
+				Expect(awsrds.RDSTagsValues(input.Tags)).To(HaveKeyWithValue("Platform", "cloudfoundry"))
+				Expect(awsrds.RDSTagsValues(input.Tags)).ToNot(HaveKey("Kubernetes Namespace"))
+			})
+		})
+
 		Context("when restoring from a point in time", func() {
 			var (
 				restoreFromPointInTimeInstanceGUID  string
 				restoreFromPointInTimeDBInstanceID  string
 				restoreFromPointInTimeDBInstanceARN string
+				restoreFromPointInTimeDBInstance    *rds.DBInstance
 				dbIdentifierTags                    map[string]string
 			)
 
@@ -480,6 +617,12 @@ var _ = Describe("RDS Broker", func() {
 				restoreFromPointInTimeDBInstanceID = dbPrefix + "-guid-of-origin-instance"
 				restoreFromPointInTimeDBInstanceARN = "arn:aws:rds:rds-region:1234567890:db:" + restoreFromPointInTimeDBInstanceID
 				provisionDetails.RawParameters = json.RawMessage(`{"restore_from_point_in_time_of": "` + restoreFromPointInTimeInstanceGUID + `"}`)
+				restoreFromPointInTimeDBInstance = &rds.DBInstance{
+					DBInstanceArn:         aws.String(restoreFromPointInTimeDBInstanceARN),
+					DBInstanceIdentifier:  aws.String(restoreFromPointInTimeDBInstanceID),
+					BackupRetentionPeriod: aws.Int64(7),
+					InstanceCreateTime:    aws.Time(time.Now().Add(-30 * 24 * time.Hour)),
+				}
 
 				dbIdentifierTags = map[string]string{
 					"Space ID":        "space-id",
@@ -489,10 +632,7 @@ var _ = Describe("RDS Broker", func() {
 			})
 
 			JustBeforeEach(func() {
-				rdsInstance.DescribeReturns(&rds.DBInstance{
-					DBInstanceArn:        aws.String(restoreFromPointInTimeDBInstanceARN),
-					DBInstanceIdentifier: aws.String(restoreFromPointInTimeDBInstanceID),
-				}, nil)
+				rdsInstance.DescribeReturns(restoreFromPointInTimeDBInstance, nil)
 				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(dbIdentifierTags), nil)
 			})
 
@@ -673,6 +813,328 @@ var _ = Describe("RDS Broker", func() {
 					Expect(err).NotTo(HaveOccurred())
 					Expect(tagParsedTime).To(BeTemporally("~", restoreTime, 1*time.Second))
 				})
+
+				Context("and the time is before the backup retention window", func() {
+					BeforeEach(func() {
+						restoreFromPointInTimeDBInstance.BackupRetentionPeriod = aws.Int64(1)
+						restoreTime = time.Now().UTC().Add(-48 * time.Hour)
+						provisionDetails.RawParameters = json.RawMessage(
+							`{` +
+								`"restore_from_point_in_time_of": "` + restoreFromPointInTimeInstanceGUID + `",` +
+								`"restore_from_point_in_time_before": "` + restoreTime.Format("2006-01-02 15:04:05") + `"` +
+								`}`,
+						)
+					})
+
+					It("returns the correct error", func() {
+						_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("to a point in time before"))
+						Expect(err.Error()).To(ContainSubstring("which is the earliest time its backups cover"))
+						Expect(rdsInstance.RestoreToPointInTimeCallCount()).To(Equal(0))
+					})
+				})
+
+				Context("and the time is before the instance was created", func() {
+					BeforeEach(func() {
+						restoreFromPointInTimeDBInstance.InstanceCreateTime = aws.Time(time.Now().Add(-30 * time.Minute))
+					})
+
+					It("returns the correct error", func() {
+						_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("to a point in time before"))
+						Expect(rdsInstance.RestoreToPointInTimeCallCount()).To(Equal(0))
+					})
+				})
+
+				Context("and the instance is MySQL", func() {
+					BeforeEach(func() {
+						rdsProperties1.Engine = stringPointer("mysql")
+						restoreFromPointInTimeDBInstance.Engine = aws.String("mysql")
+						dbIdentifierTags["Binlog Retention Hours"] = "2"
+					})
+
+					It("restores within its binlog retention", func() {
+						_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(rdsInstance.RestoreToPointInTimeCallCount()).To(Equal(1))
+					})
+
+					Context("and the time is before its binlog retention", func() {
+						BeforeEach(func() {
+							dbIdentifierTags["Binlog Retention Hours"] = "0"
+						})
+
+						It("returns the correct error", func() {
+							_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+							Expect(err).To(MatchError(ContainSubstring("to a point in time before")))
+							Expect(rdsInstance.RestoreToPointInTimeCallCount()).To(Equal(0))
+						})
+					})
+
+					Context("and its plan doesn't retain binary logs", func() {
+						BeforeEach(func() {
+							delete(dbIdentifierTags, "Binlog Retention Hours")
+						})
+
+						It("returns the correct error", func() {
+							_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+							Expect(err).To(MatchError(ContainSubstring("as its plan doesn't retain binary logs")))
+							Expect(rdsInstance.RestoreToPointInTimeCallCount()).To(Equal(0))
+						})
+					})
+				})
+			})
+
+			Context("when the instance has no automated backups", func() {
+				BeforeEach(func() {
+					restoreFromPointInTimeDBInstance.BackupRetentionPeriod = aws.Int64(0)
+				})
+
+				It("returns the correct error", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("as it has no automated backups"))
+					Expect(rdsInstance.RestoreToPointInTimeCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the engine is 'mysql'", func() {
+				BeforeEach(func() {
+					rdsProperties1.Engine = stringPointer("mysql")
+				})
+
+				It("makes the proper calls", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(rdsInstance.RestoreToPointInTimeCallCount()).To(Equal(1))
+					input := rdsInstance.RestoreToPointInTimeArgsForCall(0)
+					Expect(aws.StringValue(input.TargetDBInstanceIdentifier)).To(Equal(dbInstanceIdentifier))
+					Expect(aws.StringValue(input.SourceDBInstanceIdentifier)).To(Equal(restoreFromPointInTimeDBInstanceID))
+					Expect(aws.StringValue(input.Engine)).To(Equal("mysql"))
+					Expect(aws.BoolValue(input.UseLatestRestorableTime)).To(Equal(true))
+				})
+
+				It("sets the right tags", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).ToNot(HaveOccurred())
+
+					input := rdsInstance.RestoreToPointInTimeArgsForCall(0)
+					tagsByName := awsrds.RDSTagsValues(input.Tags)
+					Expect(tagsByName).To(HaveKeyWithValue("Restored From Database", restoreFromPointInTimeDBInstanceID))
+					Expect(tagsByName).To(HaveKeyWithValue("PendingResetUserPassword", "true"))
+					Expect(tagsByName).To(HaveKeyWithValue("PendingUpdateSettings", "true"))
+				})
+			})
+
+			Context("when the engine is 'mariadb'", func() {
+				BeforeEach(func() {
+					rdsProperties1.Engine = stringPointer("mariadb")
+				})
+
+				It("returns the correct error", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).To(MatchError("Restore from point in time not supported for engine 'mariadb'"))
+				})
+			})
+		})
+
+		Context("when adopting an existing DB instance", func() {
+			var (
+				adoptedDBInstance *rds.DBInstance
+				adoptedTags       map[string]string
+			)
+
+			BeforeEach(func() {
+				allowDBInstanceAdoption = true
+				dbInstanceAdoptionPrefixes = map[string][]string{
+					"organization-id": {"legacy-"},
+				}
+				rdsProperties1.Engine = stringPointer("postgres")
+				rdsProperties1.EngineVersion = stringPointer("13.4")
+				provisionDetails.RawParameters = json.RawMessage(`{"adopt_db_instance": "legacy-db"}`)
+
+				adoptedDBInstance = &rds.DBInstance{
+					DBInstanceIdentifier: aws.String("legacy-db"),
+					DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:legacy-db"),
+					Engine:               aws.String("postgres"),
+					EngineVersion:        aws.String("13.7"),
+					AllocatedStorage:     aws.Int64(50),
+					StorageEncrypted:     aws.Bool(false),
+				}
+				adoptedTags = map[string]string{"Owner": "someone"}
+			})
+
+			JustBeforeEach(func() {
+				rdsInstance.DescribeReturns(adoptedDBInstance, nil)
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(adoptedTags), nil)
+			})
+
+			It("tags the instance and renames it with a new master password", func() {
+				provisionedServiceSpec, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(provisionedServiceSpec.IsAsync).To(BeTrue())
+				Expect(provisionedServiceSpec.OperationData).To(Equal("provision"))
+
+				Expect(rdsInstance.CreateCallCount()).To(Equal(0))
+				Expect(rdsInstance.DescribeArgsForCall(0)).To(Equal("legacy-db"))
+
+				Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+				arn, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+				Expect(arn).To(Equal("arn:aws:rds:rds-region:1234567890:db:legacy-db"))
+				tagsByName := awsrds.RDSTagsValues(tags)
+				Expect(tagsByName).To(HaveKeyWithValue("Broker Name", brokerName))
+				Expect(tagsByName).To(HaveKeyWithValue("chargeable_entity", instanceID))
+				Expect(tagsByName).To(HaveKeyWithValue("Plan ID", "Plan-1"))
+				Expect(tagsByName).To(HaveKeyWithValue("Adopted From Database", "legacy-db"))
+				Expect(tagsByName).To(HaveKeyWithValue("Adopted by", "AWS RDS Service Broker"))
+				Expect(tagsByName).To(HaveKey(StateUpdateSettings))
+				Expect(tagsByName).To(HaveKey(StateCreateDatabase))
+				Expect(tagsByName).ToNot(HaveKey(StateResetUserPassword))
+
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
+				input := rdsInstance.ModifyArgsForCall(0)
+				Expect(aws.StringValue(input.DBInstanceIdentifier)).To(Equal("legacy-db"))
+				Expect(aws.StringValue(input.NewDBInstanceIdentifier)).To(Equal(dbInstanceIdentifier))
+				Expect(aws.StringValue(input.MasterUserPassword)).To(Equal(masterUserPassword))
+				Expect(aws.BoolValue(input.ApplyImmediately)).To(BeTrue())
+			})
+
+			Context("when the instance's database is a reserved one", func() {
+				BeforeEach(func() {
+					adoptedDBInstance.DBName = aws.String("postgres")
+				})
+
+				It("returns an error without touching the instance", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).To(MatchError(ContainSubstring("whose database is 'postgres'")))
+					Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(0))
+					Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when adoption is not enabled", func() {
+				BeforeEach(func() {
+					allowDBInstanceAdoption = false
+				})
+
+				It("returns an error without touching the instance", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).To(MatchError(ContainSubstring("not enabled")))
+					Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(0))
+					Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the broker defaults to skipping final snapshots", func() {
+				BeforeEach(func() {
+					skipFinalSnapshotDefault = boolPointer(true)
+				})
+
+				It("tags the instance to skip its final snapshot", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).ToNot(HaveOccurred())
+
+					_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+					Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue("SkipFinalSnapshot", "true"))
+				})
+			})
+
+			Context("when the organization may not adopt the instance", func() {
+				BeforeEach(func() {
+					dbInstanceAdoptionPrefixes = map[string][]string{
+						"organization-id":       {"old-"},
+						"other-organization-id": {"legacy-"},
+					}
+				})
+
+				It("returns an error without touching the instance", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).To(MatchError("Instance legacy-db may not be adopted into this organization"))
+					Expect(rdsInstance.DescribeCallCount()).To(Equal(0))
+					Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(0))
+					Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when also restoring", func() {
+				BeforeEach(func() {
+					provisionDetails.RawParameters = json.RawMessage(`{"adopt_db_instance": "legacy-db", "restore_from_latest_snapshot_of": "abc"}`)
+				})
+
+				It("returns an error", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).To(MatchError(ContainSubstring("Cannot adopt an existing instance and restore")))
+				})
+			})
+
+			Context("when the instance does not exist", func() {
+				JustBeforeEach(func() {
+					rdsInstance.DescribeReturns(nil, awsrds.ErrDBInstanceDoesNotExist)
+				})
+
+				It("returns an error", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).To(MatchError("Cannot find instance legacy-db"))
+				})
+			})
+
+			Context("when the instance is already managed by a broker", func() {
+				BeforeEach(func() {
+					adoptedTags["Broker Name"] = "some-other-broker"
+				})
+
+				It("returns an error", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).To(MatchError(ContainSubstring("already managed by broker 'some-other-broker'")))
+					Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the engine does not match the plan", func() {
+				BeforeEach(func() {
+					adoptedDBInstance.Engine = aws.String("mysql")
+				})
+
+				It("returns an error", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).To(MatchError(ContainSubstring("engine 'mysql'")))
+				})
+			})
+
+			Context("when the major version does not match the plan", func() {
+				BeforeEach(func() {
+					adoptedDBInstance.EngineVersion = aws.String("12.9")
+				})
+
+				It("returns an error", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).To(MatchError(ContainSubstring("engine version '12'")))
+				})
+			})
+
+			Context("when the instance has more storage than the plan", func() {
+				BeforeEach(func() {
+					adoptedDBInstance.AllocatedStorage = aws.Int64(500)
+				})
+
+				It("returns an error", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).To(MatchError(ContainSubstring("500GB of storage")))
+				})
+			})
+
+			Context("when storage encryption does not match the plan", func() {
+				BeforeEach(func() {
+					adoptedDBInstance.StorageEncrypted = aws.Bool(true)
+				})
+
+				It("returns an error", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).To(MatchError(ContainSubstring("storage encryption")))
+				})
 			})
 		})
 
@@ -795,6 +1257,18 @@ var _ = Describe("RDS Broker", func() {
 					})
 				})
 
+				Context("when the snapshot is in a different Kubernetes namespace", func() {
+					BeforeEach(func() {
+						dbSnapshotTags["Kubernetes Namespace"] = "other-namespace"
+						provisionDetails.RawContext = json.RawMessage(`{"platform": "kubernetes", "namespace": "orders"}`)
+					})
+
+					It("should fail to restore", func() {
+						_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+						Expect(err).To(MatchError("The service instance you are getting a snapshot from is not in the same namespace"))
+					})
+				})
+
 				Context("if it is using a different plan", func() {
 
 					BeforeEach(func() {
@@ -864,7 +1338,7 @@ var _ = Describe("RDS Broker", func() {
 						Expect(err).ToNot(HaveOccurred())
 
 						Expect(paramGroupSelector.SelectParameterGroupCallCount()).To(Equal(1))
-						_, extensions := paramGroupSelector.SelectParameterGroupArgsForCall(0)
+						_, extensions, _ := paramGroupSelector.SelectParameterGroupArgsForCall(0)
 						Expect(extensions).To(ContainElement("foo"))
 						Expect(extensions).To(ContainElement("bar"))
 					})
@@ -882,7 +1356,7 @@ var _ = Describe("RDS Broker", func() {
 							Expect(err).ToNot(HaveOccurred())
 
 							Expect(paramGroupSelector.SelectParameterGroupCallCount()).To(Equal(1))
-							_, extensions := paramGroupSelector.SelectParameterGroupArgsForCall(0)
+							_, extensions, _ := paramGroupSelector.SelectParameterGroupArgsForCall(0)
 							Expect(extensions).To(ContainElement("foo"))
 							Expect(extensions).To(ContainElement("bar"))
 							Expect(extensions).To(ContainElement("postgres_super_extension"))
@@ -1004,8 +1478,80 @@ var _ = Describe("RDS Broker", func() {
 			})
 		})
 
-		Context("when creating a new service instance", func() {
-			It("makes the proper calls", func() {
+		Context("when restoring the final snapshot of a previous instance", func() {
+			var dbSnapshotTags map[string]string
+
+			BeforeEach(func() {
+				rdsProperties1.Engine = stringPointer("postgres")
+				provisionDetails.RawParameters = json.RawMessage(`{"restore_previous": true}`)
+				dbSnapshotTags = map[string]string{
+					"Space ID":        "space-id",
+					"Organization ID": "organization-id",
+					"Plan ID":         "Plan-1",
+				}
+				rdsInstance.DescribeSnapshotReturns(&rds.DBSnapshot{
+					DBSnapshotIdentifier: aws.String(dbInstanceIdentifier + "-final-snapshot"),
+					DBSnapshotArn:        aws.String("arn:aws:rds:rds-region:1234567890:snapshot:" + dbInstanceIdentifier + "-final-snapshot"),
+					DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
+				}, nil)
+			})
+
+			JustBeforeEach(func() {
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(dbSnapshotTags), nil)
+			})
+
+			It("restores the final snapshot taken of the instance with the same guid", func() {
+				_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(rdsInstance.DescribeSnapshotCallCount()).To(Equal(1))
+				Expect(rdsInstance.DescribeSnapshotArgsForCall(0)).To(Equal(dbInstanceIdentifier + "-final-snapshot"))
+
+				Expect(rdsInstance.RestoreCallCount()).To(Equal(1))
+				input := rdsInstance.RestoreArgsForCall(0)
+				Expect(aws.StringValue(input.DBInstanceIdentifier)).To(Equal(dbInstanceIdentifier))
+				Expect(aws.StringValue(input.DBSnapshotIdentifier)).To(Equal(dbInstanceIdentifier + "-final-snapshot"))
+				Expect(rdsInstance.CreateCallCount()).To(Equal(0))
+			})
+
+			Context("when the snapshot is in a different space", func() {
+				BeforeEach(func() {
+					dbSnapshotTags["Space ID"] = "different-space-id"
+				})
+
+				It("refuses to restore it", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).To(MatchError("The service instance you are getting a snapshot from is not in the same org or space"))
+					Expect(rdsInstance.RestoreCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when there is no final snapshot", func() {
+				BeforeEach(func() {
+					rdsInstance.DescribeSnapshotReturns(nil, awsrds.ErrDBSnapshotDoesNotExist)
+				})
+
+				It("returns an error", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).To(MatchError(ContainSubstring("No final snapshot found")))
+					Expect(rdsInstance.RestoreCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when restore_from_latest_snapshot_of is also set", func() {
+				BeforeEach(func() {
+					provisionDetails.RawParameters = json.RawMessage(`{"restore_previous": true, "restore_from_latest_snapshot_of": "abc"}`)
+				})
+
+				It("returns an error", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).To(MatchError(ContainSubstring("Cannot use restore_previous along with")))
+				})
+			})
+		})
+
+		Context("when creating a new service instance", func() {
+			It("makes the proper calls", func() {
 				_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
 				Expect(err).ToNot(HaveOccurred())
 
@@ -1050,6 +1596,32 @@ var _ = Describe("RDS Broker", func() {
 				Expect(tagsByName).To(HaveKeyWithValue("chargeable_entity", instanceID))
 			})
 
+			It("does not set a 'Trial Expires' tag", func() {
+				_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+
+				input := rdsInstance.CreateArgsForCall(0)
+				Expect(awsrds.RDSTagsValues(input.Tags)).ToNot(HaveKey("Trial Expires"))
+			})
+
+			Context("when the plan is a trial plan", func() {
+				BeforeEach(func() {
+					plan1TrialDays = 30
+				})
+
+				It("tags the instance with the expiry of the trial", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).ToNot(HaveOccurred())
+
+					input := rdsInstance.CreateArgsForCall(0)
+					tagsByName := awsrds.RDSTagsValues(input.Tags)
+					Expect(tagsByName).To(HaveKey("Trial Expires"))
+					expiresAt, err := time.Parse(time.RFC3339, tagsByName["Trial Expires"])
+					Expect(err).ToNot(HaveOccurred())
+					Expect(expiresAt).To(BeTemporally("~", time.Now().Add(30*24*time.Hour), time.Minute))
+				})
+			})
+
 			It("does not set a 'Restored From Snapshot' tag", func() {
 				_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
 				Expect(err).ToNot(HaveOccurred())
@@ -1104,6 +1676,45 @@ var _ = Describe("RDS Broker", func() {
 						tagsByName := awsrds.RDSTagsValues(input.Tags)
 						Expect(tagsByName).To(HaveKeyWithValue("SkipFinalSnapshot", "true"))
 					})
+
+					Context("when the broker has a default", func() {
+						BeforeEach(func() {
+							skipFinalSnapshotDefault = boolPointer(true)
+						})
+
+						It("sets the tag to the broker's default", func() {
+							_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+							Expect(err).ToNot(HaveOccurred())
+
+							Expect(rdsInstance.CreateCallCount()).To(Equal(1))
+							input := rdsInstance.CreateArgsForCall(0)
+							tagsByName := awsrds.RDSTagsValues(input.Tags)
+							Expect(tagsByName).To(HaveKeyWithValue("SkipFinalSnapshot", "true"))
+						})
+					})
+				})
+
+				Context("with a plan that doesn't allow users to skip the final snapshot", func() {
+					BeforeEach(func() {
+						rdsProperties1.SkipFinalSnapshot = nil
+						plan1AllowUserSkipFinalSnapshot = boolPointer(false)
+					})
+
+					It("refuses to skip it", func() {
+						provisionDetails.RawParameters = json.RawMessage(`{"skip_final_snapshot": true}`)
+
+						_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+						Expect(err).To(MatchError("Service Plan 'Plan 1' does not allow skip_final_snapshot to be set to true"))
+						Expect(rdsInstance.CreateCallCount()).To(Equal(0))
+					})
+
+					It("allows the user to keep it", func() {
+						provisionDetails.RawParameters = json.RawMessage(`{"skip_final_snapshot": false}`)
+
+						_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(rdsInstance.CreateCallCount()).To(Equal(1))
+					})
 				})
 
 				Context("with a plan that specifies SkipFinalSnapshot", func() {
@@ -1324,6 +1935,101 @@ var _ = Describe("RDS Broker", func() {
 				})
 			})
 
+			Context("when network tiers are configured", func() {
+				BeforeEach(func() {
+					rdsProperties1.DBSubnetGroupName = stringPointer("test-db-subnet-group-name")
+					rdsProperties1.VpcSecurityGroupIds = []*string{stringPointer("test-vpc-security-group-ids")}
+					networkTiers = map[string]NetworkTier{
+						"isolated": {
+							DBSubnetGroupName:   "isolated-subnets",
+							VpcSecurityGroupIds: []string{"sg-isolated"},
+						},
+						"partner": {
+							DBSubnetGroupName:   "partner-subnets",
+							VpcSecurityGroupIds: []string{"sg-partner-1", "sg-partner-2"},
+						},
+					}
+				})
+
+				It("uses the plan's network by default", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).ToNot(HaveOccurred())
+					input := rdsInstance.CreateArgsForCall(0)
+					Expect(aws.StringValue(input.DBSubnetGroupName)).To(Equal("test-db-subnet-group-name"))
+					Expect(input.VpcSecurityGroupIds).To(Equal([]*string{stringPointer("test-vpc-security-group-ids")}))
+					Expect(awsrds.RDSTagsValues(input.Tags)).ToNot(HaveKey("Network Tier"))
+				})
+
+				It("uses the tier asked for with the network_tier parameter", func() {
+					provisionDetails.RawParameters = json.RawMessage(`{"network_tier": "partner"}`)
+
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).ToNot(HaveOccurred())
+					input := rdsInstance.CreateArgsForCall(0)
+					Expect(aws.StringValue(input.DBSubnetGroupName)).To(Equal("partner-subnets"))
+					Expect(input.VpcSecurityGroupIds).To(Equal([]*string{stringPointer("sg-partner-1"), stringPointer("sg-partner-2")}))
+					Expect(awsrds.RDSTagsValues(input.Tags)).To(HaveKeyWithValue("Network Tier", "partner"))
+				})
+
+				It("refuses an unknown tier", func() {
+					provisionDetails.RawParameters = json.RawMessage(`{"network_tier": "unknown"}`)
+
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).To(MatchError("Unknown network tier 'unknown'"))
+					Expect(rdsInstance.CreateCallCount()).To(Equal(0))
+				})
+
+				Context("when the organization is mapped to a tier", func() {
+					BeforeEach(func() {
+						organizationNetworkTiers = map[string]string{
+							"organization-id": "isolated",
+						}
+					})
+
+					It("uses the organization's tier", func() {
+						_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+						Expect(err).ToNot(HaveOccurred())
+						input := rdsInstance.CreateArgsForCall(0)
+						Expect(aws.StringValue(input.DBSubnetGroupName)).To(Equal("isolated-subnets"))
+						Expect(input.VpcSecurityGroupIds).To(Equal([]*string{stringPointer("sg-isolated")}))
+						Expect(awsrds.RDSTagsValues(input.Tags)).To(HaveKeyWithValue("Network Tier", "isolated"))
+					})
+
+					It("doesn't let the instance ask for another tier", func() {
+						provisionDetails.RawParameters = json.RawMessage(`{"network_tier": "partner"}`)
+
+						_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+						Expect(err).To(MatchError("Instances in this organization must use network tier 'isolated'"))
+						Expect(rdsInstance.CreateCallCount()).To(Equal(0))
+					})
+				})
+
+				Context("when another organization is mapped to a tier", func() {
+					BeforeEach(func() {
+						organizationNetworkTiers = map[string]string{
+							"other-organization-id": "isolated",
+						}
+					})
+
+					It("doesn't let the instance ask for that tier", func() {
+						provisionDetails.RawParameters = json.RawMessage(`{"network_tier": "isolated"}`)
+
+						_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+						Expect(err).To(MatchError("Network tier 'isolated' is reserved for other organizations"))
+						Expect(rdsInstance.CreateCallCount()).To(Equal(0))
+					})
+
+					It("still lets the instance ask for other tiers", func() {
+						provisionDetails.RawParameters = json.RawMessage(`{"network_tier": "partner"}`)
+
+						_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+						Expect(err).ToNot(HaveOccurred())
+						input := rdsInstance.CreateArgsForCall(0)
+						Expect(aws.StringValue(input.DBSubnetGroupName)).To(Equal("partner-subnets"))
+					})
+				})
+			})
+
 			Context("when has EngineVersion", func() {
 				BeforeEach(func() {
 					rdsProperties1.EngineVersion = stringPointer("1.2.3")
@@ -1492,6 +2198,62 @@ var _ = Describe("RDS Broker", func() {
 				})
 			})
 
+			Context("when the user opts out of automatic minor version upgrades", func() {
+				BeforeEach(func() {
+					rdsProperties1.AutoMinorVersionUpgrade = boolPointer(true)
+					provisionDetails.RawParameters = json.RawMessage(`{"auto_minor_version_upgrade": false}`)
+				})
+
+				It("refuses if the plan doesn't allow opting out", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).To(MatchError("Service Plan 'Plan 1' does not allow auto_minor_version_upgrade to be set to false"))
+					Expect(ErrorCode(err)).To(Equal(ErrCodePlanNotAllowed))
+					Expect(rdsInstance.CreateCallCount()).To(Equal(0))
+				})
+
+				Context("and the plan allows opting out", func() {
+					BeforeEach(func() {
+						plan1AllowAutoMinorOptOut = true
+					})
+
+					It("creates an instance without automatic minor version upgrades and records the choice", func() {
+						_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+						Expect(err).ToNot(HaveOccurred())
+						input := rdsInstance.CreateArgsForCall(0)
+						Expect(input.AutoMinorVersionUpgrade).To(Equal(aws.Bool(false)))
+						Expect(awsrds.RDSTagsValues(input.Tags)).To(HaveKeyWithValue(awsrds.TagAutoMinorUpgrade, "false"))
+					})
+				})
+			})
+
+			Context("when the user asks for a publicly accessible instance", func() {
+				BeforeEach(func() {
+					provisionDetails.RawParameters = json.RawMessage(`{"publicly_accessible": true}`)
+				})
+
+				It("refuses if the plan doesn't allow public access", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).To(MatchError("Service Plan 'Plan 1' does not allow publicly_accessible to be set to true"))
+					Expect(ErrorCode(err)).To(Equal(ErrCodePlanNotAllowed))
+					Expect(rdsInstance.CreateCallCount()).To(Equal(0))
+				})
+
+				Context("and the plan allows public access", func() {
+					BeforeEach(func() {
+						plan1AllowPublicAccess = true
+					})
+
+					It("creates a publicly accessible instance and records the choice", func() {
+						_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(rdsInstance.CreateCallCount()).To(Equal(1))
+						input := rdsInstance.CreateArgsForCall(0)
+						Expect(aws.BoolValue(input.PubliclyAccessible)).To(BeTrue())
+						Expect(awsrds.RDSTagsValues(input.Tags)).To(HaveKeyWithValue(awsrds.TagPubliclyAccessible, "true"))
+					})
+				})
+			})
+
 			Context("when has StorageEncrypted", func() {
 				BeforeEach(func() {
 					rdsProperties1.StorageEncrypted = boolPointer(true)
@@ -1589,9 +2351,24 @@ var _ = Describe("RDS Broker", func() {
 				})
 			})
 
+			Context("when Service Plan is deprecated", func() {
+				BeforeEach(func() {
+					provisionDetails.ServiceID = "Service-3"
+					provisionDetails.PlanID = "Plan-4"
+					plan4Deprecated = true
+				})
+
+				It("returns the proper error", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).To(MatchError("Service Plan 'Plan-4' is deprecated"))
+					Expect(rdsInstance.CreateCallCount()).To(Equal(0))
+				})
+			})
+
 			Context("when creating the DB Instance fails", func() {
 				BeforeEach(func() {
 					rdsInstance.CreateReturns(errors.New("operation failed"))
+					rdsInstance.DescribeReturns(nil, awsrds.ErrDBInstanceDoesNotExist)
 				})
 
 				It("returns the proper error", func() {
@@ -1599,6 +2376,129 @@ var _ = Describe("RDS Broker", func() {
 					Expect(err).To(HaveOccurred())
 					Expect(err.Error()).To(Equal("operation failed"))
 				})
+
+				It("does not delete anything if no DB Instance was created", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).To(HaveOccurred())
+					Expect(rdsInstance.DescribeCallCount()).To(Equal(1))
+					Expect(rdsInstance.DescribeArgsForCall(0)).To(Equal(dbInstanceIdentifier))
+					Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+				})
+
+				Context("but the DB Instance was created anyway", func() {
+					var orphanTags map[string]string
+
+					BeforeEach(func() {
+						rdsInstance.DescribeReturns(&rds.DBInstance{
+							DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
+							DBInstanceArn:        aws.String(dbInstanceArn),
+						}, nil)
+						orphanTags = map[string]string{
+							"Broker Name":       brokerName,
+							"chargeable_entity": instanceID,
+						}
+					})
+
+					JustBeforeEach(func() {
+						rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(orphanTags), nil)
+					})
+
+					It("deletes the orphaned DB Instance without a final snapshot", func() {
+						_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(Equal("operation failed"))
+
+						Expect(rdsInstance.GetResourceTagsCallCount()).To(Equal(1))
+						Expect(rdsInstance.GetResourceTagsArgsForCall(0)).To(Equal(dbInstanceArn))
+						Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+						id, skipFinalSnapshot := rdsInstance.DeleteArgsForCall(0)
+						Expect(id).To(Equal(dbInstanceIdentifier))
+						Expect(skipFinalSnapshot).To(BeTrue())
+					})
+
+					Context("and it belongs to a different broker", func() {
+						BeforeEach(func() {
+							orphanTags["Broker Name"] = "some-other-broker"
+						})
+
+						It("leaves it alone", func() {
+							_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+							Expect(err).To(HaveOccurred())
+							Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+						})
+					})
+
+					Context("and it was adopted rather than created", func() {
+						BeforeEach(func() {
+							orphanTags["Adopted From Database"] = "legacy-db"
+						})
+
+						It("leaves it alone", func() {
+							_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+							Expect(err).To(HaveOccurred())
+							Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+							Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(0))
+						})
+					})
+
+					Context("and it is still being created", func() {
+						BeforeEach(func() {
+							rdsInstance.DescribeReturns(&rds.DBInstance{
+								DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
+								DBInstanceArn:        aws.String(dbInstanceArn),
+								DBInstanceStatus:     aws.String("creating"),
+							}, nil)
+						})
+
+						It("tags it to be deleted by housekeeping", func() {
+							_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+							Expect(err).To(HaveOccurred())
+							Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+
+							Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+							arn, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+							Expect(arn).To(Equal(dbInstanceArn))
+							Expect(awsrds.RDSTagsValues(tags)).To(HaveKey("Orphaned"))
+						})
+
+						It("is deleted by housekeeping once it has been created", func() {
+							rdsInstance.DescribeByTagReturns([]*rds.DBInstance{{
+								DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
+								DBInstanceArn:        aws.String(dbInstanceArn),
+								DBInstanceStatus:     aws.String("creating"),
+							}}, nil)
+							orphanTags["Orphaned"] = time.Now().UTC().Format(time.RFC3339)
+							rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(orphanTags), nil)
+
+							Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+							Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+
+							rdsInstance.DescribeByTagReturns([]*rds.DBInstance{{
+								DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
+								DBInstanceArn:        aws.String(dbInstanceArn),
+								DBInstanceStatus:     aws.String("failed"),
+							}}, nil)
+							Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+							Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+							id, skipFinalSnapshot := rdsInstance.DeleteArgsForCall(0)
+							Expect(id).To(Equal(dbInstanceIdentifier))
+							Expect(skipFinalSnapshot).To(BeTrue())
+						})
+					})
+				})
+
+				Context("because the DB Instance already exists", func() {
+					BeforeEach(func() {
+						rdsInstance.CreateReturns(awsrds.NewError(errors.New("already exists"), awsrds.ErrCodeDBInstanceAlreadyExists))
+					})
+
+					It("does not try to clean it up", func() {
+						_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+						Expect(err).To(HaveOccurred())
+						Expect(rdsInstance.DescribeCallCount()).To(Equal(0))
+						Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+					})
+				})
 			})
 
 			Context("when using a postgres plan", func() {
@@ -1665,14 +2565,65 @@ var _ = Describe("RDS Broker", func() {
 					Expect(err).NotTo(HaveOccurred())
 				})
 
-			})
-		})
+				Context("when an extension isn't available on the plan's postgres version", func() {
+					BeforeEach(func() {
+						rdsProperties3.EngineVersion = stringPointer("10.4")
+						rdsProperties3.AllowedExtensions = append(rdsProperties3.AllowedExtensions, stringPointer("pg_cron"))
+					})
 
-	})
+					It("returns an error", func() {
+						provisionDetails.RawParameters = json.RawMessage(`{"enable_extensions": ["pg_cron"]}`)
 
-	Describe("Deprovision", func() {
-		var (
-			deprovisionDetails           domain.DeprovisionDetails
+						_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+						Expect(err).To(MatchError("pg_cron is not available on postgres 10, it requires postgres 12 or later"))
+						Expect(rdsInstance.CreateCallCount()).To(Equal(0))
+					})
+				})
+
+				It("records the additional databases to create", func() {
+					provisionDetails.RawParameters = json.RawMessage(`{"additional_databases": ["analytics", "reports"]}`)
+
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).ToNot(HaveOccurred())
+
+					input := rdsInstance.CreateArgsForCall(0)
+					Expect(awsrds.RDSTagsValues(input.Tags)).To(HaveKeyWithValue("Additional Databases", "analytics:reports"))
+				})
+
+				It("rejects additional databases with invalid names", func() {
+					provisionDetails.RawParameters = json.RawMessage(`{"additional_databases": ["Analytics-DB"]}`)
+
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).To(MatchError(ContainSubstring("additional_databases: 'Analytics-DB' must start with a lowercase letter")))
+					Expect(rdsInstance.CreateCallCount()).To(Equal(0))
+				})
+
+				It("rejects reserved database names", func() {
+					provisionDetails.RawParameters = json.RawMessage(`{"additional_databases": ["template1"]}`)
+
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).To(MatchError("additional_databases: 'template1' is reserved"))
+				})
+			})
+
+			Context("when asking for additional databases on a plan which isn't postgres", func() {
+				BeforeEach(func() {
+					provisionDetails.RawParameters = json.RawMessage(`{"additional_databases": ["analytics"]}`)
+				})
+
+				It("returns an error", func() {
+					_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, acceptsIncomplete)
+					Expect(err).To(MatchError("additional_databases is only supported for postgres"))
+					Expect(rdsInstance.CreateCallCount()).To(Equal(0))
+				})
+			})
+		})
+
+	})
+
+	Describe("Deprovision", func() {
+		var (
+			deprovisionDetails           domain.DeprovisionDetails
 			acceptsIncomplete            bool
 			properDeprovisionServiceSpec domain.DeprovisionServiceSpec
 		)
@@ -1683,8 +2634,10 @@ var _ = Describe("RDS Broker", func() {
 				PlanID:    "Plan-1",
 			}
 			acceptsIncomplete = true
+			rdsInstance.DescribeDBProxyReturns(&rds.DBProxy{}, nil)
 			properDeprovisionServiceSpec = domain.DeprovisionServiceSpec{
-				IsAsync: true,
+				IsAsync:       true,
+				OperationData: "deprovision",
 			}
 		})
 
@@ -1703,6 +2656,235 @@ var _ = Describe("RDS Broker", func() {
 			Expect(skipFinalSnapshot).To(BeTrue())
 		})
 
+		It("does not delete an RDS Proxy if the instance has none", func() {
+			_, err := rdsBroker.Deprovision(ctx, instanceID, deprovisionDetails, acceptsIncomplete)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rdsInstance.DeleteDBProxyCallCount()).To(Equal(0))
+		})
+
+		Context("when the instance has an RDS Proxy", func() {
+			BeforeEach(func() {
+				rdsInstance.GetTagStub = func(id, tagKey string) (string, error) {
+					if tagKey == "DB Proxy" {
+						return "available", nil
+					}
+					return "", nil
+				}
+			})
+
+			It("deletes the proxy along with the instance", func() {
+				_, err := rdsBroker.Deprovision(ctx, instanceID, deprovisionDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rdsInstance.DeleteDBProxyCallCount()).To(Equal(1))
+				Expect(rdsInstance.DeleteDBProxyArgsForCall(0)).To(Equal(dbInstanceIdentifier))
+				Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+			})
+
+			It("deletes the secrets of the bindings made through the proxy", func() {
+				rdsInstance.DescribeDBProxyReturns(&rds.DBProxy{
+					Auth: []*rds.UserAuthConfigInfo{
+						{SecretArn: aws.String("static-secret-arn")},
+						{SecretArn: aws.String("binding-secret-arn"), Description: aws.String("binding-id")},
+					},
+				}, nil)
+
+				_, err := rdsBroker.Deprovision(ctx, instanceID, deprovisionDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(secretStore.DeleteSecretCallCount()).To(Equal(1))
+				Expect(secretStore.DeleteSecretArgsForCall(0)).To(Equal(dbInstanceIdentifier + "-binding-id"))
+				Expect(rdsInstance.DeleteDBProxyCallCount()).To(Equal(1))
+			})
+
+			It("carries on if the proxy has already gone", func() {
+				rdsInstance.DeleteDBProxyReturns(awsrds.ErrDBProxyDoesNotExist)
+
+				_, err := rdsBroker.Deprovision(ctx, instanceID, deprovisionDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+			})
+
+			It("does not delete the instance if the proxy can't be deleted", func() {
+				rdsInstance.DeleteDBProxyReturns(errors.New("boom"))
+
+				_, err := rdsBroker.Deprovision(ctx, instanceID, deprovisionDetails, acceptsIncomplete)
+				Expect(err).To(MatchError("boom"))
+				Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the instance has a DNS name", func() {
+			BeforeEach(func() {
+				dnsConfig = &DNSConfig{HostedZoneID: "ZONE123", Domain: "db.example.com"}
+				rdsInstance.GetTagStub = func(id, tagKey string) (string, error) {
+					switch tagKey {
+					case "DNS Name":
+						return instanceID + ".db.example.com", nil
+					case "DNS Target":
+						return "endpoint-address", nil
+					}
+					return "", nil
+				}
+			})
+
+			It("deletes the CNAME along with the instance", func() {
+				_, err := rdsBroker.Deprovision(ctx, instanceID, deprovisionDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(dnsZone.DeleteCNAMECallCount()).To(Equal(1))
+				name, target := dnsZone.DeleteCNAMEArgsForCall(0)
+				Expect(name).To(Equal(instanceID + ".db.example.com"))
+				Expect(target).To(Equal("endpoint-address"))
+				Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+			})
+
+			It("does not delete the instance if the CNAME can't be deleted", func() {
+				dnsZone.DeleteCNAMEReturns(errors.New("boom"))
+
+				_, err := rdsBroker.Deprovision(ctx, instanceID, deprovisionDetails, acceptsIncomplete)
+				Expect(err).To(MatchError("boom"))
+				Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when another operation is running against the instance", func() {
+			var concurrentErr error
+
+			BeforeEach(func() {
+				rdsInstance.DeleteStub = func(id string, skipFinalSnapshot bool) error {
+					_, concurrentErr = rdsBroker.Deprovision(ctx, instanceID, deprovisionDetails, acceptsIncomplete)
+					return nil
+				}
+			})
+
+			It("rejects the concurrent operation with a ConcurrencyError", func() {
+				_, err := rdsBroker.Deprovision(ctx, instanceID, deprovisionDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(concurrentErr).To(Equal(apiresponses.ErrConcurrentInstanceAccess))
+				Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+			})
+
+			It("releases the lock once the operation is done", func() {
+				_, err := rdsBroker.Deprovision(ctx, instanceID, deprovisionDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+
+				rdsInstance.DeleteStub = nil
+				_, err = rdsBroker.Deprovision(ctx, instanceID, deprovisionDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rdsInstance.DeleteCallCount()).To(Equal(2))
+			})
+		})
+
+		Context("when an operation lease is configured", func() {
+			var leaseTags map[string]string
+
+			BeforeEach(func() {
+				operationLeaseSeconds = 300
+				leaseTags = map[string]string{"Broker Name": brokerName}
+				rdsInstance.DescribeReturns(&rds.DBInstance{
+					DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
+					DBInstanceArn:        aws.String(dbInstanceArn),
+				}, nil)
+			})
+
+			JustBeforeEach(func() {
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(leaseTags), nil)
+			})
+
+			It("takes and releases a lease tag around the operation", func() {
+				_, err := rdsBroker.Deprovision(ctx, instanceID, deprovisionDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+				arn, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+				Expect(arn).To(Equal(dbInstanceArn))
+				Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue("Operation Lease", MatchRegexp("^mybroker-[a-z0-9]{8} until ")))
+
+				Expect(rdsInstance.RemoveTagCallCount()).To(Equal(1))
+				id, tagKey := rdsInstance.RemoveTagArgsForCall(0)
+				Expect(id).To(Equal(dbInstanceIdentifier))
+				Expect(tagKey).To(Equal("Operation Lease"))
+			})
+
+			Context("and another broker holds an unexpired lease", func() {
+				BeforeEach(func() {
+					leaseTags["Operation Lease"] = "mybroker-abcdefgh until " + time.Now().Add(time.Minute).UTC().Format(time.RFC3339)
+				})
+
+				It("rejects the operation with a ConcurrencyError", func() {
+					_, err := rdsBroker.Deprovision(ctx, instanceID, deprovisionDetails, acceptsIncomplete)
+					Expect(err).To(Equal(apiresponses.ErrConcurrentInstanceAccess))
+					Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+					Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("and another broker's lease has expired", func() {
+				BeforeEach(func() {
+					leaseTags["Operation Lease"] = "mybroker-abcdefgh until " + time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+				})
+
+				It("takes over the lease", func() {
+					_, err := rdsBroker.Deprovision(ctx, instanceID, deprovisionDetails, acceptsIncomplete)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+				})
+			})
+
+			Context("and there is a state store", func() {
+				var stateStore *statestorefake.FakeStateStore
+
+				JustBeforeEach(func() {
+					stateStore = &statestorefake.FakeStateStore{}
+					stateStore.AcquireOperationLeaseReturns(true, nil)
+					rdsBroker.SetStateStore(stateStore)
+				})
+
+				It("takes and releases the lease in the store rather than a tag", func() {
+					_, err := rdsBroker.Deprovision(ctx, instanceID, deprovisionDetails, acceptsIncomplete)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(stateStore.AcquireOperationLeaseCallCount()).To(Equal(1))
+					leaseInstanceID, holder, expiresAt := stateStore.AcquireOperationLeaseArgsForCall(0)
+					Expect(leaseInstanceID).To(Equal(instanceID))
+					Expect(holder).To(MatchRegexp("^mybroker-[a-z0-9]{8}$"))
+					Expect(expiresAt).To(BeTemporally("~", time.Now().Add(300*time.Second), time.Minute))
+
+					Expect(stateStore.ReleaseOperationLeaseCallCount()).To(Equal(1))
+					leaseInstanceID, releasedBy := stateStore.ReleaseOperationLeaseArgsForCall(0)
+					Expect(leaseInstanceID).To(Equal(instanceID))
+					Expect(releasedBy).To(Equal(holder))
+
+					Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(0))
+					Expect(rdsInstance.RemoveTagCallCount()).To(Equal(0))
+				})
+
+				It("rejects the operation with a ConcurrencyError when another broker holds the lease", func() {
+					stateStore.AcquireOperationLeaseReturns(false, nil)
+
+					_, err := rdsBroker.Deprovision(ctx, instanceID, deprovisionDetails, acceptsIncomplete)
+					Expect(err).To(Equal(apiresponses.ErrConcurrentInstanceAccess))
+					Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+					Expect(stateStore.ReleaseOperationLeaseCallCount()).To(Equal(0))
+				})
+			})
+		})
+
+		Context("when the instance is tagged to skip the final snapshot on a plan which doesn't allow it", func() {
+			BeforeEach(func() {
+				rdsProperties1.SkipFinalSnapshot = nil
+				skipFinalSnapshotDefault = boolPointer(false)
+				plan1AllowUserSkipFinalSnapshot = boolPointer(false)
+				rdsInstance.GetTagReturns("true", nil)
+			})
+
+			It("takes a final snapshot", func() {
+				_, err := rdsBroker.Deprovision(ctx, instanceID, deprovisionDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+				_, skipFinalSnapshot := rdsInstance.DeleteArgsForCall(0)
+				Expect(skipFinalSnapshot).To(BeFalse())
+			})
+		})
+
 		Context("when it does not skip final snaphot", func() {
 			BeforeEach(func() {
 				rdsProperties1.SkipFinalSnapshot = boolPointer(false)
@@ -1742,6 +2924,122 @@ var _ = Describe("RDS Broker", func() {
 			})
 		})
 
+		Context("when the DB Instance can't be found by its identifier", func() {
+			var (
+				orphanedDBInstances []*rds.DBInstance
+				orphanTags          map[string]string
+			)
+
+			BeforeEach(func() {
+				rdsInstance.GetTagReturns("", awsrds.ErrDBInstanceDoesNotExist)
+				orphanedDBInstances = []*rds.DBInstance{
+					{
+						DBInstanceIdentifier: aws.String("cf-orphaned-instance"),
+						DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-orphaned-instance"),
+					},
+				}
+				orphanTags = map[string]string{
+					"Broker Name":       brokerName,
+					"chargeable_entity": instanceID,
+					"SkipFinalSnapshot": "false",
+				}
+			})
+
+			JustBeforeEach(func() {
+				rdsInstance.DescribeByTagReturns(orphanedDBInstances, nil)
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(orphanTags), nil)
+			})
+
+			It("deletes DB Instances tagged for the service instance", func() {
+				spec, err := rdsBroker.Deprovision(ctx, instanceID, deprovisionDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(spec).To(Equal(properDeprovisionServiceSpec))
+
+				Expect(rdsInstance.DescribeByTagCallCount()).To(Equal(1))
+				tagKey, tagValue, _ := rdsInstance.DescribeByTagArgsForCall(0)
+				Expect(tagKey).To(Equal("chargeable_entity"))
+				Expect(tagValue).To(Equal(instanceID))
+
+				Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+				id, skipFinalSnapshot := rdsInstance.DeleteArgsForCall(0)
+				Expect(id).To(Equal("cf-orphaned-instance"))
+				Expect(skipFinalSnapshot).To(BeFalse())
+			})
+
+			Context("and the tagged DB Instance belongs to a different broker", func() {
+				BeforeEach(func() {
+					orphanTags["Broker Name"] = "some-other-broker"
+				})
+
+				It("returns that the instance does not exist", func() {
+					_, err := rdsBroker.Deprovision(ctx, instanceID, deprovisionDetails, acceptsIncomplete)
+					Expect(err).To(Equal(apiresponses.ErrInstanceDoesNotExist))
+					Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("and there are no tagged DB Instances", func() {
+				BeforeEach(func() {
+					orphanedDBInstances = []*rds.DBInstance{}
+				})
+
+				It("returns that the instance does not exist", func() {
+					_, err := rdsBroker.Deprovision(ctx, instanceID, deprovisionDetails, acceptsIncomplete)
+					Expect(err).To(Equal(apiresponses.ErrInstanceDoesNotExist))
+					Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("and the tagged DB Instance has been soft deleted", func() {
+				BeforeEach(func() {
+					orphanTags["Purge After"] = time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+				})
+
+				It("leaves it for the housekeeping cron to purge", func() {
+					_, err := rdsBroker.Deprovision(ctx, instanceID, deprovisionDetails, acceptsIncomplete)
+					Expect(err).To(Equal(apiresponses.ErrInstanceDoesNotExist))
+					Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+				})
+			})
+		})
+
+		Context("when soft delete is enabled", func() {
+			BeforeEach(func() {
+				softDeleteDays = 7
+				rdsInstance.DescribeReturns(&rds.DBInstance{
+					DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
+					DBInstanceArn:        aws.String(dbInstanceArn),
+				}, nil)
+			})
+
+			It("renames and tags the DB Instance instead of deleting it", func() {
+				spec, err := rdsBroker.Deprovision(ctx, instanceID, deprovisionDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(spec).To(Equal(properDeprovisionServiceSpec))
+				Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+
+				Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+				arn, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+				Expect(arn).To(Equal(dbInstanceArn))
+				purgeAfter, err := time.Parse(time.RFC3339, awsrds.RDSTagsValues(tags)["Purge After"])
+				Expect(err).ToNot(HaveOccurred())
+				Expect(purgeAfter).To(BeTemporally("~", time.Now().Add(7*24*time.Hour), time.Minute))
+
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
+				input := rdsInstance.ModifyArgsForCall(0)
+				Expect(aws.StringValue(input.DBInstanceIdentifier)).To(Equal(dbInstanceIdentifier))
+				Expect(aws.StringValue(input.NewDBInstanceIdentifier)).To(Equal(dbInstanceIdentifier + "-deleted"))
+				Expect(aws.BoolValue(input.ApplyImmediately)).To(BeTrue())
+			})
+
+			It("returns that the instance does not exist if it has gone", func() {
+				rdsInstance.DescribeReturns(nil, awsrds.ErrDBInstanceDoesNotExist)
+
+				_, err := rdsBroker.Deprovision(ctx, instanceID, deprovisionDetails, acceptsIncomplete)
+				Expect(err).To(Equal(apiresponses.ErrInstanceDoesNotExist))
+			})
+		})
+
 		Context("when deleting the DB Instance fails", func() {
 			BeforeEach(func() {
 				rdsInstance.DeleteReturns(errors.New("operation failed"))
@@ -1773,6 +3071,13 @@ var _ = Describe("RDS Broker", func() {
 		)
 
 		BeforeEach(func() {
+			sqlEngine.URICalls(func(address string, port int64, dbname string, username string, password string) string {
+				return fmt.Sprintf("fake://%s:%s@%s:%d/%s?reconnect=true", username, password, address, port, dbname)
+			})
+			sqlEngine.JDBCURICalls(func(address string, port int64, dbname string, username string, password string) string {
+				return fmt.Sprintf("jdbc:fake://%s:%d/%s?user=%s&password=%s", address, port, dbname, username, password)
+			})
+
 			bindDetails = domain.BindDetails{
 				ServiceID:     "Service-1",
 				PlanID:        "Plan-1",
@@ -1790,8 +3095,7 @@ var _ = Describe("RDS Broker", func() {
 				MasterUsername: aws.String("master-username"),
 			}, nil)
 
-			sqlEngine.CreateUserUsername = dbUsername
-			sqlEngine.CreateUserPassword = "secret"
+			sqlEngine.CreateUserReturns(dbUsername, "secret", nil)
 		})
 
 		It("returns the proper response", func() {
@@ -1816,41 +3120,104 @@ var _ = Describe("RDS Broker", func() {
 			id := rdsInstance.DescribeArgsForCall(0)
 			Expect(id).To(Equal(dbInstanceIdentifier))
 
-			Expect(sqlProvider.GetSQLEngineCalled).To(BeTrue())
-			Expect(sqlProvider.GetSQLEngineEngine).To(Equal("test-engine-one"))
-			Expect(sqlEngine.OpenCalled).To(BeTrue())
-			Expect(sqlEngine.OpenAddress).To(Equal("endpoint-address"))
-			Expect(sqlEngine.OpenPort).To(Equal(int64(3306)))
-			Expect(sqlEngine.OpenDBName).To(Equal("test-db"))
-			Expect(sqlEngine.OpenUsername).To(Equal("master-username"))
-			Expect(sqlEngine.OpenPassword).ToNot(BeEmpty())
-			Expect(sqlEngine.CreateUserCalled).To(BeTrue())
-			Expect(sqlEngine.CreateUserBindingID).To(Equal(bindingID))
-			Expect(sqlEngine.CreateUserDBName).To(Equal("test-db"))
-			Expect(sqlEngine.CreateUserReadOnly).To(Equal(false))
-			Expect(sqlEngine.CloseCalled).To(BeTrue())
+			Expect(sqlProvider.GetSQLEngineCallCount()).ToNot(BeZero())
+			Expect(sqlProvider.GetSQLEngineArgsForCall(0)).To(Equal("test-engine-one"))
+			Expect(sqlEngine.OpenCallCount()).ToNot(BeZero())
+			address, port, dbname, username, password := sqlEngine.OpenArgsForCall(0)
+			Expect(address).To(Equal("endpoint-address"))
+			Expect(port).To(Equal(int64(3306)))
+			Expect(dbname).To(Equal("test-db"))
+			Expect(username).To(Equal("master-username"))
+			Expect(password).ToNot(BeEmpty())
+			Expect(sqlEngine.CreateUserCallCount()).ToNot(BeZero())
+			userBindingID, userDBName, readOnly := sqlEngine.CreateUserArgsForCall(0)
+			Expect(userBindingID).To(Equal(bindingID))
+			Expect(userDBName).To(Equal("test-db"))
+			Expect(readOnly).To(Equal(false))
+			Expect(sqlEngine.CloseCallCount()).ToNot(BeZero())
 		})
 
-		It("brokerapi integration returns the proper response", func() {
-			recorder := httptest.NewRecorder()
+		It("records the binding in a tag of the instance", func() {
+			bindDetails.BindResource = &domain.BindResource{AppGuid: "Application-2"}
 
-			bindingDetailsJson := []byte(`
-	{
-	"service_id": "Service-1",
-	"plan_id": "Plan-1",
-	"bind_resource": {
-	"app_guid": "Application-1"
-	},
-	"parameters": {}
-	}`)
+			_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+			Expect(err).ToNot(HaveOccurred())
 
-			req, _ := http.NewRequest(
-				"PUT",
-				"http://example.com/v2/service_instances/"+
-					instanceID+
-					"/service_bindings/"+
-					bindingID,
-				bytes.NewBuffer(bindingDetailsJson),
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+			_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+			tagsByName := awsrds.RDSTagsValues(tags)
+			Expect(tagsByName).To(HaveKey("Binding " + bindingID))
+			Expect(tagsByName["Binding "+bindingID]).To(MatchRegexp(`^app=Application-2 created=\S+Z read_only=false$`))
+		})
+
+		It("records the binding in the state store when there is one", func() {
+			stateStore := &statestorefake.FakeStateStore{}
+			rdsBroker.SetStateStore(stateStore)
+
+			_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(0))
+			Expect(stateStore.RecordBindingCallCount()).To(Equal(1))
+			binding := stateStore.RecordBindingArgsForCall(0)
+			Expect(binding.InstanceID).To(Equal(instanceID))
+			Expect(binding.BindingID).To(Equal(bindingID))
+			Expect(binding.AppGUID).To(Equal("Application-1"))
+			Expect(binding.CreatedAt).To(BeTemporally("~", time.Now(), time.Minute))
+			Expect(binding.ReadOnly).To(BeFalse())
+		})
+
+		It("doesn't record more bindings in tags once the instance has ten", func() {
+			tagsByName := map[string]string{}
+			for i := 0; i < 10; i++ {
+				tagsByName[fmt.Sprintf("Binding binding-%d", i)] = "app= created=2024-01-01T00:00:00Z read_only=false"
+			}
+			rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(tagsByName), nil)
+
+			_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(0))
+		})
+
+		It("doesn't record the binding in a tag when the instance's tags leave no room for the broker's own", func() {
+			tagsByName := map[string]string{}
+			for i := 0; i < 14; i++ {
+				tagsByName[fmt.Sprintf("Tag %d", i)] = "value"
+			}
+			rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(tagsByName), nil)
+
+			_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(0))
+		})
+
+		It("still binds if the binding can't be recorded", func() {
+			rdsInstance.AddTagsToResourceReturns(errors.New("too many tags"))
+
+			_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("brokerapi integration returns the proper response", func() {
+			recorder := httptest.NewRecorder()
+
+			bindingDetailsJson := []byte(`
+	{
+	"service_id": "Service-1",
+	"plan_id": "Plan-1",
+	"bind_resource": {
+	"app_guid": "Application-1"
+	},
+	"parameters": {}
+	}`)
+
+			req, _ := http.NewRequest(
+				"PUT",
+				"http://example.com/v2/service_instances/"+
+					instanceID+
+					"/service_bindings/"+
+					bindingID,
+				bytes.NewBuffer(bindingDetailsJson),
 			)
 			req.Header.Set("X-Broker-API-Version", "2.14")
 			req.SetBasicAuth(brokeruser, brokerpass)
@@ -1945,7 +3312,8 @@ var _ = Describe("RDS Broker", func() {
 						_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
 						Expect(err).ToNot(HaveOccurred())
 
-						Expect(sqlEngine.CreateUserReadOnly).To(Equal(true))
+						_, _, readOnly := sqlEngine.CreateUserArgsForCall(0)
+						Expect(readOnly).To(Equal(true))
 					})
 				})
 
@@ -1958,221 +3326,870 @@ var _ = Describe("RDS Broker", func() {
 			})
 		})
 
-		Context("when Parameters are not valid", func() {
-
-			It("returns the proper error", func() {
-				bindDetails.RawParameters = json.RawMessage(`not JSON`)
-				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
-				Expect(err).To(HaveOccurred())
-				Expect(sqlProvider.GetSQLEngineCalled).To(BeFalse())
+		Context("when binding through the connection pool", func() {
+			BeforeEach(func() {
+				bindDetails.RawParameters = json.RawMessage(`{"use_connection_pool": true}`)
 			})
 
-			Context("and user bind parameters are not allowed", func() {
+			Context("when the plan has a connection pool", func() {
 				BeforeEach(func() {
-					allowUserBindParameters = false
+					connectionPool = &ConnectionPool{
+						Host: "pgbouncer.example.com",
+						Port: 6432,
+					}
 				})
 
-				It("does not return an error", func() {
-					bindDetails.RawParameters = json.RawMessage(`not JSON`)
+				It("creates the user on the DB instance", func() {
 					_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
 					Expect(err).ToNot(HaveOccurred())
+
+					address, port, _, _, _ := sqlEngine.OpenArgsForCall(0)
+					Expect(address).To(Equal("endpoint-address"))
+					Expect(port).To(Equal(int64(3306)))
+					Expect(sqlEngine.CreateUserCallCount()).ToNot(BeZero())
+				})
+
+				It("returns the connection pool's address in the credentials", func() {
+					bindingResponse, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+					Expect(err).ToNot(HaveOccurred())
+
+					credentials := bindingResponse.Credentials.(Credentials)
+					Expect(credentials.Host).To(Equal("pgbouncer.example.com"))
+					Expect(credentials.Port).To(Equal(int64(6432)))
+					Expect(credentials.URI).To(ContainSubstring("@pgbouncer.example.com:6432/test-db"))
+					Expect(credentials.JDBCURI).To(ContainSubstring("jdbc:fake://pgbouncer.example.com:6432/test-db"))
 				})
 			})
 
-			It("returns an error for extra params", func() {
-				bindDetails.RawParameters = json.RawMessage(`{"foo": "bar"}`)
+			It("returns an error if the plan has no connection pool", func() {
 				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
-				Expect(err).To(MatchError(ContainSubstring(`unknown field "foo"`)))
-				Expect(sqlProvider.GetSQLEngineCalled).To(BeFalse())
+				Expect(err).To(MatchError("Service Plan 'Plan 1' has no connection pool"))
+				Expect(sqlEngine.CreateUserCallCount()).To(BeZero())
 			})
 		})
 
-		Context("when Service is not found", func() {
+		It("does not check the new user can connect by default", func() {
+			_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sqlEngine.CheckConnectionCallCount()).To(BeZero())
+		})
+
+		Context("when checking binding connections", func() {
 			BeforeEach(func() {
-				bindDetails.ServiceID = "unknown"
+				checkBindingConnections = true
 			})
 
-			It("returns the proper error", func() {
+			It("connects as the new user and checks the connection", func() {
 				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal("Service 'unknown' not found"))
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(sqlEngine.CheckConnectionCallCount()).ToNot(BeZero())
+				_, _, _, username, password := sqlEngine.OpenArgsForCall(sqlEngine.OpenCallCount() - 1)
+				Expect(username).To(Equal(dbUsername))
+				Expect(password).To(Equal("secret"))
+				Expect(sqlEngine.DropUserCallCount()).To(BeZero())
+			})
+
+			It("fails the binding and drops the user if the connection can't be used", func() {
+				sqlEngine.CheckConnectionReturns(errors.New("permission denied for database"))
+
+				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).To(MatchError("The new user could not connect to the database: permission denied for database"))
+
+				Expect(sqlEngine.DropUserCallCount()).ToNot(BeZero())
+				Expect(sqlEngine.DropUserArgsForCall(0)).To(Equal(bindingID))
 			})
 		})
 
-		Context("when Service Plan is not found", func() {
+		It("leaves out the servicebinding.io entries by default", func() {
+			bindingResponse, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			credentialsJSON, err := json.Marshal(bindingResponse.Credentials)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(credentialsJSON).ToNot(ContainSubstring(`"type"`))
+			Expect(credentialsJSON).ToNot(ContainSubstring(`"hostname"`))
+		})
+
+		Context("when the binding comes from Kubernetes", func() {
 			BeforeEach(func() {
-				bindDetails.PlanID = "unknown"
+				bindDetails.RawContext = json.RawMessage(`{"platform": "kubernetes", "namespace": "orders"}`)
 			})
 
-			It("returns the proper error", func() {
-				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal("Service Plan 'unknown' not found"))
+			It("uses the servicebinding.io layout", func() {
+				bindingResponse, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).ToNot(HaveOccurred())
+
+				credentials := bindingResponse.Credentials.(Credentials)
+				Expect(credentials.Type).To(Equal("test-engine-one"))
+				Expect(credentials.Hostname).To(Equal("endpoint-address"))
+				Expect(credentials.Database).To(Equal("test-db"))
 			})
 		})
 
-		Context("when describing the DB Instance fails", func() {
+		Context("when asking for the servicebinding.io layout", func() {
 			BeforeEach(func() {
-				rdsInstance.DescribeReturns(nil, errors.New("operation failed"))
+				bindDetails.RawParameters = json.RawMessage(`{"service_binding_layout": true}`)
 			})
 
-			It("returns the proper error", func() {
-				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal("operation failed"))
-			})
+			It("adds the well-known entries to the credentials", func() {
+				bindingResponse, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).ToNot(HaveOccurred())
 
-			Context("when the DB Instance does not exists", func() {
+				var credentials map[string]interface{}
+				credentialsJSON, err := json.Marshal(bindingResponse.Credentials)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(json.Unmarshal(credentialsJSON, &credentials)).To(Succeed())
+				Expect(credentials).To(HaveKeyWithValue("type", "test-engine-one"))
+				Expect(credentials).To(HaveKeyWithValue("provider", "aws-rds"))
+				Expect(credentials).To(HaveKeyWithValue("hostname", "endpoint-address"))
+				Expect(credentials).To(HaveKeyWithValue("host", "endpoint-address"))
+				Expect(credentials).To(HaveKeyWithValue("port", BeNumerically("==", 3306)))
+				Expect(credentials).To(HaveKeyWithValue("database", "test-db"))
+				Expect(credentials).To(HaveKeyWithValue("username", dbUsername))
+				Expect(credentials).To(HaveKeyWithValue("password", "secret"))
+				Expect(credentials).To(HaveKey("uri"))
+			})
+
+			Context("and binding through the connection pool", func() {
 				BeforeEach(func() {
-					rdsInstance.DescribeReturns(nil, awsrds.ErrDBInstanceDoesNotExist)
+					bindDetails.RawParameters = json.RawMessage(`{"service_binding_layout": true, "use_connection_pool": true}`)
+					connectionPool = &ConnectionPool{
+						Host: "pgbouncer.example.com",
+						Port: 6432,
+					}
 				})
 
-				It("returns the proper error", func() {
-					_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
-					Expect(err).To(HaveOccurred())
-					Expect(err).To(Equal(apiresponses.ErrInstanceDoesNotExist))
+				It("gives the pool's address as the hostname", func() {
+					bindingResponse, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+					Expect(err).ToNot(HaveOccurred())
+
+					credentials := bindingResponse.Credentials.(Credentials)
+					Expect(credentials.Hostname).To(Equal("pgbouncer.example.com"))
 				})
 			})
 		})
 
-		Context("when getting the SQL Engine fails", func() {
+		It("does not return the network if RDS doesn't describe the subnet group", func() {
+			bindingResponse, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			credentials := bindingResponse.Credentials.(Credentials)
+			Expect(credentials.Network).To(BeNil())
+		})
+
+		Context("when RDS describes the instance's network", func() {
 			BeforeEach(func() {
-				sqlProvider.GetSQLEngineError = errors.New("Engine 'unknown' not supported")
+				rdsInstance.DescribeReturns(&rds.DBInstance{
+					DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
+					Endpoint: &rds.Endpoint{
+						Address: aws.String("endpoint-address"),
+						Port:    aws.Int64(3306),
+					},
+					DBName:         aws.String("test-db"),
+					MasterUsername: aws.String("master-username"),
+					DBSubnetGroup: &rds.DBSubnetGroup{
+						VpcId: aws.String("vpc-1"),
+						Subnets: []*rds.Subnet{
+							{SubnetIdentifier: aws.String("subnet-1")},
+							{SubnetIdentifier: aws.String("subnet-2")},
+						},
+					},
+					VpcSecurityGroups: []*rds.VpcSecurityGroupMembership{
+						{VpcSecurityGroupId: aws.String("sg-1")},
+					},
+				}, nil)
 			})
 
-			It("returns the proper error", func() {
-				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal("Engine 'unknown' not supported"))
+			It("returns the VPC, subnets and security groups in the credentials", func() {
+				bindingResponse, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).ToNot(HaveOccurred())
+
+				credentials := bindingResponse.Credentials.(Credentials)
+				Expect(credentials.Network).To(Equal(&Network{
+					VpcID:            "vpc-1",
+					SubnetIDs:        []string{"subnet-1", "subnet-2"},
+					SubnetCIDRBlocks: []string{},
+					SecurityGroupIDs: []string{"sg-1"},
+				}))
+			})
+
+			Context("and the broker knows the CIDR blocks of the subnets", func() {
+				BeforeEach(func() {
+					subnetCIDRBlocks = map[string]string{
+						"subnet-1": "10.0.1.0/24",
+						"subnet-3": "10.0.3.0/24",
+					}
+				})
+
+				It("returns the CIDR blocks of the instance's subnets it knows", func() {
+					bindingResponse, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+					Expect(err).ToNot(HaveOccurred())
+
+					credentials := bindingResponse.Credentials.(Credentials)
+					Expect(credentials.Network.SubnetCIDRBlocks).To(Equal([]string{"10.0.1.0/24"}))
+				})
 			})
 		})
 
-		Context("when opening a DB connection fails", func() {
+		Context("when the master password is being changed", func() {
 			BeforeEach(func() {
-				sqlEngine.OpenError = errors.New("Failed to open sqlEngine")
+				rdsInstance.DescribeReturns(&rds.DBInstance{
+					DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
+					DBInstanceStatus:     aws.String("available"),
+					Endpoint: &rds.Endpoint{
+						Address: aws.String("endpoint-address"),
+						Port:    aws.Int64(3306),
+					},
+					DBName:         aws.String("test-db"),
+					MasterUsername: aws.String("master-username"),
+					PendingModifiedValues: &rds.PendingModifiedValues{
+						MasterUserPassword: aws.String("****"),
+					},
+				}, nil)
 			})
 
-			It("returns the proper error", func() {
+			It("asks for the binding to be retried", func() {
 				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal("Failed to open sqlEngine"))
+				Expect(err).To(Equal(apiresponses.ErrConcurrentInstanceAccess))
+				Expect(sqlEngine.OpenCallCount()).To(BeZero())
+				Expect(sqlEngine.CreateUserCallCount()).To(BeZero())
 			})
 		})
 
-		Context("when creating a DB user fails", func() {
+		Context("when RDS is resetting the master credentials", func() {
 			BeforeEach(func() {
-				sqlEngine.CreateUserError = errors.New("Failed to create user")
+				rdsInstance.DescribeReturns(&rds.DBInstance{
+					DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
+					DBInstanceStatus:     aws.String("resetting-master-credentials"),
+				}, nil)
 			})
 
-			It("returns the proper error", func() {
+			It("asks for the binding to be retried", func() {
 				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal("Failed to create user"))
-				Expect(sqlEngine.CloseCalled).To(BeTrue())
+				Expect(err).To(Equal(apiresponses.ErrConcurrentInstanceAccess))
+				Expect(sqlEngine.OpenCallCount()).To(BeZero())
 			})
 		})
-	})
-
-	Describe("Unbind", func() {
-		var (
-			unbindDetails domain.UnbindDetails
-		)
 
-		BeforeEach(func() {
-			unbindDetails = domain.UnbindDetails{
-				ServiceID: "Service-1",
-				PlanID:    "Plan-1",
-			}
+		Context("when a DNS zone is configured", func() {
+			BeforeEach(func() {
+				dnsConfig = &DNSConfig{HostedZoneID: "ZONE123", Domain: "db.example.com"}
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+					"DNS Name": instanceID + ".db.example.com",
+				}), nil)
+			})
 
-			rdsInstance.DescribeReturns(&rds.DBInstance{
-				DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
-				Endpoint: &rds.Endpoint{
-					Address: aws.String("endpoint-address"),
-					Port:    aws.Int64(3306),
-				},
-				DBName:         aws.String("test-db"),
-				MasterUsername: aws.String("master-username"),
-				Engine:         aws.String("test-engine-one"),
-			}, nil)
-		})
+			It("returns the instance's DNS name in the credentials", func() {
+				bindingResponse, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).ToNot(HaveOccurred())
 
-		It("makes the proper calls", func() {
-			spec, err := rdsBroker.Unbind(ctx, instanceID, bindingID, unbindDetails, false)
+				address, _, _, _, _ := sqlEngine.OpenArgsForCall(0)
+				Expect(address).To(Equal("endpoint-address"))
 
-			Expect(rdsInstance.DescribeCallCount()).To(Equal(1))
-			Expect(err).ToNot(HaveOccurred())
-			id := rdsInstance.DescribeArgsForCall(0)
-			Expect(id).To(Equal(dbInstanceIdentifier))
-			Expect(spec.OperationData).To(Equal(""))
+				credentials := bindingResponse.Credentials.(Credentials)
+				Expect(credentials.Host).To(Equal(instanceID + ".db.example.com"))
+				Expect(credentials.URI).To(ContainSubstring("@" + instanceID + ".db.example.com:3306/test-db"))
+			})
 
-			Expect(sqlProvider.GetSQLEngineCalled).To(BeTrue())
-			Expect(sqlProvider.GetSQLEngineEngine).To(Equal("test-engine-one"))
-			Expect(sqlEngine.OpenCalled).To(BeTrue())
-			Expect(sqlEngine.OpenAddress).To(Equal("endpoint-address"))
-			Expect(sqlEngine.OpenPort).To(Equal(int64(3306)))
-			Expect(sqlEngine.OpenDBName).To(Equal("test-db"))
-			Expect(sqlEngine.OpenUsername).To(Equal("master-username"))
-			Expect(sqlEngine.OpenPassword).ToNot(BeEmpty())
-			Expect(sqlEngine.DropUserCalled).To(BeTrue())
-			Expect(sqlEngine.DropUserBindingID).To(Equal(bindingID))
-			Expect(sqlEngine.CloseCalled).To(BeTrue())
-		})
+			It("returns the endpoint if the DNS name hasn't been created yet", func() {
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{}), nil)
 
-		Context("when Service Plan is not found", func() {
-			BeforeEach(func() {
-				unbindDetails.PlanID = "unknown"
-			})
+				bindingResponse, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).ToNot(HaveOccurred())
 
-			It("returns the proper error", func() {
-				spec, err := rdsBroker.Unbind(ctx, instanceID, bindingID, unbindDetails, false)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal("Service Plan 'unknown' not found"))
-				Expect(spec.OperationData).To(Equal(""))
+				credentials := bindingResponse.Credentials.(Credentials)
+				Expect(credentials.Host).To(Equal("endpoint-address"))
 			})
 		})
 
-		Context("when describing the DB Instance fails", func() {
+		Context("when binding through the RDS Proxy", func() {
 			BeforeEach(func() {
-				rdsInstance.DescribeReturns(nil, errors.New("operation failed"))
-			})
-
-			It("returns the proper error", func() {
-				spec, err := rdsBroker.Unbind(ctx, instanceID, bindingID, unbindDetails, false)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal("operation failed"))
-				Expect(spec.OperationData).To(Equal(""))
+				bindDetails.RawParameters = json.RawMessage(`{"use_rds_proxy": true}`)
+				rdsInstance.DescribeDBProxyReturns(&rds.DBProxy{
+					DBProxyName: aws.String(dbInstanceIdentifier),
+					Endpoint:    aws.String("proxy-endpoint"),
+					Status:      aws.String("available"),
+				}, nil)
 			})
 
-			Context("when the DB Instance does not exists", func() {
+			Context("when the proxy is available", func() {
 				BeforeEach(func() {
-					rdsInstance.DescribeReturns(nil, awsrds.ErrDBInstanceDoesNotExist)
+					rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+						"DB Proxy": "available",
+					}), nil)
 				})
 
-				It("returns the proper error", func() {
-					spec, err := rdsBroker.Unbind(ctx, instanceID, bindingID, unbindDetails, false)
-					Expect(err).To(HaveOccurred())
-					Expect(err).To(Equal(apiresponses.ErrInstanceDoesNotExist))
-					Expect(spec.OperationData).To(Equal(""))
+				It("returns the proxy's endpoint in the credentials", func() {
+					bindingResponse, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+					Expect(err).ToNot(HaveOccurred())
+
+					address, _, _, _, _ := sqlEngine.OpenArgsForCall(0)
+					Expect(address).To(Equal("endpoint-address"))
+					Expect(sqlEngine.CreateUserCallCount()).ToNot(BeZero())
+					Expect(rdsInstance.DescribeDBProxyArgsForCall(0)).To(Equal(dbInstanceIdentifier))
+
+					credentials := bindingResponse.Credentials.(Credentials)
+					Expect(credentials.Host).To(Equal("proxy-endpoint"))
+					Expect(credentials.Port).To(Equal(int64(3306)))
+					Expect(credentials.URI).To(ContainSubstring("@proxy-endpoint:3306/test-db"))
+				})
+
+				It("lets the new user through the proxy", func() {
+					rdsInstance.DescribeDBProxyReturns(&rds.DBProxy{
+						DBProxyName: aws.String(dbInstanceIdentifier),
+						Endpoint:    aws.String("proxy-endpoint"),
+						Status:      aws.String("available"),
+						Auth: []*rds.UserAuthConfigInfo{{
+							AuthScheme: aws.String("SECRETS"),
+							IAMAuth:    aws.String("DISABLED"),
+							SecretArn:  aws.String("static-secret-arn"),
+						}},
+					}, nil)
+					secretStore.CreateDBCredentialsReturns("binding-secret-arn", nil)
+
+					_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(secretStore.CreateDBCredentialsCallCount()).To(Equal(1))
+					name, username, password, _ := secretStore.CreateDBCredentialsArgsForCall(0)
+					Expect(name).To(Equal(dbInstanceIdentifier + "-" + bindingID))
+					Expect(username).To(Equal(dbUsername))
+					Expect(password).To(Equal("secret"))
+
+					Expect(rdsInstance.SetDBProxyAuthCallCount()).To(Equal(1))
+					proxyName, auth := rdsInstance.SetDBProxyAuthArgsForCall(0)
+					Expect(proxyName).To(Equal(dbInstanceIdentifier))
+					Expect(auth).To(HaveLen(2))
+					Expect(aws.StringValue(auth[0].SecretArn)).To(Equal("static-secret-arn"))
+					Expect(aws.StringValue(auth[1].SecretArn)).To(Equal("binding-secret-arn"))
+					Expect(aws.StringValue(auth[1].Description)).To(Equal(bindingID))
+				})
+
+				It("drops the user and deletes its secret if the proxy can't be changed", func() {
+					rdsInstance.SetDBProxyAuthReturns(errors.New("operation failed"))
+
+					_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+					Expect(err).To(MatchError("operation failed"))
+					Expect(sqlEngine.DropUserCallCount()).ToNot(BeZero())
+					Expect(secretStore.DeleteSecretCallCount()).To(Equal(1))
+					Expect(secretStore.DeleteSecretArgsForCall(0)).To(Equal(dbInstanceIdentifier + "-" + bindingID))
+				})
+
+				It("returns an error if the broker has no secret store", func() {
+					rdsBroker.SetSecretStore(nil)
+
+					_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+					Expect(err).To(MatchError("This broker can't make bindings through an RDS Proxy"))
+					Expect(sqlEngine.CreateUserCallCount()).To(BeZero())
 				})
 			})
-		})
 
-		Context("when getting the SQL Engine fails", func() {
-			BeforeEach(func() {
-				sqlProvider.GetSQLEngineError = errors.New("SQL Engine 'unknown' not supported")
+			It("returns an error if the instance has no proxy ready", func() {
+				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).To(MatchError("DB Instance '" + dbInstanceIdentifier + "' has no RDS Proxy ready to use"))
+				Expect(sqlEngine.CreateUserCallCount()).To(BeZero())
 			})
 
-			It("returns the proper error", func() {
-				spec, err := rdsBroker.Unbind(ctx, instanceID, bindingID, unbindDetails, false)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal("SQL Engine 'unknown' not supported"))
-				Expect(spec.OperationData).To(Equal(""))
+			It("can't be combined with the connection pool", func() {
+				bindDetails.RawParameters = json.RawMessage(`{"use_rds_proxy": true, "use_connection_pool": true}`)
+
+				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).To(MatchError("use_connection_pool and use_rds_proxy can't be combined"))
+			})
+		})
+
+		Context("when binding to one of the instance's additional databases", func() {
+			BeforeEach(func() {
+				bindDetails.RawParameters = json.RawMessage(`{"database": "analytics"}`)
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+					"Additional Databases": "analytics:reports",
+				}), nil)
+			})
+
+			It("creates the user in that database and returns it in the credentials", func() {
+				bindingResponse, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).ToNot(HaveOccurred())
+				_, _, dbname, _, _ := sqlEngine.OpenArgsForCall(0)
+				Expect(dbname).To(Equal("analytics"))
+				_, createUserDBName, _ := sqlEngine.CreateUserArgsForCall(0)
+				Expect(createUserDBName).To(Equal("analytics"))
+
+				credentials := bindingResponse.Credentials.(Credentials)
+				Expect(credentials.Name).To(Equal("analytics"))
+				Expect(credentials.URI).To(ContainSubstring("@endpoint-address:3306/analytics"))
+			})
+
+			It("accepts the main database", func() {
+				bindDetails.RawParameters = json.RawMessage(`{"database": "test-db"}`)
+
+				bindingResponse, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(bindingResponse.Credentials.(Credentials).Name).To(Equal("test-db"))
+			})
+
+			It("returns an error for a database the instance doesn't have", func() {
+				bindDetails.RawParameters = json.RawMessage(`{"database": "unknown"}`)
+
+				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).To(MatchError("Database 'unknown' is not one of the instance's databases"))
+				Expect(sqlEngine.CreateUserCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when the binding has a ttl_hours", func() {
+			BeforeEach(func() {
+				bindDetails.RawParameters = json.RawMessage(`{"ttl_hours": 2}`)
+			})
+
+			It("sets the user to expire and returns when in the credentials", func() {
+				bindingResponse, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sqlEngine.SetUserExpiryCallCount()).ToNot(BeZero())
+				userBindingID, _, expiresAt := sqlEngine.SetUserExpiryArgsForCall(0)
+				Expect(userBindingID).To(Equal(bindingID))
+				Expect(expiresAt).To(BeTemporally("~", time.Now().Add(2*time.Hour), time.Minute))
+
+				credentials := bindingResponse.Credentials.(Credentials)
+				Expect(credentials.ExpiresAt).To(Equal(expiresAt.Format(time.RFC3339)))
+			})
+
+			It("tags the instance with when its users expire", func() {
+				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(2))
+				_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+				_, _, expiresAt := sqlEngine.SetUserExpiryArgsForCall(0)
+				Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue("Expiring Users Until", expiresAt.Format(time.RFC3339)))
+			})
+
+			It("leaves the tag alone if other users expire later", func() {
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+					"Expiring Users Until": time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339),
+				}), nil)
+
+				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+				_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+				Expect(awsrds.RDSTagsValues(tags)).ToNot(HaveKey("Expiring Users Until"))
+			})
+
+			It("drops the user if its expiry can't be set", func() {
+				sqlEngine.SetUserExpiryReturns(errors.New("Failed to set expiry"))
+
+				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).To(MatchError("Failed to set expiry"))
+				Expect(sqlEngine.DropUserCallCount()).ToNot(BeZero())
+				Expect(sqlEngine.DropUserArgsForCall(0)).To(Equal(bindingID))
+			})
+
+			It("returns an error if it is too long", func() {
+				bindDetails.RawParameters = json.RawMessage(`{"ttl_hours": 721}`)
+
+				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).To(MatchError("ttl_hours must not be negative or more than 720"))
+				Expect(sqlEngine.CreateUserCallCount()).To(BeZero())
+			})
+
+			It("creates the expiry in the binding's database", func() {
+				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).ToNot(HaveOccurred())
+				_, dbname, _ := sqlEngine.SetUserExpiryArgsForCall(0)
+				Expect(dbname).To(Equal("test-db"))
+			})
+
+			It("returns an error for MySQL versions without user attributes", func() {
+				rdsInstance.DescribeReturns(&rds.DBInstance{
+					DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
+					Engine:               aws.String("mysql"),
+					EngineVersion:        aws.String("5.7.44"),
+					DBName:               aws.String("test-db"),
+				}, nil)
+
+				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).To(MatchError("ttl_hours needs MySQL 8.0.21 or later, but this instance runs MySQL 5.7.44"))
+				Expect(sqlEngine.CreateUserCallCount()).To(BeZero())
+			})
+
+			It("accepts MySQL versions with user attributes", func() {
+				rdsInstance.DescribeReturns(&rds.DBInstance{
+					DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
+					Endpoint: &rds.Endpoint{
+						Address: aws.String("endpoint-address"),
+						Port:    aws.Int64(3306),
+					},
+					Engine:         aws.String("mysql"),
+					EngineVersion:  aws.String("8.0.35"),
+					DBName:         aws.String("test-db"),
+					MasterUsername: aws.String("master-username"),
+				}, nil)
+
+				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sqlEngine.SetUserExpiryCallCount()).ToNot(BeZero())
+			})
+		})
+
+		Context("when the binding has schemas", func() {
+			BeforeEach(func() {
+				bindDetails.RawParameters = json.RawMessage(`{"schemas": ["reports"]}`)
+				rdsInstance.DescribeReturns(&rds.DBInstance{
+					DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
+					Endpoint: &rds.Endpoint{
+						Address: aws.String("endpoint-address"),
+						Port:    aws.Int64(5432),
+					},
+					Engine:         aws.String("postgres"),
+					DBName:         aws.String("test-db"),
+					MasterUsername: aws.String("master-username"),
+				}, nil)
+			})
+
+			It("creates the schemas in the database before the user", func() {
+				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sqlEngine.CreateSchemaCallCount()).ToNot(BeZero())
+				dbname, schema := sqlEngine.CreateSchemaArgsForCall(0)
+				Expect(dbname).To(Equal("test-db"))
+				Expect(schema).To(Equal("reports"))
+				Expect(sqlEngine.CreateUserCallCount()).ToNot(BeZero())
+			})
+
+			It("doesn't create the user if a schema can't be created", func() {
+				sqlEngine.CreateSchemaReturns(errors.New("Failed to create schema"))
+
+				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).To(MatchError("Failed to create schema"))
+				Expect(sqlEngine.CreateUserCallCount()).To(BeZero())
+			})
+
+			It("returns an error for reserved schemas", func() {
+				bindDetails.RawParameters = json.RawMessage(`{"schemas": ["pg_catalog"]}`)
+
+				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).To(MatchError("schemas: 'pg_catalog' is reserved"))
+				Expect(sqlEngine.CreateSchemaCallCount()).To(BeZero())
+			})
+
+			It("returns an error for MySQL", func() {
+				rdsInstance.DescribeReturns(&rds.DBInstance{
+					DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
+					Engine:               aws.String("mysql"),
+					DBName:               aws.String("test-db"),
+				}, nil)
+
+				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).To(MatchError("schemas are only supported for postgres"))
+				Expect(sqlEngine.CreateSchemaCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when Parameters are not valid", func() {
+
+			It("returns the proper error", func() {
+				bindDetails.RawParameters = json.RawMessage(`not JSON`)
+				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).To(HaveOccurred())
+				Expect(sqlProvider.GetSQLEngineCallCount()).To(BeZero())
+			})
+
+			Context("and user bind parameters are not allowed", func() {
+				BeforeEach(func() {
+					allowUserBindParameters = false
+				})
+
+				It("does not return an error", func() {
+					bindDetails.RawParameters = json.RawMessage(`not JSON`)
+					_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+					Expect(err).ToNot(HaveOccurred())
+				})
+			})
+
+			It("returns an error for extra params", func() {
+				bindDetails.RawParameters = json.RawMessage(`{"foo": "bar"}`)
+				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).To(MatchError(ContainSubstring(`unknown field "foo"`)))
+				Expect(sqlProvider.GetSQLEngineCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when Service is not found", func() {
+			BeforeEach(func() {
+				bindDetails.ServiceID = "unknown"
+			})
+
+			It("returns the proper error", func() {
+				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Service 'unknown' not found"))
+			})
+		})
+
+		Context("when Service Plan is not found", func() {
+			BeforeEach(func() {
+				bindDetails.PlanID = "unknown"
+			})
+
+			It("returns the proper error", func() {
+				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Service Plan 'unknown' not found"))
+			})
+		})
+
+		Context("when describing the DB Instance fails", func() {
+			BeforeEach(func() {
+				rdsInstance.DescribeReturns(nil, errors.New("operation failed"))
+			})
+
+			It("returns the proper error", func() {
+				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("operation failed"))
+			})
+
+			Context("when the DB Instance does not exists", func() {
+				BeforeEach(func() {
+					rdsInstance.DescribeReturns(nil, awsrds.ErrDBInstanceDoesNotExist)
+				})
+
+				It("returns the proper error", func() {
+					_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+					Expect(err).To(HaveOccurred())
+					Expect(err).To(Equal(apiresponses.ErrInstanceDoesNotExist))
+				})
+			})
+		})
+
+		Context("when getting the SQL Engine fails", func() {
+			BeforeEach(func() {
+				sqlProvider.GetSQLEngineReturns(nil, errors.New("Engine 'unknown' not supported"))
+			})
+
+			It("returns the proper error", func() {
+				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Engine 'unknown' not supported"))
 			})
 		})
 
 		Context("when opening a DB connection fails", func() {
 			BeforeEach(func() {
-				sqlEngine.OpenError = errors.New("Failed to open sqlEngine")
+				sqlEngine.OpenReturns(errors.New("Failed to open sqlEngine"))
+			})
+
+			It("returns the proper error", func() {
+				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Failed to open sqlEngine"))
+			})
+		})
+
+		Context("when creating a DB user fails", func() {
+			BeforeEach(func() {
+				sqlEngine.CreateUserReturns("", "", errors.New("Failed to create user"))
+			})
+
+			It("returns the proper error", func() {
+				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Failed to create user"))
+				Expect(sqlEngine.CloseCallCount()).ToNot(BeZero())
+			})
+		})
+	})
+
+	Describe("Unbind", func() {
+		var (
+			unbindDetails domain.UnbindDetails
+		)
+
+		BeforeEach(func() {
+			unbindDetails = domain.UnbindDetails{
+				ServiceID: "Service-1",
+				PlanID:    "Plan-1",
+			}
+
+			rdsInstance.DescribeReturns(&rds.DBInstance{
+				DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
+				Endpoint: &rds.Endpoint{
+					Address: aws.String("endpoint-address"),
+					Port:    aws.Int64(3306),
+				},
+				DBName:         aws.String("test-db"),
+				MasterUsername: aws.String("master-username"),
+				Engine:         aws.String("test-engine-one"),
+			}, nil)
+		})
+
+		It("asks for the unbinding to be retried while the master password is being changed", func() {
+			rdsInstance.DescribeReturns(&rds.DBInstance{
+				DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
+				PendingModifiedValues: &rds.PendingModifiedValues{
+					MasterUserPassword: aws.String("****"),
+				},
+			}, nil)
+
+			_, err := rdsBroker.Unbind(ctx, instanceID, bindingID, unbindDetails, false)
+			Expect(err).To(Equal(apiresponses.ErrConcurrentInstanceAccess))
+			Expect(sqlEngine.OpenCallCount()).To(BeZero())
+			Expect(sqlEngine.DropUserCallCount()).To(BeZero())
+		})
+
+		It("makes the proper calls", func() {
+			spec, err := rdsBroker.Unbind(ctx, instanceID, bindingID, unbindDetails, false)
+
+			Expect(rdsInstance.DescribeCallCount()).To(Equal(1))
+			Expect(err).ToNot(HaveOccurred())
+			id := rdsInstance.DescribeArgsForCall(0)
+			Expect(id).To(Equal(dbInstanceIdentifier))
+			Expect(spec.OperationData).To(Equal(""))
+
+			Expect(sqlProvider.GetSQLEngineCallCount()).ToNot(BeZero())
+			Expect(sqlProvider.GetSQLEngineArgsForCall(0)).To(Equal("test-engine-one"))
+			Expect(sqlEngine.OpenCallCount()).ToNot(BeZero())
+			address, port, dbname, username, password := sqlEngine.OpenArgsForCall(0)
+			Expect(address).To(Equal("endpoint-address"))
+			Expect(port).To(Equal(int64(3306)))
+			Expect(dbname).To(Equal("test-db"))
+			Expect(username).To(Equal("master-username"))
+			Expect(password).ToNot(BeEmpty())
+			Expect(sqlEngine.DropUserCallCount()).ToNot(BeZero())
+			Expect(sqlEngine.DropUserArgsForCall(0)).To(Equal(bindingID))
+			Expect(sqlEngine.CloseCallCount()).ToNot(BeZero())
+		})
+
+		It("removes the binding from the state store when there is one", func() {
+			stateStore := &statestorefake.FakeStateStore{}
+			rdsBroker.SetStateStore(stateStore)
+
+			_, err := rdsBroker.Unbind(ctx, instanceID, bindingID, unbindDetails, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(stateStore.ForgetBindingCallCount()).To(Equal(1))
+			forgottenInstanceID, forgottenBindingID := stateStore.ForgetBindingArgsForCall(0)
+			Expect(forgottenInstanceID).To(Equal(instanceID))
+			Expect(forgottenBindingID).To(Equal(bindingID))
+			Expect(rdsInstance.RemoveTagCallCount()).To(Equal(1))
+		})
+
+		It("removes the tag recording the binding", func() {
+			_, err := rdsBroker.Unbind(ctx, instanceID, bindingID, unbindDetails, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rdsInstance.RemoveTagCallCount()).To(Equal(1))
+			id, tagKey := rdsInstance.RemoveTagArgsForCall(0)
+			Expect(id).To(Equal(dbInstanceIdentifier))
+			Expect(tagKey).To(Equal("Binding " + bindingID))
+		})
+
+		Context("when the plan has an RDS Proxy", func() {
+			BeforeEach(func() {
+				unbindDetails.PlanID = "Plan-3"
+				rdsProxy = &RDSProxy{
+					RoleArn:        "arn:aws:iam::123456789012:role/rds-proxy",
+					AuthSecretArns: []string{"static-secret-arn"},
+					VpcSubnetIds:   []string{"subnet-1"},
+				}
+				rdsInstance.DescribeDBProxyReturns(&rds.DBProxy{
+					DBProxyName: aws.String(dbInstanceIdentifier),
+					Auth: []*rds.UserAuthConfigInfo{
+						{SecretArn: aws.String("static-secret-arn")},
+						{SecretArn: aws.String("binding-secret-arn"), Description: aws.String(bindingID)},
+					},
+				}, nil)
+			})
+
+			It("takes the binding's secret off the proxy and deletes it", func() {
+				_, err := rdsBroker.Unbind(ctx, instanceID, bindingID, unbindDetails, false)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(rdsInstance.SetDBProxyAuthCallCount()).To(Equal(1))
+				_, auth := rdsInstance.SetDBProxyAuthArgsForCall(0)
+				Expect(auth).To(HaveLen(1))
+				Expect(aws.StringValue(auth[0].SecretArn)).To(Equal("static-secret-arn"))
+
+				Expect(secretStore.DeleteSecretCallCount()).To(Equal(1))
+				Expect(secretStore.DeleteSecretArgsForCall(0)).To(Equal(dbInstanceIdentifier + "-" + bindingID))
+				Expect(sqlEngine.DropUserCallCount()).ToNot(BeZero())
+			})
+
+			It("leaves the proxy alone if the binding wasn't made through it", func() {
+				_, err := rdsBroker.Unbind(ctx, instanceID, "other-binding", unbindDetails, false)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(rdsInstance.SetDBProxyAuthCallCount()).To(Equal(0))
+				Expect(secretStore.DeleteSecretCallCount()).To(Equal(0))
+				Expect(sqlEngine.DropUserCallCount()).ToNot(BeZero())
+			})
+
+			It("keeps the user if the proxy can't be changed", func() {
+				rdsInstance.SetDBProxyAuthReturns(errors.New("operation failed"))
+
+				_, err := rdsBroker.Unbind(ctx, instanceID, bindingID, unbindDetails, false)
+				Expect(err).To(MatchError("operation failed"))
+				Expect(secretStore.DeleteSecretCallCount()).To(Equal(0))
+				Expect(sqlEngine.DropUserCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when Service Plan is not found", func() {
+			BeforeEach(func() {
+				unbindDetails.PlanID = "unknown"
+			})
+
+			It("returns the proper error", func() {
+				spec, err := rdsBroker.Unbind(ctx, instanceID, bindingID, unbindDetails, false)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Service Plan 'unknown' not found"))
+				Expect(spec.OperationData).To(Equal(""))
+			})
+		})
+
+		Context("when describing the DB Instance fails", func() {
+			BeforeEach(func() {
+				rdsInstance.DescribeReturns(nil, errors.New("operation failed"))
+			})
+
+			It("returns the proper error", func() {
+				spec, err := rdsBroker.Unbind(ctx, instanceID, bindingID, unbindDetails, false)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("operation failed"))
+				Expect(spec.OperationData).To(Equal(""))
+			})
+
+			Context("when the DB Instance does not exists", func() {
+				BeforeEach(func() {
+					rdsInstance.DescribeReturns(nil, awsrds.ErrDBInstanceDoesNotExist)
+				})
+
+				It("returns the proper error", func() {
+					spec, err := rdsBroker.Unbind(ctx, instanceID, bindingID, unbindDetails, false)
+					Expect(err).To(HaveOccurred())
+					Expect(err).To(Equal(apiresponses.ErrInstanceDoesNotExist))
+					Expect(spec.OperationData).To(Equal(""))
+				})
+			})
+		})
+
+		Context("when getting the SQL Engine fails", func() {
+			BeforeEach(func() {
+				sqlProvider.GetSQLEngineReturns(nil, errors.New("SQL Engine 'unknown' not supported"))
+			})
+
+			It("returns the proper error", func() {
+				spec, err := rdsBroker.Unbind(ctx, instanceID, bindingID, unbindDetails, false)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("SQL Engine 'unknown' not supported"))
+				Expect(spec.OperationData).To(Equal(""))
+			})
+		})
+
+		Context("when opening a DB connection fails", func() {
+			BeforeEach(func() {
+				sqlEngine.OpenReturns(errors.New("Failed to open sqlEngine"))
 			})
 
 			It("returns the proper error", func() {
@@ -2185,14 +4202,14 @@ var _ = Describe("RDS Broker", func() {
 
 		Context("when deleting a user fails", func() {
 			BeforeEach(func() {
-				sqlEngine.DropUserError = errors.New("Failed to delete user")
+				sqlEngine.DropUserReturns(errors.New("Failed to delete user"))
 			})
 
 			It("returns the proper error", func() {
 				spec, err := rdsBroker.Unbind(ctx, instanceID, bindingID, unbindDetails, false)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(Equal("Failed to delete user"))
-				Expect(sqlEngine.CloseCalled).To(BeTrue())
+				Expect(sqlEngine.CloseCallCount()).ToNot(BeZero())
 				Expect(spec.OperationData).To(Equal(""))
 			})
 		})
@@ -2287,6 +4304,7 @@ var _ = Describe("RDS Broker", func() {
 			Context("when the DB Instance does not exists", func() {
 				JustBeforeEach(func() {
 					rdsInstance.DescribeReturns(nil, awsrds.ErrDBInstanceDoesNotExist)
+					rdsInstance.DescribeSnapshotReturns(nil, awsrds.ErrDBSnapshotDoesNotExist)
 				})
 
 				It("returns the proper error", func() {
@@ -2332,17 +4350,77 @@ var _ = Describe("RDS Broker", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(lastOperationResponse).To(Equal(properLastOperationResponse))
 
-				Expect(rdsInstance.GetResourceTagsCallCount()).To(Equal(1))
-				id, opts := rdsInstance.GetResourceTagsArgsForCall(0)
-				Expect(id).To(Equal(dbInstanceArn))
+				Expect(rdsInstance.GetResourceTagsCallCount()).To(Equal(1))
+				id, opts := rdsInstance.GetResourceTagsArgsForCall(0)
+				Expect(id).To(Equal(dbInstanceArn))
+
+				Expect(opts).ToNot(ContainElement(awsrds.DescribeUseCachedOption))
+			})
+
+			It("returns the proper LastOperationResponse", func() {
+				lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lastOperationResponse).To(Equal(properLastOperationResponse))
+			})
+
+			It("polls RDS every time by default", func() {
+				_, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rdsInstance.DescribeCallCount()).To(Equal(2))
+			})
+
+			Context("and the last operation cache is enabled", func() {
+				BeforeEach(func() {
+					lastOperationCacheSeconds = 3600
+				})
+
+				It("returns the cached response to repeated polls", func() {
+					first, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+					Expect(err).ToNot(HaveOccurred())
+					second, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(second).To(Equal(first))
+					Expect(rdsInstance.DescribeCallCount()).To(Equal(1))
+					Expect(rdsInstance.GetResourceTagsCallCount()).To(Equal(1))
+				})
+
+				It("does not use the cached response for a different operation", func() {
+					_, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+					Expect(err).ToNot(HaveOccurred())
+					pollDetails.OperationData = "another-operation"
+					_, err = rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(rdsInstance.DescribeCallCount()).To(Equal(2))
+				})
+
+				It("forgets the cached response when the instance is updated", func() {
+					_, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+					Expect(err).ToNot(HaveOccurred())
+					_, _ = rdsBroker.Update(ctx, instanceID, domain.UpdateDetails{}, true)
+					describeCallCount := rdsInstance.DescribeCallCount()
+
+					_, err = rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(rdsInstance.DescribeCallCount()).To(Equal(describeCallCount + 1))
+				})
 
-				Expect(opts).ToNot(ContainElement(awsrds.DescribeUseCachedOption))
-			})
+				Context("when the operation has finished", func() {
+					BeforeEach(func() {
+						dbInstanceStatus = "failed"
+					})
 
-			It("returns the proper LastOperationResponse", func() {
-				lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
-				Expect(err).ToNot(HaveOccurred())
-				Expect(lastOperationResponse).To(Equal(properLastOperationResponse))
+					It("does not cache the response", func() {
+						_, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+						Expect(err).ToNot(HaveOccurred())
+						_, err = rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(rdsInstance.DescribeCallCount()).To(Equal(2))
+					})
+				})
 			})
 
 			Context("and there are pending post restore tasks", func() {
@@ -2380,6 +4458,52 @@ var _ = Describe("RDS Broker", func() {
 				Expect(lastOperationState.State).To(Equal(domain.InProgress))
 				Expect(rdsInstance.RebootCallCount()).To(Equal(1))
 			})
+
+			It("says the reboot is to apply the parameter group and records why", func() {
+				lastOperationState, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lastOperationState.Description).To(Equal("DB Instance '" + dbInstanceIdentifier + "' is rebooting to apply changes to its parameter group"))
+
+				Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+				_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+				Expect(awsrds.RDSTagsValues(tags)).To(Equal(map[string]string{awsrds.TagRebootReason: "parameter-change"}))
+			})
+		})
+
+		Context("when the instance is rebooting for a recorded reason", func() {
+			BeforeEach(func() {
+				dbInstanceStatus = "rebooting"
+			})
+
+			JustBeforeEach(func() {
+				newDBInstanceTagsByName := copyStringStringMap(defaultDBInstanceTagsByName)
+				newDBInstanceTagsByName[awsrds.TagRebootReason] = "user-requested"
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(newDBInstanceTagsByName), nil)
+			})
+
+			It("gives the reason in the description", func() {
+				lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lastOperationResponse).To(Equal(domain.LastOperation{
+					State:       domain.InProgress,
+					Description: "DB Instance '" + dbInstanceIdentifier + "' is rebooting as requested",
+				}))
+			})
+
+			Context("and the reboot has finished", func() {
+				BeforeEach(func() {
+					dbInstanceStatus = "available"
+				})
+
+				It("removes the reason", func() {
+					lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(lastOperationResponse.State).To(Equal(domain.Succeeded))
+					Expect(rdsInstance.RemoveTagCallCount()).To(Equal(1))
+					_, tagName := rdsInstance.RemoveTagArgsForCall(0)
+					Expect(tagName).To(Equal(awsrds.TagRebootReason))
+				})
+			})
 		})
 
 		Context("when the parameter group has a pending-reboot state and instance is unavailable", func() {
@@ -2396,6 +4520,29 @@ var _ = Describe("RDS Broker", func() {
 			})
 		})
 
+		Context("when another broker holds an operation lease on the instance", func() {
+			BeforeEach(func() {
+				dbInstanceStatus = "available"
+			})
+
+			JustBeforeEach(func() {
+				newDBInstanceTagsByName := copyStringStringMap(defaultDBInstanceTagsByName)
+				newDBInstanceTagsByName["Operation Lease"] = "mybroker-abcdefgh until " + time.Now().Add(time.Minute).UTC().Format(time.RFC3339)
+				newDBInstanceTagsByName["PendingUpdateSettings"] = "true"
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(newDBInstanceTagsByName), nil)
+			})
+
+			It("reports the operation as in progress without modifying the instance", func() {
+				lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lastOperationResponse).To(Equal(domain.LastOperation{
+					State:       domain.InProgress,
+					Description: "DB Instance '" + dbInstanceIdentifier + "' has another operation in progress",
+				}))
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+			})
+		})
+
 		Context("when last operation failed", func() {
 			BeforeEach(func() {
 				dbInstanceStatus = "failed"
@@ -2407,6 +4554,76 @@ var _ = Describe("RDS Broker", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(lastOperationResponse).To(Equal(properLastOperationResponse))
 			})
+
+			Context("and RDS has recorded events for the instance", func() {
+				JustBeforeEach(func() {
+					rdsInstance.DescribeEventsBetweenReturns([]*rds.Event{
+						{
+							Message:         aws.String("Finished applying modification to DB parameter group"),
+							EventCategories: []*string{aws.String("configuration change")},
+						},
+						{
+							Message:         aws.String("The parameter max_connections was set to a value incompatible with the instance class"),
+							EventCategories: []*string{aws.String("failure")},
+						},
+					}, nil)
+				})
+
+				It("includes the most recent failure event in the description", func() {
+					lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+					Expect(err).ToNot(HaveOccurred())
+					id, startTime, endTime := rdsInstance.DescribeEventsBetweenArgsForCall(0)
+					Expect(id).To(Equal(dbInstanceIdentifier))
+					Expect(endTime.Sub(startTime)).To(Equal(24 * time.Hour))
+					Expect(endTime).To(BeTemporally("~", time.Now(), time.Minute))
+					Expect(lastOperationResponse.State).To(Equal(domain.Failed))
+					Expect(lastOperationResponse.Description).To(Equal(
+						"DB Instance '" + dbInstanceIdentifier + "' status is 'failed': " +
+							"The parameter max_connections was set to a value incompatible with the instance class",
+					))
+				})
+			})
+
+			Context("and describing the events fails", func() {
+				JustBeforeEach(func() {
+					rdsInstance.DescribeEventsBetweenReturns(nil, errors.New("operation failed"))
+				})
+
+				It("returns the plain status", func() {
+					lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(lastOperationResponse).To(Equal(properLastOperationResponse))
+				})
+			})
+
+			It("leaves the DB Instance alone", func() {
+				_, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+			})
+
+			Context("and the operation was a provision", func() {
+				BeforeEach(func() {
+					pollDetails.OperationData = "provision"
+				})
+
+				JustBeforeEach(func() {
+					tagsByName := copyStringStringMap(defaultDBInstanceTagsByName)
+					tagsByName["chargeable_entity"] = instanceID
+					rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(tagsByName), nil)
+				})
+
+				It("deletes the orphaned DB Instance without a final snapshot", func() {
+					lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(lastOperationResponse).To(Equal(properLastOperationResponse))
+
+					Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+					id, skipFinalSnapshot := rdsInstance.DeleteArgsForCall(0)
+					Expect(id).To(Equal(dbInstanceIdentifier))
+					Expect(skipFinalSnapshot).To(BeTrue())
+				})
+			})
 		})
 
 		Context("when a simple major version upgrade failed", func() {
@@ -2441,7 +4658,9 @@ var _ = Describe("RDS Broker", func() {
 				Expect(id).To(Equal(dbInstanceArn))
 				tagsByName := awsrds.RDSTagsValues(tags)
 
-				Expect(tagsByName).To(Equal(defaultDBInstanceTagsByName))
+				Expect(tagsByName).To(Equal(map[string]string{
+					awsrds.TagPlanID: defaultDBInstanceTagsByName[awsrds.TagPlanID],
+				}))
 			})
 		})
 
@@ -2456,80 +4675,365 @@ var _ = Describe("RDS Broker", func() {
 				}
 			})
 
-			JustBeforeEach(func() {
-				newDBInstanceTagsByName := copyStringStringMap(defaultDBInstanceTagsByName)
+			JustBeforeEach(func() {
+				newDBInstanceTagsByName := copyStringStringMap(defaultDBInstanceTagsByName)
+
+				newDBInstanceTagsByName["Plan ID"] = "Plan-5"
+				rdsInstance.GetResourceTagsReturns(
+					awsrds.BuildRDSTags(newDBInstanceTagsByName),
+					nil,
+				)
+			})
+
+			It("returns the proper LastOperationResponse", func() {
+				lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lastOperationResponse).To(Equal(domain.LastOperation{
+					State:       domain.Succeeded,
+					Description: "DB Instance 'cf-instance-id' status is 'available'",
+				}))
+			})
+		})
+
+		Context("when a complex major version upgrade failed", func() {
+			BeforeEach(func() {
+				dbInstanceStatus = "available"
+				dbAllocatedStorage = 200
+			})
+
+			JustBeforeEach(func() {
+				newDBInstanceTagsByName := copyStringStringMap(defaultDBInstanceTagsByName)
+				newDBInstanceTagsByName["Plan ID"] = "Plan-5"
+				rdsInstance.GetResourceTagsReturns(
+					awsrds.BuildRDSTags(newDBInstanceTagsByName),
+					nil,
+				)
+			})
+
+			It("returns the proper LastOperationResponse", func() {
+				lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lastOperationResponse).To(Equal(domain.LastOperation{
+					State:       domain.Failed,
+					Description: "Operation failed and will need manual intervention to resolve. Please contact support.",
+				}))
+			})
+
+			It("does not roll back the Plan ID tag", func() {
+				_, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when last operation succeeded", func() {
+			BeforeEach(func() {
+				dbInstanceStatus = "available"
+				lastOperationState = domain.Succeeded
+			})
+
+			It("returns the proper LastOperationResponse", func() {
+				lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lastOperationResponse).To(Equal(properLastOperationResponse))
+			})
+
+			Context("the SQL engine is Postgres", func() {
+				JustBeforeEach(func() {
+					defaultDBInstance.Engine = aws.String("postgres")
+					rdsInstance.DescribeReturns(defaultDBInstance, nil)
+				})
+
+				It("attempts to create Postgres extenions", func() {
+					lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(sqlEngine.CreateExtensionsCallCount()).ToNot(BeZero())
+					Expect(lastOperationResponse).To(Equal(properLastOperationResponse))
+				})
+
+				It("doesn't update extensions if there hasn't been a major version upgrade", func() {
+					_, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(updatedExtensions(sqlEngine)).To(BeEmpty())
+					Expect(rdsInstance.RemoveTagCallCount()).To(Equal(0))
+				})
+
+				Context("and a major version upgrade is waiting for its extensions to be updated", func() {
+					var upgradedDBInstance rds.DBInstance
+
+					JustBeforeEach(func() {
+						upgradedDBInstance = *defaultDBInstance
+						upgradedDBInstance.EngineVersion = aws.String("13.4")
+						rdsInstance.DescribeReturns(&upgradedDBInstance, nil)
+
+						tagsByName := copyStringStringMap(defaultDBInstanceTagsByName)
+						tagsByName["Pending Extension Update For"] = "13"
+						rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(tagsByName), nil)
+					})
+
+					It("updates each extension and removes the tag", func() {
+						lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(lastOperationResponse).To(Equal(properLastOperationResponse))
+						Expect(updatedExtensions(sqlEngine)).To(Equal([]string{"postgis", "pg-stat-statements"}))
+
+						Expect(rdsInstance.RemoveTagCallCount()).To(Equal(1))
+						id, tagKey := rdsInstance.RemoveTagArgsForCall(0)
+						Expect(id).To(Equal(dbInstanceIdentifier))
+						Expect(tagKey).To(Equal("Pending Extension Update For"))
+					})
+
+					It("carries on when an extension fails to update", func() {
+						sqlEngine.UpdateExtensionCalls(func(extension string) error {
+							if extension == "postgis" {
+								return errors.New("extension \"postgis\" has no update path")
+							}
+							return nil
+						})
+
+						lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(lastOperationResponse).To(Equal(properLastOperationResponse))
+						Expect(updatedExtensions(sqlEngine)).To(Equal([]string{"postgis", "pg-stat-statements"}))
+						Expect(rdsInstance.RemoveTagCallCount()).To(Equal(1))
+					})
+
+					It("waits if the instance isn't on the new version yet", func() {
+						upgradedDBInstance.EngineVersion = aws.String("12.8")
+
+						_, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(updatedExtensions(sqlEngine)).To(BeEmpty())
+						Expect(rdsInstance.RemoveTagCallCount()).To(Equal(0))
+					})
+				})
+
+				It("doesn't create any databases when the instance has no additional ones", func() {
+					_, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(createdDatabases(sqlEngine)).To(BeEmpty())
+				})
+
+				Context("and the instance has additional databases", func() {
+					JustBeforeEach(func() {
+						tagsByName := copyStringStringMap(defaultDBInstanceTagsByName)
+						tagsByName["Additional Databases"] = "analytics:reports"
+						rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(tagsByName), nil)
+					})
+
+					It("creates them", func() {
+						lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(lastOperationResponse).To(Equal(properLastOperationResponse))
+						Expect(createdDatabases(sqlEngine)).To(Equal([]string{"analytics", "reports"}))
+					})
+
+					It("fails if they can't be created", func() {
+						sqlEngine.CreateDatabaseReturns(errors.New("permission denied to create database"))
+
+						lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+						Expect(err).To(MatchError("permission denied to create database"))
+						Expect(lastOperationResponse.State).To(Equal(domain.Failed))
+					})
+				})
+			})
+
+			Context("the SQL engine is MySQL", func() {
+				JustBeforeEach(func() {
+					newDBInstance := *defaultDBInstance
+					newDBInstance.Engine = aws.String("mysql")
+					rdsInstance.DescribeReturns(&newDBInstance, nil)
+				})
+
+				It("does not set the binlog retention if the plan doesn't specify it", func() {
+					_, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(sqlEngine.SetBinlogRetentionHoursCallCount()).To(BeZero())
+				})
+
+				Context("and the plan has a binlog retention", func() {
+					BeforeEach(func() {
+						rdsProperties3.BinlogRetentionHours = int64Pointer(24)
+					})
+
+					It("sets the binlog retention and records it in a tag", func() {
+						lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(sqlEngine.SetBinlogRetentionHoursCallCount()).ToNot(BeZero())
+						Expect(sqlEngine.SetBinlogRetentionHoursArgsForCall(0)).To(Equal(int64(24)))
+						Expect(lastOperationResponse).To(Equal(properLastOperationResponse))
+
+						Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+						arn, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+						Expect(arn).To(Equal(dbInstanceArn))
+						Expect(awsrds.RDSTagsValues(tags)).To(Equal(map[string]string{"Binlog Retention Hours": "24"}))
+					})
+
+					Context("which has already been set", func() {
+						JustBeforeEach(func() {
+							tagsByName := copyStringStringMap(defaultDBInstanceTagsByName)
+							tagsByName["Binlog Retention Hours"] = "24"
+							rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(tagsByName), nil)
+						})
+
+						It("doesn't connect to the database to set it again", func() {
+							lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+							Expect(err).ToNot(HaveOccurred())
+							Expect(lastOperationResponse).To(Equal(properLastOperationResponse))
+							Expect(sqlEngine.SetBinlogRetentionHoursCallCount()).To(BeZero())
+							Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(0))
+						})
+					})
+
+					It("fails if the binlog retention can't be set", func() {
+						sqlEngine.SetBinlogRetentionHoursReturns(errors.New("access denied"))
+
+						lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+						Expect(err).To(MatchError("access denied"))
+						Expect(lastOperationResponse.State).To(Equal(domain.Failed))
+					})
+				})
+			})
+
+			Context("and the plan has an RDS Proxy", func() {
+				BeforeEach(func() {
+					rdsProperties3.Engine = stringPointer("postgres")
+					rdsProxy = &RDSProxy{
+						RoleArn:        "arn:aws:iam::123456789012:role/rds-proxy",
+						AuthSecretArns: []string{"arn:aws:secretsmanager:rds-region:123456789012:secret:proxy"},
+						VpcSubnetIds:   []string{"subnet-1", "subnet-2"},
+					}
+				})
+
+				It("creates the proxy and waits for it", func() {
+					lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(lastOperationResponse).To(Equal(domain.LastOperation{
+						State:       domain.InProgress,
+						Description: "RDS Proxy for DB Instance '" + dbInstanceIdentifier + "' is being created",
+					}))
+
+					Expect(rdsInstance.CreateDBProxyCallCount()).To(Equal(1))
+					input := rdsInstance.CreateDBProxyArgsForCall(0)
+					Expect(aws.StringValue(input.DBProxyName)).To(Equal(dbInstanceIdentifier))
+					Expect(aws.StringValue(input.EngineFamily)).To(Equal("POSTGRESQL"))
+					Expect(aws.StringValue(input.RoleArn)).To(Equal("arn:aws:iam::123456789012:role/rds-proxy"))
+					Expect(aws.StringValueSlice(input.VpcSubnetIds)).To(Equal([]string{"subnet-1", "subnet-2"}))
+					Expect(input.Auth).To(HaveLen(1))
+					Expect(aws.StringValue(input.Auth[0].SecretArn)).To(Equal("arn:aws:secretsmanager:rds-region:123456789012:secret:proxy"))
+
+					Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+					arn, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+					Expect(arn).To(Equal(dbInstanceArn))
+					Expect(awsrds.RDSTagsValues(tags)).To(Equal(map[string]string{"DB Proxy": "creating"}))
+				})
+
+				It("fails if the proxy can't be created", func() {
+					rdsInstance.CreateDBProxyReturns(errors.New("boom"))
 
-				newDBInstanceTagsByName["Plan ID"] = "Plan-5"
-				rdsInstance.GetResourceTagsReturns(
-					awsrds.BuildRDSTags(newDBInstanceTagsByName),
-					nil,
-				)
-			})
+					lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+					Expect(err).To(MatchError("boom"))
+					Expect(lastOperationResponse.State).To(Equal(domain.Failed))
+				})
 
-			It("returns the proper LastOperationResponse", func() {
-				lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
-				Expect(err).ToNot(HaveOccurred())
-				Expect(lastOperationResponse).To(Equal(domain.LastOperation{
-					State:       domain.Succeeded,
-					Description: "DB Instance 'cf-instance-id' status is 'available'",
-				}))
-			})
-		})
+				Context("when the proxy is being created", func() {
+					JustBeforeEach(func() {
+						tagsByName := copyStringStringMap(defaultDBInstanceTagsByName)
+						tagsByName["DB Proxy"] = "creating"
+						rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(tagsByName), nil)
+						rdsInstance.DescribeDBProxyReturns(&rds.DBProxy{Status: aws.String("creating")}, nil)
+					})
 
-		Context("when a complex major version upgrade failed", func() {
-			BeforeEach(func() {
-				dbInstanceStatus = "available"
-				dbAllocatedStorage = 200
-			})
+					It("keeps waiting until it is available", func() {
+						lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(lastOperationResponse.State).To(Equal(domain.InProgress))
+						Expect(rdsInstance.CreateDBProxyCallCount()).To(Equal(0))
+						Expect(rdsInstance.RegisterDBProxyTargetsCallCount()).To(Equal(0))
+					})
 
-			JustBeforeEach(func() {
-				newDBInstanceTagsByName := copyStringStringMap(defaultDBInstanceTagsByName)
-				newDBInstanceTagsByName["Plan ID"] = "Plan-5"
-				rdsInstance.GetResourceTagsReturns(
-					awsrds.BuildRDSTags(newDBInstanceTagsByName),
-					nil,
-				)
-			})
+					It("registers the instance with the proxy once it is available", func() {
+						rdsInstance.DescribeDBProxyReturns(&rds.DBProxy{Status: aws.String("available")}, nil)
 
-			It("returns the proper LastOperationResponse", func() {
-				lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
-				Expect(err).ToNot(HaveOccurred())
-				Expect(lastOperationResponse).To(Equal(domain.LastOperation{
-					State:       domain.Failed,
-					Description: "Operation failed and will need manual intervention to resolve. Please contact support.",
-				}))
-			})
+						lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(lastOperationResponse).To(Equal(properLastOperationResponse))
 
-			It("does not roll back the Plan ID tag", func() {
-				_, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
-				Expect(err).ToNot(HaveOccurred())
-				Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(0))
-			})
-		})
+						Expect(rdsInstance.RegisterDBProxyTargetsCallCount()).To(Equal(1))
+						proxyName, id := rdsInstance.RegisterDBProxyTargetsArgsForCall(0)
+						Expect(proxyName).To(Equal(dbInstanceIdentifier))
+						Expect(id).To(Equal(dbInstanceIdentifier))
 
-		Context("when last operation succeeded", func() {
-			BeforeEach(func() {
-				dbInstanceStatus = "available"
-				lastOperationState = domain.Succeeded
-			})
+						Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+						_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+						Expect(awsrds.RDSTagsValues(tags)).To(Equal(map[string]string{"DB Proxy": "available"}))
+					})
+				})
 
-			It("returns the proper LastOperationResponse", func() {
-				lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
-				Expect(err).ToNot(HaveOccurred())
-				Expect(lastOperationResponse).To(Equal(properLastOperationResponse))
+				Context("when the proxy is available", func() {
+					JustBeforeEach(func() {
+						tagsByName := copyStringStringMap(defaultDBInstanceTagsByName)
+						tagsByName["DB Proxy"] = "available"
+						rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(tagsByName), nil)
+					})
+
+					It("leaves the proxy alone", func() {
+						lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(lastOperationResponse).To(Equal(properLastOperationResponse))
+						Expect(rdsInstance.CreateDBProxyCallCount()).To(Equal(0))
+						Expect(rdsInstance.DescribeDBProxyCallCount()).To(Equal(0))
+					})
+				})
 			})
 
-			Context("the SQL engine is Postgres", func() {
-				JustBeforeEach(func() {
-					defaultDBInstance.Engine = aws.String("postgres")
-					rdsInstance.DescribeReturns(defaultDBInstance, nil)
+			Context("and a DNS zone is configured", func() {
+				BeforeEach(func() {
+					dnsConfig = &DNSConfig{HostedZoneID: "ZONE123", Domain: "db.example.com."}
 				})
 
-				It("attempts to create Postgres extenions", func() {
+				It("points the instance's CNAME at its endpoint", func() {
 					lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
 					Expect(err).ToNot(HaveOccurred())
-					Expect(sqlEngine.CreateExtensionsCalled).To(BeTrue())
 					Expect(lastOperationResponse).To(Equal(properLastOperationResponse))
+
+					Expect(dnsZone.UpsertCNAMECallCount()).To(Equal(1))
+					name, target := dnsZone.UpsertCNAMEArgsForCall(0)
+					Expect(name).To(Equal(instanceID + ".db.example.com"))
+					Expect(target).To(Equal("endpoint-address"))
+
+					Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+					arn, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+					Expect(arn).To(Equal(dbInstanceArn))
+					Expect(awsrds.RDSTagsValues(tags)).To(Equal(map[string]string{
+						"DNS Name":   instanceID + ".db.example.com",
+						"DNS Target": "endpoint-address",
+					}))
+				})
+
+				It("fails if the CNAME can't be created", func() {
+					dnsZone.UpsertCNAMEReturns(errors.New("boom"))
+
+					lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+					Expect(err).To(MatchError("boom"))
+					Expect(lastOperationResponse.State).To(Equal(domain.Failed))
+				})
+
+				Context("when the CNAME already points at the endpoint", func() {
+					JustBeforeEach(func() {
+						tagsByName := copyStringStringMap(defaultDBInstanceTagsByName)
+						tagsByName["DNS Name"] = instanceID + ".db.example.com"
+						tagsByName["DNS Target"] = "endpoint-address"
+						rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(tagsByName), nil)
+					})
+
+					It("leaves the CNAME alone", func() {
+						lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(lastOperationResponse).To(Equal(properLastOperationResponse))
+						Expect(dnsZone.UpsertCNAMECallCount()).To(Equal(0))
+						Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(0))
+					})
 				})
 			})
 
@@ -2651,11 +5155,17 @@ var _ = Describe("RDS Broker", func() {
 
 				Context("when the master password needs to be rotated", func() {
 					JustBeforeEach(func() {
-						sqlEngine.CorrectPassword = "some-other-password"
+						correctPassword := "some-other-password"
+						sqlEngine.OpenCalls(func(address string, port int64, dbname string, username string, password string) error {
+							if password != correctPassword {
+								return sqlengine.LoginFailedError
+							}
+							return nil
+						})
 						// use a stub function to set the password back to what it was before. This is because the Bind()
 						// uses two different calls to the SQL engine's Open() method, the first needs to fail and the second needs to pass.
 						rdsInstance.ModifyStub = func(input *rds.ModifyDBInstanceInput) (*rds.DBInstance, error) {
-							sqlEngine.CorrectPassword = aws.StringValue(input.MasterUserPassword)
+							correctPassword = aws.StringValue(input.MasterUserPassword)
 							return &rds.DBInstance{DBInstanceIdentifier: input.DBInstanceIdentifier}, nil
 						}
 					})
@@ -2698,7 +5208,7 @@ var _ = Describe("RDS Broker", func() {
 
 					properLastOperationResponse = domain.LastOperation{
 						State:       domain.InProgress,
-						Description: "DB Instance '" + dbInstanceIdentifier + "' has pending post restore modifications",
+						Description: "DB Instance '" + dbInstanceIdentifier + "' is rebooting to finish being restored",
 					}
 				})
 
@@ -2781,12 +5291,12 @@ var _ = Describe("RDS Broker", func() {
 				It("should reset the database state by calling sqlengine.ResetState()", func() {
 					_, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
 					Expect(err).ToNot(HaveOccurred())
-					Expect(sqlEngine.ResetStateCalled).To(BeTrue())
+					Expect(sqlEngine.ResetStateCallCount()).ToNot(BeZero())
 				})
 
 				Context("when sqlengine.ResetState() fails", func() {
 					BeforeEach(func() {
-						sqlEngine.ResetStateError = errors.New("Failed to reset state")
+						sqlEngine.ResetStateReturns(errors.New("Failed to reset state"))
 					})
 					It("returns the proper error", func() {
 						_, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
@@ -2796,6 +5306,47 @@ var _ = Describe("RDS Broker", func() {
 				})
 			})
 
+			Context("but there is a pending database creation for an adopted instance", func() {
+				JustBeforeEach(func() {
+					newDBInstanceTagsByName := copyStringStringMap(defaultDBInstanceTagsByName)
+					newDBInstanceTagsByName["PendingCreateDatabase"] = "true"
+					newDBInstanceTagsByName["PendingResetUserPassword"] = "true"
+					rdsInstance.GetResourceTagsReturns(
+						awsrds.BuildRDSTags(newDBInstanceTagsByName),
+						nil,
+					)
+				})
+
+				It("creates the database from the maintenance database before resetting the master user", func() {
+					lastOperationResponse, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(lastOperationResponse.State).To(Equal(domain.InProgress))
+
+					Expect(sqlEngine.OpenCallCount()).To(Equal(1))
+					_, _, dbName, _, _ := sqlEngine.OpenArgsForCall(0)
+					Expect(dbName).To(Equal("postgres"))
+					Expect(sqlEngine.CreateDatabaseCallCount()).To(Equal(1))
+					Expect(sqlEngine.CreateDatabaseArgsForCall(0)).To(Equal("test-db"))
+					Expect(sqlEngine.ResetStateCallCount()).To(BeZero())
+
+					Expect(rdsInstance.RemoveTagCallCount()).To(Equal(1))
+					_, tagName := rdsInstance.RemoveTagArgsForCall(0)
+					Expect(tagName).To(Equal("PendingCreateDatabase"))
+				})
+
+				Context("when creating the database fails", func() {
+					BeforeEach(func() {
+						sqlEngine.CreateDatabaseReturns(errors.New("Failed to create database"))
+					})
+
+					It("returns the error and keeps the tag", func() {
+						_, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+						Expect(err).To(MatchError("Failed to create database"))
+						Expect(rdsInstance.RemoveTagCallCount()).To(Equal(0))
+					})
+				})
+			})
+
 			Context("but there are not post restore tasks or reset password to execute", func() {
 				It("should not try to change the master password", func() {
 					_, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
@@ -2805,7 +5356,7 @@ var _ = Describe("RDS Broker", func() {
 				It("should not reset the database state by not calling sqlengine.ResetState()", func() {
 					_, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
 					Expect(err).ToNot(HaveOccurred())
-					Expect(sqlEngine.ResetStateCalled).To(BeFalse())
+					Expect(sqlEngine.ResetStateCallCount()).To(BeZero())
 				})
 				It("should not call RemoveTag", func() {
 					_, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
@@ -2908,6 +5459,13 @@ var _ = Describe("RDS Broker", func() {
 				PreferredMaintenanceWindow: stringPointer("some-convenient-maintenance-window"),
 				PreferredBackupWindow:      stringPointer("some-convenient-backup-window"),
 				BackupRetentionPeriod:      int64Pointer(4),
+				EngineVersion:              stringPointer("1.2.3"),
+				DBInstanceClass:            stringPointer("db.m1.test"),
+				AllocatedStorage:           int64Pointer(100),
+				MaxAllocatedStorage:        int64Pointer(200),
+				MultiAZ:                    boolPointer(false),
+				StorageType:                stringPointer("gp2"),
+				PendingModifiedValues:      &rds.PendingModifiedValues{},
 			}
 		})
 
@@ -2934,6 +5492,91 @@ var _ = Describe("RDS Broker", func() {
 			})
 		})
 
+		Context("when the plan has a maximum instance age", func() {
+			BeforeEach(func() {
+				plan1MaxAgeDays = 365
+			})
+
+			It("doesn't warn about instances younger than it", func() {
+				defaultDBInstance.InstanceCreateTime = aws.Time(time.Now().Add(-100 * 24 * time.Hour))
+
+				instance, err := rdsBroker.GetInstance(ctx, instanceID, fetchInstanceDetails)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(instance.Parameters).ToNot(HaveKey("warnings"))
+			})
+
+			It("warns about instances older than it", func() {
+				defaultDBInstance.InstanceCreateTime = aws.Time(time.Now().Add(-400 * 24 * time.Hour))
+
+				instance, err := rdsBroker.GetInstance(ctx, instanceID, fetchInstanceDetails)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(instance.Parameters).To(HaveKeyWithValue("warnings", []string{
+					"This instance is 400 days old, which is more than the 365 days allowed by its plan. Restore it into a new instance to exercise your rebuild procedure.",
+				}))
+			})
+		})
+
+		Context("when database usage is reported", func() {
+			BeforeEach(func() {
+				databaseUsageCacheSeconds = 300
+				defaultDBInstance.DBInstanceStatus = aws.String("available")
+				sqlEngine.DatabaseUsageReturns(sqlengine.DatabaseUsage{
+					SizeBytes:  25 << 30,
+					TableCount: 12,
+				}, nil)
+			})
+
+			It("includes the size of the database and how much storage it uses", func() {
+				instance, err := rdsBroker.GetInstance(ctx, instanceID, fetchInstanceDetails)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(instance.Parameters).To(HaveKey("database_usage"))
+				usage := instance.Parameters.(map[string]interface{})["database_usage"].(DatabaseUsage)
+				Expect(usage.SizeBytes).To(Equal(int64(25 << 30)))
+				Expect(usage.TableCount).To(Equal(int64(12)))
+				Expect(usage.AllocatedStorageUsedPercent).To(Equal(25.0))
+				_, _, dbname, _, _ := sqlEngine.OpenArgsForCall(0)
+				Expect(dbname).To(Equal(dbName))
+				Expect(sqlEngine.CloseCallCount()).ToNot(BeZero())
+			})
+
+			It("returns the cached usage to repeated requests", func() {
+				first, err := rdsBroker.GetInstance(ctx, instanceID, fetchInstanceDetails)
+				Expect(err).ToNot(HaveOccurred())
+				second, err := rdsBroker.GetInstance(ctx, instanceID, fetchInstanceDetails)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(second.Parameters.(map[string]interface{})["database_usage"]).To(Equal(first.Parameters.(map[string]interface{})["database_usage"]))
+				Expect(sqlEngine.DatabaseUsageCallCount()).To(Equal(1))
+			})
+
+			It("leaves it out when it can't be gathered", func() {
+				sqlEngine.DatabaseUsageReturns(sqlengine.DatabaseUsage{}, errors.New("connection refused"))
+
+				instance, err := rdsBroker.GetInstance(ctx, instanceID, fetchInstanceDetails)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(instance.Parameters).ToNot(HaveKey("database_usage"))
+			})
+
+			It("doesn't connect to instances which aren't available", func() {
+				defaultDBInstance.DBInstanceStatus = aws.String("modifying")
+
+				instance, err := rdsBroker.GetInstance(ctx, instanceID, fetchInstanceDetails)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(instance.Parameters).ToNot(HaveKey("database_usage"))
+				Expect(sqlEngine.DatabaseUsageCallCount()).To(Equal(0))
+			})
+		})
+
+		It("doesn't report database usage by default", func() {
+			defaultDBInstance.DBInstanceStatus = aws.String("available")
+
+			instance, err := rdsBroker.GetInstance(ctx, instanceID, fetchInstanceDetails)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instance.Parameters).ToNot(HaveKey("database_usage"))
+			Expect(sqlEngine.DatabaseUsageCallCount()).To(Equal(0))
+		})
+
 		Context("when the service instance can't be found by GetResourceTags", func() {
 			JustBeforeEach(func() {
 				rdsInstance.DescribeReturns(&defaultDBInstance, nil)
@@ -2976,11 +5619,110 @@ var _ = Describe("RDS Broker", func() {
 				Expect(parameters).To(HaveKeyWithValue("preferred_backup_window", stringPointer("some-convenient-backup-window")))
 				Expect(parameters).To(HaveKeyWithValue("preferred_maintenance_window", stringPointer("some-convenient-maintenance-window")))
 				Expect(parameters).To(HaveKeyWithValue("skip_final_snapshot", true))
-				Expect(len(parameters)).To(Equal(5))
+				Expect(parameters).To(HaveKeyWithValue("endpoint_type", "private"))
+				Expect(len(parameters)).To(Equal(20))
+			})
+		})
+
+		Context("when the instance is Multi-AZ", func() {
+			var failoverDate time.Time
+
+			BeforeEach(func() {
+				defaultDBInstance.MultiAZ = boolPointer(true)
+				defaultDBInstance.AvailabilityZone = stringPointer("eu-west-1a")
+				defaultDBInstance.SecondaryAvailabilityZone = stringPointer("eu-west-1b")
+
+				failoverDate = time.Now().Add(-48 * time.Hour).UTC()
+				rdsInstance.DescribeEventsBetweenReturns([]*rds.Event{
+					{
+						Date:            aws.Time(time.Now()),
+						EventCategories: aws.StringSlice([]string{"backup"}),
+						Message:         aws.String("Backing up DB instance"),
+					},
+					{
+						Date:            aws.Time(failoverDate),
+						EventCategories: aws.StringSlice([]string{"failover"}),
+						Message:         aws.String("Multi-AZ instance failover completed"),
+					},
+				}, nil)
+			})
+
+			It("returns its availability zones and recent failovers", func() {
+				getInstanceSpec, err := rdsBroker.GetInstance(ctx, instanceID, fetchInstanceDetails)
+				Expect(err).ToNot(HaveOccurred())
+
+				parameters := getInstanceSpec.Parameters.(map[string]interface{})
+				Expect(parameters).To(HaveKeyWithValue("availability_zone", stringPointer("eu-west-1a")))
+				Expect(parameters).To(HaveKeyWithValue("secondary_availability_zone", stringPointer("eu-west-1b")))
+				Expect(parameters).To(HaveKeyWithValue("recent_failovers", []Failover{
+					{Date: failoverDate, Message: "Multi-AZ instance failover completed"},
+				}))
+
+				Expect(rdsInstance.DescribeEventsBetweenCallCount()).To(Equal(1))
+				id, startTime, endTime := rdsInstance.DescribeEventsBetweenArgsForCall(0)
+				Expect(id).To(Equal(dbInstanceIdentifier))
+				Expect(endTime.Sub(startTime)).To(Equal(14 * 24 * time.Hour))
+			})
+
+			It("leaves the failovers out if the events can't be described", func() {
+				rdsInstance.DescribeEventsBetweenReturns(nil, errors.New("operation failed"))
+
+				getInstanceSpec, err := rdsBroker.GetInstance(ctx, instanceID, fetchInstanceDetails)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(getInstanceSpec.Parameters).ToNot(HaveKey("recent_failovers"))
+			})
+		})
+
+		Context("when the master password has been rotated", func() {
+			BeforeEach(func() {
+				defaultDBInstanceTagsByName[awsrds.TagMasterPasswordRotated] = "2026-10-01T12:00:00Z"
+			})
+
+			It("returns when it was rotated", func() {
+				getInstanceSpec, err := rdsBroker.GetInstance(ctx, instanceID, fetchInstanceDetails)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(getInstanceSpec.Parameters).To(HaveKeyWithValue("master_password_rotated_at", "2026-10-01T12:00:00Z"))
+			})
+		})
+
+		It("returns the service and plan IDs from the request", func() {
+			getInstanceSpec, err := rdsBroker.GetInstance(ctx, instanceID, fetchInstanceDetails)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(getInstanceSpec.ServiceID).To(Equal("Service-1"))
+			Expect(getInstanceSpec.PlanID).To(Equal("Plan-1"))
+		})
+
+		Context("when ServiceID/PlanID aren't supplied in the request", func() {
+			BeforeEach(func() {
+				defaultDBInstanceTagsByName["Service ID"] = "Service-3"
+				defaultDBInstanceTagsByName["Plan ID"] = "Plan-3"
+				fetchInstanceDetails = domain.FetchInstanceDetails{}
+			})
+
+			It("returns the service and plan IDs from the tags", func() {
+				getInstanceSpec, err := rdsBroker.GetInstance(ctx, instanceID, fetchInstanceDetails)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(getInstanceSpec.ServiceID).To(Equal("Service-3"))
+				Expect(getInstanceSpec.PlanID).To(Equal("Plan-3"))
 			})
 		})
 
-		Context("PlanID/ServiceID supplied via request are preferred over tags", func() {
+		It("reports the current configuration of the instance", func() {
+			getInstanceSpec, err := rdsBroker.GetInstance(ctx, instanceID, fetchInstanceDetails)
+			Expect(err).ToNot(HaveOccurred())
+
+			parameters, ok := getInstanceSpec.Parameters.(map[string]interface{})
+			Expect(ok).To(BeTrue())
+			Expect(parameters).To(HaveKeyWithValue("engine_version", stringPointer("1.2.3")))
+			Expect(parameters).To(HaveKeyWithValue("db_instance_class", stringPointer("db.m1.test")))
+			Expect(parameters).To(HaveKeyWithValue("allocated_storage", int64Pointer(100)))
+			Expect(parameters).To(HaveKeyWithValue("max_allocated_storage", int64Pointer(200)))
+			Expect(parameters).To(HaveKeyWithValue("multi_az", boolPointer(false)))
+			Expect(parameters).To(HaveKeyWithValue("storage_type", stringPointer("gp2")))
+			Expect(parameters).To(HaveKeyWithValue("pending_modifications", &rds.PendingModifiedValues{}))
+		})
+
+		Context("when the PlanID/ServiceID supplied via request disagree with the tags", func() {
 			BeforeEach(func() {
 				// would result in skip_final_snapshot true
 				defaultDBInstanceTagsByName = map[string]string{
@@ -2994,8 +5736,8 @@ var _ = Describe("RDS Broker", func() {
 				}
 			})
 
-			It("defaults to the correct skip_final_snapshot value", func() {
-				getBindingSpec, err := rdsBroker.GetInstance(ctx, instanceID, fetchInstanceDetails)
+			It("reports the plan and service from the tags", func() {
+				getInstanceSpec, err := rdsBroker.GetInstance(ctx, instanceID, fetchInstanceDetails)
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(rdsInstance.DescribeCallCount()).To(Equal(1))
@@ -3004,9 +5746,44 @@ var _ = Describe("RDS Broker", func() {
 				Expect(rdsInstance.GetResourceTagsCallCount()).To(Equal(1))
 				Expect(rdsInstance.GetResourceTagsArgsForCall(0)).To(Equal(dbInstanceArn))
 
-				parameters, ok := getBindingSpec.Parameters.(map[string]interface{})
+				Expect(getInstanceSpec.PlanID).To(Equal("Plan-1"))
+				Expect(getInstanceSpec.ServiceID).To(Equal("Service-1"))
+				parameters, ok := getInstanceSpec.Parameters.(map[string]interface{})
 				Expect(ok).To(BeTrue())
-				Expect(parameters).To(HaveKeyWithValue("skip_final_snapshot", false))
+				Expect(parameters).To(HaveKeyWithValue("skip_final_snapshot", true))
+			})
+
+			It("warns about the mismatch", func() {
+				getInstanceSpec, err := rdsBroker.GetInstance(ctx, instanceID, fetchInstanceDetails)
+				Expect(err).ToNot(HaveOccurred())
+
+				parameters, ok := getInstanceSpec.Parameters.(map[string]interface{})
+				Expect(ok).To(BeTrue())
+				Expect(parameters).To(HaveKeyWithValue("warnings", []string{
+					"The platform records plan 'Plan-3' for this service instance, but its DB instance is on plan 'Plan-1'",
+					"The platform records service 'Service-3' for this service instance, but its DB instance is of service 'Service-1'",
+				}))
+			})
+		})
+
+		Context("when the service instance has no plan tag", func() {
+			BeforeEach(func() {
+				defaultDBInstanceTagsByName = map[string]string{}
+				fetchInstanceDetails = domain.FetchInstanceDetails{
+					ServiceID: "Service-1",
+					PlanID:    "Plan-1",
+				}
+			})
+
+			It("falls back to the PlanID/ServiceID supplied via request", func() {
+				getInstanceSpec, err := rdsBroker.GetInstance(ctx, instanceID, fetchInstanceDetails)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(getInstanceSpec.PlanID).To(Equal("Plan-1"))
+				Expect(getInstanceSpec.ServiceID).To(Equal("Service-1"))
+				parameters, ok := getInstanceSpec.Parameters.(map[string]interface{})
+				Expect(ok).To(BeTrue())
+				Expect(parameters).ToNot(HaveKey("warnings"))
 			})
 		})
 
@@ -3047,7 +5824,7 @@ var _ = Describe("RDS Broker", func() {
 				Expect(parameters).To(HaveKeyWithValue("preferred_maintenance_window", stringPointer("some-convenient-maintenance-window")))
 				Expect(parameters).To(HaveKeyWithValue("skip_final_snapshot", false))
 				Expect(parameters).To(HaveKeyWithValue("restored_from_snapshot_of", "some-other-db-uuid"))
-				Expect(len(parameters)).To(Equal(6))
+				Expect(len(parameters)).To(Equal(21))
 			})
 		})
 
@@ -3076,7 +5853,45 @@ var _ = Describe("RDS Broker", func() {
 				Expect(parameters).To(HaveKeyWithValue("skip_final_snapshot", false))
 				Expect(parameters).To(HaveKeyWithValue("restored_from_point_in_time_of", "some-other-db-uuid"))
 				Expect(parameters).To(HaveKeyWithValue("restored_from_point_in_time_before", "2026-01-02T15:04:05Z07:00"))
-				Expect(len(parameters)).To(Equal(7))
+				Expect(len(parameters)).To(Equal(22))
+			})
+		})
+
+		Context("when the instance is postgres", func() {
+			BeforeEach(func() {
+				defaultDBInstance.Engine = stringPointer("postgres")
+				defaultDBInstance.EngineVersion = stringPointer("14.2")
+				rdsProperties1.Engine = stringPointer("postgres")
+				rdsProperties1.EngineVersion = stringPointer("13.4")
+				rdsProperties1.ExtensionsByMajorVersion = map[string]ExtensionLists{
+					"14": {AllowedExtensions: []*string{stringPointer("pg_surgery")}},
+				}
+			})
+
+			It("returns the extensions allowed on its current version", func() {
+				getInstanceSpec, err := rdsBroker.GetInstance(ctx, instanceID, fetchInstanceDetails)
+				Expect(err).ToNot(HaveOccurred())
+
+				parameters := getInstanceSpec.Parameters.(map[string]interface{})
+				Expect(parameters).To(HaveKeyWithValue("allowed_extensions", []string{"pg_surgery"}))
+			})
+		})
+
+		Context("when the service instance has been renamed", func() {
+			BeforeEach(func() {
+				defaultDBInstanceTagsByName["Instance Name"] = "orders-db"
+				defaultDBInstanceTagsByName["Previous Instance Names"] = "shop-db/orders-db-old"
+			})
+
+			It("returns the current and previous names", func() {
+				getBindingSpec, err := rdsBroker.GetInstance(ctx, instanceID, fetchInstanceDetails)
+				Expect(err).ToNot(HaveOccurred())
+
+				parameters, ok := getBindingSpec.Parameters.(map[string]interface{})
+				Expect(ok).To(BeTrue())
+				Expect(parameters).To(HaveKeyWithValue("instance_name", "orders-db"))
+				Expect(parameters).To(HaveKeyWithValue("previous_instance_names", []string{"shop-db", "orders-db-old"}))
+				Expect(len(parameters)).To(Equal(22))
 			})
 		})
 	})
@@ -3084,14 +5899,14 @@ var _ = Describe("RDS Broker", func() {
 	Describe("CheckAndRotateCredentials", func() {
 		BeforeEach(func() {
 			sqlEngine = &sqlfake.FakeSQLEngine{}
-			sqlProvider.GetSQLEngineSQLEngine = sqlEngine
+			sqlProvider.GetSQLEngineReturns(sqlEngine, nil)
 		})
 
 		Context("when there is no DB instance", func() {
 			It("shouldn't try to connect to databases", func() {
 				rdsBroker.CheckAndRotateCredentials()
-				Expect(sqlProvider.GetSQLEngineCalled).To(BeFalse())
-				Expect(sqlEngine.OpenCalled).To(BeFalse())
+				Expect(sqlProvider.GetSQLEngineCallCount()).To(BeZero())
+				Expect(sqlEngine.OpenCallCount()).To(BeZero())
 			})
 		})
 
@@ -3117,13 +5932,14 @@ var _ = Describe("RDS Broker", func() {
 				Expect(value).To(BeEquivalentTo(brokerName))
 				Expect(opts).To(ContainElement(awsrds.DescribeUseCachedOption))
 
-				Expect(sqlProvider.GetSQLEngineCalled).To(BeTrue())
-				Expect(sqlProvider.GetSQLEngineEngine).To(BeEquivalentTo("fake-engine"))
-				Expect(sqlEngine.OpenCalled).To(BeTrue())
-				Expect(sqlEngine.OpenAddress).To(BeEquivalentTo("endpoint-address"))
-				Expect(sqlEngine.OpenPort).To(BeEquivalentTo(3306))
-				Expect(sqlEngine.OpenDBName).To(BeEquivalentTo("test-db"))
-				Expect(sqlEngine.OpenUsername).To(BeEquivalentTo("master-username"))
+				Expect(sqlProvider.GetSQLEngineCallCount()).ToNot(BeZero())
+				Expect(sqlProvider.GetSQLEngineArgsForCall(0)).To(BeEquivalentTo("fake-engine"))
+				Expect(sqlEngine.OpenCallCount()).ToNot(BeZero())
+				address, port, dbname, username, _ := sqlEngine.OpenArgsForCall(0)
+				Expect(address).To(BeEquivalentTo("endpoint-address"))
+				Expect(port).To(BeEquivalentTo(3306))
+				Expect(dbname).To(BeEquivalentTo("test-db"))
+				Expect(username).To(BeEquivalentTo("master-username"))
 			})
 
 			Context("and the passwords work", func() {
@@ -3135,7 +5951,7 @@ var _ = Describe("RDS Broker", func() {
 
 			Context("and the passwords don't work", func() {
 				BeforeEach(func() {
-					sqlEngine.OpenError = sqlengine.LoginFailedError
+					sqlEngine.OpenReturns(sqlengine.LoginFailedError)
 				})
 
 				It("should try to change the master password", func() {
@@ -3144,13 +5960,49 @@ var _ = Describe("RDS Broker", func() {
 					input := rdsInstance.ModifyArgsForCall(0)
 
 					Expect(aws.StringValue(input.DBInstanceIdentifier)).To(BeEquivalentTo(dbInstanceIdentifier))
-					Expect(aws.StringValue(input.MasterUserPassword)).To(BeEquivalentTo(sqlEngine.OpenPassword))
+					_, _, _, _, password := sqlEngine.OpenArgsForCall(0)
+					Expect(aws.StringValue(input.MasterUserPassword)).To(BeEquivalentTo(password))
+				})
+
+				It("asks bindings made while the new password is applied to be retried", func() {
+					rdsInstance.ModifyStub = func(input *rds.ModifyDBInstanceInput) (*rds.DBInstance, error) {
+						rdsInstance.DescribeReturns(&rds.DBInstance{
+							DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
+							DBInstanceStatus:     aws.String("available"),
+							PendingModifiedValues: &rds.PendingModifiedValues{
+								MasterUserPassword: aws.String("****"),
+							},
+						}, nil)
+						return &rds.DBInstance{}, nil
+					}
+					rdsBroker.CheckAndRotateCredentials()
+					Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
+
+					openCallCount := sqlEngine.OpenCallCount()
+					_, err := rdsBroker.Bind(ctx, instanceID, bindingID, domain.BindDetails{
+						ServiceID: "Service-1",
+						PlanID:    "Plan-1",
+					}, false)
+					Expect(err).To(Equal(apiresponses.ErrConcurrentInstanceAccess))
+					Expect(sqlEngine.OpenCallCount()).To(Equal(openCallCount))
+				})
+
+				It("does not change the password again while the new one is applied", func() {
+					rdsInstance.DescribeByTagReturns([]*rds.DBInstance{{
+						DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
+						DBInstanceStatus:     aws.String("resetting-master-credentials"),
+						Engine:               aws.String("fake-engine"),
+					}}, nil)
+
+					rdsBroker.CheckAndRotateCredentials()
+					Expect(sqlEngine.OpenCallCount()).To(BeZero())
+					Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
 				})
 			})
 
 			Context("and there is an unkown open error", func() {
 				BeforeEach(func() {
-					sqlEngine.OpenError = errors.New("Unknown open connection error")
+					sqlEngine.OpenReturns(errors.New("Unknown open connection error"))
 				})
 
 				It("should not try to change the master password", func() {
@@ -3200,20 +6052,22 @@ var _ = Describe("RDS Broker", func() {
 			})
 
 			It("the new password and the password used in bind are the same", func() {
-				sqlEngine.OpenError = sqlengine.LoginFailedError
+				sqlEngine.OpenReturns(sqlengine.LoginFailedError)
 				rdsBroker.CheckAndRotateCredentials()
-				expectedMasterPassword := sqlEngine.OpenPassword
+				_, _, _, _, password := sqlEngine.OpenArgsForCall(0)
+				expectedMasterPassword := password
 
 				Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
 				input := rdsInstance.ModifyArgsForCall(0)
 				Expect(aws.StringValue(input.MasterUserPassword)).To(BeEquivalentTo(expectedMasterPassword))
 
-				sqlEngine.OpenError = nil
+				sqlEngine.OpenReturns(nil)
 				_, err := rdsBroker.Bind(ctx, instanceID, bindingID, bindDetails, false)
 				Expect(err).ToNot(HaveOccurred())
-				Expect(sqlEngine.OpenCalled).To(BeTrue())
+				Expect(sqlEngine.OpenCallCount()).ToNot(BeZero())
 
-				Expect(sqlEngine.OpenPassword).To(BeEquivalentTo(expectedMasterPassword))
+				_, _, _, _, bindPassword := sqlEngine.OpenArgsForCall(sqlEngine.OpenCallCount() - 1)
+				Expect(bindPassword).To(BeEquivalentTo(expectedMasterPassword))
 			})
 		})
 	})
@@ -1,8 +1,14 @@
 package rdsbroker
 
 import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
 )
 
 var _ = Describe("RDS Broker internals", func() {
@@ -49,4 +55,74 @@ var _ = Describe("RDS Broker internals", func() {
 			Expect(actual).To(Equal("fc051869-696b-4031-a290-8f45588f308c"))
 		})
 	})
+
+	Describe("removeTag", func() {
+		var rdsInstance *rdsfake.FakeRDSInstance
+
+		BeforeEach(func() {
+			rdsInstance = &rdsfake.FakeRDSInstance{}
+			broker.dbInstance = rdsInstance
+			broker.logger = lagertest.NewTestLogger("broker_unit_test")
+			broker.pendingTagWrites = newPendingTagWrites()
+
+			rdsInstance.AddTagsToResourceReturns(errors.New("throttled"))
+			broker.writeTags("instance-id", "arn:aws:rds:rds-region:123456789012:db:cf-instance-id", map[string]string{
+				"Plan ID":    "Plan-1",
+				"Extensions": "postgis",
+			})
+		})
+
+		It("drops the removed tag from a queued write", func() {
+			Expect(broker.removeTag("cf-instance-id", "Extensions")).To(Succeed())
+			Expect(rdsInstance.RemoveTagCallCount()).To(Equal(1))
+
+			tagWrites, err := broker.queuedTagWrites()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tagWrites).To(HaveLen(1))
+			Expect(tagWrites[0].Tags).To(Equal(map[string]string{"Plan ID": "Plan-1"}))
+		})
+
+		It("forgets a queued write with no tags left", func() {
+			Expect(broker.removeTag("cf-instance-id", "Extensions")).To(Succeed())
+			Expect(broker.removeTag("cf-instance-id", "Plan ID")).To(Succeed())
+
+			tagWrites, err := broker.queuedTagWrites()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tagWrites).To(BeEmpty())
+		})
+
+		It("leaves the queued writes of other instances alone", func() {
+			Expect(broker.removeTag("cf-other-instance-id", "Extensions")).To(Succeed())
+
+			tagWrites, err := broker.queuedTagWrites()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tagWrites[0].Tags).To(HaveKey("Extensions"))
+		})
+	})
+	Describe("maintenanceWindowOpen", func() {
+		// a Tuesday
+		now := time.Date(2024, 1, 2, 3, 30, 0, 0, time.UTC)
+
+		It("is open within the window", func() {
+			Expect(maintenanceWindowOpen("tue:03:00-tue:04:00", now)).To(BeTrue())
+			Expect(maintenanceWindowOpen("mon:23:00-tue:03:31", now)).To(BeTrue())
+		})
+
+		It("is closed outside the window", func() {
+			Expect(maintenanceWindowOpen("tue:03:31-tue:04:00", now)).To(BeFalse())
+			Expect(maintenanceWindowOpen("wed:03:00-wed:04:00", now)).To(BeFalse())
+		})
+
+		It("handles windows which wrap around the end of the week", func() {
+			Expect(maintenanceWindowOpen("sat:23:00-sun:01:00", time.Date(2024, 1, 7, 0, 30, 0, 0, time.UTC))).To(BeTrue())
+			Expect(maintenanceWindowOpen("sat:23:00-sun:01:00", now)).To(BeFalse())
+		})
+
+		It("rejects malformed windows", func() {
+			_, err := maintenanceWindowOpen("tuesday 3am", now)
+			Expect(err).To(HaveOccurred())
+			_, err = maintenanceWindowOpen("tue:25:00-tue:26:00", now)
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
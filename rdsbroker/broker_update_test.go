@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/pivotal-cf/brokerapi/v9/domain"
 	"github.com/pivotal-cf/brokerapi/v9/domain/apiresponses"
+	"github.com/pivotal-cf/brokerapi/v9/middlewares"
 
 	"github.com/alphagov/paas-rds-broker/awsrds"
 	"github.com/alphagov/paas-rds-broker/rdsbroker/fakes"
@@ -29,25 +31,31 @@ var _ = Describe("RDS Broker", func() {
 	var (
 		ctx context.Context
 
-		rdsProperties1                RDSProperties
-		rdsProperties2                RDSProperties
-		rdsProperties3                RDSProperties
-		rdsPropertiesPSQL10           RDSProperties
-		rdsPropertiesPSQL11           RDSProperties
-		rdsPropertiesPSQL12           RDSProperties
-		rdsPropertiesPSQL12LowStorage RDSProperties
-		plan1                         ServicePlan
-		plan2                         ServicePlan
-		plan3                         ServicePlan
-		planPSQL10                    ServicePlan
-		planPSQL11                    ServicePlan
-		planPSQL12                    ServicePlan
-		planPSQL12LowStorage          ServicePlan
-		service1                      Service
-		service2                      Service
-		service3                      Service
-		servicePSQL                   Service
-		catalog                       Catalog
+		rdsProperties1                  RDSProperties
+		rdsProperties2                  RDSProperties
+		rdsProperties3                  RDSProperties
+		rdsPropertiesPSQL10             RDSProperties
+		rdsPropertiesPSQL11             RDSProperties
+		rdsPropertiesPSQL12             RDSProperties
+		rdsPropertiesPSQL12LowStorage   RDSProperties
+		plan1                           ServicePlan
+		plan2                           ServicePlan
+		plan3                           ServicePlan
+		plan2Deprecated                 bool
+		plan2TrialDays                  uint
+		plan2SLATier                    string
+		plan2AllowUserSkipFinalSnapshot *bool
+		plan2AllowPublicAccess          bool
+		plan2AllowAutoMinorOptOut       bool
+		planPSQL10                      ServicePlan
+		planPSQL11                      ServicePlan
+		planPSQL12                      ServicePlan
+		planPSQL12LowStorage            ServicePlan
+		service1                        Service
+		service2                        Service
+		service3                        Service
+		servicePSQL                     Service
+		catalog                         Catalog
 
 		config Config
 
@@ -66,6 +74,7 @@ var _ = Describe("RDS Broker", func() {
 		allowUserProvisionParameters bool
 		allowUserUpdateParameters    bool
 		allowUserBindParameters      bool
+		confirmClassDowngrades       bool
 		planUpdateable               bool
 		skipFinalSnapshot            bool
 		dbPrefix                     string
@@ -90,8 +99,15 @@ var _ = Describe("RDS Broker", func() {
 
 		allowUserProvisionParameters = true
 		allowUserUpdateParameters = true
+		confirmClassDowngrades = false
 		allowUserBindParameters = true
 		planUpdateable = true
+		plan2Deprecated = false
+		plan2TrialDays = 0
+		plan2SLATier = ""
+		plan2AllowUserSkipFinalSnapshot = nil
+		plan2AllowPublicAccess = false
+		plan2AllowAutoMinorOptOut = false
 		skipFinalSnapshot = true
 		dbPrefix = "cf"
 		brokerName = "mybroker"
@@ -101,7 +117,7 @@ var _ = Describe("RDS Broker", func() {
 
 		sqlProvider = &sqlfake.FakeProvider{}
 		sqlEngine = &sqlfake.FakeSQLEngine{}
-		sqlProvider.GetSQLEngineSQLEngine = sqlEngine
+		sqlProvider.GetSQLEngineReturns(sqlEngine, nil)
 
 		rdsProperties1 = RDSProperties{
 			DBInstanceClass:   stringPointer("db.m1.test"),
@@ -228,6 +244,14 @@ var _ = Describe("RDS Broker", func() {
 			Name:          "Plan 2",
 			Description:   "This is the Plan 2",
 			RDSProperties: rdsProperties2,
+			Deprecated:    plan2Deprecated,
+			TrialDays:     plan2TrialDays,
+			SLATier:       plan2SLATier,
+
+			AllowUserSkipFinalSnapshot: plan2AllowUserSkipFinalSnapshot,
+			AllowPublicAccess:          plan2AllowPublicAccess,
+
+			AllowAutoMinorVersionUpgradeOptOut: plan2AllowAutoMinorOptOut,
 		}
 		plan3 = ServicePlan{
 			ID:            "Plan-3",
@@ -314,7 +338,22 @@ var _ = Describe("RDS Broker", func() {
 			AllowUserProvisionParameters: allowUserProvisionParameters,
 			AllowUserUpdateParameters:    allowUserUpdateParameters,
 			AllowUserBindParameters:      allowUserBindParameters,
-			Catalog:                      catalog,
+
+			ConfirmInstanceClassDowngrades: confirmClassDowngrades,
+			SecurityGroupSets: map[string][]string{
+				"default":           {"sg-default"},
+				"restricted-egress": {"sg-restricted-1", "sg-restricted-2"},
+			},
+			OrganizationSecurityGroupSets: map[string][]string{
+				"organization-id": {"restricted-egress"},
+			},
+			NetworkTiers: map[string]NetworkTier{
+				"isolated": {
+					DBSubnetGroupName:   "isolated-subnets",
+					VpcSecurityGroupIds: []string{"sg-isolated"},
+				},
+			},
+			Catalog: catalog,
 		}
 
 		logger = lager.NewLogger("rdsbroker_test")
@@ -325,6 +364,7 @@ var _ = Describe("RDS Broker", func() {
 
 		paramGroupSelector = fakes.FakeParameterGroupSelector{}
 		paramGroupSelector.SelectParameterGroupReturns(newParamGroupName, nil)
+		paramGroupSelector.ParameterGroupNameReturns(newParamGroupName, nil)
 
 		rdsBroker = New(config, rdsInstance, sqlProvider, &paramGroupSelector, logger)
 
@@ -412,6 +452,88 @@ var _ = Describe("RDS Broker", func() {
 			Expect(tagsByName).To(HaveKeyWithValue("Service ID", "Service-2"))
 			Expect(tagsByName).To(HaveKeyWithValue("Plan ID", "Plan-2"))
 			Expect(tagsByName).To(HaveKeyWithValue("chargeable_entity", instanceID))
+			Expect(tagsByName).ToNot(HaveKey("Updated by user"))
+			Expect(tagsByName).ToNot(HaveKey("Instance Name"))
+		})
+
+		Context("when the request context has an instance name", func() {
+			BeforeEach(func() {
+				updateDetails.RawContext = json.RawMessage(`{"platform": "cloudfoundry", "instance_name": "orders-db"}`)
+			})
+
+			It("records the name in the tags", func() {
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+				tagsByName := awsrds.RDSTagsValues(tags)
+				Expect(tagsByName).To(HaveKeyWithValue("Instance Name", "orders-db"))
+				Expect(tagsByName).ToNot(HaveKey("Previous Instance Names"))
+			})
+
+			Context("and the instance had a different name", func() {
+				JustBeforeEach(func() {
+					rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+						"Instance Name":           "orders-db-old",
+						"Previous Instance Names": "shop-db",
+					}), nil)
+				})
+
+				It("adds the old name to the previous names", func() {
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).ToNot(HaveOccurred())
+
+					_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+					tagsByName := awsrds.RDSTagsValues(tags)
+					Expect(tagsByName).To(HaveKeyWithValue("Instance Name", "orders-db"))
+					Expect(tagsByName).To(HaveKeyWithValue("Previous Instance Names", "shop-db/orders-db-old"))
+				})
+			})
+
+			Context("and the name has characters RDS doesn't allow in tags", func() {
+				BeforeEach(func() {
+					updateDetails.RawContext = json.RawMessage(`{"instance_name": "orders/db (eu)"}`)
+				})
+
+				It("replaces them", func() {
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).ToNot(HaveOccurred())
+
+					_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+					Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue("Instance Name", "orders_db _eu_"))
+				})
+			})
+		})
+
+		Context("when the request has an originating identity", func() {
+			BeforeEach(func() {
+				// {"user_id":"683ea748-3092-4ff4-b656-39cacc4d5360"}
+				ctx = context.WithValue(ctx, middlewares.OriginatingIdentityKey,
+					"cloudfoundry eyJ1c2VyX2lkIjoiNjgzZWE3NDgtMzA5Mi00ZmY0LWI2NTYtMzljYWNjNGQ1MzYwIn0=")
+			})
+
+			It("records the user in the tags", func() {
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+				_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+				Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue("Updated by user", "683ea748-3092-4ff4-b656-39cacc4d5360"))
+			})
+
+			It("records the user in the audit log", func() {
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(testSink.Logs()).To(ContainElement(And(
+					HaveField("Message", "rdsbroker_test.broker.audit"),
+					HaveField("Data", And(
+						HaveKeyWithValue("operation", "update"),
+						HaveKeyWithValue("platform", "cloudfoundry"),
+						HaveKeyWithValue("user-id", "683ea748-3092-4ff4-b656-39cacc4d5360"),
+					)),
+				)))
+			})
 		})
 
 		Context("when custom update parameters are not provided", func() {
@@ -550,6 +672,61 @@ var _ = Describe("RDS Broker", func() {
 			})
 		})
 
+		Context("when an earlier modification is still being applied", func() {
+			It("refuses to stack another on top of it", func() {
+				existingDbInstance.DBInstanceStatus = aws.String("modifying")
+
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).To(MatchError("DB Instance '" + dbInstanceIdentifier + "' is still applying an earlier modification: it is modifying. Try the update again once it is available with no pending changes"))
+				failureResponse, ok := err.(*apiresponses.FailureResponse)
+				Expect(ok).To(BeTrue())
+				Expect(failureResponse.ValidatedStatusCode(nil)).To(Equal(http.StatusConflict))
+				Expect(ErrorCode(err)).To(Equal("ConcurrencyError"))
+
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+			})
+
+			It("lists the pending changes without their values", func() {
+				existingDbInstance.DBInstanceStatus = aws.String("available")
+				existingDbInstance.PendingModifiedValues = &rds.PendingModifiedValues{
+					DBInstanceClass:    aws.String("db.m2.test"),
+					MasterUserPassword: aws.String("secret"),
+				}
+
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).To(MatchError(ContainSubstring("it has pending changes to DBInstanceClass, MasterUserPassword.")))
+				Expect(err.Error()).ToNot(ContainSubstring("secret"))
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+			})
+
+			It("still allows previews", func() {
+				existingDbInstance.DBInstanceStatus = aws.String("modifying")
+				existingDbInstance.DBInstanceClass = aws.String("db.m1.test")
+				existingDbInstance.AllocatedStorage = aws.Int64(100)
+				existingDbInstance.MultiAZ = aws.Bool(false)
+				updateDetails.RawParameters = json.RawMessage(`{"preview": true}`)
+
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("when the instance is stopped", func() {
+			It("starts it and asks for the update to be tried again", func() {
+				existingDbInstance.DBInstanceStatus = aws.String("stopped")
+
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).To(MatchError("DB Instance '" + dbInstanceIdentifier + "' was stopped and is being started. Try the update again once it is available."))
+				failureResponse, ok := err.(*apiresponses.FailureResponse)
+				Expect(ok).To(BeTrue())
+				Expect(failureResponse.ValidatedStatusCode(nil)).To(Equal(http.StatusUnprocessableEntity))
+
+				Expect(rdsInstance.StartCallCount()).To(Equal(1))
+				Expect(rdsInstance.StartArgsForCall(0)).To(Equal(dbInstanceIdentifier))
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+			})
+		})
+
 		Context("when has CopyTagsToSnapshot", func() {
 			BeforeEach(func() {
 				rdsProperties2.CopyTagsToSnapshot = boolPointer(true)
@@ -813,6 +990,33 @@ var _ = Describe("RDS Broker", func() {
 				Expect(tagValues).To(HaveKey("SkipFinalSnapshot"))
 				Expect(tagValues).To(HaveKeyWithValue("SkipFinalSnapshot", "true"))
 			})
+
+			It("tags the instance with an hour without deprovision protection if the user requests it", func() {
+				updateDetails.RawParameters = json.RawMessage(`{"lift_deprovision_protection": true}`)
+
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+				_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+				liftedUntil, err := time.Parse(time.RFC3339, awsrds.RDSTagsValues(tags)["Deprovision Protection Lifted Until"])
+				Expect(err).ToNot(HaveOccurred())
+				Expect(liftedUntil).To(BeTemporally("~", time.Now().Add(time.Hour), time.Minute))
+			})
+
+			Context("when the plan doesn't allow users to skip the final snapshot", func() {
+				BeforeEach(func() {
+					plan2AllowUserSkipFinalSnapshot = boolPointer(false)
+				})
+
+				It("refuses to skip it", func() {
+					updateDetails.RawParameters = json.RawMessage(`{"skip_final_snapshot": true}`)
+
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).To(MatchError("Service Plan 'Plan 2' does not allow skip_final_snapshot to be set to true"))
+					Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+				})
+			})
 		})
 
 		Context("when has PubliclyAccessible", func() {
@@ -829,149 +1033,867 @@ var _ = Describe("RDS Broker", func() {
 			})
 		})
 
-		Context("when has StorageType", func() {
+		Context("when the user opts out of automatic minor version upgrades", func() {
 			BeforeEach(func() {
-				rdsProperties2.StorageType = stringPointer("test-storage-type")
+				rdsProperties2.AutoMinorVersionUpgrade = boolPointer(true)
+				updateDetails.RawParameters = json.RawMessage(`{"auto_minor_version_upgrade": false}`)
 			})
 
-			It("makes the proper calls", func() {
+			It("refuses if the plan doesn't allow opting out", func() {
 				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
-				Expect(err).ToNot(HaveOccurred())
-				Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
-				input := rdsInstance.ModifyArgsForCall(0)
-				Expect(aws.StringValue(input.StorageType)).To(Equal("test-storage-type"))
+				Expect(err).To(MatchError("Service Plan 'Plan 2' does not allow auto_minor_version_upgrade to be set to false"))
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+			})
+
+			Context("and the plan allows opting out", func() {
+				BeforeEach(func() {
+					plan2AllowAutoMinorOptOut = true
+				})
+
+				It("turns off automatic minor version upgrades and records the choice", func() {
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).ToNot(HaveOccurred())
+					input := rdsInstance.ModifyArgsForCall(0)
+					Expect(input.AutoMinorVersionUpgrade).To(Equal(aws.Bool(false)))
+
+					_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+					Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue(awsrds.TagAutoMinorUpgrade, "false"))
+				})
+
+				It("keeps the choice through later updates", func() {
+					updateDetails.RawParameters = json.RawMessage(`{}`)
+					rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+						awsrds.TagAutoMinorUpgrade: "false",
+					}), nil)
+
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).ToNot(HaveOccurred())
+					input := rdsInstance.ModifyArgsForCall(0)
+					Expect(input.AutoMinorVersionUpgrade).To(Equal(aws.Bool(false)))
+				})
 			})
 		})
 
-		Context("when has VpcSecurityGroupIds", func() {
+		Context("when the user schedules changes for the maintenance window", func() {
 			BeforeEach(func() {
-				rdsProperties2.VpcSecurityGroupIds = []*string{stringPointer("test-vpc-security-group-ids")}
+				rdsProperties2.EngineVersion = stringPointer("1.2.3")
+				updateDetails.RawParameters = json.RawMessage(`{"schedule_at_maintenance_window": true}`)
 			})
 
-			It("makes the proper calls", func() {
-				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+			JustBeforeEach(func() {
+				existingDbInstance.DBInstanceClass = aws.String("db.m1.test")
+			})
+
+			It("holds back the instance class change and records the choice", func() {
+				updateServiceSpec, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
 				Expect(err).ToNot(HaveOccurred())
-				Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
+				Expect(updateServiceSpec.IsAsync).To(BeTrue())
+
 				input := rdsInstance.ModifyArgsForCall(0)
-				Expect(input.VpcSecurityGroupIds).To(Equal(
-					[]*string{stringPointer("test-vpc-security-group-ids")},
-				))
-			})
-		})
+				Expect(aws.StringValue(input.DBInstanceClass)).To(Equal("db.m1.test"))
+				Expect(aws.StringValue(input.DBParameterGroupName)).To(Equal("originalParameterGroupName"))
 
-		Context("when request does not accept incomplete", func() {
-			BeforeEach(func() {
-				acceptsIncomplete = false
+				_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+				Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue(awsrds.TagScheduleAtMaintenance, "true"))
+				Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue(awsrds.TagScheduledMaintenance, "modify"))
 			})
 
-			It("returns the proper error", func() {
-				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
-				Expect(err).To(HaveOccurred())
-				Expect(err).To(Equal(apiresponses.ErrAsyncRequired))
-			})
-		})
+			It("schedules a requested reboot rather than rebooting", func() {
+				updateDetails.PlanID = "Plan-1"
+				updateDetails.RawParameters = json.RawMessage(`{"schedule_at_maintenance_window": true, "reboot": true, "force_failover": true}`)
 
-		Context("when Parameters are not valid", func() {
-			It("returns an error", func() {
-				updateDetails.RawParameters = json.RawMessage(`not JSON`)
 				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
-				Expect(err).To(HaveOccurred())
-				Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rdsInstance.RebootCallCount()).To(Equal(0))
+
+				_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+				Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue(awsrds.TagScheduledMaintenance, "failover"))
 			})
 
-			Context("and user update parameters are not allowed", func() {
+			Context("when the parameter group is updated", func() {
 				BeforeEach(func() {
-					allowUserUpdateParameters = false
+					newParamGroupName = "updatedParamGroupName"
 				})
 
-				It("does not return an error", func() {
-					updateDetails.RawParameters = json.RawMessage(`not JSON`)
+				It("schedules the swap with the reboot and extensions it needs", func() {
+					updateDetails.PlanID = "Plan-1"
+					updateDetails.RawParameters = json.RawMessage(`{"schedule_at_maintenance_window": true, "enable_extensions": ["postgres_super_extension"]}`)
+
 					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
 					Expect(err).ToNot(HaveOccurred())
+
+					input := rdsInstance.ModifyArgsForCall(0)
+					Expect(aws.StringValue(input.DBParameterGroupName)).To(Equal("originalParameterGroupName"))
+
+					_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+					Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue(awsrds.TagScheduledMaintenance, "modify:reboot:create-extensions"))
 				})
 			})
 
-			It("returns an error for extra params", func() {
-				updateDetails.RawParameters = json.RawMessage(`{"foo": "bar"}`)
+			It("adds to the changes already scheduled", func() {
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+					awsrds.TagScheduleAtMaintenance: "true",
+					awsrds.TagScheduledMaintenance:  "reboot",
+				}), nil)
+				updateDetails.RawParameters = json.RawMessage(`{}`)
+
 				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
-				Expect(err).To(MatchError(ContainSubstring(`unknown field "foo"`)))
-				Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+				Expect(err).ToNot(HaveOccurred())
+
+				_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+				Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue(awsrds.TagScheduledMaintenance, "modify:reboot"))
+			})
+
+			Context("when the plan change upgrades the engine version", func() {
+				BeforeEach(func() {
+					rdsProperties2.EngineVersion = stringPointer("4.5.6")
+				})
+
+				It("refuses it", func() {
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).To(MatchError("Engine version upgrades can't be scheduled for the maintenance window. Please re-run update service with schedule_at_maintenance_window set to false"))
+					Expect(ErrorCode(err)).To(Equal(ErrCodeInvalidParameters))
+					Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+				})
 			})
 		})
 
-		Context("when Service is not found", func() {
+		Context("when the user asks for a publicly accessible instance", func() {
 			BeforeEach(func() {
-				updateDetails.ServiceID = "unknown"
+				updateDetails.RawParameters = json.RawMessage(`{"publicly_accessible": true}`)
 			})
 
-			It("returns the proper error", func() {
+			It("refuses if the plan doesn't allow public access", func() {
 				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal("Service 'unknown' not found"))
+				Expect(err).To(MatchError("Service Plan 'Plan 2' does not allow publicly_accessible to be set to true"))
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+			})
+
+			Context("and the plan allows public access", func() {
+				BeforeEach(func() {
+					plan2AllowPublicAccess = true
+				})
+
+				It("makes the instance publicly accessible and records the choice", func() {
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
+					input := rdsInstance.ModifyArgsForCall(0)
+					Expect(aws.BoolValue(input.PubliclyAccessible)).To(BeTrue())
+
+					_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+					Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue(awsrds.TagPubliclyAccessible, "true"))
+				})
 			})
 		})
 
-		Context("when Plans is not updateable", func() {
+		Context("when the user chose a publicly accessible instance before", func() {
 			BeforeEach(func() {
-				planUpdateable = false
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+					awsrds.TagPubliclyAccessible: "true",
+				}), nil)
 			})
 
-			It("returns the proper error", func() {
+			Context("and the plan allows public access", func() {
+				BeforeEach(func() {
+					plan2AllowPublicAccess = true
+				})
+
+				It("keeps it publicly accessible", func() {
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).ToNot(HaveOccurred())
+					input := rdsInstance.ModifyArgsForCall(0)
+					Expect(aws.BoolValue(input.PubliclyAccessible)).To(BeTrue())
+				})
+			})
+
+			It("falls back to the plan's setting once the plan doesn't allow it", func() {
 				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
-				Expect(err).To(HaveOccurred())
-				Expect(err).To(Equal(apiresponses.ErrPlanChangeNotSupported))
+				Expect(err).ToNot(HaveOccurred())
+				input := rdsInstance.ModifyArgsForCall(0)
+				Expect(input.PubliclyAccessible).To(BeNil())
 			})
 		})
 
-		Context("when the plan is currently on an older version of postgres than we actually are", func() {
+		Context("when has StorageType", func() {
 			BeforeEach(func() {
-				existingDbInstance.EngineVersion = stringPointer("11.6")
-
-				updateDetails.PlanID = planPSQL11.ID
-				updateDetails.ServiceID = servicePSQL.ID
-				updateDetails.PreviousValues = domain.PreviousValues{
-					PlanID:    planPSQL10.ID,
-					ServiceID: servicePSQL.ID,
-					OrgID:     updateDetails.PreviousValues.OrgID,
-					SpaceID:   updateDetails.PreviousValues.SpaceID,
-				}
+				rdsProperties2.StorageType = stringPointer("test-storage-type")
 			})
 
-			It("returns no error bringing the instance back on plan", func() {
-				rdsInstance.GetFullValidTargetVersionCalls(func(engine string, currentVersion string, targetVersionMoniker string) (string, error) {
-					return "", nil
-				})
+			It("makes the proper calls", func() {
 				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
-
 				Expect(err).ToNot(HaveOccurred())
 				Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
 				input := rdsInstance.ModifyArgsForCall(0)
-				Expect(aws.StringValue(input.EngineVersion)).To(Equal("11"))
-				Expect(rdsInstance.GetFullValidTargetVersionCallCount()).To(Equal(1))
+				Expect(aws.StringValue(input.StorageType)).To(Equal("test-storage-type"))
 			})
 		})
 
-		Context("when Service Plan is not found", func() {
+		Context("when has VpcSecurityGroupIds", func() {
 			BeforeEach(func() {
-				updateDetails.PlanID = "unknown"
+				rdsProperties2.VpcSecurityGroupIds = []*string{stringPointer("test-vpc-security-group-ids")}
 			})
 
-			It("returns the proper error", func() {
+			It("makes the proper calls", func() {
 				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal("Service Plan 'unknown' not found"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
+				input := rdsInstance.ModifyArgsForCall(0)
+				Expect(input.VpcSecurityGroupIds).To(Equal(
+					[]*string{stringPointer("test-vpc-security-group-ids")},
+				))
 			})
 		})
 
-		Context("when modifying the DB Instance fails", func() {
+		Context("when moving to a security group set", func() {
 			BeforeEach(func() {
-				rdsInstance.ModifyReturns(nil, errors.New("operation failed"))
+				rdsProperties2.VpcSecurityGroupIds = []*string{stringPointer("test-vpc-security-group-ids")}
+				updateDetails.RawParameters = json.RawMessage(`{"security_group_set": "restricted-egress"}`)
 			})
 
-			It("returns the proper error", func() {
-				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal("operation failed"))
+			JustBeforeEach(func() {
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+					"Organization ID": "organization-id",
+				}), nil)
+			})
+
+			It("uses the set's security groups instead of the plan's", func() {
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
+				input := rdsInstance.ModifyArgsForCall(0)
+				Expect(input.VpcSecurityGroupIds).To(Equal(
+					[]*string{stringPointer("sg-restricted-1"), stringPointer("sg-restricted-2")},
+				))
+			})
+
+			It("records the set in the tags", func() {
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+				_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+				Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue("Security Group Set", "restricted-egress"))
+			})
+
+			Context("and the set does not exist", func() {
+				BeforeEach(func() {
+					updateDetails.RawParameters = json.RawMessage(`{"security_group_set": "unknown"}`)
+				})
+
+				It("returns the proper error", func() {
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).To(MatchError("Unknown security group set 'unknown'"))
+					Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("and the set is not available to the instance's organization", func() {
+				BeforeEach(func() {
+					updateDetails.RawParameters = json.RawMessage(`{"security_group_set": "default"}`)
+				})
+
+				It("returns the proper error", func() {
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).To(MatchError("Security group set 'default' is not available to this service instance's organization"))
+					Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+				})
+			})
+		})
+
+		Context("when the instance is in a network tier", func() {
+			BeforeEach(func() {
+				rdsProperties2.DBSubnetGroupName = stringPointer("test-db-subnet-group-name")
+				rdsProperties2.VpcSecurityGroupIds = []*string{stringPointer("test-vpc-security-group-ids")}
+			})
+
+			JustBeforeEach(func() {
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+					"Network Tier":    "isolated",
+					"Organization ID": "organization-id",
+				}), nil)
+			})
+
+			It("keeps the tier's subnet group and security groups instead of the plan's", func() {
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
+				input := rdsInstance.ModifyArgsForCall(0)
+				Expect(aws.StringValue(input.DBSubnetGroupName)).To(Equal("isolated-subnets"))
+				Expect(input.VpcSecurityGroupIds).To(Equal([]*string{stringPointer("sg-isolated")}))
+			})
+
+			It("still lets the instance move to a security group set", func() {
+				updateDetails.RawParameters = json.RawMessage(`{"security_group_set": "restricted-egress"}`)
+
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+				input := rdsInstance.ModifyArgsForCall(0)
+				Expect(aws.StringValue(input.DBSubnetGroupName)).To(Equal("isolated-subnets"))
+				Expect(input.VpcSecurityGroupIds).To(Equal(
+					[]*string{stringPointer("sg-restricted-1"), stringPointer("sg-restricted-2")},
+				))
+			})
+		})
+
+		Context("when purging other databases", func() {
+			var existingTags map[string]string
+
+			BeforeEach(func() {
+				updateDetails.PlanID = "Plan-1"
+				updateDetails.ServiceID = "Service-1"
+				sqlEngine.ListOtherDatabasesReturns([]string{"unused_db", "other_db"}, nil)
+				existingTags = map[string]string{
+					"Restored From Snapshot": "cf-origin-instance-1",
+				}
+			})
+
+			JustBeforeEach(func() {
+				existingDbInstance.DBName = aws.String("restored_db")
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(existingTags), nil)
+			})
+
+			Context("as a dry run", func() {
+				BeforeEach(func() {
+					updateDetails.RawParameters = json.RawMessage(`{"purge_other_databases": "dry_run"}`)
+				})
+
+				It("records the databases which would be dropped without dropping them", func() {
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(sqlProvider.GetSQLEngineCallCount()).ToNot(BeZero())
+					_, _, dbname, _, _ := sqlEngine.OpenArgsForCall(0)
+					Expect(dbname).To(Equal("restored_db"))
+					Expect(droppedDatabases(sqlEngine)).To(BeEmpty())
+
+					_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+					Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue("Databases To Purge", "unused_db:other_db"))
+				})
+
+				It("leaves the instance's additional databases out", func() {
+					existingTags["Additional Databases"] = "other_db"
+					rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(existingTags), nil)
+
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).ToNot(HaveOccurred())
+
+					_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+					Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue("Databases To Purge", "unused_db"))
+				})
+
+				It("fails if there are no other databases", func() {
+					sqlEngine.ListOtherDatabasesReturns([]string{}, nil)
+
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).To(MatchError("There are no databases other than 'restored_db' to purge"))
+					Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+				})
+
+				It("fails if the instance wasn't restored", func() {
+					rdsInstance.GetResourceTagsReturns([]*rds.Tag{}, nil)
+
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).To(MatchError("purge_other_databases is only available on instances restored from another instance"))
+					Expect(sqlProvider.GetSQLEngineCallCount()).To(BeZero())
+				})
+			})
+
+			Context("when confirmed", func() {
+				BeforeEach(func() {
+					updateDetails.RawParameters = json.RawMessage(`{"purge_other_databases": "confirm"}`)
+				})
+
+				It("refuses to drop anything without a dry run first", func() {
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).To(MatchError(ContainSubstring("set to 'dry_run' first")))
+					Expect(droppedDatabases(sqlEngine)).To(BeEmpty())
+				})
+
+				Context("after a dry run", func() {
+					BeforeEach(func() {
+						existingTags["Databases To Purge"] = "unused_db:dropped_since_db"
+					})
+
+					It("drops the reported databases which still exist and removes the tag", func() {
+						_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(droppedDatabases(sqlEngine)).To(Equal([]string{"unused_db"}))
+
+						Expect(rdsInstance.RemoveTagCallCount()).To(Equal(1))
+						_, tagKey := rdsInstance.RemoveTagArgsForCall(0)
+						Expect(tagKey).To(Equal("Databases To Purge"))
+					})
+
+					It("returns the error if a database can't be dropped", func() {
+						sqlEngine.DropDatabaseReturns(errors.New("database is being accessed by other users"))
+
+						_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+						Expect(err).To(MatchError("database is being accessed by other users"))
+						Expect(rdsInstance.RemoveTagCallCount()).To(Equal(0))
+					})
+
+					It("only drops them once the instance has been modified", func() {
+						rdsInstance.ModifyStub = func(*rds.ModifyDBInstanceInput) (*rds.DBInstance, error) {
+							Expect(droppedDatabases(sqlEngine)).To(BeEmpty())
+							return existingDbInstance, nil
+						}
+
+						_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
+						Expect(droppedDatabases(sqlEngine)).To(Equal([]string{"unused_db"}))
+					})
+
+					It("drops nothing if the instance can't be modified", func() {
+						rdsInstance.ModifyReturns(nil, errors.New("operation failed"))
+
+						_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+						Expect(err).To(MatchError("operation failed"))
+						Expect(droppedDatabases(sqlEngine)).To(BeEmpty())
+						Expect(rdsInstance.RemoveTagCallCount()).To(Equal(0))
+					})
+
+					It("drops nothing if the rest of the update is invalid", func() {
+						updateDetails.RawParameters = json.RawMessage(`{"purge_other_databases": "confirm", "pgaudit_log": ["ddl"]}`)
+
+						_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+						Expect(err).To(MatchError("pgaudit_log can only be set when the pgaudit extension is enabled"))
+						Expect(droppedDatabases(sqlEngine)).To(BeEmpty())
+						Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+					})
+				})
+			})
+
+			It("can't be combined with a plan change", func() {
+				updateDetails.PlanID = "Plan-2"
+				updateDetails.ServiceID = "Service-2"
+				updateDetails.RawParameters = json.RawMessage(`{"purge_other_databases": "dry_run"}`)
+
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).To(MatchError("Invalid to purge other databases and update plan in the same command"))
+			})
+
+			It("rejects unknown modes", func() {
+				updateDetails.RawParameters = json.RawMessage(`{"purge_other_databases": "yes"}`)
+
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).To(MatchError("purge_other_databases must be 'dry_run' or 'confirm'"))
+			})
+		})
+
+		Context("when the instance is already in a security group set", func() {
+			BeforeEach(func() {
+				rdsProperties2.VpcSecurityGroupIds = []*string{stringPointer("test-vpc-security-group-ids")}
+			})
+
+			JustBeforeEach(func() {
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+					"Security Group Set": "default",
+				}), nil)
+			})
+
+			It("keeps using the set's security groups", func() {
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+				input := rdsInstance.ModifyArgsForCall(0)
+				Expect(input.VpcSecurityGroupIds).To(Equal([]*string{stringPointer("sg-default")}))
+
+				_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+				Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue("Security Group Set", "default"))
+			})
+		})
+
+		Context("when request does not accept incomplete", func() {
+			BeforeEach(func() {
+				acceptsIncomplete = false
+			})
+
+			It("returns the proper error", func() {
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).To(HaveOccurred())
+				Expect(err).To(Equal(apiresponses.ErrAsyncRequired))
+			})
+		})
+
+		Context("when Parameters are not valid", func() {
+			It("returns an error", func() {
+				updateDetails.RawParameters = json.RawMessage(`not JSON`)
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).To(HaveOccurred())
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+			})
+
+			Context("and user update parameters are not allowed", func() {
+				BeforeEach(func() {
+					allowUserUpdateParameters = false
+				})
+
+				It("does not return an error", func() {
+					updateDetails.RawParameters = json.RawMessage(`not JSON`)
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).ToNot(HaveOccurred())
+				})
+			})
+
+			It("returns an error for extra params", func() {
+				updateDetails.RawParameters = json.RawMessage(`{"foo": "bar"}`)
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).To(MatchError(ContainSubstring(`unknown field "foo"`)))
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when Service is not found", func() {
+			BeforeEach(func() {
+				updateDetails.ServiceID = "unknown"
+			})
+
+			It("returns the proper error", func() {
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Service 'unknown' not found"))
+			})
+		})
+
+		Context("when Plans is not updateable", func() {
+			BeforeEach(func() {
+				planUpdateable = false
+			})
+
+			It("returns the proper error", func() {
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).To(HaveOccurred())
+				Expect(err).To(Equal(apiresponses.ErrPlanChangeNotSupported))
+			})
+		})
+
+		Context("when the plan is currently on an older version of postgres than we actually are", func() {
+			BeforeEach(func() {
+				existingDbInstance.EngineVersion = stringPointer("11.6")
+
+				updateDetails.PlanID = planPSQL11.ID
+				updateDetails.ServiceID = servicePSQL.ID
+				updateDetails.PreviousValues = domain.PreviousValues{
+					PlanID:    planPSQL10.ID,
+					ServiceID: servicePSQL.ID,
+					OrgID:     updateDetails.PreviousValues.OrgID,
+					SpaceID:   updateDetails.PreviousValues.SpaceID,
+				}
+			})
+
+			It("returns no error bringing the instance back on plan", func() {
+				rdsInstance.GetFullValidTargetVersionCalls(func(engine string, currentVersion string, targetVersionMoniker string) (string, error) {
+					return "", nil
+				})
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
+				input := rdsInstance.ModifyArgsForCall(0)
+				Expect(aws.StringValue(input.EngineVersion)).To(Equal("11"))
+				Expect(rdsInstance.GetFullValidTargetVersionCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when Service Plan is not found", func() {
+			BeforeEach(func() {
+				updateDetails.PlanID = "unknown"
+			})
+
+			It("returns the proper error", func() {
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Service Plan 'unknown' not found"))
+			})
+		})
+
+		Context("when the previous values are missing", func() {
+			BeforeEach(func() {
+				updateDetails.PreviousValues = domain.PreviousValues{}
+			})
+
+			JustBeforeEach(func() {
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+					"Plan ID": "Plan-1",
+				}), nil)
+			})
+
+			It("falls back to the plan the instance is tagged with", func() {
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(rdsInstance.DescribeArgsForCall(0)).To(Equal(dbInstanceIdentifier))
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
+				input := rdsInstance.ModifyArgsForCall(0)
+				Expect(aws.StringValue(input.DBInstanceClass)).To(Equal("db.m2.test"))
+			})
+
+			It("returns an error if the instance isn't tagged with a plan", func() {
+				rdsInstance.GetResourceTagsReturns([]*rds.Tag{}, nil)
+
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).To(MatchError("Can't find plan id for this service instance"))
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+			})
+
+			It("returns the proper error if the instance doesn't exist", func() {
+				rdsInstance.DescribeReturns(nil, awsrds.ErrDBInstanceDoesNotExist)
+
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).To(Equal(apiresponses.ErrInstanceDoesNotExist))
+			})
+		})
+
+		Context("when the new plan has a smaller instance class", func() {
+			BeforeEach(func() {
+				rdsProperties1.DBInstanceClass = stringPointer("db.m5.xlarge")
+				rdsProperties2.DBInstanceClass = stringPointer("db.m5.large")
+				confirmClassDowngrades = true
+			})
+
+			It("refuses the downgrade, describing its impact", func() {
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).To(MatchError(
+					"Service Plan 'Plan-2' has a smaller instance class than 'Plan-1': db.m5.large has about 50% of the CPU and memory of db.m5.xlarge. " +
+						"Queries may be slower, less data will be cached, fewer connections may be allowed, and the DB instance will be rebooted to move it. " +
+						"Set the confirm_downgrade parameter to true to move it anyway",
+				))
+				Expect(ErrorCode(err)).To(Equal(ErrCodeInvalidPlanChange))
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+			})
+
+			It("downgrades when it is confirmed", func() {
+				updateDetails.RawParameters = json.RawMessage(`{"confirm_downgrade": true}`)
+
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
+				input := rdsInstance.ModifyArgsForCall(0)
+				Expect(aws.StringValue(input.DBInstanceClass)).To(Equal("db.m5.large"))
+			})
+
+			It("describes the impact in previews", func() {
+				updateDetails.RawParameters = json.RawMessage(`{"preview": true}`)
+				existingDbInstance.DBInstanceClass = aws.String("db.m5.xlarge")
+				existingDbInstance.AllocatedStorage = aws.Int64(100)
+				existingDbInstance.MultiAZ = aws.Bool(false)
+
+				updateServiceSpec, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+
+				lastOperation, err := rdsBroker.LastOperation(ctx, instanceID, domain.PollDetails{
+					OperationData: updateServiceSpec.OperationData,
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lastOperation.Description).To(HaveSuffix(
+					"Service Plan 'Plan-2' has a smaller instance class than 'Plan-1': db.m5.large has about 50% of the CPU and memory of db.m5.xlarge. " +
+						"Queries may be slower, less data will be cached, fewer connections may be allowed, and the DB instance will be rebooted to move it",
+				))
+			})
+
+			Context("and the price table has both instance classes", func() {
+				BeforeEach(func() {
+					rdsProperties1.DBInstanceClass = stringPointer("db.r5.large")
+					rdsProperties2.DBInstanceClass = stringPointer("db.m5.large")
+				})
+
+				JustBeforeEach(func() {
+					config.PriceTable = &PriceTable{
+						Currency:            "USD",
+						InstanceClassHourly: map[string]float64{"db.r5.large": 0.25, "db.m5.large": 0.2},
+					}
+					rdsBroker = New(config, rdsInstance, sqlProvider, &paramGroupSelector, logger)
+				})
+
+				It("compares their prices", func() {
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).To(MatchError(ContainSubstring("db.m5.large has about 80% of the CPU and memory of db.r5.large")))
+				})
+			})
+
+			Context("and downgrades don't need confirming", func() {
+				BeforeEach(func() {
+					confirmClassDowngrades = false
+				})
+
+				It("downgrades", func() {
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
+				})
+			})
+		})
+
+		Context("when a preview is asked for", func() {
+			BeforeEach(func() {
+				updateDetails.RawParameters = json.RawMessage(`{"preview": true}`)
+			})
+
+			JustBeforeEach(func() {
+				existingDbInstance.DBInstanceClass = aws.String("db.m1.test")
+				existingDbInstance.AllocatedStorage = aws.Int64(100)
+				existingDbInstance.MultiAZ = aws.Bool(false)
+			})
+
+			It("describes the changes in the last operation without making them", func() {
+				updateServiceSpec, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(updateServiceSpec.IsAsync).To(BeTrue())
+
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+				Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(0))
+				Expect(paramGroupSelector.SelectParameterGroupCallCount()).To(Equal(0))
+				Expect(paramGroupSelector.ParameterGroupNameCallCount()).To(Equal(1))
+
+				rdsInstance.DescribeReturns(nil, errors.New("not expected to be called"))
+				lastOperation, err := rdsBroker.LastOperation(ctx, instanceID, domain.PollDetails{
+					OperationData: updateServiceSpec.OperationData,
+				})
+				Expect(err).ToNot(HaveOccurred())
+				// the plan change didn't happen, which the platform only
+				// keeps track of if the operation fails
+				Expect(lastOperation.State).To(Equal(domain.Failed))
+				Expect(lastOperation.Description).To(Equal(
+					"Preview only, nothing has been changed: the update would change " +
+						"allocated storage from 100 GB to 200 GB, " +
+						"instance class from db.m1.test to db.m2.test, " +
+						"engine version from 1.2.3 to 4.5.6. " +
+						"The DB instance would be rebooted, with some downtime. " +
+						"Estimated duration: about 70 minutes",
+				))
+			})
+
+			It("says when nothing would change, and succeeds when the plan stays the same", func() {
+				updateDetails.PlanID = "Plan-1"
+				updateDetails.ServiceID = "Service-1"
+
+				updateServiceSpec, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+
+				lastOperation, err := rdsBroker.LastOperation(ctx, instanceID, domain.PollDetails{
+					OperationData: updateServiceSpec.OperationData,
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lastOperation.State).To(Equal(domain.Succeeded))
+				Expect(lastOperation.Description).To(Equal("Preview only, nothing has been changed: the update would not change the DB instance"))
+			})
+
+			It("can't be combined with purging other databases", func() {
+				updateDetails.RawParameters = json.RawMessage(`{"preview": true, "purge_other_databases": "confirm"}`)
+
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).To(MatchError("preview can't be combined with purge_other_databases, use its dry_run instead"))
+			})
+		})
+
+		Context("when the new Service Plan is deprecated", func() {
+			BeforeEach(func() {
+				plan2Deprecated = true
+			})
+
+			It("returns the proper error", func() {
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).To(MatchError("Service Plan 'Plan-2' is deprecated"))
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+			})
+
+			It("still allows updates which stay on the plan", func() {
+				updateDetails.PreviousValues.PlanID = "Plan-2"
+				updateDetails.PreviousValues.ServiceID = "Service-2"
+
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("when the instance is on a trial plan", func() {
+			var trialExpires string
+
+			BeforeEach(func() {
+				trialExpires = time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+			})
+
+			JustBeforeEach(func() {
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+					"Trial Expires":       trialExpires,
+					"Trial Expiry Warned": "true",
+				}), nil)
+			})
+
+			It("lifts the trial when moving to a plan without one", func() {
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(rdsInstance.RemoveTagCallCount()).To(Equal(2))
+				_, tagKey := rdsInstance.RemoveTagArgsForCall(0)
+				Expect(tagKey).To(Equal("Trial Expires"))
+				_, tagKey = rdsInstance.RemoveTagArgsForCall(1)
+				Expect(tagKey).To(Equal("Trial Expiry Warned"))
+			})
+
+			Context("and the new plan is a trial plan too", func() {
+				BeforeEach(func() {
+					plan2TrialDays = 30
+				})
+
+				It("keeps the original expiry", func() {
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(rdsInstance.RemoveTagCallCount()).To(Equal(0))
+					_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+					Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue("Trial Expires", trialExpires))
+				})
+			})
+		})
+
+		Context("when the instance is tagged with the service level of its plan", func() {
+			JustBeforeEach(func() {
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+					"SLA Tier":      "gold",
+					"Support Level": "24x7",
+				}), nil)
+			})
+
+			It("removes the tags the new plan doesn't have", func() {
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(rdsInstance.RemoveTagCallCount()).To(Equal(2))
+				_, tagKey := rdsInstance.RemoveTagArgsForCall(0)
+				Expect(tagKey).To(Equal("SLA Tier"))
+				_, tagKey = rdsInstance.RemoveTagArgsForCall(1)
+				Expect(tagKey).To(Equal("Support Level"))
+			})
+
+			Context("and the new plan has a service level too", func() {
+				BeforeEach(func() {
+					plan2SLATier = "silver"
+				})
+
+				It("tags the new plan's service level", func() {
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(rdsInstance.RemoveTagCallCount()).To(Equal(1))
+					_, tagKey := rdsInstance.RemoveTagArgsForCall(0)
+					Expect(tagKey).To(Equal("Support Level"))
+					_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+					Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue("SLA Tier", "silver"))
+				})
+			})
+		})
+
+		Context("when modifying the DB Instance fails", func() {
+			BeforeEach(func() {
+				rdsInstance.ModifyReturns(nil, errors.New("operation failed"))
+			})
+
+			It("returns the proper error", func() {
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("operation failed"))
 			})
 
 			Context("when the DB Instance does not exists", func() {
@@ -1087,7 +2009,7 @@ var _ = Describe("RDS Broker", func() {
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(paramGroupSelector.SelectParameterGroupCallCount()).To(Equal(1))
-				servicePlan, _ := paramGroupSelector.SelectParameterGroupArgsForCall(0)
+				servicePlan, _, _ := paramGroupSelector.SelectParameterGroupArgsForCall(0)
 				Expect(servicePlan).To(Equal(plan2))
 
 				Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
@@ -1140,6 +2062,41 @@ var _ = Describe("RDS Broker", func() {
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(Equal(ErrCannotSkipMajorVersion.Error()))
 			})
+
+			It("marks the extensions to be updated after a major version upgrade", func() {
+				updateDetails.PlanID = planPSQL11.ID
+				updateDetails.ServiceID = servicePSQL.ID
+				updateDetails.PreviousValues = domain.PreviousValues{
+					PlanID:    planPSQL10.ID,
+					ServiceID: servicePSQL.ID,
+					OrgID:     updateDetails.PreviousValues.OrgID,
+					SpaceID:   updateDetails.PreviousValues.SpaceID,
+				}
+
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+				_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+				Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue("Pending Extension Update For", "11"))
+			})
+
+			It("doesn't mark the extensions to be updated when the major version stays the same", func() {
+				updateDetails.PlanID = planPSQL12.ID
+				updateDetails.ServiceID = servicePSQL.ID
+				updateDetails.PreviousValues = domain.PreviousValues{
+					PlanID:    planPSQL12.ID,
+					ServiceID: servicePSQL.ID,
+					OrgID:     updateDetails.PreviousValues.OrgID,
+					SpaceID:   updateDetails.PreviousValues.SpaceID,
+				}
+
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+				Expect(awsrds.RDSTagsValues(tags)).ToNot(HaveKey("Pending Extension Update For"))
+			})
 		})
 
 		Context("if an extension is in both enable_extensions and disable_extension", func() {
@@ -1168,7 +2125,10 @@ var _ = Describe("RDS Broker", func() {
 			It("returns the proper response", func() {
 				updateServiceSpec, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
 				Expect(err).ToNot(HaveOccurred())
-				Expect(updateServiceSpec).To(Equal(properUpdateServiceSpec))
+				Expect(updateServiceSpec).To(Equal(domain.UpdateServiceSpec{
+					IsAsync:       true,
+					OperationData: "reboot",
+				}))
 			})
 
 			It("makes the proper calls", func() {
@@ -1191,6 +2151,23 @@ var _ = Describe("RDS Broker", func() {
 				Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
 			})
 
+			It("records that the user asked for the reboot", func() {
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(2))
+				_, tags := rdsInstance.AddTagsToResourceArgsForCall(1)
+				Expect(awsrds.RDSTagsValues(tags)).To(Equal(map[string]string{awsrds.TagRebootReason: "user-requested"}))
+
+				Expect(testSink.Logs()).To(ContainElement(And(
+					HaveField("Message", "rdsbroker_test.broker.audit"),
+					HaveField("Data", And(
+						HaveKeyWithValue("operation", "reboot"),
+						HaveKeyWithValue("reason", "user-requested"),
+					)),
+				)))
+			})
+
 			It("fails if the reboot include a plan change", func() {
 				updateDetails.RawParameters = json.RawMessage(`{ "reboot": true, "force_failover": true }`)
 				updateDetails.PlanID = "Plan-2"
@@ -1202,6 +2179,147 @@ var _ = Describe("RDS Broker", func() {
 			})
 		})
 
+		Context("when adding databases", func() {
+			BeforeEach(func() {
+				updateDetails = domain.UpdateDetails{
+					ServiceID: "Service-1",
+					PlanID:    "Plan-1",
+					PreviousValues: domain.PreviousValues{
+						PlanID:    "Plan-1",
+						ServiceID: "Service-1",
+						OrgID:     "organization-id",
+						SpaceID:   "space-id",
+					},
+					RawParameters: json.RawMessage(`{"additional_databases": ["reports", "analytics"]}`),
+				}
+				rdsProperties1.Engine = stringPointer("postgres")
+			})
+
+			JustBeforeEach(func() {
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+					awsrds.TagAdditionalDatabases: "analytics",
+				}), nil)
+			})
+
+			It("records them alongside the existing ones", func() {
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+				Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue("Additional Databases", "analytics:reports"))
+			})
+
+			It("keeps the existing ones when none are asked for", func() {
+				updateDetails.RawParameters = json.RawMessage(`{}`)
+
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+				Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue("Additional Databases", "analytics"))
+			})
+
+			It("rejects databases listed more than once", func() {
+				updateDetails.RawParameters = json.RawMessage(`{"additional_databases": ["reports", "reports"]}`)
+
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).To(MatchError("additional_databases: 'reports' is listed more than once"))
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+			})
+
+			Context("when the plan isn't postgres", func() {
+				BeforeEach(func() {
+					rdsProperties1.Engine = stringPointer("test-engine-one")
+				})
+
+				It("returns an error", func() {
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).To(MatchError("additional_databases is only supported for postgres"))
+					Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+				})
+			})
+		})
+
+		Context("when setting pgaudit_log", func() {
+			var dbTags map[string]string
+
+			BeforeEach(func() {
+				updateDetails = domain.UpdateDetails{
+					ServiceID: "Service-1",
+					PlanID:    "Plan-1",
+					PreviousValues: domain.PreviousValues{
+						PlanID:    "Plan-1",
+						ServiceID: "Service-1",
+						OrgID:     "organization-id",
+						SpaceID:   "space-id",
+					},
+					RawParameters: json.RawMessage(`{"pgaudit_log": ["ddl", "role"], "reboot": true}`),
+				}
+				newParamGroupName = "pgauditParamGroupName"
+				rdsProperties1.AllowedExtensions = append(rdsProperties1.AllowedExtensions, stringPointer("pgaudit"))
+				dbTags = map[string]string{
+					awsrds.TagExtensions: "postgis:pg_stat_statements:pgaudit",
+				}
+			})
+
+			JustBeforeEach(func() {
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(dbTags), nil)
+			})
+
+			It("selects a parameter group logging those classes and records them", func() {
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, _, pgauditLog := paramGroupSelector.SelectParameterGroupArgsForCall(0)
+				Expect(pgauditLog).To(Equal([]string{"ddl", "role"}))
+
+				input := rdsInstance.ModifyArgsForCall(0)
+				Expect(aws.StringValue(input.DBParameterGroupName)).To(Equal("pgauditParamGroupName"))
+
+				_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+				Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue("Pgaudit Log", "ddl:role"))
+			})
+
+			It("requires a reboot if the parameter group changes", func() {
+				updateDetails.RawParameters = json.RawMessage(`{"pgaudit_log": ["ddl"]}`)
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).To(MatchError(ContainSubstring("Changing pgaudit_log requires the instance to be manually rebooted")))
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+			})
+
+			It("requires the pgaudit extension", func() {
+				dbTags[awsrds.TagExtensions] = "postgis:pg_stat_statements"
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(dbTags), nil)
+
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).To(MatchError("pgaudit_log can only be set when the pgaudit extension is enabled"))
+			})
+
+			It("rejects unknown classes", func() {
+				updateDetails.RawParameters = json.RawMessage(`{"pgaudit_log": ["everything"], "reboot": true}`)
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).To(MatchError(ContainSubstring("everything is not a valid pgaudit_log class")))
+			})
+
+			It("rejects combining none with other classes", func() {
+				updateDetails.RawParameters = json.RawMessage(`{"pgaudit_log": ["none", "ddl"], "reboot": true}`)
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).To(MatchError("pgaudit_log class none can't be combined with other classes"))
+			})
+
+			It("keeps the recorded classes when other settings are updated", func() {
+				dbTags["Pgaudit Log"] = "write"
+				rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(dbTags), nil)
+				updateDetails.RawParameters = json.RawMessage(`{}`)
+
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, _, pgauditLog := paramGroupSelector.SelectParameterGroupArgsForCall(0)
+				Expect(pgauditLog).To(Equal([]string{"write"}))
+			})
+		})
+
 		Context("when extension is added", func() {
 			BeforeEach(func() {
 				updateDetails = domain.UpdateDetails{
@@ -1232,7 +2350,7 @@ var _ = Describe("RDS Broker", func() {
 				Expect(aws.StringValue(input.DBParameterGroupName)).To(Equal(newParamGroupName))
 
 				Expect(paramGroupSelector.SelectParameterGroupCallCount()).To(Equal(1))
-				_, extensions := paramGroupSelector.SelectParameterGroupArgsForCall(0)
+				_, extensions, _ := paramGroupSelector.SelectParameterGroupArgsForCall(0)
 				Expect(extensions).To(ContainElement("postgres_super_extension"))
 				Expect(extensions).To(ContainElement("postgis"))
 				Expect(extensions).To(ContainElement("pg_stat_statements"))
@@ -1256,7 +2374,7 @@ var _ = Describe("RDS Broker", func() {
 					Value: aws.String("postgis:pg_stat_statements"),
 				}))
 
-				_, extensions := paramGroupSelector.SelectParameterGroupArgsForCall(0)
+				_, extensions, _ := paramGroupSelector.SelectParameterGroupArgsForCall(0)
 				Expect(extensions).To(HaveLen(2))
 			})
 
@@ -1266,6 +2384,68 @@ var _ = Describe("RDS Broker", func() {
 				Expect(err).To(MatchError("noext is not supported"))
 			})
 
+			Context("when the extension isn't available on the plan's postgres version", func() {
+				BeforeEach(func() {
+					rdsProperties1.Engine = stringPointer("postgres")
+					rdsProperties1.EngineVersion = stringPointer("10.4")
+					rdsProperties1.AllowedExtensions = append(rdsProperties1.AllowedExtensions, stringPointer("pg_cron"))
+				})
+
+				It("returns an error without modifying the instance", func() {
+					updateDetails.RawParameters = json.RawMessage(`{"enable_extensions": ["pg_cron"]}`)
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).To(MatchError("pg_cron is not available on postgres 10, it requires postgres 12 or later"))
+					Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the plan has extensions for the instance's major version", func() {
+				BeforeEach(func() {
+					rdsProperties1.Engine = stringPointer("postgres")
+					rdsProperties1.EngineVersion = stringPointer("13.4")
+					rdsProperties1.ExtensionsByMajorVersion = map[string]ExtensionLists{
+						"14": {
+							DefaultExtensions: []*string{stringPointer("pg_stat_statements")},
+							AllowedExtensions: []*string{stringPointer("pg_stat_statements"), stringPointer("pg_trgm")},
+						},
+					}
+				})
+
+				It("uses them once the instance has been upgraded past the plan's version", func() {
+					existingDbInstance.EngineVersion = aws.String("14.2")
+					updateDetails.RawParameters = json.RawMessage(`{"enable_extensions": ["pg_trgm"]}`)
+
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).ToNot(HaveOccurred())
+					_, extensions, _ := paramGroupSelector.SelectParameterGroupArgsForCall(0)
+					Expect(extensions).To(ContainElement("pg_trgm"))
+				})
+
+				It("refuses extensions they don't allow", func() {
+					existingDbInstance.EngineVersion = aws.String("14.2")
+					updateDetails.RawParameters = json.RawMessage(`{"enable_extensions": ["postgres_super_extension"]}`)
+
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).To(MatchError("postgres_super_extension is not supported"))
+				})
+
+				It("doesn't let their default extensions be disabled", func() {
+					existingDbInstance.EngineVersion = aws.String("14.2")
+					updateDetails.RawParameters = json.RawMessage(`{"disable_extensions": ["pg_stat_statements"]}`)
+
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).To(MatchError("pg_stat_statements cannot be disabled"))
+				})
+
+				It("uses the plan's own lists for other versions", func() {
+					existingDbInstance.EngineVersion = aws.String("13.4")
+					updateDetails.RawParameters = json.RawMessage(`{"enable_extensions": ["pg_trgm"]}`)
+
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).To(MatchError("pg_trgm is not supported"))
+				})
+			})
+
 			Context("when the parameter group is updated", func() {
 				BeforeEach(func() {
 					newParamGroupName = "updatedParamGroupName"
@@ -1317,7 +2497,7 @@ var _ = Describe("RDS Broker", func() {
 				Expect(aws.StringValue(input.DBParameterGroupName)).To(Equal(newParamGroupName))
 
 				Expect(paramGroupSelector.SelectParameterGroupCallCount()).To(Equal(1))
-				_, extensions := paramGroupSelector.SelectParameterGroupArgsForCall(0)
+				_, extensions, _ := paramGroupSelector.SelectParameterGroupArgsForCall(0)
 				Expect(extensions).ToNot(ContainElement("postgres_super_extension"))
 				Expect(extensions).To(ContainElement("postgis"))
 				Expect(extensions).To(ContainElement("pg_stat_statements"))
@@ -1330,6 +2510,57 @@ var _ = Describe("RDS Broker", func() {
 				}))
 				Expect(rdsInstance.RebootCallCount()).To(Equal(0))
 			})
+
+			Context("when the instance is postgres", func() {
+				JustBeforeEach(func() {
+					existingDbInstance.Engine = aws.String("postgres")
+				})
+
+				It("drops the extension without cascade when nothing depends on it", func() {
+					updateDetails.RawParameters = json.RawMessage(`{"disable_extensions": ["postgres_super_extension"], "reboot": true}`)
+					_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(sqlEngine.ExtensionDependentsCallCount()).ToNot(BeZero())
+					Expect(sqlEngine.DropExtensionsCallCount()).ToNot(BeZero())
+					_, cascade := sqlEngine.DropExtensionsArgsForCall(0)
+					Expect(cascade).To(BeFalse())
+				})
+
+				Context("when other objects depend on the extension", func() {
+					BeforeEach(func() {
+						sqlEngine.ExtensionDependentsReturns(map[string][]string{
+							"postgres_super_extension": {"column geom of table places", "view nearby_places"},
+						}, nil)
+					})
+
+					It("refuses to drop it and lists the dependent objects", func() {
+						updateDetails.RawParameters = json.RawMessage(`{"disable_extensions": ["postgres_super_extension"], "reboot": true}`)
+						_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+						Expect(err).To(MatchError("The extensions to disable have dependent objects, which would be dropped along with them: postgres_super_extension (column geom of table places, view nearby_places). Set force_drop_extensions to true to drop them anyway"))
+
+						Expect(sqlEngine.DropExtensionsCallCount()).To(BeZero())
+						Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+					})
+
+					It("drops it with cascade when force_drop_extensions is set", func() {
+						updateDetails.RawParameters = json.RawMessage(`{"disable_extensions": ["postgres_super_extension"], "force_drop_extensions": true, "reboot": true}`)
+						_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+						Expect(err).ToNot(HaveOccurred())
+
+						Expect(sqlEngine.DropExtensionsCallCount()).ToNot(BeZero())
+						_, cascade := sqlEngine.DropExtensionsArgsForCall(0)
+						Expect(cascade).To(BeTrue())
+						Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
+					})
+				})
+			})
+
+			It("doesn't accept force_drop_extensions without disable_extensions", func() {
+				updateDetails.RawParameters = json.RawMessage(`{"force_drop_extensions": true}`)
+				_, err := rdsBroker.Update(ctx, instanceID, updateDetails, acceptsIncomplete)
+				Expect(err).To(MatchError("force_drop_extensions can only be set along with disable_extensions"))
+			})
 		})
 
 		Context("when upgrade minor version to latest", func() {
@@ -7,12 +7,18 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
 	"github.com/pivotal-cf/brokerapi/v9"
 )
 
 const minAllocatedStorage = 5
 const maxAllocatedStorage = 6144
 
+// RDS for MySQL keeps binary logs for at most seven days
+const minBinlogRetentionHours = 1
+const maxBinlogRetentionHours = 168
+
 type Catalog struct {
 	Services       []Service `json:"services,omitempty"`
 	ExcludeEngines []Engine  `json:"exclude_engines"`
@@ -40,12 +46,52 @@ type Service struct {
 }
 
 type ServicePlan struct {
-	ID            string                         `json:"id"`
-	Name          string                         `json:"name"`
-	Description   string                         `json:"description"`
-	Free          *bool                          `json:"free,omitempty"`
-	Metadata      *brokerapi.ServicePlanMetadata `json:"metadata,omitempty"`
-	RDSProperties RDSProperties                  `json:"rds_properties,omitempty"`
+	ID                 string                         `json:"id"`
+	Name               string                         `json:"name"`
+	Description        string                         `json:"description"`
+	Free               *bool                          `json:"free,omitempty"`
+	Metadata           *brokerapi.ServicePlanMetadata `json:"metadata,omitempty"`
+	RDSProperties      RDSProperties                  `json:"rds_properties,omitempty"`
+	ConnectionPool     *ConnectionPool                `json:"connection_pool,omitempty"`
+	RDSProxy           *RDSProxy                      `json:"rds_proxy,omitempty"`
+	Deprecated         bool                           `json:"deprecated,omitempty"`
+	TrialDays          uint                           `json:"trial_days,omitempty"`
+	MaxInstanceAgeDays uint                           `json:"max_instance_age_days,omitempty"`
+	RestoreTest        bool                           `json:"restore_test,omitempty"`
+
+	// StatementTimeoutSeconds is how long statements of the users created
+	// by bindings can run for before they are cancelled, unless a binding
+	// asks for another limit. Only Postgres and MariaDB can limit users.
+	StatementTimeoutSeconds uint `json:"statement_timeout_seconds,omitempty"`
+
+	// SLATier and SupportLevel describe the service given to the plan's
+	// instances, for plans whose RDS properties are the same as others'.
+	// They are added to the plan's catalog metadata and tagged on its DB
+	// instances.
+	SLATier      string `json:"sla_tier,omitempty"`
+	SupportLevel string `json:"support_level,omitempty"`
+
+	// AllowUserSkipFinalSnapshot can be set to false to refuse the
+	// skip_final_snapshot parameter asking to skip the final snapshot.
+	AllowUserSkipFinalSnapshot *bool `json:"allow_user_skip_final_snapshot,omitempty"`
+
+	// AllowPublicAccess lets users ask for the plan's DB instances to be
+	// reachable from the internet with the publicly_accessible parameter.
+	// The plan's subnet groups must have public subnets for it to work.
+	AllowPublicAccess bool `json:"allow_public_access,omitempty"`
+
+	// AllowAutoMinorVersionUpgradeOptOut lets users turn off automatic minor
+	// version upgrades with the auto_minor_version_upgrade parameter, for
+	// applications which have to pin their version.
+	AllowAutoMinorVersionUpgradeOptOut bool `json:"allow_auto_minor_version_upgrade_opt_out,omitempty"`
+}
+
+// ConnectionPool is a connection pooler, such as pgbouncer, which fronts the
+// DB instances of a plan. Bindings can ask for its address in place of the
+// DB instance's.
+type ConnectionPool struct {
+	Host string `json:"host"`
+	Port int64  `json:"port"`
 }
 
 type RDSProperties struct {
@@ -57,6 +103,7 @@ type RDSProperties struct {
 	AutoMinorVersionUpgrade    *bool     `json:"auto_minor_version_upgrade,omitempty"`
 	AvailabilityZone           *string   `json:"availability_zone,omitempty"`
 	BackupRetentionPeriod      *int64    `json:"backup_retention_period,omitempty"`
+	BinlogRetentionHours       *int64    `json:"binlog_retention_hours,omitempty"`
 	CharacterSetName           *string   `json:"character_set_name,omitempty"`
 	DBSecurityGroups           []*string `json:"db_security_groups,omitempty"`
 	DBSubnetGroupName          *string   `json:"db_subnet_group_name,omitempty"`
@@ -76,6 +123,31 @@ type RDSProperties struct {
 	SkipFinalSnapshot          *bool     `json:"skip_final_snapshot,omitempty"`
 	DefaultExtensions          []*string `json:"default_extensions,omitempty"`
 	AllowedExtensions          []*string `json:"allowed_extensions"`
+
+	// ExtensionsByMajorVersion replaces the default and allowed extensions
+	// above for instances on the major versions it has entries for, such as
+	// "14", as instances of a plan can be upgraded past its engine version.
+	ExtensionsByMajorVersion map[string]ExtensionLists `json:"extensions_by_major_version,omitempty"`
+}
+
+// ExtensionLists are the extensions which can be enabled on an instance,
+// and those which always are.
+type ExtensionLists struct {
+	DefaultExtensions []*string `json:"default_extensions,omitempty"`
+	AllowedExtensions []*string `json:"allowed_extensions"`
+}
+
+// extensionLists returns the extensions of an instance on the engine
+// version, from the entry for its major version if there is one.
+func (rp RDSProperties) extensionLists(engineVersion string) ExtensionLists {
+	majorVersion := majorEngineVersion(aws.StringValue(rp.Engine), engineVersion)
+	if lists, ok := rp.ExtensionsByMajorVersion[majorVersion]; ok {
+		return lists
+	}
+	return ExtensionLists{
+		DefaultExtensions: rp.DefaultExtensions,
+		AllowedExtensions: rp.AllowedExtensions,
+	}
 }
 
 func (c Catalog) Validate() error {
@@ -88,6 +160,27 @@ func (c Catalog) Validate() error {
 	return nil
 }
 
+// withoutDeprecatedPlans is the catalog advertised to the platform, which
+// leaves out the plans that new instances can't use any more, and services
+// left with no plans.
+func (c Catalog) withoutDeprecatedPlans() Catalog {
+	advertised := Catalog{}
+	for _, service := range c.Services {
+		plans := []ServicePlan{}
+		for _, plan := range service.Plans {
+			if !plan.Deprecated {
+				plans = append(plans, plan)
+			}
+		}
+		if len(plans) == 0 {
+			continue
+		}
+		service.Plans = plans
+		advertised.Services = append(advertised.Services, service)
+	}
+	return advertised
+}
+
 func (c Catalog) FindService(serviceID string) (service Service, found bool) {
 	for _, service := range c.Services {
 		if service.ID == serviceID {
@@ -149,6 +242,84 @@ func (sp ServicePlan) Validate(c Catalog) error {
 		return fmt.Errorf("Validating RDS Properties configuration: %s", err)
 	}
 
+	if sp.StatementTimeoutSeconds > MaxStatementTimeoutSeconds {
+		return fmt.Errorf("StatementTimeoutSeconds must not be more than %d", MaxStatementTimeoutSeconds)
+	}
+	if sp.StatementTimeoutSeconds > 0 && strings.ToLower(aws.StringValue(sp.RDSProperties.Engine)) == "mysql" {
+		return fmt.Errorf("StatementTimeoutSeconds can't be set on plans for mysql, as MySQL can't limit the statements of users")
+	}
+
+	if sp.ConnectionPool != nil {
+		if err := sp.ConnectionPool.Validate(sp); err != nil {
+			return fmt.Errorf("Validating Connection Pool configuration: %s", err)
+		}
+	}
+
+	if sp.RDSProxy != nil {
+		if err := sp.RDSProxy.Validate(sp); err != nil {
+			return fmt.Errorf("Validating RDS Proxy configuration: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// RDSProxy configures an RDS Proxy which is created alongside each DB
+// instance of a plan.
+type RDSProxy struct {
+	RoleArn             string   `json:"role_arn"`
+	AuthSecretArns      []string `json:"auth_secret_arns"`
+	VpcSubnetIds        []string `json:"vpc_subnet_ids"`
+	VpcSecurityGroupIds []string `json:"vpc_security_group_ids,omitempty"`
+	RequireTLS          bool     `json:"require_tls,omitempty"`
+}
+
+func (rp RDSProxy) Validate(sp ServicePlan) error {
+	if rp.RoleArn == "" {
+		return fmt.Errorf("Must provide a non-empty RoleArn")
+	}
+
+	if len(rp.AuthSecretArns) == 0 {
+		return fmt.Errorf("Must provide at least one AuthSecretArn")
+	}
+
+	if len(rp.VpcSubnetIds) == 0 {
+		return fmt.Errorf("Must provide at least one VpcSubnetId")
+	}
+
+	if rdsProxyEngineFamily(sp) == "" {
+		return fmt.Errorf("RDS Proxy is only supported for mysql and postgres")
+	}
+
+	return nil
+}
+
+func rdsProxyEngineFamily(sp ServicePlan) string {
+	if sp.RDSProperties.Engine == nil {
+		return ""
+	}
+	switch strings.ToLower(*sp.RDSProperties.Engine) {
+	case "postgres":
+		return rds.EngineFamilyPostgresql
+	case "mysql":
+		return rds.EngineFamilyMysql
+	}
+	return ""
+}
+
+func (cp ConnectionPool) Validate(sp ServicePlan) error {
+	if cp.Host == "" {
+		return fmt.Errorf("Must provide a non-empty Host")
+	}
+
+	if cp.Port <= 0 {
+		return fmt.Errorf("Must provide a positive Port")
+	}
+
+	if sp.RDSProperties.Engine == nil || strings.ToLower(*sp.RDSProperties.Engine) != "postgres" {
+		return fmt.Errorf("Connection pools are only supported for postgres")
+	}
+
 	return nil
 }
 
@@ -200,6 +371,29 @@ func (rp RDSProperties) Validate(c Catalog) error {
 		return fmt.Errorf("This broker does not support RDS engine '%s'", *rp.Engine)
 	}
 
+	if rp.BinlogRetentionHours != nil {
+		switch strings.ToLower(*rp.Engine) {
+		case "mariadb", "mysql":
+		default:
+			return fmt.Errorf("BinlogRetentionHours is not supported for engine '%s'", *rp.Engine)
+		}
+		if *rp.BinlogRetentionHours < minBinlogRetentionHours || *rp.BinlogRetentionHours > maxBinlogRetentionHours {
+			return fmt.Errorf("Invalid BinlogRetentionHours value: %d (must be between %d and %d)", *rp.BinlogRetentionHours, minBinlogRetentionHours, maxBinlogRetentionHours)
+		}
+	}
+
+	for majorVersion, lists := range rp.ExtensionsByMajorVersion {
+		if majorEngineVersion(*rp.Engine, majorVersion) != majorVersion {
+			return fmt.Errorf("ExtensionsByMajorVersion key '%s' is not a major version of engine '%s'", majorVersion, *rp.Engine)
+		}
+		allowed := aws.StringValueSlice(lists.AllowedExtensions)
+		for _, extension := range aws.StringValueSlice(lists.DefaultExtensions) {
+			if !containsString(allowed, extension) {
+				return fmt.Errorf("Default extension '%s' of major version '%s' is not one of its allowed extensions", extension, majorVersion)
+			}
+		}
+	}
+
 	for _, engine := range c.ExcludeEngines {
 		if strings.ToLower(engine.Engine) == strings.ToLower(*rp.Engine) {
 			match, err := regexp.MatchString(engine.EngineVersion, *rp.EngineVersion)
@@ -0,0 +1,130 @@
+package rdsbroker
+
+import (
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// PlanOrderabilityProblem is a setting of a plan which RDS would refuse when
+// creating a DB instance on it.
+type PlanOrderabilityProblem struct {
+	ServiceName string `json:"service_name"`
+	PlanID      string `json:"plan_id"`
+	PlanName    string `json:"plan_name"`
+	Problem     string `json:"problem"`
+}
+
+// CheckCatalogOrderability checks the plans of the catalog against the DB
+// instance options RDS can order, so that the catalog can't offer plans
+// whose instances would fail to be created.
+func (b *RDSBroker) CheckCatalogOrderability() ([]PlanOrderabilityProblem, error) {
+	orderableOptions := map[string][]*rds.OrderableDBInstanceOption{}
+
+	problems := []PlanOrderabilityProblem{}
+	for _, service := range b.catalog.Services {
+		for _, plan := range service.Plans {
+			engine := aws.StringValue(plan.RDSProperties.Engine)
+			dbInstanceClass := aws.StringValue(plan.RDSProperties.DBInstanceClass)
+
+			key := engine + "/" + dbInstanceClass
+			options, ok := orderableOptions[key]
+			if !ok {
+				var err error
+				options, err = b.dbInstance.DescribeOrderableOptions(engine, dbInstanceClass)
+				if err != nil {
+					return nil, err
+				}
+				orderableOptions[key] = options
+			}
+
+			for _, problem := range planOrderabilityProblems(plan, options) {
+				problems = append(problems, PlanOrderabilityProblem{
+					ServiceName: service.Name,
+					PlanID:      plan.ID,
+					PlanName:    plan.Name,
+					Problem:     problem,
+				})
+			}
+		}
+	}
+
+	b.logger.Info("check-catalog-orderability", lager.Data{"problems": len(problems)})
+
+	return problems, nil
+}
+
+func planOrderabilityProblems(plan ServicePlan, options []*rds.OrderableDBInstanceOption) []string {
+	properties := plan.RDSProperties
+	engine := aws.StringValue(properties.Engine)
+	engineVersion := aws.StringValue(properties.EngineVersion)
+	dbInstanceClass := aws.StringValue(properties.DBInstanceClass)
+
+	versionOptions := []*rds.OrderableDBInstanceOption{}
+	for _, option := range options {
+		optionVersion := aws.StringValue(option.EngineVersion)
+		if engineVersion == "" || optionVersion == engineVersion || strings.HasPrefix(optionVersion, engineVersion+".") {
+			versionOptions = append(versionOptions, option)
+		}
+	}
+	if len(versionOptions) == 0 {
+		return []string{fmt.Sprintf("DB instance class %s can't be ordered for %s %s", dbInstanceClass, engine, engineVersion)}
+	}
+
+	storageOptions := versionOptions
+	if properties.StorageType != nil {
+		storageOptions = []*rds.OrderableDBInstanceOption{}
+		for _, option := range versionOptions {
+			if aws.StringValue(option.StorageType) == aws.StringValue(properties.StorageType) {
+				storageOptions = append(storageOptions, option)
+			}
+		}
+		if len(storageOptions) == 0 {
+			return []string{fmt.Sprintf("Storage type %s can't be ordered with DB instance class %s", aws.StringValue(properties.StorageType), dbInstanceClass)}
+		}
+	}
+
+	problems := []string{}
+
+	if properties.AllocatedStorage != nil {
+		allocatedStorage := aws.Int64Value(properties.AllocatedStorage)
+		if !anyOrderableOption(storageOptions, func(option *rds.OrderableDBInstanceOption) bool {
+			return (option.MinStorageSize == nil || allocatedStorage >= aws.Int64Value(option.MinStorageSize)) &&
+				(option.MaxStorageSize == nil || allocatedStorage <= aws.Int64Value(option.MaxStorageSize))
+		}) {
+			problems = append(problems, fmt.Sprintf("Allocated storage of %d GB is outside the sizes which can be ordered", allocatedStorage))
+		}
+	}
+
+	if aws.BoolValue(properties.MultiAZ) && !anyOrderableOption(storageOptions, func(option *rds.OrderableDBInstanceOption) bool {
+		return aws.BoolValue(option.MultiAZCapable)
+	}) {
+		problems = append(problems, fmt.Sprintf("DB instance class %s doesn't support Multi-AZ", dbInstanceClass))
+	}
+
+	if aws.BoolValue(properties.StorageEncrypted) && !anyOrderableOption(storageOptions, func(option *rds.OrderableDBInstanceOption) bool {
+		return aws.BoolValue(option.SupportsStorageEncryption)
+	}) {
+		problems = append(problems, fmt.Sprintf("DB instance class %s doesn't support storage encryption", dbInstanceClass))
+	}
+
+	if properties.Iops != nil && !anyOrderableOption(storageOptions, func(option *rds.OrderableDBInstanceOption) bool {
+		return aws.BoolValue(option.SupportsIops)
+	}) {
+		problems = append(problems, fmt.Sprintf("DB instance class %s doesn't support provisioned IOPS", dbInstanceClass))
+	}
+
+	return problems
+}
+
+func anyOrderableOption(options []*rds.OrderableDBInstanceOption, matches func(*rds.OrderableDBInstanceOption) bool) bool {
+	for _, option := range options {
+		if matches(option) {
+			return true
+		}
+	}
+	return false
+}
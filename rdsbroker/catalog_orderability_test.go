@@ -0,0 +1,133 @@
+package rdsbroker_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+)
+
+var _ = Describe("CheckCatalogOrderability", func() {
+	var (
+		rdsInstance   *rdsfake.FakeRDSInstance
+		rdsBroker     *RDSBroker
+		rdsProperties RDSProperties
+	)
+
+	BeforeEach(func() {
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		rdsProperties = RDSProperties{
+			Engine:           aws.String("postgres"),
+			EngineVersion:    aws.String("12"),
+			DBInstanceClass:  aws.String("db.t3.micro"),
+			AllocatedStorage: aws.Int64(100),
+			StorageType:      aws.String("gp2"),
+		}
+		rdsInstance.DescribeOrderableOptionsReturns([]*rds.OrderableDBInstanceOption{
+			{
+				EngineVersion:             aws.String("12.7"),
+				StorageType:               aws.String("gp2"),
+				MinStorageSize:            aws.Int64(20),
+				MaxStorageSize:            aws.Int64(1000),
+				MultiAZCapable:            aws.Bool(false),
+				SupportsStorageEncryption: aws.Bool(true),
+			},
+			{
+				EngineVersion: aws.String("11.12"),
+				StorageType:   aws.String("io1"),
+				SupportsIops:  aws.Bool(true),
+			},
+		}, nil)
+	})
+
+	JustBeforeEach(func() {
+		config := testConfig()
+		config.Catalog.Services[0].Name = "postgres"
+		config.Catalog.Services[0].Plans = []ServicePlan{
+			{ID: "plan-1", Name: "small", RDSProperties: rdsProperties},
+		}
+		rdsBroker = newTestBroker(config, rdsInstance, nil, lagertest.NewTestLogger("catalog_orderability_test"))
+	})
+
+	It("finds no problems with an orderable plan", func() {
+		problems, err := rdsBroker.CheckCatalogOrderability()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(problems).To(BeEmpty())
+
+		Expect(rdsInstance.DescribeOrderableOptionsCallCount()).To(Equal(1))
+		engine, dbInstanceClass := rdsInstance.DescribeOrderableOptionsArgsForCall(0)
+		Expect(engine).To(Equal("postgres"))
+		Expect(dbInstanceClass).To(Equal("db.t3.micro"))
+	})
+
+	Context("when the engine version can't be ordered with the instance class", func() {
+		BeforeEach(func() {
+			rdsProperties.EngineVersion = aws.String("13")
+		})
+
+		It("reports the plan", func() {
+			problems, err := rdsBroker.CheckCatalogOrderability()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(problems).To(Equal([]PlanOrderabilityProblem{{
+				ServiceName: "postgres",
+				PlanID:      "plan-1",
+				PlanName:    "small",
+				Problem:     "DB instance class db.t3.micro can't be ordered for postgres 13",
+			}}))
+		})
+	})
+
+	Context("when the storage type isn't available for the engine version", func() {
+		BeforeEach(func() {
+			rdsProperties.StorageType = aws.String("io1")
+		})
+
+		It("reports the storage type", func() {
+			problems, err := rdsBroker.CheckCatalogOrderability()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(problems).To(HaveLen(1))
+			Expect(problems[0].Problem).To(Equal("Storage type io1 can't be ordered with DB instance class db.t3.micro"))
+		})
+	})
+
+	Context("when the plan asks for unsupported storage and features", func() {
+		BeforeEach(func() {
+			rdsProperties.AllocatedStorage = aws.Int64(2000)
+			rdsProperties.MultiAZ = aws.Bool(true)
+			rdsProperties.StorageEncrypted = aws.Bool(true)
+			rdsProperties.Iops = aws.Int64(1000)
+		})
+
+		It("reports each of them", func() {
+			problems, err := rdsBroker.CheckCatalogOrderability()
+			Expect(err).ToNot(HaveOccurred())
+
+			reported := []string{}
+			for _, problem := range problems {
+				reported = append(reported, problem.Problem)
+			}
+			Expect(reported).To(Equal([]string{
+				"Allocated storage of 2000 GB is outside the sizes which can be ordered",
+				"DB instance class db.t3.micro doesn't support Multi-AZ",
+				"DB instance class db.t3.micro doesn't support provisioned IOPS",
+			}))
+		})
+	})
+
+	Context("when the orderable options can't be described", func() {
+		BeforeEach(func() {
+			rdsInstance.DescribeOrderableOptionsReturns(nil, errors.New("boom"))
+		})
+
+		It("returns the error", func() {
+			_, err := rdsBroker.CheckCatalogOrderability()
+			Expect(err).To(MatchError("boom"))
+		})
+	})
+})
@@ -208,6 +208,108 @@ var _ = Describe("ServicePlan", func() {
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("Validating RDS Properties configuration"))
 		})
+
+		It("returns error if the statement timeout is too long", func() {
+			servicePlan.RDSProperties.Engine = stringPointer("postgres")
+			servicePlan.StatementTimeoutSeconds = 86401
+
+			err := servicePlan.Validate(catalog)
+			Expect(err).To(MatchError("StatementTimeoutSeconds must not be more than 86400"))
+		})
+
+		It("returns error if a MySQL plan has a statement timeout", func() {
+			servicePlan.StatementTimeoutSeconds = 300
+
+			err := servicePlan.Validate(catalog)
+			Expect(err).To(MatchError(ContainSubstring("StatementTimeoutSeconds can't be set on plans for mysql")))
+		})
+
+		Context("when the plan has a connection pool", func() {
+			BeforeEach(func() {
+				servicePlan.RDSProperties.Engine = stringPointer("postgres")
+				servicePlan.RDSProperties.EngineVersion = stringPointer("12")
+				servicePlan.ConnectionPool = &ConnectionPool{
+					Host: "pgbouncer.example.com",
+					Port: 6432,
+				}
+			})
+
+			It("does not return error if all fields are valid", func() {
+				err := servicePlan.Validate(catalog)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns error if Host is empty", func() {
+				servicePlan.ConnectionPool.Host = ""
+
+				err := servicePlan.Validate(catalog)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Must provide a non-empty Host"))
+			})
+
+			It("returns error if Port is not set", func() {
+				servicePlan.ConnectionPool.Port = 0
+
+				err := servicePlan.Validate(catalog)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Must provide a positive Port"))
+			})
+
+			It("returns error if the engine is not postgres", func() {
+				servicePlan.RDSProperties.Engine = stringPointer("MySQL")
+
+				err := servicePlan.Validate(catalog)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Connection pools are only supported for postgres"))
+			})
+		})
+
+		Context("when the plan has an RDS Proxy", func() {
+			BeforeEach(func() {
+				servicePlan.RDSProxy = &RDSProxy{
+					RoleArn:        "arn:aws:iam::123456789012:role/rds-proxy",
+					AuthSecretArns: []string{"arn:aws:secretsmanager:eu-west-1:123456789012:secret:proxy"},
+					VpcSubnetIds:   []string{"subnet-1", "subnet-2"},
+				}
+			})
+
+			It("does not return error if all fields are valid", func() {
+				err := servicePlan.Validate(catalog)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns error if RoleArn is empty", func() {
+				servicePlan.RDSProxy.RoleArn = ""
+
+				err := servicePlan.Validate(catalog)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Must provide a non-empty RoleArn"))
+			})
+
+			It("returns error if there are no AuthSecretArns", func() {
+				servicePlan.RDSProxy.AuthSecretArns = nil
+
+				err := servicePlan.Validate(catalog)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Must provide at least one AuthSecretArn"))
+			})
+
+			It("returns error if there are no VpcSubnetIds", func() {
+				servicePlan.RDSProxy.VpcSubnetIds = nil
+
+				err := servicePlan.Validate(catalog)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Must provide at least one VpcSubnetId"))
+			})
+
+			It("returns error if the engine is not supported by RDS Proxy", func() {
+				servicePlan.RDSProperties.Engine = stringPointer("mariadb")
+
+				err := servicePlan.Validate(catalog)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("RDS Proxy is only supported for mysql and postgres"))
+			})
+		})
 	})
 })
 
@@ -274,5 +376,66 @@ var _ = Describe("RDSProperties", func() {
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("This broker does not support version"))
 		})
+
+		It("does not return error if ExtensionsByMajorVersion is valid", func() {
+			rdsProperties.Engine = stringPointer("postgres")
+			rdsProperties.ExtensionsByMajorVersion = map[string]ExtensionLists{
+				"14": {
+					DefaultExtensions: []*string{stringPointer("pg_stat_statements")},
+					AllowedExtensions: []*string{stringPointer("pg_stat_statements"), stringPointer("postgis")},
+				},
+			}
+
+			err := rdsProperties.Validate(catalog)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns error if an ExtensionsByMajorVersion key is not a major version", func() {
+			rdsProperties.Engine = stringPointer("postgres")
+			rdsProperties.ExtensionsByMajorVersion = map[string]ExtensionLists{
+				"14.2": {},
+			}
+
+			err := rdsProperties.Validate(catalog)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("ExtensionsByMajorVersion key '14.2' is not a major version of engine 'postgres'"))
+		})
+
+		It("returns error if a default extension of a major version is not allowed", func() {
+			rdsProperties.Engine = stringPointer("postgres")
+			rdsProperties.ExtensionsByMajorVersion = map[string]ExtensionLists{
+				"14": {
+					DefaultExtensions: []*string{stringPointer("pg_stat_statements")},
+				},
+			}
+
+			err := rdsProperties.Validate(catalog)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Default extension 'pg_stat_statements' of major version '14' is not one of its allowed extensions"))
+		})
+
+		It("does not return error if BinlogRetentionHours is set for MySQL", func() {
+			rdsProperties.BinlogRetentionHours = int64Pointer(24)
+
+			err := rdsProperties.Validate(catalog)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns error if BinlogRetentionHours is out of range", func() {
+			rdsProperties.BinlogRetentionHours = int64Pointer(169)
+
+			err := rdsProperties.Validate(catalog)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid BinlogRetentionHours value"))
+		})
+
+		It("returns error if BinlogRetentionHours is set for Postgres", func() {
+			rdsProperties.Engine = stringPointer("postgres")
+			rdsProperties.BinlogRetentionHours = int64Pointer(24)
+
+			err := rdsProperties.Validate(catalog)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("BinlogRetentionHours is not supported for engine 'postgres'"))
+		})
 	})
 })
@@ -3,27 +3,103 @@ package rdsbroker
 import (
 	"errors"
 	"fmt"
+	"net"
+
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 )
 
 type Config struct {
-	Region                       string  `json:"region"`
-	DBPrefix                     string  `json:"db_prefix"`
-	BrokerName                   string  `json:"broker_name"`
-	AWSPartition                 string  `json:"aws_partition"`
-	MasterPasswordSeed           string  `json:"master_password_seed"`
-	AWSTagCacheSeconds           uint    `json:"aws_tag_cache_seconds"`
-	AllowUserProvisionParameters bool    `json:"allow_user_provision_parameters"`
-	AllowUserUpdateParameters    bool    `json:"allow_user_update_parameters"`
-	AllowUserBindParameters      bool    `json:"allow_user_bind_parameters"`
-	Catalog                      Catalog `json:"catalog"`
+	Region                         string                           `json:"region"`
+	DBPrefix                       string                           `json:"db_prefix"`
+	BrokerName                     string                           `json:"broker_name"`
+	AWSPartition                   string                           `json:"aws_partition"`
+	MasterPasswordSeed             string                           `json:"master_password_seed"`
+	MasterPasswordPolicies         map[string]MasterPasswordPolicy  `json:"master_password_policies"`
+	MasterPasswordVersion          int                              `json:"master_password_version"`
+	AWSTagCacheSeconds             uint                             `json:"aws_tag_cache_seconds"`
+	AWSEngineVersionCacheSeconds   uint                             `json:"aws_engine_version_cache_seconds"`
+	AllowUserProvisionParameters   bool                             `json:"allow_user_provision_parameters"`
+	AllowUserUpdateParameters      bool                             `json:"allow_user_update_parameters"`
+	AllowUserBindParameters        bool                             `json:"allow_user_bind_parameters"`
+	AllowDBInstanceAdoption        bool                             `json:"allow_db_instance_adoption"`
+	DBInstanceAdoptionPrefixes     map[string][]string              `json:"db_instance_adoption_prefixes"`
+	SkipFinalSnapshotDefault       *bool                            `json:"skip_final_snapshot_default"`
+	TolerateRemovedPlans           bool                             `json:"tolerate_removed_plans"`
+	ConfirmInstanceClassDowngrades bool                             `json:"confirm_instance_class_downgrades"`
+	LastOperationCacheSeconds      uint                             `json:"last_operation_cache_seconds"`
+	RetryAfterSeconds              map[string]uint                  `json:"retry_after_seconds"`
+	ProvisionWarmUpAttempts        uint                             `json:"provision_warm_up_attempts"`
+	DatabaseUsageCacheSeconds      uint                             `json:"database_usage_cache_seconds"`
+	OperationLeaseSeconds          uint                             `json:"operation_lease_seconds"`
+	SecurityGroupSets              map[string][]string              `json:"security_group_sets"`
+	OrganizationSecurityGroupSets  map[string][]string              `json:"organization_security_group_sets"`
+	NetworkTiers                   map[string]NetworkTier           `json:"network_tiers"`
+	OrganizationNetworkTiers       map[string]string                `json:"organization_network_tiers"`
+	SubnetCIDRBlocks               map[string]string                `json:"subnet_cidr_blocks"`
+	KmsKeyAliases                  []string                         `json:"kms_key_aliases"`
+	CheckBindingConnections        bool                             `json:"check_binding_connections"`
+	SoftDeleteDays                 uint                             `json:"soft_delete_days"`
+	DeprovisionProtectionHours     uint                             `json:"deprovision_protection_hours"`
+	RevokeBindingsOnDeprovision    bool                             `json:"revoke_bindings_on_deprovision"`
+	TrialExpiryWarningDays         uint                             `json:"trial_expiry_warning_days"`
+	TrialGraceDays                 uint                             `json:"trial_grace_days"`
+	TrialExpiryWebhookURL          string                           `json:"trial_expiry_webhook_url"`
+	BackupAlertHours               uint                             `json:"backup_alert_hours"`
+	TransactionIDAgeWarning        uint                             `json:"transaction_id_age_warning"`
+	ConnectionUsageWarningPercent  uint                             `json:"connection_usage_warning_percent"`
+	SQLEngineCanaries              map[string]SQLEngineCanaryConfig `json:"sql_engine_canaries"`
+	RestoreTestIntervalDays        uint                             `json:"restore_test_interval_days"`
+	MinorUpgradeConcurrency        int                              `json:"minor_upgrade_concurrency"`
+	InventoryBucket                string                           `json:"inventory_bucket"`
+	InventoryPrefix                string                           `json:"inventory_prefix"`
+	OrganizationDataImportBuckets  map[string][]DataImportBucket    `json:"organization_data_import_buckets"`
+	OrganizationDataExportBuckets  map[string][]string              `json:"organization_data_export_buckets"`
+	PriceTable                     *PriceTable                      `json:"price_table"`
+	DNS                            *DNSConfig                       `json:"dns"`
+	BackupAccount                  *BackupAccountConfig             `json:"backup_account"`
+	StateStore                     *StateStoreConfig                `json:"state_store"`
+	AWSCircuitBreaker              *AWSCircuitBreakerConfig         `json:"aws_circuit_breaker"`
+	ServiceAdvisory                *ServiceAdvisoryConfig           `json:"service_advisory"`
+	AWSEndpoint                    *AWSEndpointConfig               `json:"aws_endpoint"`
+	DryRun                         bool                             `json:"dry_run"`
+	Catalog                        Catalog                          `json:"catalog"`
 }
 
 func (c *Config) FillDefaults() {
 	if c.AWSPartition == "" {
-		c.AWSPartition = "aws"
+		c.AWSPartition = partitionForRegion(c.Region)
 	}
 	if c.AWSTagCacheSeconds == 0 {
-		c.AWSTagCacheSeconds = 604800;  // 1 week
+		c.AWSTagCacheSeconds = 604800 // 1 week
+	}
+	if c.AWSEngineVersionCacheSeconds == 0 {
+		c.AWSEngineVersionCacheSeconds = 3600 // 1 hour
+	}
+	if c.MinorUpgradeConcurrency == 0 {
+		c.MinorUpgradeConcurrency = 1
+	}
+	if c.MasterPasswordVersion == 0 {
+		c.MasterPasswordVersion = MasterPasswordVersionHKDF
+	}
+	if c.ProvisionWarmUpAttempts == 0 {
+		c.ProvisionWarmUpAttempts = 3
+	}
+	if c.RetryAfterSeconds == nil {
+		c.RetryAfterSeconds = map[string]uint{}
+	}
+	for operation, seconds := range defaultRetryAfterSeconds {
+		if _, ok := c.RetryAfterSeconds[operation]; !ok {
+			c.RetryAfterSeconds[operation] = seconds
+		}
+	}
+	if c.DNS != nil && c.DNS.TTL == 0 {
+		c.DNS.TTL = 300
+	}
+	if c.AWSCircuitBreaker != nil {
+		c.AWSCircuitBreaker.FillDefaults()
+	}
+	if c.BackupAccount != nil {
+		c.BackupAccount.FillDefaults()
 	}
 }
 
@@ -44,9 +120,157 @@ func (c Config) Validate() error {
 		return errors.New("Must provide a non-empty MasterPasswordSeed")
 	}
 
+	if c.ConfirmInstanceClassDowngrades && !c.AllowUserUpdateParameters {
+		return errors.New("Must enable allow_user_update_parameters when confirm_instance_class_downgrades is enabled, so downgrades can be confirmed")
+	}
+
+	if c.AllowDBInstanceAdoption && len(c.DBInstanceAdoptionPrefixes) == 0 {
+		return errors.New("Must provide db_instance_adoption_prefixes when allow_db_instance_adoption is enabled")
+	}
+	for organizationGUID, prefixes := range c.DBInstanceAdoptionPrefixes {
+		for _, prefix := range prefixes {
+			if prefix == "" {
+				return fmt.Errorf("Adoption prefixes for organization '%s' must not be empty", organizationGUID)
+			}
+		}
+	}
+
+	for name, securityGroupIDs := range c.SecurityGroupSets {
+		if len(securityGroupIDs) == 0 {
+			return fmt.Errorf("Security group set '%s' must contain at least one security group", name)
+		}
+	}
+
+	for organizationGUID, setNames := range c.OrganizationSecurityGroupSets {
+		for _, setName := range setNames {
+			if _, ok := c.SecurityGroupSets[setName]; !ok {
+				return fmt.Errorf("Organization '%s' is allowed unknown security group set '%s'", organizationGUID, setName)
+			}
+		}
+	}
+
+	for name, networkTier := range c.NetworkTiers {
+		if err := networkTier.Validate(); err != nil {
+			return fmt.Errorf("Validating network tier '%s': %s", name, err)
+		}
+	}
+
+	for organizationGUID, networkTierName := range c.OrganizationNetworkTiers {
+		if _, ok := c.NetworkTiers[networkTierName]; !ok {
+			return fmt.Errorf("Organization '%s' is mapped to unknown network tier '%s'", organizationGUID, networkTierName)
+		}
+	}
+
+	if err := validateKmsKeyAliases(c.KmsKeyAliases); err != nil {
+		return err
+	}
+
+	if c.MasterPasswordVersion < 0 || c.MasterPasswordVersion > MasterPasswordVersionHKDF {
+		return fmt.Errorf("MasterPasswordVersion must be %d or %d", MasterPasswordVersionSHA256, MasterPasswordVersionHKDF)
+	}
+
+	for engine, policy := range c.MasterPasswordPolicies {
+		if err := policy.Validate(); err != nil {
+			return fmt.Errorf("Validating master password policy for engine '%s': %s", engine, err)
+		}
+	}
+
+	for operation := range c.RetryAfterSeconds {
+		if _, ok := defaultRetryAfterSeconds[operation]; !ok {
+			return fmt.Errorf("Unknown operation '%s' in RetryAfterSeconds", operation)
+		}
+	}
+
+	if c.TransactionIDAgeWarning >= transactionIDWraparoundAge {
+		return fmt.Errorf("TransactionIDAgeWarning must be less than %d", transactionIDWraparoundAge)
+	}
+
+	if c.ConnectionUsageWarningPercent > 100 {
+		return errors.New("ConnectionUsageWarningPercent must be 100 or less")
+	}
+
+	for engine, canary := range c.SQLEngineCanaries {
+		switch engine {
+		case "postgres", "mysql", "mariadb":
+		default:
+			return fmt.Errorf("SQL engine canary for unsupported engine '%s'", engine)
+		}
+		if err := canary.Validate(); err != nil {
+			return fmt.Errorf("Validating SQL engine canary for engine '%s': %s", engine, err)
+		}
+	}
+
+	for organizationGUID, buckets := range c.OrganizationDataImportBuckets {
+		for _, bucket := range buckets {
+			if err := bucket.Validate(); err != nil {
+				return fmt.Errorf("Validating data import bucket of organization '%s': %s", organizationGUID, err)
+			}
+		}
+	}
+
+	for organizationGUID, buckets := range c.OrganizationDataExportBuckets {
+		for _, bucket := range buckets {
+			if bucket == "" {
+				return fmt.Errorf("Organization '%s' is allowed a data export bucket with an empty name", organizationGUID)
+			}
+		}
+	}
+
+	for subnetID, cidrBlock := range c.SubnetCIDRBlocks {
+		if _, _, err := net.ParseCIDR(cidrBlock); err != nil {
+			return fmt.Errorf("Subnet '%s' has invalid CIDR block '%s'", subnetID, cidrBlock)
+		}
+	}
+
+	if c.PriceTable != nil {
+		if err := c.PriceTable.Validate(); err != nil {
+			return fmt.Errorf("Validating PriceTable configuration: %s", err)
+		}
+	}
+
+	if c.DNS != nil {
+		if err := c.DNS.Validate(); err != nil {
+			return fmt.Errorf("Validating DNS configuration: %s", err)
+		}
+	}
+
+	if c.BackupAccount != nil {
+		if err := c.BackupAccount.Validate(); err != nil {
+			return fmt.Errorf("Validating BackupAccount configuration: %s", err)
+		}
+	}
+
+	if c.ServiceAdvisory != nil {
+		if err := c.ServiceAdvisory.Validate(); err != nil {
+			return fmt.Errorf("Validating ServiceAdvisory configuration: %s", err)
+		}
+	}
+
+	if c.StateStore != nil {
+		if err := c.StateStore.Validate(); err != nil {
+			return fmt.Errorf("Validating StateStore configuration: %s", err)
+		}
+	}
+
+	if c.AWSEndpoint != nil {
+		if err := c.AWSEndpoint.Validate(); err != nil {
+			return fmt.Errorf("Validating AWSEndpoint configuration: %s", err)
+		}
+	}
+
 	if err := c.Catalog.Validate(); err != nil {
 		return fmt.Errorf("Validating Catalog configuration: %s", err)
 	}
 
 	return nil
 }
+
+// partitionForRegion is the AWS partition, such as aws-us-gov or aws-cn,
+// which the ARNs of resources in the region belong to. Regions the SDK
+// doesn't know are taken to be in the standard aws partition.
+func partitionForRegion(region string) string {
+	if partition, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region); ok {
+		return partition.ID()
+	}
+	return endpoints.AwsPartitionID
+}
@@ -1,6 +1,8 @@
 package rdsbroker_test
 
 import (
+	"strings"
+
 	"github.com/aws/aws-sdk-go/aws"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -42,10 +44,28 @@ var _ = Describe("Config", func() {
 			Expect(config.AWSPartition).To(Equal("aws"))
 		})
 
+		It("derives the aws partition from the region if empty", func() {
+			config.AWSPartition = ""
+			config.Region = "us-gov-west-1"
+			config.FillDefaults()
+			Expect(config.AWSPartition).To(Equal("aws-us-gov"))
+
+			config.AWSPartition = ""
+			config.Region = "cn-north-1"
+			config.FillDefaults()
+			Expect(config.AWSPartition).To(Equal("aws-cn"))
+		})
+
 		It("preserves aws partition if not empty", func() {
 			config.FillDefaults()
 			Expect(config.AWSPartition).To(Equal("rds-partition"))
 		})
+
+		It("sets the default DNS TTL if empty", func() {
+			config.DNS = &DNSConfig{HostedZoneID: "ZONE123", Domain: "db.example.com"}
+			config.FillDefaults()
+			Expect(config.DNS.TTL).To(Equal(int64(300)))
+		})
 	})
 
 	Describe("Validate", func() {
@@ -90,6 +110,289 @@ var _ = Describe("Config", func() {
 			Expect(err.Error()).To(ContainSubstring("Must provide a non-empty BrokerName"))
 		})
 
+		It("returns error if instance class downgrades need confirming but update parameters aren't allowed", func() {
+			config.ConfirmInstanceClassDowngrades = true
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Must enable allow_user_update_parameters when confirm_instance_class_downgrades is enabled"))
+		})
+
+		It("returns error if adoption is allowed without any adoption prefixes", func() {
+			config.AllowDBInstanceAdoption = true
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Must provide db_instance_adoption_prefixes when allow_db_instance_adoption is enabled"))
+		})
+
+		It("returns error if an adoption prefix is empty", func() {
+			config.AllowDBInstanceAdoption = true
+			config.DBInstanceAdoptionPrefixes = map[string][]string{"org-guid": {""}}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Adoption prefixes for organization 'org-guid' must not be empty"))
+		})
+
+		It("returns error if a security group set is empty", func() {
+			config.SecurityGroupSets = map[string][]string{"restricted-egress": {}}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Security group set 'restricted-egress' must contain at least one security group"))
+		})
+
+		It("returns error if an organization is allowed an unknown security group set", func() {
+			config.OrganizationSecurityGroupSets = map[string][]string{"org-guid": {"restricted-egress"}}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Organization 'org-guid' is allowed unknown security group set 'restricted-egress'"))
+		})
+
+		It("returns error if a network tier has no subnet group", func() {
+			config.NetworkTiers = map[string]NetworkTier{
+				"isolated": {VpcSecurityGroupIds: []string{"sg-isolated"}},
+			}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating network tier 'isolated': Must provide a non-empty db_subnet_group_name"))
+		})
+
+		It("returns error if a network tier has no security groups", func() {
+			config.NetworkTiers = map[string]NetworkTier{
+				"isolated": {DBSubnetGroupName: "isolated-subnets"},
+			}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating network tier 'isolated': Must provide at least one of vpc_security_group_ids"))
+		})
+
+		It("returns error if an organization is mapped to an unknown network tier", func() {
+			config.OrganizationNetworkTiers = map[string]string{"org-guid": "isolated"}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Organization 'org-guid' is mapped to unknown network tier 'isolated'"))
+		})
+
+		It("returns error if the backup account copies snapshots without a key to encrypt them with", func() {
+			config.BackupAccount = &BackupAccountConfig{
+				AccountID:   "210987654321",
+				CopyRoleARN: "arn:aws:iam::210987654321:role/rds-broker-backup",
+			}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating BackupAccount configuration: Must provide a CopyKmsKeyID in the backup account when CopyRoleARN is set"))
+		})
+
+		It("returns error if a KMS key alias is for an AWS managed key", func() {
+			config.KmsKeyAliases = []string{"alias/tenant-key", "alias/aws/rds"}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("KMS key alias 'alias/aws/rds' must start with 'alias/', and not be an AWS managed key"))
+		})
+
+		It("returns error if the master password version is unknown", func() {
+			config.MasterPasswordVersion = 3
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("MasterPasswordVersion must be 1 or 2"))
+		})
+
+		It("returns error if the transaction ID age warning is past wraparound", func() {
+			config.TransactionIDAgeWarning = 2000000000
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("TransactionIDAgeWarning must be less than 2000000000"))
+		})
+
+		It("returns error if the connection usage warning percentage is over 100", func() {
+			config.ConnectionUsageWarningPercent = 101
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("ConnectionUsageWarningPercent must be 100 or less"))
+		})
+
+		It("returns error if a data import bucket is empty", func() {
+			config.OrganizationDataImportBuckets = map[string][]DataImportBucket{
+				"organization-id": {{Bucket: "seed-bucket"}, {KeyPrefix: "seed/"}},
+			}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating data import bucket of organization 'organization-id': Must provide a non-empty bucket"))
+		})
+
+		It("returns error if a data import key prefix has characters keys can't", func() {
+			config.OrganizationDataImportBuckets = map[string][]DataImportBucket{
+				"organization-id": {{Bucket: "seed-bucket", KeyPrefix: "seed:"}},
+			}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Key prefix 'seed:' must contain only letters"))
+		})
+
+		It("returns error if the service advisory has no message", func() {
+			config.ServiceAdvisory = &ServiceAdvisoryConfig{}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating ServiceAdvisory configuration: Must provide a non-empty Message"))
+		})
+
+		It("returns error if the service advisory's until time isn't RFC3339", func() {
+			config.ServiceAdvisory = &ServiceAdvisoryConfig{Message: "RDS is impaired", Until: "tomorrow"}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Until must be an RFC3339 time"))
+		})
+
+		It("returns error if a data export bucket is empty", func() {
+			config.OrganizationDataExportBuckets = map[string][]string{"organization-id": {""}}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Organization 'organization-id' is allowed a data export bucket with an empty name"))
+		})
+
+		It("returns error if an SQL engine canary is for an unsupported engine", func() {
+			config.SQLEngineCanaries = map[string]SQLEngineCanaryConfig{
+				"sqlserver-se": {Address: "canary.example.com", Port: 1433, Username: "canary"},
+			}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("SQL engine canary for unsupported engine 'sqlserver-se'"))
+		})
+
+		It("returns error if an SQL engine canary has no address", func() {
+			config.SQLEngineCanaries = map[string]SQLEngineCanaryConfig{
+				"postgres": {Port: 5432, Username: "canary"},
+			}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating SQL engine canary for engine 'postgres': Must provide a non-empty Address"))
+		})
+
+		It("returns error if a master password policy is too short", func() {
+			config.MasterPasswordPolicies = map[string]MasterPasswordPolicy{"postgres": {Length: 4}}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating master password policy for engine 'postgres': Length must be between 8 and 43 without Characters"))
+		})
+
+		It("returns error if a master password policy allows characters RDS doesn't", func() {
+			config.MasterPasswordPolicies = map[string]MasterPasswordPolicy{"sqlserver-se": {Length: 40, Characters: "ABCDEFGHIJKLMNOP@"}}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`Characters must be printable ASCII characters other than '/', '@', '"' and space`))
+		})
+
+		It("returns error if a master password policy repeats a character", func() {
+			config.MasterPasswordPolicies = map[string]MasterPasswordPolicy{"sqlserver-se": {Length: 40, Characters: "ABCDEFGHIJKLMNOPA"}}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Characters must not contain 'A' more than once"))
+		})
+
+		It("returns error if a master password policy has more characters than a byte can pick from", func() {
+			config.MasterPasswordPolicies = map[string]MasterPasswordPolicy{"sqlserver-se": {Length: 40, Characters: strings.Repeat("ABCDEFGHIJKLMNOP", 17)}}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Characters must contain at most 256 characters"))
+		})
+
+		It("returns error if a Retry-After hint is given for an unknown operation", func() {
+			config.RetryAfterSeconds = map[string]uint{"restore": 60}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unknown operation 'restore' in RetryAfterSeconds"))
+		})
+
+		It("returns error if a subnet has an invalid CIDR block", func() {
+			config.SubnetCIDRBlocks = map[string]string{"subnet-1": "10.0.0.0"}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Subnet 'subnet-1' has invalid CIDR block '10.0.0.0'"))
+		})
+
+		It("returns error if the price table has no currency", func() {
+			config.PriceTable = &PriceTable{}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating PriceTable configuration: Must provide a non-empty Currency"))
+		})
+
+		It("returns error if the price table has a negative price", func() {
+			config.PriceTable = &PriceTable{
+				Currency:            "USD",
+				InstanceClassHourly: map[string]float64{"db.t3.small": -1},
+			}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid price -1 for instance class 'db.t3.small'"))
+		})
+
+		It("returns error if the DNS configuration has no hosted zone", func() {
+			config.DNS = &DNSConfig{Domain: "db.example.com"}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating DNS configuration: Must provide a non-empty HostedZoneID"))
+		})
+
+		It("returns error if the DNS configuration has no domain", func() {
+			config.DNS = &DNSConfig{HostedZoneID: "ZONE123", Domain: "."}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating DNS configuration: Must provide a non-empty Domain"))
+		})
+
+		It("returns error if the state store configuration has no URL", func() {
+			config.StateStore = &StateStoreConfig{}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating StateStore configuration: Must provide a non-empty URL"))
+		})
+
+		It("returns error if the AWS endpoint has no URL", func() {
+			config.AWSEndpoint = &AWSEndpointConfig{}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating AWSEndpoint configuration: Must provide a non-empty URL"))
+		})
+
+		It("returns error if the AWS endpoint has only an access key ID", func() {
+			config.AWSEndpoint = &AWSEndpointConfig{URL: "http://localhost:4566", AccessKeyID: "test"}
+
+			err := config.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating AWSEndpoint configuration: Must provide both AccessKeyID and SecretAccessKey"))
+		})
+
 		It("returns error if Catalog is not valid", func() {
 			config.Catalog = Catalog{
 				Services: []Service{
@@ -103,6 +406,35 @@ var _ = Describe("Config", func() {
 			Expect(err.Error()).To(ContainSubstring("Validating Catalog configuration"))
 		})
 	})
+
+	Describe("AWSConfig", func() {
+		BeforeEach(func() {
+			config = validConfig
+		})
+
+		It("uses AWS's own endpoints by default", func() {
+			awsConfig := config.AWSConfig()
+			Expect(aws.StringValue(awsConfig.Region)).To(Equal(config.Region))
+			Expect(awsConfig.Endpoint).To(BeNil())
+			Expect(awsConfig.Credentials).To(BeNil())
+		})
+
+		It("overrides the endpoint and credentials", func() {
+			config.AWSEndpoint = &AWSEndpointConfig{
+				URL:             "http://localhost:4566",
+				AccessKeyID:     "test",
+				SecretAccessKey: "secret",
+			}
+
+			awsConfig := config.AWSConfig()
+			Expect(aws.StringValue(awsConfig.Endpoint)).To(Equal("http://localhost:4566"))
+			Expect(aws.BoolValue(awsConfig.S3ForcePathStyle)).To(BeTrue())
+			credentials, err := awsConfig.Credentials.Get()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(credentials.AccessKeyID).To(Equal("test"))
+			Expect(credentials.SecretAccessKey).To(Equal("secret"))
+		})
+	})
 })
 
 var _ = Describe("ServicePlan", func() {
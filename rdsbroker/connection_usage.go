@@ -0,0 +1,150 @@
+package rdsbroker
+
+import (
+	"fmt"
+	"io"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// connectionUsage is how many of the connections a DB instance allows are
+// in use.
+type connectionUsage struct {
+	instanceID           string
+	dbInstanceIdentifier string
+	planID               string
+	connections          int64
+	idleConnections      int64
+	maxConnections       int64
+}
+
+// nearMax reports whether the connections in use are at least percent of
+// those allowed.
+func (u connectionUsage) nearMax(percent uint) bool {
+	return u.maxConnections > 0 && u.connections*100 >= int64(percent)*u.maxConnections
+}
+
+// checkConnectionUsage is the housekeeping job which counts the connections
+// to each available DB instance, and logs the instances using enough of
+// their max_connections for new connections to be at risk of being refused.
+// Instances which can't be counted are logged and left out. It needs to
+// connect to every instance, so it only runs when a warning percentage is
+// configured.
+func (b *RDSBroker) checkConnectionUsage(dbInstances []managedDBInstance) error {
+	if b.connectionUsageWarningPercent == 0 {
+		return nil
+	}
+
+	usages := []connectionUsage{}
+	for _, instance := range dbInstances {
+		switch aws.StringValue(instance.dbInstance.Engine) {
+		case "postgres", "mysql", "mariadb":
+		default:
+			continue
+		}
+		if aws.StringValue(instance.dbInstance.DBInstanceStatus) != "available" {
+			continue
+		}
+
+		usage, err := b.connectionUsage(instance)
+		if err != nil {
+			b.logger.Error("check-connection-usage.failed", err, lager.Data{dbInstanceLogKey: instance.dbInstance.DBInstanceIdentifier})
+			continue
+		}
+		if usage.nearMax(b.connectionUsageWarningPercent) {
+			b.logger.Info("check-connection-usage.near-max", lager.Data{
+				instanceIDLogKey:  usage.instanceID,
+				"connections":     usage.connections,
+				"idleConnections": usage.idleConnections,
+				"maxConnections":  usage.maxConnections,
+			})
+		}
+		usages = append(usages, usage)
+	}
+
+	b.instanceMetrics.lock.Lock()
+	b.instanceMetrics.connectionUsages = usages
+	b.instanceMetrics.lock.Unlock()
+
+	return nil
+}
+
+func (b *RDSBroker) connectionUsage(instance managedDBInstance) (connectionUsage, error) {
+	dbInstanceIdentifier := aws.StringValue(instance.dbInstance.DBInstanceIdentifier)
+	instanceID := b.dbInstanceIdentifierToServiceInstanceID(dbInstanceIdentifier)
+
+	sqlEngine, err := b.openSQLEngineForDBInstance(instanceID, b.dbNameFromDBInstance(instanceID, instance.dbInstance), instance.dbInstance)
+	if err != nil {
+		return connectionUsage{}, err
+	}
+	defer sqlEngine.Close()
+
+	engineUsage, err := sqlEngine.ConnectionUsage()
+	if err != nil {
+		return connectionUsage{}, err
+	}
+
+	return connectionUsage{
+		instanceID:           instanceID,
+		dbInstanceIdentifier: dbInstanceIdentifier,
+		planID:               instance.tagsByName[awsrds.TagPlanID],
+		connections:          engineUsage.Connections,
+		idleConnections:      engineUsage.IdleConnections,
+		maxConnections:       engineUsage.MaxConnections,
+	}, nil
+}
+
+// connectionUsageWarning warns about a service instance which was using
+// enough of its max_connections when housekeeping last counted them.
+func (b *RDSBroker) connectionUsageWarning(instanceID string) string {
+	b.instanceMetrics.lock.RLock()
+	defer b.instanceMetrics.lock.RUnlock()
+	for _, usage := range b.instanceMetrics.connectionUsages {
+		if usage.instanceID == instanceID && usage.nearMax(b.connectionUsageWarningPercent) {
+			return fmt.Sprintf(
+				"It was recently using %d of the %d connections it allows, %d of them idle. New connections are refused once they run out, so close idle connections or make connection pools smaller",
+				usage.connections, usage.maxConnections, usage.idleConnections,
+			)
+		}
+	}
+	return ""
+}
+
+// writeConnectionUsageMetrics writes the connections in use on each DB
+// instance, how many of them are idle, and how many it allows, in the
+// Prometheus text exposition format.
+func writeConnectionUsageMetrics(w io.Writer, usages []connectionUsage) {
+	metrics := []struct {
+		name  string
+		help  string
+		value func(connectionUsage) int64
+	}{
+		{
+			name:  "rds_broker_instance_connections",
+			help:  "How many client connections the DB instance has open.",
+			value: func(u connectionUsage) int64 { return u.connections },
+		},
+		{
+			name:  "rds_broker_instance_idle_connections",
+			help:  "How many of the client connections of the DB instance are idle.",
+			value: func(u connectionUsage) int64 { return u.idleConnections },
+		},
+		{
+			name:  "rds_broker_instance_max_connections",
+			help:  "How many client connections the DB instance allows.",
+			value: func(u connectionUsage) int64 { return u.maxConnections },
+		},
+	}
+
+	for _, metric := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n", metric.name, metric.help)
+		fmt.Fprintf(w, "# TYPE %s gauge\n", metric.name)
+		for _, usage := range usages {
+			fmt.Fprintf(w, "%s{instance_id=%q,db_instance_identifier=%q,plan_id=%q} %d\n",
+				metric.name, usage.instanceID, usage.dbInstanceIdentifier, usage.planID, metric.value(usage))
+		}
+	}
+}
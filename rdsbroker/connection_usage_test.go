@@ -0,0 +1,138 @@
+package rdsbroker_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+	"github.com/alphagov/paas-rds-broker/sqlengine"
+	sqlfake "github.com/alphagov/paas-rds-broker/sqlengine/fakes"
+)
+
+var _ = Describe("Connection usage", func() {
+	var (
+		config      Config
+		rdsInstance *rdsfake.FakeRDSInstance
+		sqlEngine   *sqlfake.FakeSQLEngine
+		rdsBroker   *RDSBroker
+		pollDetails domain.PollDetails
+	)
+
+	BeforeEach(func() {
+		config = testConfig()
+		config.ConnectionUsageWarningPercent = 90
+
+		postgresInstance := testDBInstance()
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		rdsInstance.DescribeReturns(postgresInstance, nil)
+		rdsInstance.DescribeByTagReturns([]*rds.DBInstance{
+			postgresInstance,
+			{
+				DBInstanceIdentifier: aws.String("cf-instance-2"),
+				DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-instance-2"),
+				DBInstanceStatus:     aws.String("available"),
+				Engine:               aws.String("sqlserver-se"),
+			},
+			{
+				DBInstanceIdentifier: aws.String("cf-instance-3"),
+				DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-instance-3"),
+				DBInstanceStatus:     aws.String("modifying"),
+				Engine:               aws.String("mysql"),
+			},
+		}, nil)
+		rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+			"Broker Name": "mybroker",
+			"Service ID":  "Service-1",
+			"Plan ID":     "Plan-1",
+		}), nil)
+
+		sqlEngine = &sqlfake.FakeSQLEngine{}
+		sqlEngine.ConnectionUsageReturns(sqlengine.ConnectionUsage{
+			Connections:     95,
+			IdleConnections: 80,
+			MaxConnections:  100,
+		}, nil)
+
+		pollDetails = domain.PollDetails{ServiceID: "Service-1", PlanID: "Plan-1"}
+	})
+
+	JustBeforeEach(func() {
+		rdsBroker = newTestBroker(config, rdsInstance, sqlEngine, lagertest.NewTestLogger("connection_usage_test"))
+	})
+
+	metrics := func() string {
+		recorder := httptest.NewRecorder()
+		rdsBroker.AdminHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/metrics", nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		return recorder.Body.String()
+	}
+
+	It("serves the connections of each available instance", func() {
+		Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+		Expect(sqlEngine.ConnectionUsageCallCount()).To(Equal(1))
+		Expect(metrics()).To(ContainSubstring(
+			"# TYPE rds_broker_instance_connections gauge\n" +
+				`rds_broker_instance_connections{instance_id="instance-1",db_instance_identifier="cf-instance-1",plan_id="Plan-1"} 95` + "\n" +
+				"# HELP rds_broker_instance_idle_connections How many of the client connections of the DB instance are idle.\n" +
+				"# TYPE rds_broker_instance_idle_connections gauge\n" +
+				`rds_broker_instance_idle_connections{instance_id="instance-1",db_instance_identifier="cf-instance-1",plan_id="Plan-1"} 80` + "\n" +
+				"# HELP rds_broker_instance_max_connections How many client connections the DB instance allows.\n" +
+				"# TYPE rds_broker_instance_max_connections gauge\n" +
+				`rds_broker_instance_max_connections{instance_id="instance-1",db_instance_identifier="cf-instance-1",plan_id="Plan-1"} 100` + "\n"))
+	})
+
+	It("warns when polling an instance near its max_connections", func() {
+		Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+		lastOperation, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lastOperation).To(Equal(domain.LastOperation{
+			State:       domain.Succeeded,
+			Description: "DB Instance 'cf-instance-1' status is 'available'. It was recently using 95 of the 100 connections it allows, 80 of them idle. New connections are refused once they run out, so close idle connections or make connection pools smaller",
+		}))
+	})
+
+	It("doesn't warn when the instance has connections to spare", func() {
+		sqlEngine.ConnectionUsageReturns(sqlengine.ConnectionUsage{
+			Connections:     50,
+			IdleConnections: 80,
+			MaxConnections:  100,
+		}, nil)
+		Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+		lastOperation, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lastOperation.Description).To(Equal("DB Instance 'cf-instance-1' status is 'available'"))
+	})
+
+	It("leaves out instances which can't be counted", func() {
+		sqlEngine.ConnectionUsageReturns(sqlengine.ConnectionUsage{}, errors.New("too many connections"))
+		Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+		Expect(metrics()).NotTo(ContainSubstring(`rds_broker_instance_connections{`))
+	})
+
+	Context("when no warning percentage is configured", func() {
+		BeforeEach(func() {
+			config.ConnectionUsageWarningPercent = 0
+		})
+
+		It("doesn't connect to the instances", func() {
+			Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+			Expect(sqlEngine.ConnectionUsageCallCount()).To(Equal(0))
+		})
+	})
+})
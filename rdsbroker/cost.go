@@ -0,0 +1,155 @@
+package rdsbroker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// hoursPerMonth is the number of hours AWS bills an instance running for a
+// whole month.
+const hoursPerMonth = 730
+
+// PriceTable is what RDS charges in the broker's region, used to estimate
+// the monthly cost of each DB instance. Multi-AZ instances cost twice the
+// instance and storage prices, as AWS charges for the standby.
+type PriceTable struct {
+	Currency               string             `json:"currency"`
+	InstanceClassHourly    map[string]float64 `json:"instance_class_hourly"`
+	StorageGBMonthly       map[string]float64 `json:"storage_gb_monthly"`
+	ProvisionedIOPSMonthly float64            `json:"provisioned_iops_monthly"`
+}
+
+func (p PriceTable) Validate() error {
+	if p.Currency == "" {
+		return errors.New("Must provide a non-empty Currency")
+	}
+
+	for instanceClass, price := range p.InstanceClassHourly {
+		if price < 0 {
+			return fmt.Errorf("Invalid price %v for instance class '%s'", price, instanceClass)
+		}
+	}
+
+	for storageType, price := range p.StorageGBMonthly {
+		if price < 0 {
+			return fmt.Errorf("Invalid price %v for storage type '%s'", price, storageType)
+		}
+	}
+
+	if p.ProvisionedIOPSMonthly < 0 {
+		return fmt.Errorf("Invalid provisioned IOPS price %v", p.ProvisionedIOPSMonthly)
+	}
+
+	return nil
+}
+
+// InstanceCost is the estimated monthly cost of a DB instance, with the
+// organization, space and plan it is charged to.
+type InstanceCost struct {
+	InstanceID           string
+	DBInstanceIdentifier string
+	OrganizationID       string
+	SpaceID              string
+	PlanID               string
+	MonthlyCost          float64
+}
+
+// estimatedMonthlyCost is what the DB instance would cost over a month if
+// it stayed as it is now. Stopped instances are only charged for their
+// storage. It returns false if the price table has no price for the
+// instance class or storage type.
+func (p PriceTable) estimatedMonthlyCost(dbInstance *rds.DBInstance) (float64, bool) {
+	instanceHourly, ok := p.InstanceClassHourly[aws.StringValue(dbInstance.DBInstanceClass)]
+	if !ok {
+		return 0, false
+	}
+	storageGBMonthly, ok := p.StorageGBMonthly[aws.StringValue(dbInstance.StorageType)]
+	if !ok {
+		return 0, false
+	}
+
+	cost := float64(aws.Int64Value(dbInstance.AllocatedStorage))*storageGBMonthly +
+		float64(aws.Int64Value(dbInstance.Iops))*p.ProvisionedIOPSMonthly
+	if aws.StringValue(dbInstance.DBInstanceStatus) != "stopped" {
+		cost += instanceHourly * hoursPerMonth
+	}
+	if aws.BoolValue(dbInstance.MultiAZ) {
+		cost *= 2
+	}
+
+	return cost, true
+}
+
+// InstanceCosts estimates the monthly cost of every DB instance owned by
+// this broker from the configured price table. Instances whose instance
+// class or storage type isn't in the table are left out.
+func (b *RDSBroker) InstanceCosts() ([]InstanceCost, error) {
+	if b.priceTable == nil {
+		return []InstanceCost{}, nil
+	}
+
+	dbInstances, err := b.listManagedDBInstances()
+	if err != nil {
+		return nil, err
+	}
+	return b.instanceCosts(dbInstances), nil
+}
+
+func (b *RDSBroker) instanceCosts(dbInstances []managedDBInstance) []InstanceCost {
+	costs := []InstanceCost{}
+	if b.priceTable == nil {
+		return costs
+	}
+
+	for _, instance := range dbInstances {
+		dbInstance := instance.dbInstance
+		tagsByName := instance.tagsByName
+		dbInstanceIdentifier := aws.StringValue(dbInstance.DBInstanceIdentifier)
+		monthlyCost, ok := b.priceTable.estimatedMonthlyCost(dbInstance)
+		if !ok {
+			b.logger.Debug("instance-costs.no-price", lager.Data{
+				dbInstanceLogKey:  dbInstanceIdentifier,
+				"dbInstanceClass": aws.StringValue(dbInstance.DBInstanceClass),
+				"storageType":     aws.StringValue(dbInstance.StorageType),
+			})
+			continue
+		}
+
+		instanceID := tagsByName[awsrds.TagChargeableEntity]
+		if instanceID == "" {
+			instanceID = b.dbInstanceIdentifierToServiceInstanceID(dbInstanceIdentifier)
+		}
+
+		costs = append(costs, InstanceCost{
+			InstanceID:           instanceID,
+			DBInstanceIdentifier: dbInstanceIdentifier,
+			OrganizationID:       tagsByName[awsrds.TagOrganizationID],
+			SpaceID:              tagsByName[awsrds.TagSpaceID],
+			PlanID:               tagsByName[awsrds.TagPlanID],
+			MonthlyCost:          monthlyCost,
+		})
+	}
+
+	return costs
+}
+
+// writeCostMetrics writes the estimated monthly costs in the Prometheus text
+// exposition format.
+func writeCostMetrics(w io.Writer, costs []InstanceCost, region string, currency string) {
+	const name = "rds_broker_instance_estimated_monthly_cost"
+	fmt.Fprintf(w, "# HELP %s Estimated monthly cost of the DB instance in %s, from the configured price table.\n", name, currency)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+	for _, cost := range costs {
+		fmt.Fprintf(w, "%s{instance_id=%q,db_instance_identifier=%q,organization_id=%q,space_id=%q,plan_id=%q,region=%q,currency=%q} %s\n",
+			name, cost.InstanceID, cost.DBInstanceIdentifier, cost.OrganizationID, cost.SpaceID, cost.PlanID, region, currency,
+			strconv.FormatFloat(cost.MonthlyCost, 'f', 2, 64))
+	}
+}
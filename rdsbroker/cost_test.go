@@ -0,0 +1,148 @@
+package rdsbroker_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+)
+
+var _ = Describe("InstanceCosts", func() {
+	var (
+		rdsInstance *rdsfake.FakeRDSInstance
+		rdsBroker   *RDSBroker
+		priceTable  *PriceTable
+		dbInstance  *rds.DBInstance
+	)
+
+	BeforeEach(func() {
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		priceTable = &PriceTable{
+			Currency:               "USD",
+			InstanceClassHourly:    map[string]float64{"db.t3.small": 0.04},
+			StorageGBMonthly:       map[string]float64{"gp2": 0.125, "io1": 0.2},
+			ProvisionedIOPSMonthly: 0.1,
+		}
+		dbInstance = &rds.DBInstance{
+			DBInstanceIdentifier: aws.String("cf-instance-1"),
+			DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-instance-1"),
+			DBInstanceStatus:     aws.String("available"),
+			DBInstanceClass:      aws.String("db.t3.small"),
+			AllocatedStorage:     aws.Int64(100),
+			StorageType:          aws.String("gp2"),
+			MultiAZ:              aws.Bool(false),
+		}
+
+		rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+			"Broker Name":       "mybroker",
+			"chargeable_entity": "instance-1",
+			"Plan ID":           "Plan-1",
+			"Organization ID":   "organization-id",
+			"Space ID":          "space-id",
+		}), nil)
+	})
+
+	JustBeforeEach(func() {
+		rdsInstance.DescribeByTagReturns([]*rds.DBInstance{dbInstance}, nil)
+		config := testConfig()
+		config.Region = "eu-west-1"
+		config.PriceTable = priceTable
+		rdsBroker = newTestBroker(config, rdsInstance, nil, lagertest.NewTestLogger("cost_test"))
+	})
+
+	It("estimates the instance and storage cost for a month", func() {
+		costs, err := rdsBroker.InstanceCosts()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(costs).To(Equal([]InstanceCost{
+			{
+				InstanceID:           "instance-1",
+				DBInstanceIdentifier: "cf-instance-1",
+				OrganizationID:       "organization-id",
+				SpaceID:              "space-id",
+				PlanID:               "Plan-1",
+				MonthlyCost:          0.04*730 + 100*0.125,
+			},
+		}))
+	})
+
+	Context("when the instance is Multi-AZ with provisioned IOPS", func() {
+		BeforeEach(func() {
+			dbInstance.MultiAZ = aws.Bool(true)
+			dbInstance.StorageType = aws.String("io1")
+			dbInstance.Iops = aws.Int64(1000)
+		})
+
+		It("doubles the cost for the standby", func() {
+			costs, err := rdsBroker.InstanceCosts()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(costs[0].MonthlyCost).To(BeNumerically("~", 2*(0.04*730+100*0.2+1000*0.1), 0.001))
+		})
+	})
+
+	Context("when the instance is stopped", func() {
+		BeforeEach(func() {
+			dbInstance.DBInstanceStatus = aws.String("stopped")
+		})
+
+		It("only counts the storage", func() {
+			costs, err := rdsBroker.InstanceCosts()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(costs[0].MonthlyCost).To(BeNumerically("~", 100*0.125, 0.001))
+		})
+	})
+
+	Context("when the instance class isn't in the price table", func() {
+		BeforeEach(func() {
+			dbInstance.DBInstanceClass = aws.String("db.m5.large")
+		})
+
+		It("leaves the instance out", func() {
+			costs, err := rdsBroker.InstanceCosts()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(costs).To(BeEmpty())
+		})
+	})
+
+	Context("when there is no price table", func() {
+		BeforeEach(func() {
+			priceTable = nil
+		})
+
+		It("doesn't estimate anything", func() {
+			costs, err := rdsBroker.InstanceCosts()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(costs).To(BeEmpty())
+			Expect(rdsInstance.DescribeByTagCallCount()).To(Equal(0))
+		})
+
+		It("leaves the cost out of the metrics", func() {
+			Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+			recorder := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/metrics", nil))
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).ToNot(ContainSubstring("rds_broker_instance_estimated_monthly_cost"))
+		})
+	})
+
+	It("serves the estimated cost in the metrics", func() {
+		Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+		recorder := httptest.NewRecorder()
+		rdsBroker.AdminHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/metrics", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(ContainSubstring(
+			"# TYPE rds_broker_instance_estimated_monthly_cost gauge\n" +
+				`rds_broker_instance_estimated_monthly_cost{instance_id="instance-1",db_instance_identifier="cf-instance-1",organization_id="organization-id",space_id="space-id",plan_id="Plan-1",region="eu-west-1",currency="USD"} 41.70` + "\n",
+		))
+	})
+})
@@ -0,0 +1,144 @@
+package rdsbroker
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// dataExportTimeFormat is the time of an export in the keys of its objects.
+const dataExportTimeFormat = "20060102T150405Z"
+
+// DataExportStore is where the data of deprovisioned instances is exported
+// to.
+type DataExportStore interface {
+	PutObject(*s3.PutObjectInput) (*s3.PutObjectOutput, error)
+}
+
+// SetDataExportStore sets where the data of deprovisioned instances is
+// exported to. The export_data_on_deprovision parameter is refused, and
+// deprovisions don't export, until it is set.
+func (b *RDSBroker) SetDataExportStore(store DataExportStore) {
+	b.dataExportStore = store
+}
+
+// checkDataExportAllowed checks the data of an instance can be exported to
+// the bucket when it is deprovisioned: the instance is Postgres, and the
+// bucket is one of those its organization is allowed to export to, as the
+// buckets belong to tenants.
+func (b *RDSBroker) checkDataExportAllowed(organizationGUID string, servicePlan ServicePlan, bucket string) error {
+	if engine := aws.StringValue(servicePlan.RDSProperties.Engine); engine != "postgres" {
+		return newUserError(ErrCodeUnsupportedByEngine, "Data exports not supported for engine '%s'", engine)
+	}
+	if b.dataExportStore == nil || !containsString(b.organizationDataExportBuckets[organizationGUID], bucket) {
+		return newUserError(ErrCodeDataExportNotAllowed, "Data can't be exported to bucket '%s'", bucket)
+	}
+	return nil
+}
+
+// dataExportOnDeprovisionBucket is the bucket the data of an instance is to
+// be exported to when it is deprovisioned, or empty if it isn't, or exports
+// aren't enabled.
+func (b *RDSBroker) dataExportOnDeprovisionBucket(instanceID string) (string, error) {
+	if b.dataExportStore == nil {
+		return "", nil
+	}
+	return b.dbInstance.GetTag(b.dbInstanceIdentifier(instanceID), awsrds.TagDataExportOnDeprovision)
+}
+
+// processDataExport exports the data of a soft deleted instance which was
+// deprovisioned with export_data_on_deprovision, starting it first if it
+// was stopped, then removes the tag asking for it so that the instance can
+// be purged.
+func (b *RDSBroker) processDataExport(dbInstance *rds.DBInstance, tagsByName map[string]string, now time.Time) error {
+	dbInstanceIdentifier := aws.StringValue(dbInstance.DBInstanceIdentifier)
+	status := aws.StringValue(dbInstance.DBInstanceStatus)
+	logData := lager.Data{"dbInstanceIdentifier": dbInstanceIdentifier, "status": status}
+
+	switch status {
+	case "stopped":
+		b.logger.Info("process-soft-deleted.start-for-export", logData)
+		return b.dbInstance.Start(dbInstanceIdentifier)
+	case "available":
+	default:
+		return nil
+	}
+
+	if b.dataExportStore == nil {
+		return fmt.Errorf("Data exports are not enabled, so the data of DB Instance '%s' can't be exported", dbInstanceIdentifier)
+	}
+
+	instanceID := b.dbInstanceIdentifierToServiceInstanceID(dbInstanceIdentifier)
+	bucket := tagsByName[awsrds.TagDataExport]
+	dbNames := append(
+		[]string{b.dbNameFromDBInstance(instanceID, dbInstance)},
+		unpackAdditionalDatabases(tagsByName[awsrds.TagAdditionalDatabases])...,
+	)
+	for _, dbName := range dbNames {
+		key := fmt.Sprintf("%s/%s/%s.dump", instanceID, now.UTC().Format(dataExportTimeFormat), dbName)
+		b.logger.Info("process-soft-deleted.export-data", lager.Data{
+			"dbInstanceIdentifier": dbInstanceIdentifier,
+			"bucket":               bucket,
+			"key":                  key,
+		})
+		if err := b.exportDatabase(instanceID, dbName, dbInstance, bucket, key); err != nil {
+			return fmt.Errorf("Exporting database '%s' to bucket '%s': %s", dbName, bucket, err)
+		}
+	}
+
+	return b.removeTag(dbInstanceIdentifier, awsrds.TagDataExport)
+}
+
+// exportDatabase dumps a database to a temporary file, so that its length
+// is known, and uploads it. The bucket owner is given full control of the
+// object, as the bucket belongs to the tenant.
+func (b *RDSBroker) exportDatabase(instanceID string, dbName string, dbInstance *rds.DBInstance, bucket string, key string) error {
+	dumpFile, err := os.CreateTemp("", "rds-broker-export-*.dump")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(dumpFile.Name())
+	defer dumpFile.Close()
+
+	sqlEngine, err := b.openSQLEngineForDBInstance(instanceID, dbName, dbInstance)
+	if err != nil {
+		return err
+	}
+	err = sqlEngine.ExportData(dumpFile)
+	sqlEngine.Close()
+	if err != nil {
+		return err
+	}
+
+	if _, err := dumpFile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = b.dataExportStore.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        dumpFile,
+		ContentType: aws.String("application/octet-stream"),
+		ACL:         aws.String(s3.ObjectCannedACLBucketOwnerFullControl),
+	})
+	return err
+}
+
+// dataExportBucketForProvision checks the bucket a new instance asks for its
+// data to be exported to when it is deprovisioned, if any.
+func (b *RDSBroker) dataExportBucketForProvision(organizationGUID string, servicePlan ServicePlan, bucket *string) (string, error) {
+	if aws.StringValue(bucket) == "" {
+		return "", nil
+	}
+	if err := b.checkDataExportAllowed(organizationGUID, servicePlan, *bucket); err != nil {
+		return "", err
+	}
+	return *bucket, nil
+}
@@ -0,0 +1,289 @@
+package rdsbroker_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+	sqlfake "github.com/alphagov/paas-rds-broker/sqlengine/fakes"
+)
+
+var _ = Describe("Data exports", func() {
+	var (
+		rdsInstance *rdsfake.FakeRDSInstance
+		sqlEngine   *sqlfake.FakeSQLEngine
+		store       *fakeInventoryStore
+		rdsBroker   *RDSBroker
+		dbInstance  *rds.DBInstance
+		tags        map[string]string
+	)
+
+	BeforeEach(func() {
+		dbInstance = testDBInstance()
+		dbInstance.DBParameterGroups = []*rds.DBParameterGroupStatus{
+			{DBParameterGroupName: aws.String("default")},
+		}
+		tags = map[string]string{
+			"Broker Name":                "mybroker",
+			"Plan ID":                    "Plan-1",
+			"chargeable_entity":          "instance-1",
+			"SkipFinalSnapshot":          "true",
+			"Organization ID":            "organization-id",
+			"Data Export On Deprovision": "tenant-exports",
+		}
+
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		rdsInstance.DescribeByTagStub = func(key, value string, opts ...awsrds.DescribeOption) ([]*rds.DBInstance, error) {
+			return []*rds.DBInstance{dbInstance}, nil
+		}
+		rdsInstance.DescribeStub = func(id string) (*rds.DBInstance, error) {
+			return dbInstance, nil
+		}
+		rdsInstance.GetResourceTagsStub = func(arn string, opts ...awsrds.DescribeOption) ([]*rds.Tag, error) {
+			return awsrds.BuildRDSTags(tags), nil
+		}
+		rdsInstance.GetTagStub = func(id, tagKey string) (string, error) {
+			return tags[tagKey], nil
+		}
+		rdsInstance.ModifyStub = func(input *rds.ModifyDBInstanceInput) (*rds.DBInstance, error) {
+			return dbInstance, nil
+		}
+
+		sqlEngine = &sqlfake.FakeSQLEngine{}
+		sqlEngine.ExportDataStub = func(w io.Writer) error {
+			_, err := io.WriteString(w, "PGDMP")
+			return err
+		}
+		store = &fakeInventoryStore{}
+
+		config := testConfig()
+		config.AllowUserProvisionParameters = true
+		config.AllowUserUpdateParameters = true
+		config.OrganizationDataExportBuckets = map[string][]string{
+			"organization-id":       {"tenant-exports"},
+			"other-organization-id": {"other-tenant-exports"},
+		}
+		config.FillDefaults()
+		rdsBroker = newTestBroker(config, rdsInstance, sqlEngine, lagertest.NewTestLogger("data_export_test"))
+		rdsBroker.SetDataExportStore(store)
+	})
+
+	Describe("provisioning with export_data_on_deprovision", func() {
+		var details domain.ProvisionDetails
+
+		BeforeEach(func() {
+			details = domain.ProvisionDetails{
+				ServiceID:        "Service-1",
+				PlanID:           "Plan-1",
+				OrganizationGUID: "organization-id",
+				SpaceGUID:        "space-id",
+				RawParameters:    json.RawMessage(`{"export_data_on_deprovision": "tenant-exports"}`),
+			}
+		})
+
+		It("tags the instance with the bucket", func() {
+			_, err := rdsBroker.Provision(context.Background(), "instance-2", details, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rdsInstance.CreateCallCount()).To(Equal(1))
+			tagsByName := awsrds.RDSTagsValues(rdsInstance.CreateArgsForCall(0).Tags)
+			Expect(tagsByName).To(HaveKeyWithValue("Data Export On Deprovision", "tenant-exports"))
+		})
+
+		It("fails for buckets which aren't allowed", func() {
+			details.RawParameters = json.RawMessage(`{"export_data_on_deprovision": "other-bucket"}`)
+
+			_, err := rdsBroker.Provision(context.Background(), "instance-2", details, true)
+			Expect(err).To(MatchError("Data can't be exported to bucket 'other-bucket'"))
+			Expect(rdsInstance.CreateCallCount()).To(BeZero())
+		})
+
+		It("fails for buckets which only other organizations are allowed", func() {
+			details.RawParameters = json.RawMessage(`{"export_data_on_deprovision": "other-tenant-exports"}`)
+
+			_, err := rdsBroker.Provision(context.Background(), "instance-2", details, true)
+			Expect(err).To(MatchError("Data can't be exported to bucket 'other-tenant-exports'"))
+			Expect(rdsInstance.CreateCallCount()).To(BeZero())
+		})
+
+		It("fails for plans which aren't Postgres", func() {
+			details.PlanID = "Plan-2"
+
+			_, err := rdsBroker.Provision(context.Background(), "instance-2", details, true)
+			Expect(err).To(MatchError("Data exports not supported for engine 'mysql'"))
+			Expect(rdsInstance.CreateCallCount()).To(BeZero())
+		})
+	})
+
+	Describe("updating export_data_on_deprovision", func() {
+		var details domain.UpdateDetails
+
+		BeforeEach(func() {
+			delete(tags, "Data Export On Deprovision")
+			details = domain.UpdateDetails{
+				ServiceID:      "Service-1",
+				PlanID:         "Plan-1",
+				PreviousValues: domain.PreviousValues{PlanID: "Plan-1"},
+				RawParameters:  json.RawMessage(`{"export_data_on_deprovision": "tenant-exports"}`),
+			}
+		})
+
+		It("tags the instance with the bucket", func() {
+			_, err := rdsBroker.Update(context.Background(), "instance-1", details, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+			_, addedTags := rdsInstance.AddTagsToResourceArgsForCall(0)
+			Expect(awsrds.RDSTagsValues(addedTags)).To(HaveKeyWithValue("Data Export On Deprovision", "tenant-exports"))
+		})
+
+		It("removes the tag when set to an empty string", func() {
+			tags["Data Export On Deprovision"] = "tenant-exports"
+			details.RawParameters = json.RawMessage(`{"export_data_on_deprovision": ""}`)
+
+			_, err := rdsBroker.Update(context.Background(), "instance-1", details, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rdsInstance.RemoveTagCallCount()).To(Equal(1))
+			_, tagKey := rdsInstance.RemoveTagArgsForCall(0)
+			Expect(tagKey).To(Equal("Data Export On Deprovision"))
+		})
+
+		It("fails for buckets which aren't allowed", func() {
+			details.RawParameters = json.RawMessage(`{"export_data_on_deprovision": "other-bucket"}`)
+
+			_, err := rdsBroker.Update(context.Background(), "instance-1", details, true)
+			Expect(err).To(MatchError("Data can't be exported to bucket 'other-bucket'"))
+			Expect(rdsInstance.ModifyCallCount()).To(BeZero())
+		})
+
+		It("fails for buckets which the instance's organization isn't allowed", func() {
+			tags["Organization ID"] = "other-organization-id"
+
+			_, err := rdsBroker.Update(context.Background(), "instance-1", details, true)
+			Expect(err).To(MatchError("Data can't be exported to bucket 'tenant-exports'"))
+			Expect(rdsInstance.ModifyCallCount()).To(BeZero())
+		})
+	})
+
+	Describe("deprovisioning", func() {
+		var details domain.DeprovisionDetails
+
+		BeforeEach(func() {
+			details = domain.DeprovisionDetails{ServiceID: "Service-1", PlanID: "Plan-1"}
+		})
+
+		It("soft deletes the instance, tagged to have its data exported, instead of deleting it", func() {
+			_, err := rdsBroker.Deprovision(context.Background(), "instance-1", details, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rdsInstance.DeleteCallCount()).To(BeZero())
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+			_, addedTags := rdsInstance.AddTagsToResourceArgsForCall(0)
+			Expect(awsrds.RDSTagsValues(addedTags)).To(HaveKeyWithValue("Pending Data Export", "tenant-exports"))
+			Expect(awsrds.RDSTagsValues(addedTags)).To(HaveKey("Purge After"))
+
+			Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
+			Expect(aws.StringValue(rdsInstance.ModifyArgsForCall(0).NewDBInstanceIdentifier)).To(Equal("cf-instance-1-deleted"))
+		})
+
+		It("deletes instances without the tag", func() {
+			delete(tags, "Data Export On Deprovision")
+
+			_, err := rdsBroker.Deprovision(context.Background(), "instance-1", details, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+		})
+
+		It("deletes the instance if data exports aren't enabled", func() {
+			rdsBroker.SetDataExportStore(nil)
+
+			_, err := rdsBroker.Deprovision(context.Background(), "instance-1", details, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+		})
+	})
+
+	Describe("ProcessSoftDeletedInstances", func() {
+		BeforeEach(func() {
+			dbInstance.DBInstanceIdentifier = aws.String("cf-instance-1-deleted")
+			tags["Purge After"] = time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+			tags["Pending Data Export"] = "tenant-exports"
+			tags["Additional Databases"] = "reporting"
+		})
+
+		It("exports each database to the bucket, removes the tag and keeps the instance until the next run", func() {
+			Expect(rdsBroker.ProcessSoftDeletedInstances()).To(Succeed())
+
+			Expect(sqlEngine.ExportDataCallCount()).To(Equal(2))
+			_, _, dbname, _, _ := sqlEngine.OpenArgsForCall(0)
+			Expect(dbname).To(Equal("mydb"))
+			_, _, dbname, _, _ = sqlEngine.OpenArgsForCall(1)
+			Expect(dbname).To(Equal("reporting"))
+
+			Expect(store.objects).To(HaveLen(2))
+			Expect(store.objects[0].bucket).To(Equal("tenant-exports"))
+			Expect(store.objects[0].key).To(MatchRegexp(`^instance-1/\d{8}T\d{6}Z/mydb\.dump$`))
+			Expect(store.objects[0].body).To(Equal([]byte("PGDMP")))
+			Expect(store.objects[1].key).To(MatchRegexp(`^instance-1/\d{8}T\d{6}Z/reporting\.dump$`))
+
+			Expect(rdsInstance.RemoveTagCallCount()).To(Equal(1))
+			id, tagKey := rdsInstance.RemoveTagArgsForCall(0)
+			Expect(id).To(Equal("cf-instance-1-deleted"))
+			Expect(tagKey).To(Equal("Pending Data Export"))
+			Expect(rdsInstance.DeleteCallCount()).To(BeZero())
+		})
+
+		It("starts the instance if it is stopped", func() {
+			dbInstance.DBInstanceStatus = aws.String("stopped")
+
+			Expect(rdsBroker.ProcessSoftDeletedInstances()).To(Succeed())
+
+			Expect(rdsInstance.StartCallCount()).To(Equal(1))
+			Expect(sqlEngine.ExportDataCallCount()).To(BeZero())
+			Expect(rdsInstance.DeleteCallCount()).To(BeZero())
+		})
+
+		It("waits for the instance to be renamed", func() {
+			dbInstance.DBInstanceStatus = aws.String("renaming")
+
+			Expect(rdsBroker.ProcessSoftDeletedInstances()).To(Succeed())
+
+			Expect(sqlEngine.ExportDataCallCount()).To(BeZero())
+			Expect(rdsInstance.StopCallCount()).To(BeZero())
+			Expect(rdsInstance.DeleteCallCount()).To(BeZero())
+		})
+
+		It("keeps the tag and the instance if the export fails", func() {
+			sqlEngine.ExportDataReturns(errors.New("pg_dump failed: exit status 1"))
+			sqlEngine.ExportDataStub = nil
+
+			Expect(rdsBroker.ProcessSoftDeletedInstances()).To(Succeed())
+
+			Expect(store.objects).To(BeEmpty())
+			Expect(rdsInstance.RemoveTagCallCount()).To(BeZero())
+			Expect(rdsInstance.DeleteCallCount()).To(BeZero())
+		})
+
+		It("purges the instance once its data has been exported", func() {
+			delete(tags, "Pending Data Export")
+
+			Expect(rdsBroker.ProcessSoftDeletedInstances()).To(Succeed())
+
+			Expect(sqlEngine.ExportDataCallCount()).To(BeZero())
+			Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,250 @@
+package rdsbroker
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	"github.com/alphagov/paas-rds-broker/sqlengine"
+)
+
+// dataImportURLExpiry is how long the URLs presigned for a data import can
+// be used for. The import fetches them as soon as they are made.
+const dataImportURLExpiry = 15 * time.Minute
+
+// maxTagValueLength is the longest value RDS accepts for a tag.
+const maxTagValueLength = 256
+
+// importKeyPattern matches the S3 keys which can be imported from. They are
+// kept in a tag until the import has run, so are limited to the characters
+// RDS allows in tag values, without the colon which separates them.
+var importKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_./=+@-]+$`)
+
+// DataImportPresigner presigns URLs to fetch the objects data is imported
+// from.
+type DataImportPresigner interface {
+	PresignGetObject(bucket string, key string, expiry time.Duration) (string, error)
+}
+
+// S3Presigner presigns URLs with the broker's own credentials, so that data
+// can only be imported from the objects its IAM role can read.
+type S3Presigner struct {
+	s3 *s3.S3
+}
+
+func NewS3Presigner(s3Client *s3.S3) *S3Presigner {
+	return &S3Presigner{s3: s3Client}
+}
+
+func (p *S3Presigner) PresignGetObject(bucket string, key string, expiry time.Duration) (string, error) {
+	req, _ := p.s3.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	return req.Presign(expiry)
+}
+
+// SetDataImportPresigner sets how the objects of data imports are fetched.
+// The import_data provision parameter is refused until it is set.
+func (b *RDSBroker) SetDataImportPresigner(presigner DataImportPresigner) {
+	b.dataImportPresigner = presigner
+}
+
+// DataImportBucket is a bucket an organization can import data from, and
+// the prefix of the keys in it which belong to the organization.
+type DataImportBucket struct {
+	Bucket    string `json:"bucket"`
+	KeyPrefix string `json:"key_prefix"`
+}
+
+func (d DataImportBucket) Validate() error {
+	if d.Bucket == "" {
+		return errors.New("Must provide a non-empty bucket")
+	}
+	if d.KeyPrefix != "" && !importKeyPattern.MatchString(d.KeyPrefix) {
+		return fmt.Errorf("Key prefix '%s' must contain only letters, digits and the characters _ . / = + @ -", d.KeyPrefix)
+	}
+	return nil
+}
+
+// allows reports whether the key of the bucket is under the prefix.
+func (d DataImportBucket) allows(bucket string, key string) bool {
+	return d.Bucket == bucket && strings.HasPrefix(key, d.KeyPrefix)
+}
+
+// ImportDataParameters are the S3 objects the database of a new instance is
+// seeded with: a single SQL dump, or a CSV file for each table named after
+// it.
+type ImportDataParameters struct {
+	Format string   `json:"format"`
+	Bucket string   `json:"bucket"`
+	Keys   []string `json:"keys"`
+}
+
+func (p ImportDataParameters) validate() []error {
+	problems := []error{}
+	switch p.Format {
+	case sqlengine.ImportFormatSQL:
+		if len(p.Keys) != 1 {
+			problems = append(problems, fmt.Errorf("import_data: an SQL import must have exactly one key"))
+		}
+	case sqlengine.ImportFormatCSV:
+	default:
+		problems = append(problems, fmt.Errorf("import_data: format must be '%s' or '%s'", sqlengine.ImportFormatSQL, sqlengine.ImportFormatCSV))
+	}
+	if p.Bucket == "" {
+		problems = append(problems, fmt.Errorf("import_data: bucket must not be empty"))
+	}
+	if len(p.Keys) == 0 {
+		problems = append(problems, fmt.Errorf("import_data: keys must not be empty"))
+	}
+
+	for _, key := range p.Keys {
+		if !importKeyPattern.MatchString(key) {
+			problems = append(problems, fmt.Errorf("import_data: key '%s' must contain only letters, digits and the characters _ . / = + @ -", key))
+			continue
+		}
+		if p.Format == sqlengine.ImportFormatCSV {
+			if table, ok := csvImportTable(key); !ok {
+				problems = append(problems, fmt.Errorf("import_data: key '%s' must end with the name of a table and .csv, such as 'seed/users.csv'", key))
+			} else if !additionalDatabaseNamePattern.MatchString(table) {
+				problems = append(problems, fmt.Errorf("import_data: table '%s' must start with a lowercase letter and contain only lowercase letters, digits and underscores, up to 63 characters", table))
+			}
+		}
+	}
+	return problems
+}
+
+// csvImportTable is the table a CSV file is imported into, from its key.
+func csvImportTable(key string) (string, bool) {
+	base := path.Base(key)
+	if !strings.HasSuffix(base, ".csv") || base == ".csv" {
+		return "", false
+	}
+	return strings.TrimSuffix(base, ".csv"), true
+}
+
+// packDataImport records a data import in a tag, as its format, bucket and
+// keys separated by colons.
+func packDataImport(p ImportDataParameters) string {
+	return strings.Join(append([]string{p.Format, p.Bucket}, p.Keys...), ":")
+}
+
+func unpackDataImport(tagValue string) (ImportDataParameters, error) {
+	parts := strings.Split(tagValue, ":")
+	if len(parts) < 3 {
+		return ImportDataParameters{}, fmt.Errorf("Invalid data import tag '%s'", tagValue)
+	}
+	return ImportDataParameters{Format: parts[0], Bucket: parts[1], Keys: parts[2:]}, nil
+}
+
+// checkDataImportAllowed checks the data a new instance is to be seeded with
+// can be imported: the instance is Postgres, and every key is under the
+// prefix of one of the buckets its organization is allowed to import from,
+// as the broker can read the data of other organizations too.
+func (b *RDSBroker) checkDataImportAllowed(organizationGUID string, servicePlan ServicePlan, importData ImportDataParameters) error {
+	if engine := aws.StringValue(servicePlan.RDSProperties.Engine); engine != "postgres" {
+		return newUserError(ErrCodeUnsupportedByEngine, "Data imports not supported for engine '%s'", engine)
+	}
+	allowedBuckets := b.organizationDataImportBuckets[organizationGUID]
+	if b.dataImportPresigner == nil || !allowsBucket(allowedBuckets, importData.Bucket) {
+		return newUserError(ErrCodeDataImportNotAllowed, "Data can't be imported from bucket '%s'", importData.Bucket)
+	}
+	for _, key := range importData.Keys {
+		if !allowsKey(allowedBuckets, importData.Bucket, key) {
+			return newUserError(ErrCodeDataImportNotAllowed, "Data can't be imported from key '%s' of bucket '%s'", key, importData.Bucket)
+		}
+	}
+	if len(packDataImport(importData)) > maxTagValueLength {
+		return newUserError(ErrCodeInvalidParameters, "import_data: the bucket and keys must be %d characters or less altogether", maxTagValueLength-len(importData.Format)-len(importData.Keys)-1)
+	}
+	return nil
+}
+
+func allowsBucket(allowedBuckets []DataImportBucket, bucket string) bool {
+	for _, allowed := range allowedBuckets {
+		if allowed.Bucket == bucket {
+			return true
+		}
+	}
+	return false
+}
+
+func allowsKey(allowedBuckets []DataImportBucket, bucket string, key string) bool {
+	for _, allowed := range allowedBuckets {
+		if allowed.allows(bucket, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureDataImported seeds the database of a new instance with the data it
+// was provisioned with in the background, once it is available and any
+// template schema has been copied into it, returning true while it is being
+// imported. The tag asking for the import is removed once it is done.
+func (b *RDSBroker) ensureDataImported(instanceID string, dbInstance *rds.DBInstance, tagsByName map[string]string) (bool, error) {
+	tagValue, exists := tagsByName[awsrds.TagDataImport]
+	if !exists {
+		return false, nil
+	}
+	if b.dataImportPresigner == nil {
+		return false, fmt.Errorf("Data imports are not enabled, so the data of DB Instance '%s' can't be imported", b.dbInstanceIdentifier(instanceID))
+	}
+
+	importData, err := unpackDataImport(tagValue)
+	if err != nil {
+		return false, err
+	}
+
+	importing, err := b.ensureBackgroundStep(instanceID, dbInstance, tagsByName, awsrds.TagDataImportProgress, func() error {
+		return b.importData(instanceID, dbInstance, importData)
+	})
+	if err != nil {
+		return false, fmt.Errorf("Importing data from bucket '%s': %s", importData.Bucket, err)
+	}
+	return importing, nil
+}
+
+func (b *RDSBroker) importData(instanceID string, dbInstance *rds.DBInstance, importData ImportDataParameters) error {
+	b.logger.Info("import-data", lager.Data{
+		instanceIDLogKey: instanceID,
+		"format":         importData.Format,
+		"bucket":         importData.Bucket,
+		"keys":           importData.Keys,
+	})
+
+	objects := []sqlengine.ImportObject{}
+	for _, key := range importData.Keys {
+		url, err := b.dataImportPresigner.PresignGetObject(importData.Bucket, key, dataImportURLExpiry)
+		if err != nil {
+			return err
+		}
+		name := key
+		if table, ok := csvImportTable(key); ok && importData.Format == sqlengine.ImportFormatCSV {
+			name = table
+		}
+		objects = append(objects, sqlengine.ImportObject{Name: name, URL: url})
+	}
+
+	sqlEngine, err := b.openSQLEngineForDBInstance(instanceID, b.dbNameFromDBInstance(instanceID, dbInstance), dbInstance)
+	if err != nil {
+		return err
+	}
+	defer sqlEngine.Close()
+
+	if err := sqlEngine.ImportData(importData.Format, objects); err != nil {
+		return err
+	}
+
+	return b.removeTag(b.dbInstanceIdentifier(instanceID), awsrds.TagDataImport)
+}
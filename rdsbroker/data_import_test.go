@@ -0,0 +1,271 @@
+package rdsbroker_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+	"github.com/alphagov/paas-rds-broker/sqlengine"
+	sqlfake "github.com/alphagov/paas-rds-broker/sqlengine/fakes"
+)
+
+type fakeDataImportPresigner struct {
+	expiries []time.Duration
+	err      error
+}
+
+func (f *fakeDataImportPresigner) PresignGetObject(bucket string, key string, expiry time.Duration) (string, error) {
+	f.expiries = append(f.expiries, expiry)
+	if f.err != nil {
+		return "", f.err
+	}
+	return fmt.Sprintf("https://%s.s3.amazonaws.com/%s?X-Amz-Signature=signed", bucket, key), nil
+}
+
+var _ = Describe("Data imports", func() {
+	var (
+		rdsInstance   *rdsfake.FakeRDSInstance
+		sqlEngine     *sqlfake.FakeSQLEngine
+		presigner     *fakeDataImportPresigner
+		rdsBroker     *RDSBroker
+		newDBInstance *rds.DBInstance
+		instanceTags  map[string]string
+	)
+
+	BeforeEach(func() {
+		newDBInstance = testDBInstance()
+		instanceTags = map[string]string{
+			"Broker Name":         "mybroker",
+			"Plan ID":             "Plan-1",
+			"Pending Data Import": "csv:seed-bucket:seed/users.csv:seed/orders.csv",
+		}
+
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		rdsInstance.DescribeReturns(newDBInstance, nil)
+		rdsInstance.GetResourceTagsStub = func(arn string, opts ...awsrds.DescribeOption) ([]*rds.Tag, error) {
+			return awsrds.BuildRDSTags(instanceTags), nil
+		}
+
+		sqlEngine = &sqlfake.FakeSQLEngine{}
+		presigner = &fakeDataImportPresigner{}
+
+		config := testConfig()
+		config.AllowUserProvisionParameters = true
+		config.OrganizationDataImportBuckets = map[string][]DataImportBucket{
+			"organization-id": {
+				{Bucket: "seed-bucket", KeyPrefix: "dumps/"},
+				{Bucket: "seed-bucket", KeyPrefix: "seed/"},
+			},
+			"other-organization-id": {{Bucket: "other-bucket"}},
+		}
+		config.FillDefaults()
+		rdsBroker = newTestBroker(config, rdsInstance, sqlEngine, lagertest.NewTestLogger("data_import_test"))
+		rdsBroker.SetDataImportPresigner(presigner)
+	})
+
+	Describe("provisioning with import_data", func() {
+		var details domain.ProvisionDetails
+
+		BeforeEach(func() {
+			details = domain.ProvisionDetails{
+				ServiceID:        "Service-1",
+				PlanID:           "Plan-1",
+				OrganizationGUID: "organization-id",
+				SpaceGUID:        "space-id",
+				RawParameters:    json.RawMessage(`{"import_data": {"format": "sql", "bucket": "seed-bucket", "keys": ["dumps/seed.sql"]}}`),
+			}
+		})
+
+		It("creates the instance tagged to have the data imported into it", func() {
+			_, err := rdsBroker.Provision(context.Background(), "instance-1", details, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rdsInstance.CreateCallCount()).To(Equal(1))
+			tagsByName := awsrds.RDSTagsValues(rdsInstance.CreateArgsForCall(0).Tags)
+			Expect(tagsByName).To(HaveKeyWithValue("Pending Data Import", "sql:seed-bucket:dumps/seed.sql"))
+		})
+
+		It("fails for buckets which aren't allowed", func() {
+			details.RawParameters = json.RawMessage(`{"import_data": {"format": "sql", "bucket": "other-bucket", "keys": ["dumps/seed.sql"]}}`)
+
+			_, err := rdsBroker.Provision(context.Background(), "instance-1", details, true)
+			Expect(err).To(MatchError("Data can't be imported from bucket 'other-bucket'"))
+			Expect(rdsInstance.CreateCallCount()).To(BeZero())
+		})
+
+		It("fails for buckets which only other organizations are allowed", func() {
+			details.OrganizationGUID = "other-organization-id"
+
+			_, err := rdsBroker.Provision(context.Background(), "instance-1", details, true)
+			Expect(err).To(MatchError("Data can't be imported from bucket 'seed-bucket'"))
+			Expect(rdsInstance.CreateCallCount()).To(BeZero())
+		})
+
+		It("fails for keys outside the organization's prefixes", func() {
+			details.RawParameters = json.RawMessage(`{"import_data": {"format": "csv", "bucket": "seed-bucket", "keys": ["seed/users.csv", "other-org/users.csv"]}}`)
+
+			_, err := rdsBroker.Provision(context.Background(), "instance-1", details, true)
+			Expect(err).To(MatchError("Data can't be imported from key 'other-org/users.csv' of bucket 'seed-bucket'"))
+			Expect(rdsInstance.CreateCallCount()).To(BeZero())
+		})
+
+		It("fails if data imports aren't enabled", func() {
+			rdsBroker.SetDataImportPresigner(nil)
+
+			_, err := rdsBroker.Provision(context.Background(), "instance-1", details, true)
+			Expect(err).To(MatchError("Data can't be imported from bucket 'seed-bucket'"))
+			Expect(rdsInstance.CreateCallCount()).To(BeZero())
+		})
+
+		It("fails for plans which aren't Postgres", func() {
+			details.PlanID = "Plan-2"
+
+			_, err := rdsBroker.Provision(context.Background(), "instance-1", details, true)
+			Expect(err).To(MatchError("Data imports not supported for engine 'mysql'"))
+			Expect(rdsInstance.CreateCallCount()).To(BeZero())
+		})
+
+		DescribeTable("refuses invalid imports",
+			func(importData string, expectedError string) {
+				details.RawParameters = json.RawMessage(fmt.Sprintf(`{"import_data": %s}`, importData))
+
+				_, err := rdsBroker.Provision(context.Background(), "instance-1", details, true)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(expectedError))
+				Expect(rdsInstance.CreateCallCount()).To(BeZero())
+			},
+			Entry("unknown format", `{"format": "parquet", "bucket": "seed-bucket", "keys": ["seed.parquet"]}`, "import_data: format must be 'sql' or 'csv'"),
+			Entry("no keys", `{"format": "csv", "bucket": "seed-bucket", "keys": []}`, "import_data: keys must not be empty"),
+			Entry("several SQL dumps", `{"format": "sql", "bucket": "seed-bucket", "keys": ["dumps/a.sql", "dumps/b.sql"]}`, "import_data: an SQL import must have exactly one key"),
+			Entry("key with a colon", `{"format": "sql", "bucket": "seed-bucket", "keys": ["dumps:seed.sql"]}`, "import_data: key 'dumps:seed.sql' must contain only letters"),
+			Entry("CSV key without .csv", `{"format": "csv", "bucket": "seed-bucket", "keys": ["seed/users.txt"]}`, "import_data: key 'seed/users.txt' must end with the name of a table and .csv"),
+			Entry("CSV key which isn't a table name", `{"format": "csv", "bucket": "seed-bucket", "keys": ["seed/Users.csv"]}`, "import_data: table 'Users' must start with a lowercase letter"),
+			Entry("combined with a restore", `{"format": "sql", "bucket": "seed-bucket", "keys": ["dumps/seed.sql"]}, "restore_previous": true`, "Cannot use import_data along with adopt_db_instance or the restore parameters"),
+		)
+	})
+
+	Describe("polling the provision", func() {
+		var pollDetails domain.PollDetails
+
+		BeforeEach(func() {
+			pollDetails = domain.PollDetails{ServiceID: "Service-1", PlanID: "Plan-1"}
+		})
+
+		It("imports each object into the new instance's database in the background and removes the tag", func() {
+			lastOperation, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lastOperation.State).To(Equal(domain.InProgress))
+			Expect(lastOperation.Description).To(ContainSubstring("is having its data imported"))
+
+			_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+			Expect(awsrds.RDSTagsValues(tags)["Data Import Progress"]).To(HavePrefix("running since "))
+
+			Eventually(rdsInstance.RemoveTagCallCount).Should(Equal(2))
+			address, _, dbname, _, _ := sqlEngine.OpenArgsForCall(0)
+			Expect(address).To(Equal("endpoint-address"))
+			Expect(dbname).To(Equal("mydb"))
+
+			Expect(sqlEngine.ImportDataCallCount()).To(Equal(1))
+			format, objects := sqlEngine.ImportDataArgsForCall(0)
+			Expect(format).To(Equal("csv"))
+			Expect(objects).To(Equal([]sqlengine.ImportObject{
+				{Name: "users", URL: "https://seed-bucket.s3.amazonaws.com/seed/users.csv?X-Amz-Signature=signed"},
+				{Name: "orders", URL: "https://seed-bucket.s3.amazonaws.com/seed/orders.csv?X-Amz-Signature=signed"},
+			}))
+			Expect(presigner.expiries).To(Equal([]time.Duration{15 * time.Minute, 15 * time.Minute}))
+
+			id, tagKey := rdsInstance.RemoveTagArgsForCall(0)
+			Expect(id).To(Equal("cf-instance-1"))
+			Expect(tagKey).To(Equal("Pending Data Import"))
+			_, tagKey = rdsInstance.RemoveTagArgsForCall(1)
+			Expect(tagKey).To(Equal("Data Import Progress"))
+		})
+
+		It("names SQL dumps after their key", func() {
+			instanceTags["Pending Data Import"] = "sql:seed-bucket:dumps/seed.sql"
+
+			_, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(sqlEngine.ImportDataCallCount).Should(Equal(1))
+			format, objects := sqlEngine.ImportDataArgsForCall(0)
+			Expect(format).To(Equal("sql"))
+			Expect(objects).To(ConsistOf(sqlengine.ImportObject{
+				Name: "dumps/seed.sql",
+				URL:  "https://seed-bucket.s3.amazonaws.com/dumps/seed.sql?X-Amz-Signature=signed",
+			}))
+		})
+
+		It("records the failure and keeps the tag if the data can't be imported", func() {
+			sqlEngine.ImportDataReturns(errors.New("Importing users: pq: relation \"users\" does not exist"))
+
+			lastOperation, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lastOperation.State).To(Equal(domain.InProgress))
+
+			Eventually(rdsInstance.AddTagsToResourceCallCount).Should(Equal(2))
+			_, tags := rdsInstance.AddTagsToResourceArgsForCall(1)
+			Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue("Data Import Progress", "failed: Importing users: pq: relation _users_ does not exist"))
+			Expect(rdsInstance.RemoveTagCallCount()).To(BeZero())
+		})
+
+		It("records the failure if the objects can't be presigned", func() {
+			presigner.err = errors.New("no credentials")
+
+			_, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(rdsInstance.AddTagsToResourceCallCount).Should(Equal(2))
+			_, tags := rdsInstance.AddTagsToResourceArgsForCall(1)
+			Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue("Data Import Progress", "failed: no credentials"))
+			Expect(sqlEngine.ImportDataCallCount()).To(BeZero())
+		})
+
+		It("only reports the state of an import which is running", func() {
+			instanceTags["Data Import Progress"] = "running since " + time.Now().UTC().Format(time.RFC3339)
+
+			lastOperation, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lastOperation.State).To(Equal(domain.InProgress))
+			Consistently(sqlEngine.ImportDataCallCount).Should(BeZero())
+		})
+
+		It("fails the provision once the import has failed", func() {
+			instanceTags["Data Import Progress"] = "failed: no credentials"
+
+			lastOperation, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+			Expect(err).To(MatchError("Importing data from bucket 'seed-bucket': no credentials"))
+			Expect(lastOperation.State).To(Equal(domain.Failed))
+			Expect(sqlEngine.ImportDataCallCount()).To(BeZero())
+		})
+
+		It("waits for the template schema to be copied first", func() {
+			instanceTags["Pending Schema Copy From"] = "template-1"
+			instanceTags["Schema Copy Progress"] = "running since " + time.Now().UTC().Format(time.RFC3339)
+
+			lastOperation, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lastOperation.State).To(Equal(domain.InProgress))
+			Consistently(sqlEngine.ImportDataCallCount).Should(BeZero())
+		})
+
+		It("doesn't import data into instances without the tag", func() {
+			delete(instanceTags, "Pending Data Import")
+
+			_, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sqlEngine.ImportDataCallCount()).To(BeZero())
+		})
+	})
+})
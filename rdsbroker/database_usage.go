@@ -0,0 +1,99 @@
+package rdsbroker
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// DatabaseUsage is how much of its DB instance's storage a service
+// instance's database is using, as reported when fetching the instance.
+type DatabaseUsage struct {
+	SizeBytes                   int64     `json:"size_bytes"`
+	TableCount                  int64     `json:"table_count"`
+	AllocatedStorageUsedPercent float64   `json:"allocated_storage_used_percent"`
+	GatheredAt                  time.Time `json:"gathered_at"`
+}
+
+// databaseUsageCache remembers the usage of each instance's database for a
+// while, as gathering it means connecting to the database. A nil or
+// zero-duration cache means usage isn't reported at all.
+type databaseUsageCache struct {
+	duration    time.Duration
+	timeNowFunc func() time.Time
+
+	lock    sync.Mutex
+	entries map[string]DatabaseUsage
+}
+
+func newDatabaseUsageCache(duration time.Duration) *databaseUsageCache {
+	return &databaseUsageCache{
+		duration:    duration,
+		timeNowFunc: time.Now,
+		entries:     map[string]DatabaseUsage{},
+	}
+}
+
+func (c *databaseUsageCache) enabled() bool {
+	return c != nil && c.duration > 0
+}
+
+func (c *databaseUsageCache) get(instanceID string) (DatabaseUsage, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	usage, ok := c.entries[instanceID]
+	if !ok {
+		return DatabaseUsage{}, false
+	}
+	if c.timeNowFunc().After(usage.GatheredAt.Add(c.duration)) {
+		delete(c.entries, instanceID)
+		return DatabaseUsage{}, false
+	}
+	return usage, true
+}
+
+func (c *databaseUsageCache) set(instanceID string, usage DatabaseUsage) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.entries[instanceID] = usage
+}
+
+// databaseUsage returns the usage of the database of an available DB
+// instance, from the cache if it was gathered recently enough.
+func (b *RDSBroker) databaseUsage(instanceID string, dbInstance *rds.DBInstance) (DatabaseUsage, error) {
+	if usage, ok := b.databaseUsageCache.get(instanceID); ok {
+		return usage, nil
+	}
+
+	b.logger.Debug("gather-database-usage", lager.Data{
+		instanceIDLogKey: instanceID,
+	})
+
+	dbName := b.dbNameFromDBInstance(instanceID, dbInstance)
+	sqlEngine, err := b.openSQLEngineForDBInstance(instanceID, dbName, dbInstance)
+	if err != nil {
+		return DatabaseUsage{}, err
+	}
+	defer sqlEngine.Close()
+
+	engineUsage, err := sqlEngine.DatabaseUsage()
+	if err != nil {
+		return DatabaseUsage{}, err
+	}
+
+	usage := DatabaseUsage{
+		SizeBytes:  engineUsage.SizeBytes,
+		TableCount: engineUsage.TableCount,
+		GatheredAt: b.databaseUsageCache.timeNowFunc(),
+	}
+	if allocatedGiB := aws.Int64Value(dbInstance.AllocatedStorage); allocatedGiB > 0 {
+		usage.AllocatedStorageUsedPercent = 100 * float64(usage.SizeBytes) / float64(allocatedGiB<<30)
+	}
+	b.databaseUsageCache.set(instanceID, usage)
+	return usage, nil
+}
@@ -0,0 +1,261 @@
+package rdsbroker
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	"github.com/alphagov/paas-rds-broker/awssecrets"
+)
+
+const (
+	dbProxyCreating  = "creating"
+	dbProxyAvailable = "available"
+)
+
+// ensureDBProxy creates the RDS Proxy for a DB instance whose plan has one,
+// and registers the DB instance as its target once the proxy is available.
+// The state of the proxy is kept in the "DB Proxy" tag, so that bindings
+// know when they can be given the proxy's endpoint.
+func (b *RDSBroker) ensureDBProxy(instanceID string, dbInstance *rds.DBInstance, tagsByName map[string]string) (asyncOperationTriggered bool, err error) {
+	servicePlan, ok := b.catalog.FindServicePlan(tagsByName[awsrds.TagPlanID])
+	if !ok || servicePlan.RDSProxy == nil {
+		return false, nil
+	}
+
+	proxyName := b.dbInstanceIdentifier(instanceID)
+
+	switch tagsByName[awsrds.TagDBProxy] {
+	case dbProxyAvailable:
+		return false, nil
+
+	case dbProxyCreating:
+		dbProxy, err := b.dbInstance.DescribeDBProxy(proxyName)
+		if err != nil {
+			return false, err
+		}
+		if aws.StringValue(dbProxy.Status) != rds.DBProxyStatusAvailable {
+			return true, nil
+		}
+
+		b.logger.Info("ensure-db-proxy.register-targets", lager.Data{
+			instanceIDLogKey: instanceID,
+			"proxyName":      proxyName,
+		})
+		if err := b.dbInstance.RegisterDBProxyTargets(proxyName, b.dbInstanceIdentifier(instanceID)); err != nil {
+			return false, err
+		}
+		return false, b.setDBProxyTag(dbInstance, dbProxyAvailable)
+
+	default:
+		b.logger.Info("ensure-db-proxy.create", lager.Data{
+			instanceIDLogKey: instanceID,
+			"proxyName":      proxyName,
+		})
+		err := b.dbInstance.CreateDBProxy(b.newCreateDBProxyInput(proxyName, servicePlan, tagsByName))
+		if err != nil {
+			return false, err
+		}
+		return true, b.setDBProxyTag(dbInstance, dbProxyCreating)
+	}
+}
+
+func (b *RDSBroker) newCreateDBProxyInput(proxyName string, servicePlan ServicePlan, tagsByName map[string]string) *rds.CreateDBProxyInput {
+	auth := []*rds.UserAuthConfig{}
+	for _, secretArn := range servicePlan.RDSProxy.AuthSecretArns {
+		auth = append(auth, &rds.UserAuthConfig{
+			AuthScheme: aws.String(rds.AuthSchemeSecrets),
+			IAMAuth:    aws.String(rds.IAMAuthModeDisabled),
+			SecretArn:  aws.String(secretArn),
+		})
+	}
+
+	return &rds.CreateDBProxyInput{
+		DBProxyName:         aws.String(proxyName),
+		EngineFamily:        aws.String(rdsProxyEngineFamily(servicePlan)),
+		RoleArn:             aws.String(servicePlan.RDSProxy.RoleArn),
+		Auth:                auth,
+		VpcSubnetIds:        aws.StringSlice(servicePlan.RDSProxy.VpcSubnetIds),
+		VpcSecurityGroupIds: aws.StringSlice(servicePlan.RDSProxy.VpcSecurityGroupIds),
+		RequireTLS:          aws.Bool(servicePlan.RDSProxy.RequireTLS),
+		Tags: awsrds.BuildRDSTags(map[string]string{
+			"Owner":                    "Cloud Foundry",
+			awsrds.TagBrokerName:       b.brokerName,
+			awsrds.TagChargeableEntity: tagsByName[awsrds.TagChargeableEntity],
+		}),
+	}
+}
+
+func (b *RDSBroker) setDBProxyTag(dbInstance *rds.DBInstance, state string) error {
+	return b.dbInstance.AddTagsToResource(
+		aws.StringValue(dbInstance.DBInstanceArn),
+		awsrds.BuildRDSTags(map[string]string{awsrds.TagDBProxy: state}),
+	)
+}
+
+// dbProxyEndpoint returns the endpoint of a DB instance's RDS Proxy, if it has
+// one which is ready to take connections.
+func (b *RDSBroker) dbProxyEndpoint(instanceID string, tagsByName map[string]string) (string, error) {
+	if tagsByName[awsrds.TagDBProxy] != dbProxyAvailable {
+		return "", fmt.Errorf("DB Instance '%s' has no RDS Proxy ready to use", b.dbInstanceIdentifier(instanceID))
+	}
+
+	dbProxy, err := b.dbInstance.DescribeDBProxy(b.dbInstanceIdentifier(instanceID))
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(dbProxy.Endpoint), nil
+}
+
+// SetSecretStore sets where the credentials of bindings made through an RDS
+// Proxy are kept for the proxy to read. No such bindings can be made until it
+// is set.
+func (b *RDSBroker) SetSecretStore(secretStore awssecrets.SecretStore) {
+	b.secretStore = secretStore
+}
+
+func (b *RDSBroker) dbProxySecretName(instanceID, bindingID string) string {
+	return b.dbInstanceIdentifier(instanceID) + "-" + bindingID
+}
+
+// allowThroughDBProxy keeps a binding user's credentials in a secret of its
+// own and adds the secret to those of the DB instance's RDS Proxy, as the
+// proxy only lets through the users it holds credentials for. The binding ID
+// is the description of the proxy's entry for the secret, which is how the
+// entry is found again on unbind.
+func (b *RDSBroker) allowThroughDBProxy(instanceID, bindingID, username, password string, tagsByName map[string]string) error {
+	secretName := b.dbProxySecretName(instanceID, bindingID)
+	secretArn, err := b.secretStore.CreateDBCredentials(secretName, username, password, map[string]string{
+		"Owner":                    "Cloud Foundry",
+		awsrds.TagBrokerName:       b.brokerName,
+		awsrds.TagChargeableEntity: tagsByName[awsrds.TagChargeableEntity],
+	})
+	if err != nil {
+		return err
+	}
+
+	b.logger.Info("allow-through-db-proxy", lager.Data{
+		instanceIDLogKey: instanceID,
+		bindingIDLogKey:  bindingID,
+	})
+	err = b.changeDBProxyAuth(instanceID, func(auth []*rds.UserAuthConfig) []*rds.UserAuthConfig {
+		return append(auth, &rds.UserAuthConfig{
+			AuthScheme:  aws.String(rds.AuthSchemeSecrets),
+			Description: aws.String(bindingID),
+			IAMAuth:     aws.String(rds.IAMAuthModeDisabled),
+			SecretArn:   aws.String(secretArn),
+		})
+	})
+	if err != nil {
+		if deleteErr := b.secretStore.DeleteSecret(secretName); deleteErr != nil {
+			b.logger.Error("allow-through-db-proxy.delete-secret", deleteErr, lager.Data{bindingIDLogKey: bindingID})
+		}
+		return err
+	}
+	return nil
+}
+
+// denyThroughDBProxy undoes allowThroughDBProxy. Bindings which weren't made
+// through the proxy are left alone.
+func (b *RDSBroker) denyThroughDBProxy(instanceID, bindingID string) error {
+	if b.secretStore == nil {
+		return nil
+	}
+
+	found := false
+	err := b.changeDBProxyAuth(instanceID, func(auth []*rds.UserAuthConfig) []*rds.UserAuthConfig {
+		kept := []*rds.UserAuthConfig{}
+		for _, userAuth := range auth {
+			if aws.StringValue(userAuth.Description) == bindingID {
+				found = true
+				continue
+			}
+			kept = append(kept, userAuth)
+		}
+		if !found {
+			return nil
+		}
+		return kept
+	})
+	if err == awsrds.ErrDBProxyDoesNotExist || !found {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	b.logger.Info("deny-through-db-proxy", lager.Data{
+		instanceIDLogKey: instanceID,
+		bindingIDLogKey:  bindingID,
+	})
+	return b.secretStore.DeleteSecret(b.dbProxySecretName(instanceID, bindingID))
+}
+
+// changeDBProxyAuth replaces the secrets of a DB instance's RDS Proxy with
+// those returned by change, unless it returns nil. RDS only takes the whole
+// list, so changes are made one at a time.
+func (b *RDSBroker) changeDBProxyAuth(instanceID string, change func([]*rds.UserAuthConfig) []*rds.UserAuthConfig) error {
+	b.dbProxyAuthLock.Lock()
+	defer b.dbProxyAuthLock.Unlock()
+
+	proxyName := b.dbInstanceIdentifier(instanceID)
+	dbProxy, err := b.dbInstance.DescribeDBProxy(proxyName)
+	if err != nil {
+		return err
+	}
+
+	auth := []*rds.UserAuthConfig{}
+	for _, userAuth := range dbProxy.Auth {
+		auth = append(auth, &rds.UserAuthConfig{
+			AuthScheme:  userAuth.AuthScheme,
+			Description: userAuth.Description,
+			IAMAuth:     userAuth.IAMAuth,
+			SecretArn:   userAuth.SecretArn,
+			UserName:    userAuth.UserName,
+		})
+	}
+
+	auth = change(auth)
+	if auth == nil {
+		return nil
+	}
+	return b.dbInstance.SetDBProxyAuth(proxyName, auth)
+}
+
+// deleteDBProxy deletes the RDS Proxy of a DB instance, if it has one, along
+// with the secrets of the bindings made through it.
+func (b *RDSBroker) deleteDBProxy(instanceID string) error {
+	dbProxyState, err := b.dbInstance.GetTag(b.dbInstanceIdentifier(instanceID), awsrds.TagDBProxy)
+	if err != nil || dbProxyState == "" {
+		return err
+	}
+
+	if b.secretStore != nil {
+		dbProxy, err := b.dbInstance.DescribeDBProxy(b.dbInstanceIdentifier(instanceID))
+		if err == awsrds.ErrDBProxyDoesNotExist {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, userAuth := range dbProxy.Auth {
+			bindingID := aws.StringValue(userAuth.Description)
+			if bindingID == "" {
+				continue
+			}
+			if err := b.secretStore.DeleteSecret(b.dbProxySecretName(instanceID, bindingID)); err != nil {
+				return err
+			}
+		}
+	}
+
+	b.logger.Info("delete-db-proxy", lager.Data{instanceIDLogKey: instanceID})
+	err = b.dbInstance.DeleteDBProxy(b.dbInstanceIdentifier(instanceID))
+	if err == awsrds.ErrDBProxyDoesNotExist {
+		return nil
+	}
+	return err
+}
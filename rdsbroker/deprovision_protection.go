@@ -0,0 +1,96 @@
+package rdsbroker
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// MetricStatistics is the part of the CloudWatch API the deprovision
+// protection uses.
+type MetricStatistics interface {
+	GetMetricStatistics(*cloudwatch.GetMetricStatisticsInput) (*cloudwatch.GetMetricStatisticsOutput, error)
+}
+
+// SetMetricStatistics sets where the deprovision protection reads the
+// connections to a DB instance from. Instances can be deprovisioned without
+// checking until it is set.
+func (b *RDSBroker) SetMetricStatistics(metricStatistics MetricStatistics) {
+	b.metricStatistics = metricStatistics
+}
+
+// deprovisionProtectionLiftDuration is how long the lift_deprovision_protection
+// update parameter lets an instance in use be deprovisioned for.
+const deprovisionProtectionLiftDuration = time.Hour
+
+// checkNotInUse refuses to delete a DB instance which has had connections
+// open throughout any hour of the deprovision protection window, unless the
+// deprovision is forced or the protection has been lifted by an update. The
+// minimum connections of each hour are used, so that the broker's own short
+// connections when unbinding don't count. If the connections can't be read
+// the deletion goes ahead, as the protection is only a safety net.
+func (b *RDSBroker) checkNotInUse(instanceID string, force bool) error {
+	if b.metricStatistics == nil || b.deprovisionProtectionWindow == 0 || force {
+		return nil
+	}
+
+	liftedUntil, err := b.dbInstance.GetTag(b.dbInstanceIdentifier(instanceID), awsrds.TagUnprotectedUntil)
+	if err != nil {
+		return err
+	}
+	if liftedUntil != "" {
+		if until, err := time.Parse(time.RFC3339, liftedUntil); err == nil && time.Now().Before(until) {
+			b.logger.Info("deprovision-protection.lifted", lager.Data{instanceIDLogKey: instanceID, "until": liftedUntil})
+			return nil
+		}
+	}
+
+	now := time.Now()
+	output, err := b.metricStatistics.GetMetricStatistics(&cloudwatch.GetMetricStatisticsInput{
+		Namespace:  aws.String("AWS/RDS"),
+		MetricName: aws.String("DatabaseConnections"),
+		Dimensions: []*cloudwatch.Dimension{
+			{
+				Name:  aws.String("DBInstanceIdentifier"),
+				Value: aws.String(b.dbInstanceIdentifier(instanceID)),
+			},
+		},
+		StartTime:  aws.Time(now.Add(-b.deprovisionProtectionWindow)),
+		EndTime:    aws.Time(now),
+		Period:     aws.Int64(int64(time.Hour.Seconds())),
+		Statistics: aws.StringSlice([]string{cloudwatch.StatisticMinimum}),
+	})
+	if err != nil {
+		b.logger.Error("deprovision-protection.get-metric-statistics", err, lager.Data{instanceIDLogKey: instanceID})
+		return nil
+	}
+
+	var minimumConnections float64
+	for _, datapoint := range output.Datapoints {
+		if aws.Float64Value(datapoint.Minimum) > minimumConnections {
+			minimumConnections = aws.Float64Value(datapoint.Minimum)
+		}
+	}
+	if minimumConnections == 0 {
+		return nil
+	}
+
+	b.logger.Info("deprovision-protection.in-use", lager.Data{
+		instanceIDLogKey:     instanceID,
+		"minimumConnections": minimumConnections,
+	})
+	return newCodedError(
+		http.StatusUnprocessableEntity,
+		ErrCodeDeprovisionProtection,
+		fmt.Errorf(
+			"DB Instance '%s' has had at least %d connections open throughout an hour in the last %d hours, so it looks to be still in use. If you are sure it can be deleted, update it with the lift_deprovision_protection parameter and deprovision it again within the hour",
+			b.dbInstanceIdentifier(instanceID), int64(minimumConnections), int64(b.deprovisionProtectionWindow.Hours()),
+		),
+	)
+}
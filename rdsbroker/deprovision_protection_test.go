@@ -0,0 +1,151 @@
+package rdsbroker_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+	"github.com/pivotal-cf/brokerapi/v9/domain/apiresponses"
+
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+)
+
+type fakeMetricStatistics struct {
+	input      *cloudwatch.GetMetricStatisticsInput
+	datapoints []*cloudwatch.Datapoint
+	err        error
+}
+
+func (f *fakeMetricStatistics) GetMetricStatistics(input *cloudwatch.GetMetricStatisticsInput) (*cloudwatch.GetMetricStatisticsOutput, error) {
+	f.input = input
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &cloudwatch.GetMetricStatisticsOutput{Datapoints: f.datapoints}, nil
+}
+
+var _ = Describe("Deprovision protection", func() {
+	var (
+		rdsInstance                *rdsfake.FakeRDSInstance
+		metricStatistics           *fakeMetricStatistics
+		rdsBroker                  *RDSBroker
+		deprovisionProtectionHours uint
+		deprovisionDetails         domain.DeprovisionDetails
+	)
+
+	BeforeEach(func() {
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		metricStatistics = &fakeMetricStatistics{}
+		deprovisionProtectionHours = 24
+		deprovisionDetails = domain.DeprovisionDetails{
+			ServiceID: "Service-1",
+			PlanID:    "Plan-1",
+		}
+	})
+
+	JustBeforeEach(func() {
+		config := testConfig()
+		config.DeprovisionProtectionHours = deprovisionProtectionHours
+		rdsBroker = newTestBroker(config, rdsInstance, nil, lagertest.NewTestLogger("deprovision_protection_test"))
+		rdsBroker.SetMetricStatistics(metricStatistics)
+	})
+
+	It("deletes an instance which has had no steady connections", func() {
+		metricStatistics.datapoints = []*cloudwatch.Datapoint{
+			{Minimum: aws.Float64(0), Maximum: aws.Float64(3)},
+			{Minimum: aws.Float64(0)},
+		}
+
+		_, err := rdsBroker.Deprovision(context.Background(), "instance-id", deprovisionDetails, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+
+		Expect(aws.StringValue(metricStatistics.input.Namespace)).To(Equal("AWS/RDS"))
+		Expect(aws.StringValue(metricStatistics.input.MetricName)).To(Equal("DatabaseConnections"))
+		Expect(metricStatistics.input.Dimensions).To(HaveLen(1))
+		Expect(aws.StringValue(metricStatistics.input.Dimensions[0].Value)).To(Equal("cf-instance-id"))
+		Expect(aws.TimeValue(metricStatistics.input.EndTime).Sub(aws.TimeValue(metricStatistics.input.StartTime))).To(Equal(24 * time.Hour))
+		Expect(aws.StringValueSlice(metricStatistics.input.Statistics)).To(Equal([]string{"Minimum"}))
+	})
+
+	It("refuses to delete an instance which has had connections open throughout an hour", func() {
+		metricStatistics.datapoints = []*cloudwatch.Datapoint{
+			{Minimum: aws.Float64(0)},
+			{Minimum: aws.Float64(4)},
+		}
+
+		_, err := rdsBroker.Deprovision(context.Background(), "instance-id", deprovisionDetails, true)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("DB Instance 'cf-instance-id' has had at least 4 connections open throughout an hour in the last 24 hours"))
+		Expect(err.(*apiresponses.FailureResponse).ValidatedStatusCode(nil)).To(Equal(422))
+		Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+	})
+
+	It("deletes an instance in use when the deprovision is forced", func() {
+		metricStatistics.datapoints = []*cloudwatch.Datapoint{{Minimum: aws.Float64(4)}}
+		deprovisionDetails.Force = true
+
+		_, err := rdsBroker.Deprovision(context.Background(), "instance-id", deprovisionDetails, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+		Expect(metricStatistics.input).To(BeNil())
+	})
+
+	It("deletes an instance in use when the protection has been lifted by an update", func() {
+		metricStatistics.datapoints = []*cloudwatch.Datapoint{{Minimum: aws.Float64(4)}}
+		rdsInstance.GetTagStub = func(id, tagKey string) (string, error) {
+			if tagKey == "Deprovision Protection Lifted Until" {
+				return time.Now().Add(time.Minute).UTC().Format(time.RFC3339), nil
+			}
+			return "", nil
+		}
+
+		_, err := rdsBroker.Deprovision(context.Background(), "instance-id", deprovisionDetails, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+		Expect(metricStatistics.input).To(BeNil())
+	})
+
+	It("refuses to delete an instance in use once the lifted protection has lapsed", func() {
+		metricStatistics.datapoints = []*cloudwatch.Datapoint{{Minimum: aws.Float64(4)}}
+		rdsInstance.GetTagStub = func(id, tagKey string) (string, error) {
+			if tagKey == "Deprovision Protection Lifted Until" {
+				return time.Now().Add(-time.Minute).UTC().Format(time.RFC3339), nil
+			}
+			return "", nil
+		}
+
+		_, err := rdsBroker.Deprovision(context.Background(), "instance-id", deprovisionDetails, true)
+		Expect(err).To(HaveOccurred())
+		Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+	})
+
+	It("deletes the instance if the connections can't be checked", func() {
+		metricStatistics.err = errors.New("throttled")
+
+		_, err := rdsBroker.Deprovision(context.Background(), "instance-id", deprovisionDetails, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+	})
+
+	Context("when the protection is disabled", func() {
+		BeforeEach(func() {
+			deprovisionProtectionHours = 0
+		})
+
+		It("does not check the connections", func() {
+			metricStatistics.datapoints = []*cloudwatch.Datapoint{{Minimum: aws.Float64(4)}}
+
+			_, err := rdsBroker.Deprovision(context.Background(), "instance-id", deprovisionDetails, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(metricStatistics.input).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,104 @@
+package rdsbroker
+
+import (
+	"errors"
+	"strings"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	"github.com/alphagov/paas-rds-broker/awsroute53"
+)
+
+// DNSConfig is the Route53 hosted zone in which the broker creates a CNAME
+// `<instance id>.<domain>` for each DB instance, so that applications are
+// bound to a name which can be repointed, rather than the RDS endpoint.
+type DNSConfig struct {
+	HostedZoneID string `json:"hosted_zone_id"`
+	Domain       string `json:"domain"`
+	TTL          int64  `json:"ttl"`
+}
+
+func (d DNSConfig) Validate() error {
+	if d.HostedZoneID == "" {
+		return errors.New("Must provide a non-empty HostedZoneID")
+	}
+
+	if strings.Trim(d.Domain, ".") == "" {
+		return errors.New("Must provide a non-empty Domain")
+	}
+
+	if d.TTL < 0 {
+		return errors.New("TTL must not be negative")
+	}
+
+	return nil
+}
+
+// SetDNSZone sets the hosted zone the DB instance CNAMEs are created in. No
+// CNAMEs are created until it is set.
+func (b *RDSBroker) SetDNSZone(dnsZone awsroute53.DNSZone) {
+	b.dnsZone = dnsZone
+}
+
+func (b *RDSBroker) dnsName(instanceID string) string {
+	return instanceID + "." + b.dnsDomain
+}
+
+// ensureDNSName points the DB instance's CNAME at its endpoint. The name and
+// the endpoint it points at are kept in the "DNS Name" and "DNS Target" tags,
+// so that the record is only changed when the endpoint does, and can be
+// deleted along with the instance.
+func (b *RDSBroker) ensureDNSName(instanceID string, dbInstance *rds.DBInstance, tagsByName map[string]string) error {
+	if b.dnsZone == nil {
+		return nil
+	}
+
+	target := awsrds.GetDBAddress(dbInstance.Endpoint)
+	if target == "" || tagsByName[awsrds.TagDNSTarget] == target {
+		return nil
+	}
+
+	name := b.dnsName(instanceID)
+	b.logger.Info("ensure-dns-name", lager.Data{
+		instanceIDLogKey: instanceID,
+		"name":           name,
+		"target":         target,
+	})
+	if err := b.dnsZone.UpsertCNAME(name, target); err != nil {
+		return err
+	}
+
+	return b.dbInstance.AddTagsToResource(
+		aws.StringValue(dbInstance.DBInstanceArn),
+		awsrds.BuildRDSTags(map[string]string{
+			awsrds.TagDNSName:   name,
+			awsrds.TagDNSTarget: target,
+		}),
+	)
+}
+
+// deleteDNSName deletes the CNAME of a DB instance, if it has one.
+func (b *RDSBroker) deleteDNSName(instanceID string) error {
+	if b.dnsZone == nil {
+		return nil
+	}
+
+	dbInstanceIdentifier := b.dbInstanceIdentifier(instanceID)
+	name, err := b.dbInstance.GetTag(dbInstanceIdentifier, awsrds.TagDNSName)
+	if err != nil || name == "" {
+		return err
+	}
+	target, err := b.dbInstance.GetTag(dbInstanceIdentifier, awsrds.TagDNSTarget)
+	if err != nil {
+		return err
+	}
+
+	b.logger.Info("delete-dns-name", lager.Data{
+		instanceIDLogKey: instanceID,
+		"name":           name,
+	})
+	return b.dnsZone.DeleteCNAME(name, target)
+}
@@ -0,0 +1,68 @@
+package rdsbroker
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pivotal-cf/brokerapi/v9/domain/apiresponses"
+)
+
+// The codes the broker gives in the error field of its error responses, so
+// that tooling can tell failures apart without matching their descriptions,
+// which may be reworded. Codes are never renamed or reused.
+const (
+	ErrCodeInvalidParameters      = "invalid-parameters"
+	ErrCodeServiceNotFound        = "service-not-found"
+	ErrCodePlanNotFound           = "plan-not-found"
+	ErrCodePlanDeprecated         = "plan-deprecated"
+	ErrCodePlanNotAllowed         = "not-allowed-by-plan"
+	ErrCodeInvalidPlanChange      = "invalid-plan-change"
+	ErrCodeUnsupportedByEngine    = "unsupported-by-engine"
+	ErrCodeExtensionNotAllowed    = "extension-not-allowed"
+	ErrCodeRebootRequired         = "reboot-required"
+	ErrCodeRestoreSourceNotFound  = "restore-source-not-found"
+	ErrCodeRestoreNotPermitted    = "restore-not-permitted"
+	ErrCodeInvalidRestoreWindow   = "invalid-restore-window"
+	ErrCodeAdoptionNotAllowed     = "adoption-not-allowed"
+	ErrCodeSecurityGroupSetDenied = "security-group-set-not-allowed"
+	ErrCodeNetworkTierDenied      = "network-tier-not-allowed"
+	ErrCodeKmsKeyDenied           = "kms-key-not-allowed"
+	ErrCodeInstanceStopped        = "instance-stopped"
+	ErrCodeInstanceStorageFull    = "instance-storage-full"
+	ErrCodeDeprovisionProtection  = "deprovision-protection"
+	ErrCodeAWSUnavailable         = "aws-unavailable"
+	ErrCodeDataImportNotAllowed   = "data-import-not-allowed"
+	ErrCodeDataExportNotAllowed   = "data-export-not-allowed"
+)
+
+// newCodedError returns an error for the broker API with the code in the
+// error field of its response. The code is also logged as the action.
+func newCodedError(statusCode int, code string, err error) *apiresponses.FailureResponse {
+	return apiresponses.NewFailureResponseBuilder(err, statusCode, code).WithErrorKey(code).Build()
+}
+
+// newUserError returns an error which the user can fix by changing their
+// request.
+func newUserError(code string, format string, args ...interface{}) error {
+	return newCodedError(http.StatusBadRequest, code, fmt.Errorf(format, args...))
+}
+
+// newInvalidParametersError gives an error from parsing or validating the
+// parameters of a request the invalid-parameters code.
+func newInvalidParametersError(err error) error {
+	return newCodedError(http.StatusBadRequest, ErrCodeInvalidParameters, err)
+}
+
+// ErrorCode returns the code of an error returned by the broker, or "" if it
+// doesn't have one.
+func ErrorCode(err error) string {
+	failureResponse, ok := err.(*apiresponses.FailureResponse)
+	if !ok {
+		return ""
+	}
+	errorResponse, ok := failureResponse.ErrorResponse().(apiresponses.ErrorResponse)
+	if !ok {
+		return ""
+	}
+	return errorResponse.Error
+}
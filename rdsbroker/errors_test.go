@@ -0,0 +1,89 @@
+package rdsbroker_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+	"github.com/pivotal-cf/brokerapi/v9/domain/apiresponses"
+
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+	sqlfake "github.com/alphagov/paas-rds-broker/sqlengine/fakes"
+)
+
+var _ = Describe("Error codes", func() {
+	var (
+		rdsBroker        *RDSBroker
+		provisionDetails domain.ProvisionDetails
+	)
+
+	BeforeEach(func() {
+		config := testConfig()
+		config.AllowUserProvisionParameters = true
+		rdsBroker = newTestBroker(config, &rdsfake.FakeRDSInstance{}, &sqlfake.FakeSQLEngine{}, lagertest.NewTestLogger("errors_test"))
+
+		provisionDetails = domain.ProvisionDetails{
+			ServiceID:        "Service-1",
+			PlanID:           "Plan-1",
+			OrganizationGUID: "organization-id",
+			SpaceGUID:        "space-id",
+		}
+	})
+
+	It("gives parameters which can't be parsed the invalid-parameters code", func() {
+		provisionDetails.RawParameters = json.RawMessage(`{"unknown_parameter": true}`)
+
+		_, err := rdsBroker.Provision(context.Background(), "instance-id", provisionDetails, true)
+		Expect(err).To(HaveOccurred())
+		Expect(ErrorCode(err)).To(Equal(ErrCodeInvalidParameters))
+		Expect(err.(*apiresponses.FailureResponse).ValidatedStatusCode(nil)).To(Equal(http.StatusBadRequest))
+	})
+
+	It("returns every problem with the parameters together", func() {
+		provisionDetails.RawParameters = json.RawMessage(`{"unknown_parameter": true, "other_parameter": 1, "additional_databases": ["postgres"]}`)
+
+		_, err := rdsBroker.Provision(context.Background(), "instance-id", provisionDetails, true)
+		Expect(err).To(MatchError(`There are 3 problems with the parameters:
+- unknown field "other_parameter"
+- unknown field "unknown_parameter"
+- additional_databases: 'postgres' is reserved`))
+		Expect(ErrorCode(err)).To(Equal(ErrCodeInvalidParameters))
+	})
+
+	It("gives unknown plans the plan-not-found code", func() {
+		provisionDetails.PlanID = "Plan-3"
+
+		_, err := rdsBroker.Provision(context.Background(), "instance-id", provisionDetails, true)
+		Expect(err).To(MatchError("Service Plan 'Plan-3' not found"))
+		Expect(ErrorCode(err)).To(Equal(ErrCodePlanNotFound))
+	})
+
+	It("gives extensions which aren't allowed the extension-not-allowed code", func() {
+		provisionDetails.RawParameters = json.RawMessage(`{"enable_extensions": ["not-an-extension"]}`)
+
+		_, err := rdsBroker.Provision(context.Background(), "instance-id", provisionDetails, true)
+		Expect(err).To(HaveOccurred())
+		Expect(ErrorCode(err)).To(Equal(ErrCodeExtensionNotAllowed))
+	})
+
+	It("puts the code in the error field of the response", func() {
+		provisionDetails.RawParameters = json.RawMessage(`{"restore_from_latest_snapshot_before": "2024-01-01 00:00:00"}`)
+
+		_, err := rdsBroker.Provision(context.Background(), "instance-id", provisionDetails, true)
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*apiresponses.FailureResponse).ErrorResponse()).To(Equal(apiresponses.ErrorResponse{
+			Error:       ErrCodeInvalidParameters,
+			Description: "Parameter restore_from_latest_snapshot_before should be used with restore_from_latest_snapshot_of",
+		}))
+	})
+
+	It("gives errors which weren't made by the broker no code", func() {
+		Expect(ErrorCode(errors.New("operation failed"))).To(BeEmpty())
+	})
+})
@@ -0,0 +1,46 @@
+package rdsbroker
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// InstanceEvent is an event RDS has recorded for a DB instance, such as a
+// failure to provision it for lack of capacity in an availability zone.
+type InstanceEvent struct {
+	Date       time.Time `json:"date"`
+	Categories []string  `json:"categories"`
+	Message    string    `json:"message"`
+}
+
+// InstanceEvents lists the events RDS has recorded for the DB instance of a
+// service instance between since and until, most recent first.
+func (b *RDSBroker) InstanceEvents(instanceID string, since time.Time, until time.Time) ([]InstanceEvent, error) {
+	dbInstance, _, err := b.describeOwnedDBInstance(instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := b.dbInstance.DescribeEventsBetween(aws.StringValue(dbInstance.DBInstanceIdentifier), since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	instanceEvents := []InstanceEvent{}
+	for _, event := range events {
+		instanceEvents = append(instanceEvents, InstanceEvent{
+			Date:       aws.TimeValue(event.Date),
+			Categories: aws.StringValueSlice(event.EventCategories),
+			Message:    aws.StringValue(event.Message),
+		})
+	}
+
+	b.logger.Info("admin.instance-events", lager.Data{
+		instanceIDLogKey: instanceID,
+		"count":          len(instanceEvents),
+	})
+
+	return instanceEvents, nil
+}
@@ -0,0 +1,121 @@
+package rdsbroker
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/Masterminds/semver"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	"github.com/alphagov/paas-rds-broker/sqlengine"
+)
+
+// mysqlUserExpiryMinVersion is the first version of MySQL with the user
+// attributes that the expiry of MySQL users is kept in.
+var mysqlUserExpiryMinVersion = semver.MustParse("8.0.21")
+
+// checkUserExpirySupported refuses ttl_hours for DB instances which can't
+// expire users, so that the binding fails rather than its credentials
+// outliving it.
+func checkUserExpirySupported(dbInstance *rds.DBInstance) error {
+	if aws.StringValue(dbInstance.Engine) != "mysql" {
+		return nil
+	}
+	engineVersion := aws.StringValue(dbInstance.EngineVersion)
+	version, err := semver.NewVersion(engineVersion)
+	if err != nil || version.LessThan(mysqlUserExpiryMinVersion) {
+		return newUserError(ErrCodeUnsupportedByEngine, "ttl_hours needs MySQL %s or later, but this instance runs MySQL %s", mysqlUserExpiryMinVersion, engineVersion)
+	}
+	return nil
+}
+
+// expireBindingUser sets when the user of a binding made with ttl_hours
+// expires, and tags the DB instance with the latest expiry of its users so
+// that housekeeping knows which instances have users to prune.
+func (b *RDSBroker) expireBindingUser(
+	sqlEngine sqlengine.SQLEngine,
+	dbInstance *rds.DBInstance,
+	bindingID string,
+	dbName string,
+	expiresAt time.Time,
+	expiringUsersUntil string,
+) error {
+	if err := sqlEngine.SetUserExpiry(bindingID, dbName, expiresAt); err != nil {
+		return err
+	}
+
+	if until, err := time.Parse(time.RFC3339, expiringUsersUntil); err == nil && !until.Before(expiresAt) {
+		return nil
+	}
+
+	return b.dbInstance.AddTagsToResource(
+		aws.StringValue(dbInstance.DBInstanceArn),
+		awsrds.BuildRDSTags(map[string]string{awsrds.TagExpiringUsersUntil: expiresAt.Format(time.RFC3339)}),
+	)
+}
+
+// PruneExpiredUsers drops the users of bindings made with ttl_hours once they
+// have expired, and ends their sessions. Postgres already refuses new logins
+// to expired users, but MySQL has no such expiry so its users keep working
+// until they are pruned. The tag is removed once all the users it covers
+// have expired.
+func (b *RDSBroker) PruneExpiredUsers() error {
+	dbInstances, err := b.listManagedDBInstances()
+	if err != nil {
+		return err
+	}
+	return b.pruneExpiredUsers(dbInstances)
+}
+
+func (b *RDSBroker) pruneExpiredUsers(dbInstances []managedDBInstance) error {
+	for _, instance := range dbInstances {
+		expiringUsersUntil := instance.tagsByName[awsrds.TagExpiringUsersUntil]
+		if expiringUsersUntil == "" {
+			continue
+		}
+
+		if err := b.pruneExpiredInstanceUsers(instance.dbInstance, expiringUsersUntil, time.Now()); err != nil {
+			b.logger.Error("prune-expired-users", err, lager.Data{dbInstanceLogKey: instance.dbInstance.DBInstanceIdentifier})
+		}
+	}
+
+	return nil
+}
+
+func (b *RDSBroker) pruneExpiredInstanceUsers(dbInstance *rds.DBInstance, expiringUsersUntil string, now time.Time) error {
+	dbInstanceIdentifier := aws.StringValue(dbInstance.DBInstanceIdentifier)
+	instanceID := b.dbInstanceIdentifierToServiceInstanceID(dbInstanceIdentifier)
+
+	if status := aws.StringValue(dbInstance.DBInstanceStatus); status != "available" {
+		b.logger.Info("prune-expired-users.skipped", lager.Data{
+			instanceIDLogKey: instanceID,
+			"status":         status,
+		})
+		return nil
+	}
+
+	sqlEngine, err := b.openSQLEngineForDBInstance(instanceID, b.dbNameFromDBInstance(instanceID, dbInstance), dbInstance)
+	if err != nil {
+		return err
+	}
+	defer sqlEngine.Close()
+
+	users, err := sqlEngine.DropExpiredUsers()
+	if err != nil {
+		return err
+	}
+	if len(users) > 0 {
+		b.logger.Info("prune-expired-users.dropped", lager.Data{
+			instanceIDLogKey: instanceID,
+			"users":          users,
+		})
+	}
+
+	until, err := time.Parse(time.RFC3339, expiringUsersUntil)
+	if err != nil || now.After(until) {
+		return b.removeTag(dbInstanceIdentifier, awsrds.TagExpiringUsersUntil)
+	}
+	return nil
+}
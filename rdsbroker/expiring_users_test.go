@@ -0,0 +1,99 @@
+package rdsbroker_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+	sqlfake "github.com/alphagov/paas-rds-broker/sqlengine/fakes"
+)
+
+var _ = Describe("PruneExpiredUsers", func() {
+	var (
+		rdsInstance *rdsfake.FakeRDSInstance
+		sqlEngine   *sqlfake.FakeSQLEngine
+		rdsBroker   *RDSBroker
+		dbInstance  *rds.DBInstance
+		tags        map[string]string
+	)
+
+	BeforeEach(func() {
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		sqlEngine = &sqlfake.FakeSQLEngine{}
+
+		dbInstance = testDBInstance()
+		tags = map[string]string{
+			"Broker Name":          "mybroker",
+			"Expiring Users Until": time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		}
+	})
+
+	JustBeforeEach(func() {
+		rdsBroker = newTestBroker(testConfig(), rdsInstance, sqlEngine, lagertest.NewTestLogger("expiring_users_test"))
+
+		rdsInstance.DescribeByTagReturns([]*rds.DBInstance{dbInstance}, nil)
+		rdsInstance.GetResourceTagsStub = func(string, ...awsrds.DescribeOption) ([]*rds.Tag, error) {
+			return awsrds.BuildRDSTags(tags), nil
+		}
+	})
+
+	It("drops the expired users of instances with expiring users", func() {
+		sqlEngine.DropExpiredUsersReturns([]string{"uabcdefghijklmno"}, nil)
+
+		Expect(rdsBroker.PruneExpiredUsers()).To(Succeed())
+		Expect(sqlEngine.DropExpiredUsersCallCount()).ToNot(BeZero())
+		address, _, _, username, _ := sqlEngine.OpenArgsForCall(0)
+		Expect(address).To(Equal("endpoint-address"))
+		Expect(username).To(Equal("master-username"))
+		Expect(sqlEngine.CloseCallCount()).ToNot(BeZero())
+		Expect(rdsInstance.RemoveTagCallCount()).To(Equal(0))
+	})
+
+	It("removes the tag once all the users have expired", func() {
+		tags["Expiring Users Until"] = time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+		Expect(rdsBroker.PruneExpiredUsers()).To(Succeed())
+		Expect(sqlEngine.DropExpiredUsersCallCount()).ToNot(BeZero())
+		Expect(rdsInstance.RemoveTagCallCount()).To(Equal(1))
+		id, tagKey := rdsInstance.RemoveTagArgsForCall(0)
+		Expect(id).To(Equal("cf-instance-1"))
+		Expect(tagKey).To(Equal("Expiring Users Until"))
+	})
+
+	It("leaves instances without expiring users alone", func() {
+		delete(tags, "Expiring Users Until")
+
+		Expect(rdsBroker.PruneExpiredUsers()).To(Succeed())
+		Expect(sqlEngine.OpenCallCount()).To(BeZero())
+	})
+
+	It("skips instances which aren't available", func() {
+		dbInstance.DBInstanceStatus = aws.String("stopped")
+
+		Expect(rdsBroker.PruneExpiredUsers()).To(Succeed())
+		Expect(sqlEngine.OpenCallCount()).To(BeZero())
+		Expect(rdsInstance.RemoveTagCallCount()).To(Equal(0))
+	})
+
+	It("keeps the tag if the users can't be dropped", func() {
+		tags["Expiring Users Until"] = time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+		sqlEngine.DropExpiredUsersReturns(nil, errors.New("Failed to drop users"))
+
+		Expect(rdsBroker.PruneExpiredUsers()).To(Succeed())
+		Expect(rdsInstance.RemoveTagCallCount()).To(Equal(0))
+	})
+
+	It("returns an error if the instances can't be listed", func() {
+		rdsInstance.DescribeByTagReturns(nil, errors.New("Failed to describe"))
+
+		Expect(rdsBroker.PruneExpiredUsers()).To(MatchError("Failed to describe"))
+	})
+})
@@ -0,0 +1,151 @@
+package rdsbroker
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+var (
+	ErrNotMultiAZ          = errors.New("DB instance is not Multi-AZ")
+	ErrFailoverUnavailable = errors.New("DB instance is not available to fail over")
+)
+
+// FailoverTest is the progress of the latest failover requested through the
+// admin API for a DB instance. The failover times come from the RDS events
+// of the instance, so they are only known for a day after the failover.
+type FailoverTest struct {
+	InstanceID           string     `json:"instance_id"`
+	DBInstanceIdentifier string     `json:"db_instance_identifier"`
+	DBInstanceStatus     string     `json:"db_instance_status"`
+	RequestedAt          *time.Time `json:"requested_at"`
+	FailoverStartedAt    *time.Time `json:"failover_started_at"`
+	FailoverCompletedAt  *time.Time `json:"failover_completed_at"`
+	DurationSeconds      *float64   `json:"duration_seconds"`
+}
+
+// FailoverInstance forces a Multi-AZ DB instance to fail over to its standby
+// by rebooting it, so that operators can check how applications cope. The
+// request time is tagged on the instance for FailoverStatus.
+func (b *RDSBroker) FailoverInstance(instanceID string) (*FailoverTest, error) {
+	dbInstance, _, err := b.describeOwnedDBInstance(instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if !aws.BoolValue(dbInstance.MultiAZ) {
+		return nil, ErrNotMultiAZ
+	}
+	if aws.StringValue(dbInstance.DBInstanceStatus) != "available" {
+		return nil, ErrFailoverUnavailable
+	}
+
+	requestedAt := time.Now().UTC().Truncate(time.Second)
+	b.logger.Info("admin.failover", lager.Data{
+		instanceIDLogKey:       instanceID,
+		"dbInstanceIdentifier": aws.StringValue(dbInstance.DBInstanceIdentifier),
+	})
+	err = b.dbInstance.Reboot(&rds.RebootDBInstanceInput{
+		DBInstanceIdentifier: dbInstance.DBInstanceIdentifier,
+		ForceFailover:        aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.dbInstance.AddTagsToResource(
+		aws.StringValue(dbInstance.DBInstanceArn),
+		awsrds.BuildRDSTags(map[string]string{awsrds.TagFailoverRequestedAt: requestedAt.Format(time.RFC3339)}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FailoverTest{
+		InstanceID:           instanceID,
+		DBInstanceIdentifier: aws.StringValue(dbInstance.DBInstanceIdentifier),
+		DBInstanceStatus:     "rebooting",
+		RequestedAt:          &requestedAt,
+	}, nil
+}
+
+// FailoverStatus reports how far the latest failover requested for a DB
+// instance has got, from the failover events RDS has recorded since it was
+// requested. The fields are left empty until the matching event appears.
+func (b *RDSBroker) FailoverStatus(instanceID string) (*FailoverTest, error) {
+	dbInstance, tagsByName, err := b.describeOwnedDBInstance(instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	failoverTest := &FailoverTest{
+		InstanceID:           instanceID,
+		DBInstanceIdentifier: aws.StringValue(dbInstance.DBInstanceIdentifier),
+		DBInstanceStatus:     aws.StringValue(dbInstance.DBInstanceStatus),
+	}
+	requestedAt, err := time.Parse(time.RFC3339, tagsByName[awsrds.TagFailoverRequestedAt])
+	if err != nil {
+		return failoverTest, nil
+	}
+	failoverTest.RequestedAt = &requestedAt
+
+	events, err := b.dbInstance.DescribeEvents(aws.StringValue(dbInstance.DBInstanceIdentifier))
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		if !isFailoverEvent(event) || aws.TimeValue(event.Date).Before(requestedAt) {
+			continue
+		}
+		message := strings.ToLower(aws.StringValue(event.Message))
+		// Events are most recent first, so keep the earliest of each.
+		if strings.Contains(message, "started") {
+			failoverTest.FailoverStartedAt = event.Date
+		}
+		if strings.Contains(message, "completed") {
+			failoverTest.FailoverCompletedAt = event.Date
+		}
+	}
+
+	if failoverTest.FailoverCompletedAt != nil {
+		startedAt := requestedAt
+		if failoverTest.FailoverStartedAt != nil {
+			startedAt = *failoverTest.FailoverStartedAt
+		}
+		failoverTest.DurationSeconds = aws.Float64(failoverTest.FailoverCompletedAt.Sub(startedAt).Seconds())
+	}
+
+	return failoverTest, nil
+}
+
+func (b *RDSBroker) describeOwnedDBInstance(instanceID string) (*rds.DBInstance, map[string]string, error) {
+	dbInstance, err := b.dbInstance.Describe(b.dbInstanceIdentifier(instanceID))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tags, err := b.dbInstance.GetResourceTags(aws.StringValue(dbInstance.DBInstanceArn))
+	if err != nil {
+		return nil, nil, err
+	}
+	tagsByName := awsrds.RDSTagsValues(tags)
+	if tagsByName[awsrds.TagBrokerName] != b.brokerName {
+		return nil, nil, awsrds.ErrDBInstanceDoesNotExist
+	}
+
+	return dbInstance, tagsByName, nil
+}
+
+func isFailoverEvent(event *rds.Event) bool {
+	for _, category := range event.EventCategories {
+		if aws.StringValue(category) == "failover" {
+			return true
+		}
+	}
+	return false
+}
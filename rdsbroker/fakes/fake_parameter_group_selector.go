@@ -8,11 +8,27 @@ import (
 )
 
 type FakeParameterGroupSelector struct {
-	SelectParameterGroupStub        func(rdsbroker.ServicePlan, []string) (string, error)
+	ParameterGroupNameStub        func(rdsbroker.ServicePlan, []string, []string) (string, error)
+	parameterGroupNameMutex       sync.RWMutex
+	parameterGroupNameArgsForCall []struct {
+		arg1 rdsbroker.ServicePlan
+		arg2 []string
+		arg3 []string
+	}
+	parameterGroupNameReturns struct {
+		result1 string
+		result2 error
+	}
+	parameterGroupNameReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	SelectParameterGroupStub        func(rdsbroker.ServicePlan, []string, []string) (string, error)
 	selectParameterGroupMutex       sync.RWMutex
 	selectParameterGroupArgsForCall []struct {
 		arg1 rdsbroker.ServicePlan
 		arg2 []string
+		arg3 []string
 	}
 	selectParameterGroupReturns struct {
 		result1 string
@@ -26,24 +42,106 @@ type FakeParameterGroupSelector struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeParameterGroupSelector) SelectParameterGroup(arg1 rdsbroker.ServicePlan, arg2 []string) (string, error) {
+func (fake *FakeParameterGroupSelector) ParameterGroupName(arg1 rdsbroker.ServicePlan, arg2 []string, arg3 []string) (string, error) {
+	var arg2Copy []string
+	if arg2 != nil {
+		arg2Copy = make([]string, len(arg2))
+		copy(arg2Copy, arg2)
+	}
+	var arg3Copy []string
+	if arg3 != nil {
+		arg3Copy = make([]string, len(arg3))
+		copy(arg3Copy, arg3)
+	}
+	fake.parameterGroupNameMutex.Lock()
+	ret, specificReturn := fake.parameterGroupNameReturnsOnCall[len(fake.parameterGroupNameArgsForCall)]
+	fake.parameterGroupNameArgsForCall = append(fake.parameterGroupNameArgsForCall, struct {
+		arg1 rdsbroker.ServicePlan
+		arg2 []string
+		arg3 []string
+	}{arg1, arg2Copy, arg3Copy})
+	stub := fake.ParameterGroupNameStub
+	fakeReturns := fake.parameterGroupNameReturns
+	fake.recordInvocation("ParameterGroupName", []interface{}{arg1, arg2Copy, arg3Copy})
+	fake.parameterGroupNameMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeParameterGroupSelector) ParameterGroupNameCallCount() int {
+	fake.parameterGroupNameMutex.RLock()
+	defer fake.parameterGroupNameMutex.RUnlock()
+	return len(fake.parameterGroupNameArgsForCall)
+}
+
+func (fake *FakeParameterGroupSelector) ParameterGroupNameCalls(stub func(rdsbroker.ServicePlan, []string, []string) (string, error)) {
+	fake.parameterGroupNameMutex.Lock()
+	defer fake.parameterGroupNameMutex.Unlock()
+	fake.ParameterGroupNameStub = stub
+}
+
+func (fake *FakeParameterGroupSelector) ParameterGroupNameArgsForCall(i int) (rdsbroker.ServicePlan, []string, []string) {
+	fake.parameterGroupNameMutex.RLock()
+	defer fake.parameterGroupNameMutex.RUnlock()
+	argsForCall := fake.parameterGroupNameArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeParameterGroupSelector) ParameterGroupNameReturns(result1 string, result2 error) {
+	fake.parameterGroupNameMutex.Lock()
+	defer fake.parameterGroupNameMutex.Unlock()
+	fake.ParameterGroupNameStub = nil
+	fake.parameterGroupNameReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeParameterGroupSelector) ParameterGroupNameReturnsOnCall(i int, result1 string, result2 error) {
+	fake.parameterGroupNameMutex.Lock()
+	defer fake.parameterGroupNameMutex.Unlock()
+	fake.ParameterGroupNameStub = nil
+	if fake.parameterGroupNameReturnsOnCall == nil {
+		fake.parameterGroupNameReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.parameterGroupNameReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeParameterGroupSelector) SelectParameterGroup(arg1 rdsbroker.ServicePlan, arg2 []string, arg3 []string) (string, error) {
 	var arg2Copy []string
 	if arg2 != nil {
 		arg2Copy = make([]string, len(arg2))
 		copy(arg2Copy, arg2)
 	}
+	var arg3Copy []string
+	if arg3 != nil {
+		arg3Copy = make([]string, len(arg3))
+		copy(arg3Copy, arg3)
+	}
 	fake.selectParameterGroupMutex.Lock()
 	ret, specificReturn := fake.selectParameterGroupReturnsOnCall[len(fake.selectParameterGroupArgsForCall)]
 	fake.selectParameterGroupArgsForCall = append(fake.selectParameterGroupArgsForCall, struct {
 		arg1 rdsbroker.ServicePlan
 		arg2 []string
-	}{arg1, arg2Copy})
+		arg3 []string
+	}{arg1, arg2Copy, arg3Copy})
 	stub := fake.SelectParameterGroupStub
 	fakeReturns := fake.selectParameterGroupReturns
-	fake.recordInvocation("SelectParameterGroup", []interface{}{arg1, arg2Copy})
+	fake.recordInvocation("SelectParameterGroup", []interface{}{arg1, arg2Copy, arg3Copy})
 	fake.selectParameterGroupMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2)
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -57,17 +155,17 @@ func (fake *FakeParameterGroupSelector) SelectParameterGroupCallCount() int {
 	return len(fake.selectParameterGroupArgsForCall)
 }
 
-func (fake *FakeParameterGroupSelector) SelectParameterGroupCalls(stub func(rdsbroker.ServicePlan, []string) (string, error)) {
+func (fake *FakeParameterGroupSelector) SelectParameterGroupCalls(stub func(rdsbroker.ServicePlan, []string, []string) (string, error)) {
 	fake.selectParameterGroupMutex.Lock()
 	defer fake.selectParameterGroupMutex.Unlock()
 	fake.SelectParameterGroupStub = stub
 }
 
-func (fake *FakeParameterGroupSelector) SelectParameterGroupArgsForCall(i int) (rdsbroker.ServicePlan, []string) {
+func (fake *FakeParameterGroupSelector) SelectParameterGroupArgsForCall(i int) (rdsbroker.ServicePlan, []string, []string) {
 	fake.selectParameterGroupMutex.RLock()
 	defer fake.selectParameterGroupMutex.RUnlock()
 	argsForCall := fake.selectParameterGroupArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeParameterGroupSelector) SelectParameterGroupReturns(result1 string, result2 error) {
@@ -99,6 +197,8 @@ func (fake *FakeParameterGroupSelector) SelectParameterGroupReturnsOnCall(i int,
 func (fake *FakeParameterGroupSelector) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.parameterGroupNameMutex.RLock()
+	defer fake.parameterGroupNameMutex.RUnlock()
 	fake.selectParameterGroupMutex.RLock()
 	defer fake.selectParameterGroupMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
@@ -0,0 +1,64 @@
+package rdsbroker
+
+import (
+	"strconv"
+)
+
+// userSkipFinalSnapshotAllowed is whether users may ask for the DB
+// instances of a plan to be deleted without a final snapshot. Plans allow
+// it unless they say otherwise.
+func userSkipFinalSnapshotAllowed(servicePlan ServicePlan) bool {
+	return servicePlan.AllowUserSkipFinalSnapshot == nil || *servicePlan.AllowUserSkipFinalSnapshot
+}
+
+// checkSkipFinalSnapshotAllowed refuses a skip_final_snapshot parameter
+// asking to skip the final snapshot on a plan which doesn't allow it.
+// Asking to keep the final snapshot is always allowed.
+func checkSkipFinalSnapshotAllowed(servicePlan ServicePlan, skipFinalSnapshot *bool) error {
+	if skipFinalSnapshot != nil && *skipFinalSnapshot && !userSkipFinalSnapshotAllowed(servicePlan) {
+		return newUserError(ErrCodePlanNotAllowed, "Service Plan '%s' does not allow skip_final_snapshot to be set to true", servicePlan.Name)
+	}
+	return nil
+}
+
+// defaultSkipFinalSnapshot is whether the DB instances of a plan skip their
+// final snapshot when the user hasn't said: the plan's setting, otherwise
+// the broker's, otherwise fallback.
+func (b *RDSBroker) defaultSkipFinalSnapshot(servicePlan ServicePlan, fallback bool) bool {
+	if servicePlan.RDSProperties.SkipFinalSnapshot != nil {
+		return *servicePlan.RDSProperties.SkipFinalSnapshot
+	}
+	if b.skipFinalSnapshotDefault != nil {
+		return *b.skipFinalSnapshotDefault
+	}
+	return fallback
+}
+
+// provisionSkipFinalSnapshot is whether a new DB instance skips its final
+// snapshot, which is recorded in its SkipFinalSnapshot tag.
+func (b *RDSBroker) provisionSkipFinalSnapshot(servicePlan ServicePlan, provisionParameters ProvisionParameters) bool {
+	if provisionParameters.SkipFinalSnapshot != nil {
+		return *provisionParameters.SkipFinalSnapshot
+	}
+	return b.defaultSkipFinalSnapshot(servicePlan, false)
+}
+
+// resolveSkipFinalSnapshot determines whether we actually want to skip the
+// final snapshot given the servicePlan and the instance's SkipFinalSnapshot
+// tagValue. A tag asking to skip it is ignored on plans which don't allow
+// users to skip it, in case the instance was moved onto such a plan.
+func (b *RDSBroker) resolveSkipFinalSnapshot(servicePlan ServicePlan, tagValue string) (bool, error) {
+	skipDBInstanceFinalSnapshot := b.defaultSkipFinalSnapshot(servicePlan, true)
+
+	if tagValue != "" {
+		taggedSkipFinalSnapshot, err := strconv.ParseBool(tagValue)
+		if err != nil {
+			return false, err
+		}
+		if !taggedSkipFinalSnapshot || userSkipFinalSnapshotAllowed(servicePlan) {
+			skipDBInstanceFinalSnapshot = taggedSkipFinalSnapshot
+		}
+	}
+
+	return skipDBInstanceFinalSnapshot, nil
+}
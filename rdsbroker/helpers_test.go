@@ -1,5 +1,16 @@
 package rdsbroker_test
 
+import (
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+	"github.com/alphagov/paas-rds-broker/rdsbroker/fakes"
+	sqlfake "github.com/alphagov/paas-rds-broker/sqlengine/fakes"
+)
+
 func boolPointer(input bool) *bool {
 	return &input
 }
@@ -22,3 +33,103 @@ func copyStringStringMap(inMap map[string]string) map[string]string {
 
 	return outMap
 }
+
+// createdDatabases lists the names of the databases the fake SQL engine
+// was asked to create, in order.
+func createdDatabases(sqlEngine *sqlfake.FakeSQLEngine) []string {
+	dbNames := []string{}
+	for i := 0; i < sqlEngine.CreateDatabaseCallCount(); i++ {
+		dbNames = append(dbNames, sqlEngine.CreateDatabaseArgsForCall(i))
+	}
+	return dbNames
+}
+
+// droppedDatabases lists the names of the databases the fake SQL engine
+// was asked to drop, in order.
+func droppedDatabases(sqlEngine *sqlfake.FakeSQLEngine) []string {
+	dbNames := []string{}
+	for i := 0; i < sqlEngine.DropDatabaseCallCount(); i++ {
+		dbNames = append(dbNames, sqlEngine.DropDatabaseArgsForCall(i))
+	}
+	return dbNames
+}
+
+// updatedExtensions lists the extensions the fake SQL engine was asked to
+// update, in order.
+func updatedExtensions(sqlEngine *sqlfake.FakeSQLEngine) []string {
+	extensions := []string{}
+	for i := 0; i < sqlEngine.UpdateExtensionCallCount(); i++ {
+		extensions = append(extensions, sqlEngine.UpdateExtensionArgsForCall(i))
+	}
+	return extensions
+}
+
+// testConfig is the config the tests of the broker's features start from,
+// with a service of a Postgres plan, Plan-1, and a MySQL plan, Plan-2.
+// Tests change what they need of it before making the broker.
+func testConfig() Config {
+	return Config{
+		Region:             "rds-region",
+		DBPrefix:           "cf",
+		BrokerName:         "mybroker",
+		MasterPasswordSeed: "something-secret",
+		Catalog: Catalog{
+			Services: []Service{
+				{
+					ID:            "Service-1",
+					PlanUpdatable: true,
+					Plans: []ServicePlan{
+						{
+							ID:   "Plan-1",
+							Name: "small",
+							RDSProperties: RDSProperties{
+								Engine:           aws.String("postgres"),
+								EngineVersion:    aws.String("13"),
+								DBInstanceClass:  aws.String("db.t3.small"),
+								AllocatedStorage: aws.Int64(100),
+							},
+						},
+						{
+							ID:   "Plan-2",
+							Name: "mysql",
+							RDSProperties: RDSProperties{
+								Engine:           aws.String("mysql"),
+								EngineVersion:    aws.String("8.0"),
+								DBInstanceClass:  aws.String("db.t3.small"),
+								AllocatedStorage: aws.Int64(100),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// testDBInstance is an available Postgres instance of the broker, as the
+// tests of the broker's features have AWS describe it.
+func testDBInstance() *rds.DBInstance {
+	return &rds.DBInstance{
+		DBInstanceIdentifier: aws.String("cf-instance-1"),
+		DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-instance-1"),
+		DBInstanceStatus:     aws.String("available"),
+		Engine:               aws.String("postgres"),
+		EngineVersion:        aws.String("13.4"),
+		DBName:               aws.String("mydb"),
+		MasterUsername:       aws.String("master-username"),
+		Endpoint: &rds.Endpoint{
+			Address: aws.String("endpoint-address"),
+			Port:    aws.Int64(5432),
+		},
+	}
+}
+
+// newTestBroker makes a broker of the config whose SQL engines are all
+// sqlEngine, or fakes which do nothing if it's nil.
+func newTestBroker(config Config, rdsInstance awsrds.RDSInstance, sqlEngine *sqlfake.FakeSQLEngine, logger lager.Logger) *RDSBroker {
+	sqlProvider := &sqlfake.FakeProvider{}
+	if sqlEngine != nil {
+		sqlProvider.GetSQLEngineReturns(sqlEngine, nil)
+	}
+	return New(config, rdsInstance, sqlProvider, &fakes.FakeParameterGroupSelector{}, logger)
+}
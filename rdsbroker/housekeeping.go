@@ -0,0 +1,108 @@
+package rdsbroker
+
+import (
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// managedDBInstance is a DB instance owned by this broker, along with its
+// tags.
+type managedDBInstance struct {
+	dbInstance *rds.DBInstance
+	tagsByName map[string]string
+}
+
+// housekeepingJob is one of the periodic jobs run over the broker's DB
+// instances by RunHousekeeping.
+type housekeepingJob struct {
+	name string
+	run  func(dbInstances []managedDBInstance) error
+}
+
+// listManagedDBInstances describes every DB instance owned by this broker
+// and fetches its tags. Instances whose tags can't be fetched are logged and
+// left out, so that one of them doesn't hold up the rest.
+func (b *RDSBroker) listManagedDBInstances() ([]managedDBInstance, error) {
+	dbInstances, err := b.dbInstance.DescribeByTag(awsrds.TagBrokerName, b.brokerName)
+	if err != nil {
+		return nil, err
+	}
+
+	managedDBInstances := []managedDBInstance{}
+	for _, dbInstance := range dbInstances {
+		tags, err := b.dbInstance.GetResourceTags(aws.StringValue(dbInstance.DBInstanceArn))
+		if err != nil {
+			b.logger.Error("list-db-instances.get-tags", err, lager.Data{dbInstanceLogKey: dbInstance.DBInstanceIdentifier})
+			continue
+		}
+		managedDBInstances = append(managedDBInstances, managedDBInstance{
+			dbInstance: dbInstance,
+			tagsByName: awsrds.RDSTagsValues(tags),
+		})
+	}
+
+	return managedDBInstances, nil
+}
+
+// keepStopped stops a DB instance which is meant to stay stopped if it is
+// running, as RDS starts stopped instances again after seven days. It
+// reports whether the instance had to be stopped.
+func (b *RDSBroker) keepStopped(dbInstance *rds.DBInstance, action string, logData lager.Data) (bool, error) {
+	if aws.StringValue(dbInstance.DBInstanceStatus) != "available" {
+		return false, nil
+	}
+
+	b.logger.Info(action, logData)
+	if err := b.dbInstance.Stop(aws.StringValue(dbInstance.DBInstanceIdentifier)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (b *RDSBroker) housekeepingJobs() []housekeepingJob {
+	return []housekeepingJob{
+		{"delete-orphaned-instances", b.deleteOrphanedInstances},
+		{"process-soft-deleted-instances", b.processSoftDeletedInstances},
+		{"process-trial-instances", b.processTrialInstances},
+		{"check-instance-ages", func(dbInstances []managedDBInstance) error {
+			b.checkInstanceAges(dbInstances)
+			return nil
+		}},
+		{"check-backups", func(dbInstances []managedDBInstance) error {
+			b.checkBackups(dbInstances)
+			return nil
+		}},
+		{"process-restore-tests", b.processRestoreTests},
+		{"share-snapshots", b.shareSnapshots},
+		{"export-inventory", b.exportInventory},
+		{"report-version-drift", b.reportVersionDrift},
+		{"run-minor-upgrades", b.runMinorUpgrades},
+		{"run-scheduled-maintenance", b.runScheduledMaintenance},
+		{"prune-expired-users", b.pruneExpiredUsers},
+		{"check-transaction-id-ages", b.checkTransactionIDAges},
+		{"check-connection-usage", b.checkConnectionUsage},
+		{"check-sql-engines", b.checkSQLEngines},
+		{"gather-instance-metrics", b.gatherInstanceMetrics},
+	}
+}
+
+// RunHousekeeping lists the broker's DB instances and their tags once, then
+// runs every housekeeping job over them. A failing job is logged and doesn't
+// stop the jobs after it.
+func (b *RDSBroker) RunHousekeeping() error {
+	dbInstances, err := b.listManagedDBInstances()
+	if err != nil {
+		return err
+	}
+
+	for _, job := range b.housekeepingJobs() {
+		if err := job.run(dbInstances); err != nil {
+			b.logger.Error(job.name, err)
+		}
+	}
+
+	return nil
+}
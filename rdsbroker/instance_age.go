@@ -0,0 +1,141 @@
+package rdsbroker
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// instanceAgeWarning returns a message nudging the owner of a DB instance to
+// rebuild it from a backup if it is older than its plan allows, or an empty
+// string if it isn't. Restoring into a new instance resets its age, which is
+// the point: it exercises the restore procedure.
+func instanceAgeWarning(dbInstance *rds.DBInstance, servicePlan ServicePlan, now time.Time) string {
+	if servicePlan.MaxInstanceAgeDays == 0 || dbInstance.InstanceCreateTime == nil {
+		return ""
+	}
+
+	ageDays := int(now.Sub(aws.TimeValue(dbInstance.InstanceCreateTime)).Hours() / 24)
+	if ageDays <= int(servicePlan.MaxInstanceAgeDays) {
+		return ""
+	}
+
+	return fmt.Sprintf(
+		"This instance is %d days old, which is more than the %d days allowed by its plan. Restore it into a new instance to exercise your rebuild procedure.",
+		ageDays, servicePlan.MaxInstanceAgeDays,
+	)
+}
+
+// CheckInstanceAges logs every DB instance which is older than the maximum
+// age of its plan, and returns how many there are.
+func (b *RDSBroker) CheckInstanceAges() (int, error) {
+	dbInstances, err := b.listManagedDBInstances()
+	if err != nil {
+		return 0, err
+	}
+	return b.checkInstanceAges(dbInstances), nil
+}
+
+// instanceAge is how old a DB instance on a plan with a maximum age is.
+type instanceAge struct {
+	instanceID           string
+	dbInstanceIdentifier string
+	planID               string
+	ageDays              int
+	maxAgeDays           int
+}
+
+func (a instanceAge) exceeded() bool {
+	return a.ageDays > a.maxAgeDays
+}
+
+// instanceAges gives the ages of the DB instances on plans with a maximum
+// age. Soft deleted instances are left out.
+func (b *RDSBroker) instanceAges(dbInstances []managedDBInstance, now time.Time) []instanceAge {
+	ages := []instanceAge{}
+	for _, instance := range dbInstances {
+		dbInstance := instance.dbInstance
+		if instance.tagsByName[awsrds.TagPurgeAfter] != "" || dbInstance.InstanceCreateTime == nil {
+			continue
+		}
+
+		servicePlan, ok := b.catalog.FindServicePlan(instance.tagsByName[awsrds.TagPlanID])
+		if !ok || servicePlan.MaxInstanceAgeDays == 0 {
+			continue
+		}
+
+		dbInstanceIdentifier := aws.StringValue(dbInstance.DBInstanceIdentifier)
+		ages = append(ages, instanceAge{
+			instanceID:           b.dbInstanceIdentifierToServiceInstanceID(dbInstanceIdentifier),
+			dbInstanceIdentifier: dbInstanceIdentifier,
+			planID:               servicePlan.ID,
+			ageDays:              int(now.Sub(aws.TimeValue(dbInstance.InstanceCreateTime)).Hours() / 24),
+			maxAgeDays:           int(servicePlan.MaxInstanceAgeDays),
+		})
+	}
+	return ages
+}
+
+func (b *RDSBroker) checkInstanceAges(dbInstances []managedDBInstance) int {
+	ages := b.instanceAges(dbInstances, time.Now())
+	agedInstances := 0
+	for _, age := range ages {
+		if age.exceeded() {
+			agedInstances++
+			b.logger.Info("instance-age.exceeded", lager.Data{
+				instanceIDLogKey:     age.instanceID,
+				"planID":             age.planID,
+				"ageDays":            age.ageDays,
+				"maxInstanceAgeDays": age.maxAgeDays,
+			})
+		}
+	}
+
+	b.logger.Info("instance-age.checked", lager.Data{
+		"checkedInstances": len(ages),
+		"agedInstances":    agedInstances,
+	})
+
+	return agedInstances
+}
+
+// writeInstanceAgeMetrics writes the ages of the DB instances on plans with
+// a maximum age in the Prometheus text exposition format.
+func writeInstanceAgeMetrics(w io.Writer, ages []instanceAge) {
+	metrics := []struct {
+		name  string
+		help  string
+		value func(instanceAge) int
+	}{
+		{
+			name:  "rds_broker_instance_age_days",
+			help:  "Days since the DB instance was created, for instances on plans with a maximum age.",
+			value: func(a instanceAge) int { return a.ageDays },
+		},
+		{
+			name: "rds_broker_instance_max_age_exceeded",
+			help: "Whether the DB instance is older than the maximum age of its plan, and should be rebuilt from a backup.",
+			value: func(a instanceAge) int {
+				if a.exceeded() {
+					return 1
+				}
+				return 0
+			},
+		},
+	}
+
+	for _, metric := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n", metric.name, metric.help)
+		fmt.Fprintf(w, "# TYPE %s gauge\n", metric.name)
+		for _, age := range ages {
+			fmt.Fprintf(w, "%s{instance_id=%q,db_instance_identifier=%q,plan_id=%q} %d\n",
+				metric.name, age.instanceID, age.dbInstanceIdentifier, age.planID, metric.value(age))
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package rdsbroker_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+)
+
+var _ = Describe("CheckInstanceAges", func() {
+	var (
+		rdsInstance *rdsfake.FakeRDSInstance
+		rdsBroker   *RDSBroker
+		logger      *lagertest.TestLogger
+		dbInstances []*rds.DBInstance
+	)
+
+	BeforeEach(func() {
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		logger = lagertest.NewTestLogger("instance_age_test")
+		config := testConfig()
+		config.Catalog.Services[0].Plans[0].MaxInstanceAgeDays = 365
+		rdsBroker = newTestBroker(config, rdsInstance, nil, logger)
+
+		dbInstances = []*rds.DBInstance{
+			{
+				DBInstanceIdentifier: aws.String("cf-old-instance"),
+				DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-old-instance"),
+				InstanceCreateTime:   aws.Time(time.Now().Add(-400 * 24 * time.Hour)),
+			},
+			{
+				DBInstanceIdentifier: aws.String("cf-new-instance"),
+				DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-new-instance"),
+				InstanceCreateTime:   aws.Time(time.Now().Add(-10 * 24 * time.Hour)),
+			},
+		}
+		rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+			"Broker Name": "mybroker",
+			"Plan ID":     "Plan-1",
+		}), nil)
+	})
+
+	JustBeforeEach(func() {
+		rdsInstance.DescribeByTagReturns(dbInstances, nil)
+	})
+
+	It("counts and logs the instances older than their plan allows", func() {
+		agedInstances, err := rdsBroker.CheckInstanceAges()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(agedInstances).To(Equal(1))
+
+		tagKey, tagValue, _ := rdsInstance.DescribeByTagArgsForCall(0)
+		Expect(tagKey).To(Equal("Broker Name"))
+		Expect(tagValue).To(Equal("mybroker"))
+
+		Expect(logger.LogMessages()).To(ContainElement("instance_age_test.broker.instance-age.exceeded"))
+		Expect(logger.LogMessages()).To(ContainElement("instance_age_test.broker.instance-age.checked"))
+	})
+
+	It("serves the ages in the metrics", func() {
+		Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+		recorder := httptest.NewRecorder()
+		rdsBroker.AdminHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/metrics", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(ContainSubstring(
+			"# TYPE rds_broker_instance_age_days gauge\n" +
+				`rds_broker_instance_age_days{instance_id="old-instance",db_instance_identifier="cf-old-instance",plan_id="Plan-1"} 400` + "\n" +
+				`rds_broker_instance_age_days{instance_id="new-instance",db_instance_identifier="cf-new-instance",plan_id="Plan-1"} 10` + "\n",
+		))
+		Expect(recorder.Body.String()).To(ContainSubstring(
+			"# TYPE rds_broker_instance_max_age_exceeded gauge\n" +
+				`rds_broker_instance_max_age_exceeded{instance_id="old-instance",db_instance_identifier="cf-old-instance",plan_id="Plan-1"} 1` + "\n" +
+				`rds_broker_instance_max_age_exceeded{instance_id="new-instance",db_instance_identifier="cf-new-instance",plan_id="Plan-1"} 0` + "\n",
+		))
+	})
+
+	Context("when the plan has no maximum instance age", func() {
+		BeforeEach(func() {
+			rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+				"Broker Name": "mybroker",
+				"Plan ID":     "Plan-2",
+			}), nil)
+		})
+
+		It("doesn't report the instances", func() {
+			agedInstances, err := rdsBroker.CheckInstanceAges()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(agedInstances).To(Equal(0))
+		})
+	})
+
+	Context("when the instances can't be described", func() {
+		JustBeforeEach(func() {
+			rdsInstance.DescribeByTagReturns(nil, errors.New("boom"))
+		})
+
+		It("returns the error", func() {
+			_, err := rdsBroker.CheckInstanceAges()
+			Expect(err).To(MatchError("boom"))
+		})
+	})
+})
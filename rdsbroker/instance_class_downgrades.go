@@ -0,0 +1,93 @@
+package rdsbroker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// instanceClassSizes is the capacity of each size of instance class, in
+// multiples of a large. Within a family each size has twice the vCPUs and
+// memory of the one below it, and an Nxlarge has N times those of an xlarge.
+var instanceClassSizes = map[string]float64{
+	"nano":   1.0 / 16,
+	"micro":  1.0 / 8,
+	"small":  1.0 / 4,
+	"medium": 1.0 / 2,
+	"large":  1,
+	"xlarge": 2,
+}
+
+// instanceClassCapacity is roughly how much CPU and memory an instance
+// class has, in multiples of a large, from its size. It returns false for
+// sizes it doesn't know, such as metal.
+func instanceClassCapacity(instanceClass string) (float64, bool) {
+	parts := strings.Split(instanceClass, ".")
+	size := parts[len(parts)-1]
+	if capacity, ok := instanceClassSizes[size]; ok {
+		return capacity, true
+	}
+	if strings.HasSuffix(size, "xlarge") {
+		multiple, err := strconv.Atoi(strings.TrimSuffix(size, "xlarge"))
+		if err == nil && multiple > 0 {
+			return float64(multiple) * instanceClassSizes["xlarge"], true
+		}
+	}
+	return 0, false
+}
+
+// instanceClassRatio is the capacity of the new instance class relative to
+// the old one. When the price table has both classes their prices are
+// compared, as they follow the capacity of classes across families too;
+// otherwise the sizes of the classes are. It returns false when neither can
+// tell the classes apart.
+func (b *RDSBroker) instanceClassRatio(oldClass string, newClass string) (float64, bool) {
+	if b.priceTable != nil {
+		oldPrice, oldFound := b.priceTable.InstanceClassHourly[oldClass]
+		newPrice, newFound := b.priceTable.InstanceClassHourly[newClass]
+		if oldFound && newFound && oldPrice > 0 {
+			return newPrice / oldPrice, true
+		}
+	}
+
+	oldCapacity, oldFound := instanceClassCapacity(oldClass)
+	newCapacity, newFound := instanceClassCapacity(newClass)
+	if !oldFound || !newFound {
+		return 0, false
+	}
+	return newCapacity / oldCapacity, true
+}
+
+// instanceClassDowngradeWarning describes what moving a DB instance from
+// the previous plan's instance class to the new plan's smaller one would do
+// to its performance, or returns an empty string if the new class isn't
+// smaller.
+func (b *RDSBroker) instanceClassDowngradeWarning(servicePlan ServicePlan, previousServicePlan ServicePlan) string {
+	oldClass := aws.StringValue(previousServicePlan.RDSProperties.DBInstanceClass)
+	newClass := aws.StringValue(servicePlan.RDSProperties.DBInstanceClass)
+	if oldClass == "" || newClass == "" || oldClass == newClass {
+		return ""
+	}
+
+	ratio, ok := b.instanceClassRatio(oldClass, newClass)
+	if !ok || ratio >= 1 {
+		return ""
+	}
+	return fmt.Sprintf(
+		"Service Plan '%s' has a smaller instance class than '%s': %s has about %d%% of the CPU and memory of %s. Queries may be slower, less data will be cached, fewer connections may be allowed, and the DB instance will be rebooted to move it",
+		servicePlan.ID, previousServicePlan.ID, newClass, int(ratio*100+0.5), oldClass,
+	)
+}
+
+// checkInstanceClassDowngrade refuses a plan change onto a smaller instance
+// class unless the update confirms it, so a mistyped plan doesn't slow down
+// a production database. Previews aren't refused, as they report the
+// downgrade instead.
+func (b *RDSBroker) checkInstanceClassDowngrade(downgradeWarning string, updateParameters UpdateParameters) error {
+	if !b.confirmInstanceClassDowngrades || downgradeWarning == "" || updateParameters.ConfirmDowngrade || updateParameters.Preview {
+		return nil
+	}
+	return newUserError(ErrCodeInvalidPlanChange, "%s. Set the confirm_downgrade parameter to true to move it anyway", downgradeWarning)
+}
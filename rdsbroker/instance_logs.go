@@ -0,0 +1,164 @@
+package rdsbroker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+var ErrNoLogFiles = errors.New("DB instance has no log files of that type")
+
+// instanceLogFilePrefixes are the directories RDS keeps each type of log in,
+// by engine. Postgres writes its slow queries, once
+// log_min_duration_statement is set, to the same log as everything else.
+var instanceLogFilePrefixes = map[string]map[string]string{
+	"mariadb": {
+		"slowquery": "slowquery/",
+		"general":   "general/",
+	},
+	"mysql": {
+		"slowquery": "slowquery/",
+		"general":   "general/",
+	},
+	"postgres": {
+		"slowquery": "error/",
+		"general":   "error/",
+	},
+}
+
+// InstanceLogFile is a log file RDS keeps for a DB instance.
+type InstanceLogFile struct {
+	DBInstanceIdentifier string    `json:"db_instance_identifier"`
+	Name                 string    `json:"name"`
+	Size                 int64     `json:"size"`
+	LastWritten          time.Time `json:"last_written"`
+}
+
+// InstanceLogFiles are the log files RDS keeps for the DB instance of a
+// service instance, along with the parameters which decide what gets logged
+// and for how long.
+type InstanceLogFiles struct {
+	InstanceID           string            `json:"instance_id"`
+	DBInstanceIdentifier string            `json:"db_instance_identifier"`
+	LogFiles             []InstanceLogFile `json:"log_files"`
+	TotalSize            int64             `json:"total_size"`
+	LoggingParameters    map[string]string `json:"logging_parameters"`
+}
+
+// loggingParameterNames are the parameters, by engine, which turn logs on
+// and decide how long they are kept.
+var loggingParameterNames = map[string][]string{
+	"mariadb": {"general_log", "log_output", "long_query_time", "slow_query_log"},
+	"mysql":   {"general_log", "log_output", "long_query_time", "slow_query_log"},
+	"postgres": {
+		"log_min_duration_statement",
+		"log_rotation_age",
+		"log_rotation_size",
+		"log_statement",
+		"rds.log_retention_period",
+	},
+}
+
+// ListInstanceLogFiles lists every log file of the DB instance of a service
+// instance, most recently written first, so that operators can check logging
+// is turned on and that the logs aren't growing too large.
+func (b *RDSBroker) ListInstanceLogFiles(instanceID string) (*InstanceLogFiles, error) {
+	dbInstance, _, err := b.describeOwnedDBInstance(instanceID)
+	if err != nil {
+		return nil, err
+	}
+	dbInstanceIdentifier := aws.StringValue(dbInstance.DBInstanceIdentifier)
+
+	rdsLogFiles, err := b.dbInstance.DescribeLogFiles(dbInstanceIdentifier, "")
+	if err != nil {
+		return nil, err
+	}
+
+	instanceLogFiles := &InstanceLogFiles{
+		InstanceID:           instanceID,
+		DBInstanceIdentifier: dbInstanceIdentifier,
+		LogFiles:             []InstanceLogFile{},
+		LoggingParameters:    map[string]string{},
+	}
+	for _, rdsLogFile := range rdsLogFiles {
+		logFile := newInstanceLogFile(dbInstanceIdentifier, rdsLogFile)
+		instanceLogFiles.LogFiles = append(instanceLogFiles.LogFiles, logFile)
+		instanceLogFiles.TotalSize += logFile.Size
+	}
+
+	if len(dbInstance.DBParameterGroups) > 0 {
+		parameterGroupName := aws.StringValue(dbInstance.DBParameterGroups[0].DBParameterGroupName)
+		rdsParameters, err := b.dbInstance.DescribeParameters(parameterGroupName)
+		if err != nil {
+			return nil, err
+		}
+		for _, rdsParameter := range rdsParameters {
+			name := aws.StringValue(rdsParameter.ParameterName)
+			if rdsParameter.ParameterValue != nil && containsString(loggingParameterNames[aws.StringValue(dbInstance.Engine)], name) {
+				instanceLogFiles.LoggingParameters[name] = aws.StringValue(rdsParameter.ParameterValue)
+			}
+		}
+	}
+
+	b.logger.Info("admin.instance-log-files", lager.Data{
+		instanceIDLogKey: instanceID,
+		"count":          len(instanceLogFiles.LogFiles),
+		"totalSize":      instanceLogFiles.TotalSize,
+	})
+
+	return instanceLogFiles, nil
+}
+
+func newInstanceLogFile(dbInstanceIdentifier string, rdsLogFile *rds.DescribeDBLogFilesDetails) InstanceLogFile {
+	return InstanceLogFile{
+		DBInstanceIdentifier: dbInstanceIdentifier,
+		Name:                 aws.StringValue(rdsLogFile.LogFileName),
+		Size:                 aws.Int64Value(rdsLogFile.Size),
+		// RDS gives the time in milliseconds since the epoch
+		LastWritten: time.UnixMilli(aws.Int64Value(rdsLogFile.LastWritten)).UTC(),
+	}
+}
+
+// LatestInstanceLogFile finds the most recently written log file of the
+// given type, `slowquery` or `general`, for the DB instance of a service
+// instance.
+func (b *RDSBroker) LatestInstanceLogFile(instanceID string, logType string) (*InstanceLogFile, error) {
+	dbInstance, _, err := b.describeOwnedDBInstance(instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	engine := aws.StringValue(dbInstance.Engine)
+	prefix, ok := instanceLogFilePrefixes[engine][logType]
+	if !ok {
+		return nil, fmt.Errorf("%s logs are not available for %s instances", logType, engine)
+	}
+
+	dbInstanceIdentifier := aws.StringValue(dbInstance.DBInstanceIdentifier)
+	logFiles, err := b.dbInstance.DescribeLogFiles(dbInstanceIdentifier, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if len(logFiles) == 0 {
+		return nil, ErrNoLogFiles
+	}
+
+	logFile := newInstanceLogFile(dbInstanceIdentifier, logFiles[0])
+	return &logFile, nil
+}
+
+// DownloadInstanceLogFile writes the contents of a log file to w as they are
+// downloaded from RDS.
+func (b *RDSBroker) DownloadInstanceLogFile(logFile *InstanceLogFile, w io.Writer) error {
+	b.logger.Info("admin.download-instance-log-file", lager.Data{
+		"db_instance_identifier": logFile.DBInstanceIdentifier,
+		"log_file":               logFile.Name,
+	})
+
+	return b.dbInstance.DownloadLogFile(logFile.DBInstanceIdentifier, logFile.Name, w)
+}
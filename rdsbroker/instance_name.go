@@ -0,0 +1,65 @@
+package rdsbroker
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+)
+
+const (
+	instanceNamesSeparator = "/"
+	maxRDSTagValueLength   = 256
+)
+
+// instanceNameFromContext returns the platform's name for the service
+// instance, which Cloud Foundry sends as "instance_name" in the request
+// context. Names can change over the lifetime of an instance, unlike its GUID.
+func instanceNameFromContext(rawContext json.RawMessage) string {
+	if len(rawContext) == 0 {
+		return ""
+	}
+	var properties struct {
+		InstanceName string `json:"instance_name"`
+	}
+	if err := json.Unmarshal(rawContext, &properties); err != nil {
+		return ""
+	}
+	return sanitizeTagValue(properties.InstanceName)
+}
+
+// sanitizeTagValue replaces the characters RDS doesn't accept in tag values,
+// along with our own separator, and truncates the value to the longest RDS
+// allows.
+func sanitizeTagValue(value string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) || strings.ContainsRune("_.:=+-@", r) {
+			return r
+		}
+		return '_'
+	}, value)
+
+	runes := []rune(sanitized)
+	if len(runes) > maxRDSTagValueLength {
+		runes = runes[:maxRDSTagValueLength]
+	}
+	return string(runes)
+}
+
+// appendPreviousInstanceName adds name to the packed list of names the
+// instance has had, dropping the oldest names if the list would no longer
+// fit in a tag.
+func appendPreviousInstanceName(previousNames string, name string) string {
+	names := unpackInstanceNames(previousNames)
+	names = append(names, name)
+	for len(names) > 1 && len(strings.Join(names, instanceNamesSeparator)) > maxRDSTagValueLength {
+		names = names[1:]
+	}
+	return strings.Join(names, instanceNamesSeparator)
+}
+
+func unpackInstanceNames(packedNames string) []string {
+	if packedNames == "" {
+		return []string{}
+	}
+	return strings.Split(packedNames, instanceNamesSeparator)
+}
@@ -0,0 +1,220 @@
+package rdsbroker
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// InventoryStore is the part of the S3 API the inventory export uses.
+type InventoryStore interface {
+	PutObject(*s3.PutObjectInput) (*s3.PutObjectOutput, error)
+}
+
+// InventoryRecord is one DB instance in the inventory exported for billing
+// and CMDB pipelines.
+type InventoryRecord struct {
+	InstanceID           string            `json:"instance_id"`
+	DBInstanceIdentifier string            `json:"db_instance_identifier"`
+	ServiceID            string            `json:"service_id"`
+	PlanID               string            `json:"plan_id"`
+	PlanName             string            `json:"plan_name"`
+	OrganizationID       string            `json:"organization_id"`
+	SpaceID              string            `json:"space_id"`
+	Engine               string            `json:"engine"`
+	EngineVersion        string            `json:"engine_version"`
+	DBInstanceClass      string            `json:"db_instance_class"`
+	AllocatedStorage     int64             `json:"allocated_storage"`
+	StorageType          string            `json:"storage_type"`
+	DBInstanceStatus     string            `json:"db_instance_status"`
+	State                string            `json:"state"`
+	Tags                 map[string]string `json:"tags"`
+}
+
+var inventoryCSVHeader = []string{
+	"instance_id",
+	"db_instance_identifier",
+	"service_id",
+	"plan_id",
+	"plan_name",
+	"organization_id",
+	"space_id",
+	"engine",
+	"engine_version",
+	"db_instance_class",
+	"allocated_storage",
+	"storage_type",
+	"db_instance_status",
+	"state",
+	"tags",
+}
+
+// SetInventoryStore sets where ExportInventory writes the inventory. The
+// export is skipped until it is set.
+func (b *RDSBroker) SetInventoryStore(store InventoryStore) {
+	b.inventoryStore = store
+}
+
+// Inventory lists every DB instance owned by this broker, with the service
+// instance it belongs to and its size and state.
+func (b *RDSBroker) Inventory() ([]InventoryRecord, error) {
+	dbInstances, err := b.listManagedDBInstances()
+	if err != nil {
+		return nil, err
+	}
+	return b.inventory(dbInstances), nil
+}
+
+func (b *RDSBroker) inventory(dbInstances []managedDBInstance) []InventoryRecord {
+	records := []InventoryRecord{}
+	for _, instance := range dbInstances {
+		dbInstance := instance.dbInstance
+		tagsByName := instance.tagsByName
+		dbInstanceIdentifier := aws.StringValue(dbInstance.DBInstanceIdentifier)
+
+		instanceID := tagsByName[awsrds.TagChargeableEntity]
+		if instanceID == "" {
+			instanceID = b.dbInstanceIdentifierToServiceInstanceID(dbInstanceIdentifier)
+		}
+		servicePlan, _ := b.catalog.FindServicePlan(tagsByName[awsrds.TagPlanID])
+
+		dbInstanceStatus := aws.StringValue(dbInstance.DBInstanceStatus)
+		records = append(records, InventoryRecord{
+			InstanceID:           instanceID,
+			DBInstanceIdentifier: dbInstanceIdentifier,
+			ServiceID:            tagsByName[awsrds.TagServiceID],
+			PlanID:               tagsByName[awsrds.TagPlanID],
+			PlanName:             servicePlan.Name,
+			OrganizationID:       tagsByName[awsrds.TagOrganizationID],
+			SpaceID:              tagsByName[awsrds.TagSpaceID],
+			Engine:               aws.StringValue(dbInstance.Engine),
+			EngineVersion:        aws.StringValue(dbInstance.EngineVersion),
+			DBInstanceClass:      aws.StringValue(dbInstance.DBInstanceClass),
+			AllocatedStorage:     aws.Int64Value(dbInstance.AllocatedStorage),
+			StorageType:          aws.StringValue(dbInstance.StorageType),
+			DBInstanceStatus:     dbInstanceStatus,
+			State:                string(rdsStatus2State[dbInstanceStatus]),
+			Tags:                 tagsByName,
+		})
+	}
+
+	return records
+}
+
+// ExportInventory writes the inventory to the configured S3 bucket as
+// `<prefix>inventory-<date>.json` and `.csv`, once per UTC day. Later runs
+// on the same day do nothing, and a run after a restart overwrites the
+// day's objects.
+func (b *RDSBroker) ExportInventory() error {
+	if b.inventoryStore == nil || b.inventoryBucket == "" {
+		return nil
+	}
+
+	today := time.Now().UTC().Format("2006-01-02")
+	if b.inventoryExportedOn == today {
+		return nil
+	}
+
+	dbInstances, err := b.listManagedDBInstances()
+	if err != nil {
+		return err
+	}
+	return b.exportInventory(dbInstances)
+}
+
+func (b *RDSBroker) exportInventory(dbInstances []managedDBInstance) error {
+	if b.inventoryStore == nil || b.inventoryBucket == "" {
+		return nil
+	}
+
+	today := time.Now().UTC().Format("2006-01-02")
+	if b.inventoryExportedOn == today {
+		return nil
+	}
+
+	records := b.inventory(dbInstances)
+
+	jsonBody, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	csvBody, err := inventoryCSV(records)
+	if err != nil {
+		return err
+	}
+
+	key := b.inventoryPrefix + "inventory-" + today
+	for _, object := range []struct {
+		key         string
+		contentType string
+		body        []byte
+	}{
+		{key + ".json", "application/json", jsonBody},
+		{key + ".csv", "text/csv", csvBody},
+	} {
+		_, err := b.inventoryStore.PutObject(&s3.PutObjectInput{
+			Bucket:      aws.String(b.inventoryBucket),
+			Key:         aws.String(object.key),
+			ContentType: aws.String(object.contentType),
+			Body:        bytes.NewReader(object.body),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	b.inventoryExportedOn = today
+	b.logger.Info("export-inventory", lager.Data{
+		"bucket": b.inventoryBucket,
+		"key":    key,
+		"count":  len(records),
+	})
+
+	return nil
+}
+
+// inventoryCSV writes one row per record, with the tags as a JSON object as
+// they don't fit in a fixed set of columns.
+func inventoryCSV(records []InventoryRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(inventoryCSVHeader); err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		tags, err := json.Marshal(record.Tags)
+		if err != nil {
+			return nil, err
+		}
+		err = w.Write([]string{
+			record.InstanceID,
+			record.DBInstanceIdentifier,
+			record.ServiceID,
+			record.PlanID,
+			record.PlanName,
+			record.OrganizationID,
+			record.SpaceID,
+			record.Engine,
+			record.EngineVersion,
+			record.DBInstanceClass,
+			strconv.FormatInt(record.AllocatedStorage, 10),
+			record.StorageType,
+			record.DBInstanceStatus,
+			record.State,
+			string(tags),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
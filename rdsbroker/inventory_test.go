@@ -0,0 +1,187 @@
+package rdsbroker_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/s3"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+)
+
+type putObject struct {
+	bucket      string
+	key         string
+	contentType string
+	body        []byte
+}
+
+type fakeInventoryStore struct {
+	objects []putObject
+	err     error
+}
+
+func (f *fakeInventoryStore) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	body, err := io.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.objects = append(f.objects, putObject{
+		bucket:      aws.StringValue(input.Bucket),
+		key:         aws.StringValue(input.Key),
+		contentType: aws.StringValue(input.ContentType),
+		body:        body,
+	})
+	return &s3.PutObjectOutput{}, nil
+}
+
+var _ = Describe("Inventory", func() {
+	var (
+		rdsInstance     *rdsfake.FakeRDSInstance
+		store           *fakeInventoryStore
+		rdsBroker       *RDSBroker
+		inventoryBucket string
+		today           string
+	)
+
+	BeforeEach(func() {
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		store = &fakeInventoryStore{}
+		inventoryBucket = "inventory-bucket"
+		today = time.Now().UTC().Format("2006-01-02")
+
+		rdsInstance.DescribeByTagReturns([]*rds.DBInstance{
+			{
+				DBInstanceIdentifier: aws.String("cf-instance-1"),
+				DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-instance-1"),
+				DBInstanceStatus:     aws.String("available"),
+				DBInstanceClass:      aws.String("db.t3.small"),
+				Engine:               aws.String("postgres"),
+				EngineVersion:        aws.String("13.7"),
+				AllocatedStorage:     aws.Int64(100),
+				StorageType:          aws.String("gp2"),
+			},
+		}, nil)
+		rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+			"Broker Name":       "mybroker",
+			"chargeable_entity": "instance-1",
+			"Service ID":        "Service-1",
+			"Plan ID":           "Plan-1",
+			"Organization ID":   "organization-id",
+			"Space ID":          "space-id",
+		}), nil)
+	})
+
+	JustBeforeEach(func() {
+		config := testConfig()
+		config.InventoryBucket = inventoryBucket
+		config.InventoryPrefix = "rds/"
+		rdsBroker = newTestBroker(config, rdsInstance, nil, lagertest.NewTestLogger("inventory_test"))
+		rdsBroker.SetInventoryStore(store)
+	})
+
+	Describe("Inventory", func() {
+		It("describes every instance owned by this broker", func() {
+			records, err := rdsBroker.Inventory()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(records).To(HaveLen(1))
+			Expect(records[0]).To(Equal(InventoryRecord{
+				InstanceID:           "instance-1",
+				DBInstanceIdentifier: "cf-instance-1",
+				ServiceID:            "Service-1",
+				PlanID:               "Plan-1",
+				PlanName:             "small",
+				OrganizationID:       "organization-id",
+				SpaceID:              "space-id",
+				Engine:               "postgres",
+				EngineVersion:        "13.7",
+				DBInstanceClass:      "db.t3.small",
+				AllocatedStorage:     100,
+				StorageType:          "gp2",
+				DBInstanceStatus:     "available",
+				State:                "succeeded",
+				Tags: map[string]string{
+					"Broker Name":       "mybroker",
+					"chargeable_entity": "instance-1",
+					"Service ID":        "Service-1",
+					"Plan ID":           "Plan-1",
+					"Organization ID":   "organization-id",
+					"Space ID":          "space-id",
+				},
+			}))
+		})
+	})
+
+	Describe("ExportInventory", func() {
+		It("writes the inventory to the bucket as JSON and CSV", func() {
+			Expect(rdsBroker.ExportInventory()).To(Succeed())
+
+			Expect(store.objects).To(HaveLen(2))
+
+			Expect(store.objects[0].bucket).To(Equal("inventory-bucket"))
+			Expect(store.objects[0].key).To(Equal("rds/inventory-" + today + ".json"))
+			Expect(store.objects[0].contentType).To(Equal("application/json"))
+			var records []map[string]interface{}
+			Expect(json.Unmarshal(store.objects[0].body, &records)).To(Succeed())
+			Expect(records).To(HaveLen(1))
+			Expect(records[0]).To(HaveKeyWithValue("instance_id", "instance-1"))
+			Expect(records[0]).To(HaveKeyWithValue("engine_version", "13.7"))
+
+			Expect(store.objects[1].bucket).To(Equal("inventory-bucket"))
+			Expect(store.objects[1].key).To(Equal("rds/inventory-" + today + ".csv"))
+			Expect(store.objects[1].contentType).To(Equal("text/csv"))
+			rows, err := csv.NewReader(bytes.NewReader(store.objects[1].body)).ReadAll()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rows).To(HaveLen(2))
+			Expect(rows[0][0]).To(Equal("instance_id"))
+			Expect(rows[1][:11]).To(Equal([]string{
+				"instance-1", "cf-instance-1", "Service-1", "Plan-1", "small",
+				"organization-id", "space-id", "postgres", "13.7", "db.t3.small", "100",
+			}))
+		})
+
+		It("only writes the inventory once a day", func() {
+			Expect(rdsBroker.ExportInventory()).To(Succeed())
+			Expect(rdsBroker.ExportInventory()).To(Succeed())
+
+			Expect(store.objects).To(HaveLen(2))
+			Expect(rdsInstance.DescribeByTagCallCount()).To(Equal(1))
+		})
+
+		It("tries again on the next run if the upload fails", func() {
+			store.err = errors.New("access denied")
+			Expect(rdsBroker.ExportInventory()).To(MatchError("access denied"))
+
+			store.err = nil
+			Expect(rdsBroker.ExportInventory()).To(Succeed())
+			Expect(store.objects).To(HaveLen(2))
+		})
+
+		Context("when no bucket is configured", func() {
+			BeforeEach(func() {
+				inventoryBucket = ""
+			})
+
+			It("does nothing", func() {
+				Expect(rdsBroker.ExportInventory()).To(Succeed())
+				Expect(store.objects).To(BeEmpty())
+				Expect(rdsInstance.DescribeByTagCallCount()).To(Equal(0))
+			})
+		})
+	})
+})
@@ -0,0 +1,64 @@
+package rdsbroker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+
+	"github.com/alphagov/paas-rds-broker/awskms"
+)
+
+// SetKeyResolver lets users choose one of the broker's kms_key_aliases to
+// encrypt their instance with, using the resolver to look the keys up.
+func (b *RDSBroker) SetKeyResolver(keyResolver awskms.KeyResolver) {
+	b.keyResolver = keyResolver
+}
+
+// checkKmsKeyAliasAllowed refuses a kms_key_alias parameter for a key which
+// isn't in the broker's allow-list, on a plan which doesn't encrypt its
+// instances, or for an instance which doesn't start out empty. Restored and
+// adopted instances keep the key of the data they are made from.
+func (b *RDSBroker) checkKmsKeyAliasAllowed(servicePlan ServicePlan, provisionParameters ProvisionParameters) error {
+	alias := provisionParameters.KmsKeyAlias
+	if alias == "" {
+		return nil
+	}
+	if !aws.BoolValue(servicePlan.RDSProperties.StorageEncrypted) {
+		return newUserError(ErrCodePlanNotAllowed, "Service Plan '%s' does not encrypt storage, so kms_key_alias can't be set", servicePlan.Name)
+	}
+	if provisionParameters.AdoptDBInstance != nil ||
+		provisionParameters.RestoreFromLatestSnapshotOf != nil ||
+		provisionParameters.RestoreFromPointInTimeOf != nil ||
+		provisionParameters.RestorePrevious {
+		return newUserError(ErrCodeInvalidParameters, "kms_key_alias can't be combined with adopt_db_instance or the restore parameters")
+	}
+	if b.keyResolver == nil || !containsString(b.kmsKeyAliases, alias) {
+		return newUserError(ErrCodeKmsKeyDenied, "KMS key '%s' is not available", alias)
+	}
+	return nil
+}
+
+// kmsKeyIDForProvision returns the key a new instance is encrypted with:
+// the customer managed key the user chose, or else the plan's key.
+func (b *RDSBroker) kmsKeyIDForProvision(servicePlan ServicePlan, alias string) (*string, error) {
+	if alias == "" {
+		return servicePlan.RDSProperties.KmsKeyID, nil
+	}
+	arn, err := b.keyResolver.ResolveAlias(alias)
+	if err != nil {
+		return nil, newUserError(ErrCodeKmsKeyDenied, "%s", err)
+	}
+	return aws.String(arn), nil
+}
+
+// validateKmsKeyAliases checks the broker's allow-list only has aliases of
+// keys which could be customer managed.
+func validateKmsKeyAliases(aliases []string) error {
+	for _, alias := range aliases {
+		if !strings.HasPrefix(alias, "alias/") || strings.HasPrefix(alias, "alias/aws/") {
+			return fmt.Errorf("KMS key alias '%s' must start with 'alias/', and not be an AWS managed key", alias)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package rdsbroker_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+
+	kmsfake "github.com/alphagov/paas-rds-broker/awskms/fakes"
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+	sqlfake "github.com/alphagov/paas-rds-broker/sqlengine/fakes"
+)
+
+var _ = Describe("Customer managed KMS keys", func() {
+	const keyARN = "arn:aws:kms:rds-region:1234567890:key/tenant-key"
+
+	var (
+		config           Config
+		rdsInstance      *rdsfake.FakeRDSInstance
+		keyResolver      *kmsfake.FakeKeyResolver
+		rdsBroker        *RDSBroker
+		provisionDetails domain.ProvisionDetails
+	)
+
+	BeforeEach(func() {
+		config = testConfig()
+		config.AllowUserProvisionParameters = true
+		config.KmsKeyAliases = []string{"alias/tenant-key"}
+		config.Catalog.Services[0].Plans[0].RDSProperties.StorageEncrypted = aws.Bool(true)
+		config.Catalog.Services[0].Plans[0].RDSProperties.KmsKeyID = aws.String("plan-key")
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		keyResolver = &kmsfake.FakeKeyResolver{}
+		keyResolver.ResolveAliasReturns(keyARN, nil)
+
+		provisionDetails = domain.ProvisionDetails{
+			ServiceID:        "Service-1",
+			PlanID:           "Plan-1",
+			OrganizationGUID: "organization-id",
+			SpaceGUID:        "space-id",
+			RawParameters:    json.RawMessage(`{"kms_key_alias": "alias/tenant-key"}`),
+		}
+	})
+
+	JustBeforeEach(func() {
+		rdsBroker = newTestBroker(config, rdsInstance, &sqlfake.FakeSQLEngine{}, lagertest.NewTestLogger("kms_keys_test"))
+		rdsBroker.SetKeyResolver(keyResolver)
+	})
+
+	It("encrypts the instance with the key the alias refers to", func() {
+		_, err := rdsBroker.Provision(context.Background(), "instance-id", provisionDetails, true)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(keyResolver.ResolveAliasArgsForCall(0)).To(Equal("alias/tenant-key"))
+		Expect(rdsInstance.CreateCallCount()).To(Equal(1))
+		input := rdsInstance.CreateArgsForCall(0)
+		Expect(aws.StringValue(input.KmsKeyId)).To(Equal(keyARN))
+		Expect(awsrds.RDSTagsValues(input.Tags)).To(HaveKeyWithValue(awsrds.TagKmsKeyAlias, "alias/tenant-key"))
+	})
+
+	It("uses the plan's key when no alias is given", func() {
+		provisionDetails.RawParameters = nil
+
+		_, err := rdsBroker.Provision(context.Background(), "instance-id", provisionDetails, true)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(keyResolver.ResolveAliasCallCount()).To(Equal(0))
+		input := rdsInstance.CreateArgsForCall(0)
+		Expect(aws.StringValue(input.KmsKeyId)).To(Equal("plan-key"))
+		Expect(awsrds.RDSTagsValues(input.Tags)).NotTo(HaveKey(awsrds.TagKmsKeyAlias))
+	})
+
+	It("refuses aliases which aren't in the allow-list", func() {
+		provisionDetails.RawParameters = json.RawMessage(`{"kms_key_alias": "alias/other-key"}`)
+
+		_, err := rdsBroker.Provision(context.Background(), "instance-id", provisionDetails, true)
+		Expect(err).To(MatchError("KMS key 'alias/other-key' is not available"))
+		Expect(ErrorCode(err)).To(Equal(ErrCodeKmsKeyDenied))
+		Expect(rdsInstance.CreateCallCount()).To(Equal(0))
+	})
+
+	It("refuses keys which can't be used", func() {
+		keyResolver.ResolveAliasReturns("", errors.New("KMS key 'alias/tenant-key' is Disabled"))
+
+		_, err := rdsBroker.Provision(context.Background(), "instance-id", provisionDetails, true)
+		Expect(err).To(MatchError("KMS key 'alias/tenant-key' is Disabled"))
+		Expect(ErrorCode(err)).To(Equal(ErrCodeKmsKeyDenied))
+		Expect(rdsInstance.CreateCallCount()).To(Equal(0))
+	})
+
+	It("refuses an alias on a plan which doesn't encrypt storage", func() {
+		provisionDetails.PlanID = "Plan-2"
+
+		_, err := rdsBroker.Provision(context.Background(), "instance-id", provisionDetails, true)
+		Expect(err).To(MatchError("Service Plan 'mysql' does not encrypt storage, so kms_key_alias can't be set"))
+		Expect(ErrorCode(err)).To(Equal(ErrCodePlanNotAllowed))
+	})
+
+	It("refuses an alias when restoring", func() {
+		provisionDetails.RawParameters = json.RawMessage(`{"kms_key_alias": "alias/tenant-key", "restore_previous": true}`)
+
+		_, err := rdsBroker.Provision(context.Background(), "instance-id", provisionDetails, true)
+		Expect(err).To(MatchError("kms_key_alias can't be combined with adopt_db_instance or the restore parameters"))
+		Expect(ErrorCode(err)).To(Equal(ErrCodeInvalidParameters))
+	})
+
+	Context("when the broker can't look keys up", func() {
+		JustBeforeEach(func() {
+			rdsBroker.SetKeyResolver(nil)
+		})
+
+		It("refuses every alias", func() {
+			_, err := rdsBroker.Provision(context.Background(), "instance-id", provisionDetails, true)
+			Expect(err).To(MatchError("KMS key 'alias/tenant-key' is not available"))
+		})
+	})
+})
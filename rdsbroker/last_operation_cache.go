@@ -0,0 +1,79 @@
+package rdsbroker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+)
+
+// lastOperationCache remembers in-progress LastOperation responses for a
+// short while, so that a platform polling many instances at once doesn't
+// turn every poll into a round of RDS API calls. A nil or zero-duration cache
+// never returns anything.
+type lastOperationCache struct {
+	duration    time.Duration
+	timeNowFunc func() time.Time
+
+	lock    sync.Mutex
+	entries map[string]lastOperationCacheEntry
+}
+
+type lastOperationCacheEntry struct {
+	pollDetails   domain.PollDetails
+	lastOperation domain.LastOperation
+	requestTime   time.Time
+}
+
+func newLastOperationCache(duration time.Duration) *lastOperationCache {
+	return &lastOperationCache{
+		duration:    duration,
+		timeNowFunc: time.Now,
+		entries:     map[string]lastOperationCacheEntry{},
+	}
+}
+
+func (c *lastOperationCache) get(instanceID string, pollDetails domain.PollDetails) (domain.LastOperation, bool) {
+	if c == nil || c.duration == 0 {
+		return domain.LastOperation{}, false
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, ok := c.entries[instanceID]
+	if !ok || entry.pollDetails != pollDetails {
+		return domain.LastOperation{}, false
+	}
+	if c.timeNowFunc().After(entry.requestTime.Add(c.duration)) {
+		delete(c.entries, instanceID)
+		return domain.LastOperation{}, false
+	}
+	return entry.lastOperation, true
+}
+
+func (c *lastOperationCache) set(instanceID string, pollDetails domain.PollDetails, lastOperation domain.LastOperation) {
+	if c == nil || c.duration == 0 {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.entries[instanceID] = lastOperationCacheEntry{
+		pollDetails:   pollDetails,
+		lastOperation: lastOperation,
+		requestTime:   c.timeNowFunc(),
+	}
+}
+
+func (c *lastOperationCache) invalidate(instanceID string) {
+	if c == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.entries, instanceID)
+}
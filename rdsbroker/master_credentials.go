@@ -0,0 +1,299 @@
+package rdsbroker
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// The versions of the derivation of master passwords from the broker's
+// seed. Version 1 hashes the seed and instance ID joined together, and
+// version 2 derives a key from the seed with HKDF, salted with the instance
+// ID. The version of each DB instance's password is kept in its Master
+// Password Version tag, which instances with version 1 passwords don't have.
+const (
+	MasterPasswordVersionSHA256 = 1
+	MasterPasswordVersionHKDF   = 2
+)
+
+// masterPasswordKeyInfo is the HKDF info of version 2 passwords. Its ":0"
+// is left from when their key material was read in numbered blocks, and
+// keeps the passwords of existing DB instances the same.
+const masterPasswordKeyInfo = "rds-broker master password:0"
+
+// base64URLCharacters are the characters of version 1 passwords, which
+// version 2 passwords use too unless an engine's policy gives others.
+const base64URLCharacters = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+
+// masterPasswordForbiddenCharacters can't be used in the master passwords of
+// any RDS engine.
+const masterPasswordForbiddenCharacters = `/@" `
+
+// MasterPasswordPolicy sets the length and characters of the master
+// passwords of an engine's DB instances, for engines whose rules the
+// broker's default passwords don't meet. Without Characters, passwords are
+// drawn from the same URL safe base64 characters as the default ones.
+type MasterPasswordPolicy struct {
+	Length     int    `json:"length"`
+	Characters string `json:"characters"`
+}
+
+func (p MasterPasswordPolicy) Validate() error {
+	if p.Characters == "" {
+		if p.Length < 8 || p.Length > 43 {
+			return errors.New("Length must be between 8 and 43 without Characters")
+		}
+		return nil
+	}
+	if p.Length < 8 || p.Length > 128 {
+		return errors.New("Length must be between 8 and 128")
+	}
+	if len(p.Characters) > 256 {
+		return errors.New("Characters must contain at most 256 characters")
+	}
+	seen := map[rune]bool{}
+	for _, c := range p.Characters {
+		if c < '!' || c > '~' || strings.ContainsRune(masterPasswordForbiddenCharacters, c) {
+			return errors.New(`Characters must be printable ASCII characters other than '/', '@', '"' and space`)
+		}
+		if seen[c] {
+			return fmt.Errorf("Characters must not contain '%c' more than once", c)
+		}
+		seen[c] = true
+	}
+	if len(p.Characters) < 16 {
+		return errors.New("Characters must contain at least 16 characters")
+	}
+	return nil
+}
+
+// masterPasswordDerivation is how a master password is derived from the
+// broker's seed: the version of the derivation, and the policy of the
+// engine it follows, if any. The policy is kept in the Master Password
+// Policy tag of each DB instance, so that adding or changing the policy of
+// an engine only changes the passwords the broker derives from then on.
+type masterPasswordDerivation struct {
+	version int
+	policy  *MasterPasswordPolicy
+}
+
+// newMasterPasswordDerivation is the derivation of the master passwords the
+// broker gives DB instances of the engine.
+func (b *RDSBroker) newMasterPasswordDerivation(engine string) masterPasswordDerivation {
+	derivation := masterPasswordDerivation{version: b.masterPasswordVersion}
+	if policy, ok := b.masterPasswordPolicies[engine]; ok {
+		derivation.policy = &policy
+	}
+	return derivation
+}
+
+func masterPasswordDerivationFromTags(tagsByName map[string]string) masterPasswordDerivation {
+	return masterPasswordDerivation{
+		version: masterPasswordVersionFromTags(tagsByName),
+		policy:  unpackMasterPasswordPolicy(tagsByName[awsrds.TagMasterPasswordPolicy]),
+	}
+}
+
+func (d masterPasswordDerivation) equal(other masterPasswordDerivation) bool {
+	if d.policy == nil || other.policy == nil {
+		return d.version == other.version && d.policy == nil && other.policy == nil
+	}
+	return d.version == other.version && *d.policy == *other.policy
+}
+
+// packMasterPasswordPolicy writes a policy as a tag value. Tag values can't
+// hold most punctuation, so the characters are base64 encoded, which fits
+// as they are distinct printable ASCII ones.
+func packMasterPasswordPolicy(policy *MasterPasswordPolicy) string {
+	if policy == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d:%s", policy.Length, base64.StdEncoding.EncodeToString([]byte(policy.Characters)))
+}
+
+func unpackMasterPasswordPolicy(value string) *MasterPasswordPolicy {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+	length, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil
+	}
+	characters, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil
+	}
+	return &MasterPasswordPolicy{Length: length, Characters: string(characters)}
+}
+
+// MasterPassword returns the master password the broker derives from its
+// seed for a new DB instance of a service instance using the engine, with a
+// version of the derivation.
+func (b *RDSBroker) MasterPassword(instanceID string, engine string, version int) string {
+	derivation := b.newMasterPasswordDerivation(engine)
+	derivation.version = version
+	return b.generateMasterPassword(instanceID, derivation)
+}
+
+// masterPasswordVersionTag returns the value of the Master Password Version
+// tag of DB instances whose password has the version.
+func masterPasswordVersionTag(version int) string {
+	if version <= MasterPasswordVersionSHA256 {
+		return ""
+	}
+	return strconv.Itoa(version)
+}
+
+func masterPasswordVersionFromTags(tagsByName map[string]string) int {
+	version, err := strconv.Atoi(tagsByName[awsrds.TagMasterPasswordVersion])
+	if err != nil {
+		return MasterPasswordVersionSHA256
+	}
+	return version
+}
+
+// masterPasswordDerivationOf returns how the master password of a DB
+// instance was derived. Its tags aren't read from the cache, as they change
+// when the password is rotated to another derivation.
+func (b *RDSBroker) masterPasswordDerivationOf(dbInstance *rds.DBInstance) (masterPasswordDerivation, error) {
+	tags, err := b.dbInstance.GetResourceTags(aws.StringValue(dbInstance.DBInstanceArn))
+	if err != nil {
+		return masterPasswordDerivation{}, err
+	}
+	return masterPasswordDerivationFromTags(awsrds.RDSTagsValues(tags)), nil
+}
+
+// masterPasswordForDBInstance returns the master password a service
+// instance's DB instance has, with the derivation it is tagged with.
+func (b *RDSBroker) masterPasswordForDBInstance(instanceID string, dbInstance *rds.DBInstance) (string, error) {
+	derivation, err := b.masterPasswordDerivationOf(dbInstance)
+	if err != nil {
+		return "", err
+	}
+	return b.generateMasterPassword(instanceID, derivation), nil
+}
+
+// recordMasterPasswordRotation writes the tags of a DB instance, along with
+// when its master password was reset and the derivation of the password it
+// has been given. The time lets security reviews check that passwords were
+// really rotated after the seed was changed.
+func (b *RDSBroker) recordMasterPasswordRotation(instanceID string, dbInstanceArn string, tags map[string]string, previous masterPasswordDerivation, derivation masterPasswordDerivation) error {
+	tags[awsrds.TagMasterPasswordRotated] = time.Now().UTC().Format(time.RFC3339)
+	versionTag := masterPasswordVersionTag(derivation.version)
+	if versionTag != "" {
+		tags[awsrds.TagMasterPasswordVersion] = versionTag
+	}
+	if derivation.policy != nil {
+		tags[awsrds.TagMasterPasswordPolicy] = packMasterPasswordPolicy(derivation.policy)
+	}
+	b.writeTags(instanceID, dbInstanceArn, tags)
+
+	if versionTag == "" && previous.version != derivation.version {
+		if err := b.removeTag(b.dbInstanceIdentifier(instanceID), awsrds.TagMasterPasswordVersion); err != nil {
+			return err
+		}
+	}
+	if derivation.policy == nil && previous.policy != nil {
+		return b.removeTag(b.dbInstanceIdentifier(instanceID), awsrds.TagMasterPasswordPolicy)
+	}
+	return nil
+}
+
+// masterPasswordRotatedAt returns when the master password of a DB instance
+// was last reset by the broker, and false if it never has been.
+func masterPasswordRotatedAt(tagsByName map[string]string) (time.Time, bool) {
+	rotatedAt, err := time.Parse(time.RFC3339, tagsByName[awsrds.TagMasterPasswordRotated])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return rotatedAt, true
+}
+
+// masterPasswordRotation is when the master password of a DB instance was
+// last reset, and the version it was given.
+type masterPasswordRotation struct {
+	instanceID           string
+	dbInstanceIdentifier string
+	planID               string
+	version              int
+	rotatedAt            time.Time
+}
+
+// masterPasswordRotations gives the master password versions of the DB
+// instances, with when each was last rotated if it ever has been.
+func (b *RDSBroker) masterPasswordRotations(dbInstances []managedDBInstance) []masterPasswordRotation {
+	rotations := []masterPasswordRotation{}
+	for _, instance := range dbInstances {
+		dbInstanceIdentifier := aws.StringValue(instance.dbInstance.DBInstanceIdentifier)
+		rotatedAt, _ := masterPasswordRotatedAt(instance.tagsByName)
+		rotations = append(rotations, masterPasswordRotation{
+			instanceID:           b.dbInstanceIdentifierToServiceInstanceID(dbInstanceIdentifier),
+			dbInstanceIdentifier: dbInstanceIdentifier,
+			planID:               instance.tagsByName[awsrds.TagPlanID],
+			version:              masterPasswordVersionFromTags(instance.tagsByName),
+			rotatedAt:            rotatedAt,
+		})
+	}
+	return rotations
+}
+
+// writeMasterPasswordRotationMetrics writes the master password versions
+// of the DB instances, and when they were last rotated, in the Prometheus
+// text exposition format. Instances whose password has never been rotated
+// have no rotation timestamp.
+func writeMasterPasswordRotationMetrics(w io.Writer, rotations []masterPasswordRotation) {
+	const versionName = "rds_broker_master_password_version"
+	fmt.Fprintf(w, "# HELP %s The version of the derivation of the master password of the DB instance.\n", versionName)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", versionName)
+	for _, rotation := range rotations {
+		fmt.Fprintf(w, "%s{instance_id=%q,db_instance_identifier=%q,plan_id=%q} %d\n",
+			versionName, rotation.instanceID, rotation.dbInstanceIdentifier, rotation.planID, rotation.version)
+	}
+
+	const rotatedName = "rds_broker_master_password_rotated_timestamp_seconds"
+	fmt.Fprintf(w, "# HELP %s When the broker last reset the master password of the DB instance.\n", rotatedName)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", rotatedName)
+	for _, rotation := range rotations {
+		if rotation.rotatedAt.IsZero() {
+			continue
+		}
+		fmt.Fprintf(w, "%s{instance_id=%q,db_instance_identifier=%q,plan_id=%q} %d\n",
+			rotatedName, rotation.instanceID, rotation.dbInstanceIdentifier, rotation.planID, rotation.rotatedAt.Unix())
+	}
+}
+
+// DBInstanceIdentifier returns the identifier of the DB instance of a
+// service instance.
+func (b *RDSBroker) DBInstanceIdentifier(instanceID string) string {
+	return b.dbInstanceIdentifier(instanceID)
+}
+
+// VerifyConnection connects to the database of a service instance with its
+// master credentials, to check that the master password derived from the
+// broker's seed is the one the DB instance has.
+func (b *RDSBroker) VerifyConnection(instanceID string) error {
+	dbInstance, err := b.dbInstance.Describe(b.dbInstanceIdentifier(instanceID))
+	if err != nil {
+		return err
+	}
+	if dbInstance.Endpoint == nil {
+		return fmt.Errorf("DB instance %s has no endpoint, its status is '%s'", aws.StringValue(dbInstance.DBInstanceIdentifier), aws.StringValue(dbInstance.DBInstanceStatus))
+	}
+
+	sqlEngine, err := b.openSQLEngineForDBInstance(instanceID, b.dbNameFromDBInstance(instanceID, dbInstance), dbInstance)
+	if err != nil {
+		return err
+	}
+	sqlEngine.Close()
+	return nil
+}
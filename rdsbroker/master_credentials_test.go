@@ -0,0 +1,261 @@
+package rdsbroker_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+	"github.com/alphagov/paas-rds-broker/sqlengine"
+	sqlfake "github.com/alphagov/paas-rds-broker/sqlengine/fakes"
+	"github.com/alphagov/paas-rds-broker/utils"
+)
+
+var _ = Describe("Master credentials", func() {
+	var (
+		rdsInstance *rdsfake.FakeRDSInstance
+		sqlEngine   *sqlfake.FakeSQLEngine
+		rdsBroker   *RDSBroker
+	)
+
+	BeforeEach(func() {
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		dbInstance := testDBInstance()
+		dbInstance.DBInstanceIdentifier = aws.String("cf-instance-id")
+		rdsInstance.DescribeReturns(dbInstance, nil)
+		sqlEngine = &sqlfake.FakeSQLEngine{}
+
+		rdsBroker = newTestBroker(testConfig(), rdsInstance, sqlEngine, lagertest.NewTestLogger("master_credentials_test"))
+	})
+
+	It("derives the master password from the seed", func() {
+		Expect(rdsBroker.MasterPassword("instance-id", "postgres", MasterPasswordVersionSHA256)).To(Equal(utils.GenerateHash("something-secret"+"instance-id", MasterPasswordLength)))
+	})
+
+	Context("when the engine has a master password policy", func() {
+		BeforeEach(func() {
+			config := testConfig()
+			config.MasterPasswordPolicies = map[string]MasterPasswordPolicy{
+				"sqlserver-se": {Length: 64, Characters: "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!#"},
+				"postgres":     {Length: 40},
+			}
+			rdsBroker = newTestBroker(config, rdsInstance, sqlEngine, lagertest.NewTestLogger("master_credentials_test"))
+		})
+
+		It("derives the master password following the policy", func() {
+			password := rdsBroker.MasterPassword("instance-id", "sqlserver-se", MasterPasswordVersionSHA256)
+			Expect(password).To(HaveLen(64))
+			Expect(password).To(MatchRegexp(`^[A-Z0-9!#]+$`))
+		})
+
+		It("keeps the default characters when the policy only sets a length", func() {
+			Expect(rdsBroker.MasterPassword("instance-id", "postgres", MasterPasswordVersionSHA256)).To(Equal(utils.GenerateHash("something-secret"+"instance-id", 40)))
+		})
+
+		It("derives the default master password for other engines", func() {
+			Expect(rdsBroker.MasterPassword("instance-id", "mysql", MasterPasswordVersionSHA256)).To(Equal(utils.GenerateHash("something-secret"+"instance-id", MasterPasswordLength)))
+		})
+
+		It("connects with the password of the policy the DB instance is tagged with", func() {
+			rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{awsrds.TagMasterPasswordPolicy: "40:"}), nil)
+
+			Expect(rdsBroker.VerifyConnection("instance-id")).To(Succeed())
+			_, _, _, _, password := sqlEngine.OpenArgsForCall(0)
+			Expect(password).To(Equal(utils.GenerateHash("something-secret"+"instance-id", 40)))
+		})
+
+		It("connects to DB instances made before the policy with their original password", func() {
+			Expect(rdsBroker.VerifyConnection("instance-id")).To(Succeed())
+			_, _, _, _, password := sqlEngine.OpenArgsForCall(0)
+			Expect(password).To(Equal(utils.GenerateHash("something-secret"+"instance-id", MasterPasswordLength)))
+		})
+
+		It("moves master passwords which still work over to the policy", func() {
+			rdsInstance.DescribeByTagReturns([]*rds.DBInstance{{
+				DBInstanceIdentifier: aws.String("cf-instance-id"),
+				DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-instance-id"),
+				DBInstanceStatus:     aws.String("available"),
+				Engine:               aws.String("sqlserver-se"),
+			}}, nil)
+
+			rdsBroker.CheckAndRotateCredentials()
+
+			_, _, _, _, password := sqlEngine.OpenArgsForCall(0)
+			Expect(password).To(Equal(utils.GenerateHash("something-secret"+"instance-id", MasterPasswordLength)))
+			Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
+			Expect(aws.StringValue(rdsInstance.ModifyArgsForCall(0).MasterUserPassword)).To(Equal(rdsBroker.MasterPassword("instance-id", "sqlserver-se", MasterPasswordVersionSHA256)))
+
+			_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+			Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue(awsrds.TagMasterPasswordPolicy, "64:QUJDREVGR0hJSktMTU5PUFFSU1RVVldYWVowMTIzNDU2Nzg5ISM="))
+		})
+
+		It("leaves master passwords which follow the policy alone", func() {
+			rdsInstance.DescribeByTagReturns([]*rds.DBInstance{{
+				DBInstanceIdentifier: aws.String("cf-instance-id"),
+				DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-instance-id"),
+				DBInstanceStatus:     aws.String("available"),
+				Engine:               aws.String("postgres"),
+			}}, nil)
+			rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{awsrds.TagMasterPasswordPolicy: "40:"}), nil)
+
+			rdsBroker.CheckAndRotateCredentials()
+
+			Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+		})
+	})
+
+	It("derives version 2 master passwords from a key derived from the seed", func() {
+		password := rdsBroker.MasterPassword("instance-id", "postgres", MasterPasswordVersionHKDF)
+		Expect(password).To(HaveLen(MasterPasswordLength))
+		Expect(password).To(MatchRegexp(`^[A-Za-z0-9_-]+$`))
+		Expect(password).NotTo(Equal(rdsBroker.MasterPassword("instance-id", "postgres", MasterPasswordVersionSHA256)))
+		Expect(password).NotTo(Equal(rdsBroker.MasterPassword("other-instance-id", "postgres", MasterPasswordVersionHKDF)))
+	})
+
+	It("connects with the version of the password the DB instance is tagged with", func() {
+		rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{awsrds.TagMasterPasswordVersion: "2"}), nil)
+
+		Expect(rdsBroker.VerifyConnection("instance-id")).To(Succeed())
+		_, _, _, _, password := sqlEngine.OpenArgsForCall(0)
+		Expect(password).To(Equal(rdsBroker.MasterPassword("instance-id", "postgres", MasterPasswordVersionHKDF)))
+	})
+
+	It("derives the DB instance identifier from the prefix", func() {
+		Expect(rdsBroker.DBInstanceIdentifier("instance_id")).To(Equal("cf-instance-id"))
+	})
+
+	It("verifies the connection with the master credentials", func() {
+		Expect(rdsBroker.VerifyConnection("instance-id")).To(Succeed())
+
+		Expect(rdsInstance.DescribeArgsForCall(0)).To(Equal("cf-instance-id"))
+		address, _, dbname, username, password := sqlEngine.OpenArgsForCall(0)
+		Expect(address).To(Equal("endpoint-address"))
+		Expect(dbname).To(Equal("mydb"))
+		Expect(username).To(Equal("master-username"))
+		Expect(password).To(Equal(rdsBroker.MasterPassword("instance-id", "postgres", MasterPasswordVersionSHA256)))
+	})
+
+	It("returns the error when the connection fails", func() {
+		sqlEngine.OpenReturns(errors.New("password authentication failed"))
+
+		Expect(rdsBroker.VerifyConnection("instance-id")).To(MatchError("password authentication failed"))
+	})
+
+	Describe("CheckAndRotateCredentials", func() {
+		BeforeEach(func() {
+			config := testConfig()
+			config.MasterPasswordVersion = MasterPasswordVersionHKDF
+			rdsBroker = newTestBroker(config, rdsInstance, sqlEngine, lagertest.NewTestLogger("master_credentials_test"))
+
+			rdsInstance.DescribeByTagReturns([]*rds.DBInstance{{
+				DBInstanceIdentifier: aws.String("cf-instance-id"),
+				DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-instance-id"),
+				DBInstanceStatus:     aws.String("available"),
+				Engine:               aws.String("postgres"),
+				Endpoint: &rds.Endpoint{
+					Address: aws.String("endpoint-address"),
+					Port:    aws.Int64(5432),
+				},
+				MasterUsername: aws.String("master-username"),
+			}}, nil)
+		})
+
+		It("rotates version 1 master passwords which still work to the configured version", func() {
+			rdsBroker.CheckAndRotateCredentials()
+
+			_, _, _, _, password := sqlEngine.OpenArgsForCall(0)
+			Expect(password).To(Equal(rdsBroker.MasterPassword("instance-id", "postgres", MasterPasswordVersionSHA256)))
+			Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
+			input := rdsInstance.ModifyArgsForCall(0)
+			Expect(aws.StringValue(input.DBInstanceIdentifier)).To(Equal("cf-instance-id"))
+			Expect(aws.StringValue(input.MasterUserPassword)).To(Equal(rdsBroker.MasterPassword("instance-id", "postgres", MasterPasswordVersionHKDF)))
+
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+			arn, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+			Expect(arn).To(Equal("arn:aws:rds:rds-region:1234567890:db:cf-instance-id"))
+			tagsByName := awsrds.RDSTagsValues(tags)
+			Expect(tagsByName).To(HaveLen(2))
+			Expect(tagsByName).To(HaveKeyWithValue(awsrds.TagMasterPasswordVersion, "2"))
+			rotatedAt, err := time.Parse(time.RFC3339, tagsByName[awsrds.TagMasterPasswordRotated])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rotatedAt).To(BeTemporally("~", time.Now(), time.Minute))
+		})
+
+		It("leaves master passwords of the configured version alone", func() {
+			rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{awsrds.TagMasterPasswordVersion: "2"}), nil)
+
+			rdsBroker.CheckAndRotateCredentials()
+
+			_, _, _, _, password := sqlEngine.OpenArgsForCall(0)
+			Expect(password).To(Equal(rdsBroker.MasterPassword("instance-id", "postgres", MasterPasswordVersionHKDF)))
+			Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(0))
+		})
+
+		It("resets master passwords which don't work to the configured version", func() {
+			rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{awsrds.TagMasterPasswordVersion: "2"}), nil)
+			sqlEngine.OpenReturns(sqlengine.LoginFailedError)
+
+			rdsBroker.CheckAndRotateCredentials()
+
+			Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
+			input := rdsInstance.ModifyArgsForCall(0)
+			Expect(aws.StringValue(input.MasterUserPassword)).To(Equal(rdsBroker.MasterPassword("instance-id", "postgres", MasterPasswordVersionHKDF)))
+
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+			_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+			Expect(awsrds.RDSTagsValues(tags)).To(HaveKey(awsrds.TagMasterPasswordRotated))
+		})
+
+		It("doesn't rotate master passwords when it can't connect", func() {
+			sqlEngine.OpenReturns(errors.New("connection refused"))
+
+			rdsBroker.CheckAndRotateCredentials()
+
+			Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("master password rotation metrics", func() {
+		It("serves the version of every master password, and when those which were rotated were", func() {
+			rdsInstance.DescribeByTagReturns([]*rds.DBInstance{
+				{DBInstanceIdentifier: aws.String("cf-rotated-instance"), DBInstanceArn: aws.String("arn:rotated")},
+				{DBInstanceIdentifier: aws.String("cf-original-instance"), DBInstanceArn: aws.String("arn:original")},
+			}, nil)
+			rdsInstance.GetResourceTagsStub = func(arn string, opts ...awsrds.DescribeOption) ([]*rds.Tag, error) {
+				if arn == "arn:rotated" {
+					return awsrds.BuildRDSTags(map[string]string{
+						awsrds.TagPlanID:                "Plan-1",
+						awsrds.TagMasterPasswordVersion: "2",
+						awsrds.TagMasterPasswordRotated: "2026-10-01T12:00:00Z",
+					}), nil
+				}
+				return awsrds.BuildRDSTags(map[string]string{awsrds.TagPlanID: "Plan-1"}), nil
+			}
+
+			Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+			recorder := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/metrics", nil))
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			metrics := recorder.Body.String()
+
+			Expect(metrics).To(ContainSubstring(
+				`rds_broker_master_password_version{instance_id="rotated-instance",db_instance_identifier="cf-rotated-instance",plan_id="Plan-1"} 2` + "\n" +
+					`rds_broker_master_password_version{instance_id="original-instance",db_instance_identifier="cf-original-instance",plan_id="Plan-1"} 1` + "\n"))
+			Expect(metrics).To(ContainSubstring(
+				"# TYPE rds_broker_master_password_rotated_timestamp_seconds gauge\n" +
+					`rds_broker_master_password_rotated_timestamp_seconds{instance_id="rotated-instance",db_instance_identifier="cf-rotated-instance",plan_id="Plan-1"} 1790856000` + "\n"))
+			Expect(metrics).NotTo(ContainSubstring(`rds_broker_master_password_rotated_timestamp_seconds{instance_id="original-instance"`))
+		})
+	})
+})
@@ -0,0 +1,91 @@
+package rdsbroker
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// instanceMetrics are the metrics of each DB instance served by
+// /admin/metrics. Gathering them takes calls to RDS for every instance, so
+// housekeeping gathers them and scrapes are served the latest values, rather
+// than every scrape calling RDS and risking throttling.
+type instanceMetrics struct {
+	lock       sync.RWMutex
+	gatheredAt time.Time
+	backups    []BackupStatus
+	costs      []InstanceCost
+	ages       []instanceAge
+	placements []instancePlacement
+	rotations  []masterPasswordRotation
+
+	// versionDrifts are refreshed by their own housekeeping job, as
+	// they are also exported.
+	versionDrifts []VersionDrift
+
+	// transactionIDAges are refreshed by their own housekeeping job, as
+	// they also warn users fetching their instances.
+	transactionIDAges []transactionIDAge
+
+	// connectionUsages are refreshed by their own housekeeping job, as
+	// they also warn users polling their instances.
+	connectionUsages []connectionUsage
+}
+
+// driftMetricsWriter is implemented by a ParameterGroupSelector which
+// corrects parameter group drift, to serve what it found.
+type driftMetricsWriter interface {
+	writeDriftMetrics(w io.Writer)
+}
+
+// gatherInstanceMetrics is the housekeeping job which refreshes the
+// instance metrics.
+func (b *RDSBroker) gatherInstanceMetrics(dbInstances []managedDBInstance) error {
+	backups, err := b.backupStatuses(dbInstances)
+	if err != nil {
+		return err
+	}
+	costs := b.instanceCosts(dbInstances)
+	ages := b.instanceAges(dbInstances, time.Now())
+	placements, err := b.instancePlacements(dbInstances, time.Now())
+	if err != nil {
+		return err
+	}
+
+	b.instanceMetrics.lock.Lock()
+	defer b.instanceMetrics.lock.Unlock()
+	b.instanceMetrics.gatheredAt = time.Now()
+	b.instanceMetrics.backups = backups
+	b.instanceMetrics.costs = costs
+	b.instanceMetrics.ages = ages
+	b.instanceMetrics.placements = placements
+	b.instanceMetrics.rotations = b.masterPasswordRotations(dbInstances)
+	return nil
+}
+
+// writeInstanceMetrics writes the latest instance metrics, if housekeeping
+// has gathered any, in the Prometheus text exposition format.
+func (b *RDSBroker) writeInstanceMetrics(w io.Writer) {
+	b.instanceMetrics.lock.RLock()
+	defer b.instanceMetrics.lock.RUnlock()
+	if b.instanceMetrics.gatheredAt.IsZero() {
+		return
+	}
+
+	const name = "rds_broker_instance_metrics_gathered_timestamp_seconds"
+	fmt.Fprintf(w, "# HELP %s When the metrics of the DB instances were last gathered by housekeeping.\n", name)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+	fmt.Fprintf(w, "%s %d\n", name, b.instanceMetrics.gatheredAt.Unix())
+
+	writeBackupMetrics(w, b.instanceMetrics.backups)
+	if b.priceTable != nil {
+		writeCostMetrics(w, b.instanceMetrics.costs, b.region, b.priceTable.Currency)
+	}
+	writeInstanceAgeMetrics(w, b.instanceMetrics.ages)
+	writePlacementMetrics(w, b.instanceMetrics.placements)
+	writeMasterPasswordRotationMetrics(w, b.instanceMetrics.rotations)
+	writeVersionDriftMetrics(w, b.instanceMetrics.versionDrifts)
+	writeTransactionIDAgeMetrics(w, b.instanceMetrics.transactionIDAges)
+	writeConnectionUsageMetrics(w, b.instanceMetrics.connectionUsages)
+}
@@ -0,0 +1,382 @@
+package rdsbroker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/pivotal-cf/brokerapi/v9/domain/apiresponses"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// The states of a DB instance in a batch minor version upgrade, recorded in
+// its Minor Upgrade tag.
+const (
+	MinorUpgradeScheduled = "scheduled"
+	MinorUpgradeUpgrading = "upgrading"
+	MinorUpgradeUpgraded  = "upgraded"
+	MinorUpgradeFailed    = "failed"
+)
+
+var errUnknownPlan = errors.New("Unknown plan")
+
+// minorUpgradeFailedStatuses are the statuses RDS leaves a DB instance in
+// when an upgrade has gone wrong.
+var minorUpgradeFailedStatuses = []string{"failed", "incompatible-parameters", "incompatible-restore", "storage-full"}
+
+// MinorUpgrade is the progress of a DB instance through a batch minor
+// version upgrade.
+type MinorUpgrade struct {
+	InstanceID                 string `json:"instance_id"`
+	DBInstanceIdentifier       string `json:"db_instance_identifier"`
+	PlanID                     string `json:"plan_id"`
+	DBInstanceStatus           string `json:"db_instance_status"`
+	PreferredMaintenanceWindow string `json:"preferred_maintenance_window"`
+	EngineVersion              string `json:"engine_version"`
+	TargetVersion              string `json:"target_version,omitempty"`
+	State                      string `json:"state"`
+}
+
+func (b *RDSBroker) minorUpgrade(instance managedDBInstance) MinorUpgrade {
+	dbInstanceIdentifier := aws.StringValue(instance.dbInstance.DBInstanceIdentifier)
+	return MinorUpgrade{
+		InstanceID:                 b.dbInstanceIdentifierToServiceInstanceID(dbInstanceIdentifier),
+		DBInstanceIdentifier:       dbInstanceIdentifier,
+		PlanID:                     instance.tagsByName[awsrds.TagPlanID],
+		DBInstanceStatus:           aws.StringValue(instance.dbInstance.DBInstanceStatus),
+		PreferredMaintenanceWindow: aws.StringValue(instance.dbInstance.PreferredMaintenanceWindow),
+		EngineVersion:              aws.StringValue(instance.dbInstance.EngineVersion),
+		TargetVersion:              instance.tagsByName[awsrds.TagMinorUpgradeTarget],
+		State:                      instance.tagsByName[awsrds.TagMinorUpgrade],
+	}
+}
+
+// ScheduleMinorUpgrades schedules every DB instance of a plan to be upgraded
+// to the latest minor version of its engine, as if each had been updated
+// with update_minor_version_to_latest. Housekeeping starts the upgrades in
+// each instance's maintenance window, a few at a time. Instances already
+// being upgraded are left alone.
+func (b *RDSBroker) ScheduleMinorUpgrades(planID string) ([]MinorUpgrade, error) {
+	if _, ok := b.catalog.FindServicePlan(planID); !ok {
+		return nil, errUnknownPlan
+	}
+
+	dbInstances, err := b.listManagedDBInstances()
+	if err != nil {
+		return nil, err
+	}
+
+	upgrades := []MinorUpgrade{}
+	for _, instance := range dbInstances {
+		if instance.tagsByName[awsrds.TagPlanID] != planID || instance.tagsByName[awsrds.TagPurgeAfter] != "" {
+			continue
+		}
+		if state := instance.tagsByName[awsrds.TagMinorUpgrade]; state == MinorUpgradeScheduled || state == MinorUpgradeUpgrading {
+			continue
+		}
+
+		upgrade := b.minorUpgrade(instance)
+		upgrade.State = MinorUpgradeScheduled
+		upgrade.TargetVersion = ""
+		err := b.dbInstance.AddTagsToResource(
+			aws.StringValue(instance.dbInstance.DBInstanceArn),
+			awsrds.BuildRDSTags(map[string]string{awsrds.TagMinorUpgrade: MinorUpgradeScheduled}),
+		)
+		if err != nil {
+			return nil, err
+		}
+		upgrades = append(upgrades, upgrade)
+	}
+
+	b.logger.Info("admin.schedule-minor-upgrades", lager.Data{
+		"planID": planID,
+		"count":  len(upgrades),
+	})
+	return upgrades, nil
+}
+
+// CancelMinorUpgrades unschedules the minor version upgrades of a plan's
+// DB instances which haven't started yet, and returns how many there were.
+func (b *RDSBroker) CancelMinorUpgrades(planID string) (int, error) {
+	dbInstances, err := b.listManagedDBInstances()
+	if err != nil {
+		return 0, err
+	}
+
+	cancelled := 0
+	for _, instance := range dbInstances {
+		if instance.tagsByName[awsrds.TagPlanID] != planID || instance.tagsByName[awsrds.TagMinorUpgrade] != MinorUpgradeScheduled {
+			continue
+		}
+		if err := b.removeTag(aws.StringValue(instance.dbInstance.DBInstanceIdentifier), awsrds.TagMinorUpgrade); err != nil {
+			return cancelled, err
+		}
+		cancelled++
+	}
+
+	b.logger.Info("admin.cancel-minor-upgrades", lager.Data{
+		"planID": planID,
+		"count":  cancelled,
+	})
+	return cancelled, nil
+}
+
+// MinorUpgrades lists the DB instances which have been scheduled for a
+// batch minor version upgrade, with how far each has got.
+func (b *RDSBroker) MinorUpgrades() ([]MinorUpgrade, error) {
+	dbInstances, err := b.listManagedDBInstances()
+	if err != nil {
+		return nil, err
+	}
+
+	upgrades := []MinorUpgrade{}
+	for _, instance := range dbInstances {
+		if instance.tagsByName[awsrds.TagMinorUpgrade] != "" {
+			upgrades = append(upgrades, b.minorUpgrade(instance))
+		}
+	}
+	return upgrades, nil
+}
+
+// runMinorUpgrades is the housekeeping job which moves the batch minor
+// version upgrades along: it records the outcome of the upgrades which have
+// finished, then starts scheduled ones whose maintenance window is open,
+// keeping no more than the configured number running at once.
+func (b *RDSBroker) runMinorUpgrades(dbInstances []managedDBInstance) error {
+	now := time.Now()
+	running := 0
+	scheduled := []managedDBInstance{}
+	for _, instance := range dbInstances {
+		switch instance.tagsByName[awsrds.TagMinorUpgrade] {
+		case MinorUpgradeUpgrading:
+			if state := minorUpgradeOutcome(instance); state != MinorUpgradeUpgrading {
+				b.recordMinorUpgrade(instance, state, instance.tagsByName[awsrds.TagMinorUpgradeTarget])
+				continue
+			}
+			running++
+		case MinorUpgradeScheduled:
+			scheduled = append(scheduled, instance)
+		}
+	}
+
+	sort.Slice(scheduled, func(i, j int) bool {
+		return aws.StringValue(scheduled[i].dbInstance.DBInstanceIdentifier) < aws.StringValue(scheduled[j].dbInstance.DBInstanceIdentifier)
+	})
+	for _, instance := range scheduled {
+		if running >= b.minorUpgradeConcurrency {
+			break
+		}
+		if aws.StringValue(instance.dbInstance.DBInstanceStatus) != "available" {
+			continue
+		}
+		open, err := maintenanceWindowOpen(aws.StringValue(instance.dbInstance.PreferredMaintenanceWindow), now)
+		if err != nil {
+			b.logger.Error("minor-upgrades.parse-maintenance-window", err, lager.Data{dbInstanceLogKey: instance.dbInstance.DBInstanceIdentifier})
+			continue
+		}
+		if !open {
+			continue
+		}
+
+		started, err := b.startMinorUpgrade(instance)
+		if err != nil {
+			b.logger.Error("minor-upgrades.start", err, lager.Data{dbInstanceLogKey: instance.dbInstance.DBInstanceIdentifier})
+			b.recordMinorUpgrade(instance, MinorUpgradeFailed, "")
+			continue
+		}
+		if started {
+			running++
+		}
+	}
+
+	b.logger.Info("minor-upgrades.run", lager.Data{
+		"running":   running,
+		"scheduled": len(scheduled),
+	})
+	return nil
+}
+
+// minorUpgradeOutcome is the state of an upgrade which has been started:
+// upgraded once the instance is available on the target version, failed if
+// RDS has given up on it, and otherwise still upgrading.
+func minorUpgradeOutcome(instance managedDBInstance) string {
+	status := aws.StringValue(instance.dbInstance.DBInstanceStatus)
+	if containsString(minorUpgradeFailedStatuses, status) {
+		return MinorUpgradeFailed
+	}
+	if status == "available" && aws.StringValue(instance.dbInstance.EngineVersion) == instance.tagsByName[awsrds.TagMinorUpgradeTarget] {
+		return MinorUpgradeUpgraded
+	}
+	return MinorUpgradeUpgrading
+}
+
+// startMinorUpgrade upgrades a DB instance to the latest minor version of
+// its engine right away, under the operation lock so that it doesn't race
+// an update from the platform. It reports whether an upgrade was started:
+// instances already on the latest version are recorded as upgraded, and
+// instances with another operation running are left for the next run.
+func (b *RDSBroker) startMinorUpgrade(instance managedDBInstance) (bool, error) {
+	dbInstanceIdentifier := aws.StringValue(instance.dbInstance.DBInstanceIdentifier)
+	release, err := b.acquireOperationLock(b.dbInstanceIdentifierToServiceInstanceID(dbInstanceIdentifier))
+	if err == apiresponses.ErrConcurrentInstanceAccess {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	engineVersion := aws.StringValue(instance.dbInstance.EngineVersion)
+	latestVersion, err := b.dbInstance.GetLatestMinorVersion(aws.StringValue(instance.dbInstance.Engine), engineVersion)
+	if err != nil {
+		return false, err
+	}
+	if latestVersion == nil {
+		b.recordMinorUpgrade(instance, MinorUpgradeUpgraded, engineVersion)
+		return false, nil
+	}
+
+	b.logger.Info("minor-upgrades.start", lager.Data{
+		dbInstanceLogKey: dbInstanceIdentifier,
+		"engineVersion":  engineVersion,
+		"targetVersion":  aws.StringValue(latestVersion),
+	})
+	_, err = b.dbInstance.Modify(&rds.ModifyDBInstanceInput{
+		DBInstanceIdentifier: instance.dbInstance.DBInstanceIdentifier,
+		EngineVersion:        latestVersion,
+		ApplyImmediately:     aws.Bool(true),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	b.recordMinorUpgrade(instance, MinorUpgradeUpgrading, aws.StringValue(latestVersion))
+	return true, nil
+}
+
+func (b *RDSBroker) recordMinorUpgrade(instance managedDBInstance, state string, targetVersion string) {
+	dbInstanceIdentifier := aws.StringValue(instance.dbInstance.DBInstanceIdentifier)
+	if state != MinorUpgradeUpgrading {
+		b.logger.Info("minor-upgrades."+state, lager.Data{
+			dbInstanceLogKey: dbInstanceIdentifier,
+			"targetVersion":  targetVersion,
+		})
+	}
+	b.writeTags(
+		b.dbInstanceIdentifierToServiceInstanceID(dbInstanceIdentifier),
+		aws.StringValue(instance.dbInstance.DBInstanceArn),
+		map[string]string{
+			awsrds.TagMinorUpgrade:       state,
+			awsrds.TagMinorUpgradeTarget: targetVersion,
+		},
+	)
+}
+
+var maintenanceWindowDays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// maintenanceWindowOpen reports whether now falls within a weekly
+// maintenance window, given in RDS's ddd:hh24:mi-ddd:hh24:mi format in UTC.
+func maintenanceWindowOpen(window string, now time.Time) (bool, error) {
+	bounds := strings.Split(window, "-")
+	if len(bounds) != 2 {
+		return false, fmt.Errorf("malformed maintenance window '%s'", window)
+	}
+	start, err := minuteOfWeek(bounds[0])
+	if err != nil {
+		return false, err
+	}
+	end, err := minuteOfWeek(bounds[1])
+	if err != nil {
+		return false, err
+	}
+
+	now = now.UTC()
+	current := (int(now.Weekday())*24+now.Hour())*60 + now.Minute()
+	if start <= end {
+		return start <= current && current < end, nil
+	}
+	// the window wraps around the end of the week
+	return current >= start || current < end, nil
+}
+
+func minuteOfWeek(dayTime string) (int, error) {
+	parts := strings.Split(strings.ToLower(dayTime), ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("malformed maintenance window time '%s'", dayTime)
+	}
+	day := -1
+	for i, name := range maintenanceWindowDays {
+		if parts[0] == name {
+			day = i
+		}
+	}
+	hour, hourErr := strconv.Atoi(parts[1])
+	minute, minuteErr := strconv.Atoi(parts[2])
+	if day < 0 || hourErr != nil || minuteErr != nil || hour > 23 || minute > 59 {
+		return 0, fmt.Errorf("malformed maintenance window time '%s'", dayTime)
+	}
+	return (day*24+hour)*60 + minute, nil
+}
+
+func (b *RDSBroker) handleMinorUpgrades(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		upgrades, err := b.MinorUpgrades()
+		if err != nil {
+			b.logger.Error("admin.minor-upgrades", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(upgrades)
+
+	case http.MethodPost:
+		var request struct {
+			PlanID string `json:"plan_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.PlanID == "" {
+			http.Error(w, "The request body must be a JSON object with a plan_id", http.StatusBadRequest)
+			return
+		}
+		upgrades, err := b.ScheduleMinorUpgrades(request.PlanID)
+		if err == errUnknownPlan {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			b.logger.Error("admin.schedule-minor-upgrades", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(upgrades)
+
+	case http.MethodDelete:
+		planID := r.URL.Query().Get("plan_id")
+		if planID == "" {
+			http.Error(w, "plan_id must be given", http.StatusBadRequest)
+			return
+		}
+		cancelled, err := b.CancelMinorUpgrades(planID)
+		if err != nil {
+			b.logger.Error("admin.cancel-minor-upgrades", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{"cancelled": cancelled})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,195 @@
+package rdsbroker_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+)
+
+// maintenanceWindowAround returns a maintenance window from an hour before
+// to an hour after the given time.
+func maintenanceWindowAround(t time.Time) string {
+	format := func(t time.Time) string {
+		return strings.ToLower(t.UTC().Format("Mon")) + t.UTC().Format(":15:04")
+	}
+	return format(t.Add(-time.Hour)) + "-" + format(t.Add(time.Hour))
+}
+
+var _ = Describe("Minor upgrades", func() {
+	var (
+		rdsInstance *rdsfake.FakeRDSInstance
+		logger      *lagertest.TestLogger
+		rdsBroker   *RDSBroker
+		dbInstances []*rds.DBInstance
+		tags        map[string]map[string]string
+	)
+
+	minorUpgradeTagWrites := func() map[string]map[string]string {
+		writes := map[string]map[string]string{}
+		for i := 0; i < rdsInstance.AddTagsToResourceCallCount(); i++ {
+			arn, rdsTags := rdsInstance.AddTagsToResourceArgsForCall(i)
+			values := awsrds.RDSTagsValues(rdsTags)
+			if _, ok := values[awsrds.TagMinorUpgrade]; ok {
+				writes[arn] = values
+			}
+		}
+		return writes
+	}
+
+	BeforeEach(func() {
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		logger = lagertest.NewTestLogger("minor_upgrades_test")
+		config := testConfig()
+		config.MinorUpgradeConcurrency = 1
+		config.Catalog.Services[0].Plans[1].RDSProperties = config.Catalog.Services[0].Plans[0].RDSProperties
+		rdsBroker = newTestBroker(config, rdsInstance, nil, logger)
+
+		window := maintenanceWindowAround(time.Now())
+		dbInstances = []*rds.DBInstance{}
+		tags = map[string]map[string]string{}
+		for _, id := range []string{"cf-instance-1", "cf-instance-2", "cf-instance-3"} {
+			arn := "arn:aws:rds:rds-region:1234567890:db:" + id
+			dbInstances = append(dbInstances, &rds.DBInstance{
+				DBInstanceIdentifier:       aws.String(id),
+				DBInstanceArn:              aws.String(arn),
+				DBInstanceStatus:           aws.String("available"),
+				Engine:                     aws.String("postgres"),
+				EngineVersion:              aws.String("13.4"),
+				PreferredMaintenanceWindow: aws.String(window),
+			})
+			tags[arn] = map[string]string{
+				"Broker Name": "mybroker",
+				"Plan ID":     "Plan-1",
+			}
+		}
+		tags["arn:aws:rds:rds-region:1234567890:db:cf-instance-3"]["Plan ID"] = "Plan-2"
+
+		rdsInstance.DescribeByTagStub = func(key, value string, opts ...awsrds.DescribeOption) ([]*rds.DBInstance, error) {
+			return dbInstances, nil
+		}
+		rdsInstance.GetResourceTagsStub = func(arn string, opts ...awsrds.DescribeOption) ([]*rds.Tag, error) {
+			return awsrds.BuildRDSTags(tags[arn]), nil
+		}
+		rdsInstance.GetLatestMinorVersionReturns(aws.String("13.7"), nil)
+	})
+
+	It("schedules upgrades of every instance of a plan", func() {
+		recorder := httptest.NewRecorder()
+		rdsBroker.AdminHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/admin/minor-upgrades", strings.NewReader(`{"plan_id": "Plan-1"}`)))
+		Expect(recorder.Code).To(Equal(http.StatusAccepted))
+
+		var upgrades []MinorUpgrade
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &upgrades)).To(Succeed())
+		Expect(upgrades).To(HaveLen(2))
+		Expect(upgrades[0].InstanceID).To(Equal("instance-1"))
+		Expect(upgrades[0].State).To(Equal(MinorUpgradeScheduled))
+
+		writes := minorUpgradeTagWrites()
+		Expect(writes).To(HaveLen(2))
+		Expect(writes).To(HaveKeyWithValue("arn:aws:rds:rds-region:1234567890:db:cf-instance-1", HaveKeyWithValue(awsrds.TagMinorUpgrade, MinorUpgradeScheduled)))
+		Expect(writes).ToNot(HaveKey("arn:aws:rds:rds-region:1234567890:db:cf-instance-3"))
+	})
+
+	It("refuses to schedule upgrades of an unknown plan", func() {
+		recorder := httptest.NewRecorder()
+		rdsBroker.AdminHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/admin/minor-upgrades", strings.NewReader(`{"plan_id": "Plan-3"}`)))
+		Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+		Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(0))
+	})
+
+	It("cancels upgrades which haven't started", func() {
+		tags["arn:aws:rds:rds-region:1234567890:db:cf-instance-1"][awsrds.TagMinorUpgrade] = MinorUpgradeScheduled
+		tags["arn:aws:rds:rds-region:1234567890:db:cf-instance-2"][awsrds.TagMinorUpgrade] = MinorUpgradeUpgrading
+
+		recorder := httptest.NewRecorder()
+		rdsBroker.AdminHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/admin/minor-upgrades?plan_id=Plan-1", nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(MatchJSON(`{"cancelled": 1}`))
+
+		Expect(rdsInstance.RemoveTagCallCount()).To(Equal(1))
+		id, key := rdsInstance.RemoveTagArgsForCall(0)
+		Expect(id).To(Equal("cf-instance-1"))
+		Expect(key).To(Equal(awsrds.TagMinorUpgrade))
+	})
+
+	Describe("running the upgrades", func() {
+		BeforeEach(func() {
+			for _, instanceTags := range tags {
+				instanceTags[awsrds.TagMinorUpgrade] = MinorUpgradeScheduled
+			}
+		})
+
+		It("starts no more upgrades than the concurrency allows", func() {
+			Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+			Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
+			input := rdsInstance.ModifyArgsForCall(0)
+			Expect(input).To(Equal(&rds.ModifyDBInstanceInput{
+				DBInstanceIdentifier: aws.String("cf-instance-1"),
+				EngineVersion:        aws.String("13.7"),
+				ApplyImmediately:     aws.Bool(true),
+			}))
+
+			Expect(minorUpgradeTagWrites()).To(Equal(map[string]map[string]string{
+				"arn:aws:rds:rds-region:1234567890:db:cf-instance-1": {
+					awsrds.TagMinorUpgrade:       MinorUpgradeUpgrading,
+					awsrds.TagMinorUpgradeTarget: "13.7",
+				},
+			}))
+		})
+
+		It("waits for the maintenance window of each instance", func() {
+			for _, dbInstance := range dbInstances {
+				dbInstance.PreferredMaintenanceWindow = aws.String(maintenanceWindowAround(time.Now().Add(72 * time.Hour)))
+			}
+
+			Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+			Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+		})
+
+		It("records the outcome of upgrades which have finished and starts the next", func() {
+			tags["arn:aws:rds:rds-region:1234567890:db:cf-instance-1"][awsrds.TagMinorUpgrade] = MinorUpgradeUpgrading
+			tags["arn:aws:rds:rds-region:1234567890:db:cf-instance-1"][awsrds.TagMinorUpgradeTarget] = "13.7"
+			dbInstances[0].EngineVersion = aws.String("13.7")
+			tags["arn:aws:rds:rds-region:1234567890:db:cf-instance-2"][awsrds.TagMinorUpgrade] = MinorUpgradeUpgrading
+			tags["arn:aws:rds:rds-region:1234567890:db:cf-instance-2"][awsrds.TagMinorUpgradeTarget] = "13.7"
+			dbInstances[1].DBInstanceStatus = aws.String("incompatible-parameters")
+
+			Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+			writes := minorUpgradeTagWrites()
+			Expect(writes).To(HaveKeyWithValue("arn:aws:rds:rds-region:1234567890:db:cf-instance-1", HaveKeyWithValue(awsrds.TagMinorUpgrade, MinorUpgradeUpgraded)))
+			Expect(writes).To(HaveKeyWithValue("arn:aws:rds:rds-region:1234567890:db:cf-instance-2", HaveKeyWithValue(awsrds.TagMinorUpgrade, MinorUpgradeFailed)))
+			Expect(writes).To(HaveKeyWithValue("arn:aws:rds:rds-region:1234567890:db:cf-instance-3", HaveKeyWithValue(awsrds.TagMinorUpgrade, MinorUpgradeUpgrading)))
+		})
+
+		It("leaves instances still being upgraded to count towards the concurrency", func() {
+			tags["arn:aws:rds:rds-region:1234567890:db:cf-instance-1"][awsrds.TagMinorUpgrade] = MinorUpgradeUpgrading
+			tags["arn:aws:rds:rds-region:1234567890:db:cf-instance-1"][awsrds.TagMinorUpgradeTarget] = "13.7"
+			dbInstances[0].DBInstanceStatus = aws.String("upgrading")
+
+			Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+			Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+		})
+
+		It("marks instances already on the latest minor version as upgraded", func() {
+			rdsInstance.GetLatestMinorVersionReturns(nil, nil)
+
+			Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+			Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+			Expect(minorUpgradeTagWrites()).To(HaveLen(3))
+		})
+	})
+})
@@ -0,0 +1,79 @@
+package rdsbroker
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/pivotal-cf/brokerapi/v9/domain/apiresponses"
+)
+
+// modifyingDBInstanceStatuses are the statuses of a DB instance while a
+// change to it is still being applied, during which another modification
+// would be stacked on top of it.
+var modifyingDBInstanceStatuses = []string{
+	"modifying",
+	"upgrading",
+	"rebooting",
+	"renaming",
+	"resetting-master-credentials",
+	"configuring-enhanced-monitoring",
+	"configuring-iam-database-auth",
+	"configuring-log-exports",
+	"converting-to-vpc",
+	"moving-to-vpc",
+	"maintenance",
+}
+
+// pendingModifications lists the names of the settings of a DB instance
+// which have been modified but not yet applied, leaving out their values as
+// they may include the master password.
+func pendingModifications(dbInstance *rds.DBInstance) []string {
+	modifications := []string{}
+	if dbInstance.PendingModifiedValues == nil {
+		return modifications
+	}
+
+	values := reflect.ValueOf(*dbInstance.PendingModifiedValues)
+	for i := 0; i < values.NumField(); i++ {
+		field := values.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		switch value := values.Field(i); value.Kind() {
+		case reflect.Ptr, reflect.Slice:
+			if !value.IsNil() {
+				modifications = append(modifications, field.Name)
+			}
+		}
+	}
+	return modifications
+}
+
+// checkNoModificationInProgress refuses to modify a DB instance while an
+// earlier change to it is still being applied, or is pending, as the
+// outcome of both together can't be predicted.
+func (b *RDSBroker) checkNoModificationInProgress(instanceID string, dbInstance *rds.DBInstance) error {
+	status := aws.StringValue(dbInstance.DBInstanceStatus)
+	modifying := containsString(modifyingDBInstanceStatuses, status)
+	modifications := pendingModifications(dbInstance)
+	if !modifying && len(modifications) == 0 {
+		return nil
+	}
+
+	details := []string{}
+	if modifying {
+		details = append(details, fmt.Sprintf("it is %s", status))
+	}
+	if len(modifications) > 0 {
+		details = append(details, fmt.Sprintf("it has pending changes to %s", strings.Join(modifications, ", ")))
+	}
+	err := fmt.Errorf(
+		"DB Instance '%s' is still applying an earlier modification: %s. Try the update again once it is available with no pending changes",
+		b.dbInstanceIdentifier(instanceID), strings.Join(details, " and "),
+	)
+	return apiresponses.NewFailureResponseBuilder(err, http.StatusConflict, "modification-in-progress").WithErrorKey("ConcurrencyError").Build()
+}
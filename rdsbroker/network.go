@@ -0,0 +1,45 @@
+package rdsbroker
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// Network describes where a DB instance sits in its VPC, so that consumers
+// outside the platform can write firewall rules for it. DescribeDBInstances
+// only returns the IDs of the subnets, so their CIDR blocks come from the
+// broker's subnet_cidr_blocks.
+type Network struct {
+	VpcID            string   `json:"vpc_id"`
+	SubnetIDs        []string `json:"subnet_ids"`
+	SubnetCIDRBlocks []string `json:"subnet_cidr_blocks"`
+	SecurityGroupIDs []string `json:"security_group_ids"`
+}
+
+// networkFromDBInstance returns the network of the DB instance, or nil if
+// RDS didn't describe its subnet group. Subnets missing from the broker's
+// subnet_cidr_blocks are left out of the CIDR blocks.
+func (b *RDSBroker) networkFromDBInstance(dbInstance *rds.DBInstance) *Network {
+	if dbInstance.DBSubnetGroup == nil {
+		return nil
+	}
+
+	network := &Network{
+		VpcID:            aws.StringValue(dbInstance.DBSubnetGroup.VpcId),
+		SubnetIDs:        []string{},
+		SubnetCIDRBlocks: []string{},
+		SecurityGroupIDs: []string{},
+	}
+	for _, subnet := range dbInstance.DBSubnetGroup.Subnets {
+		subnetID := aws.StringValue(subnet.SubnetIdentifier)
+		network.SubnetIDs = append(network.SubnetIDs, subnetID)
+		if cidrBlock, ok := b.subnetCIDRBlocks[subnetID]; ok {
+			network.SubnetCIDRBlocks = append(network.SubnetCIDRBlocks, cidrBlock)
+		}
+	}
+	for _, securityGroup := range dbInstance.VpcSecurityGroups {
+		network.SecurityGroupIDs = append(network.SecurityGroupIDs, aws.StringValue(securityGroup.VpcSecurityGroupId))
+	}
+
+	return network
+}
@@ -0,0 +1,70 @@
+package rdsbroker
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// NetworkTier is a dedicated DB subnet group and set of security groups
+// which DB instances can be provisioned into instead of those of their plan,
+// to isolate some organizations' databases from the rest.
+type NetworkTier struct {
+	DBSubnetGroupName   string   `json:"db_subnet_group_name"`
+	VpcSecurityGroupIds []string `json:"vpc_security_group_ids"`
+}
+
+func (t NetworkTier) Validate() error {
+	if t.DBSubnetGroupName == "" {
+		return errors.New("Must provide a non-empty db_subnet_group_name")
+	}
+	if len(t.VpcSecurityGroupIds) == 0 {
+		return errors.New("Must provide at least one of vpc_security_group_ids")
+	}
+	return nil
+}
+
+// networkTierForProvision picks the network tier of a new DB instance. An
+// organization mapped to a tier always gets that one, so that its users
+// can't ask their way out of it, while other organizations can ask for any
+// tier with the network_tier parameter, except those which organizations
+// are mapped to, as those tiers isolate their organizations' databases. No
+// tier means the plan's network.
+func (b *RDSBroker) networkTierForProvision(organizationGUID string, requested *string) (string, error) {
+	if tierName, ok := b.organizationNetworkTiers[organizationGUID]; ok {
+		if requested != nil && *requested != tierName {
+			return "", newUserError(ErrCodeNetworkTierDenied, "Instances in this organization must use network tier '%s'", tierName)
+		}
+		return tierName, nil
+	}
+	if requested == nil {
+		return "", nil
+	}
+	if _, ok := b.networkTiers[*requested]; !ok {
+		return "", newUserError(ErrCodeNetworkTierDenied, "Unknown network tier '%s'", *requested)
+	}
+	for _, tierName := range b.organizationNetworkTiers {
+		if tierName == *requested {
+			return "", newUserError(ErrCodeNetworkTierDenied, "Network tier '%s' is reserved for other organizations", *requested)
+		}
+	}
+	return *requested, nil
+}
+
+// networkTierSubnetGroup returns the DB subnet group of the named tier, or
+// the plan's if the tier is unknown or empty.
+func (b *RDSBroker) networkTierSubnetGroup(servicePlan ServicePlan, tierName string) *string {
+	if tier, ok := b.networkTiers[tierName]; ok && tierName != "" {
+		return aws.String(tier.DBSubnetGroupName)
+	}
+	return servicePlan.RDSProperties.DBSubnetGroupName
+}
+
+// networkTierSecurityGroups returns the security groups of the named tier, or
+// the plan's if the tier is unknown or empty.
+func (b *RDSBroker) networkTierSecurityGroups(servicePlan ServicePlan, tierName string) []*string {
+	if tier, ok := b.networkTiers[tierName]; ok && tierName != "" {
+		return aws.StringSlice(tier.VpcSecurityGroupIds)
+	}
+	return servicePlan.RDSProperties.VpcSecurityGroupIds
+}
@@ -0,0 +1,153 @@
+package rdsbroker
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pivotal-cf/brokerapi/v9/domain/apiresponses"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+const operationLeaseSeparator = " until "
+
+// instanceLocks makes sure only one mutating operation runs against a
+// service instance at a time within this broker process.
+type instanceLocks struct {
+	lock sync.Mutex
+	held map[string]bool
+}
+
+func (l *instanceLocks) tryLock(instanceID string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.held == nil {
+		l.held = map[string]bool{}
+	}
+	if l.held[instanceID] {
+		return false
+	}
+	l.held[instanceID] = true
+	return true
+}
+
+func (l *instanceLocks) isLocked(instanceID string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.held[instanceID]
+}
+
+func (l *instanceLocks) unlock(instanceID string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	delete(l.held, instanceID)
+}
+
+// acquireOperationLock claims the service instance for a mutating operation,
+// returning apiresponses.ErrConcurrentInstanceAccess if another operation is
+// already running against it. The returned function must be called once the
+// operation is done.
+//
+// If an operation lease is configured the claim is also recorded, so that
+// other broker processes see it too: in the state store when there is one,
+// which takes the lease atomically, and otherwise in a tag on the DB
+// instance. Tags can't be updated atomically, so this narrows rather than
+// closes the window in which two processes can both believe they hold the
+// lease.
+func (b *RDSBroker) acquireOperationLock(instanceID string) (func(), error) {
+	if !b.instanceLocks.tryLock(instanceID) {
+		return nil, apiresponses.ErrConcurrentInstanceAccess
+	}
+	release := func() { b.instanceLocks.unlock(instanceID) }
+
+	if b.operationLeaseDuration == 0 {
+		return release, nil
+	}
+
+	if b.stateStore != nil {
+		acquired, err := b.stateStore.AcquireOperationLease(instanceID, b.brokerID, time.Now().Add(b.operationLeaseDuration))
+		if err != nil {
+			release()
+			return nil, err
+		}
+		if !acquired {
+			release()
+			return nil, apiresponses.ErrConcurrentInstanceAccess
+		}
+		return func() {
+			if err := b.stateStore.ReleaseOperationLease(instanceID, b.brokerID); err != nil {
+				b.logger.Error("release-operation-lease", err, lager.Data{instanceIDLogKey: instanceID})
+			}
+			release()
+		}, nil
+	}
+
+	dbInstanceIdentifier := b.dbInstanceIdentifier(instanceID)
+	dbInstance, err := b.dbInstance.Describe(dbInstanceIdentifier)
+	if err != nil {
+		if err == awsrds.ErrDBInstanceDoesNotExist {
+			// nothing to hold a lease on
+			return release, nil
+		}
+		release()
+		return nil, err
+	}
+
+	tags, err := b.dbInstance.GetResourceTags(aws.StringValue(dbInstance.DBInstanceArn))
+	if err != nil {
+		release()
+		return nil, err
+	}
+	if b.leaseHeldByOther(awsrds.RDSTagsValues(tags)) {
+		release()
+		return nil, apiresponses.ErrConcurrentInstanceAccess
+	}
+
+	lease := b.brokerID + operationLeaseSeparator + time.Now().Add(b.operationLeaseDuration).UTC().Format(time.RFC3339)
+	err = b.dbInstance.AddTagsToResource(
+		aws.StringValue(dbInstance.DBInstanceArn),
+		awsrds.BuildRDSTags(map[string]string{awsrds.TagOperationLease: lease}),
+	)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	return func() {
+		if err := b.removeTag(dbInstanceIdentifier, awsrds.TagOperationLease); err != nil {
+			b.logger.Error("release-operation-lease", err, lager.Data{instanceIDLogKey: instanceID})
+		}
+		release()
+	}, nil
+}
+
+// leaseHeldByOther reports whether the instance's tags show an unexpired
+// operation lease taken by another broker process.
+func (b *RDSBroker) leaseHeldByOther(tagsByName map[string]string) bool {
+	lease, ok := tagsByName[awsrds.TagOperationLease]
+	if !ok {
+		return false
+	}
+
+	parts := strings.SplitN(lease, operationLeaseSeparator, 2)
+	if len(parts) != 2 {
+		b.logger.Error("parse-operation-lease", fmt.Errorf("malformed lease '%s'", lease))
+		return false
+	}
+	if parts[0] == b.brokerID {
+		return false
+	}
+	expiry, err := time.Parse(time.RFC3339, parts[1])
+	if err != nil {
+		b.logger.Error("parse-operation-lease", err)
+		return false
+	}
+	return time.Now().Before(expiry)
+}
@@ -0,0 +1,69 @@
+package rdsbroker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/pivotal-cf/brokerapi/v9/middlewares"
+)
+
+// OriginatingIdentity is the platform user on whose behalf a request was
+// made, as sent in the X-Broker-API-Originating-Identity header.
+type OriginatingIdentity struct {
+	Platform string
+	UserID   string
+}
+
+// originatingIdentityFromContext decodes the originating identity header
+// which brokerapi stores in the request context. The header value is the
+// platform name followed by base64 encoded JSON; Cloud Foundry puts the user
+// GUID in its "user_id" property.
+func originatingIdentityFromContext(ctx context.Context) (OriginatingIdentity, bool) {
+	if ctx == nil {
+		return OriginatingIdentity{}, false
+	}
+	header, _ := ctx.Value(middlewares.OriginatingIdentityKey).(string)
+	parts := strings.SplitN(strings.TrimSpace(header), " ", 2)
+	if len(parts) != 2 {
+		return OriginatingIdentity{}, false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return OriginatingIdentity{}, false
+	}
+	var properties struct {
+		UserID string `json:"user_id"`
+	}
+	if err := json.Unmarshal(decoded, &properties); err != nil || properties.UserID == "" {
+		return OriginatingIdentity{}, false
+	}
+
+	return OriginatingIdentity{Platform: parts[0], UserID: properties.UserID}, true
+}
+
+// auditLog records that operation was requested against instanceID, and by
+// whom if the platform told us.
+func (b *RDSBroker) auditLog(ctx context.Context, operation string, instanceID string) {
+	b.auditLogWithData(ctx, operation, instanceID, nil)
+}
+
+// auditLogWithData is auditLog with details of the operation, such as why
+// the broker made it.
+func (b *RDSBroker) auditLogWithData(ctx context.Context, operation string, instanceID string, details lager.Data) {
+	data := lager.Data{
+		instanceIDLogKey: instanceID,
+		"operation":      operation,
+	}
+	for key, value := range details {
+		data[key] = value
+	}
+	if identity, ok := originatingIdentityFromContext(ctx); ok {
+		data["platform"] = identity.Platform
+		data["user-id"] = identity.UserID
+	}
+	b.logger.Info("audit", data)
+}
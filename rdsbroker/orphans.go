@@ -0,0 +1,31 @@
+package rdsbroker
+
+import (
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// deleteOrphanedInstances deletes the DB instances which
+// cleanUpOrphanedDBInstance couldn't, because they were still being created
+// or the delete failed.
+func (b *RDSBroker) deleteOrphanedInstances(dbInstances []managedDBInstance) error {
+	for _, instance := range dbInstances {
+		if instance.tagsByName[awsrds.TagOrphaned] == "" {
+			continue
+		}
+		status := aws.StringValue(instance.dbInstance.DBInstanceStatus)
+		if status == "creating" || status == "deleting" {
+			continue
+		}
+
+		dbInstanceIdentifier := aws.StringValue(instance.dbInstance.DBInstanceIdentifier)
+		b.logger.Info("delete-orphaned-instance", lager.Data{dbInstanceLogKey: dbInstanceIdentifier})
+		if err := b.dbInstance.Delete(dbInstanceIdentifier, true); err != nil {
+			b.logger.Error("delete-orphaned-instance", err, lager.Data{dbInstanceLogKey: dbInstanceIdentifier})
+		}
+	}
+
+	return nil
+}
@@ -2,9 +2,12 @@ package rdsbroker
 
 import (
 	"fmt"
+	"io"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"code.cloudfoundry.org/lager/v3"
 	"github.com/alphagov/paas-rds-broker/awsrds"
@@ -14,7 +17,8 @@ import (
 
 //go:generate counterfeiter -o fakes/fake_parameter_group_selector.go . ParameterGroupSelector
 type ParameterGroupSelector interface {
-	SelectParameterGroup(servicePlan ServicePlan, extensions []string) (string, error)
+	SelectParameterGroup(servicePlan ServicePlan, extensions []string, pgauditLog []string) (string, error)
+	ParameterGroupName(servicePlan ServicePlan, extensions []string, pgauditLog []string) (string, error)
 }
 
 type ParameterGroupSource struct {
@@ -22,21 +26,47 @@ type ParameterGroupSource struct {
 	rdsInstance                awsrds.RDSInstance
 	logger                     lager.Logger
 	supportedPreloadExtensions map[string][]DBExtension
+
+	engineFamiliesMutex sync.Mutex
+	engineFamilies      map[string]string
+
+	driftMutex sync.RWMutex
+	drift      *parameterGroupDrift
+}
+
+// parameterGroupDrift is what the latest drift correction found.
+type parameterGroupDrift struct {
+	checkedAt         time.Time
+	checkedGroups     int
+	driftedParameters int
+	failedGroups      int
 }
 
 func NewParameterGroupSource(config Config, rdsInstance awsrds.RDSInstance, supportedPreloadExtensions map[string][]DBExtension, logger lager.Logger) *ParameterGroupSource {
-	return &ParameterGroupSource{config, rdsInstance, logger, supportedPreloadExtensions}
+	return &ParameterGroupSource{
+		config:                     config,
+		rdsInstance:                rdsInstance,
+		logger:                     logger,
+		supportedPreloadExtensions: supportedPreloadExtensions,
+		engineFamilies:             map[string]string{},
+	}
 }
 
-func (pgs *ParameterGroupSource) SelectParameterGroup(servicePlan ServicePlan, extensions []string) (string, error) {
+func (pgs *ParameterGroupSource) SelectParameterGroup(servicePlan ServicePlan, extensions []string, pgauditLog []string) (string, error) {
 	pgs.logger.Debug("selecting a parameter group", lager.Data{
 		servicePlanLogKey: servicePlan,
 		extensionsLogKey:  extensions,
+		"pgauditLog":      pgauditLog,
 	})
 
-	groupName := composeGroupName(pgs.config, servicePlan, extensions, pgs.supportedPreloadExtensions)
+	servicePlan, err := pgs.withEngineFamily(servicePlan)
+	if err != nil {
+		return "", err
+	}
+
+	groupName := composeGroupName(pgs.config, servicePlan, extensions, pgauditLog, pgs.supportedPreloadExtensions)
 	pgs.logger.Info(fmt.Sprintf("database should be created with parameter group '%s'", groupName))
-	_, err := pgs.rdsInstance.GetParameterGroup(groupName)
+	_, err = pgs.rdsInstance.GetParameterGroup(groupName)
 
 	if err != nil {
 		if !isParameterGroupNotFoundError(err) {
@@ -47,7 +77,7 @@ func (pgs *ParameterGroupSource) SelectParameterGroup(servicePlan ServicePlan, e
 				return "", err
 			}
 
-			err = pgs.setParameterGroupProperties(groupName, servicePlan, extensions)
+			err = pgs.setParameterGroupProperties(groupName, servicePlan, extensions, pgauditLog)
 			if err != nil {
 				return "", err
 			}
@@ -60,6 +90,46 @@ func (pgs *ParameterGroupSource) SelectParameterGroup(servicePlan ServicePlan, e
 	return groupName, nil
 }
 
+// ParameterGroupName returns the name of the parameter group which
+// SelectParameterGroup would select, without creating it.
+func (pgs *ParameterGroupSource) ParameterGroupName(servicePlan ServicePlan, extensions []string, pgauditLog []string) (string, error) {
+	servicePlan, err := pgs.withEngineFamily(servicePlan)
+	if err != nil {
+		return "", err
+	}
+
+	return composeGroupName(pgs.config, servicePlan, extensions, pgauditLog, pgs.supportedPreloadExtensions), nil
+}
+
+// withEngineFamily fills in the plan's engine family when the catalog leaves
+// it out, using the family RDS reports for the plan's engine version. This
+// means plans for a new major version don't need a family configuring.
+func (pgs *ParameterGroupSource) withEngineFamily(servicePlan ServicePlan) (ServicePlan, error) {
+	if aws.StringValue(servicePlan.RDSProperties.EngineFamily) != "" {
+		return servicePlan, nil
+	}
+
+	engine := aws.StringValue(servicePlan.RDSProperties.Engine)
+	engineVersion := aws.StringValue(servicePlan.RDSProperties.EngineVersion)
+	key := engine + "/" + engineVersion
+
+	pgs.engineFamiliesMutex.Lock()
+	defer pgs.engineFamiliesMutex.Unlock()
+
+	family, ok := pgs.engineFamilies[key]
+	if !ok {
+		var err error
+		family, err = pgs.rdsInstance.GetParameterGroupFamily(engine, engineVersion)
+		if err != nil {
+			return servicePlan, err
+		}
+		pgs.engineFamilies[key] = family
+	}
+
+	servicePlan.RDSProperties.EngineFamily = aws.String(family)
+	return servicePlan, nil
+}
+
 func (pgs *ParameterGroupSource) createParameterGroup(name string, servicePlan ServicePlan) error {
 	pgs.logger.Debug("creating a parameter group", lager.Data{
 		"groupName": name,
@@ -72,17 +142,36 @@ func (pgs *ParameterGroupSource) createParameterGroup(name string, servicePlan S
 	})
 }
 
-func (pgs *ParameterGroupSource) setParameterGroupProperties(name string, servicePlan ServicePlan, extensions []string) error {
-	if aws.StringValue(servicePlan.RDSProperties.Engine) == "postgres" {
-		return pgs.setPostgresParameterGroupProperties(name, servicePlan, extensions)
-	} else if aws.StringValue(servicePlan.RDSProperties.Engine) == "mysql" {
-		return pgs.setMySQLParameterGroupProperties(name)
+func (pgs *ParameterGroupSource) setParameterGroupProperties(name string, servicePlan ServicePlan, extensions []string, pgauditLog []string) error {
+	dbParams := pgs.parameterGroupProperties(servicePlan, extensions, pgauditLog)
+	if len(dbParams) == 0 {
+		return nil
 	}
 
-	return nil
+	pgs.logger.Debug("modifying a parameter group", lager.Data{
+		"groupName":  name,
+		"parameters": dbParams,
+	})
+
+	return pgs.rdsInstance.ModifyParameterGroup(&rds.ModifyDBParameterGroupInput{
+		DBParameterGroupName: aws.String(name),
+		Parameters:           dbParams,
+	})
+}
+
+// parameterGroupProperties is the set of parameters the broker sets on the
+// parameter group for a plan, extensions and pgaudit log classes.
+func (pgs *ParameterGroupSource) parameterGroupProperties(servicePlan ServicePlan, extensions []string, pgauditLog []string) []*rds.Parameter {
+	switch aws.StringValue(servicePlan.RDSProperties.Engine) {
+	case "postgres":
+		return pgs.postgresParameterGroupProperties(servicePlan, extensions, pgauditLog)
+	case "mysql":
+		return mySQLParameterGroupProperties()
+	}
+	return []*rds.Parameter{}
 }
 
-func (pgs *ParameterGroupSource) setPostgresParameterGroupProperties(name string, servicePlan ServicePlan, extensions []string) error {
+func (pgs *ParameterGroupSource) postgresParameterGroupProperties(servicePlan ServicePlan, extensions []string, pgauditLog []string) []*rds.Parameter {
 	dbParams := []*rds.Parameter{}
 	dbParams = append(dbParams, rdsParameter("rds.force_ssl", "1", "pending-reboot"))
 	dbParams = append(dbParams, rdsParameter("rds.log_retention_period", "10080", "immediate"))
@@ -94,35 +183,193 @@ func (pgs *ParameterGroupSource) setPostgresParameterGroupProperties(name string
 		dbParams = append(dbParams, rdsParameter("shared_preload_libraries", libsCSV, "pending-reboot"))
 	}
 
-	pgs.logger.Debug("modifying a parameter group", lager.Data{
-		"groupName":  name,
-		"parameters": dbParams,
-	})
+	if auditsStatements(preloadLibs, pgauditLog) {
+		dbParams = append(dbParams, rdsParameter("pgaudit.log", strings.Join(pgauditLog, ","), "immediate"))
+	}
 
-	return pgs.rdsInstance.ModifyParameterGroup(&rds.ModifyDBParameterGroupInput{
-		DBParameterGroupName: aws.String(name),
-		Parameters:           dbParams,
-	})
+	return dbParams
 }
 
-func (pgs *ParameterGroupSource) setMySQLParameterGroupProperties(name string) error {
+func mySQLParameterGroupProperties() []*rds.Parameter {
 	maxAllowedPacketBytes := 1024 * 1024 * 256
-	dbParams := []*rds.Parameter{
+	return []*rds.Parameter{
 		rdsParameter("max_allowed_packet", strconv.Itoa(maxAllowedPacketBytes), rds.ApplyMethodImmediate),
+		// runs the events which drop the users of bindings made with
+		// ttl_hours when they expire
+		rdsParameter("event_scheduler", "ON", rds.ApplyMethodImmediate),
+	}
+}
+
+// CorrectParameterGroupDrift checks the parameter groups used by the DB
+// instances of this broker against the parameters the broker would set on
+// them, and puts back any which have been changed or removed since the group
+// was created. It returns how many parameters had drifted. A group which
+// can't be checked is logged and skipped, and counted in the drift metrics.
+//
+// Only groups whose name matches what the instance's plan, extensions and
+// pgaudit log classes would select are checked, as the parameters of any
+// other group can't be known.
+func (pgs *ParameterGroupSource) CorrectParameterGroupDrift() (int, error) {
+	dbInstances, err := pgs.rdsInstance.DescribeByTag(awsrds.TagBrokerName, pgs.config.BrokerName)
+	if err != nil {
+		return 0, err
+	}
+
+	checkedGroups := map[string]bool{}
+	driftedParameters := 0
+	failedGroups := 0
+	for _, dbInstance := range dbInstances {
+		if len(dbInstance.DBParameterGroups) == 0 {
+			continue
+		}
+		groupName := aws.StringValue(dbInstance.DBParameterGroups[0].DBParameterGroupName)
+		if checkedGroups[groupName] {
+			continue
+		}
+		logData := lager.Data{"groupName": groupName, dbInstanceLogKey: dbInstance.DBInstanceIdentifier}
+
+		tags, err := pgs.rdsInstance.GetResourceTags(aws.StringValue(dbInstance.DBInstanceArn))
+		if err != nil {
+			pgs.logger.Error("parameter-group-drift.get-tags", err, logData)
+			failedGroups++
+			continue
+		}
+		tagsByName := awsrds.RDSTagsValues(tags)
+
+		servicePlan, ok := pgs.config.Catalog.FindServicePlan(tagsByName[awsrds.TagPlanID])
+		if !ok {
+			continue
+		}
+		servicePlan, err = pgs.withEngineFamily(servicePlan)
+		if err != nil {
+			pgs.logger.Error("parameter-group-drift.engine-family", err, logData)
+			failedGroups++
+			continue
+		}
+		extensions := unpackExtensions(tagsByName[awsrds.TagExtensions])
+		var pgauditLog []string
+		if pgauditLogTag := tagsByName[awsrds.TagPgauditLog]; pgauditLogTag != "" {
+			pgauditLog = strings.Split(pgauditLogTag, ":")
+		}
+		if composeGroupName(pgs.config, servicePlan, extensions, pgauditLog, pgs.supportedPreloadExtensions) != groupName {
+			continue
+		}
+		checkedGroups[groupName] = true
+
+		drifted, err := pgs.correctGroupDrift(groupName, pgs.parameterGroupProperties(servicePlan, extensions, pgauditLog))
+		driftedParameters += drifted
+		if err != nil {
+			pgs.logger.Error("parameter-group-drift.correct", err, logData)
+			failedGroups++
+		}
+	}
+
+	pgs.logger.Info("parameter-group-drift.checked", lager.Data{
+		"checkedGroups":     len(checkedGroups),
+		"driftedParameters": driftedParameters,
+		"failedGroups":      failedGroups,
+	})
+
+	pgs.driftMutex.Lock()
+	pgs.drift = &parameterGroupDrift{
+		checkedAt:         time.Now(),
+		checkedGroups:     len(checkedGroups),
+		driftedParameters: driftedParameters,
+		failedGroups:      failedGroups,
+	}
+	pgs.driftMutex.Unlock()
+
+	return driftedParameters, nil
+}
+
+// writeDriftMetrics writes what the latest drift correction found in the
+// Prometheus text exposition format, once there has been one.
+func (pgs *ParameterGroupSource) writeDriftMetrics(w io.Writer) {
+	pgs.driftMutex.RLock()
+	defer pgs.driftMutex.RUnlock()
+	if pgs.drift == nil {
+		return
+	}
+
+	metrics := []struct {
+		name  string
+		help  string
+		value int64
+	}{
+		{
+			name:  "rds_broker_parameter_group_drift_checked_timestamp_seconds",
+			help:  "When the broker's parameter groups were last checked for drift.",
+			value: pgs.drift.checkedAt.Unix(),
+		},
+		{
+			name:  "rds_broker_parameter_group_drift_checked_groups",
+			help:  "Parameter groups checked for drift by the latest check.",
+			value: int64(pgs.drift.checkedGroups),
+		},
+		{
+			name:  "rds_broker_parameter_group_drifted_parameters",
+			help:  "Parameters found changed from what the broker sets by the latest check, and put back.",
+			value: int64(pgs.drift.driftedParameters),
+		},
+		{
+			name:  "rds_broker_parameter_group_drift_check_failures",
+			help:  "Parameter groups which the latest check failed to check or correct.",
+			value: int64(pgs.drift.failedGroups),
+		},
+	}
+	for _, metric := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n", metric.name, metric.help)
+		fmt.Fprintf(w, "# TYPE %s gauge\n", metric.name)
+		fmt.Fprintf(w, "%s %d\n", metric.name, metric.value)
+	}
+}
+
+func (pgs *ParameterGroupSource) correctGroupDrift(groupName string, desiredParams []*rds.Parameter) (int, error) {
+	if len(desiredParams) == 0 {
+		return 0, nil
+	}
+
+	currentParams, err := pgs.rdsInstance.DescribeParameters(groupName)
+	if err != nil {
+		return 0, err
+	}
+	currentValues := map[string]*string{}
+	for _, param := range currentParams {
+		currentValues[aws.StringValue(param.ParameterName)] = param.ParameterValue
+	}
+
+	corrections := []*rds.Parameter{}
+	for _, param := range desiredParams {
+		name := aws.StringValue(param.ParameterName)
+		currentValue := currentValues[name]
+		if currentValue != nil && *currentValue == aws.StringValue(param.ParameterValue) {
+			continue
+		}
+		pgs.logger.Info("parameter-group-drift.found", lager.Data{
+			"groupName":     groupName,
+			"parameter":     name,
+			"expectedValue": aws.StringValue(param.ParameterValue),
+			"actualValue":   aws.StringValue(currentValue),
+		})
+		corrections = append(corrections, param)
+	}
+
+	if len(corrections) == 0 {
+		return 0, nil
 	}
 
 	pgs.logger.Debug("modifying a parameter group", lager.Data{
-		"groupName":  name,
-		"parameters": dbParams,
+		"groupName":  groupName,
+		"parameters": corrections,
 	})
 
-	return pgs.rdsInstance.ModifyParameterGroup(&rds.ModifyDBParameterGroupInput{
-		DBParameterGroupName: aws.String(name),
-		Parameters:           dbParams,
+	return len(corrections), pgs.rdsInstance.ModifyParameterGroup(&rds.ModifyDBParameterGroupInput{
+		DBParameterGroupName: aws.String(groupName),
+		Parameters:           corrections,
 	})
 }
 
-func composeGroupName(config Config, servicePlan ServicePlan, extensions []string, supportedPreloadExtensions map[string][]DBExtension) string {
+func composeGroupName(config Config, servicePlan ServicePlan, extensions []string, pgauditLog []string, supportedPreloadExtensions map[string][]DBExtension) string {
 
 	normalisedFamily := normaliseIdentifier(aws.StringValue(servicePlan.RDSProperties.EngineFamily))
 	normalisedExtensions := []string{}
@@ -146,6 +393,17 @@ func composeGroupName(config Config, servicePlan ServicePlan, extensions []strin
 
 	if aws.StringValue(servicePlan.RDSProperties.Engine) == "postgres" && len(normalisedExtensions) > 0 {
 		identifier = fmt.Sprintf("%s-%s", identifier, strings.Join(normalisedExtensions, "-"))
+
+		// each set of audited classes needs its own group, as the
+		// setting applies to every instance using the group
+		if auditsStatements(relevantExtensions, pgauditLog) {
+			normalisedClasses := []string{}
+			for _, class := range pgauditLog {
+				normalisedClasses = append(normalisedClasses, normaliseIdentifier(class))
+			}
+			sort.Strings(normalisedClasses)
+			identifier = fmt.Sprintf("%s-pgauditlog-%s", identifier, strings.Join(normalisedClasses, "-"))
+		}
 	}
 
 	return identifier
@@ -170,6 +428,20 @@ func filterExtensionsNeedingPreloads(servicePlan ServicePlan, requestedExtension
 	return relevantExtensions
 }
 
+// auditsStatements reports whether pgaudit is being preloaded with classes of
+// statement to log. Without any, pgaudit logs nothing by default.
+func auditsStatements(preloadedExtensions []string, pgauditLog []string) bool {
+	if len(pgauditLog) == 0 || (len(pgauditLog) == 1 && pgauditLog[0] == "none") {
+		return false
+	}
+	for _, ext := range preloadedExtensions {
+		if ext == "pgaudit" {
+			return true
+		}
+	}
+	return false
+}
+
 func isParameterGroupNotFoundError(err error) bool {
 	return strings.HasPrefix(err.Error(), rds.ErrCodeDBParameterGroupNotFoundFault)
 }
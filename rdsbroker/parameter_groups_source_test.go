@@ -2,11 +2,15 @@ package rdsbroker
 
 import (
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"strconv"
 
 	"code.cloudfoundry.org/lager/v3"
 	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/alphagov/paas-rds-broker/awsrds"
 	"github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	sqlfake "github.com/alphagov/paas-rds-broker/sqlengine/fakes"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/rds"
@@ -47,18 +51,18 @@ var _ = Describe("ParameterGroupsSource", func() {
 		})
 
 		It("prepends the configured dbprefix", func() {
-			name := composeGroupName(config, servicePlan, extensions, map[string][]DBExtension{})
+			name := composeGroupName(config, servicePlan, extensions, nil, map[string][]DBExtension{})
 			Expect(name).To(HavePrefix(config.DBPrefix))
 		})
 
 		It("contains the normalised engine family", func() {
 			servicePlan.RDSProperties.EngineFamily = aws.String("test-db-engine-family")
-			name := composeGroupName(config, servicePlan, extensions, map[string][]DBExtension{})
+			name := composeGroupName(config, servicePlan, extensions, nil, map[string][]DBExtension{})
 			Expect(name).To(ContainSubstring("testdbenginefamily"))
 		})
 
 		It("contains the broker name", func() {
-			name := composeGroupName(config, servicePlan, extensions, map[string][]DBExtension{})
+			name := composeGroupName(config, servicePlan, extensions, nil, map[string][]DBExtension{})
 			Expect(name).To(ContainSubstring("envname"))
 		})
 
@@ -66,19 +70,19 @@ var _ = Describe("ParameterGroupsSource", func() {
 			It("only if the db engine is postgres", func() {
 				extensions = []string{"pg_stat_statements"}
 				servicePlan.RDSProperties.Engine = aws.String("database")
-				name := composeGroupName(config, servicePlan, extensions, map[string][]DBExtension{})
+				name := composeGroupName(config, servicePlan, extensions, nil, map[string][]DBExtension{})
 				Expect(name).ToNot(HaveSuffix("pgstatstatements"))
 			})
 
 			It("which have been normalised", func() {
 				extensions = []string{"pg_stat_statements"}
-				name := composeGroupName(config, servicePlan, extensions, supportedPreloads)
+				name := composeGroupName(config, servicePlan, extensions, nil, supportedPreloads)
 				Expect(name).To(HaveSuffix("pgstatstatements"))
 			})
 
 			It("which require a pre-load library for that engine version", func() {
 				extensions = []string{"pg_stat_statements", "notanext"}
-				name := composeGroupName(config, servicePlan, extensions, supportedPreloads)
+				name := composeGroupName(config, servicePlan, extensions, nil, supportedPreloads)
 				Expect(name).To(HaveSuffix("pgstatstatements"))
 				Expect(name).ToNot(ContainSubstring("notanext"))
 			})
@@ -91,7 +95,7 @@ var _ = Describe("ParameterGroupsSource", func() {
 					RequiresPreloadLibrary: true,
 				})
 
-				name := composeGroupName(config, servicePlan, extensions, supportedPreloads)
+				name := composeGroupName(config, servicePlan, extensions, nil, supportedPreloads)
 
 				Expect(name).To(HaveSuffix("pgstatstatements-pgz"))
 			})
@@ -109,11 +113,37 @@ var _ = Describe("ParameterGroupsSource", func() {
 					RequiresPreloadLibrary: true,
 				})
 
-				name := composeGroupName(config, servicePlan, extensions, supportedPreloads)
+				name := composeGroupName(config, servicePlan, extensions, nil, supportedPreloads)
 
 				Expect(name).To(HaveSuffix("pga-pgstatstatements-pgz"))
 			})
 		})
+
+		Context("when pgaudit is preloaded with classes to log", func() {
+			BeforeEach(func() {
+				extensions = []string{"pgaudit", "pg_stat_statements"}
+				supportedPreloads["postgres10"] = append(supportedPreloads["postgres10"], DBExtension{
+					Name:                   "pgaudit",
+					RequiresPreloadLibrary: true,
+				})
+			})
+
+			It("includes the sorted classes in the name", func() {
+				name := composeGroupName(config, servicePlan, extensions, []string{"role", "ddl"}, supportedPreloads)
+				Expect(name).To(HaveSuffix("pgaudit-pgstatstatements-pgauditlog-ddl-role"))
+			})
+
+			It("uses the same name as without pgaudit_log when the classes are none", func() {
+				name := composeGroupName(config, servicePlan, extensions, []string{"none"}, supportedPreloads)
+				Expect(name).To(HaveSuffix("pgaudit-pgstatstatements"))
+			})
+
+			It("ignores the classes if pgaudit isn't enabled", func() {
+				extensions = []string{"pg_stat_statements"}
+				name := composeGroupName(config, servicePlan, extensions, []string{"ddl"}, supportedPreloads)
+				Expect(name).To(HaveSuffix("pgstatstatements"))
+			})
+		})
 	})
 
 	Describe("SelectParameterGroup", func() {
@@ -165,7 +195,7 @@ var _ = Describe("ParameterGroupsSource", func() {
 			rdsError := awserr.New(rds.ErrCodeDBClusterAlreadyExistsFault, "not found", nil)
 			rdsFake.GetParameterGroupReturns(nil, rdsError)
 
-			_, err := parameterGroupSource.SelectParameterGroup(servicePlan, extensions)
+			_, err := parameterGroupSource.SelectParameterGroup(servicePlan, extensions, nil)
 			Expect(err).To(HaveOccurred())
 		})
 
@@ -180,12 +210,12 @@ var _ = Describe("ParameterGroupsSource", func() {
 			})
 
 			It("does not attempt to create the group", func() {
-				parameterGroupSource.SelectParameterGroup(servicePlan, extensions)
+				parameterGroupSource.SelectParameterGroup(servicePlan, extensions, nil)
 				Expect(rdsFake.CreateParameterGroupCallCount()).To(Equal(0))
 			})
 
 			It("returns the group name", func() {
-				name, _ := parameterGroupSource.SelectParameterGroup(servicePlan, extensions)
+				name, _ := parameterGroupSource.SelectParameterGroup(servicePlan, extensions, nil)
 				Expect(name).To(Equal("rdsbroker-postgres10-envname"))
 			})
 		})
@@ -195,10 +225,18 @@ var _ = Describe("ParameterGroupsSource", func() {
 				rdsFake.GetParameterGroupReturns(nil, errors.New(rds.ErrCodeDBParameterGroupNotFoundFault+": errMsg"))
 			})
 
+			It("only names the group when asked for its name", func() {
+				name, err := parameterGroupSource.ParameterGroupName(servicePlan, extensions, nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(name).To(Equal("rdsbroker-postgres10-envname"))
+				Expect(rdsFake.GetParameterGroupCallCount()).To(Equal(0))
+				Expect(rdsFake.CreateParameterGroupCallCount()).To(Equal(0))
+			})
+
 			It("attempts to create the group", func() {
 				rdsFake.CreateParameterGroupReturns(nil)
 
-				parameterGroupSource.SelectParameterGroup(servicePlan, extensions)
+				parameterGroupSource.SelectParameterGroup(servicePlan, extensions, nil)
 
 				Expect(rdsFake.CreateParameterGroupCallCount()).To(Equal(1))
 				createDBParameterGroupInput := rdsFake.CreateParameterGroupArgsForCall(0)
@@ -209,18 +247,55 @@ var _ = Describe("ParameterGroupsSource", func() {
 				rdsFake.CreateParameterGroupReturns(nil)
 				servicePlan.RDSProperties.EngineFamily = aws.String("postgres10-cfg")
 
-				parameterGroupSource.SelectParameterGroup(servicePlan, extensions)
+				parameterGroupSource.SelectParameterGroup(servicePlan, extensions, nil)
 
 				Expect(rdsFake.CreateParameterGroupCallCount()).To(Equal(1))
 				createDBParameterGroupInput := rdsFake.CreateParameterGroupArgsForCall(0)
 				Expect(aws.StringValue(createDBParameterGroupInput.DBParameterGroupFamily)).To(Equal(aws.StringValue(servicePlan.RDSProperties.EngineFamily)))
 			})
 
+			Context("when the plan does not configure an engine family", func() {
+				BeforeEach(func() {
+					servicePlan.RDSProperties.EngineVersion = aws.String("16")
+					servicePlan.RDSProperties.EngineFamily = nil
+					rdsFake.GetParameterGroupFamilyReturns("postgres16", nil)
+				})
+
+				It("sets the group family to the one RDS reports for the engine version", func() {
+					name, err := parameterGroupSource.SelectParameterGroup(servicePlan, extensions, nil)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(name).To(Equal("rdsbroker-postgres16-envname"))
+
+					Expect(rdsFake.GetParameterGroupFamilyCallCount()).To(Equal(1))
+					engine, version := rdsFake.GetParameterGroupFamilyArgsForCall(0)
+					Expect(engine).To(Equal("postgres"))
+					Expect(version).To(Equal("16"))
+
+					createDBParameterGroupInput := rdsFake.CreateParameterGroupArgsForCall(0)
+					Expect(aws.StringValue(createDBParameterGroupInput.DBParameterGroupFamily)).To(Equal("postgres16"))
+				})
+
+				It("only looks up the family once for each engine version", func() {
+					parameterGroupSource.SelectParameterGroup(servicePlan, extensions, nil)
+					parameterGroupSource.SelectParameterGroup(servicePlan, extensions, nil)
+
+					Expect(rdsFake.GetParameterGroupFamilyCallCount()).To(Equal(1))
+				})
+
+				It("returns an error if the family can't be found", func() {
+					rdsFake.GetParameterGroupFamilyReturns("", errors.New("boom"))
+
+					_, err := parameterGroupSource.SelectParameterGroup(servicePlan, extensions, nil)
+					Expect(err).To(MatchError("boom"))
+					Expect(rdsFake.CreateParameterGroupCallCount()).To(Equal(0))
+				})
+			})
+
 			It("returns an error if creating the parameter group fails", func() {
 				createError := awserr.New(rds.ErrCodeDBParameterGroupAlreadyExistsFault, "exists", nil)
 				rdsFake.CreateParameterGroupReturns(createError)
 
-				_, err := parameterGroupSource.SelectParameterGroup(servicePlan, extensions)
+				_, err := parameterGroupSource.SelectParameterGroup(servicePlan, extensions, nil)
 
 				Expect(err).To(HaveOccurred())
 			})
@@ -230,7 +305,7 @@ var _ = Describe("ParameterGroupsSource", func() {
 					It("and sets the force SSL property", func() {
 						rdsFake.ModifyParameterGroupReturns(nil)
 
-						parameterGroupSource.SelectParameterGroup(servicePlan, extensions)
+						parameterGroupSource.SelectParameterGroup(servicePlan, extensions, nil)
 						Expect(rdsFake.ModifyParameterGroupCallCount()).To(Equal(1), "ModifyParameterGroup was not called")
 
 						modifyInput := rdsFake.ModifyParameterGroupArgsForCall(0)
@@ -250,7 +325,7 @@ var _ = Describe("ParameterGroupsSource", func() {
 					It("and sets the log retention period", func() {
 						rdsFake.ModifyParameterGroupReturns(nil)
 
-						parameterGroupSource.SelectParameterGroup(servicePlan, extensions)
+						parameterGroupSource.SelectParameterGroup(servicePlan, extensions, nil)
 						Expect(rdsFake.ModifyParameterGroupCallCount()).To(Equal(1), "ModifyParameterGroup was not called")
 
 						modifyInput := rdsFake.ModifyParameterGroupArgsForCall(0)
@@ -278,7 +353,7 @@ var _ = Describe("ParameterGroupsSource", func() {
 
 					rdsFake.ModifyParameterGroupReturns(nil)
 
-					parameterGroupSource.SelectParameterGroup(servicePlan, extensions)
+					parameterGroupSource.SelectParameterGroup(servicePlan, extensions, nil)
 
 					Expect(rdsFake.ModifyParameterGroupCallCount()).To(Equal(1), "ModifyParameterGroup was not called")
 
@@ -302,7 +377,7 @@ var _ = Describe("ParameterGroupsSource", func() {
 
 					rdsFake.ModifyParameterGroupReturns(nil)
 
-					parameterGroupSource.SelectParameterGroup(servicePlan, extensions)
+					parameterGroupSource.SelectParameterGroup(servicePlan, extensions, nil)
 					Expect(rdsFake.ModifyParameterGroupCallCount()).To(Equal(1), "ModifyParameterGroup was not called")
 
 					modifyInput := rdsFake.ModifyParameterGroupArgsForCall(0)
@@ -316,6 +391,32 @@ var _ = Describe("ParameterGroupsSource", func() {
 
 					Expect(discovered).To(BeFalse(), "The shared_preload_libraries property was set when it shouldn't have been")
 				})
+
+				It("sets the pgaudit.log parameter when pgaudit is preloaded with classes to log", func() {
+					extensions = []string{"pgaudit"}
+					supportedPreloads["postgres10"] = append(supportedPreloads["postgres10"], DBExtension{
+						Name:                   "pgaudit",
+						RequiresPreloadLibrary: true,
+					})
+
+					rdsFake.ModifyParameterGroupReturns(nil)
+
+					name, err := parameterGroupSource.SelectParameterGroup(servicePlan, extensions, []string{"ddl", "role"})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(name).To(Equal("rdsbroker-postgres10-envname-pgaudit-pgauditlog-ddl-role"))
+
+					modifyInput := rdsFake.ModifyParameterGroupArgsForCall(0)
+					var relevantParam *rds.Parameter = nil
+					for _, param := range modifyInput.Parameters {
+						if aws.StringValue(param.ParameterName) == "pgaudit.log" {
+							relevantParam = param
+						}
+					}
+
+					Expect(relevantParam).ToNot(BeNil())
+					Expect(aws.StringValue(relevantParam.ParameterValue)).To(Equal("ddl,role"))
+					Expect(aws.StringValue(relevantParam.ApplyMethod)).To(Equal("immediate"))
+				})
 			})
 
 			Describe("when it is for a MySQL database", func() {
@@ -326,7 +427,7 @@ var _ = Describe("ParameterGroupsSource", func() {
 				It("will set the 'max_allowed_packet' property to 256mb", func() {
 					rdsFake.ModifyParameterGroupReturns(nil)
 
-					parameterGroupSource.SelectParameterGroup(servicePlan, extensions)
+					parameterGroupSource.SelectParameterGroup(servicePlan, extensions, nil)
 					Expect(rdsFake.ModifyParameterGroupCallCount()).To(Equal(1), "ModifyParameterGroup was not called")
 
 					modifyInput := rdsFake.ModifyParameterGroupArgsForCall(0)
@@ -341,8 +442,207 @@ var _ = Describe("ParameterGroupsSource", func() {
 					Expect(relevantParam).ToNot(BeNil())
 					Expect(aws.StringValue(relevantParam.ParameterValue)).To(Equal(strconv.Itoa(1024 * 1024 * 256)))
 				})
+
+				It("will turn the event scheduler on", func() {
+					rdsFake.ModifyParameterGroupReturns(nil)
+
+					parameterGroupSource.SelectParameterGroup(servicePlan, extensions, nil)
+					Expect(rdsFake.ModifyParameterGroupCallCount()).To(Equal(1), "ModifyParameterGroup was not called")
+
+					modifyInput := rdsFake.ModifyParameterGroupArgsForCall(0)
+					Expect(modifyInput.Parameters).To(ContainElement(&rds.Parameter{
+						ParameterName:  aws.String("event_scheduler"),
+						ParameterValue: aws.String("ON"),
+						ApplyMethod:    aws.String("immediate"),
+					}))
+				})
 			})
 		})
 
 	})
+
+	Describe("CorrectParameterGroupDrift", func() {
+		var rdsFake *fakes.FakeRDSInstance
+		var parameterGroupSource *ParameterGroupSource
+
+		BeforeEach(func() {
+			config := Config{
+				DBPrefix:   "rdsbroker",
+				BrokerName: "envname",
+				Catalog: Catalog{
+					Services: []Service{
+						{
+							ID: "service-1",
+							Plans: []ServicePlan{
+								{
+									ID: "plan-1",
+									RDSProperties: RDSProperties{
+										Engine:        aws.String("postgres"),
+										EngineVersion: aws.String("10"),
+										EngineFamily:  aws.String("postgres10"),
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			supportedPreloads := map[string][]DBExtension{
+				"postgres10": {
+					DBExtension{
+						Name:                   "pg_stat_statements",
+						RequiresPreloadLibrary: true,
+					},
+				},
+			}
+
+			rdsFake = &fakes.FakeRDSInstance{}
+			parameterGroupSource = NewParameterGroupSource(config, rdsFake, supportedPreloads, lagertest.NewTestLogger("rdsbroker_test"))
+
+			rdsFake.DescribeByTagReturns([]*rds.DBInstance{
+				{
+					DBInstanceArn: aws.String("arn:instance-1"),
+					DBParameterGroups: []*rds.DBParameterGroupStatus{
+						{DBParameterGroupName: aws.String("rdsbroker-postgres10-envname-pgstatstatements")},
+					},
+				},
+				{
+					DBInstanceArn: aws.String("arn:instance-2"),
+					DBParameterGroups: []*rds.DBParameterGroupStatus{
+						{DBParameterGroupName: aws.String("rdsbroker-postgres10-envname-pgstatstatements")},
+					},
+				},
+			}, nil)
+			rdsFake.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+				"Plan ID":    "plan-1",
+				"Extensions": "pg_stat_statements",
+			}), nil)
+			rdsFake.DescribeParametersReturns([]*rds.Parameter{
+				{ParameterName: aws.String("rds.force_ssl"), ParameterValue: aws.String("1")},
+				{ParameterName: aws.String("rds.log_retention_period"), ParameterValue: aws.String("10080")},
+				{ParameterName: aws.String("shared_preload_libraries"), ParameterValue: aws.String("pg_stat_statements")},
+				{ParameterName: aws.String("work_mem")},
+			}, nil)
+		})
+
+		It("looks up the instances of this broker", func() {
+			_, err := parameterGroupSource.CorrectParameterGroupDrift()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rdsFake.DescribeByTagCallCount()).To(Equal(1))
+			tagKey, tagValue, _ := rdsFake.DescribeByTagArgsForCall(0)
+			Expect(tagKey).To(Equal("Broker Name"))
+			Expect(tagValue).To(Equal("envname"))
+		})
+
+		It("checks each parameter group once", func() {
+			_, err := parameterGroupSource.CorrectParameterGroupDrift()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rdsFake.DescribeParametersCallCount()).To(Equal(1))
+			Expect(rdsFake.DescribeParametersArgsForCall(0)).To(Equal("rdsbroker-postgres10-envname-pgstatstatements"))
+		})
+
+		It("does not modify a group which has not drifted", func() {
+			drifted, err := parameterGroupSource.CorrectParameterGroupDrift()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(drifted).To(Equal(0))
+			Expect(rdsFake.ModifyParameterGroupCallCount()).To(Equal(0))
+		})
+
+		It("puts back the parameters which have drifted", func() {
+			rdsFake.DescribeParametersReturns([]*rds.Parameter{
+				{ParameterName: aws.String("rds.force_ssl"), ParameterValue: aws.String("0")},
+				{ParameterName: aws.String("rds.log_retention_period"), ParameterValue: aws.String("10080")},
+				{ParameterName: aws.String("shared_preload_libraries")},
+			}, nil)
+
+			drifted, err := parameterGroupSource.CorrectParameterGroupDrift()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(drifted).To(Equal(2))
+			Expect(rdsFake.ModifyParameterGroupCallCount()).To(Equal(1))
+			modifyInput := rdsFake.ModifyParameterGroupArgsForCall(0)
+			Expect(aws.StringValue(modifyInput.DBParameterGroupName)).To(Equal("rdsbroker-postgres10-envname-pgstatstatements"))
+			Expect(modifyInput.Parameters).To(ConsistOf(
+				rdsParameter("rds.force_ssl", "1", "pending-reboot"),
+				rdsParameter("shared_preload_libraries", "pg_stat_statements", "pending-reboot"),
+			))
+		})
+
+		It("skips groups which the instance's tags would not select", func() {
+			rdsFake.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+				"Plan ID": "plan-1",
+			}), nil)
+
+			_, err := parameterGroupSource.CorrectParameterGroupDrift()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rdsFake.DescribeParametersCallCount()).To(Equal(0))
+		})
+
+		It("skips instances with an unknown plan", func() {
+			rdsFake.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+				"Plan ID": "unknown-plan",
+			}), nil)
+
+			_, err := parameterGroupSource.CorrectParameterGroupDrift()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rdsFake.DescribeParametersCallCount()).To(Equal(0))
+		})
+
+		It("returns an error if the instances can't be listed", func() {
+			rdsFake.DescribeByTagReturns(nil, errors.New("boom"))
+
+			_, err := parameterGroupSource.CorrectParameterGroupDrift()
+			Expect(err).To(MatchError("boom"))
+		})
+
+		It("carries on with the other groups if one can't be checked", func() {
+			rdsFake.DescribeByTagReturns([]*rds.DBInstance{
+				{
+					DBInstanceArn: aws.String("arn:instance-1"),
+					DBParameterGroups: []*rds.DBParameterGroupStatus{
+						{DBParameterGroupName: aws.String("rdsbroker-postgres10-envname-pgstatstatements")},
+					},
+				},
+				{
+					DBInstanceArn: aws.String("arn:instance-2"),
+					DBParameterGroups: []*rds.DBParameterGroupStatus{
+						{DBParameterGroupName: aws.String("rdsbroker-postgres10-envname")},
+					},
+				},
+			}, nil)
+			rdsFake.GetResourceTagsReturnsOnCall(1, awsrds.BuildRDSTags(map[string]string{
+				"Plan ID": "plan-1",
+			}), nil)
+			rdsFake.DescribeParametersReturnsOnCall(0, nil, errors.New("boom"))
+
+			_, err := parameterGroupSource.CorrectParameterGroupDrift()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rdsFake.DescribeParametersCallCount()).To(Equal(2))
+			Expect(rdsFake.DescribeParametersArgsForCall(1)).To(Equal("rdsbroker-postgres10-envname"))
+		})
+
+		It("serves what it found in the broker's metrics", func() {
+			rdsFake.DescribeParametersReturns([]*rds.Parameter{
+				{ParameterName: aws.String("rds.force_ssl"), ParameterValue: aws.String("0")},
+				{ParameterName: aws.String("rds.log_retention_period"), ParameterValue: aws.String("10080")},
+				{ParameterName: aws.String("shared_preload_libraries"), ParameterValue: aws.String("pg_stat_statements")},
+			}, nil)
+			_, err := parameterGroupSource.CorrectParameterGroupDrift()
+			Expect(err).ToNot(HaveOccurred())
+
+			rdsBroker := New(Config{DBPrefix: "rdsbroker", BrokerName: "envname"}, rdsFake, &sqlfake.FakeProvider{}, parameterGroupSource, lagertest.NewTestLogger("rdsbroker_test"))
+			recorder := httptest.NewRecorder()
+			rdsBroker.AdminHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/metrics", nil))
+
+			Expect(recorder.Body.String()).To(ContainSubstring("rds_broker_parameter_group_drift_checked_groups 1\n"))
+			Expect(recorder.Body.String()).To(ContainSubstring("rds_broker_parameter_group_drifted_parameters 1\n"))
+			Expect(recorder.Body.String()).To(ContainSubstring("rds_broker_parameter_group_drift_check_failures 0\n"))
+		})
+	})
 })
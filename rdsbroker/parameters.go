@@ -1,19 +1,39 @@
 package rdsbroker
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
 
 type ProvisionParameters struct {
-	BackupRetentionPeriod           int64    `json:"backup_retention_period"`
-	CharacterSetName                string   `json:"character_set_name"`
-	DBName                          string   `json:"dbname"`
-	PreferredBackupWindow           string   `json:"preferred_backup_window"`
-	PreferredMaintenanceWindow      string   `json:"preferred_maintenance_window"`
-	SkipFinalSnapshot               *bool    `json:"skip_final_snapshot"`
-	RestoreFromPointInTimeOf        *string  `json:"restore_from_point_in_time_of"`
-	RestoreFromPointInTimeBefore    *string  `json:"restore_from_point_in_time_before"`
-	RestoreFromLatestSnapshotOf     *string  `json:"restore_from_latest_snapshot_of"`
-	RestoreFromLatestSnapshotBefore *string  `json:"restore_from_latest_snapshot_before"`
-	Extensions                      []string `json:"enable_extensions"`
+	BackupRetentionPeriod           int64                 `json:"backup_retention_period"`
+	CharacterSetName                string                `json:"character_set_name"`
+	DBName                          string                `json:"dbname"`
+	PreferredBackupWindow           string                `json:"preferred_backup_window"`
+	PreferredMaintenanceWindow      string                `json:"preferred_maintenance_window"`
+	SkipFinalSnapshot               *bool                 `json:"skip_final_snapshot"`
+	RestoreFromPointInTimeOf        *string               `json:"restore_from_point_in_time_of"`
+	RestoreFromPointInTimeBefore    *string               `json:"restore_from_point_in_time_before"`
+	RestoreFromLatestSnapshotOf     *string               `json:"restore_from_latest_snapshot_of"`
+	RestoreFromLatestSnapshotBefore *string               `json:"restore_from_latest_snapshot_before"`
+	Extensions                      []string              `json:"enable_extensions"`
+	AdoptDBInstance                 *string               `json:"adopt_db_instance"`
+	AdditionalDatabases             []string              `json:"additional_databases"`
+	NetworkTier                     *string               `json:"network_tier"`
+	RestorePrevious                 bool                  `json:"restore_previous"`
+	PubliclyAccessible              *bool                 `json:"publicly_accessible"`
+	AutoMinorVersionUpgrade         *bool                 `json:"auto_minor_version_upgrade"`
+	ScheduleAtMaintenanceWindow     *bool                 `json:"schedule_at_maintenance_window"`
+	KmsKeyAlias                     string                `json:"kms_key_alias"`
+	TemplateDatabaseInstance        *string               `json:"template_database_instance"`
+	ImportData                      *ImportDataParameters `json:"import_data"`
+	ExportDataOnDeprovision         *string               `json:"export_data_on_deprovision"`
 }
 
 type UpdateParameters struct {
@@ -27,36 +47,237 @@ type UpdateParameters struct {
 	ForceFailover               *bool    `json:"force_failover"`
 	EnableExtensions            []string `json:"enable_extensions"`
 	DisableExtensions           []string `json:"disable_extensions"`
+	ForceDropExtensions         bool     `json:"force_drop_extensions"`
+	SecurityGroupSet            *string  `json:"security_group_set"`
+	PurgeOtherDatabases         *string  `json:"purge_other_databases"`
+	PgauditLog                  []string `json:"pgaudit_log"`
+	Preview                     bool     `json:"preview"`
+	AdditionalDatabases         []string `json:"additional_databases"`
+	LiftDeprovisionProtection   bool     `json:"lift_deprovision_protection"`
+	PubliclyAccessible          *bool    `json:"publicly_accessible"`
+	AutoMinorVersionUpgrade     *bool    `json:"auto_minor_version_upgrade"`
+	ScheduleAtMaintenanceWindow *bool    `json:"schedule_at_maintenance_window"`
+	ConfirmDowngrade            bool     `json:"confirm_downgrade"`
+	ExportDataOnDeprovision     *string  `json:"export_data_on_deprovision"`
 }
 
+// PgauditLogClasses are the classes of statement which users can choose for
+// pgaudit to log. "none" turns statement logging back off.
+var PgauditLogClasses = []string{"read", "write", "function", "role", "ddl", "misc", "all", "none"}
+
 type BindParameters struct {
-	ReadOnly bool `json:"read_only"`
+	ReadOnly                bool     `json:"read_only"`
+	UseConnectionPool       bool     `json:"use_connection_pool"`
+	UseRDSProxy             bool     `json:"use_rds_proxy"`
+	ServiceBindingLayout    bool     `json:"service_binding_layout"`
+	Database                string   `json:"database"`
+	TTLHours                int      `json:"ttl_hours"`
+	Schemas                 []string `json:"schemas"`
+	StatementTimeoutSeconds *int     `json:"statement_timeout_seconds"`
+}
+
+// MaxBindingTTLHours is the longest a binding can ask its credentials to
+// last for before they expire.
+const MaxBindingTTLHours = 24 * 30
+
+func (bp *BindParameters) Validate() error {
+	problems := ParameterErrors{}
+	if bp.TTLHours < 0 || bp.TTLHours > MaxBindingTTLHours {
+		problems = append(problems, fmt.Errorf("ttl_hours must not be negative or more than %d", MaxBindingTTLHours))
+	}
+	if bp.StatementTimeoutSeconds != nil && (*bp.StatementTimeoutSeconds < 0 || *bp.StatementTimeoutSeconds > MaxStatementTimeoutSeconds) {
+		problems = append(problems, fmt.Errorf("statement_timeout_seconds must not be negative or more than %d", MaxStatementTimeoutSeconds))
+	}
+	for _, schema := range bp.Schemas {
+		if !additionalDatabaseNamePattern.MatchString(schema) {
+			problems = append(problems, fmt.Errorf("schemas: '%s' must start with a lowercase letter and contain only lowercase letters, digits and underscores, up to 63 characters", schema))
+		} else if schema == "public" || schema == "information_schema" || strings.HasPrefix(schema, "pg_") {
+			problems = append(problems, fmt.Errorf("schemas: '%s' is reserved", schema))
+		}
+	}
+	if bp.UseConnectionPool && bp.UseRDSProxy {
+		problems = append(problems, fmt.Errorf("use_connection_pool and use_rds_proxy can't be combined"))
+	}
+	return problems.errOrNil()
 }
 
 func (pp *ProvisionParameters) Validate() error {
-	return nil
+	problems := ParameterErrors{}
+	if pp.RestoreFromLatestSnapshotOf != nil && pp.RestoreFromPointInTimeOf != nil {
+		problems = append(problems, fmt.Errorf("Cannot use both restore_from_latest_snapshot_of and restore_from_point_in_time_of at the same time"))
+	}
+	if pp.RestoreFromLatestSnapshotOf == nil && pp.RestoreFromLatestSnapshotBefore != nil {
+		problems = append(problems, fmt.Errorf("Parameter restore_from_latest_snapshot_before should be used with restore_from_latest_snapshot_of"))
+	}
+	if pp.RestoreFromPointInTimeOf == nil && pp.RestoreFromPointInTimeBefore != nil {
+		problems = append(problems, fmt.Errorf("Parameter restore_from_point_in_time_before should be used with restore_from_point_in_time_of"))
+	}
+	if pp.RestorePrevious && (pp.RestoreFromLatestSnapshotOf != nil || pp.RestoreFromPointInTimeOf != nil) {
+		problems = append(problems, fmt.Errorf("Cannot use restore_previous along with restore_from_latest_snapshot_of or restore_from_point_in_time_of"))
+	}
+	if pp.AdoptDBInstance != nil && (pp.RestoreFromLatestSnapshotOf != nil || pp.RestoreFromPointInTimeOf != nil || pp.RestorePrevious) {
+		problems = append(problems, fmt.Errorf("Cannot adopt an existing instance and restore at the same time"))
+	}
+	if pp.TemplateDatabaseInstance != nil && (pp.AdoptDBInstance != nil || pp.RestoreFromLatestSnapshotOf != nil || pp.RestoreFromPointInTimeOf != nil || pp.RestorePrevious) {
+		problems = append(problems, fmt.Errorf("Cannot use template_database_instance along with adopt_db_instance or the restore parameters"))
+	}
+	if pp.ImportData != nil {
+		if pp.AdoptDBInstance != nil || pp.RestoreFromLatestSnapshotOf != nil || pp.RestoreFromPointInTimeOf != nil || pp.RestorePrevious {
+			problems = append(problems, fmt.Errorf("Cannot use import_data along with adopt_db_instance or the restore parameters"))
+		}
+		problems = append(problems, pp.ImportData.validate()...)
+	}
+	if pp.ExportDataOnDeprovision != nil && pp.AdoptDBInstance != nil {
+		problems = append(problems, fmt.Errorf("Cannot use export_data_on_deprovision along with adopt_db_instance, set it with an update once the instance is adopted"))
+	}
+	problems = append(problems, validateAdditionalDatabases(pp.AdditionalDatabases)...)
+	return problems.errOrNil()
 }
 
 func (up *UpdateParameters) Validate() error {
+	problems := ParameterErrors{}
 	for _, ext1 := range up.EnableExtensions {
 		for _, ext2 := range up.DisableExtensions {
 			if ext1 == ext2 {
-				return fmt.Errorf("%s is set in both enable_extensions and disable_extensions", ext1)
+				problems = append(problems, fmt.Errorf("%s is set in both enable_extensions and disable_extensions", ext1))
 			}
 		}
 	}
-	return nil
+	if up.ForceDropExtensions && len(up.DisableExtensions) == 0 {
+		problems = append(problems, fmt.Errorf("force_drop_extensions can only be set along with disable_extensions"))
+	}
+	if up.PurgeOtherDatabases != nil {
+		switch *up.PurgeOtherDatabases {
+		case PurgeOtherDatabasesDryRun, PurgeOtherDatabasesConfirm:
+		default:
+			problems = append(problems, fmt.Errorf("purge_other_databases must be '%s' or '%s'", PurgeOtherDatabasesDryRun, PurgeOtherDatabasesConfirm))
+		}
+	}
+	if up.Preview && up.PurgeOtherDatabases != nil {
+		problems = append(problems, fmt.Errorf("preview can't be combined with purge_other_databases, use its dry_run instead"))
+	}
+	for _, class := range up.PgauditLog {
+		if !containsString(PgauditLogClasses, class) {
+			problems = append(problems, fmt.Errorf("%s is not a valid pgaudit_log class, must be one of %s", class, strings.Join(PgauditLogClasses, ", ")))
+		} else if (class == "none" || class == "all") && len(up.PgauditLog) > 1 {
+			problems = append(problems, fmt.Errorf("pgaudit_log class %s can't be combined with other classes", class))
+		}
+	}
+	if aws.BoolValue(up.Reboot) && aws.BoolValue(up.UpgradeMinorVersionToLatest) {
+		problems = append(problems, fmt.Errorf("Cannot reboot and upgrade minor version to latest at the same time"))
+	}
+	problems = append(problems, validateAdditionalDatabases(up.AdditionalDatabases)...)
+	return problems.errOrNil()
 }
 
 func (up *UpdateParameters) CheckForCompatibilityWithPlanChange() error {
+	problems := ParameterErrors{}
 	if up.Reboot != nil && *up.Reboot {
-		return fmt.Errorf("Invalid to reboot and update plan in the same command")
+		problems = append(problems, fmt.Errorf("Invalid to reboot and update plan in the same command"))
 	}
 	if len(up.EnableExtensions) > 0 {
-		return fmt.Errorf("Invalid to enable extensions and update plan in the same command")
+		problems = append(problems, fmt.Errorf("Invalid to enable extensions and update plan in the same command"))
 	}
 	if len(up.DisableExtensions) > 0 {
-		return fmt.Errorf("Invalid to disable extensions and update plan in the same command")
+		problems = append(problems, fmt.Errorf("Invalid to disable extensions and update plan in the same command"))
+	}
+	if up.PurgeOtherDatabases != nil {
+		problems = append(problems, fmt.Errorf("Invalid to purge other databases and update plan in the same command"))
+	}
+	if len(up.PgauditLog) > 0 {
+		problems = append(problems, fmt.Errorf("Invalid to set pgaudit_log and update plan in the same command"))
+	}
+	if up.UpgradeMinorVersionToLatest != nil && *up.UpgradeMinorVersionToLatest {
+		problems = append(problems, fmt.Errorf("Cannot specify a version and upgrade minor version to latest at the same time"))
+	}
+	return problems.errOrNil()
+}
+
+// ParameterErrors are all of the problems found with the parameters of a
+// request, so that users can fix them in one go rather than one at a time.
+type ParameterErrors []error
+
+func (e ParameterErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	messages := make([]string, len(e))
+	for i, problem := range e {
+		messages[i] = "- " + problem.Error()
+	}
+	return fmt.Sprintf("There are %d problems with the parameters:\n%s", len(e), strings.Join(messages, "\n"))
+}
+
+// errOrNil returns nil when there are no problems, as a nil ParameterErrors
+// isn't a nil error.
+func (e ParameterErrors) errOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// decodeParameters decodes the raw parameters of a request into parameters
+// and validates them, returning every problem found: each unknown
+// parameter, the first parameter of the wrong type, and everything
+// Validate finds wrong with the rest.
+func decodeParameters(rawParameters json.RawMessage, parameters interface{ Validate() error }) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(rawParameters, &fields); err != nil {
+		return newInvalidParametersError(err)
+	}
+
+	problems := ParameterErrors{}
+	knownFields := jsonFieldNames(parameters)
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !knownFields[name] {
+			problems = append(problems, fmt.Errorf("unknown field %q", name))
+		}
+	}
+
+	if err := json.Unmarshal(rawParameters, parameters); err != nil {
+		problems = append(problems, err)
+	}
+	if err := parameters.Validate(); err != nil {
+		if validationProblems, ok := err.(ParameterErrors); ok {
+			problems = append(problems, validationProblems...)
+		} else {
+			problems = append(problems, err)
+		}
+	}
+
+	if len(problems) > 0 {
+		return newInvalidParametersError(problems)
 	}
 	return nil
 }
+
+// jsonFieldNames returns the JSON names of the fields of the struct v
+// points to.
+func jsonFieldNames(v interface{}) map[string]bool {
+	names := map[string]bool{}
+	t := reflect.TypeOf(v).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = t.Field(i).Name
+		}
+		names[name] = true
+	}
+	return names
+}
+
+// userChoice is a user's choice of a boolean parameter in the form it's
+// recorded in the tags of an instance, or previousChoice if they haven't
+// made one.
+func userChoice(value *bool, previousChoice string) string {
+	if value == nil {
+		return previousChoice
+	}
+	return strconv.FormatBool(*value)
+}
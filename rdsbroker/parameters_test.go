@@ -0,0 +1,79 @@
+package rdsbroker_test
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+)
+
+var _ = Describe("Parameters", func() {
+	Describe("ProvisionParameters.Validate", func() {
+		It("returns every problem with the parameters", func() {
+			parameters := ProvisionParameters{
+				RestoreFromLatestSnapshotBefore: aws.String("2024-01-01 00:00:00"),
+				AdditionalDatabases:             []string{"Reporting", "postgres"},
+			}
+
+			err := parameters.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err).To(BeAssignableToTypeOf(ParameterErrors{}))
+			Expect(err.(ParameterErrors)).To(HaveLen(3))
+			Expect(err.Error()).To(Equal(`There are 3 problems with the parameters:
+- Parameter restore_from_latest_snapshot_before should be used with restore_from_latest_snapshot_of
+- additional_databases: 'Reporting' must start with a lowercase letter and contain only lowercase letters, digits and underscores, up to 63 characters
+- additional_databases: 'postgres' is reserved`))
+		})
+
+		It("returns nil when there are no problems", func() {
+			parameters := ProvisionParameters{AdditionalDatabases: []string{"reporting"}}
+			Expect(parameters.Validate()).To(Succeed())
+		})
+	})
+
+	Describe("UpdateParameters.Validate", func() {
+		It("returns a single problem on its own", func() {
+			parameters := UpdateParameters{ForceDropExtensions: true}
+
+			err := parameters.Validate()
+			Expect(err).To(MatchError("force_drop_extensions can only be set along with disable_extensions"))
+		})
+
+		It("returns every problem with the parameters", func() {
+			parameters := UpdateParameters{
+				Reboot:                      aws.Bool(true),
+				UpgradeMinorVersionToLatest: aws.Bool(true),
+				PgauditLog:                  []string{"everything"},
+			}
+
+			err := parameters.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.(ParameterErrors)).To(ConsistOf(
+				MatchError(ContainSubstring("everything is not a valid pgaudit_log class")),
+				MatchError("Cannot reboot and upgrade minor version to latest at the same time"),
+			))
+		})
+	})
+
+	Describe("BindParameters.Validate", func() {
+		It("returns every problem with the parameters", func() {
+			parameters := BindParameters{
+				TTLHours:                -1,
+				Schemas:                 []string{"public"},
+				UseConnectionPool:       true,
+				UseRDSProxy:             true,
+				StatementTimeoutSeconds: aws.Int(-1),
+			}
+
+			err := parameters.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.(ParameterErrors)).To(ConsistOf(
+				MatchError(ContainSubstring("ttl_hours must not be negative")),
+				MatchError(ContainSubstring("statement_timeout_seconds must not be negative")),
+				MatchError("schemas: 'public' is reserved"),
+				MatchError("use_connection_pool and use_rds_proxy can't be combined"),
+			))
+		})
+	})
+})
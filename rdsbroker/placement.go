@@ -0,0 +1,120 @@
+package rdsbroker
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// failoverHistoryPeriod is how far back the failovers of a DB instance are
+// reported. RDS only keeps events for 14 days.
+const failoverHistoryPeriod = 14 * 24 * time.Hour
+
+// Failover is a failover of a Multi-AZ DB instance to its standby which RDS
+// has recorded, whether requested or caused by maintenance or a failure.
+type Failover struct {
+	Date    time.Time `json:"date"`
+	Message string    `json:"message"`
+}
+
+// recentFailovers lists the failovers of a DB instance in the last
+// failoverHistoryPeriod, most recent first. Only Multi-AZ instances fail
+// over, so other instances have none without calling RDS.
+func (b *RDSBroker) recentFailovers(dbInstance *rds.DBInstance, now time.Time) ([]Failover, error) {
+	failovers := []Failover{}
+	if !aws.BoolValue(dbInstance.MultiAZ) {
+		return failovers, nil
+	}
+
+	events, err := b.dbInstance.DescribeEventsBetween(aws.StringValue(dbInstance.DBInstanceIdentifier), now.Add(-failoverHistoryPeriod), now)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		if isFailoverEvent(event) {
+			failovers = append(failovers, Failover{
+				Date:    aws.TimeValue(event.Date),
+				Message: aws.StringValue(event.Message),
+			})
+		}
+	}
+	return failovers, nil
+}
+
+// instancePlacement is where a DB instance is running, and how often it has
+// failed over recently.
+type instancePlacement struct {
+	instanceID                string
+	dbInstanceIdentifier      string
+	planID                    string
+	availabilityZone          string
+	secondaryAvailabilityZone string
+	multiAZ                   bool
+	failovers                 []Failover
+}
+
+// instancePlacements gives the placement of the DB instances. Soft deleted
+// instances are left out.
+func (b *RDSBroker) instancePlacements(dbInstances []managedDBInstance, now time.Time) ([]instancePlacement, error) {
+	placements := []instancePlacement{}
+	for _, instance := range dbInstances {
+		dbInstance := instance.dbInstance
+		if instance.tagsByName[awsrds.TagPurgeAfter] != "" {
+			continue
+		}
+
+		failovers, err := b.recentFailovers(dbInstance, now)
+		if err != nil {
+			return nil, err
+		}
+
+		dbInstanceIdentifier := aws.StringValue(dbInstance.DBInstanceIdentifier)
+		placements = append(placements, instancePlacement{
+			instanceID:                b.dbInstanceIdentifierToServiceInstanceID(dbInstanceIdentifier),
+			dbInstanceIdentifier:      dbInstanceIdentifier,
+			planID:                    instance.tagsByName[awsrds.TagPlanID],
+			availabilityZone:          aws.StringValue(dbInstance.AvailabilityZone),
+			secondaryAvailabilityZone: aws.StringValue(dbInstance.SecondaryAvailabilityZone),
+			multiAZ:                   aws.BoolValue(dbInstance.MultiAZ),
+			failovers:                 failovers,
+		})
+	}
+	return placements, nil
+}
+
+// writePlacementMetrics writes the availability zones and recent failovers
+// of the DB instances in the Prometheus text exposition format.
+func writePlacementMetrics(w io.Writer, placements []instancePlacement) {
+	const zoneName = "rds_broker_instance_availability_zone_info"
+	fmt.Fprintf(w, "# HELP %s The availability zones of the DB instance and, for Multi-AZ instances, its standby.\n", zoneName)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", zoneName)
+	for _, placement := range placements {
+		fmt.Fprintf(w, "%s{instance_id=%q,db_instance_identifier=%q,plan_id=%q,availability_zone=%q,secondary_availability_zone=%q,multi_az=%q} 1\n",
+			zoneName, placement.instanceID, placement.dbInstanceIdentifier, placement.planID,
+			placement.availabilityZone, placement.secondaryAvailabilityZone, fmt.Sprint(placement.multiAZ))
+	}
+
+	const failoversName = "rds_broker_instance_recent_failovers"
+	fmt.Fprintf(w, "# HELP %s Failovers of the DB instance in the last 14 days.\n", failoversName)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", failoversName)
+	for _, placement := range placements {
+		fmt.Fprintf(w, "%s{instance_id=%q,db_instance_identifier=%q,plan_id=%q} %d\n",
+			failoversName, placement.instanceID, placement.dbInstanceIdentifier, placement.planID, len(placement.failovers))
+	}
+
+	const lastFailoverName = "rds_broker_instance_last_failover_timestamp_seconds"
+	fmt.Fprintf(w, "# HELP %s When the DB instance last failed over, for instances which have in the last 14 days.\n", lastFailoverName)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", lastFailoverName)
+	for _, placement := range placements {
+		if len(placement.failovers) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s{instance_id=%q,db_instance_identifier=%q,plan_id=%q} %d\n",
+			lastFailoverName, placement.instanceID, placement.dbInstanceIdentifier, placement.planID, placement.failovers[0].Date.Unix())
+	}
+}
@@ -0,0 +1,90 @@
+package rdsbroker_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+)
+
+var _ = Describe("Placement metrics", func() {
+	var (
+		rdsInstance  *rdsfake.FakeRDSInstance
+		rdsBroker    *RDSBroker
+		failoverDate time.Time
+	)
+
+	BeforeEach(func() {
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		rdsBroker = newTestBroker(testConfig(), rdsInstance, nil, lagertest.NewTestLogger("placement_test"))
+
+		rdsInstance.DescribeByTagReturns([]*rds.DBInstance{
+			{
+				DBInstanceIdentifier:      aws.String("cf-multi-az-instance"),
+				DBInstanceArn:             aws.String("arn:aws:rds:rds-region:1234567890:db:cf-multi-az-instance"),
+				MultiAZ:                   aws.Bool(true),
+				AvailabilityZone:          aws.String("eu-west-1b"),
+				SecondaryAvailabilityZone: aws.String("eu-west-1a"),
+			},
+			{
+				DBInstanceIdentifier: aws.String("cf-single-az-instance"),
+				DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-single-az-instance"),
+				MultiAZ:              aws.Bool(false),
+				AvailabilityZone:     aws.String("eu-west-1c"),
+			},
+		}, nil)
+		rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+			"Broker Name": "mybroker",
+			"Plan ID":     "Plan-1",
+		}), nil)
+
+		failoverDate = time.Unix(1700000000, 0)
+		rdsInstance.DescribeEventsBetweenReturns([]*rds.Event{
+			{
+				Date:            aws.Time(failoverDate),
+				EventCategories: aws.StringSlice([]string{"failover"}),
+				Message:         aws.String("Multi-AZ instance failover completed"),
+			},
+			{
+				Date:            aws.Time(failoverDate.Add(-time.Minute)),
+				EventCategories: aws.StringSlice([]string{"failover"}),
+				Message:         aws.String("Multi-AZ instance failover started"),
+			},
+		}, nil)
+	})
+
+	It("serves the availability zones and recent failovers of every instance", func() {
+		Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+		recorder := httptest.NewRecorder()
+		rdsBroker.AdminHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/metrics", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(ContainSubstring(
+			"# TYPE rds_broker_instance_availability_zone_info gauge\n" +
+				`rds_broker_instance_availability_zone_info{instance_id="multi-az-instance",db_instance_identifier="cf-multi-az-instance",plan_id="Plan-1",availability_zone="eu-west-1b",secondary_availability_zone="eu-west-1a",multi_az="true"} 1` + "\n" +
+				`rds_broker_instance_availability_zone_info{instance_id="single-az-instance",db_instance_identifier="cf-single-az-instance",plan_id="Plan-1",availability_zone="eu-west-1c",secondary_availability_zone="",multi_az="false"} 1` + "\n",
+		))
+		Expect(recorder.Body.String()).To(ContainSubstring(
+			"# TYPE rds_broker_instance_recent_failovers gauge\n" +
+				`rds_broker_instance_recent_failovers{instance_id="multi-az-instance",db_instance_identifier="cf-multi-az-instance",plan_id="Plan-1"} 2` + "\n" +
+				`rds_broker_instance_recent_failovers{instance_id="single-az-instance",db_instance_identifier="cf-single-az-instance",plan_id="Plan-1"} 0` + "\n",
+		))
+		Expect(recorder.Body.String()).To(ContainSubstring(
+			"# TYPE rds_broker_instance_last_failover_timestamp_seconds gauge\n" +
+				`rds_broker_instance_last_failover_timestamp_seconds{instance_id="multi-az-instance",db_instance_identifier="cf-multi-az-instance",plan_id="Plan-1"} 1700000000` + "\n",
+		))
+
+		By("only describing the events of Multi-AZ instances")
+		Expect(rdsInstance.DescribeEventsBetweenCallCount()).To(Equal(1))
+	})
+})
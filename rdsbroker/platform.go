@@ -0,0 +1,47 @@
+package rdsbroker
+
+import (
+	"encoding/json"
+)
+
+// The platforms the broker knows about, as sent in the "platform" property
+// of the request context.
+const (
+	PlatformCloudFoundry = "cloudfoundry"
+	PlatformKubernetes   = "kubernetes"
+)
+
+// platformContext is the part of the OSB request context which tells the
+// platforms apart. Cloud Foundry places service instances in an org and
+// space, which are also sent outside of the context, while Kubernetes places
+// them in a namespace and sends placeholders for the org and space.
+type platformContext struct {
+	Platform  string `json:"platform"`
+	Namespace string `json:"namespace"`
+}
+
+func platformContextFrom(rawContext json.RawMessage) platformContext {
+	var context platformContext
+	if len(rawContext) == 0 {
+		return context
+	}
+	if err := json.Unmarshal(rawContext, &context); err != nil {
+		return platformContext{}
+	}
+	context.Platform = sanitizeTagValue(context.Platform)
+	context.Namespace = sanitizeTagValue(context.Namespace)
+	return context
+}
+
+func (c platformContext) isKubernetes() bool {
+	return c.Platform == PlatformKubernetes
+}
+
+// kubernetesNamespace is the namespace of the service instance, or empty on
+// other platforms.
+func (c platformContext) kubernetesNamespace() string {
+	if !c.isKubernetes() {
+		return ""
+	}
+	return c.Namespace
+}
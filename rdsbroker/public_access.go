@@ -0,0 +1,36 @@
+package rdsbroker
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// checkPublicAccessAllowed refuses a publicly_accessible parameter asking
+// for an instance reachable from the internet on a plan which doesn't allow
+// it. Asking for a private instance is always allowed.
+func checkPublicAccessAllowed(servicePlan ServicePlan, publiclyAccessible *bool) error {
+	if publiclyAccessible != nil && *publiclyAccessible && !servicePlan.AllowPublicAccess {
+		return newUserError(ErrCodePlanNotAllowed, "Service Plan '%s' does not allow publicly_accessible to be set to true", servicePlan.Name)
+	}
+	return nil
+}
+
+// publiclyAccessible is whether an instance of the plan is reachable from
+// the internet. The user's choice is only kept while the plan allows public
+// access, so moving to a plan which doesn't makes the instance private again
+// unless the plan itself says otherwise.
+func publiclyAccessible(servicePlan ServicePlan, choice string) *bool {
+	if servicePlan.AllowPublicAccess && choice != "" {
+		return aws.Bool(choice == "true")
+	}
+	return servicePlan.RDSProperties.PubliclyAccessible
+}
+
+// endpointType describes whether the endpoint of an instance is reachable
+// from the internet.
+func endpointType(dbInstance *rds.DBInstance) string {
+	if aws.BoolValue(dbInstance.PubliclyAccessible) {
+		return "public"
+	}
+	return "private"
+}
@@ -0,0 +1,135 @@
+package rdsbroker
+
+import (
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	"github.com/alphagov/paas-rds-broker/sqlengine"
+)
+
+const (
+	PurgeOtherDatabasesDryRun  = "dry_run"
+	PurgeOtherDatabasesConfirm = "confirm"
+
+	databasesToPurgeSeparator = ":"
+)
+
+// The "purge_other_databases" update parameter deals with the databases a
+// restore brings along besides the one the broker binds applications to. A
+// dry run finds them and records them in the "Databases To Purge" tag, so
+// that users can check the list before confirming. Confirming drops only the
+// databases the dry run reported, in case more have been created since.
+
+// checkPurgeOtherDatabases checks that purge_other_databases can be used in
+// the given mode, without touching the database.
+func checkPurgeOtherDatabases(tagsByName map[string]string, mode string) error {
+	if tagsByName[awsrds.TagRestoredFromSnapshot] == "" && tagsByName[awsrds.TagOriginDatabase] == "" {
+		return newUserError(ErrCodeInvalidParameters, "purge_other_databases is only available on instances restored from another instance")
+	}
+
+	if _, dryRunDone := tagsByName[awsrds.TagDatabasesToPurge]; mode == PurgeOtherDatabasesConfirm && !dryRunDone {
+		return newUserError(ErrCodeInvalidParameters, "Run update with purge_other_databases set to '%s' first, to check which databases will be dropped", PurgeOtherDatabasesDryRun)
+	}
+
+	return nil
+}
+
+// findDatabasesToPurge is the dry run, returning the databases which would be
+// dropped packed for the "Databases To Purge" tag.
+func (b *RDSBroker) findDatabasesToPurge(instanceID string, dbInstance *rds.DBInstance, tagsByName map[string]string) (string, error) {
+	dbName := b.dbNameFromDBInstance(instanceID, dbInstance)
+	sqlEngine, err := b.openSQLEngineForDBInstance(instanceID, dbName, dbInstance)
+	if err != nil {
+		return "", err
+	}
+	defer sqlEngine.Close()
+
+	otherDatabases, err := listOtherDatabases(sqlEngine, tagsByName)
+	if err != nil {
+		return "", err
+	}
+
+	if len(otherDatabases) == 0 {
+		return "", fmt.Errorf("There are no databases other than '%s' to purge", dbName)
+	}
+	for _, otherDatabase := range otherDatabases {
+		if sanitizeTagValue(otherDatabase) != otherDatabase || strings.Contains(otherDatabase, databasesToPurgeSeparator) {
+			return "", fmt.Errorf("Database '%s' can't be purged by the broker as its name can't be recorded", otherDatabase)
+		}
+	}
+	packedDatabases := strings.Join(otherDatabases, databasesToPurgeSeparator)
+	if len(packedDatabases) > maxRDSTagValueLength {
+		return "", fmt.Errorf("There are too many databases other than '%s' for the broker to purge", dbName)
+	}
+
+	b.logger.Info("purge-other-databases.dry-run", lager.Data{
+		instanceIDLogKey: instanceID,
+		"dbName":         dbName,
+		"databases":      otherDatabases,
+	})
+	return packedDatabases, nil
+}
+
+// dropDatabasesToPurge drops the databases a dry run reported which still
+// exist, then removes the "Databases To Purge" tag.
+func (b *RDSBroker) dropDatabasesToPurge(instanceID string, dbInstance *rds.DBInstance, tagsByName map[string]string) error {
+	dbName := b.dbNameFromDBInstance(instanceID, dbInstance)
+	sqlEngine, err := b.openSQLEngineForDBInstance(instanceID, dbName, dbInstance)
+	if err != nil {
+		return err
+	}
+	defer sqlEngine.Close()
+
+	otherDatabases, err := listOtherDatabases(sqlEngine, tagsByName)
+	if err != nil {
+		return err
+	}
+
+	stillExists := map[string]bool{}
+	for _, otherDatabase := range otherDatabases {
+		stillExists[otherDatabase] = true
+	}
+	for _, reportedDatabase := range unpackDatabasesToPurge(tagsByName[awsrds.TagDatabasesToPurge]) {
+		if !stillExists[reportedDatabase] {
+			continue
+		}
+		b.logger.Info("purge-other-databases.drop", lager.Data{
+			instanceIDLogKey: instanceID,
+			"database":       reportedDatabase,
+		})
+		if err := sqlEngine.DropDatabase(reportedDatabase); err != nil {
+			return err
+		}
+	}
+
+	return b.removeTag(b.dbInstanceIdentifier(instanceID), awsrds.TagDatabasesToPurge)
+}
+
+// listOtherDatabases lists the databases besides the one the broker binds
+// applications to, apart from the instance's additional databases, which are
+// kept as they were asked for.
+func listOtherDatabases(sqlEngine sqlengine.SQLEngine, tagsByName map[string]string) ([]string, error) {
+	allOtherDatabases, err := sqlEngine.ListOtherDatabases()
+	if err != nil {
+		return nil, err
+	}
+	additionalDatabases := unpackAdditionalDatabases(tagsByName[awsrds.TagAdditionalDatabases])
+	otherDatabases := []string{}
+	for _, otherDatabase := range allOtherDatabases {
+		if !containsString(additionalDatabases, otherDatabase) {
+			otherDatabases = append(otherDatabases, otherDatabase)
+		}
+	}
+	return otherDatabases, nil
+}
+
+func unpackDatabasesToPurge(packedDatabases string) []string {
+	if packedDatabases == "" {
+		return []string{}
+	}
+	return strings.Split(packedDatabases, databasesToPurgeSeparator)
+}
@@ -0,0 +1,52 @@
+package rdsbroker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// The reasons the broker reboots a DB instance. They are recorded in its
+// Reboot Reason tag so that every poll of the operation can tell users why
+// their instance is down, not just the one which rebooted it.
+const (
+	rebootReasonParameterChange = "parameter-change"
+	rebootReasonRestore         = "restore"
+	rebootReasonUserRequested   = "user-requested"
+)
+
+var rebootReasonDescriptions = map[string]string{
+	rebootReasonParameterChange: "to apply changes to its parameter group",
+	rebootReasonRestore:         "to finish being restored",
+	rebootReasonUserRequested:   "as requested",
+}
+
+func (b *RDSBroker) rebootingDescription(instanceID string, reason string) string {
+	description := fmt.Sprintf("DB Instance '%s' is rebooting", b.dbInstanceIdentifier(instanceID))
+	if reasonDescription, ok := rebootReasonDescriptions[reason]; ok {
+		description += " " + reasonDescription
+	}
+	return description
+}
+
+// rebootForReason reboots a DB instance, recording why in the audit log and
+// its tags. Reboots the broker makes itself are audited with ctx's identity,
+// which for the steps of a last operation is whoever polled it.
+func (b *RDSBroker) rebootForReason(ctx context.Context, instanceID string, dbInstanceArn string, forceFailover *bool, reason string) error {
+	b.auditLogWithData(ctx, "reboot", instanceID, map[string]interface{}{"reason": reason})
+
+	err := b.dbInstance.Reboot(&rds.RebootDBInstanceInput{
+		DBInstanceIdentifier: aws.String(b.dbInstanceIdentifier(instanceID)),
+		ForceFailover:        forceFailover,
+	})
+	if err != nil {
+		return err
+	}
+
+	b.writeTags(instanceID, dbInstanceArn, map[string]string{awsrds.TagRebootReason: reason})
+	return nil
+}
@@ -0,0 +1,46 @@
+package rdsbroker
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// findServicePlanForRead finds the plan a DB instance is on for operations
+// which only read it. When the broker tolerates removed plans, a plan which
+// is no longer in the catalog is made up from the DB instance itself, so
+// that instances left on it can still be described and polled. Operations
+// which change instances keep looking plans up in the catalog, so nothing
+// can be moved onto a removed plan.
+func (b *RDSBroker) findServicePlanForRead(planID string, dbInstance *rds.DBInstance) (ServicePlan, bool) {
+	servicePlan, ok := b.catalog.FindServicePlan(planID)
+	if ok || !b.tolerateRemovedPlans || planID == "" {
+		return servicePlan, ok
+	}
+	return removedServicePlan(planID, dbInstance), true
+}
+
+// removedServicePlan makes up a plan which a DB instance agrees with, for
+// an instance on a plan which has been removed from the catalog. It has
+// none of the optional features of plans, so nothing is set up on the
+// instance which it doesn't already have.
+func removedServicePlan(planID string, dbInstance *rds.DBInstance) ServicePlan {
+	return ServicePlan{
+		ID:   planID,
+		Name: planID,
+		RDSProperties: RDSProperties{
+			Engine:           dbInstance.Engine,
+			EngineVersion:    dbInstance.EngineVersion,
+			DBInstanceClass:  dbInstance.DBInstanceClass,
+			AllocatedStorage: dbInstance.AllocatedStorage,
+			MultiAZ:          dbInstance.MultiAZ,
+			StorageType:      dbInstance.StorageType,
+			StorageEncrypted: dbInstance.StorageEncrypted,
+			KmsKeyID:         dbInstance.KmsKeyId,
+		},
+	}
+}
+
+func removedPlanWarning(planID string) string {
+	return fmt.Sprintf("Service Plan '%s' has been removed from the catalog, so this instance can't be updated", planID)
+}
@@ -0,0 +1,94 @@
+package rdsbroker_test
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+	sqlfake "github.com/alphagov/paas-rds-broker/sqlengine/fakes"
+)
+
+var _ = Describe("Removed plans", func() {
+	var (
+		config      Config
+		rdsInstance *rdsfake.FakeRDSInstance
+		rdsBroker   *RDSBroker
+	)
+
+	BeforeEach(func() {
+		config = testConfig()
+
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		dbInstance := testDBInstance()
+		dbInstance.EngineVersion = aws.String("12.7")
+		dbInstance.DBInstanceClass = aws.String("db.m5.large")
+		dbInstance.AllocatedStorage = aws.Int64(100)
+		dbInstance.MultiAZ = aws.Bool(false)
+		rdsInstance.DescribeReturns(dbInstance, nil)
+		rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+			"Broker Name":       "mybroker",
+			"Service ID":        "Service-1",
+			"Plan ID":           "Removed-Plan",
+			"SkipFinalSnapshot": "true",
+		}), nil)
+	})
+
+	JustBeforeEach(func() {
+		rdsBroker = newTestBroker(config, rdsInstance, &sqlfake.FakeSQLEngine{}, lagertest.NewTestLogger("removed_plans_test"))
+	})
+
+	It("can't describe instances on removed plans by default", func() {
+		_, err := rdsBroker.GetInstance(context.Background(), "instance-1", domain.FetchInstanceDetails{})
+		Expect(err).To(MatchError("Service Plan 'Removed-Plan' not found"))
+		Expect(ErrorCode(err)).To(Equal(ErrCodePlanNotFound))
+	})
+
+	Context("when removed plans are tolerated", func() {
+		BeforeEach(func() {
+			config.TolerateRemovedPlans = true
+		})
+
+		It("describes instances on removed plans from the DB instance", func() {
+			instance, err := rdsBroker.GetInstance(context.Background(), "instance-1", domain.FetchInstanceDetails{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(instance.ServiceID).To(Equal("Service-1"))
+			Expect(instance.PlanID).To(Equal("Removed-Plan"))
+
+			parameters := instance.Parameters.(map[string]interface{})
+			Expect(parameters["db_instance_class"]).To(Equal(aws.String("db.m5.large")))
+			Expect(parameters["skip_final_snapshot"]).To(BeTrue())
+			Expect(parameters["allowed_extensions"]).To(BeEmpty())
+			Expect(parameters["warnings"]).To(ConsistOf(
+				"Service Plan 'Removed-Plan' has been removed from the catalog, so this instance can't be updated",
+			))
+		})
+
+		It("completes polls of operations on instances on removed plans", func() {
+			lastOperation, err := rdsBroker.LastOperation(context.Background(), "instance-1", domain.PollDetails{
+				ServiceID: "Service-1",
+				PlanID:    "",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lastOperation.State).To(Equal(domain.Succeeded))
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(0))
+		})
+
+		It("still refuses updates onto removed plans", func() {
+			_, err := rdsBroker.Update(context.Background(), "instance-1", domain.UpdateDetails{
+				ServiceID:      "Service-1",
+				PlanID:         "Removed-Plan",
+				PreviousValues: domain.PreviousValues{PlanID: "Plan-1"},
+			}, true)
+			Expect(err).To(MatchError("Service Plan 'Removed-Plan' not found"))
+			Expect(ErrorCode(err)).To(Equal(ErrCodePlanNotFound))
+			Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,167 @@
+package rdsbroker
+
+import (
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// restoreTestIdentifierSuffix is appended to the identifier of a DB instance
+// to name the temporary instance its latest snapshot is restored into. The
+// temporary instance isn't tagged with the broker name, so the broker never
+// mistakes it for a service instance.
+const restoreTestIdentifierSuffix = "-restore-test"
+
+const (
+	restoreTestPassed = "passed"
+	restoreTestFailed = "failed"
+)
+
+// ProcessRestoreTests moves the restore test of every DB instance on a plan
+// with restore tests one step on: the latest automated snapshot of instances
+// due a test is restored into a temporary instance, which is checked with a
+// query once it is available, the result is tagged on the tested instance,
+// and the temporary instance is deleted.
+func (b *RDSBroker) ProcessRestoreTests() error {
+	if b.restoreTestInterval == 0 {
+		return nil
+	}
+
+	dbInstances, err := b.listManagedDBInstances()
+	if err != nil {
+		return err
+	}
+	return b.processRestoreTests(dbInstances)
+}
+
+func (b *RDSBroker) processRestoreTests(dbInstances []managedDBInstance) error {
+	if b.restoreTestInterval == 0 {
+		return nil
+	}
+
+	for _, instance := range dbInstances {
+		tagsByName := instance.tagsByName
+		if tagsByName[awsrds.TagPurgeAfter] != "" {
+			continue
+		}
+		servicePlan, ok := b.catalog.FindServicePlan(tagsByName[awsrds.TagPlanID])
+		if !ok || !servicePlan.RestoreTest {
+			continue
+		}
+
+		if err := b.processRestoreTest(instance.dbInstance, tagsByName, time.Now()); err != nil {
+			b.logger.Error("restore-test", err, lager.Data{dbInstanceLogKey: instance.dbInstance.DBInstanceIdentifier})
+		}
+	}
+
+	return nil
+}
+
+func (b *RDSBroker) processRestoreTest(dbInstance *rds.DBInstance, tagsByName map[string]string, now time.Time) error {
+	dbInstanceIdentifier := aws.StringValue(dbInstance.DBInstanceIdentifier)
+	testInstanceIdentifier := dbInstanceIdentifier + restoreTestIdentifierSuffix
+	logData := lager.Data{"dbInstanceIdentifier": dbInstanceIdentifier, "testInstanceIdentifier": testInstanceIdentifier}
+
+	testInstance, err := b.dbInstance.Describe(testInstanceIdentifier)
+	if err == awsrds.ErrDBInstanceDoesNotExist {
+		if restoreTestedAt, err := time.Parse(time.RFC3339, tagsByName[awsrds.TagRestoreTestedAt]); err == nil && now.Sub(restoreTestedAt) < b.restoreTestInterval {
+			return nil
+		}
+		return b.startRestoreTest(dbInstance, testInstanceIdentifier, now)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch status := aws.StringValue(testInstance.DBInstanceStatus); status {
+	case "available":
+		instanceID := b.dbInstanceIdentifierToServiceInstanceID(dbInstanceIdentifier)
+		sqlEngine, err := b.openSQLEngineForDBInstance(instanceID, b.dbNameFromDBInstance(instanceID, testInstance), testInstance)
+		if err == nil {
+			err = sqlEngine.CheckConnection()
+			sqlEngine.Close()
+		}
+		if err != nil {
+			b.logger.Error("restore-test.check", err, logData)
+			return b.finishRestoreTest(dbInstance, testInstanceIdentifier, restoreTestFailed, now)
+		}
+		return b.finishRestoreTest(dbInstance, testInstanceIdentifier, restoreTestPassed, now)
+	case "failed", "incompatible-restore", "incompatible-parameters", "incompatible-network", "storage-full":
+		b.logger.Error("restore-test.restore", fmt.Errorf("restored DB instance is %s", status), logData)
+		return b.finishRestoreTest(dbInstance, testInstanceIdentifier, restoreTestFailed, now)
+	}
+
+	return nil
+}
+
+func (b *RDSBroker) startRestoreTest(dbInstance *rds.DBInstance, testInstanceIdentifier string, now time.Time) error {
+	dbInstanceIdentifier := aws.StringValue(dbInstance.DBInstanceIdentifier)
+	snapshots, err := b.dbInstance.DescribeSnapshots(dbInstanceIdentifier)
+	if err != nil {
+		return err
+	}
+	snapshot := latestAutomatedSnapshot(snapshots)
+	if snapshot == nil {
+		return nil
+	}
+
+	restoreInput := &rds.RestoreDBInstanceFromDBSnapshotInput{
+		DBInstanceIdentifier: aws.String(testInstanceIdentifier),
+		DBSnapshotIdentifier: snapshot.DBSnapshotIdentifier,
+		DBInstanceClass:      dbInstance.DBInstanceClass,
+		MultiAZ:              aws.Bool(false),
+		PubliclyAccessible:   dbInstance.PubliclyAccessible,
+		Tags: awsrds.BuildRDSTags(map[string]string{
+			awsrds.TagRestoreTestOf: dbInstanceIdentifier,
+		}),
+	}
+	if dbInstance.DBSubnetGroup != nil {
+		restoreInput.DBSubnetGroupName = dbInstance.DBSubnetGroup.DBSubnetGroupName
+	}
+	if len(dbInstance.DBParameterGroups) > 0 {
+		restoreInput.DBParameterGroupName = dbInstance.DBParameterGroups[0].DBParameterGroupName
+	}
+	for _, securityGroup := range dbInstance.VpcSecurityGroups {
+		restoreInput.VpcSecurityGroupIds = append(restoreInput.VpcSecurityGroupIds, securityGroup.VpcSecurityGroupId)
+	}
+
+	b.logger.Info("restore-test.start", lager.Data{
+		"dbInstanceIdentifier": dbInstanceIdentifier,
+		"dbSnapshotIdentifier": aws.StringValue(snapshot.DBSnapshotIdentifier),
+	})
+	if err := b.dbInstance.Restore(restoreInput); err != nil {
+		b.logger.Error("restore-test.restore", err, lager.Data{"dbInstanceIdentifier": dbInstanceIdentifier})
+		return b.recordRestoreTest(dbInstance, restoreTestFailed, now)
+	}
+	return nil
+}
+
+func (b *RDSBroker) finishRestoreTest(dbInstance *rds.DBInstance, testInstanceIdentifier string, result string, now time.Time) error {
+	if err := b.recordRestoreTest(dbInstance, result, now); err != nil {
+		return err
+	}
+	err := b.dbInstance.Delete(testInstanceIdentifier, true)
+	if err == awsrds.ErrDBInstanceDoesNotExist {
+		return nil
+	}
+	return err
+}
+
+func (b *RDSBroker) recordRestoreTest(dbInstance *rds.DBInstance, result string, now time.Time) error {
+	b.logger.Info("restore-test.finish", lager.Data{
+		"dbInstanceIdentifier": aws.StringValue(dbInstance.DBInstanceIdentifier),
+		"result":               result,
+	})
+	return b.dbInstance.AddTagsToResource(
+		aws.StringValue(dbInstance.DBInstanceArn),
+		awsrds.BuildRDSTags(map[string]string{
+			awsrds.TagRestoreTestedAt:   now.UTC().Format(time.RFC3339),
+			awsrds.TagRestoreTestResult: result,
+		}),
+	)
+}
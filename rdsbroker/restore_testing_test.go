@@ -0,0 +1,220 @@
+package rdsbroker_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+	sqlfake "github.com/alphagov/paas-rds-broker/sqlengine/fakes"
+)
+
+var _ = Describe("ProcessRestoreTests", func() {
+	var (
+		rdsInstance             *rdsfake.FakeRDSInstance
+		sqlEngine               *sqlfake.FakeSQLEngine
+		rdsBroker               *RDSBroker
+		restoreTestIntervalDays uint
+		restoreTest             bool
+		tags                    map[string]string
+		testInstance            *rds.DBInstance
+	)
+
+	BeforeEach(func() {
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		sqlEngine = &sqlfake.FakeSQLEngine{}
+		restoreTestIntervalDays = 7
+		restoreTest = true
+		testInstance = nil
+
+		rdsInstance.DescribeByTagReturns([]*rds.DBInstance{
+			{
+				DBInstanceIdentifier: aws.String("cf-instance-1"),
+				DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-instance-1"),
+				DBInstanceClass:      aws.String("db.t3.small"),
+				DBSubnetGroup:        &rds.DBSubnetGroup{DBSubnetGroupName: aws.String("subnet-group")},
+				DBParameterGroups: []*rds.DBParameterGroupStatus{
+					{DBParameterGroupName: aws.String("parameter-group")},
+				},
+				VpcSecurityGroups: []*rds.VpcSecurityGroupMembership{
+					{VpcSecurityGroupId: aws.String("sg-1")},
+				},
+			},
+		}, nil)
+		rdsInstance.DescribeSnapshotsReturns([]*rds.DBSnapshot{
+			{
+				DBSnapshotIdentifier: aws.String("rds:cf-instance-1-2023-11-14"),
+				SnapshotType:         aws.String("automated"),
+				Status:               aws.String("available"),
+				SnapshotCreateTime:   aws.Time(time.Now().Add(-time.Hour)),
+			},
+		}, nil)
+		tags = map[string]string{
+			"Broker Name": "mybroker",
+			"Plan ID":     "Plan-1",
+		}
+	})
+
+	JustBeforeEach(func() {
+		config := testConfig()
+		config.RestoreTestIntervalDays = restoreTestIntervalDays
+		config.Catalog.Services[0].Plans[0].RestoreTest = restoreTest
+		rdsBroker = newTestBroker(config, rdsInstance, sqlEngine, lagertest.NewTestLogger("restore_testing_test"))
+
+		rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(tags), nil)
+		if testInstance == nil {
+			rdsInstance.DescribeReturns(nil, awsrds.ErrDBInstanceDoesNotExist)
+		} else {
+			rdsInstance.DescribeReturns(testInstance, nil)
+		}
+	})
+
+	It("restores the latest automated snapshot into a temporary instance", func() {
+		Expect(rdsBroker.ProcessRestoreTests()).To(Succeed())
+
+		Expect(rdsInstance.DescribeArgsForCall(0)).To(Equal("cf-instance-1-restore-test"))
+		Expect(rdsInstance.RestoreCallCount()).To(Equal(1))
+		input := rdsInstance.RestoreArgsForCall(0)
+		Expect(aws.StringValue(input.DBInstanceIdentifier)).To(Equal("cf-instance-1-restore-test"))
+		Expect(aws.StringValue(input.DBSnapshotIdentifier)).To(Equal("rds:cf-instance-1-2023-11-14"))
+		Expect(aws.StringValue(input.DBInstanceClass)).To(Equal("db.t3.small"))
+		Expect(aws.StringValue(input.DBSubnetGroupName)).To(Equal("subnet-group"))
+		Expect(aws.StringValue(input.DBParameterGroupName)).To(Equal("parameter-group"))
+		Expect(aws.StringValueSlice(input.VpcSecurityGroupIds)).To(Equal([]string{"sg-1"}))
+		Expect(awsrds.RDSTagsValues(input.Tags)).To(Equal(map[string]string{"Restore Test Of": "cf-instance-1"}))
+	})
+
+	Context("when restore tests are disabled", func() {
+		BeforeEach(func() {
+			restoreTestIntervalDays = 0
+		})
+
+		It("does nothing", func() {
+			Expect(rdsBroker.ProcessRestoreTests()).To(Succeed())
+			Expect(rdsInstance.DescribeByTagCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the plan doesn't have restore tests", func() {
+		BeforeEach(func() {
+			restoreTest = false
+		})
+
+		It("doesn't test the instance", func() {
+			Expect(rdsBroker.ProcessRestoreTests()).To(Succeed())
+			Expect(rdsInstance.RestoreCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the instance was tested recently", func() {
+		BeforeEach(func() {
+			tags["Restore Tested At"] = time.Now().Add(-24 * time.Hour).UTC().Format(time.RFC3339)
+		})
+
+		It("doesn't test it again yet", func() {
+			Expect(rdsBroker.ProcessRestoreTests()).To(Succeed())
+			Expect(rdsInstance.RestoreCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the restore can't be started", func() {
+		BeforeEach(func() {
+			rdsInstance.RestoreReturns(errors.New("boom"))
+		})
+
+		It("records the test as failed", func() {
+			Expect(rdsBroker.ProcessRestoreTests()).To(Succeed())
+
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+			_, addedTags := rdsInstance.AddTagsToResourceArgsForCall(0)
+			Expect(awsrds.RDSTagsValues(addedTags)).To(HaveKeyWithValue("Restore Test Result", "failed"))
+		})
+	})
+
+	Context("when the temporary instance is available", func() {
+		BeforeEach(func() {
+			testInstance = &rds.DBInstance{
+				DBInstanceIdentifier: aws.String("cf-instance-1-restore-test"),
+				DBInstanceStatus:     aws.String("available"),
+				DBName:               aws.String("db_name"),
+				Engine:               aws.String("postgres"),
+				Endpoint: &rds.Endpoint{
+					Address: aws.String("restore-test-endpoint"),
+					Port:    aws.Int64(5432),
+				},
+			}
+		})
+
+		It("checks it with a query, records the result and deletes it", func() {
+			Expect(rdsBroker.ProcessRestoreTests()).To(Succeed())
+
+			address, _, dbname, _, _ := sqlEngine.OpenArgsForCall(0)
+			Expect(address).To(Equal("restore-test-endpoint"))
+			Expect(dbname).To(Equal("db_name"))
+			Expect(sqlEngine.CheckConnectionCallCount()).ToNot(BeZero())
+
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+			arn, addedTags := rdsInstance.AddTagsToResourceArgsForCall(0)
+			Expect(arn).To(Equal("arn:aws:rds:rds-region:1234567890:db:cf-instance-1"))
+			Expect(awsrds.RDSTagsValues(addedTags)).To(HaveKeyWithValue("Restore Test Result", "passed"))
+			Expect(awsrds.RDSTagsValues(addedTags)).To(HaveKey("Restore Tested At"))
+
+			Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+			id, skipFinalSnapshot := rdsInstance.DeleteArgsForCall(0)
+			Expect(id).To(Equal("cf-instance-1-restore-test"))
+			Expect(skipFinalSnapshot).To(BeTrue())
+			Expect(rdsInstance.RestoreCallCount()).To(Equal(0))
+		})
+
+		It("records a failure if the query fails", func() {
+			sqlEngine.CheckConnectionReturns(errors.New("connection refused"))
+
+			Expect(rdsBroker.ProcessRestoreTests()).To(Succeed())
+
+			_, addedTags := rdsInstance.AddTagsToResourceArgsForCall(0)
+			Expect(awsrds.RDSTagsValues(addedTags)).To(HaveKeyWithValue("Restore Test Result", "failed"))
+			Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when the temporary instance is still being restored", func() {
+		BeforeEach(func() {
+			testInstance = &rds.DBInstance{
+				DBInstanceIdentifier: aws.String("cf-instance-1-restore-test"),
+				DBInstanceStatus:     aws.String("creating"),
+			}
+		})
+
+		It("waits for it", func() {
+			Expect(rdsBroker.ProcessRestoreTests()).To(Succeed())
+
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(0))
+			Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+			Expect(rdsInstance.RestoreCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the temporary instance failed to restore", func() {
+		BeforeEach(func() {
+			testInstance = &rds.DBInstance{
+				DBInstanceIdentifier: aws.String("cf-instance-1-restore-test"),
+				DBInstanceStatus:     aws.String("incompatible-restore"),
+			}
+		})
+
+		It("records a failure and deletes it", func() {
+			Expect(rdsBroker.ProcessRestoreTests()).To(Succeed())
+
+			_, addedTags := rdsInstance.AddTagsToResourceArgsForCall(0)
+			Expect(awsrds.RDSTagsValues(addedTags)).To(HaveKeyWithValue("Restore Test Result", "failed"))
+			Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+		})
+	})
+})
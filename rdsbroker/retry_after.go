@@ -0,0 +1,93 @@
+package rdsbroker
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// The operations which can be given their own Retry-After hints, with how
+// long the platform is told to wait between polls of each by default.
+// Creates take many minutes, so polling them often only adds load, while
+// reboots are over quickly.
+const (
+	RetryAfterProvision   = "provision"
+	RetryAfterUpdate      = "update"
+	RetryAfterReboot      = "reboot"
+	RetryAfterDeprovision = "deprovision"
+)
+
+var defaultRetryAfterSeconds = map[string]uint{
+	RetryAfterProvision:   60,
+	RetryAfterUpdate:      30,
+	RetryAfterReboot:      15,
+	RetryAfterDeprovision: 30,
+}
+
+type retryAfterContextKey struct{}
+
+type retryAfterHint struct {
+	seconds uint
+}
+
+// RetryAfterHandler lets the broker give a Retry-After header on the
+// responses to requests which start an asynchronous operation, and on the
+// polls of operations which are still in progress. The broker API library
+// has no way to set response headers, so the broker leaves a hint in the
+// request context which is written out along with the status code.
+func RetryAfterHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hint := &retryAfterHint{}
+		ctx := context.WithValue(r.Context(), retryAfterContextKey{}, hint)
+		next.ServeHTTP(&retryAfterResponseWriter{ResponseWriter: w, hint: hint}, r.WithContext(ctx))
+	})
+}
+
+type retryAfterResponseWriter struct {
+	http.ResponseWriter
+	hint        *retryAfterHint
+	wroteHeader bool
+}
+
+func (w *retryAfterResponseWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if w.hint.seconds > 0 && (statusCode == http.StatusOK || statusCode == http.StatusAccepted) {
+			w.Header().Set("Retry-After", strconv.FormatUint(uint64(w.hint.seconds), 10))
+		}
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *retryAfterResponseWriter) Write(body []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(body)
+}
+
+// hintRetryAfter asks for the response to the request to carry a
+// Retry-After header for the operation. It does nothing when the request
+// didn't come through RetryAfterHandler, or the operation's hint is 0.
+func (b *RDSBroker) hintRetryAfter(ctx context.Context, operation string) {
+	hint, ok := ctx.Value(retryAfterContextKey{}).(*retryAfterHint)
+	if !ok {
+		return
+	}
+	hint.seconds = b.retryAfterSeconds[operation]
+}
+
+// retryAfterOperation returns the operation whose Retry-After hint is given
+// to the polls of an operation, from the operation data it was started with.
+func retryAfterOperation(operationData string) string {
+	switch {
+	case isProvisionOperation(operationData):
+		return RetryAfterProvision
+	case operationData == operationDataReboot:
+		return RetryAfterReboot
+	case operationData == operationDataDeprovision:
+		return RetryAfterDeprovision
+	default:
+		return RetryAfterUpdate
+	}
+}
@@ -0,0 +1,105 @@
+package rdsbroker_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v9"
+
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+	sqlfake "github.com/alphagov/paas-rds-broker/sqlengine/fakes"
+)
+
+var _ = Describe("Retry-After hints", func() {
+	var (
+		config      Config
+		rdsInstance *rdsfake.FakeRDSInstance
+		handler     http.Handler
+	)
+
+	BeforeEach(func() {
+		config = testConfig()
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		dbInstance := testDBInstance()
+		dbInstance.DBInstanceStatus = aws.String("creating")
+		rdsInstance.DescribeReturns(dbInstance, nil)
+	})
+
+	JustBeforeEach(func() {
+		config.FillDefaults()
+		logger := lagertest.NewTestLogger("retry_after_test")
+		rdsBroker := newTestBroker(config, rdsInstance, &sqlfake.FakeSQLEngine{}, logger)
+		credentials := brokerapi.BrokerCredentials{Username: "brokeruser", Password: "brokerpass"}
+		handler = RetryAfterHandler(brokerapi.New(rdsBroker, logger, credentials))
+	})
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "http://example.com"+path, strings.NewReader(body))
+		req.Header.Set("X-Broker-API-Version", "2.14")
+		req.SetBasicAuth("brokeruser", "brokerpass")
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	provisionBody := `{"service_id": "Service-1", "plan_id": "Plan-1", "organization_guid": "organization-id", "space_guid": "space-id"}`
+
+	It("hints how long to wait before polling a provision", func() {
+		recorder := serve("PUT", "/v2/service_instances/instance-1?accepts_incomplete=true", provisionBody)
+		Expect(recorder.Code).To(Equal(http.StatusAccepted))
+		Expect(recorder.Header().Get("Retry-After")).To(Equal("60"))
+	})
+
+	It("hints polls of operations which are still in progress by the type of operation", func() {
+		recorder := serve("GET", "/v2/service_instances/instance-1/last_operation?operation=provision", "")
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(ContainSubstring(`"state":"in progress"`))
+		Expect(recorder.Header().Get("Retry-After")).To(Equal("60"))
+
+		rdsInstance.DescribeReturns(&rds.DBInstance{
+			DBInstanceIdentifier: aws.String("cf-instance-1"),
+			DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-instance-1"),
+			DBInstanceStatus:     aws.String("rebooting"),
+		}, nil)
+		recorder = serve("GET", "/v2/service_instances/instance-1/last_operation?operation=reboot", "")
+		Expect(recorder.Header().Get("Retry-After")).To(Equal("15"))
+	})
+
+	It("doesn't hint polls of operations which have finished", func() {
+		rdsInstance.DescribeReturns(&rds.DBInstance{
+			DBInstanceIdentifier: aws.String("cf-instance-1"),
+			DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-instance-1"),
+			DBInstanceStatus:     aws.String("available"),
+		}, nil)
+
+		recorder := serve("GET", "/v2/service_instances/instance-1/last_operation?operation=reboot", "")
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(ContainSubstring(`"state":"succeeded"`))
+		Expect(recorder.Header().Get("Retry-After")).To(BeEmpty())
+	})
+
+	It("doesn't hint errors", func() {
+		recorder := serve("PUT", "/v2/service_instances/instance-1?accepts_incomplete=true", `{"service_id": "Service-1", "plan_id": "Plan-3", "organization_guid": "organization-id", "space_guid": "space-id"}`)
+		Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+		Expect(recorder.Header().Get("Retry-After")).To(BeEmpty())
+	})
+
+	Context("when an operation's hint is configured as 0", func() {
+		BeforeEach(func() {
+			config.RetryAfterSeconds = map[string]uint{RetryAfterProvision: 0}
+		})
+
+		It("doesn't give a Retry-After header", func() {
+			recorder := serve("PUT", "/v2/service_instances/instance-1?accepts_incomplete=true", provisionBody)
+			Expect(recorder.Code).To(Equal(http.StatusAccepted))
+			Expect(recorder.Header().Get("Retry-After")).To(BeEmpty())
+		})
+	})
+})
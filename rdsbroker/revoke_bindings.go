@@ -0,0 +1,59 @@
+package rdsbroker
+
+import (
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// revokeBindings drops the users of a DB instance other than the master user
+// and ends their sessions, once RDS has accepted its deletion, so that as
+// little as possible is written while the final snapshot is taken. It isn't
+// done any earlier, as an instance whose deletion is refused is still in use.
+// Instances which can't be connected to are left as they are, and failures
+// are only logged, as the instance is going anyway.
+func (b *RDSBroker) revokeBindings(instanceID string, dbInstanceIdentifier string) {
+	if !b.revokeBindingsOnDeprovision {
+		return
+	}
+
+	if err := b.dropUsersAndConnections(instanceID, dbInstanceIdentifier); err != nil {
+		b.logger.Error("revoke-bindings.failed", err, lager.Data{
+			instanceIDLogKey:       instanceID,
+			"dbInstanceIdentifier": dbInstanceIdentifier,
+		})
+	}
+}
+
+func (b *RDSBroker) dropUsersAndConnections(instanceID string, dbInstanceIdentifier string) error {
+	dbInstance, err := b.dbInstance.Describe(dbInstanceIdentifier)
+	if err != nil {
+		return err
+	}
+
+	status := aws.StringValue(dbInstance.DBInstanceStatus)
+	if status != "available" && status != "deleting" {
+		b.logger.Info("revoke-bindings.skipped", lager.Data{
+			instanceIDLogKey: instanceID,
+			"status":         status,
+		})
+		return nil
+	}
+
+	sqlEngine, err := b.openSQLEngineForDBInstance(instanceID, b.dbNameFromDBInstance(instanceID, dbInstance), dbInstance)
+	if err != nil {
+		return err
+	}
+	defer sqlEngine.Close()
+
+	// Users are dropped first so that no new sessions can be opened once
+	// the existing ones are ended
+	if err := sqlEngine.ResetState(); err != nil {
+		return err
+	}
+	if err := sqlEngine.TerminateConnections(); err != nil {
+		return err
+	}
+
+	b.logger.Info("revoke-bindings.done", lager.Data{instanceIDLogKey: instanceID})
+	return nil
+}
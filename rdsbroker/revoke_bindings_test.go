@@ -0,0 +1,144 @@
+package rdsbroker_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+	sqlfake "github.com/alphagov/paas-rds-broker/sqlengine/fakes"
+)
+
+var _ = Describe("Revoking bindings on deprovision", func() {
+	var (
+		rdsInstance        *rdsfake.FakeRDSInstance
+		sqlEngine          *sqlfake.FakeSQLEngine
+		rdsBroker          *RDSBroker
+		revokeBindings     bool
+		softDeleteDays     uint
+		deprovisionDetails domain.DeprovisionDetails
+	)
+
+	BeforeEach(func() {
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		dbInstance := testDBInstance()
+		dbInstance.DBInstanceIdentifier = aws.String("cf-instance-id")
+		rdsInstance.DescribeReturns(dbInstance, nil)
+		sqlEngine = &sqlfake.FakeSQLEngine{}
+		revokeBindings = true
+		softDeleteDays = 0
+		deprovisionDetails = domain.DeprovisionDetails{
+			ServiceID: "Service-1",
+			PlanID:    "Plan-1",
+		}
+	})
+
+	JustBeforeEach(func() {
+		config := testConfig()
+		config.RevokeBindingsOnDeprovision = revokeBindings
+		config.SoftDeleteDays = softDeleteDays
+		rdsBroker = newTestBroker(config, rdsInstance, sqlEngine, lagertest.NewTestLogger("revoke_bindings_test"))
+	})
+
+	It("drops the users and ends their sessions once the instance's deletion is accepted", func() {
+		rdsInstance.DeleteStub = func(string, bool) error {
+			Expect(sqlEngine.OpenCallCount()).To(BeZero())
+			return nil
+		}
+
+		_, err := rdsBroker.Deprovision(context.Background(), "instance-id", deprovisionDetails, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+		Expect(sqlEngine.ResetStateCallCount()).ToNot(BeZero())
+		Expect(sqlEngine.TerminateConnectionsCallCount()).ToNot(BeZero())
+		address, _, _, username, _ := sqlEngine.OpenArgsForCall(0)
+		Expect(address).To(Equal("endpoint-address"))
+		Expect(username).To(Equal("master-username"))
+		Expect(sqlEngine.CloseCallCount()).ToNot(BeZero())
+	})
+
+	It("leaves the users alone if the instance's deletion is refused", func() {
+		rdsInstance.DeleteReturns(errors.New("operation failed"))
+
+		_, err := rdsBroker.Deprovision(context.Background(), "instance-id", deprovisionDetails, true)
+		Expect(err).To(MatchError("operation failed"))
+		Expect(sqlEngine.OpenCallCount()).To(BeZero())
+	})
+
+	It("leaves instances which can't be connected to alone", func() {
+		rdsInstance.DescribeReturns(&rds.DBInstance{
+			DBInstanceIdentifier: aws.String("cf-instance-id"),
+			DBInstanceStatus:     aws.String("stopped"),
+		}, nil)
+
+		_, err := rdsBroker.Deprovision(context.Background(), "instance-id", deprovisionDetails, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sqlEngine.OpenCallCount()).To(BeZero())
+		Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+	})
+
+	Context("when the sessions can't be ended", func() {
+		BeforeEach(func() {
+			sqlEngine.TerminateConnectionsReturns(errors.New("permission denied"))
+		})
+
+		It("still deprovisions the instance", func() {
+			_, err := rdsBroker.Deprovision(context.Background(), "instance-id", deprovisionDetails, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when instances are soft deleted", func() {
+		BeforeEach(func() {
+			softDeleteDays = 7
+		})
+
+		It("keeps the bindings of the soft deleted instance", func() {
+			_, err := rdsBroker.Deprovision(context.Background(), "instance-id", deprovisionDetails, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+			Expect(sqlEngine.OpenCallCount()).To(BeZero())
+		})
+
+		It("revokes them once the instance is purged", func() {
+			rdsInstance.DescribeByTagReturns([]*rds.DBInstance{{
+				DBInstanceIdentifier: aws.String("cf-instance-id-deleted"),
+				DBInstanceArn:        aws.String("arn:aws:rds:rds-region:123456789012:db:cf-instance-id-deleted"),
+				DBInstanceStatus:     aws.String("available"),
+			}}, nil)
+			rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+				awsrds.TagPlanID:     "Plan-1",
+				awsrds.TagPurgeAfter: time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+			}), nil)
+
+			Expect(rdsBroker.ProcessSoftDeletedInstances()).To(Succeed())
+			Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+			Expect(rdsInstance.DescribeArgsForCall(rdsInstance.DescribeCallCount() - 1)).To(Equal("cf-instance-id-deleted"))
+			Expect(sqlEngine.ResetStateCallCount()).ToNot(BeZero())
+			Expect(sqlEngine.TerminateConnectionsCallCount()).ToNot(BeZero())
+		})
+	})
+
+	Context("when it isn't enabled", func() {
+		BeforeEach(func() {
+			revokeBindings = false
+		})
+
+		It("doesn't connect to the instance", func() {
+			_, err := rdsBroker.Deprovision(context.Background(), "instance-id", deprovisionDetails, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sqlEngine.OpenCallCount()).To(BeZero())
+			Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,181 @@
+package rdsbroker
+
+import (
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/pivotal-cf/brokerapi/v9/domain/apiresponses"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// The steps of an update which are held back for the instance's maintenance
+// window when it is scheduled there. They are recorded in its Scheduled
+// Maintenance tag and run by housekeeping in this order.
+const (
+	scheduledStepModify           = "modify"
+	scheduledStepReboot           = "reboot"
+	scheduledStepFailover         = "failover"
+	scheduledStepCreateExtensions = "create-extensions"
+)
+
+var scheduledStepSequence = []string{scheduledStepModify, scheduledStepReboot, scheduledStepFailover, scheduledStepCreateExtensions}
+
+func unpackScheduledSteps(tag string) []string {
+	if tag == "" {
+		return nil
+	}
+	return strings.Split(tag, ":")
+}
+
+// packScheduledSteps puts the steps into the order they're run in, once
+// each. A failover reboots the instance, so it stands in for a reboot.
+func packScheduledSteps(steps []string) string {
+	packed := []string{}
+	for _, step := range scheduledStepSequence {
+		if step == scheduledStepReboot && containsString(steps, scheduledStepFailover) {
+			continue
+		}
+		if containsString(steps, step) {
+			packed = append(packed, step)
+		}
+	}
+	return strings.Join(packed, ":")
+}
+
+// holdBackDisruptiveChanges takes the changes which take the instance down
+// out of a modification, so that the rest can be applied straight away, and
+// returns the steps which will make them in the maintenance window. The
+// parameter group is swapped by the scheduled modification rather than
+// straight away as the swap only takes effect on a reboot, after which any
+// newly enabled extensions can be created.
+func holdBackDisruptiveChanges(modifyDBInstanceInput *rds.ModifyDBInstanceInput, existingInstance *rds.DBInstance, updateParameters UpdateParameters) []string {
+	steps := []string{}
+	if modifyDBInstanceInput.DBInstanceClass != nil && aws.StringValue(modifyDBInstanceInput.DBInstanceClass) != aws.StringValue(existingInstance.DBInstanceClass) {
+		modifyDBInstanceInput.DBInstanceClass = existingInstance.DBInstanceClass
+		steps = append(steps, scheduledStepModify)
+	}
+	previousParameterGroup := existingInstance.DBParameterGroups[0].DBParameterGroupName
+	if aws.StringValue(modifyDBInstanceInput.DBParameterGroupName) != aws.StringValue(previousParameterGroup) {
+		modifyDBInstanceInput.DBParameterGroupName = previousParameterGroup
+		steps = append(steps, scheduledStepModify, scheduledStepReboot, scheduledStepCreateExtensions)
+	}
+	if aws.BoolValue(updateParameters.Reboot) {
+		if aws.BoolValue(updateParameters.ForceFailover) {
+			steps = append(steps, scheduledStepFailover)
+		} else {
+			steps = append(steps, scheduledStepReboot)
+		}
+	}
+	return steps
+}
+
+// runScheduledMaintenance is the housekeeping job which makes the changes
+// held back for instances' maintenance windows. One step is run for each
+// instance which is available inside its window, as each step leaves the
+// instance busy until a later run.
+func (b *RDSBroker) runScheduledMaintenance(dbInstances []managedDBInstance) error {
+	now := time.Now()
+	for _, instance := range dbInstances {
+		steps := unpackScheduledSteps(instance.tagsByName[awsrds.TagScheduledMaintenance])
+		if len(steps) == 0 || instance.tagsByName[awsrds.TagPurgeAfter] != "" {
+			continue
+		}
+		if aws.StringValue(instance.dbInstance.DBInstanceStatus) != "available" {
+			continue
+		}
+		open, err := maintenanceWindowOpen(aws.StringValue(instance.dbInstance.PreferredMaintenanceWindow), now)
+		if err != nil {
+			b.logger.Error("scheduled-maintenance.parse-maintenance-window", err, lager.Data{dbInstanceLogKey: instance.dbInstance.DBInstanceIdentifier})
+			continue
+		}
+		if !open {
+			continue
+		}
+
+		// a step which fails is tried again on the next run
+		if err := b.runScheduledStep(instance, steps); err != nil {
+			b.logger.Error("scheduled-maintenance.run", err, lager.Data{
+				dbInstanceLogKey: instance.dbInstance.DBInstanceIdentifier,
+				"step":           steps[0],
+			})
+		}
+	}
+	return nil
+}
+
+// runScheduledStep runs the first of an instance's scheduled steps, under
+// the operation lock, and records the rest. An instance with another
+// operation in progress is left for the next run.
+func (b *RDSBroker) runScheduledStep(instance managedDBInstance, steps []string) error {
+	dbInstanceIdentifier := aws.StringValue(instance.dbInstance.DBInstanceIdentifier)
+	instanceID := b.dbInstanceIdentifierToServiceInstanceID(dbInstanceIdentifier)
+	release, err := b.acquireOperationLock(instanceID)
+	if err == apiresponses.ErrConcurrentInstanceAccess {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	b.logger.Info("scheduled-maintenance.run", lager.Data{
+		dbInstanceLogKey: dbInstanceIdentifier,
+		"step":           steps[0],
+	})
+	switch steps[0] {
+	case scheduledStepModify:
+		err = b.applyScheduledModification(instanceID, instance.tagsByName)
+	case scheduledStepReboot, scheduledStepFailover:
+		err = b.dbInstance.Reboot(&rds.RebootDBInstanceInput{
+			DBInstanceIdentifier: aws.String(dbInstanceIdentifier),
+			ForceFailover:        aws.Bool(steps[0] == scheduledStepFailover),
+		})
+	case scheduledStepCreateExtensions:
+		err = b.ensureCreateExtensions(instanceID, instance.dbInstance, instance.tagsByName)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(steps) == 1 {
+		return b.removeTag(dbInstanceIdentifier, awsrds.TagScheduledMaintenance)
+	}
+	b.writeTags(instanceID, aws.StringValue(instance.dbInstance.DBInstanceArn), map[string]string{
+		awsrds.TagScheduledMaintenance: packScheduledSteps(steps[1:]),
+	})
+	return nil
+}
+
+// applyScheduledModification moves the instance to its plan's instance
+// class and the parameter group for its plan and extensions, the changes
+// held back by holdBackDisruptiveChanges.
+func (b *RDSBroker) applyScheduledModification(instanceID string, tagsByName map[string]string) error {
+	servicePlan, ok := b.catalog.FindServicePlan(tagsByName[awsrds.TagPlanID])
+	if !ok {
+		return newUserError(ErrCodePlanNotFound, "Service Plan '%s' not found", tagsByName[awsrds.TagPlanID])
+	}
+
+	var extensions, pgauditLog []string
+	if extensionsTag := tagsByName[awsrds.TagExtensions]; extensionsTag != "" {
+		extensions = unpackExtensions(extensionsTag)
+	}
+	if pgauditLogTag := tagsByName[awsrds.TagPgauditLog]; pgauditLogTag != "" {
+		pgauditLog = strings.Split(pgauditLogTag, ":")
+	}
+	parameterGroupName, err := b.parameterGroupsSelector.SelectParameterGroup(servicePlan, extensions, pgauditLog)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.dbInstance.Modify(&rds.ModifyDBInstanceInput{
+		DBInstanceIdentifier: aws.String(b.dbInstanceIdentifier(instanceID)),
+		DBInstanceClass:      servicePlan.RDSProperties.DBInstanceClass,
+		DBParameterGroupName: aws.String(parameterGroupName),
+		ApplyImmediately:     aws.Bool(true),
+	})
+	return err
+}
@@ -0,0 +1,131 @@
+package rdsbroker_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+	"github.com/alphagov/paas-rds-broker/rdsbroker/fakes"
+	sqlfake "github.com/alphagov/paas-rds-broker/sqlengine/fakes"
+)
+
+var _ = Describe("Scheduled maintenance", func() {
+	var (
+		rdsInstance        *rdsfake.FakeRDSInstance
+		paramGroupSelector *fakes.FakeParameterGroupSelector
+		sqlEngine          *sqlfake.FakeSQLEngine
+		rdsBroker          *RDSBroker
+		dbInstance         *rds.DBInstance
+		tags               map[string]string
+	)
+
+	const dbInstanceArn = "arn:aws:rds:rds-region:1234567890:db:cf-instance-id"
+
+	BeforeEach(func() {
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		paramGroupSelector = &fakes.FakeParameterGroupSelector{}
+		paramGroupSelector.SelectParameterGroupReturns("rdsbroker-postgres13-pgaudit", nil)
+		sqlEngine = &sqlfake.FakeSQLEngine{}
+		config := testConfig()
+		config.Catalog.Services[0].Plans[0].RDSProperties.DBInstanceClass = aws.String("db.m5.large")
+		sqlProvider := &sqlfake.FakeProvider{}
+		sqlProvider.GetSQLEngineReturns(sqlEngine, nil)
+		rdsBroker = New(config, rdsInstance, sqlProvider, paramGroupSelector, lagertest.NewTestLogger("scheduled_maintenance_test"))
+
+		dbInstance = testDBInstance()
+		dbInstance.DBInstanceIdentifier = aws.String("cf-instance-id")
+		dbInstance.DBInstanceArn = aws.String(dbInstanceArn)
+		dbInstance.DBInstanceClass = aws.String("db.t3.micro")
+		dbInstance.EngineVersion = aws.String("13.7")
+		dbInstance.PreferredMaintenanceWindow = aws.String(maintenanceWindowAround(time.Now()))
+		tags = map[string]string{
+			"Broker Name":                   "mybroker",
+			"Plan ID":                       "Plan-1",
+			awsrds.TagExtensions:            "pgaudit",
+			awsrds.TagPgauditLog:            "ddl:role",
+			awsrds.TagScheduledMaintenance:  "modify:reboot:create-extensions",
+			awsrds.TagScheduleAtMaintenance: "true",
+		}
+		rdsInstance.DescribeByTagStub = func(key, value string, opts ...awsrds.DescribeOption) ([]*rds.DBInstance, error) {
+			return []*rds.DBInstance{dbInstance}, nil
+		}
+		rdsInstance.GetResourceTagsStub = func(arn string, opts ...awsrds.DescribeOption) ([]*rds.Tag, error) {
+			return awsrds.BuildRDSTags(tags), nil
+		}
+	})
+
+	scheduledMaintenanceWrites := func() []string {
+		writes := []string{}
+		for i := 0; i < rdsInstance.AddTagsToResourceCallCount(); i++ {
+			_, rdsTags := rdsInstance.AddTagsToResourceArgsForCall(i)
+			if steps, ok := awsrds.RDSTagsValues(rdsTags)[awsrds.TagScheduledMaintenance]; ok {
+				writes = append(writes, steps)
+			}
+		}
+		return writes
+	}
+
+	It("applies the held back instance class and parameter group first", func() {
+		Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+		Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
+		Expect(rdsInstance.ModifyArgsForCall(0)).To(Equal(&rds.ModifyDBInstanceInput{
+			DBInstanceIdentifier: aws.String("cf-instance-id"),
+			DBInstanceClass:      aws.String("db.m5.large"),
+			DBParameterGroupName: aws.String("rdsbroker-postgres13-pgaudit"),
+			ApplyImmediately:     aws.Bool(true),
+		}))
+		_, extensions, pgauditLog := paramGroupSelector.SelectParameterGroupArgsForCall(0)
+		Expect(extensions).To(Equal([]string{"pgaudit"}))
+		Expect(pgauditLog).To(Equal([]string{"ddl", "role"}))
+
+		Expect(rdsInstance.RebootCallCount()).To(Equal(0))
+		Expect(scheduledMaintenanceWrites()).To(Equal([]string{"reboot:create-extensions"}))
+	})
+
+	It("reboots the instance once the modification has been applied", func() {
+		tags[awsrds.TagScheduledMaintenance] = "failover:create-extensions"
+
+		Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+		Expect(rdsInstance.RebootCallCount()).To(Equal(1))
+		Expect(rdsInstance.RebootArgsForCall(0)).To(Equal(&rds.RebootDBInstanceInput{
+			DBInstanceIdentifier: aws.String("cf-instance-id"),
+			ForceFailover:        aws.Bool(true),
+		}))
+		Expect(scheduledMaintenanceWrites()).To(Equal([]string{"create-extensions"}))
+	})
+
+	It("creates the extensions last and removes the tag", func() {
+		tags[awsrds.TagScheduledMaintenance] = "create-extensions"
+
+		Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+		Expect(sqlEngine.CreateExtensionsCallCount()).ToNot(BeZero())
+		Expect(rdsInstance.RemoveTagCallCount()).To(Equal(1))
+		_, key := rdsInstance.RemoveTagArgsForCall(0)
+		Expect(key).To(Equal(awsrds.TagScheduledMaintenance))
+	})
+
+	It("waits for the instance's maintenance window", func() {
+		dbInstance.PreferredMaintenanceWindow = aws.String(maintenanceWindowAround(time.Now().Add(72 * time.Hour)))
+
+		Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+		Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+		Expect(scheduledMaintenanceWrites()).To(BeEmpty())
+	})
+
+	It("waits for the instance to be available", func() {
+		dbInstance.DBInstanceStatus = aws.String("modifying")
+
+		Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+		Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+	})
+})
@@ -0,0 +1,72 @@
+package rdsbroker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+)
+
+// ServiceAdvisoryConfig is a note for users which is added to the
+// descriptions of operations and the parameters of instances, such as
+// during a regional RDS impairment, so that they don't report AWS-wide
+// problems as incidents with their own instances. It can be limited to
+// instances of some engines, and stops being shown after Until.
+type ServiceAdvisoryConfig struct {
+	Message string   `json:"message"`
+	Until   string   `json:"until"`
+	Engines []string `json:"engines"`
+}
+
+func (c ServiceAdvisoryConfig) Validate() error {
+	if c.Message == "" {
+		return errors.New("Must provide a non-empty Message")
+	}
+	if c.Until != "" {
+		if _, err := time.Parse(time.RFC3339, c.Until); err != nil {
+			return fmt.Errorf("Until must be an RFC3339 time: %s", err)
+		}
+	}
+	return nil
+}
+
+// serviceAdvisoryFor is the message of the service advisory which applies
+// to instances of the engine at the time, or empty if there isn't one.
+func (b *RDSBroker) serviceAdvisoryFor(engine string, now time.Time) string {
+	if b.serviceAdvisory == nil {
+		return ""
+	}
+	if b.serviceAdvisory.Until != "" {
+		until, err := time.Parse(time.RFC3339, b.serviceAdvisory.Until)
+		if err == nil && now.After(until) {
+			return ""
+		}
+	}
+	if len(b.serviceAdvisory.Engines) > 0 && !containsString(b.serviceAdvisory.Engines, engine) {
+		return ""
+	}
+	return b.serviceAdvisory.Message
+}
+
+// withServiceAdvisory adds the service advisory for the engine of the
+// instance's plan to the description of an operation. It is added to each
+// response rather than to cached ones, so that it disappears as soon as it
+// expires. Polls without a known plan only get advisories for every engine.
+func (b *RDSBroker) withServiceAdvisory(lastOperation domain.LastOperation, planID string) domain.LastOperation {
+	engine := ""
+	if servicePlan, ok := b.catalog.FindServicePlan(planID); ok {
+		engine = aws.StringValue(servicePlan.RDSProperties.Engine)
+	}
+	advisory := b.serviceAdvisoryFor(engine, time.Now())
+	if advisory == "" {
+		return lastOperation
+	}
+	if lastOperation.Description == "" {
+		lastOperation.Description = "Service advisory: " + advisory
+	} else {
+		lastOperation.Description += ". Service advisory: " + advisory
+	}
+	return lastOperation
+}
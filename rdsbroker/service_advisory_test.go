@@ -0,0 +1,125 @@
+package rdsbroker_test
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+)
+
+var _ = Describe("Service advisories", func() {
+	var (
+		rdsInstance     *rdsfake.FakeRDSInstance
+		serviceAdvisory *ServiceAdvisoryConfig
+		rdsBroker       *RDSBroker
+		dbInstance      *rds.DBInstance
+		pollDetails     domain.PollDetails
+	)
+
+	BeforeEach(func() {
+		serviceAdvisory = &ServiceAdvisoryConfig{
+			Message: "AWS is investigating increased RDS API error rates in eu-west-1",
+		}
+		dbInstance = testDBInstance()
+		dbInstance.DBInstanceStatus = aws.String("modifying")
+		pollDetails = domain.PollDetails{ServiceID: "Service-1", PlanID: "Plan-1"}
+
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		rdsInstance.DescribeReturns(dbInstance, nil)
+		rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+			"Broker Name": "mybroker",
+			"Plan ID":     "Plan-1",
+		}), nil)
+	})
+
+	JustBeforeEach(func() {
+		config := testConfig()
+		config.ServiceAdvisory = serviceAdvisory
+		config.FillDefaults()
+		rdsBroker = newTestBroker(config, rdsInstance, nil, lagertest.NewTestLogger("service_advisory_test"))
+	})
+
+	It("adds the advisory to the descriptions of operations", func() {
+		lastOperation, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lastOperation.State).To(Equal(domain.InProgress))
+		Expect(lastOperation.Description).To(Equal(
+			"DB Instance 'cf-instance-1' status is 'modifying'. Service advisory: AWS is investigating increased RDS API error rates in eu-west-1",
+		))
+	})
+
+	It("adds the advisory to the parameters of instances", func() {
+		instance, err := rdsBroker.GetInstance(context.Background(), "instance-1", domain.FetchInstanceDetails{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(instance.Parameters).To(HaveKeyWithValue("service_advisory", "AWS is investigating increased RDS API error rates in eu-west-1"))
+	})
+
+	It("gives the advisory along with errors while AWS is unavailable", func() {
+		rdsBroker.SetAWSAvailability(&fakeAWSAvailability{available: false})
+
+		_, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Service advisory: AWS is investigating increased RDS API error rates in eu-west-1"))
+	})
+
+	Context("when the advisory has expired", func() {
+		BeforeEach(func() {
+			serviceAdvisory.Until = time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+		})
+
+		It("isn't shown", func() {
+			lastOperation, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lastOperation.Description).To(Equal("DB Instance 'cf-instance-1' status is 'modifying'"))
+
+			instance, err := rdsBroker.GetInstance(context.Background(), "instance-1", domain.FetchInstanceDetails{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instance.Parameters).ToNot(HaveKey("service_advisory"))
+		})
+	})
+
+	Context("when the advisory is for other engines", func() {
+		BeforeEach(func() {
+			serviceAdvisory.Engines = []string{"mysql"}
+		})
+
+		It("isn't shown", func() {
+			lastOperation, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lastOperation.Description).ToNot(ContainSubstring("Service advisory"))
+
+			instance, err := rdsBroker.GetInstance(context.Background(), "instance-1", domain.FetchInstanceDetails{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instance.Parameters).ToNot(HaveKey("service_advisory"))
+		})
+
+		It("isn't given along with errors while AWS is unavailable", func() {
+			rdsBroker.SetAWSAvailability(&fakeAWSAvailability{available: false})
+
+			_, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).ToNot(ContainSubstring("Service advisory"))
+		})
+	})
+
+	Context("without an advisory", func() {
+		BeforeEach(func() {
+			serviceAdvisory = nil
+		})
+
+		It("leaves descriptions alone", func() {
+			lastOperation, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lastOperation.Description).To(Equal("DB Instance 'cf-instance-1' status is 'modifying'"))
+		})
+	})
+})
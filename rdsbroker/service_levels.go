@@ -0,0 +1,63 @@
+package rdsbroker
+
+import (
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// serviceLevelTags are the tags recording the service level of the plan of
+// a DB instance, so billing and support tooling can tell apart instances on
+// plans which only differ in it.
+func (sp ServicePlan) serviceLevelTags() map[string]string {
+	tags := map[string]string{}
+	if sp.SLATier != "" {
+		tags[awsrds.TagSLATier] = sp.SLATier
+	}
+	if sp.SupportLevel != "" {
+		tags[awsrds.TagSupportLevel] = sp.SupportLevel
+	}
+	return tags
+}
+
+// removeStaleServiceLevelTags removes the service level tags of the plan an
+// instance is being updated from which its new plan doesn't have.
+func (b *RDSBroker) removeStaleServiceLevelTags(instanceID string, servicePlan ServicePlan, tagsByName map[string]string) error {
+	newTags := servicePlan.serviceLevelTags()
+	for _, tagKey := range []string{awsrds.TagSLATier, awsrds.TagSupportLevel} {
+		if _, ok := newTags[tagKey]; ok || tagsByName[tagKey] == "" {
+			continue
+		}
+		if err := b.removeTag(b.dbInstanceIdentifier(instanceID), tagKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withServiceLevelMetadata adds the service level of each plan to its
+// catalog metadata, alongside any costs it is configured with.
+func (c Catalog) withServiceLevelMetadata(services []domain.Service) {
+	for i := range services {
+		for j := range services[i].Plans {
+			plan := &services[i].Plans[j]
+			servicePlan, found := c.FindServicePlan(plan.ID)
+			if !found || (servicePlan.SLATier == "" && servicePlan.SupportLevel == "") {
+				continue
+			}
+
+			if plan.Metadata == nil {
+				plan.Metadata = &domain.ServicePlanMetadata{}
+			}
+			if plan.Metadata.AdditionalMetadata == nil {
+				plan.Metadata.AdditionalMetadata = map[string]interface{}{}
+			}
+			if servicePlan.SLATier != "" {
+				plan.Metadata.AdditionalMetadata["slaTier"] = servicePlan.SLATier
+			}
+			if servicePlan.SupportLevel != "" {
+				plan.Metadata.AdditionalMetadata["supportLevel"] = servicePlan.SupportLevel
+			}
+		}
+	}
+}
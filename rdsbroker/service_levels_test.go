@@ -0,0 +1,92 @@
+package rdsbroker_test
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v9"
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+	sqlfake "github.com/alphagov/paas-rds-broker/sqlengine/fakes"
+)
+
+var _ = Describe("Plan service levels", func() {
+	var (
+		rdsInstance *rdsfake.FakeRDSInstance
+		rdsBroker   *RDSBroker
+	)
+
+	BeforeEach(func() {
+		config := testConfig()
+		plans := config.Catalog.Services[0].Plans
+		plans[1] = ServicePlan{
+			ID:   "Plan-2",
+			Name: "small-ha-support",
+			Metadata: &brokerapi.ServicePlanMetadata{
+				DisplayName: "Small with support",
+				Costs: []brokerapi.ServicePlanCost{
+					{Amount: map[string]float64{"gbp": 50}, Unit: "MONTHLY"},
+				},
+			},
+			RDSProperties: plans[0].RDSProperties,
+			SLATier:       "gold",
+			SupportLevel:  "24x7",
+		}
+		config.FillDefaults()
+
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		rdsBroker = newTestBroker(config, rdsInstance, &sqlfake.FakeSQLEngine{}, lagertest.NewTestLogger("service_levels_test"))
+	})
+
+	It("adds the service level of plans to their catalog metadata", func() {
+		services, err := rdsBroker.Services(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(services[0].Plans).To(HaveLen(2))
+
+		Expect(services[0].Plans[0].Metadata).To(BeNil())
+
+		metadata := services[0].Plans[1].Metadata
+		Expect(metadata.DisplayName).To(Equal("Small with support"))
+		Expect(metadata.Costs).To(HaveLen(1))
+		Expect(metadata.AdditionalMetadata).To(Equal(map[string]interface{}{
+			"slaTier":      "gold",
+			"supportLevel": "24x7",
+		}))
+	})
+
+	It("tags DB instances with the service level of their plan", func() {
+		details := domain.ProvisionDetails{
+			ServiceID:        "Service-1",
+			PlanID:           "Plan-2",
+			OrganizationGUID: "organization-id",
+			SpaceGUID:        "space-id",
+		}
+		_, err := rdsBroker.Provision(context.Background(), "instance-1", details, true)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(rdsInstance.CreateCallCount()).To(Equal(1))
+		tagsByName := awsrds.RDSTagsValues(rdsInstance.CreateArgsForCall(0).Tags)
+		Expect(tagsByName).To(HaveKeyWithValue("SLA Tier", "gold"))
+		Expect(tagsByName).To(HaveKeyWithValue("Support Level", "24x7"))
+	})
+
+	It("doesn't tag DB instances on plans without a service level", func() {
+		details := domain.ProvisionDetails{
+			ServiceID:        "Service-1",
+			PlanID:           "Plan-1",
+			OrganizationGUID: "organization-id",
+			SpaceGUID:        "space-id",
+		}
+		_, err := rdsBroker.Provision(context.Background(), "instance-1", details, true)
+		Expect(err).ToNot(HaveOccurred())
+
+		tagsByName := awsrds.RDSTagsValues(rdsInstance.CreateArgsForCall(0).Tags)
+		Expect(tagsByName).ToNot(HaveKey("SLA Tier"))
+		Expect(tagsByName).ToNot(HaveKey("Support Level"))
+	})
+})
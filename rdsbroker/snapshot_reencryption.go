@@ -0,0 +1,167 @@
+package rdsbroker
+
+import (
+	"fmt"
+	"reflect"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+	"github.com/pivotal-cf/brokerapi/v9/domain/apiresponses"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// defaultRDSKmsKeyID is the AWS managed key RDS uses when a plan doesn't
+// name one. It has to be given explicitly when copying a snapshot, as leaving
+// the key out keeps the source snapshot's key.
+const defaultRDSKmsKeyID = "alias/aws/rds"
+
+const reencryptedSnapshotSuffix = "-reencrypted"
+
+// operationDataReencryptedRestore is returned as the operation data of a
+// provision which is restoring from a snapshot being re-encrypted, so that
+// its polls know to look for the copy while there is no DB instance yet.
+const operationDataReencryptedRestore = "reencrypted-restore"
+
+// plansDifferOnlyInKmsKey reports whether restoring data from an instance on
+// fromPlan into toPlan only needs the data re-encrypting with toPlan's key.
+func plansDifferOnlyInKmsKey(fromPlan, toPlan ServicePlan) bool {
+	if !aws.BoolValue(toPlan.RDSProperties.StorageEncrypted) {
+		return false
+	}
+	if reflect.DeepEqual(fromPlan.RDSProperties.KmsKeyID, toPlan.RDSProperties.KmsKeyID) {
+		return false
+	}
+
+	fromProperties := fromPlan.RDSProperties
+	toProperties := toPlan.RDSProperties
+	fromProperties.KmsKeyID = nil
+	toProperties.KmsKeyID = nil
+	return reflect.DeepEqual(fromProperties, toProperties)
+}
+
+func (b *RDSBroker) isKmsKeyOnlyPlanChange(fromPlanID, toPlanID string) bool {
+	fromPlan, ok := b.catalog.FindServicePlan(fromPlanID)
+	if !ok {
+		return false
+	}
+	toPlan, ok := b.catalog.FindServicePlan(toPlanID)
+	if !ok {
+		return false
+	}
+	return plansDifferOnlyInKmsKey(fromPlan, toPlan)
+}
+
+func (b *RDSBroker) reencryptedSnapshotIdentifier(instanceID string) string {
+	return b.dbInstanceIdentifier(instanceID) + reencryptedSnapshotSuffix
+}
+
+// copySnapshotForRestore starts copying snapshot under the service plan's
+// KMS key. RDS can't change the key of an instance or of a restore, so the
+// restore itself has to wait until LastOperation finds the copy available.
+// The copy carries the tags the restored instance should have, which is all
+// we need to finish the restore from there.
+func (b *RDSBroker) copySnapshotForRestore(instanceID string, snapshot *rds.DBSnapshot, servicePlan ServicePlan, restoreDBInstanceInput *rds.RestoreDBInstanceFromDBSnapshotInput) error {
+	kmsKeyID := servicePlan.RDSProperties.KmsKeyID
+	if kmsKeyID == nil {
+		kmsKeyID = aws.String(defaultRDSKmsKeyID)
+	}
+
+	b.logger.Info("copy-snapshot-for-restore", lager.Data{
+		instanceIDLogKey:     instanceID,
+		"snapshotIdentifier": snapshot.DBSnapshotIdentifier,
+		"kmsKeyID":           kmsKeyID,
+	})
+
+	return b.dbInstance.CopySnapshot(&rds.CopyDBSnapshotInput{
+		SourceDBSnapshotIdentifier: snapshot.DBSnapshotArn,
+		TargetDBSnapshotIdentifier: aws.String(b.reencryptedSnapshotIdentifier(instanceID)),
+		KmsKeyId:                   kmsKeyID,
+		Tags:                       restoreDBInstanceInput.Tags,
+	})
+}
+
+// restoreFromReencryptedSnapshot is used by LastOperation when there is no DB
+// instance yet, to drive a restore which is waiting on a snapshot copy. It
+// returns apiresponses.ErrInstanceDoesNotExist if there is no such copy.
+//
+// The copy is deleted once the restore has started. If that fails it is left
+// to be removed along with the instance's other snapshots, as it is tagged
+// like them.
+func (b *RDSBroker) restoreFromReencryptedSnapshot(instanceID string) (domain.LastOperation, error) {
+	snapshot, err := b.dbInstance.DescribeSnapshot(b.reencryptedSnapshotIdentifier(instanceID))
+	if err != nil {
+		if err == awsrds.ErrDBSnapshotDoesNotExist {
+			err = apiresponses.ErrInstanceDoesNotExist
+		}
+		return domain.LastOperation{State: domain.Failed}, err
+	}
+
+	status := aws.StringValue(snapshot.Status)
+	switch status {
+	case "available":
+	case "creating", "copying":
+		return domain.LastOperation{
+			State:       domain.InProgress,
+			Description: fmt.Sprintf("Snapshot '%s' is being re-encrypted", aws.StringValue(snapshot.DBSnapshotIdentifier)),
+		}, nil
+	default:
+		return domain.LastOperation{
+			State:       domain.Failed,
+			Description: fmt.Sprintf("Re-encrypting snapshot '%s' failed with status '%s'", aws.StringValue(snapshot.DBSnapshotIdentifier), status),
+		}, nil
+	}
+
+	tags, err := b.dbInstance.GetResourceTags(aws.StringValue(snapshot.DBSnapshotArn))
+	if err != nil {
+		return domain.LastOperation{State: domain.Failed}, err
+	}
+	tagsByName := awsrds.RDSTagsValues(tags)
+
+	servicePlan, ok := b.catalog.FindServicePlan(tagsByName[awsrds.TagPlanID])
+	if !ok {
+		return domain.LastOperation{State: domain.Failed}, fmt.Errorf("Service Plan '%s' not found", tagsByName[awsrds.TagPlanID])
+	}
+
+	provisionParameters := ProvisionParameters{}
+	if skipFinalSnapshot, ok := tagsByName[awsrds.TagSkipFinalSnapshot]; ok {
+		provisionParameters.SkipFinalSnapshot = aws.Bool(skipFinalSnapshot == "true")
+	}
+	if extensions, ok := tagsByName[awsrds.TagExtensions]; ok && extensions != "" {
+		provisionParameters.Extensions = unpackExtensions(extensions)
+	}
+	provisionParameters.AdditionalDatabases = unpackAdditionalDatabases(tagsByName[awsrds.TagAdditionalDatabases])
+	details := domain.ProvisionDetails{
+		ServiceID:        tagsByName[awsrds.TagServiceID],
+		PlanID:           tagsByName[awsrds.TagPlanID],
+		OrganizationGUID: tagsByName[awsrds.TagOrganizationID],
+		SpaceGUID:        tagsByName[awsrds.TagSpaceID],
+	}
+
+	restoreDBInstanceInput, err := b.restoreDBInstanceInput(instanceID, snapshot, servicePlan, provisionParameters, details)
+	if err != nil {
+		return domain.LastOperation{State: domain.Failed}, err
+	}
+	// keep the tags recorded against the original snapshot rather than
+	// describing the copy as the origin
+	restoreDBInstanceInput.Tags = tags
+
+	if err := b.dbInstance.Restore(restoreDBInstanceInput); err != nil {
+		b.cleanUpOrphanedDBInstance(instanceID, err)
+		return domain.LastOperation{State: domain.Failed}, err
+	}
+
+	if err := b.dbInstance.DeleteSnapshot(aws.StringValue(snapshot.DBSnapshotIdentifier)); err != nil {
+		b.logger.Error("delete-reencrypted-snapshot", err, lager.Data{
+			instanceIDLogKey:     instanceID,
+			"snapshotIdentifier": snapshot.DBSnapshotIdentifier,
+		})
+	}
+
+	return domain.LastOperation{
+		State:       domain.InProgress,
+		Description: fmt.Sprintf("DB Instance '%s' is being restored", b.dbInstanceIdentifier(instanceID)),
+	}, nil
+}
@@ -0,0 +1,256 @@
+package rdsbroker_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+	"github.com/pivotal-cf/brokerapi/v9/domain/apiresponses"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+	"github.com/alphagov/paas-rds-broker/rdsbroker/fakes"
+	sqlfake "github.com/alphagov/paas-rds-broker/sqlengine/fakes"
+)
+
+var _ = Describe("Restoring into a plan with a different KMS key", func() {
+	const (
+		instanceID           = "instance-id"
+		reencryptedSnapshot  = "cf-instance-id-reencrypted"
+		originSnapshotID     = "cf-origin-instance-1"
+		originSnapshotArn    = "arn:aws:rds:rds-region:1234567890:snapshot:cf-origin-instance-1"
+		reencryptedSnapshArn = "arn:aws:rds:rds-region:1234567890:snapshot:cf-instance-id-reencrypted"
+	)
+
+	var (
+		ctx              context.Context
+		rdsInstance      *rdsfake.FakeRDSInstance
+		rdsBroker        *RDSBroker
+		logger           lager.Logger
+		provisionDetails domain.ProvisionDetails
+		sourceTags       map[string]string
+	)
+
+	encryptedPlan := func(id string, kmsKeyID *string) ServicePlan {
+		return ServicePlan{
+			ID:          id,
+			Name:        id,
+			Description: id,
+			RDSProperties: RDSProperties{
+				DBInstanceClass:  aws.String("db.m1.test"),
+				Engine:           aws.String("mysql"),
+				EngineVersion:    aws.String("8.0"),
+				AllocatedStorage: aws.Int64(100),
+				StorageEncrypted: aws.Bool(true),
+				KmsKeyID:         kmsKeyID,
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		logger = lagertest.NewTestLogger("snapshot_reencryption_test")
+
+		largerPlan := encryptedPlan("larger-plan", aws.String("new-key"))
+		largerPlan.RDSProperties.AllocatedStorage = aws.Int64(200)
+
+		config := testConfig()
+		config.AllowUserProvisionParameters = true
+		config.Catalog.Services[0].Plans = []ServicePlan{
+			encryptedPlan("old-key-plan", aws.String("old-key")),
+			encryptedPlan("new-key-plan", aws.String("new-key")),
+			encryptedPlan("default-key-plan", nil),
+			largerPlan,
+		}
+		paramGroupSelector := &fakes.FakeParameterGroupSelector{}
+		paramGroupSelector.SelectParameterGroupReturns("cf-mysql80-mybroker", nil)
+		rdsBroker = New(config, rdsInstance, &sqlfake.FakeProvider{}, paramGroupSelector, logger)
+
+		provisionDetails = domain.ProvisionDetails{
+			ServiceID:        "Service-1",
+			PlanID:           "new-key-plan",
+			OrganizationGUID: "organization-id",
+			SpaceGUID:        "space-id",
+			RawParameters:    json.RawMessage(`{"restore_from_latest_snapshot_of": "origin-instance"}`),
+		}
+		sourceTags = map[string]string{
+			"Space ID":        "space-id",
+			"Organization ID": "organization-id",
+			"Plan ID":         "old-key-plan",
+		}
+
+		rdsInstance.DescribeSnapshotsReturns([]*rds.DBSnapshot{
+			{
+				DBSnapshotIdentifier: aws.String(originSnapshotID),
+				DBSnapshotArn:        aws.String(originSnapshotArn),
+				DBInstanceIdentifier: aws.String("cf-origin-instance"),
+			},
+		}, nil)
+		rdsInstance.DescribeReturns(&rds.DBInstance{
+			DBInstanceIdentifier:  aws.String("cf-origin-instance"),
+			DBInstanceArn:         aws.String("arn:aws:rds:rds-region:1234567890:db:cf-origin-instance"),
+			BackupRetentionPeriod: aws.Int64(7),
+		}, nil)
+	})
+
+	JustBeforeEach(func() {
+		rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(sourceTags), nil)
+	})
+
+	Describe("Provision", func() {
+		It("copies the snapshot under the new key instead of restoring it", func() {
+			provisionedServiceSpec, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(provisionedServiceSpec.OperationData).To(Equal("reencrypted-restore"))
+
+			Expect(rdsInstance.RestoreCallCount()).To(Equal(0))
+			Expect(rdsInstance.CopySnapshotCallCount()).To(Equal(1))
+			input := rdsInstance.CopySnapshotArgsForCall(0)
+			Expect(aws.StringValue(input.SourceDBSnapshotIdentifier)).To(Equal(originSnapshotArn))
+			Expect(aws.StringValue(input.TargetDBSnapshotIdentifier)).To(Equal(reencryptedSnapshot))
+			Expect(aws.StringValue(input.KmsKeyId)).To(Equal("new-key"))
+
+			tagsByName := awsrds.RDSTagsValues(input.Tags)
+			Expect(tagsByName).To(HaveKeyWithValue("Plan ID", "new-key-plan"))
+			Expect(tagsByName).To(HaveKeyWithValue("chargeable_entity", instanceID))
+			Expect(tagsByName).To(HaveKeyWithValue("Restored From Snapshot", originSnapshotID))
+		})
+
+		It("uses the AWS managed key if the plan doesn't specify one", func() {
+			provisionDetails.PlanID = "default-key-plan"
+
+			_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rdsInstance.CopySnapshotCallCount()).To(Equal(1))
+			input := rdsInstance.CopySnapshotArgsForCall(0)
+			Expect(aws.StringValue(input.KmsKeyId)).To(Equal("alias/aws/rds"))
+		})
+
+		It("still requires the plans to match apart from the key", func() {
+			provisionDetails.PlanID = "larger-plan"
+
+			_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, true)
+			Expect(err).To(MatchError("You must use the same plan as the service instance you are restoring from"))
+			Expect(rdsInstance.CopySnapshotCallCount()).To(Equal(0))
+		})
+
+		It("refuses point in time restores", func() {
+			provisionDetails.RawParameters = json.RawMessage(`{"restore_from_point_in_time_of": "origin-instance"}`)
+
+			_, err := rdsBroker.Provision(ctx, instanceID, provisionDetails, true)
+			Expect(err).To(MatchError(ContainSubstring("restore from a snapshot instead")))
+			Expect(rdsInstance.RestoreToPointInTimeCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("LastOperation", func() {
+		var (
+			pollDetails        domain.PollDetails
+			copiedSnapshot     *rds.DBSnapshot
+			copiedSnapshotTags []*rds.Tag
+		)
+
+		BeforeEach(func() {
+			pollDetails = domain.PollDetails{ServiceID: "Service-1", PlanID: "new-key-plan", OperationData: "reencrypted-restore"}
+			rdsInstance.DescribeReturns(nil, awsrds.ErrDBInstanceDoesNotExist)
+
+			copiedSnapshot = &rds.DBSnapshot{
+				DBSnapshotIdentifier: aws.String(reencryptedSnapshot),
+				DBSnapshotArn:        aws.String(reencryptedSnapshArn),
+				DBInstanceIdentifier: aws.String("cf-origin-instance"),
+				Status:               aws.String("copying"),
+			}
+			rdsInstance.DescribeSnapshotReturns(copiedSnapshot, nil)
+
+			sourceTags = map[string]string{
+				"Broker Name":            "mybroker",
+				"Service ID":             "Service-1",
+				"Plan ID":                "new-key-plan",
+				"Space ID":               "space-id",
+				"Organization ID":        "organization-id",
+				"Restored From Snapshot": originSnapshotID,
+				"chargeable_entity":      instanceID,
+			}
+			copiedSnapshotTags = awsrds.BuildRDSTags(sourceTags)
+		})
+
+		It("reports the copy in progress", func() {
+			lastOperation, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lastOperation.State).To(Equal(domain.InProgress))
+			Expect(lastOperation.Description).To(ContainSubstring("is being re-encrypted"))
+
+			Expect(rdsInstance.DescribeSnapshotArgsForCall(0)).To(Equal(reencryptedSnapshot))
+			Expect(rdsInstance.RestoreCallCount()).To(Equal(0))
+		})
+
+		It("restores from the copy once it is available", func() {
+			copiedSnapshot.Status = aws.String("available")
+
+			lastOperation, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lastOperation.State).To(Equal(domain.InProgress))
+
+			Expect(rdsInstance.RestoreCallCount()).To(Equal(1))
+			input := rdsInstance.RestoreArgsForCall(0)
+			Expect(aws.StringValue(input.DBInstanceIdentifier)).To(Equal("cf-instance-id"))
+			Expect(aws.StringValue(input.DBSnapshotIdentifier)).To(Equal(reencryptedSnapshot))
+			Expect(aws.StringValue(input.Engine)).To(Equal("mysql"))
+			Expect(input.Tags).To(ConsistOf(copiedSnapshotTags))
+		})
+
+		It("deletes the copy once the restore has started", func() {
+			copiedSnapshot.Status = aws.String("available")
+
+			_, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rdsInstance.DeleteSnapshotCallCount()).To(Equal(1))
+			Expect(rdsInstance.DeleteSnapshotArgsForCall(0)).To(Equal(reencryptedSnapshot))
+		})
+
+		It("keeps the copy if the restore fails", func() {
+			copiedSnapshot.Status = aws.String("available")
+			rdsInstance.RestoreReturns(errors.New("operation failed"))
+
+			_, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+			Expect(err).To(MatchError("operation failed"))
+			Expect(rdsInstance.DeleteSnapshotCallCount()).To(Equal(0))
+		})
+
+		It("doesn't look for a copy when polling other operations", func() {
+			pollDetails.OperationData = "provision"
+
+			_, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+			Expect(err).To(Equal(apiresponses.ErrInstanceDoesNotExist))
+			Expect(rdsInstance.DescribeSnapshotCallCount()).To(Equal(0))
+			Expect(rdsInstance.RestoreCallCount()).To(Equal(0))
+		})
+
+		It("fails if the copy failed", func() {
+			copiedSnapshot.Status = aws.String("failed")
+
+			lastOperation, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lastOperation.State).To(Equal(domain.Failed))
+			Expect(rdsInstance.RestoreCallCount()).To(Equal(0))
+		})
+
+		It("returns ErrInstanceDoesNotExist if there is no copy either", func() {
+			rdsInstance.DescribeSnapshotReturns(nil, awsrds.ErrDBSnapshotDoesNotExist)
+
+			_, err := rdsBroker.LastOperation(ctx, instanceID, pollDetails)
+			Expect(err).To(Equal(apiresponses.ErrInstanceDoesNotExist))
+		})
+	})
+})
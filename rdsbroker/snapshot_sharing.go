@@ -0,0 +1,158 @@
+package rdsbroker
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// BackupAccountConfig is a second AWS account which the broker shares its
+// manual snapshots with, and optionally copies them into, so that backups
+// survive the compromise of the account the instances run in.
+type BackupAccountConfig struct {
+	AccountID           string `json:"account_id"`
+	MaxSnapshotAgeHours uint   `json:"max_snapshot_age_hours"`
+	CopyRoleARN         string `json:"copy_role_arn"`
+	CopyKmsKeyID        string `json:"copy_kms_key_id"`
+}
+
+// defaultMaxSnapshotAgeHours is how old the snapshots which are shared can
+// be by default. Older snapshots were shared when they were new, or were
+// taken before the backup account was configured.
+const defaultMaxSnapshotAgeHours = 24 * 7
+
+var awsAccountIDPattern = regexp.MustCompile(`^[0-9]{12}$`)
+
+func (c *BackupAccountConfig) FillDefaults() {
+	if c.MaxSnapshotAgeHours == 0 {
+		c.MaxSnapshotAgeHours = defaultMaxSnapshotAgeHours
+	}
+}
+
+func (c BackupAccountConfig) Validate() error {
+	if !awsAccountIDPattern.MatchString(c.AccountID) {
+		return errors.New("AccountID must be a 12 digit AWS account ID")
+	}
+	if c.CopyRoleARN != "" && c.CopyKmsKeyID == "" {
+		return errors.New("Must provide a CopyKmsKeyID in the backup account when CopyRoleARN is set")
+	}
+	return nil
+}
+
+// SetBackupAccountDBInstance lets the broker copy the snapshots it shares
+// into the backup account, using RDS with a role in that account.
+func (b *RDSBroker) SetBackupAccountDBInstance(backupDBInstance awsrds.RDSInstance) {
+	b.backupDBInstance = backupDBInstance
+}
+
+// shareSnapshots is the housekeeping job which shares the broker's recent
+// manual snapshots, such as final snapshots, with the backup account, and
+// copies them there if the broker can act in it. Each snapshot is tagged
+// once it has been shared and copied, so that it is only done once. The
+// job doesn't stop at a snapshot which fails, but returns an error if any
+// did so that it is logged.
+func (b *RDSBroker) shareSnapshots(_ []managedDBInstance) error {
+	if b.backupAccount == nil {
+		return nil
+	}
+
+	createdAfter := time.Now().Add(-time.Duration(b.backupAccount.MaxSnapshotAgeHours) * time.Hour)
+	snapshots, err := b.dbInstance.DescribeManualSnapshots(b.brokerName, createdAfter)
+	if err != nil {
+		return err
+	}
+
+	shared, copied, failed := 0, 0, 0
+	for _, snapshot := range snapshots {
+		if aws.StringValue(snapshot.Status) != "available" {
+			continue
+		}
+
+		didShare, didCopy, err := b.shareSnapshot(snapshot)
+		if didShare {
+			shared++
+		}
+		if didCopy {
+			copied++
+		}
+		if err != nil {
+			failed++
+			b.logger.Error("share-snapshots.failed", err, lager.Data{
+				"snapshotID": aws.StringValue(snapshot.DBSnapshotIdentifier),
+				"accountID":  b.backupAccount.AccountID,
+			})
+		}
+	}
+
+	b.logger.Info("share-snapshots.done", lager.Data{
+		"checkedSnapshots": len(snapshots),
+		"sharedSnapshots":  shared,
+		"copiedSnapshots":  copied,
+		"failedSnapshots":  failed,
+	})
+
+	if failed > 0 {
+		return fmt.Errorf("failed to share %d snapshots with account %s", failed, b.backupAccount.AccountID)
+	}
+	return nil
+}
+
+func (b *RDSBroker) shareSnapshot(snapshot *rds.DBSnapshot) (shared bool, copied bool, err error) {
+	snapshotID := aws.StringValue(snapshot.DBSnapshotIdentifier)
+	snapshotArn := aws.StringValue(snapshot.DBSnapshotArn)
+	accountID := b.backupAccount.AccountID
+
+	tags, err := b.dbInstance.GetResourceTags(snapshotArn)
+	if err != nil {
+		return false, false, err
+	}
+	tagsByName := awsrds.RDSTagsValues(tags)
+
+	if tagsByName[awsrds.TagSharedWithAccount] != accountID {
+		if err := b.dbInstance.ShareSnapshot(snapshotID, accountID); err != nil {
+			return false, false, err
+		}
+		err := b.dbInstance.AddTagsToResource(snapshotArn, awsrds.BuildRDSTags(map[string]string{
+			awsrds.TagSharedWithAccount: accountID,
+		}))
+		if err != nil {
+			return true, false, err
+		}
+		shared = true
+	}
+
+	if b.backupDBInstance == nil || tagsByName[awsrds.TagCopiedToAccount] == accountID {
+		return shared, false, nil
+	}
+
+	// the copy keeps the identifier and tags of the snapshot, but not the
+	// broker name, so that no broker cleans it up. It is encrypted with a
+	// key of the backup account, as the key of the original could be
+	// disabled from the account it is in.
+	copyTags := map[string]string{}
+	for key, value := range tagsByName {
+		if key != awsrds.TagBrokerName {
+			copyTags[key] = value
+		}
+	}
+	err = b.backupDBInstance.CopySnapshot(&rds.CopyDBSnapshotInput{
+		SourceDBSnapshotIdentifier: aws.String(snapshotArn),
+		TargetDBSnapshotIdentifier: aws.String(snapshotID),
+		KmsKeyId:                   aws.String(b.backupAccount.CopyKmsKeyID),
+		Tags:                       awsrds.BuildRDSTags(copyTags),
+	})
+	if err != nil {
+		return shared, false, err
+	}
+	err = b.dbInstance.AddTagsToResource(snapshotArn, awsrds.BuildRDSTags(map[string]string{
+		awsrds.TagCopiedToAccount: accountID,
+	}))
+	return shared, true, err
+}
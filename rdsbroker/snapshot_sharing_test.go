@@ -0,0 +1,172 @@
+package rdsbroker_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+	sqlfake "github.com/alphagov/paas-rds-broker/sqlengine/fakes"
+)
+
+var _ = Describe("Snapshot sharing", func() {
+	const (
+		backupAccountID = "210987654321"
+		snapshotArn     = "arn:aws:rds:rds-region:123456789012:snapshot:cf-instance-1-final-snapshot"
+	)
+
+	var (
+		config           Config
+		rdsInstance      *rdsfake.FakeRDSInstance
+		backupDBInstance *rdsfake.FakeRDSInstance
+		snapshotTags     map[string]string
+		logger           *lagertest.TestLogger
+		rdsBroker        *RDSBroker
+	)
+
+	BeforeEach(func() {
+		config = testConfig()
+		config.BackupAccount = &BackupAccountConfig{
+			AccountID:    backupAccountID,
+			CopyRoleARN:  "arn:aws:iam::210987654321:role/rds-broker-backup",
+			CopyKmsKeyID: "arn:aws:kms:rds-region:210987654321:key/backup-key",
+		}
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		rdsInstance.DescribeManualSnapshotsReturns([]*rds.DBSnapshot{
+			{
+				DBSnapshotIdentifier: aws.String("cf-instance-1-final-snapshot"),
+				DBSnapshotArn:        aws.String(snapshotArn),
+				Status:               aws.String("available"),
+			},
+			{
+				DBSnapshotIdentifier: aws.String("cf-instance-2-final-snapshot"),
+				DBSnapshotArn:        aws.String("arn:aws:rds:rds-region:123456789012:snapshot:cf-instance-2-final-snapshot"),
+				Status:               aws.String("creating"),
+			},
+		}, nil)
+		snapshotTags = map[string]string{
+			awsrds.TagBrokerName: "mybroker",
+			awsrds.TagPlanID:     "Plan-1",
+		}
+		rdsInstance.GetResourceTagsStub = func(arn string, opts ...awsrds.DescribeOption) ([]*rds.Tag, error) {
+			return awsrds.BuildRDSTags(snapshotTags), nil
+		}
+		backupDBInstance = &rdsfake.FakeRDSInstance{}
+		logger = lagertest.NewTestLogger("snapshot_sharing_test")
+	})
+
+	JustBeforeEach(func() {
+		rdsBroker = newTestBroker(config, rdsInstance, &sqlfake.FakeSQLEngine{}, logger)
+		rdsBroker.SetBackupAccountDBInstance(backupDBInstance)
+	})
+
+	It("shares the broker's recent available manual snapshots with the backup account", func() {
+		Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+		brokerName, createdAfter := rdsInstance.DescribeManualSnapshotsArgsForCall(0)
+		Expect(brokerName).To(Equal("mybroker"))
+		Expect(createdAfter).To(BeTemporally("~", time.Now().Add(-7*24*time.Hour), time.Minute))
+
+		Expect(rdsInstance.ShareSnapshotCallCount()).To(Equal(1))
+		snapshotID, accountID := rdsInstance.ShareSnapshotArgsForCall(0)
+		Expect(snapshotID).To(Equal("cf-instance-1-final-snapshot"))
+		Expect(accountID).To(Equal(backupAccountID))
+
+		arn, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+		Expect(arn).To(Equal(snapshotArn))
+		Expect(awsrds.RDSTagsValues(tags)).To(Equal(map[string]string{awsrds.TagSharedWithAccount: backupAccountID}))
+
+		Expect(logger.LogMessages()).To(ContainElement("snapshot_sharing_test.broker.share-snapshots.done"))
+	})
+
+	It("copies the shared snapshots into the backup account", func() {
+		Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+		Expect(backupDBInstance.CopySnapshotCallCount()).To(Equal(1))
+		input := backupDBInstance.CopySnapshotArgsForCall(0)
+		Expect(aws.StringValue(input.SourceDBSnapshotIdentifier)).To(Equal(snapshotArn))
+		Expect(aws.StringValue(input.TargetDBSnapshotIdentifier)).To(Equal("cf-instance-1-final-snapshot"))
+		Expect(aws.StringValue(input.KmsKeyId)).To(Equal("arn:aws:kms:rds-region:210987654321:key/backup-key"))
+		Expect(awsrds.RDSTagsValues(input.Tags)).To(Equal(map[string]string{awsrds.TagPlanID: "Plan-1"}))
+
+		arn, tags := rdsInstance.AddTagsToResourceArgsForCall(1)
+		Expect(arn).To(Equal(snapshotArn))
+		Expect(awsrds.RDSTagsValues(tags)).To(Equal(map[string]string{awsrds.TagCopiedToAccount: backupAccountID}))
+	})
+
+	Context("when the snapshot has already been shared and copied", func() {
+		BeforeEach(func() {
+			snapshotTags[awsrds.TagSharedWithAccount] = backupAccountID
+			snapshotTags[awsrds.TagCopiedToAccount] = backupAccountID
+		})
+
+		It("leaves it alone", func() {
+			Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+			Expect(rdsInstance.ShareSnapshotCallCount()).To(Equal(0))
+			Expect(backupDBInstance.CopySnapshotCallCount()).To(Equal(0))
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the snapshot was shared with another account", func() {
+		BeforeEach(func() {
+			snapshotTags[awsrds.TagSharedWithAccount] = "111111111111"
+			snapshotTags[awsrds.TagCopiedToAccount] = "111111111111"
+		})
+
+		It("shares and copies it again", func() {
+			Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+			Expect(rdsInstance.ShareSnapshotCallCount()).To(Equal(1))
+			Expect(backupDBInstance.CopySnapshotCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when the broker can't act in the backup account", func() {
+		JustBeforeEach(func() {
+			rdsBroker.SetBackupAccountDBInstance(nil)
+		})
+
+		It("only shares the snapshots", func() {
+			Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+			Expect(rdsInstance.ShareSnapshotCallCount()).To(Equal(1))
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when sharing fails", func() {
+		BeforeEach(func() {
+			rdsInstance.ShareSnapshotReturns(errors.New("encrypted with the default key"))
+		})
+
+		It("logs the failure and doesn't copy the snapshot", func() {
+			Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+			Expect(backupDBInstance.CopySnapshotCallCount()).To(Equal(0))
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(0))
+			Expect(logger.LogMessages()).To(ContainElement("snapshot_sharing_test.broker.share-snapshots.failed"))
+			Expect(logger.LogMessages()).To(ContainElement("snapshot_sharing_test.broker.share-snapshots"))
+		})
+	})
+
+	Context("without a backup account", func() {
+		BeforeEach(func() {
+			config.BackupAccount = nil
+		})
+
+		It("does nothing", func() {
+			Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+			Expect(rdsInstance.DescribeManualSnapshotsCallCount()).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,197 @@
+package rdsbroker
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// softDeletedIdentifierSuffix is appended to the identifier of a DB instance
+// when it is soft deleted, so that it is no longer found by the broker under
+// its service instance.
+const softDeletedIdentifierSuffix = "-deleted"
+
+var ErrNotSoftDeleted = errors.New("DB instance has not been soft deleted")
+
+// softDeleteDBInstance renames and tags a DB instance with the time after
+// which it can be purged, instead of deleting it. The instance is stopped
+// and eventually deleted by ProcessSoftDeletedInstances, after its data is
+// exported to the dataExportBucket if one is given.
+func (b *RDSBroker) softDeleteDBInstance(instanceID string, dataExportBucket string) error {
+	dbInstanceIdentifier := b.dbInstanceIdentifier(instanceID)
+
+	if err := b.deleteDBProxy(instanceID); err != nil {
+		return err
+	}
+	if err := b.deleteDNSName(instanceID); err != nil {
+		return err
+	}
+
+	dbInstance, err := b.dbInstance.Describe(dbInstanceIdentifier)
+	if err != nil {
+		return err
+	}
+
+	purgeAfter := time.Now().Add(b.softDeleteDuration).UTC()
+	b.logger.Info("soft-delete", lager.Data{
+		instanceIDLogKey:   instanceID,
+		"purgeAfter":       purgeAfter,
+		"dataExportBucket": dataExportBucket,
+	})
+
+	tags := map[string]string{awsrds.TagPurgeAfter: purgeAfter.Format(time.RFC3339)}
+	if dataExportBucket != "" {
+		tags[awsrds.TagDataExport] = dataExportBucket
+	}
+	err = b.dbInstance.AddTagsToResource(aws.StringValue(dbInstance.DBInstanceArn), awsrds.BuildRDSTags(tags))
+	if err != nil {
+		return err
+	}
+	if err := b.removeTag(dbInstanceIdentifier, awsrds.TagDBProxy); err != nil {
+		return err
+	}
+	if b.dnsZone != nil {
+		for _, tag := range []string{awsrds.TagDNSName, awsrds.TagDNSTarget} {
+			if err := b.removeTag(dbInstanceIdentifier, tag); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = b.dbInstance.Modify(&rds.ModifyDBInstanceInput{
+		DBInstanceIdentifier:    aws.String(dbInstanceIdentifier),
+		NewDBInstanceIdentifier: aws.String(dbInstanceIdentifier + softDeletedIdentifierSuffix),
+		ApplyImmediately:        aws.Bool(true),
+	})
+	return err
+}
+
+// UndeleteInstance asks for a soft deleted DB instance to be brought back
+// under its service instance. The instance is started and renamed by the
+// following runs of ProcessSoftDeletedInstances.
+func (b *RDSBroker) UndeleteInstance(instanceID string) error {
+	dbInstance, err := b.dbInstance.Describe(b.dbInstanceIdentifier(instanceID) + softDeletedIdentifierSuffix)
+	if err != nil {
+		return err
+	}
+
+	tags, err := b.dbInstance.GetResourceTags(aws.StringValue(dbInstance.DBInstanceArn))
+	if err != nil {
+		return err
+	}
+	tagsByName := awsrds.RDSTagsValues(tags)
+	if tagsByName[awsrds.TagBrokerName] != b.brokerName || tagsByName[awsrds.TagPurgeAfter] == "" {
+		return ErrNotSoftDeleted
+	}
+
+	b.logger.Info("undelete", lager.Data{instanceIDLogKey: instanceID})
+	return b.dbInstance.AddTagsToResource(
+		aws.StringValue(dbInstance.DBInstanceArn),
+		awsrds.BuildRDSTags(map[string]string{awsrds.TagUndeleteRequested: "true"}),
+	)
+}
+
+// ProcessSoftDeletedInstances moves every soft deleted DB instance one step
+// on: instances waiting to be undeleted are started and renamed back, those
+// past their purge time are deleted, and the rest are kept stopped.
+func (b *RDSBroker) ProcessSoftDeletedInstances() error {
+	dbInstances, err := b.listManagedDBInstances()
+	if err != nil {
+		return err
+	}
+	return b.processSoftDeletedInstances(dbInstances)
+}
+
+func (b *RDSBroker) processSoftDeletedInstances(dbInstances []managedDBInstance) error {
+	for _, instance := range dbInstances {
+		if instance.tagsByName[awsrds.TagPurgeAfter] == "" {
+			continue
+		}
+
+		if err := b.processSoftDeletedInstance(instance.dbInstance, instance.tagsByName, time.Now()); err != nil {
+			b.logger.Error("process-soft-deleted", err, lager.Data{dbInstanceLogKey: instance.dbInstance.DBInstanceIdentifier})
+		}
+	}
+
+	return nil
+}
+
+func (b *RDSBroker) processSoftDeletedInstance(dbInstance *rds.DBInstance, tagsByName map[string]string, now time.Time) error {
+	dbInstanceIdentifier := aws.StringValue(dbInstance.DBInstanceIdentifier)
+	status := aws.StringValue(dbInstance.DBInstanceStatus)
+	logData := lager.Data{"dbInstanceIdentifier": dbInstanceIdentifier, "status": status}
+
+	if tagsByName[awsrds.TagUndeleteRequested] != "" {
+		switch {
+		case status == "stopped":
+			b.logger.Info("process-soft-deleted.start", logData)
+			return b.dbInstance.Start(dbInstanceIdentifier)
+		case status != "available":
+			return nil
+		case strings.HasSuffix(dbInstanceIdentifier, softDeletedIdentifierSuffix):
+			b.logger.Info("process-soft-deleted.rename", logData)
+			_, err := b.dbInstance.Modify(&rds.ModifyDBInstanceInput{
+				DBInstanceIdentifier:    aws.String(dbInstanceIdentifier),
+				NewDBInstanceIdentifier: aws.String(strings.TrimSuffix(dbInstanceIdentifier, softDeletedIdentifierSuffix)),
+				ApplyImmediately:        aws.Bool(true),
+			})
+			return err
+		default:
+			b.logger.Info("process-soft-deleted.undeleted", logData)
+			instanceID := b.dbInstanceIdentifierToServiceInstanceID(dbInstanceIdentifier)
+			if err := b.ensureDNSName(instanceID, dbInstance, tagsByName); err != nil {
+				return err
+			}
+			if err := b.removeTag(dbInstanceIdentifier, awsrds.TagUndeleteRequested); err != nil {
+				return err
+			}
+			if tagsByName[awsrds.TagDataExport] != "" {
+				if err := b.removeTag(dbInstanceIdentifier, awsrds.TagDataExport); err != nil {
+					return err
+				}
+			}
+			return b.removeTag(dbInstanceIdentifier, awsrds.TagPurgeAfter)
+		}
+	}
+
+	// the instance is kept running, and isn't purged, until its data has
+	// been exported
+	if tagsByName[awsrds.TagDataExport] != "" {
+		return b.processDataExport(dbInstance, tagsByName, now)
+	}
+
+	purgeAfter, err := time.Parse(time.RFC3339, tagsByName[awsrds.TagPurgeAfter])
+	if err != nil {
+		return err
+	}
+
+	if now.After(purgeAfter) {
+		if status == "deleting" {
+			return nil
+		}
+		servicePlan, _ := b.catalog.FindServicePlan(tagsByName[awsrds.TagPlanID])
+		skipFinalSnapshot, err := b.resolveSkipFinalSnapshot(servicePlan, tagsByName[awsrds.TagSkipFinalSnapshot])
+		if err != nil {
+			return err
+		}
+		b.logger.Info("process-soft-deleted.purge", logData)
+		err = b.dbInstance.Delete(dbInstanceIdentifier, skipFinalSnapshot)
+		if err == awsrds.ErrDBInstanceDoesNotExist {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		b.revokeBindings(b.dbInstanceIdentifierToServiceInstanceID(dbInstanceIdentifier), dbInstanceIdentifier)
+		return nil
+	}
+
+	_, err = b.keepStopped(dbInstance, "process-soft-deleted.stop", logData)
+	return err
+}
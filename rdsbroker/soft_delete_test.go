@@ -0,0 +1,162 @@
+package rdsbroker_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+)
+
+var _ = Describe("Soft delete", func() {
+	var (
+		rdsInstance *rdsfake.FakeRDSInstance
+		rdsBroker   *RDSBroker
+		dbInstance  *rds.DBInstance
+		tags        map[string]string
+	)
+
+	BeforeEach(func() {
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		config := testConfig()
+		config.SoftDeleteDays = 7
+		rdsBroker = newTestBroker(config, rdsInstance, nil, lagertest.NewTestLogger("soft_delete_test"))
+
+		dbInstance = &rds.DBInstance{
+			DBInstanceIdentifier: aws.String("cf-instance-1-deleted"),
+			DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-instance-1-deleted"),
+			DBInstanceStatus:     aws.String("available"),
+		}
+		tags = map[string]string{
+			"Broker Name":       "mybroker",
+			"chargeable_entity": "instance-1",
+			"SkipFinalSnapshot": "false",
+			"Purge After":       time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		}
+	})
+
+	JustBeforeEach(func() {
+		rdsInstance.DescribeByTagReturns([]*rds.DBInstance{dbInstance}, nil)
+		rdsInstance.DescribeReturns(dbInstance, nil)
+		rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(tags), nil)
+	})
+
+	Describe("ProcessSoftDeletedInstances", func() {
+		It("stops soft deleted DB instances which are running", func() {
+			Expect(rdsBroker.ProcessSoftDeletedInstances()).To(Succeed())
+
+			Expect(rdsInstance.StopCallCount()).To(Equal(1))
+			Expect(rdsInstance.StopArgsForCall(0)).To(Equal("cf-instance-1-deleted"))
+			Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+		})
+
+		Context("when the DB instance has not been soft deleted", func() {
+			BeforeEach(func() {
+				delete(tags, "Purge After")
+			})
+
+			It("leaves it alone", func() {
+				Expect(rdsBroker.ProcessSoftDeletedInstances()).To(Succeed())
+
+				Expect(rdsInstance.StopCallCount()).To(Equal(0))
+				Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the purge time has passed", func() {
+			BeforeEach(func() {
+				dbInstance.DBInstanceStatus = aws.String("stopped")
+				tags["Purge After"] = time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+			})
+
+			It("deletes the DB instance", func() {
+				Expect(rdsBroker.ProcessSoftDeletedInstances()).To(Succeed())
+
+				Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+				id, skipFinalSnapshot := rdsInstance.DeleteArgsForCall(0)
+				Expect(id).To(Equal("cf-instance-1-deleted"))
+				Expect(skipFinalSnapshot).To(BeFalse())
+			})
+		})
+
+		Context("when an undelete has been requested", func() {
+			BeforeEach(func() {
+				tags["Undelete Requested"] = "true"
+			})
+
+			It("starts the DB instance if it is stopped", func() {
+				dbInstance.DBInstanceStatus = aws.String("stopped")
+
+				Expect(rdsBroker.ProcessSoftDeletedInstances()).To(Succeed())
+
+				Expect(rdsInstance.StartCallCount()).To(Equal(1))
+				Expect(rdsInstance.StartArgsForCall(0)).To(Equal("cf-instance-1-deleted"))
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+			})
+
+			It("renames the DB instance back once it is available", func() {
+				Expect(rdsBroker.ProcessSoftDeletedInstances()).To(Succeed())
+
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
+				input := rdsInstance.ModifyArgsForCall(0)
+				Expect(aws.StringValue(input.DBInstanceIdentifier)).To(Equal("cf-instance-1-deleted"))
+				Expect(aws.StringValue(input.NewDBInstanceIdentifier)).To(Equal("cf-instance-1"))
+				Expect(rdsInstance.StopCallCount()).To(Equal(0))
+			})
+
+			It("removes the soft delete tags once it has been renamed", func() {
+				dbInstance.DBInstanceIdentifier = aws.String("cf-instance-1")
+
+				Expect(rdsBroker.ProcessSoftDeletedInstances()).To(Succeed())
+
+				Expect(rdsInstance.RemoveTagCallCount()).To(Equal(2))
+				_, tagKey := rdsInstance.RemoveTagArgsForCall(0)
+				Expect(tagKey).To(Equal("Undelete Requested"))
+				_, tagKey = rdsInstance.RemoveTagArgsForCall(1)
+				Expect(tagKey).To(Equal("Purge After"))
+			})
+
+			Context("and the purge time has passed", func() {
+				BeforeEach(func() {
+					tags["Purge After"] = time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+				})
+
+				It("does not delete it", func() {
+					Expect(rdsBroker.ProcessSoftDeletedInstances()).To(Succeed())
+
+					Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+					Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
+				})
+			})
+		})
+	})
+
+	Describe("UndeleteInstance", func() {
+		It("tags the soft deleted DB instance to be undeleted", func() {
+			Expect(rdsBroker.UndeleteInstance("instance-1")).To(Succeed())
+
+			Expect(rdsInstance.DescribeArgsForCall(0)).To(Equal("cf-instance-1-deleted"))
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+			arn, addedTags := rdsInstance.AddTagsToResourceArgsForCall(0)
+			Expect(arn).To(Equal("arn:aws:rds:rds-region:1234567890:db:cf-instance-1-deleted"))
+			Expect(awsrds.RDSTagsValues(addedTags)).To(HaveKeyWithValue("Undelete Requested", "true"))
+		})
+
+		Context("when the DB instance has not been soft deleted", func() {
+			BeforeEach(func() {
+				delete(tags, "Purge After")
+			})
+
+			It("refuses to undelete it", func() {
+				Expect(rdsBroker.UndeleteInstance("instance-1")).To(MatchError(ErrNotSoftDeleted))
+				Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(0))
+			})
+		})
+	})
+})
@@ -0,0 +1,138 @@
+package rdsbroker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// sqlEnginePingTimeout is how long pinging an SQL engine can take before it
+// is reported as failed, so that a canary database which doesn't answer
+// doesn't hold up the healthcheck.
+const sqlEnginePingTimeout = 10 * time.Second
+
+// SQLEngineCanaryConfig is a database which pinging an SQL engine connects
+// to, to check the broker can reach and log in to databases of the engine.
+type SQLEngineCanaryConfig struct {
+	Address  string `json:"address"`
+	Port     int64  `json:"port"`
+	DBName   string `json:"dbname"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+func (c SQLEngineCanaryConfig) Validate() error {
+	if c.Address == "" {
+		return errors.New("Must provide a non-empty Address")
+	}
+	if c.Port <= 0 {
+		return errors.New("Must provide a positive Port")
+	}
+	if c.Username == "" {
+		return errors.New("Must provide a non-empty Username")
+	}
+
+	return nil
+}
+
+// SQLEngineHealth is whether one of the SQL engines of the catalog's plans
+// could be pinged.
+type SQLEngineHealth struct {
+	Engine  string `json:"engine"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+// catalogSQLEngines lists the engines of the catalog's plans which the
+// broker connects to, once each and in order.
+func (b *RDSBroker) catalogSQLEngines() []string {
+	engines := map[string]bool{}
+	for _, service := range b.catalog.Services {
+		for _, plan := range service.Plans {
+			engine := strings.ToLower(aws.StringValue(plan.RDSProperties.Engine))
+			switch engine {
+			case "postgres", "mysql", "mariadb":
+				engines[engine] = true
+			}
+		}
+	}
+
+	sortedEngines := []string{}
+	for engine := range engines {
+		sortedEngines = append(sortedEngines, engine)
+	}
+	sort.Strings(sortedEngines)
+	return sortedEngines
+}
+
+// CheckSQLEngines pings each of the SQL engines of the catalog's plans,
+// checking its driver works and, if it has a canary database, that the
+// broker can connect to it.
+func (b *RDSBroker) CheckSQLEngines() []SQLEngineHealth {
+	healths := []SQLEngineHealth{}
+	for _, engine := range b.catalogSQLEngines() {
+		health := SQLEngineHealth{Engine: engine, Healthy: true}
+		if err := b.pingSQLEngine(engine); err != nil {
+			health.Healthy = false
+			health.Error = err.Error()
+		}
+		healths = append(healths, health)
+	}
+	return healths
+}
+
+func (b *RDSBroker) pingSQLEngine(engine string) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- b.sqlProvider.Ping(engine)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(sqlEnginePingTimeout):
+		return fmt.Errorf("Pinging SQL engine '%s' timed out after %s", engine, sqlEnginePingTimeout)
+	}
+}
+
+// checkSQLEngines is the housekeeping job which logs the SQL engines which
+// can't be pinged, as binding to their instances is likely to fail.
+func (b *RDSBroker) checkSQLEngines(dbInstances []managedDBInstance) error {
+	for _, health := range b.CheckSQLEngines() {
+		if !health.Healthy {
+			b.logger.Error("check-sql-engines.unhealthy", errors.New(health.Error), lager.Data{"engine": health.Engine})
+		}
+	}
+	return nil
+}
+
+// DeepHealthcheckHandler serves the health of the SQL engines of the
+// catalog's plans, answering 503 Service Unavailable if any of them can't
+// be pinged.
+func (b *RDSBroker) DeepHealthcheckHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		healths := b.CheckSQLEngines()
+		status := http.StatusOK
+		for _, health := range healths {
+			if !health.Healthy {
+				status = http.StatusServiceUnavailable
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{"sql_engines": healths})
+	})
+}
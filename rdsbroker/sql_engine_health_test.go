@@ -0,0 +1,96 @@
+package rdsbroker_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+	"github.com/alphagov/paas-rds-broker/rdsbroker/fakes"
+	sqlfake "github.com/alphagov/paas-rds-broker/sqlengine/fakes"
+)
+
+var _ = Describe("SQL engine health", func() {
+	var (
+		sqlProvider *sqlfake.FakeProvider
+		rdsInstance *rdsfake.FakeRDSInstance
+		logger      *lagertest.TestLogger
+		rdsBroker   *RDSBroker
+	)
+
+	BeforeEach(func() {
+		config := testConfig()
+		config.Catalog.Services = append(config.Catalog.Services, Service{
+			ID: "Service-2",
+			Plans: []ServicePlan{
+				{ID: "Plan-3", RDSProperties: RDSProperties{Engine: aws.String("mysql")}},
+				{ID: "Plan-4", RDSProperties: RDSProperties{Engine: aws.String("sqlserver-se")}},
+			},
+		})
+
+		sqlProvider = &sqlfake.FakeProvider{}
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		logger = lagertest.NewTestLogger("sql_engine_health_test")
+		rdsBroker = New(config, rdsInstance, sqlProvider, &fakes.FakeParameterGroupSelector{}, logger)
+	})
+
+	It("pings each engine of the catalog once", func() {
+		Expect(rdsBroker.CheckSQLEngines()).To(Equal([]SQLEngineHealth{
+			{Engine: "mysql", Healthy: true},
+			{Engine: "postgres", Healthy: true},
+		}))
+
+		Expect(sqlProvider.PingCallCount()).To(Equal(2))
+		Expect(sqlProvider.PingArgsForCall(0)).To(Equal("mysql"))
+		Expect(sqlProvider.PingArgsForCall(1)).To(Equal("postgres"))
+	})
+
+	Context("when an engine can't be pinged", func() {
+		BeforeEach(func() {
+			sqlProvider.PingCalls(func(engine string) error {
+				if engine == "postgres" {
+					return errors.New("Connecting to the canary database of engine 'postgres': connection refused")
+				}
+				return nil
+			})
+		})
+
+		It("reports it as unhealthy", func() {
+			Expect(rdsBroker.CheckSQLEngines()).To(Equal([]SQLEngineHealth{
+				{Engine: "mysql", Healthy: true},
+				{Engine: "postgres", Healthy: false, Error: "Connecting to the canary database of engine 'postgres': connection refused"},
+			}))
+		})
+
+		It("fails the deep healthcheck", func() {
+			w := httptest.NewRecorder()
+			rdsBroker.DeepHealthcheckHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthcheck/deep", nil))
+			Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+
+			var body struct {
+				SQLEngines []SQLEngineHealth `json:"sql_engines"`
+			}
+			Expect(json.Unmarshal(w.Body.Bytes(), &body)).To(Succeed())
+			Expect(body.SQLEngines).To(HaveLen(2))
+			Expect(body.SQLEngines[1].Healthy).To(BeFalse())
+		})
+
+		It("logs it during housekeeping", func() {
+			Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+			Expect(logger.LogMessages()).To(ContainElement("sql_engine_health_test.broker.check-sql-engines.unhealthy"))
+		})
+	})
+
+	It("passes the deep healthcheck when every engine can be pinged", func() {
+		w := httptest.NewRecorder()
+		rdsBroker.DeepHealthcheckHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthcheck/deep", nil))
+		Expect(w.Code).To(Equal(http.StatusOK))
+	})
+})
@@ -0,0 +1,28 @@
+package rdsbroker
+
+import (
+	"errors"
+
+	"github.com/alphagov/paas-rds-broker/statestore"
+)
+
+// StateStoreConfig is the Postgres database the broker keeps its own state
+// in, rather than in the tags of the DB instances.
+type StateStoreConfig struct {
+	URL string `json:"url"`
+}
+
+func (s StateStoreConfig) Validate() error {
+	if s.URL == "" {
+		return errors.New("Must provide a non-empty URL")
+	}
+
+	return nil
+}
+
+// SetStateStore sets where the broker keeps the state it would otherwise
+// keep in the tags of the DB instances. State already in tags is still
+// read, so that a store can be added to an existing broker.
+func (b *RDSBroker) SetStateStore(stateStore statestore.StateStore) {
+	b.stateStore = stateStore
+}
@@ -0,0 +1,33 @@
+package rdsbroker
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// MaxStatementTimeoutSeconds is the longest statement timeout a plan or a
+// binding can ask for.
+const MaxStatementTimeoutSeconds = 24 * 60 * 60
+
+// bindingStatementTimeout is how long the statements of a binding's user can
+// run for: what the binding asked for, otherwise its plan's default. Zero is
+// no limit.
+func bindingStatementTimeout(servicePlan ServicePlan, bindParameters BindParameters) time.Duration {
+	if bindParameters.StatementTimeoutSeconds != nil {
+		return time.Duration(*bindParameters.StatementTimeoutSeconds) * time.Second
+	}
+	return time.Duration(servicePlan.StatementTimeoutSeconds) * time.Second
+}
+
+// checkStatementTimeoutSupported refuses a statement timeout for DB
+// instances which can't limit the statements of a user, so that the
+// binding fails rather than its queries running unchecked. MySQL's
+// max_execution_time can't be set for a user.
+func checkStatementTimeoutSupported(dbInstance *rds.DBInstance) error {
+	if aws.StringValue(dbInstance.Engine) == "mysql" {
+		return newUserError(ErrCodeUnsupportedByEngine, "statement_timeout_seconds is only supported for postgres and mariadb")
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package rdsbroker_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+	sqlfake "github.com/alphagov/paas-rds-broker/sqlengine/fakes"
+)
+
+var _ = Describe("Statement timeouts", func() {
+	var (
+		servicePlan ServicePlan
+		rdsInstance *rdsfake.FakeRDSInstance
+		sqlEngine   *sqlfake.FakeSQLEngine
+		rdsBroker   *RDSBroker
+		bindDetails domain.BindDetails
+	)
+
+	BeforeEach(func() {
+		servicePlan = testConfig().Catalog.Services[0].Plans[0]
+		servicePlan.StatementTimeoutSeconds = 300
+
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		rdsInstance.DescribeReturns(testDBInstance(), nil)
+		sqlEngine = &sqlfake.FakeSQLEngine{}
+
+		bindDetails = domain.BindDetails{
+			ServiceID: "Service-1",
+			PlanID:    "Plan-1",
+		}
+	})
+
+	JustBeforeEach(func() {
+		config := testConfig()
+		config.AllowUserBindParameters = true
+		config.Catalog.Services[0].Plans[0] = servicePlan
+		rdsBroker = newTestBroker(config, rdsInstance, sqlEngine, lagertest.NewTestLogger("statement_timeout_test"))
+	})
+
+	It("sets the plan's statement timeout on the binding's user", func() {
+		_, err := rdsBroker.Bind(context.Background(), "instance-1", "binding-1", bindDetails, false)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(sqlEngine.SetUserStatementTimeoutCallCount()).ToNot(BeZero())
+		bindingID, timeout := sqlEngine.SetUserStatementTimeoutArgsForCall(0)
+		Expect(bindingID).To(Equal("binding-1"))
+		Expect(timeout).To(Equal(5 * time.Minute))
+	})
+
+	It("lets the binding ask for another statement timeout", func() {
+		bindDetails.RawParameters = json.RawMessage(`{"statement_timeout_seconds": 30}`)
+
+		_, err := rdsBroker.Bind(context.Background(), "instance-1", "binding-1", bindDetails, false)
+		Expect(err).NotTo(HaveOccurred())
+		_, timeout := sqlEngine.SetUserStatementTimeoutArgsForCall(0)
+		Expect(timeout).To(Equal(30 * time.Second))
+	})
+
+	It("lets the binding ask for no statement timeout", func() {
+		bindDetails.RawParameters = json.RawMessage(`{"statement_timeout_seconds": 0}`)
+
+		_, err := rdsBroker.Bind(context.Background(), "instance-1", "binding-1", bindDetails, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sqlEngine.SetUserStatementTimeoutCallCount()).To(BeZero())
+	})
+
+	It("refuses statement timeouts which are too long", func() {
+		bindDetails.RawParameters = json.RawMessage(`{"statement_timeout_seconds": 86401}`)
+
+		_, err := rdsBroker.Bind(context.Background(), "instance-1", "binding-1", bindDetails, false)
+		Expect(err).To(MatchError("statement_timeout_seconds must not be negative or more than 86400"))
+		Expect(ErrorCode(err)).To(Equal(ErrCodeInvalidParameters))
+		Expect(sqlEngine.CreateUserCallCount()).To(BeZero())
+	})
+
+	It("drops the user if its statement timeout can't be set", func() {
+		sqlEngine.SetUserStatementTimeoutReturns(errors.New("permission denied"))
+
+		_, err := rdsBroker.Bind(context.Background(), "instance-1", "binding-1", bindDetails, false)
+		Expect(err).To(MatchError("permission denied"))
+		Expect(sqlEngine.DropUserCallCount()).ToNot(BeZero())
+		Expect(sqlEngine.DropUserArgsForCall(0)).To(Equal("binding-1"))
+	})
+
+	Context("when the plan has no statement timeout", func() {
+		BeforeEach(func() {
+			servicePlan.StatementTimeoutSeconds = 0
+		})
+
+		It("doesn't limit the binding's user", func() {
+			_, err := rdsBroker.Bind(context.Background(), "instance-1", "binding-1", bindDetails, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sqlEngine.SetUserStatementTimeoutCallCount()).To(BeZero())
+		})
+	})
+
+	Context("when the instance runs MySQL", func() {
+		BeforeEach(func() {
+			servicePlan.StatementTimeoutSeconds = 0
+			servicePlan.RDSProperties.Engine = aws.String("mysql")
+			rdsInstance.DescribeReturns(&rds.DBInstance{
+				DBInstanceIdentifier: aws.String("cf-instance-1"),
+				Engine:               aws.String("mysql"),
+				DBName:               aws.String("mydb"),
+			}, nil)
+		})
+
+		It("refuses a statement timeout, as MySQL can't limit users", func() {
+			bindDetails.RawParameters = json.RawMessage(`{"statement_timeout_seconds": 30}`)
+
+			_, err := rdsBroker.Bind(context.Background(), "instance-1", "binding-1", bindDetails, false)
+			Expect(err).To(MatchError("statement_timeout_seconds is only supported for postgres and mariadb"))
+			Expect(ErrorCode(err)).To(Equal(ErrCodeUnsupportedByEngine))
+			Expect(sqlEngine.CreateUserCallCount()).To(BeZero())
+		})
+	})
+})
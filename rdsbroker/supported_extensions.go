@@ -97,3 +97,50 @@ var SupportedPreloadExtensions = map[string][]DBExtension{
 		},
 	},
 }
+
+// ExtensionVersionRange is the range of postgres major versions on which an
+// extension is available. A zero bound means the range is open on that side.
+type ExtensionVersionRange struct {
+	MinMajorVersion int64
+	MaxMajorVersion int64
+}
+
+// Lists the extensions which are only available on some
+// postgres major versions. Extensions not listed here are
+// assumed to be available on every version.
+var ExtensionCompatibility = map[string]ExtensionVersionRange{
+	"bool_plperl":    {MinMajorVersion: 13},
+	"chkpass":        {MaxMajorVersion: 10},
+	"old_snapshot":   {MinMajorVersion: 14},
+	"pg_cron":        {MinMajorVersion: 12},
+	"pg_partman":     {MinMajorVersion: 12},
+	"pg_surgery":     {MinMajorVersion: 14},
+	"pg_transport":   {MinMajorVersion: 11},
+	"postgis_raster": {MinMajorVersion: 12},
+	"tsearch2":       {MaxMajorVersion: 9},
+}
+
+// extensionsAreCompatible checks that every extension is available on the
+// service plan's postgres major version, so that we can refuse a request
+// rather than fail when later creating the extension.
+func extensionsAreCompatible(plan ServicePlan, extensions []string) error {
+	version, err := plan.EngineVersion()
+	if err != nil {
+		return err
+	}
+	majorVersion := version.Major()
+
+	for _, extension := range extensions {
+		versionRange, ok := ExtensionCompatibility[extension]
+		if !ok {
+			continue
+		}
+		if versionRange.MinMajorVersion != 0 && majorVersion < versionRange.MinMajorVersion {
+			return newUserError(ErrCodeExtensionNotAllowed, "%s is not available on postgres %d, it requires postgres %d or later", extension, majorVersion, versionRange.MinMajorVersion)
+		}
+		if versionRange.MaxMajorVersion != 0 && majorVersion > versionRange.MaxMajorVersion {
+			return newUserError(ErrCodeExtensionNotAllowed, "%s is not available on postgres %d, it was removed after postgres %d", extension, majorVersion, versionRange.MaxMajorVersion)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package rdsbroker
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	"github.com/alphagov/paas-rds-broker/statestore"
+)
+
+const (
+	tagWriteRetryInitialBackoff = 30 * time.Second
+	tagWriteRetryMaxBackoff     = time.Hour
+)
+
+// pendingTagWrites holds the failed tag writes of a broker without a state
+// store, which are lost if it restarts. Its lock is held for the whole of
+// every change to the queue, including the write of the tags to RDS, with or
+// without a state store, so that a write and a retry of the same tags can't
+// undo each other.
+type pendingTagWrites struct {
+	lock      sync.Mutex
+	tagWrites map[string]statestore.PendingTagWrite
+}
+
+func newPendingTagWrites() *pendingTagWrites {
+	return &pendingTagWrites{tagWrites: map[string]statestore.PendingTagWrite{}}
+}
+
+// tagWriteRetryBackoff is how long to wait before the next attempt of a tag
+// write which has failed this many times, doubling up to an hour.
+func tagWriteRetryBackoff(attempts int) time.Duration {
+	backoff := tagWriteRetryInitialBackoff
+	for i := 1; i < attempts && backoff < tagWriteRetryMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > tagWriteRetryMaxBackoff {
+		backoff = tagWriteRetryMaxBackoff
+	}
+	return backoff
+}
+
+// writeTags adds tags which record the broker's view of an instance after
+// RDS has already been changed, such as its new plan. A failed write is
+// queued to be retried by RetryTagWrites rather than failing the request,
+// as the change has already been made. Later writes to the same instance
+// take precedence over queued ones.
+func (b *RDSBroker) writeTags(instanceID string, resourceARN string, tags map[string]string) {
+	logData := lager.Data{instanceIDLogKey: instanceID, "resourceARN": resourceARN}
+
+	b.pendingTagWrites.lock.Lock()
+	defer b.pendingTagWrites.lock.Unlock()
+
+	writeErr := b.dbInstance.AddTagsToResource(resourceARN, awsrds.BuildRDSTags(tags))
+
+	pending, ok, err := b.pendingTagWrite(resourceARN)
+	if err != nil {
+		b.logger.Error("write-tags.load-pending", err, logData)
+	}
+
+	if writeErr == nil {
+		if !ok {
+			return
+		}
+		for key := range tags {
+			delete(pending.Tags, key)
+		}
+		if len(pending.Tags) == 0 {
+			err = b.forgetPendingTagWrite(resourceARN)
+		} else {
+			err = b.savePendingTagWrite(pending)
+		}
+		if err != nil {
+			b.logger.Error("write-tags.update-pending", err, logData)
+		}
+		return
+	}
+
+	b.logger.Error("write-tags", writeErr, logData)
+	if !ok {
+		pending = statestore.PendingTagWrite{
+			InstanceID:  instanceID,
+			ResourceARN: resourceARN,
+			Tags:        map[string]string{},
+		}
+	}
+	for key, value := range tags {
+		pending.Tags[key] = value
+	}
+	pending.Attempts++
+	pending.NextAttemptAt = time.Now().Add(tagWriteRetryBackoff(pending.Attempts)).UTC()
+	if err := b.savePendingTagWrite(pending); err != nil {
+		b.logger.Error("write-tags.queue", err, logData)
+	}
+}
+
+// RetryTagWrites retries the queued tag writes which are due, backing off
+// those which fail again.
+func (b *RDSBroker) RetryTagWrites() error {
+	return b.retryTagWrites(false)
+}
+
+// ForceTagWrites retries every queued tag write straight away, whether or not
+// it is due, for operators who don't want to wait for the back off.
+func (b *RDSBroker) ForceTagWrites() error {
+	return b.retryTagWrites(true)
+}
+
+func (b *RDSBroker) retryTagWrites(force bool) error {
+	b.pendingTagWrites.lock.Lock()
+	defer b.pendingTagWrites.lock.Unlock()
+
+	tagWrites, err := b.listPendingTagWrites()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, tagWrite := range tagWrites {
+		if !force && tagWrite.NextAttemptAt.After(now) {
+			continue
+		}
+		logData := lager.Data{
+			instanceIDLogKey: tagWrite.InstanceID,
+			"resourceARN":    tagWrite.ResourceARN,
+			"attempts":       tagWrite.Attempts,
+		}
+
+		err := b.dbInstance.AddTagsToResource(tagWrite.ResourceARN, awsrds.BuildRDSTags(tagWrite.Tags))
+		if err == awsrds.ErrDBInstanceDoesNotExist {
+			b.logger.Info("retry-tag-writes.instance-gone", logData)
+			err = b.forgetPendingTagWrite(tagWrite.ResourceARN)
+		} else if err != nil {
+			b.logger.Error("retry-tag-writes.failed", err, logData)
+			tagWrite.Attempts++
+			tagWrite.NextAttemptAt = now.Add(tagWriteRetryBackoff(tagWrite.Attempts)).UTC()
+			err = b.savePendingTagWrite(tagWrite)
+		} else {
+			b.logger.Info("retry-tag-writes.written", logData)
+			err = b.forgetPendingTagWrite(tagWrite.ResourceARN)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunTagWriteRetries retries the queued tag writes at every interval. Each
+// broker process runs it, as without a state store the queue is only held
+// by the process whose write failed.
+func (b *RDSBroker) RunTagWriteRetries(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := b.RetryTagWrites(); err != nil {
+			b.logger.Error("retry-tag-writes", err)
+		}
+	}
+}
+
+// removeTag removes a tag from a DB instance, along with any queued write of
+// it, so that a retry doesn't bring the tag back.
+func (b *RDSBroker) removeTag(dbInstanceIdentifier string, tagKey string) error {
+	b.pendingTagWrites.lock.Lock()
+	defer b.pendingTagWrites.lock.Unlock()
+
+	tagWrites, err := b.listPendingTagWrites()
+	if err != nil {
+		return err
+	}
+	for _, tagWrite := range tagWrites {
+		if b.dbInstanceIdentifier(tagWrite.InstanceID) != dbInstanceIdentifier {
+			continue
+		}
+		if _, ok := tagWrite.Tags[tagKey]; !ok {
+			continue
+		}
+		delete(tagWrite.Tags, tagKey)
+		if len(tagWrite.Tags) == 0 {
+			err = b.forgetPendingTagWrite(tagWrite.ResourceARN)
+		} else {
+			err = b.savePendingTagWrite(tagWrite)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return b.dbInstance.RemoveTag(dbInstanceIdentifier, tagKey)
+}
+
+// queuedTagWrites lists the queued tag writes, for the metrics.
+func (b *RDSBroker) queuedTagWrites() ([]statestore.PendingTagWrite, error) {
+	b.pendingTagWrites.lock.Lock()
+	defer b.pendingTagWrites.lock.Unlock()
+
+	return b.listPendingTagWrites()
+}
+
+// The functions below must only be called with the lock of
+// b.pendingTagWrites held.
+
+func (b *RDSBroker) listPendingTagWrites() ([]statestore.PendingTagWrite, error) {
+	if b.stateStore != nil {
+		return b.stateStore.ListPendingTagWrites()
+	}
+
+	tagWrites := []statestore.PendingTagWrite{}
+	for _, tagWrite := range b.pendingTagWrites.tagWrites {
+		tagWrites = append(tagWrites, copyPendingTagWrite(tagWrite))
+	}
+	return tagWrites, nil
+}
+
+func (b *RDSBroker) pendingTagWrite(resourceARN string) (statestore.PendingTagWrite, bool, error) {
+	tagWrites, err := b.listPendingTagWrites()
+	if err != nil {
+		return statestore.PendingTagWrite{}, false, err
+	}
+	for _, tagWrite := range tagWrites {
+		if tagWrite.ResourceARN == resourceARN {
+			return tagWrite, true, nil
+		}
+	}
+	return statestore.PendingTagWrite{}, false, nil
+}
+
+func (b *RDSBroker) savePendingTagWrite(tagWrite statestore.PendingTagWrite) error {
+	if b.stateStore != nil {
+		return b.stateStore.RecordPendingTagWrite(tagWrite)
+	}
+
+	b.pendingTagWrites.tagWrites[tagWrite.ResourceARN] = copyPendingTagWrite(tagWrite)
+	return nil
+}
+
+func (b *RDSBroker) forgetPendingTagWrite(resourceARN string) error {
+	if b.stateStore != nil {
+		return b.stateStore.ForgetPendingTagWrite(resourceARN)
+	}
+
+	delete(b.pendingTagWrites.tagWrites, resourceARN)
+	return nil
+}
+
+func copyPendingTagWrite(tagWrite statestore.PendingTagWrite) statestore.PendingTagWrite {
+	tags := map[string]string{}
+	for key, value := range tagWrite.Tags {
+		tags[key] = value
+	}
+	tagWrite.Tags = tags
+	return tagWrite
+}
+
+// writeTagWriteMetrics writes the number of queued tag writes in the
+// Prometheus text exposition format.
+func writeTagWriteMetrics(w io.Writer, tagWrites []statestore.PendingTagWrite) {
+	const name = "rds_broker_pending_tag_writes"
+	fmt.Fprintf(w, "# HELP %s Failed writes of tags to DB instances waiting to be retried.\n", name)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+	fmt.Fprintf(w, "%s %d\n", name, len(tagWrites))
+}
@@ -0,0 +1,177 @@
+package rdsbroker_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+	"github.com/alphagov/paas-rds-broker/statestore"
+	statestorefake "github.com/alphagov/paas-rds-broker/statestore/fakes"
+)
+
+var _ = Describe("Tag write retries", func() {
+	const dbInstanceArn = "arn:aws:rds:rds-region:123456789012:db:cf-instance-id"
+
+	var (
+		rdsInstance *rdsfake.FakeRDSInstance
+		rdsBroker   *RDSBroker
+		dbInstance  *rds.DBInstance
+		tagsByName  map[string]string
+	)
+
+	BeforeEach(func() {
+		dbInstance = &rds.DBInstance{
+			DBInstanceArn: aws.String(dbInstanceArn),
+			DBParameterGroups: []*rds.DBParameterGroupStatus{
+				{DBParameterGroupName: aws.String("cf-postgres10-mybroker")},
+			},
+		}
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		rdsInstance.ModifyReturns(dbInstance, nil)
+		tagsByName = map[string]string{
+			StateUpdateSettings:  "true",
+			awsrds.TagServiceID:  "Service-1",
+			awsrds.TagPlanID:     "Plan-1",
+			awsrds.TagSpaceID:    "space-id",
+			awsrds.TagBrokerName: "mybroker",
+		}
+
+		rdsBroker = newTestBroker(testConfig(), rdsInstance, nil, lagertest.NewTestLogger("tag_writes_test"))
+	})
+
+	Context("without a state store", func() {
+		It("queues a failed tag write after a modify and backs off its retry", func() {
+			rdsInstance.AddTagsToResourceReturns(errors.New("throttled"))
+
+			_, err := rdsBroker.PostRestoreTasks("instance-id", dbInstance, tagsByName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+
+			Expect(rdsBroker.RetryTagWrites()).To(Succeed())
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+		})
+
+		It("retries a queued tag write before it is due when forced", func() {
+			rdsInstance.AddTagsToResourceReturnsOnCall(0, errors.New("throttled"))
+
+			_, err := rdsBroker.PostRestoreTasks("instance-id", dbInstance, tagsByName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rdsBroker.ForceTagWrites()).To(Succeed())
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(2))
+
+			Expect(rdsBroker.ForceTagWrites()).To(Succeed())
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(2))
+		})
+
+		It("drops the queued tags which a later write succeeds in writing", func() {
+			rdsInstance.AddTagsToResourceReturnsOnCall(0, errors.New("throttled"))
+
+			_, err := rdsBroker.PostRestoreTasks("instance-id", dbInstance, tagsByName)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rdsBroker.PostRestoreTasks("instance-id", dbInstance, tagsByName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(2))
+
+			Expect(rdsBroker.RetryTagWrites()).To(Succeed())
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(2))
+		})
+	})
+
+	Context("with a state store", func() {
+		var stateStore *statestorefake.FakeStateStore
+
+		BeforeEach(func() {
+			stateStore = &statestorefake.FakeStateStore{}
+			rdsBroker.SetStateStore(stateStore)
+		})
+
+		It("persists a failed tag write", func() {
+			rdsInstance.AddTagsToResourceReturns(errors.New("throttled"))
+
+			_, err := rdsBroker.PostRestoreTasks("instance-id", dbInstance, tagsByName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(stateStore.RecordPendingTagWriteCallCount()).To(Equal(1))
+			tagWrite := stateStore.RecordPendingTagWriteArgsForCall(0)
+			Expect(tagWrite.InstanceID).To(Equal("instance-id"))
+			Expect(tagWrite.ResourceARN).To(Equal(dbInstanceArn))
+			Expect(tagWrite.Tags).To(HaveKeyWithValue(awsrds.TagPlanID, "Plan-1"))
+			Expect(tagWrite.Attempts).To(Equal(1))
+			Expect(tagWrite.NextAttemptAt).To(BeTemporally("~", time.Now().Add(30*time.Second), 5*time.Second))
+		})
+
+		It("retries the tag writes which are due and forgets them once written", func() {
+			stateStore.ListPendingTagWritesReturns([]statestore.PendingTagWrite{
+				{
+					InstanceID:    "instance-id",
+					ResourceARN:   dbInstanceArn,
+					Tags:          map[string]string{awsrds.TagPlanID: "Plan-1"},
+					Attempts:      2,
+					NextAttemptAt: time.Now().Add(-time.Minute),
+				},
+				{
+					InstanceID:    "other-instance-id",
+					ResourceARN:   "arn:aws:rds:rds-region:123456789012:db:cf-other-instance-id",
+					Tags:          map[string]string{awsrds.TagPlanID: "Plan-1"},
+					Attempts:      1,
+					NextAttemptAt: time.Now().Add(time.Minute),
+				},
+			}, nil)
+
+			Expect(rdsBroker.RetryTagWrites()).To(Succeed())
+
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+			resourceARN, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+			Expect(resourceARN).To(Equal(dbInstanceArn))
+			Expect(awsrds.RDSTagsValues(tags)).To(Equal(map[string]string{awsrds.TagPlanID: "Plan-1"}))
+			Expect(stateStore.ForgetPendingTagWriteCallCount()).To(Equal(1))
+			Expect(stateStore.ForgetPendingTagWriteArgsForCall(0)).To(Equal(dbInstanceArn))
+		})
+
+		It("backs off a retry which fails again", func() {
+			stateStore.ListPendingTagWritesReturns([]statestore.PendingTagWrite{
+				{
+					InstanceID:    "instance-id",
+					ResourceARN:   dbInstanceArn,
+					Tags:          map[string]string{awsrds.TagPlanID: "Plan-1"},
+					Attempts:      2,
+					NextAttemptAt: time.Now().Add(-time.Minute),
+				},
+			}, nil)
+			rdsInstance.AddTagsToResourceReturns(errors.New("throttled"))
+
+			Expect(rdsBroker.RetryTagWrites()).To(Succeed())
+
+			Expect(stateStore.RecordPendingTagWriteCallCount()).To(Equal(1))
+			tagWrite := stateStore.RecordPendingTagWriteArgsForCall(0)
+			Expect(tagWrite.Attempts).To(Equal(3))
+			Expect(tagWrite.NextAttemptAt).To(BeTemporally("~", time.Now().Add(2*time.Minute), 5*time.Second))
+		})
+
+		It("forgets the tag writes of instances which no longer exist", func() {
+			stateStore.ListPendingTagWritesReturns([]statestore.PendingTagWrite{
+				{
+					InstanceID:    "instance-id",
+					ResourceARN:   dbInstanceArn,
+					Tags:          map[string]string{awsrds.TagPlanID: "Plan-1"},
+					NextAttemptAt: time.Now().Add(-time.Minute),
+				},
+			}, nil)
+			rdsInstance.AddTagsToResourceReturns(awsrds.ErrDBInstanceDoesNotExist)
+
+			Expect(rdsBroker.RetryTagWrites()).To(Succeed())
+
+			Expect(stateStore.ForgetPendingTagWriteCallCount()).To(Equal(1))
+			Expect(stateStore.RecordPendingTagWriteCallCount()).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,107 @@
+package rdsbroker
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// checkTemplateDatabaseInstance checks a new instance may be created with
+// the schema of the template_database_instance it was given: a Postgres
+// instance of this broker in the same org and space.
+func (b *RDSBroker) checkTemplateDatabaseInstance(details domain.ProvisionDetails, servicePlan ServicePlan, templateInstanceID string) error {
+	if templateInstanceID == "" {
+		return newUserError(ErrCodeInvalidParameters, "Invalid guid: '%s'", templateInstanceID)
+	}
+	if engine := aws.StringValue(servicePlan.RDSProperties.Engine); engine != "postgres" {
+		return newUserError(ErrCodeUnsupportedByEngine, "Template databases not supported for engine '%s'", engine)
+	}
+
+	templateDBInstance, err := b.dbInstance.Describe(b.dbInstanceIdentifier(templateInstanceID))
+	if err != nil {
+		if err == awsrds.ErrDBInstanceDoesNotExist {
+			return newUserError(ErrCodeRestoreSourceNotFound, "Template database instance '%s' not found", templateInstanceID)
+		}
+		return err
+	}
+	if aws.StringValue(templateDBInstance.Engine) != "postgres" {
+		return newUserError(ErrCodeUnsupportedByEngine, "Template database instance '%s' is not a Postgres instance", templateInstanceID)
+	}
+
+	tags, err := b.dbInstance.GetResourceTags(aws.StringValue(templateDBInstance.DBInstanceArn))
+	if err != nil {
+		return err
+	}
+	tagsByName := awsrds.RDSTagsValues(tags)
+	if tagsByName[awsrds.TagBrokerName] != b.brokerName {
+		return newUserError(ErrCodeRestoreSourceNotFound, "Template database instance '%s' not found", templateInstanceID)
+	}
+	if tagsByName[awsrds.TagSpaceID] != details.SpaceGUID || tagsByName[awsrds.TagOrganizationID] != details.OrganizationGUID {
+		return newUserError(ErrCodeRestoreNotPermitted, "The template database instance is not in the same org or space")
+	}
+	if tagsByName[awsrds.TagKubernetesNamespace] != platformContextFrom(details.RawContext).kubernetesNamespace() {
+		return newUserError(ErrCodeRestoreNotPermitted, "The template database instance is not in the same namespace")
+	}
+
+	return nil
+}
+
+// ensureSchemaCopied copies the schema of the template database instance a
+// new instance was created with into its database in the background, once
+// it is available, returning true while it is being copied. The tag asking
+// for the copy is removed once it is done. Failing the provision is left to
+// the caller, as the instance is of little use without the schema.
+func (b *RDSBroker) ensureSchemaCopied(instanceID string, dbInstance *rds.DBInstance, tagsByName map[string]string) (bool, error) {
+	templateInstanceID, exists := tagsByName[awsrds.TagSchemaCopyFrom]
+	if !exists {
+		return false, nil
+	}
+
+	copying, err := b.ensureBackgroundStep(instanceID, dbInstance, tagsByName, awsrds.TagSchemaCopyProgress, func() error {
+		return b.copySchema(instanceID, dbInstance, templateInstanceID)
+	})
+	if err != nil {
+		return false, fmt.Errorf("Copying the schema of template database instance '%s': %s", templateInstanceID, err)
+	}
+	return copying, nil
+}
+
+func (b *RDSBroker) copySchema(instanceID string, dbInstance *rds.DBInstance, templateInstanceID string) error {
+	b.logger.Info("copy-template-schema", lager.Data{
+		instanceIDLogKey:           instanceID,
+		"templateDatabaseInstance": templateInstanceID,
+	})
+
+	templateDBInstance, err := b.dbInstance.Describe(b.dbInstanceIdentifier(templateInstanceID))
+	if err != nil {
+		return fmt.Errorf("Describing template database instance: %s", err)
+	}
+	templatePassword, err := b.masterPasswordForDBInstance(templateInstanceID, templateDBInstance)
+	if err != nil {
+		return err
+	}
+
+	sqlEngine, err := b.openSQLEngineForDBInstance(instanceID, b.dbNameFromDBInstance(instanceID, dbInstance), dbInstance)
+	if err != nil {
+		return err
+	}
+	defer sqlEngine.Close()
+
+	err = sqlEngine.CopySchemaFrom(
+		awsrds.GetDBAddress(templateDBInstance.Endpoint),
+		awsrds.GetDBPort(templateDBInstance.Endpoint),
+		b.dbNameFromDBInstance(templateInstanceID, templateDBInstance),
+		aws.StringValue(templateDBInstance.MasterUsername),
+		templatePassword,
+	)
+	if err != nil {
+		return err
+	}
+
+	return b.removeTag(b.dbInstanceIdentifier(instanceID), awsrds.TagSchemaCopyFrom)
+}
@@ -0,0 +1,246 @@
+package rdsbroker_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+	sqlfake "github.com/alphagov/paas-rds-broker/sqlengine/fakes"
+)
+
+var _ = Describe("Template databases", func() {
+	var (
+		rdsInstance        *rdsfake.FakeRDSInstance
+		sqlEngine          *sqlfake.FakeSQLEngine
+		rdsBroker          *RDSBroker
+		templateDBInstance *rds.DBInstance
+		newDBInstance      *rds.DBInstance
+		tagsByArn          map[string]map[string]string
+	)
+
+	BeforeEach(func() {
+		templateDBInstance = &rds.DBInstance{
+			DBInstanceIdentifier: aws.String("cf-template-1"),
+			DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-template-1"),
+			DBInstanceStatus:     aws.String("available"),
+			Engine:               aws.String("postgres"),
+			DBName:               aws.String("templatedb"),
+			MasterUsername:       aws.String("template-master"),
+			Endpoint: &rds.Endpoint{
+				Address: aws.String("template-address"),
+				Port:    aws.Int64(5432),
+			},
+		}
+		newDBInstance = testDBInstance()
+		tagsByArn = map[string]map[string]string{
+			"arn:aws:rds:rds-region:1234567890:db:cf-template-1": {
+				"Broker Name":     "mybroker",
+				"Plan ID":         "Plan-1",
+				"Organization ID": "organization-id",
+				"Space ID":        "space-id",
+			},
+			"arn:aws:rds:rds-region:1234567890:db:cf-instance-1": {
+				"Broker Name":              "mybroker",
+				"Plan ID":                  "Plan-1",
+				"Pending Schema Copy From": "template-1",
+			},
+		}
+
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		rdsInstance.DescribeStub = func(id string) (*rds.DBInstance, error) {
+			switch id {
+			case "cf-template-1":
+				return templateDBInstance, nil
+			case "cf-instance-1":
+				return newDBInstance, nil
+			}
+			return nil, awsrds.ErrDBInstanceDoesNotExist
+		}
+		rdsInstance.GetResourceTagsStub = func(arn string, opts ...awsrds.DescribeOption) ([]*rds.Tag, error) {
+			return awsrds.BuildRDSTags(tagsByArn[arn]), nil
+		}
+
+		sqlEngine = &sqlfake.FakeSQLEngine{}
+
+		config := testConfig()
+		config.AllowUserProvisionParameters = true
+		config.FillDefaults()
+		rdsBroker = newTestBroker(config, rdsInstance, sqlEngine, lagertest.NewTestLogger("template_databases_test"))
+	})
+
+	Describe("provisioning with template_database_instance", func() {
+		var details domain.ProvisionDetails
+
+		BeforeEach(func() {
+			details = domain.ProvisionDetails{
+				ServiceID:        "Service-1",
+				PlanID:           "Plan-1",
+				OrganizationGUID: "organization-id",
+				SpaceGUID:        "space-id",
+				RawParameters:    json.RawMessage(`{"template_database_instance": "template-1"}`),
+			}
+		})
+
+		It("creates the instance tagged to have the template's schema copied into it", func() {
+			_, err := rdsBroker.Provision(context.Background(), "instance-1", details, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rdsInstance.CreateCallCount()).To(Equal(1))
+			tagsByName := awsrds.RDSTagsValues(rdsInstance.CreateArgsForCall(0).Tags)
+			Expect(tagsByName).To(HaveKeyWithValue("Pending Schema Copy From", "template-1"))
+			Expect(rdsInstance.RestoreCallCount()).To(BeZero())
+		})
+
+		It("fails if the template doesn't exist", func() {
+			details.RawParameters = json.RawMessage(`{"template_database_instance": "template-2"}`)
+
+			_, err := rdsBroker.Provision(context.Background(), "instance-1", details, true)
+			Expect(err).To(MatchError("Template database instance 'template-2' not found"))
+			Expect(rdsInstance.CreateCallCount()).To(BeZero())
+		})
+
+		It("fails if the template belongs to another broker", func() {
+			tagsByArn["arn:aws:rds:rds-region:1234567890:db:cf-template-1"]["Broker Name"] = "otherbroker"
+
+			_, err := rdsBroker.Provision(context.Background(), "instance-1", details, true)
+			Expect(err).To(MatchError("Template database instance 'template-1' not found"))
+			Expect(rdsInstance.CreateCallCount()).To(BeZero())
+		})
+
+		It("fails if the template is in another space", func() {
+			details.SpaceGUID = "other-space-id"
+
+			_, err := rdsBroker.Provision(context.Background(), "instance-1", details, true)
+			Expect(err).To(MatchError("The template database instance is not in the same org or space"))
+			Expect(rdsInstance.CreateCallCount()).To(BeZero())
+		})
+
+		It("fails if the template isn't a Postgres instance", func() {
+			templateDBInstance.Engine = aws.String("mysql")
+
+			_, err := rdsBroker.Provision(context.Background(), "instance-1", details, true)
+			Expect(err).To(MatchError("Template database instance 'template-1' is not a Postgres instance"))
+			Expect(rdsInstance.CreateCallCount()).To(BeZero())
+		})
+
+		It("fails for plans which aren't Postgres", func() {
+			details.PlanID = "Plan-2"
+
+			_, err := rdsBroker.Provision(context.Background(), "instance-1", details, true)
+			Expect(err).To(MatchError("Template databases not supported for engine 'mysql'"))
+			Expect(rdsInstance.CreateCallCount()).To(BeZero())
+		})
+
+		It("fails if combined with a restore", func() {
+			details.RawParameters = json.RawMessage(`{"template_database_instance": "template-1", "restore_previous": true}`)
+
+			_, err := rdsBroker.Provision(context.Background(), "instance-1", details, true)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Cannot use template_database_instance along with adopt_db_instance or the restore parameters"))
+		})
+	})
+
+	Describe("polling the provision", func() {
+		var pollDetails domain.PollDetails
+
+		BeforeEach(func() {
+			pollDetails = domain.PollDetails{ServiceID: "Service-1", PlanID: "Plan-1"}
+		})
+
+		It("starts copying the template's schema into the new instance's database in the background", func() {
+			lastOperation, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lastOperation.State).To(Equal(domain.InProgress))
+			Expect(lastOperation.Description).To(ContainSubstring("is having the schema of its template database instance copied into it"))
+
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(BeNumerically(">=", 1))
+			arn, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+			Expect(arn).To(Equal("arn:aws:rds:rds-region:1234567890:db:cf-instance-1"))
+			Expect(awsrds.RDSTagsValues(tags)["Schema Copy Progress"]).To(HavePrefix("running since "))
+
+			Eventually(rdsInstance.RemoveTagCallCount).Should(Equal(2))
+			address, _, dbname, username, _ := sqlEngine.OpenArgsForCall(0)
+			Expect(address).To(Equal("endpoint-address"))
+			Expect(dbname).To(Equal("mydb"))
+			Expect(username).To(Equal("master-username"))
+
+			Expect(sqlEngine.CopySchemaFromCallCount()).To(Equal(1))
+			address, port, dbname, username, password := sqlEngine.CopySchemaFromArgsForCall(0)
+			Expect(address).To(Equal("template-address"))
+			Expect(port).To(Equal(int64(5432)))
+			Expect(dbname).To(Equal("templatedb"))
+			Expect(username).To(Equal("template-master"))
+			Expect(password).ToNot(BeEmpty())
+
+			id, tagKey := rdsInstance.RemoveTagArgsForCall(0)
+			Expect(id).To(Equal("cf-instance-1"))
+			Expect(tagKey).To(Equal("Pending Schema Copy From"))
+			id, tagKey = rdsInstance.RemoveTagArgsForCall(1)
+			Expect(id).To(Equal("cf-instance-1"))
+			Expect(tagKey).To(Equal("Schema Copy Progress"))
+		})
+
+		It("records the failure and keeps the tag if the schema can't be copied", func() {
+			sqlEngine.CopySchemaFromReturns(errors.New("pg_restore failed: exit status 1"))
+
+			lastOperation, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lastOperation.State).To(Equal(domain.InProgress))
+
+			Eventually(rdsInstance.AddTagsToResourceCallCount).Should(Equal(2))
+			_, tags := rdsInstance.AddTagsToResourceArgsForCall(1)
+			Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue("Schema Copy Progress", "failed: pg_restore failed: exit status 1"))
+			Expect(rdsInstance.RemoveTagCallCount()).To(BeZero())
+		})
+
+		It("doesn't run the copy again while it is running", func() {
+			tagsByArn["arn:aws:rds:rds-region:1234567890:db:cf-instance-1"]["Schema Copy Progress"] = "running since " + time.Now().UTC().Format(time.RFC3339)
+
+			lastOperation, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lastOperation.State).To(Equal(domain.InProgress))
+			Consistently(sqlEngine.CopySchemaFromCallCount).Should(BeZero())
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(BeZero())
+		})
+
+		It("fails the provision once the copy has failed", func() {
+			tagsByArn["arn:aws:rds:rds-region:1234567890:db:cf-instance-1"]["Schema Copy Progress"] = "failed: pg_restore failed: exit status 1"
+
+			lastOperation, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+			Expect(err).To(MatchError("Copying the schema of template database instance 'template-1': pg_restore failed: exit status 1"))
+			Expect(lastOperation.State).To(Equal(domain.Failed))
+			Expect(sqlEngine.CopySchemaFromCallCount()).To(BeZero())
+		})
+
+		It("fails the provision if a copy was abandoned", func() {
+			tagsByArn["arn:aws:rds:rds-region:1234567890:db:cf-instance-1"]["Schema Copy Progress"] = "running since " + time.Now().Add(-2*time.Hour).UTC().Format(time.RFC3339)
+
+			lastOperation, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+			Expect(err).To(MatchError("Copying the schema of template database instance 'template-1': abandoned as it had not finished within 1h0m0s"))
+			Expect(lastOperation.State).To(Equal(domain.Failed))
+
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+			_, tags := rdsInstance.AddTagsToResourceArgsForCall(0)
+			Expect(awsrds.RDSTagsValues(tags)).To(HaveKeyWithValue("Schema Copy Progress", "failed: abandoned as it had not finished within 1h0m0s"))
+		})
+
+		It("doesn't copy a schema into instances without a template", func() {
+			delete(tagsByArn["arn:aws:rds:rds-region:1234567890:db:cf-instance-1"], "Pending Schema Copy From")
+
+			_, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sqlEngine.CopySchemaFromCallCount()).To(BeZero())
+		})
+	})
+})
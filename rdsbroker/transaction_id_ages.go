@@ -0,0 +1,124 @@
+package rdsbroker
+
+import (
+	"fmt"
+	"io"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// transactionIDWraparoundAge is roughly how old the oldest unfrozen
+// transaction ID of a Postgres database can get before the server stops
+// accepting writes to it, to avoid transaction ID wraparound.
+const transactionIDWraparoundAge = 2000000000
+
+// transactionIDAge is the oldest unfrozen transaction ID of the databases on
+// a Postgres DB instance.
+type transactionIDAge struct {
+	instanceID           string
+	dbInstanceIdentifier string
+	planID               string
+	dbName               string
+	age                  int64
+}
+
+// checkTransactionIDAges is the housekeeping job which measures how old the
+// oldest unfrozen transaction ID on each available Postgres DB instance is,
+// and logs the instances where it is old enough for autovacuum to be falling
+// behind. Instances which can't be measured are logged and left out. It
+// needs to connect to every instance, so it only runs when a warning age is
+// configured.
+func (b *RDSBroker) checkTransactionIDAges(dbInstances []managedDBInstance) error {
+	if b.transactionIDAgeWarning == 0 {
+		return nil
+	}
+
+	ages := []transactionIDAge{}
+	for _, instance := range dbInstances {
+		if aws.StringValue(instance.dbInstance.Engine) != "postgres" ||
+			aws.StringValue(instance.dbInstance.DBInstanceStatus) != "available" {
+			continue
+		}
+
+		age, err := b.transactionIDAge(instance)
+		if err != nil {
+			b.logger.Error("check-transaction-id-ages.failed", err, lager.Data{dbInstanceLogKey: instance.dbInstance.DBInstanceIdentifier})
+			continue
+		}
+		if age.age >= b.transactionIDAgeWarning {
+			b.logger.Info("check-transaction-id-ages.old", lager.Data{
+				instanceIDLogKey: age.instanceID,
+				"dbName":         age.dbName,
+				"age":            age.age,
+			})
+		}
+		ages = append(ages, age)
+	}
+
+	b.instanceMetrics.lock.Lock()
+	b.instanceMetrics.transactionIDAges = ages
+	b.instanceMetrics.lock.Unlock()
+
+	return nil
+}
+
+func (b *RDSBroker) transactionIDAge(instance managedDBInstance) (transactionIDAge, error) {
+	dbInstanceIdentifier := aws.StringValue(instance.dbInstance.DBInstanceIdentifier)
+	instanceID := b.dbInstanceIdentifierToServiceInstanceID(dbInstanceIdentifier)
+
+	sqlEngine, err := b.openSQLEngineForDBInstance(instanceID, b.dbNameFromDBInstance(instanceID, instance.dbInstance), instance.dbInstance)
+	if err != nil {
+		return transactionIDAge{}, err
+	}
+	defer sqlEngine.Close()
+
+	ages, err := sqlEngine.TransactionIDAges()
+	if err != nil {
+		return transactionIDAge{}, err
+	}
+
+	oldest := transactionIDAge{
+		instanceID:           instanceID,
+		dbInstanceIdentifier: dbInstanceIdentifier,
+		planID:               instance.tagsByName[awsrds.TagPlanID],
+	}
+	for dbName, age := range ages {
+		if age > oldest.age || (age == oldest.age && dbName < oldest.dbName) {
+			oldest.dbName = dbName
+			oldest.age = age
+		}
+	}
+	return oldest, nil
+}
+
+// oldTransactionIDWarning warns about a service instance whose oldest
+// unfrozen transaction ID was old enough when housekeeping last measured it.
+func (b *RDSBroker) oldTransactionIDWarning(instanceID string) string {
+	b.instanceMetrics.lock.RLock()
+	defer b.instanceMetrics.lock.RUnlock()
+	for _, age := range b.instanceMetrics.transactionIDAges {
+		if age.instanceID == instanceID && age.age >= b.transactionIDAgeWarning {
+			return fmt.Sprintf(
+				"The oldest transaction ID in database '%s' is %d transactions old. Autovacuum is falling behind, and the database will stop accepting writes if it reaches %d. Check for long running transactions and tables autovacuum can't process.",
+				age.dbName, age.age, transactionIDWraparoundAge,
+			)
+		}
+	}
+	return ""
+}
+
+// writeTransactionIDAgeMetrics writes the age of the oldest unfrozen
+// transaction ID of each Postgres DB instance in the Prometheus text
+// exposition format.
+func writeTransactionIDAgeMetrics(w io.Writer, ages []transactionIDAge) {
+	const name = "rds_broker_transaction_id_age"
+	fmt.Fprintf(w, "# HELP %s How many transactions old the oldest unfrozen transaction ID of the databases on the DB instance is.\n", name)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+	for _, age := range ages {
+		fmt.Fprintf(w, "%s{instance_id=%q,db_instance_identifier=%q,plan_id=%q} %d\n",
+			name, age.instanceID, age.dbInstanceIdentifier, age.planID, age.age)
+	}
+}
@@ -0,0 +1,127 @@
+package rdsbroker_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+	sqlfake "github.com/alphagov/paas-rds-broker/sqlengine/fakes"
+)
+
+var _ = Describe("Transaction ID ages", func() {
+	var (
+		config      Config
+		rdsInstance *rdsfake.FakeRDSInstance
+		sqlEngine   *sqlfake.FakeSQLEngine
+		rdsBroker   *RDSBroker
+	)
+
+	BeforeEach(func() {
+		config = testConfig()
+		config.TransactionIDAgeWarning = 1000000000
+
+		postgresInstance := testDBInstance()
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		rdsInstance.DescribeReturns(postgresInstance, nil)
+		rdsInstance.DescribeByTagReturns([]*rds.DBInstance{
+			postgresInstance,
+			{
+				DBInstanceIdentifier: aws.String("cf-instance-2"),
+				DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-instance-2"),
+				DBInstanceStatus:     aws.String("available"),
+				Engine:               aws.String("mysql"),
+			},
+			{
+				DBInstanceIdentifier: aws.String("cf-instance-3"),
+				DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-instance-3"),
+				DBInstanceStatus:     aws.String("stopped"),
+				Engine:               aws.String("postgres"),
+			},
+		}, nil)
+		rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+			"Broker Name": "mybroker",
+			"Service ID":  "Service-1",
+			"Plan ID":     "Plan-1",
+		}), nil)
+
+		sqlEngine = &sqlfake.FakeSQLEngine{}
+		sqlEngine.TransactionIDAgesReturns(map[string]int64{
+			"mydb":      1200000000,
+			"postgres":  300000000,
+			"template1": 300000000,
+		}, nil)
+	})
+
+	JustBeforeEach(func() {
+		rdsBroker = newTestBroker(config, rdsInstance, sqlEngine, lagertest.NewTestLogger("transaction_id_ages_test"))
+	})
+
+	metrics := func() string {
+		recorder := httptest.NewRecorder()
+		rdsBroker.AdminHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/metrics", nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		return recorder.Body.String()
+	}
+
+	getInstanceWarnings := func() interface{} {
+		instance, err := rdsBroker.GetInstance(context.Background(), "instance-1", domain.FetchInstanceDetails{})
+		Expect(err).NotTo(HaveOccurred())
+		return instance.Parameters.(map[string]interface{})["warnings"]
+	}
+
+	It("serves the oldest transaction ID age of each available Postgres instance", func() {
+		Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+		Expect(sqlEngine.TransactionIDAgesCallCount()).To(Equal(1))
+		Expect(metrics()).To(ContainSubstring(
+			"# TYPE rds_broker_transaction_id_age gauge\n" +
+				`rds_broker_transaction_id_age{instance_id="instance-1",db_instance_identifier="cf-instance-1",plan_id="Plan-1"} 1200000000` + "\n"))
+	})
+
+	It("warns when fetching an instance whose transaction IDs are old", func() {
+		Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+		Expect(getInstanceWarnings()).To(ConsistOf(
+			"The oldest transaction ID in database 'mydb' is 1200000000 transactions old. Autovacuum is falling behind, and the database will stop accepting writes if it reaches 2000000000. Check for long running transactions and tables autovacuum can't process.",
+		))
+	})
+
+	It("doesn't warn when the transaction IDs are younger than the warning age", func() {
+		sqlEngine.TransactionIDAgesReturns(map[string]int64{"mydb": 200000000}, nil)
+		Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+		Expect(getInstanceWarnings()).To(BeNil())
+	})
+
+	It("leaves out instances which can't be measured", func() {
+		sqlEngine.TransactionIDAgesReturns(nil, errors.New("connection reset"))
+		Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+		Expect(metrics()).NotTo(ContainSubstring(`rds_broker_transaction_id_age{`))
+		Expect(getInstanceWarnings()).To(BeNil())
+	})
+
+	Context("when no warning age is configured", func() {
+		BeforeEach(func() {
+			config.TransactionIDAgeWarning = 0
+		})
+
+		It("doesn't connect to the instances", func() {
+			Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+			Expect(sqlEngine.TransactionIDAgesCallCount()).To(Equal(0))
+			Expect(getInstanceWarnings()).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,167 @@
+package rdsbroker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// TrialExpiryNotification is sent to the trial expiry webhook when a trial
+// instance is about to expire, and when it is stopped or deleted because it
+// has.
+type TrialExpiryNotification struct {
+	Event                string    `json:"event"`
+	InstanceID           string    `json:"instance_id"`
+	DBInstanceIdentifier string    `json:"db_instance_identifier"`
+	PlanID               string    `json:"plan_id"`
+	OrganizationID       string    `json:"organization_id"`
+	SpaceID              string    `json:"space_id"`
+	ExpiresAt            time.Time `json:"expires_at"`
+}
+
+const (
+	trialExpiryEventWarning = "expiring"
+	trialExpiryEventStopped = "stopped"
+	trialExpiryEventDeleted = "deleted"
+)
+
+// trialExpiresAt returns the value of the trial expiry tag of a new instance
+// on the plan, or an empty string if the plan is not a trial plan.
+func trialExpiresAt(servicePlan ServicePlan, now time.Time) string {
+	if servicePlan.TrialDays == 0 {
+		return ""
+	}
+	return now.Add(24 * time.Hour * time.Duration(servicePlan.TrialDays)).UTC().Format(time.RFC3339)
+}
+
+// ProcessTrialInstances enforces the expiry of instances on trial plans:
+// their owners are warned as the expiry approaches, expired instances are
+// stopped, and once the grace period has passed they are deleted. Instances
+// which have been updated to a plan without a trial are no longer tagged and
+// are left alone.
+func (b *RDSBroker) ProcessTrialInstances() error {
+	dbInstances, err := b.listManagedDBInstances()
+	if err != nil {
+		return err
+	}
+	return b.processTrialInstances(dbInstances)
+}
+
+func (b *RDSBroker) processTrialInstances(dbInstances []managedDBInstance) error {
+	for _, instance := range dbInstances {
+		tagsByName := instance.tagsByName
+		if tagsByName[awsrds.TagTrialExpires] == "" || tagsByName[awsrds.TagPurgeAfter] != "" {
+			continue
+		}
+
+		if err := b.processTrialInstance(instance.dbInstance, tagsByName, time.Now()); err != nil {
+			b.logger.Error("process-trial", err, lager.Data{dbInstanceLogKey: instance.dbInstance.DBInstanceIdentifier})
+		}
+	}
+
+	return nil
+}
+
+func (b *RDSBroker) processTrialInstance(dbInstance *rds.DBInstance, tagsByName map[string]string, now time.Time) error {
+	dbInstanceIdentifier := aws.StringValue(dbInstance.DBInstanceIdentifier)
+	instanceID := b.dbInstanceIdentifierToServiceInstanceID(dbInstanceIdentifier)
+	status := aws.StringValue(dbInstance.DBInstanceStatus)
+
+	expiresAt, err := time.Parse(time.RFC3339, tagsByName[awsrds.TagTrialExpires])
+	if err != nil {
+		return err
+	}
+
+	notification := TrialExpiryNotification{
+		InstanceID:           instanceID,
+		DBInstanceIdentifier: dbInstanceIdentifier,
+		PlanID:               tagsByName[awsrds.TagPlanID],
+		OrganizationID:       tagsByName[awsrds.TagOrganizationID],
+		SpaceID:              tagsByName[awsrds.TagSpaceID],
+		ExpiresAt:            expiresAt,
+	}
+	logData := lager.Data{instanceIDLogKey: instanceID, "status": status, "expiresAt": expiresAt}
+
+	switch {
+	case now.After(expiresAt.Add(b.trialGraceDuration)):
+		if status == "deleting" {
+			return nil
+		}
+		b.logger.Info("process-trial.delete", logData)
+		if b.softDeleteDuration > 0 {
+			err = b.softDeleteDBInstance(instanceID, "")
+		} else {
+			servicePlan, _ := b.catalog.FindServicePlan(tagsByName[awsrds.TagPlanID])
+			var skipFinalSnapshot bool
+			skipFinalSnapshot, err = b.resolveSkipFinalSnapshot(servicePlan, tagsByName[awsrds.TagSkipFinalSnapshot])
+			if err != nil {
+				return err
+			}
+			err = b.dbInstance.Delete(dbInstanceIdentifier, skipFinalSnapshot)
+		}
+		if err == awsrds.ErrDBInstanceDoesNotExist {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		notification.Event = trialExpiryEventDeleted
+		b.notifyTrialExpiry(notification)
+
+	case now.After(expiresAt):
+		stopped, err := b.keepStopped(dbInstance, "process-trial.stop", logData)
+		if err != nil || !stopped {
+			return err
+		}
+		notification.Event = trialExpiryEventStopped
+		b.notifyTrialExpiry(notification)
+
+	case now.After(expiresAt.Add(-b.trialWarningDuration)):
+		if tagsByName[awsrds.TagTrialExpiryWarned] != "" {
+			return nil
+		}
+		b.logger.Info("process-trial.warn", logData)
+		notification.Event = trialExpiryEventWarning
+		b.notifyTrialExpiry(notification)
+		return b.dbInstance.AddTagsToResource(
+			aws.StringValue(dbInstance.DBInstanceArn),
+			awsrds.BuildRDSTags(map[string]string{awsrds.TagTrialExpiryWarned: "true"}),
+		)
+	}
+
+	return nil
+}
+
+// notifyTrialExpiry posts the notification to the trial expiry webhook, if
+// one is configured. Failures are only logged, as the log line is the
+// notification of last resort.
+func (b *RDSBroker) notifyTrialExpiry(notification TrialExpiryNotification) {
+	if b.trialExpiryWebhookURL == "" {
+		return
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		b.logger.Error("notify-trial-expiry", err)
+		return
+	}
+
+	resp, err := b.trialExpiryWebhookClient.Post(b.trialExpiryWebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		b.logger.Error("notify-trial-expiry", err, lager.Data{instanceIDLogKey: notification.InstanceID})
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		b.logger.Error("notify-trial-expiry", fmt.Errorf("webhook returned status %d", resp.StatusCode), lager.Data{instanceIDLogKey: notification.InstanceID})
+	}
+}
@@ -0,0 +1,211 @@
+package rdsbroker_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+)
+
+var _ = Describe("ProcessTrialInstances", func() {
+	var (
+		rdsInstance    *rdsfake.FakeRDSInstance
+		rdsBroker      *RDSBroker
+		dbInstance     *rds.DBInstance
+		tags           map[string]string
+		softDeleteDays uint
+		webhook        *httptest.Server
+		notifications  chan TrialExpiryNotification
+	)
+
+	BeforeEach(func() {
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		softDeleteDays = 0
+
+		notifications = make(chan TrialExpiryNotification, 10)
+		webhook = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			var notification TrialExpiryNotification
+			Expect(json.NewDecoder(r.Body).Decode(&notification)).To(Succeed())
+			notifications <- notification
+		}))
+
+		dbInstance = &rds.DBInstance{
+			DBInstanceIdentifier: aws.String("cf-instance-1"),
+			DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-instance-1"),
+			DBInstanceStatus:     aws.String("available"),
+		}
+		tags = map[string]string{
+			"Broker Name":       "mybroker",
+			"chargeable_entity": "instance-1",
+			"Plan ID":           "Plan-1",
+			"Organization ID":   "organization-id",
+			"SkipFinalSnapshot": "true",
+			"Trial Expires":     time.Now().Add(10 * 24 * time.Hour).UTC().Format(time.RFC3339),
+		}
+	})
+
+	AfterEach(func() {
+		webhook.Close()
+	})
+
+	JustBeforeEach(func() {
+		config := testConfig()
+		config.SoftDeleteDays = softDeleteDays
+		config.TrialExpiryWarningDays = 3
+		config.TrialGraceDays = 7
+		config.TrialExpiryWebhookURL = webhook.URL
+		rdsBroker = newTestBroker(config, rdsInstance, nil, lagertest.NewTestLogger("trial_test"))
+
+		rdsInstance.DescribeByTagReturns([]*rds.DBInstance{dbInstance}, nil)
+		rdsInstance.DescribeReturns(dbInstance, nil)
+		rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(tags), nil)
+	})
+
+	It("leaves trial instances alone until their expiry approaches", func() {
+		Expect(rdsBroker.ProcessTrialInstances()).To(Succeed())
+
+		Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(0))
+		Expect(rdsInstance.StopCallCount()).To(Equal(0))
+		Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+		Expect(notifications).ToNot(Receive())
+	})
+
+	Context("when the instance is not on a trial plan", func() {
+		BeforeEach(func() {
+			delete(tags, "Trial Expires")
+		})
+
+		It("leaves it alone", func() {
+			Expect(rdsBroker.ProcessTrialInstances()).To(Succeed())
+
+			Expect(rdsInstance.StopCallCount()).To(Equal(0))
+			Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the expiry is within the warning period", func() {
+		BeforeEach(func() {
+			tags["Trial Expires"] = time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+		})
+
+		It("notifies the webhook and tags the instance as warned", func() {
+			Expect(rdsBroker.ProcessTrialInstances()).To(Succeed())
+
+			var notification TrialExpiryNotification
+			Expect(notifications).To(Receive(&notification))
+			Expect(notification.Event).To(Equal("expiring"))
+			Expect(notification.InstanceID).To(Equal("instance-1"))
+			Expect(notification.PlanID).To(Equal("Plan-1"))
+			Expect(notification.OrganizationID).To(Equal("organization-id"))
+
+			Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(1))
+			_, addedTags := rdsInstance.AddTagsToResourceArgsForCall(0)
+			Expect(awsrds.RDSTagsValues(addedTags)).To(HaveKeyWithValue("Trial Expiry Warned", "true"))
+			Expect(rdsInstance.StopCallCount()).To(Equal(0))
+		})
+
+		Context("and the owner has already been warned", func() {
+			BeforeEach(func() {
+				tags["Trial Expiry Warned"] = "true"
+			})
+
+			It("doesn't warn them again", func() {
+				Expect(rdsBroker.ProcessTrialInstances()).To(Succeed())
+
+				Expect(notifications).ToNot(Receive())
+				Expect(rdsInstance.AddTagsToResourceCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Context("when the trial has expired", func() {
+		BeforeEach(func() {
+			tags["Trial Expires"] = time.Now().Add(-24 * time.Hour).UTC().Format(time.RFC3339)
+		})
+
+		It("stops the instance", func() {
+			Expect(rdsBroker.ProcessTrialInstances()).To(Succeed())
+
+			Expect(rdsInstance.StopCallCount()).To(Equal(1))
+			Expect(rdsInstance.StopArgsForCall(0)).To(Equal("cf-instance-1"))
+			Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+
+			var notification TrialExpiryNotification
+			Expect(notifications).To(Receive(&notification))
+			Expect(notification.Event).To(Equal("stopped"))
+		})
+
+		Context("and the instance is already stopped", func() {
+			BeforeEach(func() {
+				dbInstance.DBInstanceStatus = aws.String("stopped")
+			})
+
+			It("does nothing", func() {
+				Expect(rdsBroker.ProcessTrialInstances()).To(Succeed())
+
+				Expect(rdsInstance.StopCallCount()).To(Equal(0))
+				Expect(notifications).ToNot(Receive())
+			})
+		})
+	})
+
+	Context("when the grace period has passed", func() {
+		BeforeEach(func() {
+			dbInstance.DBInstanceStatus = aws.String("stopped")
+			tags["Trial Expires"] = time.Now().Add(-8 * 24 * time.Hour).UTC().Format(time.RFC3339)
+		})
+
+		It("deletes the instance", func() {
+			Expect(rdsBroker.ProcessTrialInstances()).To(Succeed())
+
+			Expect(rdsInstance.DeleteCallCount()).To(Equal(1))
+			id, skipFinalSnapshot := rdsInstance.DeleteArgsForCall(0)
+			Expect(id).To(Equal("cf-instance-1"))
+			Expect(skipFinalSnapshot).To(BeTrue())
+
+			var notification TrialExpiryNotification
+			Expect(notifications).To(Receive(&notification))
+			Expect(notification.Event).To(Equal("deleted"))
+		})
+
+		Context("and soft delete is enabled", func() {
+			BeforeEach(func() {
+				softDeleteDays = 7
+			})
+
+			It("soft deletes the instance", func() {
+				Expect(rdsBroker.ProcessTrialInstances()).To(Succeed())
+
+				Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+				Expect(rdsInstance.ModifyCallCount()).To(Equal(1))
+				input := rdsInstance.ModifyArgsForCall(0)
+				Expect(aws.StringValue(input.NewDBInstanceIdentifier)).To(Equal("cf-instance-1-deleted"))
+			})
+		})
+	})
+
+	Context("when the instance has been soft deleted", func() {
+		BeforeEach(func() {
+			tags["Trial Expires"] = time.Now().Add(-8 * 24 * time.Hour).UTC().Format(time.RFC3339)
+			tags["Purge After"] = time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+		})
+
+		It("leaves it to the soft delete processing", func() {
+			Expect(rdsBroker.ProcessTrialInstances()).To(Succeed())
+
+			Expect(rdsInstance.DeleteCallCount()).To(Equal(0))
+			Expect(rdsInstance.ModifyCallCount()).To(Equal(0))
+		})
+	})
+})
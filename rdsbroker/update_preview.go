@@ -0,0 +1,156 @@
+package rdsbroker
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+)
+
+// Rough durations of each kind of change, from watching them happen on our
+// instances. They depend on the size and load of the instance, so they are
+// only good enough to tell a short change from a long one.
+const (
+	previewMinutesInstanceClass = 15
+	previewMinutesStorage       = 10
+	previewMinutesMultiAZ       = 30
+	previewMinutesMinorVersion  = 20
+	previewMinutesMajorVersion  = 45
+	previewMinutesReboot        = 5
+)
+
+// UpdatePreview is what an update would change on a DB instance, returned
+// instead of making the change when the `preview` update parameter is set.
+type UpdatePreview struct {
+	Changes                  []string `json:"changes"`
+	TargetEngineVersion      string   `json:"target_engine_version"`
+	RebootExpected           bool     `json:"reboot_expected"`
+	AppliedAtMaintenance     bool     `json:"applied_at_maintenance_window"`
+	EstimatedDurationMinutes int      `json:"estimated_duration_minutes"`
+	PlanChange               bool     `json:"plan_change"`
+	Warnings                 []string `json:"warnings,omitempty"`
+}
+
+// operationDataPreviewPrefix starts the operation data of a previewed
+// update, which carries the preview for LastOperation to return, as nothing
+// is recorded anywhere else.
+const operationDataPreviewPrefix = "preview:"
+
+func (p UpdatePreview) operationData() (string, error) {
+	encoded, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	return operationDataPreviewPrefix + string(encoded), nil
+}
+
+func updatePreviewFromOperationData(operationData string) (UpdatePreview, bool) {
+	if !strings.HasPrefix(operationData, operationDataPreviewPrefix) {
+		return UpdatePreview{}, false
+	}
+	var preview UpdatePreview
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(operationData, operationDataPreviewPrefix)), &preview); err != nil {
+		return UpdatePreview{}, false
+	}
+	return preview, true
+}
+
+// lastOperation reports the previewed update as finished, with the preview
+// as its description. A previewed plan change is reported as failed, as
+// otherwise the platform would record the instance as being on the new
+// plan.
+func (p UpdatePreview) lastOperation() domain.LastOperation {
+	state := domain.Succeeded
+	if p.PlanChange {
+		state = domain.Failed
+	}
+	return domain.LastOperation{State: state, Description: p.String()}
+}
+
+func (b *RDSBroker) previewUpdate(dbInstance *rds.DBInstance, servicePlan ServicePlan, modifyDBInstanceInput *rds.ModifyDBInstanceInput, reboot bool) (UpdatePreview, error) {
+	preview := UpdatePreview{
+		Changes:              []string{},
+		TargetEngineVersion:  aws.StringValue(dbInstance.EngineVersion),
+		RebootExpected:       reboot,
+		AppliedAtMaintenance: !aws.BoolValue(modifyDBInstanceInput.ApplyImmediately),
+	}
+
+	disagreements, _, err := b.compareDBDescriptionWithPlan(dbInstance, servicePlan)
+	if err != nil {
+		return UpdatePreview{}, err
+	}
+
+	for _, disagreement := range disagreements {
+		switch disagreement {
+		case disagreementDBInstanceClass:
+			preview.Changes = append(preview.Changes, fmt.Sprintf(
+				"instance class from %s to %s",
+				aws.StringValue(dbInstance.DBInstanceClass), aws.StringValue(modifyDBInstanceInput.DBInstanceClass),
+			))
+			preview.RebootExpected = true
+			preview.EstimatedDurationMinutes += previewMinutesInstanceClass
+		case disagreementAllocatedStorage:
+			preview.Changes = append(preview.Changes, fmt.Sprintf(
+				"allocated storage from %d GB to %d GB",
+				aws.Int64Value(dbInstance.AllocatedStorage), aws.Int64Value(modifyDBInstanceInput.AllocatedStorage),
+			))
+			preview.EstimatedDurationMinutes += previewMinutesStorage
+		case disagreementMultiAZ:
+			preview.Changes = append(preview.Changes, fmt.Sprintf(
+				"Multi-AZ from %t to %t",
+				aws.BoolValue(dbInstance.MultiAZ), aws.BoolValue(modifyDBInstanceInput.MultiAZ),
+			))
+			preview.EstimatedDurationMinutes += previewMinutesMultiAZ
+		}
+	}
+
+	targetEngineVersion := aws.StringValue(modifyDBInstanceInput.EngineVersion)
+	if targetEngineVersion != "" && targetEngineVersion != aws.StringValue(dbInstance.EngineVersion) && !strings.HasPrefix(aws.StringValue(dbInstance.EngineVersion), targetEngineVersion+".") {
+		preview.Changes = append(preview.Changes, fmt.Sprintf(
+			"engine version from %s to %s",
+			aws.StringValue(dbInstance.EngineVersion), targetEngineVersion,
+		))
+		preview.TargetEngineVersion = targetEngineVersion
+		preview.RebootExpected = true
+		if containsString(disagreements, disagreementEngine) {
+			preview.EstimatedDurationMinutes += previewMinutesMajorVersion
+		} else {
+			preview.EstimatedDurationMinutes += previewMinutesMinorVersion
+		}
+	}
+
+	if preview.RebootExpected && preview.EstimatedDurationMinutes == 0 {
+		preview.EstimatedDurationMinutes = previewMinutesReboot
+	}
+
+	return preview, nil
+}
+
+// String describes the preview for the last operation of the update, which
+// is what the platform shows the user.
+func (p UpdatePreview) String() string {
+	if len(p.Changes) == 0 && !p.RebootExpected {
+		return "Preview only, nothing has been changed: the update would not change the DB instance"
+	}
+
+	description := "Preview only, nothing has been changed: the update would change " + strings.Join(p.Changes, ", ")
+	if len(p.Changes) == 0 {
+		description = "Preview only, nothing has been changed: the update would only reboot the DB instance"
+	}
+	if p.RebootExpected {
+		description += ". The DB instance would be rebooted, with some downtime"
+	} else {
+		description += ". No downtime is expected"
+	}
+	if p.AppliedAtMaintenance {
+		description += ". The changes would be applied in the next maintenance window"
+	}
+	description += fmt.Sprintf(". Estimated duration: about %d minutes", p.EstimatedDurationMinutes)
+	for _, warning := range p.Warnings {
+		description += ". " + warning
+	}
+	return description
+}
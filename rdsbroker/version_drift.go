@@ -0,0 +1,207 @@
+package rdsbroker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/Masterminds/semver"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+)
+
+// VersionDrift compares the engine version of a DB instance with the
+// version of its plan and the latest minor version RDS offers for it, to
+// find the tenants to nudge into upgrading.
+type VersionDrift struct {
+	InstanceID           string `json:"instance_id"`
+	DBInstanceIdentifier string `json:"db_instance_identifier"`
+	PlanID               string `json:"plan_id"`
+	OrganizationID       string `json:"organization_id"`
+	SpaceID              string `json:"space_id"`
+	Engine               string `json:"engine"`
+	EngineVersion        string `json:"engine_version"`
+	PlanEngineVersion    string `json:"plan_engine_version"`
+	LatestMinorVersion   string `json:"latest_minor_version"`
+	BehindPlan           bool   `json:"behind_plan"`
+	BehindLatestMinor    bool   `json:"behind_latest_minor"`
+}
+
+// versionDrifts compares the versions of the DB instances. An instance
+// whose latest minor version can't be found is logged and reported without
+// one. Soft deleted instances are left out.
+func (b *RDSBroker) versionDrifts(dbInstances []managedDBInstance) []VersionDrift {
+	drifts := []VersionDrift{}
+	for _, instance := range dbInstances {
+		dbInstance := instance.dbInstance
+		if instance.tagsByName[awsrds.TagPurgeAfter] != "" {
+			continue
+		}
+
+		dbInstanceIdentifier := aws.StringValue(dbInstance.DBInstanceIdentifier)
+		engine := aws.StringValue(dbInstance.Engine)
+		engineVersion := aws.StringValue(dbInstance.EngineVersion)
+		servicePlan, _ := b.catalog.FindServicePlan(instance.tagsByName[awsrds.TagPlanID])
+		planEngineVersion := aws.StringValue(servicePlan.RDSProperties.EngineVersion)
+
+		latestMinorVersion := engineVersion
+		latest, err := b.dbInstance.GetLatestMinorVersion(engine, engineVersion)
+		if err != nil {
+			b.logger.Error("version-drift.get-latest-minor-version", err, lager.Data{dbInstanceLogKey: dbInstanceIdentifier})
+			latestMinorVersion = ""
+		} else if latest != nil {
+			latestMinorVersion = aws.StringValue(latest)
+		}
+
+		drifts = append(drifts, VersionDrift{
+			InstanceID:           b.dbInstanceIdentifierToServiceInstanceID(dbInstanceIdentifier),
+			DBInstanceIdentifier: dbInstanceIdentifier,
+			PlanID:               instance.tagsByName[awsrds.TagPlanID],
+			OrganizationID:       instance.tagsByName[awsrds.TagOrganizationID],
+			SpaceID:              instance.tagsByName[awsrds.TagSpaceID],
+			Engine:               engine,
+			EngineVersion:        engineVersion,
+			PlanEngineVersion:    planEngineVersion,
+			LatestMinorVersion:   latestMinorVersion,
+			BehindPlan:           planEngineVersion != "" && versionBehind(engineVersion, planEngineVersion),
+			BehindLatestMinor:    latestMinorVersion != "" && versionBehind(engineVersion, latestMinorVersion),
+		})
+	}
+	return drifts
+}
+
+// versionBehind is whether version is older than target, compared only as
+// precisely as target is given, so that 13.4 isn't behind a plan's 13.
+// Versions which aren't semantic versions are never behind.
+func versionBehind(version string, target string) bool {
+	targetParts := strings.Split(target, ".")
+	versionParts := strings.Split(version, ".")
+	if len(versionParts) > len(targetParts) {
+		versionParts = versionParts[:len(targetParts)]
+	}
+
+	v, err := semver.NewVersion(strings.Join(versionParts, "."))
+	if err != nil {
+		return false
+	}
+	t, err := semver.NewVersion(target)
+	if err != nil {
+		return false
+	}
+	return v.LessThan(t)
+}
+
+// reportVersionDrift is the housekeeping job which logs the DB instances
+// behind their plan's version or the latest minor version, serves the
+// comparison in the instance metrics, and writes it to the inventory bucket
+// once a day.
+func (b *RDSBroker) reportVersionDrift(dbInstances []managedDBInstance) error {
+	drifts := b.versionDrifts(dbInstances)
+
+	behindPlan, behindLatestMinor := 0, 0
+	for _, drift := range drifts {
+		if drift.BehindPlan {
+			behindPlan++
+		}
+		if drift.BehindLatestMinor {
+			behindLatestMinor++
+		}
+		if drift.BehindPlan || drift.BehindLatestMinor {
+			b.logger.Info("version-drift.behind", lager.Data{
+				instanceIDLogKey:     drift.InstanceID,
+				"planID":             drift.PlanID,
+				"engineVersion":      drift.EngineVersion,
+				"planEngineVersion":  drift.PlanEngineVersion,
+				"latestMinorVersion": drift.LatestMinorVersion,
+			})
+		}
+	}
+	b.logger.Info("version-drift.checked", lager.Data{
+		"checkedInstances":  len(drifts),
+		"behindPlan":        behindPlan,
+		"behindLatestMinor": behindLatestMinor,
+	})
+
+	b.instanceMetrics.lock.Lock()
+	b.instanceMetrics.versionDrifts = drifts
+	b.instanceMetrics.lock.Unlock()
+
+	return b.exportVersionDrift(drifts)
+}
+
+// exportVersionDrift writes the comparison to the inventory bucket as
+// `<prefix>version-drift-<date>.json`, once per UTC day.
+func (b *RDSBroker) exportVersionDrift(drifts []VersionDrift) error {
+	if b.inventoryStore == nil || b.inventoryBucket == "" {
+		return nil
+	}
+
+	today := time.Now().UTC().Format("2006-01-02")
+	if b.versionDriftExportedOn == today {
+		return nil
+	}
+
+	body, err := json.Marshal(drifts)
+	if err != nil {
+		return err
+	}
+	key := b.inventoryPrefix + "version-drift-" + today + ".json"
+	_, err = b.inventoryStore.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(b.inventoryBucket),
+		Key:         aws.String(key),
+		ContentType: aws.String("application/json"),
+		Body:        bytes.NewReader(body),
+	})
+	if err != nil {
+		return err
+	}
+
+	b.versionDriftExportedOn = today
+	b.logger.Info("export-version-drift", lager.Data{
+		"bucket": b.inventoryBucket,
+		"key":    key,
+		"count":  len(drifts),
+	})
+	return nil
+}
+
+// writeVersionDriftMetrics writes whether each DB instance is behind its
+// plan's version or the latest minor version in the Prometheus text
+// exposition format.
+func writeVersionDriftMetrics(w io.Writer, drifts []VersionDrift) {
+	metrics := []struct {
+		name  string
+		help  string
+		value func(VersionDrift) bool
+	}{
+		{
+			name:  "rds_broker_instance_engine_version_behind_plan",
+			help:  "Whether the engine version of the DB instance is older than the version of its plan.",
+			value: func(d VersionDrift) bool { return d.BehindPlan },
+		},
+		{
+			name:  "rds_broker_instance_engine_version_behind_latest_minor",
+			help:  "Whether the engine version of the DB instance is older than the latest minor version RDS offers for it.",
+			value: func(d VersionDrift) bool { return d.BehindLatestMinor },
+		},
+	}
+
+	for _, metric := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n", metric.name, metric.help)
+		fmt.Fprintf(w, "# TYPE %s gauge\n", metric.name)
+		for _, drift := range drifts {
+			value := 0
+			if metric.value(drift) {
+				value = 1
+			}
+			fmt.Fprintf(w, "%s{instance_id=%q,db_instance_identifier=%q,plan_id=%q,engine_version=%q} %d\n",
+				metric.name, drift.InstanceID, drift.DBInstanceIdentifier, drift.PlanID, drift.EngineVersion, value)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package rdsbroker_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+)
+
+var _ = Describe("Version drift", func() {
+	var (
+		rdsInstance *rdsfake.FakeRDSInstance
+		store       *fakeInventoryStore
+		logger      *lagertest.TestLogger
+		rdsBroker   *RDSBroker
+	)
+
+	BeforeEach(func() {
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		store = &fakeInventoryStore{}
+		logger = lagertest.NewTestLogger("version_drift_test")
+		config := testConfig()
+		config.InventoryBucket = "inventory-bucket"
+		config.InventoryPrefix = "rds/"
+		config.Catalog.Services[0].Plans[1].RDSProperties.Engine = aws.String("postgres")
+		config.Catalog.Services[0].Plans[1].RDSProperties.EngineVersion = aws.String("14")
+		rdsBroker = newTestBroker(config, rdsInstance, nil, logger)
+		rdsBroker.SetInventoryStore(store)
+
+		rdsInstance.DescribeByTagReturns([]*rds.DBInstance{
+			{
+				DBInstanceIdentifier: aws.String("cf-current-instance"),
+				DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-current-instance"),
+				Engine:               aws.String("postgres"),
+				EngineVersion:        aws.String("13.7"),
+			},
+			{
+				DBInstanceIdentifier: aws.String("cf-old-instance"),
+				DBInstanceArn:        aws.String("arn:aws:rds:rds-region:1234567890:db:cf-old-instance"),
+				Engine:               aws.String("postgres"),
+				EngineVersion:        aws.String("13.4"),
+			},
+		}, nil)
+		rdsInstance.GetResourceTagsStub = func(arn string, opts ...awsrds.DescribeOption) ([]*rds.Tag, error) {
+			planID := "Plan-1"
+			if arn == "arn:aws:rds:rds-region:1234567890:db:cf-old-instance" {
+				planID = "Plan-2"
+			}
+			return awsrds.BuildRDSTags(map[string]string{
+				"Broker Name": "mybroker",
+				"Plan ID":     planID,
+			}), nil
+		}
+		rdsInstance.GetLatestMinorVersionStub = func(engine string, version string) (*string, error) {
+			if version == "13.7" {
+				return nil, nil
+			}
+			return aws.String("13.7"), nil
+		}
+	})
+
+	It("compares each instance's version with its plan's and the latest minor version", func() {
+		Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+		Expect(logger.LogMessages()).To(ContainElement("version_drift_test.broker.version-drift.behind"))
+		Expect(logger.LogMessages()).To(ContainElement("version_drift_test.broker.version-drift.checked"))
+
+		var object putObject
+		for _, o := range store.objects {
+			if o.key == "rds/version-drift-"+time.Now().UTC().Format("2006-01-02")+".json" {
+				object = o
+			}
+		}
+		Expect(object.bucket).To(Equal("inventory-bucket"))
+
+		var drifts []VersionDrift
+		Expect(json.Unmarshal(object.body, &drifts)).To(Succeed())
+		Expect(drifts).To(ConsistOf(
+			VersionDrift{
+				InstanceID:           "current-instance",
+				DBInstanceIdentifier: "cf-current-instance",
+				PlanID:               "Plan-1",
+				Engine:               "postgres",
+				EngineVersion:        "13.7",
+				PlanEngineVersion:    "13",
+				LatestMinorVersion:   "13.7",
+			},
+			VersionDrift{
+				InstanceID:           "old-instance",
+				DBInstanceIdentifier: "cf-old-instance",
+				PlanID:               "Plan-2",
+				Engine:               "postgres",
+				EngineVersion:        "13.4",
+				PlanEngineVersion:    "14",
+				LatestMinorVersion:   "13.7",
+				BehindPlan:           true,
+				BehindLatestMinor:    true,
+			},
+		))
+	})
+
+	It("serves whether each instance is behind in the metrics", func() {
+		Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+
+		recorder := httptest.NewRecorder()
+		rdsBroker.AdminHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/metrics", nil))
+
+		Expect(recorder.Body.String()).To(ContainSubstring(
+			"# TYPE rds_broker_instance_engine_version_behind_plan gauge\n" +
+				`rds_broker_instance_engine_version_behind_plan{instance_id="current-instance",db_instance_identifier="cf-current-instance",plan_id="Plan-1",engine_version="13.7"} 0` + "\n" +
+				`rds_broker_instance_engine_version_behind_plan{instance_id="old-instance",db_instance_identifier="cf-old-instance",plan_id="Plan-2",engine_version="13.4"} 1` + "\n",
+		))
+		Expect(recorder.Body.String()).To(ContainSubstring(
+			`rds_broker_instance_engine_version_behind_latest_minor{instance_id="old-instance",db_instance_identifier="cf-old-instance",plan_id="Plan-2",engine_version="13.4"} 1`,
+		))
+	})
+
+	It("reports instances whose latest minor version can't be found without one", func() {
+		rdsInstance.GetLatestMinorVersionStub = nil
+		rdsInstance.GetLatestMinorVersionReturns(nil, errors.New("Did not find a single version"))
+
+		Expect(rdsBroker.RunHousekeeping()).To(Succeed())
+		Expect(logger.LogMessages()).To(ContainElement("version_drift_test.broker.version-drift.get-latest-minor-version"))
+		// the inventory's JSON and CSV, and the version drift
+		Expect(store.objects).To(HaveLen(3))
+	})
+})
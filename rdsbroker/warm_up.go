@@ -0,0 +1,46 @@
+package rdsbroker
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// warmUpRetryInterval is how long the broker waits between attempts to
+// connect to a newly provisioned DB instance.
+const warmUpRetryInterval = time.Second
+
+// warmedUp reports whether a newly provisioned DB instance accepts
+// connections. RDS marks instances available a little before their
+// databases can always be reached, and a provision which succeeded then
+// would leave the first bind to fail.
+func (b *RDSBroker) warmedUp(instanceID string, dbInstance *rds.DBInstance) bool {
+	dbName := b.dbNameFromDBInstance(instanceID, dbInstance)
+	for attempt := 1; attempt <= int(b.warmUpAttempts); attempt++ {
+		if attempt > 1 {
+			time.Sleep(warmUpRetryInterval)
+		}
+
+		err := b.checkMasterConnection(instanceID, dbName, dbInstance)
+		if err == nil {
+			return true
+		}
+		b.logger.Info("warm-up.connection-failed", lager.Data{
+			instanceIDLogKey: instanceID,
+			"attempt":        attempt,
+			"error":          err.Error(),
+		})
+	}
+	return b.warmUpAttempts == 0
+}
+
+func (b *RDSBroker) checkMasterConnection(instanceID string, dbName string, dbInstance *rds.DBInstance) error {
+	sqlEngine, err := b.openSQLEngineForDBInstance(instanceID, dbName, dbInstance)
+	if err != nil {
+		return err
+	}
+	defer sqlEngine.Close()
+
+	return sqlEngine.CheckConnection()
+}
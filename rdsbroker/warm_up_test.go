@@ -0,0 +1,82 @@
+package rdsbroker_test
+
+import (
+	"context"
+	"errors"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v9/domain"
+
+	"github.com/alphagov/paas-rds-broker/awsrds"
+	rdsfake "github.com/alphagov/paas-rds-broker/awsrds/fakes"
+	. "github.com/alphagov/paas-rds-broker/rdsbroker"
+	sqlfake "github.com/alphagov/paas-rds-broker/sqlengine/fakes"
+)
+
+var _ = Describe("Provision warm-up", func() {
+	var (
+		rdsInstance *rdsfake.FakeRDSInstance
+		sqlEngine   *sqlfake.FakeSQLEngine
+		rdsBroker   *RDSBroker
+		pollDetails domain.PollDetails
+	)
+
+	BeforeEach(func() {
+		rdsInstance = &rdsfake.FakeRDSInstance{}
+		rdsInstance.DescribeReturns(testDBInstance(), nil)
+		rdsInstance.GetResourceTagsReturns(awsrds.BuildRDSTags(map[string]string{
+			"Broker Name": "mybroker",
+			"Plan ID":     "Plan-1",
+		}), nil)
+
+		sqlEngine = &sqlfake.FakeSQLEngine{}
+
+		config := testConfig()
+		config.ProvisionWarmUpAttempts = 1
+		rdsBroker = newTestBroker(config, rdsInstance, sqlEngine, lagertest.NewTestLogger("warm_up_test"))
+
+		pollDetails = domain.PollDetails{ServiceID: "Service-1", PlanID: "Plan-1", OperationData: "provision"}
+	})
+
+	It("succeeds once the DB instance accepts connections", func() {
+		lastOperation, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lastOperation.State).To(Equal(domain.Succeeded))
+
+		address, _, _, username, _ := sqlEngine.OpenArgsForCall(0)
+		Expect(address).To(Equal("endpoint-address"))
+		Expect(username).To(Equal("master-username"))
+		Expect(sqlEngine.CheckConnectionCallCount()).ToNot(BeZero())
+	})
+
+	It("stays in progress while the DB instance can't be connected to", func() {
+		sqlEngine.OpenReturns(errors.New("connection refused"))
+
+		lastOperation, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lastOperation).To(Equal(domain.LastOperation{
+			State:       domain.InProgress,
+			Description: "DB Instance 'cf-instance-1' is available but not yet accepting connections",
+		}))
+	})
+
+	It("stays in progress while queries fail", func() {
+		sqlEngine.CheckConnectionReturns(errors.New("the database system is starting up"))
+
+		lastOperation, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lastOperation.State).To(Equal(domain.InProgress))
+	})
+
+	It("doesn't check operations other than provisions", func() {
+		sqlEngine.CheckConnectionReturns(errors.New("the database system is starting up"))
+		pollDetails.OperationData = ""
+
+		lastOperation, err := rdsBroker.LastOperation(context.Background(), "instance-1", pollDetails)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lastOperation.State).To(Equal(domain.Succeeded))
+		Expect(sqlEngine.CheckConnectionCallCount()).To(BeZero())
+	})
+})
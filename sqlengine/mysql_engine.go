@@ -2,17 +2,22 @@ package sqlengine
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 
 	"github.com/go-sql-driver/mysql" // MySQL Driver
 
 	"strings"
+	"time"
 
 	"code.cloudfoundry.org/lager/v3"
 )
 
 const ER_ACCESS_DENIED_ERROR = 1045
+const ER_CANNOT_USER = 1396
+const ER_NO_SUCH_THREAD = 1094
 
 type MySQLEngine struct {
 	logger            lager.Logger
@@ -171,12 +176,16 @@ func (d *MySQLEngine) DropUser(bindingID string) error {
 	dropUserStatement := "DROP USER `" + username + "`@`%`;"
 	logger.Debug("drop-user", lager.Data{"statement": dropUserStatement})
 
-	_, err := d.db.Exec(dropUserStatement)
-	if err == nil {
+	_, firstErr := d.db.Exec(dropUserStatement)
+	if firstErr == nil {
 		return nil
 	}
 
-	logger.Error("sql-error", err)
+	if isMySQLUserNotFoundError(firstErr) {
+		logger.Info("warning", lager.Data{"warning": "User " + username + " does not exist"})
+	} else {
+		logger.Error("sql-error", firstErr)
+	}
 
 	// Try to drop the username generated the old way
 
@@ -189,13 +198,34 @@ func (d *MySQLEngine) DropUser(bindingID string) error {
 	dropUserStatement = "DROP USER `" + username + "`@`%`;"
 	logger.Debug("drop-user", lager.Data{"statement": dropUserStatement})
 
-	_, err = d.db.Exec(dropUserStatement)
-	if err != nil {
+	_, err := d.db.Exec(dropUserStatement)
+	if err == nil {
+		return nil
+	}
+	if !isMySQLUserNotFoundError(err) {
 		logger.Error("sql-error", err)
-		return err
 	}
 
-	return nil
+	// Neither user exists, e.g. because it was removed by hand or by a
+	// previous unbind that failed part way, so there is nothing to do
+	if isMySQLUserNotFoundError(firstErr) && isMySQLUserNotFoundError(err) {
+		logger.Info("warning", lager.Data{"warning": "User " + username + " does not exist"})
+		return nil
+	}
+
+	// a user which doesn't exist isn't the reason the user couldn't be
+	// dropped, so the other error is returned
+	if !isMySQLUserNotFoundError(firstErr) {
+		return firstErr
+	}
+	return err
+}
+
+// isMySQLUserNotFoundError reports whether err is MySQL refusing to drop a
+// user, which it does when the user does not exist.
+func isMySQLUserNotFoundError(err error) bool {
+	mysqlErr, ok := err.(*mysql.MySQLError)
+	return ok && mysqlErr.Number == ER_CANNOT_USER
 }
 
 func (d *MySQLEngine) ResetState() error {
@@ -227,6 +257,218 @@ func (d *MySQLEngine) ResetState() error {
 	return nil
 }
 
+// TerminateConnections kills the sessions of every user other than the RDS
+// system users and the one we're connected as. The sessions of users which
+// have already been dropped are killed too.
+func (d *MySQLEngine) TerminateConnections() error {
+	logger := d.logger.Session("terminate-connections")
+	logger.Debug("start")
+
+	return d.killThreads(logger, `
+		SELECT ID
+		FROM information_schema.PROCESSLIST
+		WHERE ID != CONNECTION_ID()
+			AND USER != SUBSTRING_INDEX(CURRENT_USER(), '@', 1)
+			AND USER NOT IN ('rdsadmin', 'rdsrepladmin', 'event_scheduler', 'system user')
+	`)
+}
+
+// killThreads kills the threads whose IDs are selected by query.
+func (d *MySQLEngine) killThreads(logger lager.Logger, query string, args ...interface{}) error {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		logger.Error("sql-error", err)
+		return err
+	}
+	defer rows.Close()
+
+	threadIDs := []int64{}
+	for rows.Next() {
+		var threadID int64
+		if err := rows.Scan(&threadID); err != nil {
+			logger.Error("sql-error", err)
+			return err
+		}
+		threadIDs = append(threadIDs, threadID)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("sql-error", err)
+		return err
+	}
+
+	// The master user can't KILL other users' threads on RDS, so the RDS
+	// procedure is used instead
+	for _, threadID := range threadIDs {
+		_, err := d.db.Exec("CALL mysql.rds_kill(?)", threadID)
+		if err != nil {
+			// The session may have ended on its own in the meantime
+			if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == ER_NO_SUCH_THREAD {
+				continue
+			}
+			logger.Error("sql-error", err, lager.Data{"threadID": threadID})
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mysqlUserExpiryAttribute is the key of the user attribute in which the
+// expiry of a binding's user is kept, as MySQL can't expire logins itself.
+const mysqlUserExpiryAttribute = "broker_expires_at"
+
+// mysqlUserExpiryEventPrefix starts the name of the event which drops a
+// binding's user when it expires.
+const mysqlUserExpiryEventPrefix = "broker_expire_"
+
+// mysqlUserExpiryFormat sorts the same as text and as time, so that expiries
+// can be compared with the current time in SQL.
+const mysqlUserExpiryFormat = "2006-01-02 15:04:05"
+
+// SetUserExpiry records when a binding's user should stop working. MySQL
+// has no way to refuse logins after a given time, so an event is scheduled
+// to drop the user and end its sessions at that time. The expiry is also
+// kept as a user attribute so that DropExpiredUsers can catch any user the
+// event missed, such as while the event scheduler was off. User attributes
+// need MySQL 8.0.21 or later.
+func (d *MySQLEngine) SetUserExpiry(bindingID, dbname string, expiresAt time.Time) error {
+	logger := d.logger.Session("set-user-expiry", lager.Data{bindingIDLogKey: bindingID, "expiresAt": expiresAt})
+	logger.Debug("start")
+
+	username := d.UsernameGenerator(bindingID)
+	if err := checkMySQLIdentifierSafe(username); err != nil {
+		return err
+	}
+	if err := checkMySQLIdentifierSafe(dbname); err != nil {
+		return err
+	}
+
+	attribute, err := json.Marshal(map[string]string{
+		mysqlUserExpiryAttribute: expiresAt.UTC().Format(mysqlUserExpiryFormat),
+	})
+	if err != nil {
+		return err
+	}
+
+	// ATTRIBUTE only takes a literal, which is safe to build as the JSON
+	// only holds our key and a formatted time
+	_, err = d.db.Exec("ALTER USER `" + username + "`@`%` ATTRIBUTE '" + string(attribute) + "';")
+	if err != nil {
+		logger.Error("sql-error", err)
+		return err
+	}
+
+	// The statement is one compound statement, so it doesn't need the
+	// delimiter changing. Sessions are killed with the RDS procedure as the
+	// event runs as the master user. The connection has no default database,
+// so the event is created in the binding's.
+	delaySeconds := int64(time.Until(expiresAt).Seconds())
+	if delaySeconds < 0 {
+		delaySeconds = 0
+	}
+	_, err = d.db.Exec(fmt.Sprintf(`
+		CREATE EVENT IF NOT EXISTS `+"`%s`.`%s%s`"+`
+		ON SCHEDULE AT UTC_TIMESTAMP() + INTERVAL %d SECOND
+		ON COMPLETION NOT PRESERVE
+		DO BEGIN
+			DECLARE done INT DEFAULT 0;
+			DECLARE thread_id BIGINT;
+			DECLARE threads CURSOR FOR SELECT ID FROM information_schema.PROCESSLIST WHERE USER = '%s';
+			DECLARE CONTINUE HANDLER FOR NOT FOUND SET done = 1;
+			DECLARE CONTINUE HANDLER FOR %d BEGIN END;
+			DROP USER IF EXISTS `+"`%s`@`%%`"+`;
+			OPEN threads;
+			kill_threads: LOOP
+				FETCH threads INTO thread_id;
+				IF done THEN
+					LEAVE kill_threads;
+				END IF;
+				CALL mysql.rds_kill(thread_id);
+			END LOOP;
+			CLOSE threads;
+		END`,
+		dbname, mysqlUserExpiryEventPrefix, username, delaySeconds, username, ER_NO_SUCH_THREAD, username,
+	))
+	if err != nil {
+		logger.Error("sql-error", err)
+		return err
+	}
+
+	return nil
+}
+
+// SetUserStatementTimeout makes the statements of a binding's user which
+// run for longer than timeout be aborted. Only MariaDB has a limit on the
+// execution time of statements which can be set for a user; MySQL's
+// max_execution_time can only be set for the whole server or by sessions.
+func (d *MySQLEngine) SetUserStatementTimeout(bindingID string, timeout time.Duration) error {
+	logger := d.logger.Session("set-user-statement-timeout", lager.Data{bindingIDLogKey: bindingID, "timeout": timeout.String()})
+	logger.Debug("start")
+
+	username := d.UsernameGenerator(bindingID)
+	if err := checkMySQLIdentifierSafe(username); err != nil {
+		return err
+	}
+
+	// the limit only takes a literal, which is safe to build from a number
+	_, err := d.db.Exec(fmt.Sprintf("ALTER USER `%s`@`%%` WITH MAX_STATEMENT_TIME %g;", username, timeout.Seconds()))
+	if err != nil {
+		logger.Error("sql-error", err)
+		return err
+	}
+	return nil
+}
+
+// DropExpiredUsers kills the sessions of the broker's users whose expiry
+// has passed and drops them, returning their names.
+func (d *MySQLEngine) DropExpiredUsers() ([]string, error) {
+	logger := d.logger.Session("drop-expired-users")
+	logger.Debug("start")
+
+	rows, err := d.db.Query(`
+		SELECT USER
+		FROM information_schema.USER_ATTRIBUTES
+		WHERE HOST = '%'
+			AND JSON_UNQUOTE(JSON_EXTRACT(ATTRIBUTE, ?)) < UTC_TIMESTAMP()
+	`, "$."+mysqlUserExpiryAttribute)
+	if err != nil {
+		logger.Error("sql-error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	expiredUsers := []string{}
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			logger.Error("sql-error", err)
+			return nil, err
+		}
+		if brokerUsernamePattern.MatchString(username) {
+			expiredUsers = append(expiredUsers, username)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("sql-error", err)
+		return nil, err
+	}
+
+	dropped := []string{}
+	for _, username := range expiredUsers {
+		// dropped first, so that no new sessions can be opened
+		if _, err := d.db.Exec("DROP USER IF EXISTS `" + username + "`@`%`;"); err != nil {
+			logger.Error("sql-error", err, lager.Data{"username": username})
+			return dropped, err
+		}
+		if err := d.killThreads(logger, "SELECT ID FROM information_schema.PROCESSLIST WHERE USER = ?", username); err != nil {
+			return dropped, err
+		}
+		dropped = append(dropped, username)
+	}
+
+	return dropped, nil
+}
+
 func (d *MySQLEngine) listNonSuperUsers(logger lager.Logger) ([]string, error) {
 	users := []string{}
 
@@ -255,6 +497,78 @@ func (d *MySQLEngine) listNonSuperUsers(logger lager.Logger) ([]string, error) {
 	return users, nil
 }
 
+// ListOtherDatabases lists the databases on the server other than the one
+// we're connected to, leaving out the system schemas.
+func (d *MySQLEngine) ListOtherDatabases() ([]string, error) {
+	logger := d.logger.Session("list-other-databases")
+	logger.Debug("start")
+
+	databases := []string{}
+	rows, err := d.db.Query(`
+		SELECT SCHEMA_NAME
+		FROM information_schema.SCHEMATA
+		WHERE SCHEMA_NAME NOT IN ('information_schema', 'innodb', 'mysql', 'performance_schema', 'sys', 'tmp')
+			AND SCHEMA_NAME != DATABASE()
+	`)
+	if err != nil {
+		logger.Error("sql-error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var dbname string
+		err = rows.Scan(&dbname)
+		if err != nil {
+			logger.Error("sql-error", err)
+			return nil, err
+		}
+		databases = append(databases, dbname)
+	}
+	return databases, rows.Err()
+}
+
+// CreateSchema creates a schema, which in MySQL is a database of its own.
+// dbname is only needed by PostgreSQL, where schemas live in a database. It
+// does nothing to a schema which already exists.
+func (d *MySQLEngine) CreateSchema(dbname, schema string) error {
+	return d.CreateDatabase(schema)
+}
+
+// CreateDatabase creates a database on the server, unless it already exists.
+func (d *MySQLEngine) CreateDatabase(dbname string) error {
+	logger := d.logger.Session("create-database", lager.Data{"dbname": dbname})
+	logger.Debug("start")
+
+	if err := checkMySQLIdentifierSafe(dbname); err != nil {
+		return err
+	}
+
+	_, err := d.db.Exec("CREATE DATABASE IF NOT EXISTS `" + dbname + "`;")
+	if err != nil {
+		logger.Error("sql-error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *MySQLEngine) DropDatabase(dbname string) error {
+	logger := d.logger.Session("drop-database", lager.Data{"dbname": dbname})
+	logger.Debug("start")
+
+	if err := checkMySQLIdentifierSafe(dbname); err != nil {
+		return err
+	}
+
+	_, err := d.db.Exec("DROP DATABASE IF EXISTS `" + dbname + "`;")
+	if err != nil {
+		logger.Error("sql-error", err)
+		return err
+	}
+
+	return nil
+}
+
 func (d *MySQLEngine) URI(address string, port int64, dbname string, username string, password string) string {
 	return fmt.Sprintf("mysql://%s:%s@%s:%d/%s?reconnect=true&useSSL=%t", username, password, address, port, dbname, d.requireSSL)
 }
@@ -271,6 +585,106 @@ func (d *MySQLEngine) CreateExtensions(extensions []string) error {
 	return nil
 }
 
-func (d *MySQLEngine) DropExtensions(extensions []string) error {
+func (d *MySQLEngine) DropExtensions(extensions []string, cascade bool) error {
+	return nil
+}
+
+func (d *MySQLEngine) ExtensionDependents(extensions []string) (map[string][]string, error) {
+	return map[string][]string{}, nil
+}
+
+func (d *MySQLEngine) UpdateExtension(extension string) error {
+	return nil
+}
+
+// SetBinlogRetentionHours tells RDS how long to keep binary logs on the
+// instance before purging them.
+func (d *MySQLEngine) SetBinlogRetentionHours(hours int64) error {
+	logger := d.logger.Session("set-binlog-retention-hours", lager.Data{"hours": hours})
+	logger.Debug("start")
+
+	_, err := d.db.Exec("CALL mysql.rds_set_configuration('binlog retention hours', ?)", hours)
+	if err != nil {
+		logger.Error("sql-error", err)
+		return err
+	}
+
 	return nil
 }
+
+// CheckConnection runs a trivial query, to check that the connection is usable.
+func (d *MySQLEngine) CheckConnection() error {
+	logger := d.logger.Session("check-connection")
+	logger.Debug("start")
+
+	_, err := d.db.Exec("SELECT 1")
+	if err != nil {
+		logger.Error("sql-error", err)
+		return err
+	}
+
+	return nil
+}
+
+// DatabaseUsage returns the size of the data and indexes of the tables in the
+// connected database, along with how many there are.
+func (d *MySQLEngine) DatabaseUsage() (DatabaseUsage, error) {
+	logger := d.logger.Session("database-usage")
+	logger.Debug("start")
+
+	var usage DatabaseUsage
+	err := d.db.QueryRow(`
+		SELECT COALESCE(SUM(data_length + index_length), 0), COUNT(*)
+		FROM information_schema.tables
+		WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE'
+	`).Scan(&usage.SizeBytes, &usage.TableCount)
+	if err != nil {
+		logger.Error("sql-error", err)
+		return DatabaseUsage{}, err
+	}
+	return usage, nil
+}
+
+// TransactionIDAges returns no ages: transaction ID wraparound is a Postgres
+// concept.
+func (d *MySQLEngine) TransactionIDAges() (map[string]int64, error) {
+	return map[string]int64{}, nil
+}
+
+// ConnectionUsage counts the client connections to the server, and how many
+// of them are sleeping between queries, against its max_connections. The
+// connection making the query is counted too.
+func (d *MySQLEngine) ConnectionUsage() (ConnectionUsage, error) {
+	logger := d.logger.Session("connection-usage")
+	logger.Debug("start")
+
+	var usage ConnectionUsage
+	err := d.db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(command = 'Sleep'), 0), @@max_connections
+		FROM information_schema.processlist
+		WHERE command <> 'Daemon'
+	`).Scan(&usage.Connections, &usage.IdleConnections, &usage.MaxConnections)
+	if err != nil {
+		logger.Error("sql-error", err)
+		return ConnectionUsage{}, err
+	}
+	return usage, nil
+}
+
+// CopySchemaFrom isn't supported for MySQL, as template databases are only
+// offered for Postgres.
+func (d *MySQLEngine) CopySchemaFrom(address string, port int64, dbname string, username string, password string) error {
+	return errors.New("Copying the schema of another database is only supported for Postgres")
+}
+
+// ImportData isn't supported for MySQL, as data imports are only offered for
+// Postgres.
+func (d *MySQLEngine) ImportData(format string, objects []ImportObject) error {
+	return errors.New("Importing data is only supported for Postgres")
+}
+
+// ExportData isn't supported for MySQL, as data exports are only offered for
+// Postgres.
+func (d *MySQLEngine) ExportData(w io.Writer) error {
+	return errors.New("Exporting data is only supported for Postgres")
+}
@@ -138,6 +138,43 @@ var _ = Describe("MySQLEngine", func() {
 			err = mysqlEngine.DropUser(bindingID)
 			Expect(err).ToNot(HaveOccurred())
 		})
+
+		It("DropUser() should succeed when the user is already gone", func() {
+			_, _, err := mysqlEngine.CreateUser(bindingID, dbname, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = mysqlEngine.DropUser(bindingID)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = mysqlEngine.DropUser(bindingID)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("Schemas", func() {
+		var schema string
+
+		BeforeEach(func() {
+			schema = "schema" + randomTestSuffix
+			err := mysqlEngine.Open(address, port, dbname, masterUsername, masterPassword)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("CreateSchema() creates a database", func() {
+			err := mysqlEngine.CreateSchema(dbname, schema)
+			Expect(err).ToNot(HaveOccurred())
+
+			By("doing nothing when the schema already exists")
+			err = mysqlEngine.CreateSchema(dbname, schema)
+			Expect(err).ToNot(HaveOccurred())
+
+			databases, err := mysqlEngine.ListOtherDatabases()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(databases).To(ContainElement(schema))
+
+			err = mysqlEngine.DropDatabase(schema)
+			Expect(err).ToNot(HaveOccurred())
+		})
 	})
 
 	Describe("ResetState", func() {
@@ -218,4 +255,42 @@ var _ = Describe("MySQLEngine", func() {
 		})
 	})
 
+	Describe("Other databases", func() {
+		var otherDBName string
+
+		BeforeEach(func() {
+			otherDBName = "otherdb" + randomTestSuffix
+			createMysqlDB(template1ConnectionString, otherDBName)
+
+			err := mysqlEngine.Open(address, port, dbname, masterUsername, masterPassword)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			dropMysqlDB(template1ConnectionString, otherDBName)
+		})
+
+		It("lists databases other than the one connected to", func() {
+			databases, err := mysqlEngine.ListOtherDatabases()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(databases).To(ContainElement(otherDBName))
+			Expect(databases).ToNot(ContainElement(dbname))
+			Expect(databases).ToNot(ContainElement("mysql"))
+		})
+
+		It("drops a database", func() {
+			err := mysqlEngine.DropDatabase(otherDBName)
+			Expect(err).ToNot(HaveOccurred())
+
+			databases, err := mysqlEngine.ListOtherDatabases()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(databases).ToNot(ContainElement(otherDBName))
+		})
+
+		It("refuses to drop a database with an unsafe name", func() {
+			err := mysqlEngine.DropDatabase("foo`; DROP DATABASE `" + dbname)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
 })
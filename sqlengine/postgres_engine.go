@@ -2,10 +2,18 @@ package sqlengine
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
+	"net/http"
 	"net/url"
+	"os"
+	"os/exec"
+	"strings"
 	"text/template"
 	"time"
 
@@ -19,18 +27,42 @@ const (
 	pqErrDuplicateContent = "42710"
 	pqErrInternalError    = "XX000"
 	pqErrInvalidPassword  = "28P01"
+	pqErrUndefinedObject  = "42704"
 
 	bindingIDLogKey  = "binding-id"
 	extensionsLogKey = "extensions"
 )
 
+// schemaCopyTimeout is how long copying the schema of another database is
+// given before pg_dump and pg_restore are killed.
+const schemaCopyTimeout = 10 * time.Minute
+
+// dataImportTimeout is how long importing data is given before it is
+// abandoned and rolled back.
+const dataImportTimeout = 30 * time.Minute
+
+// dataExportTimeout is how long pg_dump is given to export a database
+// before it is killed.
+const dataExportTimeout = 30 * time.Minute
+
 type PostgresEngine struct {
 	logger            lager.Logger
 	db                *sql.DB
+	opened            *postgresConnection
 	requireSSL        bool
 	UsernameGenerator func(string) string
 }
 
+// postgresConnection is what the engine was opened with, for the client
+// programs which make their own connections to the database.
+type postgresConnection struct {
+	address  string
+	port     int64
+	dbname   string
+	username string
+	password string
+}
+
 func NewPostgresEngine(logger lager.Logger) *PostgresEngine {
 	return &PostgresEngine{
 		logger:            logger.Session("postgres-engine"),
@@ -67,6 +99,7 @@ func (d *PostgresEngine) Open(address string, port int64, dbname string, usernam
 		return err
 	}
 
+	d.opened = &postgresConnection{address, port, dbname, username, password}
 	return nil
 }
 
@@ -202,6 +235,13 @@ func (d *PostgresEngine) CreateUser(bindingID, dbname string, readOnly bool) (us
 
 }
 
+// isPostgresRoleNotFoundError reports whether err is PostgreSQL saying that
+// the role being dropped does not exist.
+func isPostgresRoleNotFoundError(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == pqErrUndefinedObject
+}
+
 func (d *PostgresEngine) DropUser(bindingID string) error {
 	logger := d.logger.Session("drop-user", lager.Data{bindingIDLogKey: bindingID})
 	logger.Debug("start")
@@ -221,7 +261,7 @@ func (d *PostgresEngine) DropUser(bindingID string) error {
 	// event-triggers based permissions the `username` won't exist.
 	// Also we changed how we generate usernames so we have to try to drop the username generated
 	// the old way. If none of the usernames exist then we swallow the error
-	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "42704" {
+	if isPostgresRoleNotFoundError(err) {
 		logger.Info("warning", lager.Data{"warning": "User " + username + " does not exist"})
 
 		username = generateUsernameOld(bindingID)
@@ -230,7 +270,7 @@ func (d *PostgresEngine) DropUser(bindingID string) error {
 			pq.QuoteIdentifier(username),
 		)
 		if _, err = d.db.Exec(dropUserStatement); err != nil {
-			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "42704" {
+			if isPostgresRoleNotFoundError(err) {
 				logger.Info("warning", lager.Data{"warning": "User " + username + " does not exist"})
 				return nil
 			}
@@ -289,6 +329,122 @@ func (d *PostgresEngine) ResetState() error {
 	return nil
 }
 
+// SetUserExpiry makes the password of a binding's user stop working at
+// expiresAt. Roles belong to the whole server, so dbname isn't needed. The
+// user is only dropped by DropExpiredUsers.
+func (d *PostgresEngine) SetUserExpiry(bindingID, dbname string, expiresAt time.Time) error {
+	logger := d.logger.Session("set-user-expiry", lager.Data{bindingIDLogKey: bindingID, "expiresAt": expiresAt})
+	logger.Debug("start")
+
+	statement := fmt.Sprintf(
+		`alter role %s valid until %s`,
+		pq.QuoteIdentifier(d.UsernameGenerator(bindingID)),
+		pq.QuoteLiteral(expiresAt.UTC().Format(time.RFC3339)),
+	)
+	if _, err := d.db.Exec(statement); err != nil {
+		logger.Error("sql-error", err)
+		return err
+	}
+	return nil
+}
+
+// SetUserStatementTimeout makes the statements of a binding's user which
+// run for longer than timeout be cancelled, unless a session sets its own.
+func (d *PostgresEngine) SetUserStatementTimeout(bindingID string, timeout time.Duration) error {
+	logger := d.logger.Session("set-user-statement-timeout", lager.Data{bindingIDLogKey: bindingID, "timeout": timeout.String()})
+	logger.Debug("start")
+
+	statement := fmt.Sprintf(
+		`alter role %s set statement_timeout = %d`,
+		pq.QuoteIdentifier(d.UsernameGenerator(bindingID)),
+		timeout.Milliseconds(),
+	)
+	if _, err := d.db.Exec(statement); err != nil {
+		logger.Error("sql-error", err)
+		return err
+	}
+	return nil
+}
+
+// DropExpiredUsers ends the sessions of the broker's users whose password
+// has expired and drops them, returning their names.
+func (d *PostgresEngine) DropExpiredUsers() ([]string, error) {
+	logger := d.logger.Session("drop-expired-users")
+	logger.Debug("start")
+
+	rows, err := d.db.Query(
+		`select rolname
+		from pg_roles
+		where rolvaliduntil < now()
+		and rolsuper != true
+		and rolname != current_user`,
+	)
+	if err != nil {
+		logger.Error("sql-error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	expiredUsers := []string{}
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			logger.Error("sql-error", err)
+			return nil, err
+		}
+		if brokerUsernamePattern.MatchString(username) {
+			expiredUsers = append(expiredUsers, username)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("sql-error", err)
+		return nil, err
+	}
+
+	dropped := []string{}
+	for _, username := range expiredUsers {
+		if _, err := d.db.Exec(`select pg_terminate_backend(pid) from pg_stat_activity where usename = $1`, username); err != nil {
+			logger.Error("sql-error", err, lager.Data{"username": username})
+			return dropped, err
+		}
+		if _, err := d.db.Exec(`drop role ` + pq.QuoteIdentifier(username)); err != nil && !isPostgresRoleNotFoundError(err) {
+			logger.Error("sql-error", err, lager.Data{"username": username})
+			return dropped, err
+		}
+		dropped = append(dropped, username)
+	}
+
+	return dropped, nil
+}
+
+// TerminateConnections ends the sessions of every user other than the
+// superusers and the one we're connected as. The sessions of roles which have
+// already been dropped are ended too.
+func (d *PostgresEngine) TerminateConnections() error {
+	logger := d.logger.Session("terminate-connections")
+	logger.Debug("start")
+
+	_, err := d.db.Exec(
+		`select pg_terminate_backend(pid)
+		from pg_stat_activity
+		where pid != pg_backend_pid()
+		and usesysid is not null
+		and usesysid not in (
+			select usesysid
+			from pg_user
+			where usesuper = true
+			or usename = current_user
+			or usename = 'rdstopmgr'
+		)`,
+	)
+	if err != nil {
+		logger.Error("sql-error", err)
+		return err
+	}
+
+	return nil
+}
+
 func (d *PostgresEngine) listNonSuperUsers(logger lager.Logger) ([]string, error) {
 	users := []string{}
 
@@ -337,7 +493,8 @@ func (d *PostgresEngine) JDBCURI(address string, port int64, dbname string, user
 }
 
 const createExtensionPattern = `CREATE EXTENSION IF NOT EXISTS {{.extensionIden}}`
-const dropExtensionPattern = `DROP EXTENSION IF EXISTS {{.extensionIden}}`
+const dropExtensionPattern = `DROP EXTENSION IF EXISTS {{.extensionIden}}{{if .cascade}} CASCADE{{end}}`
+const updateExtensionPattern = `ALTER EXTENSION {{.extensionIden}} UPDATE`
 
 func (d *PostgresEngine) CreateExtensions(extensions []string) error {
 	logger := d.logger.Session("create-extensions", lager.Data{extensionsLogKey: extensions})
@@ -360,8 +517,10 @@ func (d *PostgresEngine) CreateExtensions(extensions []string) error {
 	return nil
 }
 
-func (d *PostgresEngine) DropExtensions(extensions []string) error {
-	logger := d.logger.Session("drop-extensions", lager.Data{extensionsLogKey: extensions})
+// DropExtensions drops the given extensions. With cascade set, any objects
+// which depend on an extension are dropped along with it.
+func (d *PostgresEngine) DropExtensions(extensions []string, cascade bool) error {
+	logger := d.logger.Session("drop-extensions", lager.Data{extensionsLogKey: extensions, "cascade": cascade})
 	logger.Debug("start")
 
 	for _, extension := range extensions {
@@ -369,8 +528,9 @@ func (d *PostgresEngine) DropExtensions(extensions []string) error {
 			extension + "Extension",
 		).Parse(dropExtensionPattern))
 		var dropExtensionStatement bytes.Buffer
-		if err := dropExtensionTemplate.Execute(&dropExtensionStatement, map[string]string{
+		if err := dropExtensionTemplate.Execute(&dropExtensionStatement, map[string]interface{}{
 			"extensionIden": pq.QuoteIdentifier(extension),
+			"cascade":       cascade,
 		}); err != nil {
 			return err
 		}
@@ -381,6 +541,215 @@ func (d *PostgresEngine) DropExtensions(extensions []string) error {
 	return nil
 }
 
+// extensionDependentsQuery describes the objects which depend on a member of
+// an extension without being part of the extension themselves, such as a
+// table column using a type the extension provides. Dropping the extension
+// without CASCADE fails while any of these exist.
+const extensionDependentsQuery = `
+	SELECT DISTINCT pg_catalog.pg_describe_object(dep.classid, dep.objid, dep.objsubid)
+	FROM pg_catalog.pg_extension ext
+	JOIN pg_catalog.pg_depend member
+		ON member.refclassid = 'pg_catalog.pg_extension'::regclass
+		AND member.refobjid = ext.oid
+		AND member.deptype = 'e'
+	JOIN pg_catalog.pg_depend dep
+		ON dep.refclassid = member.classid
+		AND dep.refobjid = member.objid
+		AND dep.deptype IN ('n', 'a')
+	WHERE ext.extname = $1
+	AND NOT EXISTS (
+		SELECT 1 FROM pg_catalog.pg_depend own
+		WHERE own.classid = dep.classid
+		AND own.objid = dep.objid
+		AND own.refclassid = 'pg_catalog.pg_extension'::regclass
+		AND own.deptype = 'e'
+	)
+	ORDER BY 1
+`
+
+// ExtensionDependents returns the objects which would be dropped along with
+// each of the given extensions, leaving out extensions which have none.
+func (d *PostgresEngine) ExtensionDependents(extensions []string) (map[string][]string, error) {
+	logger := d.logger.Session("extension-dependents", lager.Data{extensionsLogKey: extensions})
+	logger.Debug("start")
+
+	dependents := map[string][]string{}
+	for _, extension := range extensions {
+		rows, err := d.db.Query(extensionDependentsQuery, extension)
+		if err != nil {
+			logger.Error("sql-error", err)
+			return nil, err
+		}
+		for rows.Next() {
+			var object string
+			if err := rows.Scan(&object); err != nil {
+				rows.Close()
+				logger.Error("sql-error", err)
+				return nil, err
+			}
+			dependents[extension] = append(dependents[extension], object)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			logger.Error("sql-error", err)
+			return nil, err
+		}
+	}
+	return dependents, nil
+}
+
+// UpdateExtension updates an installed extension to the default version
+// available on the server, which is needed after a major version upgrade.
+func (d *PostgresEngine) UpdateExtension(extension string) error {
+	logger := d.logger.Session("update-extension", lager.Data{"extension": extension})
+	logger.Debug("start")
+
+	updateExtensionTemplate := template.Must(template.New(
+		extension + "Extension",
+	).Parse(updateExtensionPattern))
+	var updateExtensionStatement bytes.Buffer
+	if err := updateExtensionTemplate.Execute(&updateExtensionStatement, map[string]string{
+		"extensionIden": pq.QuoteIdentifier(extension),
+	}); err != nil {
+		return err
+	}
+	if _, err := d.db.Exec(updateExtensionStatement.String()); err != nil {
+		logger.Error("sql-error", err)
+		return err
+	}
+	return nil
+}
+
+// SetBinlogRetentionHours is a no-op: binary logs are a MySQL concept.
+func (d *PostgresEngine) SetBinlogRetentionHours(hours int64) error {
+	return nil
+}
+
+// ListOtherDatabases lists the databases on the server other than the one
+// we're connected to, leaving out templates and those RDS manages.
+func (d *PostgresEngine) ListOtherDatabases() ([]string, error) {
+	logger := d.logger.Session("list-other-databases")
+	logger.Debug("start")
+
+	databases := []string{}
+	rows, err := d.db.Query(
+		`select datname
+		from pg_database
+		where datistemplate = false
+		and datname != current_database()
+		and datname not in ('postgres', 'rdsadmin')`,
+	)
+	if err != nil {
+		logger.Error("sql-error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var dbname string
+		err = rows.Scan(&dbname)
+		if err != nil {
+			logger.Error("sql-error", err)
+			return nil, err
+		}
+		databases = append(databases, dbname)
+	}
+	return databases, rows.Err()
+}
+
+// CreateDatabase creates a database on the server, unless it already exists.
+func (d *PostgresEngine) CreateDatabase(dbname string) error {
+	logger := d.logger.Session("create-database", lager.Data{"dbname": dbname})
+	logger.Debug("start")
+
+	// postgres has no CREATE DATABASE IF NOT EXISTS, and CREATE DATABASE
+	// can't run in a transaction or a DO block
+	var exists bool
+	if err := d.db.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", dbname).Scan(&exists); err != nil {
+		logger.Error("sql-error", err)
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	if _, err := d.db.Exec("CREATE DATABASE " + pq.QuoteIdentifier(dbname)); err != nil {
+		logger.Error("sql-error", err)
+		return err
+	}
+	return nil
+}
+
+func (d *PostgresEngine) DropDatabase(dbname string) error {
+	logger := d.logger.Session("drop-database", lager.Data{"dbname": dbname})
+	logger.Debug("start")
+
+	if _, err := d.db.Exec("DROP DATABASE IF EXISTS " + pq.QuoteIdentifier(dbname)); err != nil {
+		logger.Error("sql-error", err)
+		return err
+	}
+	return nil
+}
+
+// CreateSchema creates a schema in the database we're connected to, which
+// the users of dbname can create objects in and read from like the default
+// schema. Read only users are given SELECT on what is in the schema, now
+// and by default on what the manager role creates in it later; the tables
+// binding users create are made readable by the make_readable trigger. It
+// does nothing to a schema which already exists.
+func (d *PostgresEngine) CreateSchema(dbname, schema string) error {
+	logger := d.logger.Session("create-schema", lager.Data{"schema": schema})
+	logger.Debug("start")
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		logger.Error("sql-error", err)
+		return err
+	}
+	commitCalled := false
+	defer func() {
+		if !commitCalled {
+			tx.Rollback()
+		}
+	}()
+
+	if err := d.ensureGroup(logger, tx, dbname); err != nil {
+		return err
+	}
+
+	schemaIden := pq.QuoteIdentifier(schema)
+	managerIden := pq.QuoteIdentifier(dbname + "_manager")
+	readerIden := pq.QuoteIdentifier(dbname + "_reader")
+	statements := []string{
+		fmt.Sprintf(`create schema if not exists %s`, schemaIden),
+		fmt.Sprintf(`grant all on schema %s to %s`, schemaIden, managerIden),
+		fmt.Sprintf(`grant usage on schema %s to %s`, schemaIden, readerIden),
+		fmt.Sprintf(`grant select on all tables in schema %s to %s`, schemaIden, readerIden),
+		fmt.Sprintf(`grant select on all sequences in schema %s to %s`, schemaIden, readerIden),
+		// default privileges can only be set for a role we are a member of,
+		// and the membership only lasts as long as the transaction
+		fmt.Sprintf(`grant %s to current_user`, managerIden),
+		fmt.Sprintf(`alter default privileges for role %s in schema %s grant select on tables to %s`, managerIden, schemaIden, readerIden),
+		fmt.Sprintf(`alter default privileges for role %s in schema %s grant select on sequences to %s`, managerIden, schemaIden, readerIden),
+		fmt.Sprintf(`revoke %s from current_user`, managerIden),
+	}
+	for _, statement := range statements {
+		logger.Debug("exec", lager.Data{"statement": statement})
+		if _, err := tx.Exec(statement); err != nil {
+			logger.Error("sql-error", err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error("commit.sql-error", err)
+		return err
+	}
+	commitCalled = true // Prevent Rollback being called in deferred function
+
+	return nil
+}
+
 const doWrapperPattern = "DO {{.bodyStr}}"
 
 const ensureGroupBodyPattern = `
@@ -637,3 +1006,427 @@ func (d *PostgresEngine) ensureUser(logger lager.Logger, tx *sql.Tx, dbname stri
 
 	return nil
 }
+
+// CheckConnection runs a trivial query, to check that the connection is usable.
+func (d *PostgresEngine) CheckConnection() error {
+	logger := d.logger.Session("check-connection")
+	logger.Debug("start")
+
+	_, err := d.db.Exec("SELECT 1")
+	if err != nil {
+		logger.Error("sql-error", err)
+		return err
+	}
+
+	return nil
+}
+
+// DatabaseUsage returns the size on disk of the connected database and the
+// number of tables in it, leaving out the system catalogs.
+func (d *PostgresEngine) DatabaseUsage() (DatabaseUsage, error) {
+	logger := d.logger.Session("database-usage")
+	logger.Debug("start")
+
+	var usage DatabaseUsage
+	err := d.db.QueryRow(`
+		SELECT
+			pg_catalog.pg_database_size(current_database()),
+			(SELECT count(*) FROM information_schema.tables
+				WHERE table_type = 'BASE TABLE'
+				AND table_schema NOT IN ('pg_catalog', 'information_schema'))
+	`).Scan(&usage.SizeBytes, &usage.TableCount)
+	if err != nil {
+		logger.Error("sql-error", err)
+		return DatabaseUsage{}, err
+	}
+	return usage, nil
+}
+
+// TransactionIDAges returns how many transactions old the oldest unfrozen
+// transaction ID of each database on the server is. Once one gets to around
+// 2 billion, the server stops accepting writes to avoid wraparound, so a
+// growing age means autovacuum isn't freezing rows fast enough.
+func (d *PostgresEngine) TransactionIDAges() (map[string]int64, error) {
+	logger := d.logger.Session("transaction-id-ages")
+	logger.Debug("start")
+
+	rows, err := d.db.Query("SELECT datname, age(datfrozenxid) FROM pg_catalog.pg_database WHERE datallowconn")
+	if err != nil {
+		logger.Error("sql-error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	ages := map[string]int64{}
+	for rows.Next() {
+		var dbname string
+		var age int64
+		if err := rows.Scan(&dbname, &age); err != nil {
+			logger.Error("sql-error", err)
+			return nil, err
+		}
+		ages[dbname] = age
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("sql-error", err)
+		return nil, err
+	}
+	return ages, nil
+}
+
+// ConnectionUsage counts the client connections to the server, and how many
+// of them are idle, against its max_connections. The connection making the
+// query is counted too.
+func (d *PostgresEngine) ConnectionUsage() (ConnectionUsage, error) {
+	logger := d.logger.Session("connection-usage")
+	logger.Debug("start")
+
+	var usage ConnectionUsage
+	err := d.db.QueryRow(`
+		SELECT
+			count(*),
+			count(*) FILTER (WHERE state = 'idle'),
+			current_setting('max_connections')::bigint
+		FROM pg_catalog.pg_stat_activity
+		WHERE backend_type = 'client backend'
+	`).Scan(&usage.Connections, &usage.IdleConnections, &usage.MaxConnections)
+	if err != nil {
+		logger.Error("sql-error", err)
+		return ConnectionUsage{}, err
+	}
+	return usage, nil
+}
+
+// CopySchemaFrom copies the schema of another database, without its data,
+// into the one the engine is connected to, by piping pg_dump into
+// pg_restore. Objects are created owned by the engine's user, and grants
+// aren't copied. Both programs need to be on the PATH, at the major version
+// of the servers or later.
+func (d *PostgresEngine) CopySchemaFrom(address string, port int64, dbname string, username string, password string) error {
+	logger := d.logger.Session("copy-schema-from", lager.Data{"address": address, "dbname": dbname})
+	logger.Debug("start")
+
+	if d.opened == nil {
+		return errors.New("The engine must be opened before copying a schema into it")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), schemaCopyTimeout)
+	defer cancel()
+
+	dump := exec.CommandContext(ctx, "pg_dump", "--schema-only", "--no-owner", "--no-privileges", "--format=custom")
+	dump.Env = d.clientEnv(postgresConnection{address, port, dbname, username, password})
+	dumpStderr := &bytes.Buffer{}
+	dump.Stderr = dumpStderr
+
+	restore := exec.CommandContext(ctx, "pg_restore", "--no-owner", "--no-privileges", "--exit-on-error", "--dbname", d.opened.dbname)
+	restore.Env = d.clientEnv(*d.opened)
+	restoreStderr := &bytes.Buffer{}
+	restore.Stderr = restoreStderr
+
+	dumpOutput, err := dump.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	restore.Stdin = dumpOutput
+
+	if err := dump.Start(); err != nil {
+		return fmt.Errorf("Starting pg_dump: %s", err)
+	}
+	if err := restore.Start(); err != nil {
+		dump.Process.Kill()
+		dump.Wait()
+		return fmt.Errorf("Starting pg_restore: %s", err)
+	}
+	// pg_restore has its own copy of the pipe, and closing ours lets pg_dump
+	// stop if pg_restore exits early
+	dumpOutput.Close()
+
+	restoreErr := restore.Wait()
+	dumpErr := dump.Wait()
+	// pg_restore is checked first, as pg_dump fails writing to the pipe
+	// when pg_restore has given up
+	if restoreErr != nil {
+		logger.Error("pg-restore", restoreErr, lager.Data{"stderr": restoreStderr.String()})
+		return fmt.Errorf("pg_restore failed: %s: %s", restoreErr, strings.TrimSpace(restoreStderr.String()))
+	}
+	if dumpErr != nil {
+		logger.Error("pg-dump", dumpErr, lager.Data{"stderr": dumpStderr.String()})
+		return fmt.Errorf("pg_dump failed: %s: %s", dumpErr, strings.TrimSpace(dumpStderr.String()))
+	}
+
+	return nil
+}
+
+// ImportData seeds the database the engine is connected to with the data
+// of the objects. An SQL dump is run through the driver in a single
+// transaction which stops at the first error, so plain pg_dump output with
+// COPY blocks can be imported, but psql meta-commands can't. CSV files are
+// copied into the tables they are named after, with their first row naming
+// the columns and empty values taken as NULL, all in one transaction.
+func (d *PostgresEngine) ImportData(format string, objects []ImportObject) error {
+	logger := d.logger.Session("import-data", lager.Data{"format": format, "objects": len(objects)})
+	logger.Debug("start")
+
+	ctx, cancel := context.WithTimeout(context.Background(), dataImportTimeout)
+	defer cancel()
+
+	switch format {
+	case ImportFormatSQL:
+		return d.importSQL(ctx, logger, objects)
+	case ImportFormatCSV:
+		return d.importCSV(ctx, logger, objects)
+	}
+	return fmt.Errorf("Unknown data import format '%s'", format)
+}
+
+func (d *PostgresEngine) importSQL(ctx context.Context, logger lager.Logger, objects []ImportObject) error {
+	// the dump may change the settings of its session, so it gets its own
+	// connection, which is reset before it goes back into the pool
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		logger.Error("sql-error", err)
+		return err
+	}
+	defer conn.Close()
+	defer conn.ExecContext(context.Background(), "RESET ALL")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("sql-error", err)
+		return err
+	}
+
+	for _, object := range objects {
+		if err := runSQLDump(ctx, tx, object); err != nil {
+			logger.Error("run-sql-dump", err, lager.Data{"object": object.Name})
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error("sql-error", err)
+		return err
+	}
+	return nil
+}
+
+// sqlDumpBatchSize is roughly how much of an SQL dump is sent to the
+// database at once, between its COPY statements.
+const sqlDumpBatchSize = 1024 * 1024
+
+func runSQLDump(ctx context.Context, tx *sql.Tx, object ImportObject) error {
+	body, err := fetchImportObject(ctx, object)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	dump := newSQLDumpReader(body)
+	batch := &strings.Builder{}
+	flush := func() error {
+		if batch.Len() == 0 {
+			return nil
+		}
+		_, err := tx.ExecContext(ctx, batch.String())
+		batch.Reset()
+		return err
+	}
+
+	for {
+		statement, err := dump.nextStatement()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Importing %s: %s", object.Name, err)
+		}
+
+		if !copyFromStdinPattern.MatchString(statement) {
+			batch.WriteString(statement)
+			batch.WriteString("\n")
+			if batch.Len() >= sqlDumpBatchSize {
+				if err := flush(); err != nil {
+					return fmt.Errorf("Importing %s: %s", object.Name, err)
+				}
+			}
+			continue
+		}
+
+		if err := flush(); err != nil {
+			return fmt.Errorf("Importing %s: %s", object.Name, err)
+		}
+		if err := copyFromSQLDump(ctx, tx, dump, statement); err != nil {
+			return fmt.Errorf("Importing %s: %s", object.Name, err)
+		}
+	}
+
+	if err := flush(); err != nil {
+		return fmt.Errorf("Importing %s: %s", object.Name, err)
+	}
+	return nil
+}
+
+// copyFromSQLDump runs a COPY ... FROM stdin statement of an SQL dump with
+// the rows of data which follow it.
+func copyFromSQLDump(ctx context.Context, tx *sql.Tx, dump *sqlDumpReader, statement string) error {
+	if err := dump.skipRestOfLine(); err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, statement)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for {
+		values, ok, err := dump.copyRow()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			return err
+		}
+	}
+
+	_, err = stmt.ExecContext(ctx)
+	return err
+}
+
+func (d *PostgresEngine) importCSV(ctx context.Context, logger lager.Logger, objects []ImportObject) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("sql-error", err)
+		return err
+	}
+
+	for _, object := range objects {
+		if err := copyCSV(ctx, tx, object); err != nil {
+			logger.Error("copy-csv", err, lager.Data{"object": object.Name})
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error("sql-error", err)
+		return err
+	}
+	return nil
+}
+
+func copyCSV(ctx context.Context, tx *sql.Tx, object ImportObject) error {
+	body, err := fetchImportObject(ctx, object)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	reader := csv.NewReader(body)
+	columns, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("Reading the header of %s: %s", object.Name, err)
+	}
+
+	stmt, err := tx.Prepare(pq.CopyIn(object.Name, columns...))
+	if err != nil {
+		return fmt.Errorf("Importing %s: %s", object.Name, err)
+	}
+	defer stmt.Close()
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Reading %s: %s", object.Name, err)
+		}
+
+		values := make([]interface{}, len(record))
+		for i, value := range record {
+			if value != "" {
+				values[i] = value
+			}
+		}
+		if _, err := stmt.Exec(values...); err != nil {
+			return fmt.Errorf("Importing %s: %s", object.Name, err)
+		}
+	}
+
+	if _, err := stmt.Exec(); err != nil {
+		return fmt.Errorf("Importing %s: %s", object.Name, err)
+	}
+	return nil
+}
+
+// ExportData writes a dump of the database the engine is connected to, in
+// pg_dump's custom format, which pg_restore can load into any Postgres of
+// the same major version or later. Ownership and grants are left out, as
+// the roles of the broker's bindings won't exist wherever it is restored.
+func (d *PostgresEngine) ExportData(w io.Writer) error {
+	logger := d.logger.Session("export-data")
+	logger.Debug("start")
+
+	if d.opened == nil {
+		return errors.New("The engine must be opened before exporting data from it")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dataExportTimeout)
+	defer cancel()
+
+	dump := exec.CommandContext(ctx, "pg_dump", "--no-owner", "--no-privileges", "--format=custom", "--dbname", d.opened.dbname)
+	dump.Env = d.clientEnv(*d.opened)
+	dump.Stdout = w
+	stderr := &bytes.Buffer{}
+	dump.Stderr = stderr
+
+	if err := dump.Run(); err != nil {
+		logger.Error("pg-dump", err, lager.Data{"stderr": stderr.String()})
+		return fmt.Errorf("pg_dump failed: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// fetchImportObject starts downloading an object to import. Errors leave out
+// its URL, as it may be presigned.
+func fetchImportObject(ctx context.Context, object ImportObject) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, object.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Fetching %s: invalid URL", object.Name)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("Fetching %s: %s", object.Name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Fetching %s: %s", object.Name, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// clientEnv is the environment which points the Postgres client programs
+// at a database, so that the password isn't on their command line.
+func (d *PostgresEngine) clientEnv(connection postgresConnection) []string {
+	sslMode := "require"
+	if !d.requireSSL {
+		sslMode = "disable"
+	}
+	return append(os.Environ(),
+		"PGHOST="+connection.address,
+		fmt.Sprintf("PGPORT=%d", connection.port),
+		"PGDATABASE="+connection.dbname,
+		"PGUSER="+connection.username,
+		"PGPASSWORD="+connection.password,
+		"PGSSLMODE="+sslMode,
+	)
+}
@@ -1,12 +1,17 @@
 package sqlengine
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/alphagov/paas-rds-broker/utils"
 	"github.com/lib/pq"
@@ -179,6 +184,15 @@ var _ = Describe("PostgresEngine", func() {
 		Expect(err).ToNot(HaveOccurred())
 	})
 
+	It("can check a connection is usable", func() {
+		err := postgresEngine.Open(address, port, dbname, masterUsername, masterPassword)
+		defer postgresEngine.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		err = postgresEngine.CheckConnection()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
 	It("returns error if engine is the database is not reachable", func() {
 		err := postgresEngine.Open("localhost", 1, dbname, masterUsername, masterPassword)
 		defer postgresEngine.Close()
@@ -254,6 +268,21 @@ var _ = Describe("PostgresEngine", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 
+		It("can set a statement timeout on the user", func() {
+			err := postgresEngine.SetUserStatementTimeout(bindingID, 30*time.Second)
+			Expect(err).ToNot(HaveOccurred())
+
+			connectionString := postgresEngine.URI(address, port, dbname, createdUser, createdPassword)
+			db, err := sql.Open("postgres", connectionString)
+			Expect(err).ToNot(HaveOccurred())
+			defer db.Close()
+
+			var statementTimeout string
+			err = db.QueryRow("SHOW statement_timeout").Scan(&statementTimeout)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(statementTimeout).To(Equal("30s"))
+		})
+
 		It("creates a user with the necessary permissions on the database", func() {
 			connectionString := postgresEngine.URI(address, port, dbname, createdUser, createdPassword)
 			db, err := sql.Open("postgres", connectionString)
@@ -660,6 +689,274 @@ var _ = Describe("PostgresEngine", func() {
 		})
 	})
 
+	Describe("TerminateConnections", func() {
+		var (
+			bindingID       string
+			createdUser     string
+			createdPassword string
+		)
+
+		BeforeEach(func() {
+			bindingID = "binding-id" + randomTestSuffix
+			err := postgresEngine.Open(address, port, dbname, masterUsername, masterPassword)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdUser, createdPassword, err = postgresEngine.CreateUser(bindingID, dbname, false)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			err := postgresEngine.DropUser(bindingID)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("ends the sessions of the created users", func() {
+			connectionString := postgresEngine.URI(address, port, dbname, createdUser, createdPassword)
+			db, err := sql.Open("postgres", connectionString)
+			Expect(err).ToNot(HaveOccurred())
+			defer db.Close()
+
+			conn, err := db.Conn(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.Close()
+			Expect(conn.PingContext(context.Background())).To(Succeed())
+
+			err = postgresEngine.TerminateConnections()
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = conn.ExecContext(context.Background(), "select 1")
+			Expect(err).To(HaveOccurred())
+
+			By("keeping our own session")
+			Expect(postgresEngine.CheckConnection()).To(Succeed())
+		})
+	})
+
+	Describe("CreateDatabase", func() {
+		var otherDBName string
+
+		BeforeEach(func() {
+			otherDBName = "other" + randomTestSuffix
+			err := postgresEngine.Open(address, port, dbname, masterUsername, masterPassword)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			err := postgresEngine.DropDatabase(otherDBName)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("creates the database, and does nothing when it already exists", func() {
+			err := postgresEngine.CreateDatabase(otherDBName)
+			Expect(err).ToNot(HaveOccurred())
+			err = postgresEngine.CreateDatabase(otherDBName)
+			Expect(err).ToNot(HaveOccurred())
+
+			databases, err := postgresEngine.ListOtherDatabases()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(databases).To(ContainElement(otherDBName))
+		})
+	})
+
+	Describe("CopySchemaFrom", func() {
+		var templateDBName string
+
+		BeforeEach(func() {
+			templateDBName = "template" + randomTestSuffix
+			createDB(template1ConnectionString, templateDBName)
+			createObjects(postgresEngine.URI(address, port, templateDBName, masterUsername, masterPassword), "widgets")
+
+			err := postgresEngine.Open(address, port, dbname, masterUsername, masterPassword)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			dropDB(template1ConnectionString, templateDBName)
+		})
+
+		It("creates the template's tables without their rows", func() {
+			err := postgresEngine.CopySchemaFrom(address, port, templateDBName, masterUsername, masterPassword)
+			Expect(err).ToNot(HaveOccurred())
+
+			db, err := sql.Open("postgres", postgresEngine.URI(address, port, dbname, masterUsername, masterPassword))
+			Expect(err).ToNot(HaveOccurred())
+			defer db.Close()
+
+			var rows int
+			err = db.QueryRow("SELECT count(*) FROM widgets").Scan(&rows)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rows).To(Equal(0))
+		})
+
+		It("returns error if the template can't be dumped", func() {
+			err := postgresEngine.CopySchemaFrom(address, port, "missing"+randomTestSuffix, masterUsername, masterPassword)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("ExportData", func() {
+		It("writes a dump in pg_dump's custom format", func() {
+			err := postgresEngine.Open(address, port, dbname, masterUsername, masterPassword)
+			Expect(err).ToNot(HaveOccurred())
+
+			dump := &bytes.Buffer{}
+			err = postgresEngine.ExportData(dump)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dump.String()).To(HavePrefix("PGDMP"))
+		})
+
+		It("returns error if the engine hasn't been opened", func() {
+			err := postgresEngine.ExportData(&bytes.Buffer{})
+			Expect(err).To(MatchError("The engine must be opened before exporting data from it"))
+		})
+	})
+
+	Describe("ImportData", func() {
+		var (
+			server  *httptest.Server
+			objects map[string]string
+		)
+
+		BeforeEach(func() {
+			objects = map[string]string{
+				"/seed.sql":    "CREATE TABLE gadgets (id integer, name text);\nINSERT INTO gadgets VALUES (1, 'sprocket');\n",
+				"/gadgets.csv": "name,id\nsprocket,1\n,2\n",
+				"/dump.sql":    "\\restrict abc123\nCREATE TABLE gadgets (id integer, name text);\nCOPY public.gadgets (id, name) FROM stdin;\n1\tsprocket\n2\t\\N\n\\.\n\\unrestrict abc123\n",
+				"/shell.sql":   "CREATE TABLE gadgets (id integer, name text);\n\\! touch /tmp/imported\n",
+			}
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, ok := objects[r.URL.Path]
+				if !ok {
+					http.NotFound(w, r)
+					return
+				}
+				fmt.Fprint(w, body)
+			}))
+
+			err := postgresEngine.Open(address, port, dbname, masterUsername, masterPassword)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			server.Close()
+			_, err := postgresEngine.db.Exec("DROP TABLE IF EXISTS gadgets")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("runs SQL dumps", func() {
+			err := postgresEngine.ImportData(ImportFormatSQL, []ImportObject{{Name: "seed.sql", URL: server.URL + "/seed.sql"}})
+			Expect(err).ToNot(HaveOccurred())
+
+			var name string
+			err = postgresEngine.db.QueryRow("SELECT name FROM gadgets WHERE id = 1").Scan(&name)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(Equal("sprocket"))
+		})
+
+		It("runs the COPY statements of pg_dump output", func() {
+			err := postgresEngine.ImportData(ImportFormatSQL, []ImportObject{{Name: "dump.sql", URL: server.URL + "/dump.sql"}})
+			Expect(err).ToNot(HaveOccurred())
+
+			var nulls int
+			err = postgresEngine.db.QueryRow("SELECT count(*) FROM gadgets WHERE name IS NULL").Scan(&nulls)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nulls).To(Equal(1))
+		})
+
+		It("refuses SQL dumps with psql meta-commands, importing none of them", func() {
+			err := postgresEngine.ImportData(ImportFormatSQL, []ImportObject{{Name: "shell.sql", URL: server.URL + "/shell.sql"}})
+			Expect(err).To(MatchError(`Importing shell.sql: psql meta-commands such as '\!' aren't supported`))
+
+			var exists bool
+			err = postgresEngine.db.QueryRow("SELECT to_regclass('gadgets') IS NOT NULL").Scan(&exists)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeFalse())
+		})
+
+		It("copies CSV files into the tables they are named after", func() {
+			_, err := postgresEngine.db.Exec("CREATE TABLE gadgets (id integer, name text)")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = postgresEngine.ImportData(ImportFormatCSV, []ImportObject{{Name: "gadgets", URL: server.URL + "/gadgets.csv"}})
+			Expect(err).ToNot(HaveOccurred())
+
+			var nulls int
+			err = postgresEngine.db.QueryRow("SELECT count(*) FROM gadgets WHERE name IS NULL").Scan(&nulls)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nulls).To(Equal(1))
+		})
+
+		It("returns error without the URL if an object can't be fetched", func() {
+			err := postgresEngine.ImportData(ImportFormatSQL, []ImportObject{{Name: "missing.sql", URL: server.URL + "/missing.sql"}})
+			Expect(err).To(MatchError("Fetching missing.sql: 404 Not Found"))
+		})
+	})
+
+	Describe("Schemas", func() {
+		var (
+			bindingID string
+			schema    string
+		)
+
+		BeforeEach(func() {
+			bindingID = "binding-id" + randomTestSuffix
+			schema = "schema" + randomTestSuffix
+			err := postgresEngine.Open(address, port, dbname, masterUsername, masterPassword)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			err := postgresEngine.DropUser(bindingID)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = postgresEngine.db.Exec("drop schema if exists " + pq.QuoteIdentifier(schema) + " cascade")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("CreateSchema() creates a schema the users can create tables in", func() {
+			err := postgresEngine.CreateSchema(dbname, schema)
+			Expect(err).ToNot(HaveOccurred())
+
+			By("doing nothing when the schema already exists")
+			err = postgresEngine.CreateSchema(dbname, schema)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdUser, createdPassword, err := postgresEngine.CreateUser(bindingID, dbname, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			connectionString := postgresEngine.URI(address, port, dbname, createdUser, createdPassword)
+			db, err := sql.Open("postgres", connectionString)
+			Expect(err).ToNot(HaveOccurred())
+			defer db.Close()
+
+			_, err = db.Exec(fmt.Sprintf("create table %s.things (id int)", pq.QuoteIdentifier(schema)))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = db.Exec(fmt.Sprintf("drop table %s.things", pq.QuoteIdentifier(schema)))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("CreateSchema() lets read only users read what the manager role creates in it", func() {
+			err := postgresEngine.CreateSchema(dbname, schema)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = postgresEngine.db.Exec(fmt.Sprintf(
+				"set role %s; create table %s.things (id int); reset role",
+				pq.QuoteIdentifier(dbname+"_manager"), pq.QuoteIdentifier(schema),
+			))
+			Expect(err).ToNot(HaveOccurred())
+
+			createdUser, createdPassword, err := postgresEngine.CreateUser(bindingID, dbname, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			connectionString := postgresEngine.URI(address, port, dbname, createdUser, createdPassword)
+			db, err := sql.Open("postgres", connectionString)
+			Expect(err).ToNot(HaveOccurred())
+			defer db.Close()
+
+			_, err = db.Exec(fmt.Sprintf("select * from %s.things", pq.QuoteIdentifier(schema)))
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
 	Describe("Extensions", func() {
 		It("can create and drop extensions", func() {
 			By("creating the extensions")
@@ -685,7 +982,7 @@ var _ = Describe("PostgresEngine", func() {
 			Expect(extensions).To(ContainElement("pgcrypto"))
 
 			By("dropping the extensions")
-			err = postgresEngine.DropExtensions([]string{"pgcrypto"})
+			err = postgresEngine.DropExtensions([]string{"pgcrypto"}, false)
 			Expect(err).ToNot(HaveOccurred())
 			rows, err = postgresEngine.db.Query("SELECT extname FROM pg_catalog.pg_extension")
 			defer rows.Close()
@@ -703,5 +1000,127 @@ var _ = Describe("PostgresEngine", func() {
 			Expect(extensions).To(ContainElement("uuid-ossp"))
 			Expect(extensions).ToNot(ContainElement("pgcrypto"))
 		})
+
+		It("can update an installed extension", func() {
+			err := postgresEngine.Open(address, port, dbname, masterUsername, masterPassword)
+			defer postgresEngine.Close()
+			Expect(err).ToNot(HaveOccurred())
+			err = postgresEngine.CreateExtensions([]string{"uuid-ossp"})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = postgresEngine.UpdateExtension("uuid-ossp")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("fails to update an extension which isn't installed", func() {
+			err := postgresEngine.Open(address, port, dbname, masterUsername, masterPassword)
+			defer postgresEngine.Close()
+			Expect(err).ToNot(HaveOccurred())
+
+			err = postgresEngine.UpdateExtension("hstore")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("lists objects depending on an extension and only drops them with cascade", func() {
+			err := postgresEngine.Open(address, port, dbname, masterUsername, masterPassword)
+			defer postgresEngine.Close()
+			Expect(err).ToNot(HaveOccurred())
+			err = postgresEngine.CreateExtensions([]string{"citext", "pgcrypto"})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = postgresEngine.db.Exec("CREATE TABLE citext_users (email citext)")
+			Expect(err).ToNot(HaveOccurred())
+
+			By("listing the dependent objects")
+			dependents, err := postgresEngine.ExtensionDependents([]string{"citext", "pgcrypto"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dependents).To(Equal(map[string][]string{
+				"citext": {"column email of table citext_users"},
+			}))
+
+			By("failing to drop the extension without cascade")
+			err = postgresEngine.DropExtensions([]string{"citext"}, false)
+			Expect(err).To(HaveOccurred())
+
+			By("dropping the extension and its dependents with cascade")
+			err = postgresEngine.DropExtensions([]string{"citext"}, true)
+			Expect(err).ToNot(HaveOccurred())
+			dependents, err = postgresEngine.ExtensionDependents([]string{"citext"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dependents).To(BeEmpty())
+		})
+	})
+
+	Describe("DatabaseUsage", func() {
+		It("reports the size of the database and the number of tables in it", func() {
+			err := postgresEngine.Open(address, port, dbname, masterUsername, masterPassword)
+			defer postgresEngine.Close()
+			Expect(err).ToNot(HaveOccurred())
+
+			before, err := postgresEngine.DatabaseUsage()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(before.SizeBytes).To(BeNumerically(">", 0))
+
+			_, err = postgresEngine.db.Exec("CREATE TABLE usage_test (id integer)")
+			Expect(err).ToNot(HaveOccurred())
+
+			after, err := postgresEngine.DatabaseUsage()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(after.TableCount).To(Equal(before.TableCount + 1))
+		})
+	})
+
+	Describe("TransactionIDAges", func() {
+		It("reports the age of the oldest unfrozen transaction ID of each database", func() {
+			err := postgresEngine.Open(address, port, dbname, masterUsername, masterPassword)
+			defer postgresEngine.Close()
+			Expect(err).ToNot(HaveOccurred())
+
+			ages, err := postgresEngine.TransactionIDAges()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ages).To(HaveKey(dbname))
+			Expect(ages).ToNot(HaveKey("template0"))
+			Expect(ages[dbname]).To(BeNumerically(">=", 0))
+		})
+	})
+
+	Describe("ConnectionUsage", func() {
+		It("counts the client connections, including its own, against max_connections", func() {
+			err := postgresEngine.Open(address, port, dbname, masterUsername, masterPassword)
+			defer postgresEngine.Close()
+			Expect(err).ToNot(HaveOccurred())
+
+			usage, err := postgresEngine.ConnectionUsage()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(usage.Connections).To(BeNumerically(">=", 1))
+			Expect(usage.IdleConnections).To(BeNumerically("<", usage.Connections))
+			Expect(usage.MaxConnections).To(BeNumerically(">", usage.Connections))
+		})
+	})
+
+	Describe("Other databases", func() {
+		It("can list and drop databases other than the one connected to", func() {
+			otherDBName := "otherdb" + randomTestSuffix
+			createDB(template1ConnectionString, otherDBName)
+
+			err := postgresEngine.Open(address, port, dbname, masterUsername, masterPassword)
+			defer postgresEngine.Close()
+			Expect(err).ToNot(HaveOccurred())
+
+			By("listing the other databases")
+			databases, err := postgresEngine.ListOtherDatabases()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(databases).To(ContainElement(otherDBName))
+			Expect(databases).ToNot(ContainElement(dbname))
+			Expect(databases).ToNot(ContainElement("postgres"))
+			Expect(databases).ToNot(ContainElement("template1"))
+
+			By("dropping the other database")
+			err = postgresEngine.DropDatabase(otherDBName)
+			Expect(err).ToNot(HaveOccurred())
+
+			databases, err = postgresEngine.ListOtherDatabases()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(databases).ToNot(ContainElement(otherDBName))
+		})
 	})
 })
@@ -1,5 +1,7 @@
 package sqlengine
 
+//go:generate counterfeiter -o fakes/fake_provider.go . Provider
 type Provider interface {
 	GetSQLEngine(engine string) (SQLEngine, error)
+	Ping(engine string) error
 }
@@ -1,22 +1,40 @@
 package sqlengine
 
 import (
+	"database/sql"
 	"fmt"
 	"strings"
 
 	"code.cloudfoundry.org/lager/v3"
 )
 
+// Canary is a database which pinging an engine connects to, to check the
+// broker can reach databases of that engine and log in to them.
+type Canary struct {
+	Address  string
+	Port     int64
+	DBName   string
+	Username string
+	Password string
+}
+
 type ProviderService struct {
-	logger lager.Logger
+	logger   lager.Logger
+	canaries map[string]Canary
 }
 
 func NewProviderService(logger lager.Logger) *ProviderService {
 	return &ProviderService{
-		logger: logger,
+		logger:   logger,
+		canaries: map[string]Canary{},
 	}
 }
 
+// SetCanary sets the database which pinging the engine connects to.
+func (p *ProviderService) SetCanary(engine string, canary Canary) {
+	p.canaries[strings.ToLower(engine)] = canary
+}
+
 func (p *ProviderService) GetSQLEngine(engine string) (SQLEngine, error) {
 	switch strings.ToLower(engine) {
 	case "mariadb", "mysql":
@@ -27,3 +45,54 @@ func (p *ProviderService) GetSQLEngine(engine string) (SQLEngine, error) {
 
 	return nil, fmt.Errorf("SQL Engine '%s' not supported", engine)
 }
+
+// Ping checks the engine is supported and its database driver is
+// registered, then, if the engine has a canary, connects to it and runs a
+// query.
+func (p *ProviderService) Ping(engine string) error {
+	sqlEngine, err := p.GetSQLEngine(engine)
+	if err != nil {
+		return err
+	}
+
+	driverName := sqlDriverName(engine)
+	if !driverRegistered(driverName) {
+		return fmt.Errorf("SQL driver '%s' for engine '%s' is not registered", driverName, engine)
+	}
+
+	canary, found := p.canaries[strings.ToLower(engine)]
+	if !found {
+		return nil
+	}
+	p.logger.Debug("ping.canary", lager.Data{"engine": engine, "address": canary.Address, "dbname": canary.DBName})
+	err = sqlEngine.Open(canary.Address, canary.Port, canary.DBName, canary.Username, canary.Password)
+	defer sqlEngine.Close()
+	if err != nil {
+		return fmt.Errorf("Connecting to the canary database of engine '%s': %s", engine, err)
+	}
+
+	if err := sqlEngine.CheckConnection(); err != nil {
+		return fmt.Errorf("Querying the canary database of engine '%s': %s", engine, err)
+	}
+	return nil
+}
+
+// sqlDriverName is the name the database/sql driver of a supported engine
+// is registered under.
+func sqlDriverName(engine string) string {
+	switch strings.ToLower(engine) {
+	case "mariadb", "mysql":
+		return "mysql"
+	default:
+		return "postgres"
+	}
+}
+
+func driverRegistered(driverName string) bool {
+	for _, registered := range sql.Drivers() {
+		if registered == driverName {
+			return true
+		}
+	}
+	return false
+}
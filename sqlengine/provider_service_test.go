@@ -59,4 +59,32 @@ var _ = Describe("Provider Service", func() {
 			})
 		})
 	})
+
+	Describe("Ping", func() {
+		It("returns error if engine is not supported", func() {
+			err := sqlProvider.Ping("unknown")
+			Expect(err).To(MatchError("SQL Engine 'unknown' not supported"))
+		})
+
+		It("succeeds for a supported engine without a canary", func() {
+			Expect(sqlProvider.Ping("postgres")).To(Succeed())
+			Expect(sqlProvider.Ping("mariadb")).To(Succeed())
+		})
+
+		It("returns error if the canary database can't be connected to", func() {
+			sqlProvider.SetCanary("postgres", Canary{
+				Address:  "127.0.0.1",
+				Port:     1,
+				DBName:   "canary",
+				Username: "canary",
+				Password: "secret",
+			})
+
+			err := sqlProvider.Ping("postgres")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Connecting to the canary database of engine 'postgres'"))
+
+			Expect(sqlProvider.Ping("mysql")).To(Succeed())
+		})
+	})
 })
@@ -0,0 +1,175 @@
+package sqlengine
+
+import (
+	"io"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+)
+
+// SimulatedProvider provides SQL engines which don't connect to anything,
+// for DB instances simulated in dry run mode.
+type SimulatedProvider struct {
+	provider *ProviderService
+	logger   lager.Logger
+}
+
+func NewSimulatedProvider(logger lager.Logger) *SimulatedProvider {
+	return &SimulatedProvider{
+		provider: NewProviderService(logger),
+		logger:   logger,
+	}
+}
+
+func (p *SimulatedProvider) GetSQLEngine(engine string) (SQLEngine, error) {
+	sqlEngine, err := p.provider.GetSQLEngine(engine)
+	if err != nil {
+		return nil, err
+	}
+	return &SimulatedEngine{
+		engine: sqlEngine,
+		logger: p.logger.Session("simulated-sql-engine", lager.Data{"engine": engine}),
+	}, nil
+}
+
+// Ping only checks the engine is supported, as simulated engines don't
+// connect to anything.
+func (p *SimulatedProvider) Ping(engine string) error {
+	_, err := p.provider.GetSQLEngine(engine)
+	return err
+}
+
+// SimulatedEngine logs the changes it is asked to make instead of making
+// them. The URIs it gives are those of the engine it simulates, so that
+// binding credentials look the same as they would for a real database.
+type SimulatedEngine struct {
+	engine SQLEngine
+	logger lager.Logger
+}
+
+func (d *SimulatedEngine) Open(address string, port int64, dbname string, username string, password string) error {
+	d.logger.Debug("open", lager.Data{"address": address, "dbname": dbname, "username": username})
+	return nil
+}
+
+func (d *SimulatedEngine) Close() {}
+
+func (d *SimulatedEngine) CreateUser(bindingID, dbname string, readOnly bool) (string, string, error) {
+	d.logger.Info("create-user", lager.Data{"binding-id": bindingID, "dbname": dbname, "read-only": readOnly})
+	return generateUsername(bindingID), generatePassword(), nil
+}
+
+func (d *SimulatedEngine) DropUser(bindingID string) error {
+	d.logger.Info("drop-user", lager.Data{"binding-id": bindingID})
+	return nil
+}
+
+func (d *SimulatedEngine) SetUserExpiry(bindingID, dbname string, expiresAt time.Time) error {
+	d.logger.Info("set-user-expiry", lager.Data{"binding-id": bindingID, "expires-at": expiresAt})
+	return nil
+}
+
+func (d *SimulatedEngine) SetUserStatementTimeout(bindingID string, timeout time.Duration) error {
+	d.logger.Info("set-user-statement-timeout", lager.Data{"binding-id": bindingID, "timeout": timeout.String()})
+	return nil
+}
+
+func (d *SimulatedEngine) DropExpiredUsers() ([]string, error) {
+	return []string{}, nil
+}
+
+func (d *SimulatedEngine) ResetState() error {
+	d.logger.Info("reset-state")
+	return nil
+}
+
+func (d *SimulatedEngine) TerminateConnections() error {
+	d.logger.Info("terminate-connections")
+	return nil
+}
+
+func (d *SimulatedEngine) URI(address string, port int64, dbname string, username string, password string) string {
+	return d.engine.URI(address, port, dbname, username, password)
+}
+
+func (d *SimulatedEngine) JDBCURI(address string, port int64, dbname string, username string, password string) string {
+	return d.engine.JDBCURI(address, port, dbname, username, password)
+}
+
+func (d *SimulatedEngine) CreateExtensions(extensions []string) error {
+	d.logger.Info("create-extensions", lager.Data{"extensions": extensions})
+	return nil
+}
+
+func (d *SimulatedEngine) DropExtensions(extensions []string, cascade bool) error {
+	d.logger.Info("drop-extensions", lager.Data{"extensions": extensions, "cascade": cascade})
+	return nil
+}
+
+func (d *SimulatedEngine) ExtensionDependents(extensions []string) (map[string][]string, error) {
+	return map[string][]string{}, nil
+}
+
+func (d *SimulatedEngine) UpdateExtension(extension string) error {
+	d.logger.Info("update-extension", lager.Data{"extension": extension})
+	return nil
+}
+
+func (d *SimulatedEngine) SetBinlogRetentionHours(hours int64) error {
+	d.logger.Info("set-binlog-retention-hours", lager.Data{"hours": hours})
+	return nil
+}
+
+func (d *SimulatedEngine) ListOtherDatabases() ([]string, error) {
+	return []string{}, nil
+}
+
+func (d *SimulatedEngine) CreateDatabase(dbname string) error {
+	d.logger.Info("create-database", lager.Data{"dbname": dbname})
+	return nil
+}
+
+func (d *SimulatedEngine) DropDatabase(dbname string) error {
+	d.logger.Info("drop-database", lager.Data{"dbname": dbname})
+	return nil
+}
+
+func (d *SimulatedEngine) CreateSchema(dbname, schema string) error {
+	d.logger.Info("create-schema", lager.Data{"dbname": dbname, "schema": schema})
+	return nil
+}
+
+func (d *SimulatedEngine) CheckConnection() error {
+	return nil
+}
+
+func (d *SimulatedEngine) DatabaseUsage() (DatabaseUsage, error) {
+	return DatabaseUsage{}, nil
+}
+
+func (d *SimulatedEngine) TransactionIDAges() (map[string]int64, error) {
+	return map[string]int64{}, nil
+}
+
+func (d *SimulatedEngine) ConnectionUsage() (ConnectionUsage, error) {
+	return ConnectionUsage{}, nil
+}
+
+func (d *SimulatedEngine) CopySchemaFrom(address string, port int64, dbname string, username string, password string) error {
+	d.logger.Info("copy-schema-from", lager.Data{"address": address, "dbname": dbname})
+	return nil
+}
+
+func (d *SimulatedEngine) ImportData(format string, objects []ImportObject) error {
+	names := []string{}
+	for _, object := range objects {
+		names = append(names, object.Name)
+	}
+	d.logger.Info("import-data", lager.Data{"format": format, "objects": names})
+	return nil
+}
+
+func (d *SimulatedEngine) ExportData(w io.Writer) error {
+	d.logger.Info("export-data")
+	return nil
+}
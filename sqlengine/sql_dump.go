@@ -0,0 +1,299 @@
+package sqlengine
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// copyFromStdinPattern matches the COPY statements of a dump whose data
+// follows them, as pg_dump writes by default.
+var copyFromStdinPattern = regexp.MustCompile(`(?is)^COPY\s.*\sFROM\s+STDIN\b`)
+
+// ignoredMetaCommands are the psql meta-commands pg_dump writes into plain
+// dumps, which only guard against others being run as psql reads the dump.
+var ignoredMetaCommands = map[string]bool{`\restrict`: true, `\unrestrict`: true}
+
+// sqlDumpReader splits a plain SQL dump, such as pg_dump writes, into its
+// statements and the rows of data which follow its COPY ... FROM stdin
+// statements, so that it can be run through the driver rather than by psql.
+// psql would also run any meta-commands in the dump, such as \! which runs
+// a shell command, so they are refused instead.
+type sqlDumpReader struct {
+	reader *bufio.Reader
+}
+
+func newSQLDumpReader(r io.Reader) *sqlDumpReader {
+	return &sqlDumpReader{reader: bufio.NewReader(r)}
+}
+
+// nextStatement reads up to the end of the next statement, returning it
+// without the comments and whitespace before it, which are all there is to
+// it at the end of the dump. It returns io.EOF once the whole dump has been
+// read. The data of a COPY ... FROM stdin statement must be read with
+// copyRow before the next statement.
+func (r *sqlDumpReader) nextStatement() (string, error) {
+	var (
+		statement   bytes.Buffer
+		started     bool
+		quote       byte
+		escapes     bool
+		dollarTag   string
+		dollarStart int
+		lineComment bool
+		blockDepth  int
+	)
+
+	for {
+		c, err := r.reader.ReadByte()
+		if err == io.EOF {
+			if statement.Len() == 0 && !started {
+				return "", io.EOF
+			}
+			return statement.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case lineComment:
+			if c == '\n' {
+				lineComment = false
+				if started {
+					statement.WriteByte(c)
+				}
+			}
+			continue
+		case blockDepth > 0:
+			if c == '*' && r.peekIs('/') {
+				r.reader.ReadByte()
+				blockDepth--
+				if blockDepth == 0 && started {
+					statement.WriteByte(' ')
+				}
+			} else if c == '/' && r.peekIs('*') {
+				r.reader.ReadByte()
+				blockDepth++
+			}
+			continue
+		case quote != 0:
+			statement.WriteByte(c)
+			if escapes && c == '\\' {
+				next, err := r.reader.ReadByte()
+				if err == nil {
+					statement.WriteByte(next)
+				}
+			} else if c == quote {
+				if r.peekIs(quote) {
+					next, _ := r.reader.ReadByte()
+					statement.WriteByte(next)
+				} else {
+					quote = 0
+				}
+			}
+			continue
+		case dollarTag != "":
+			statement.WriteByte(c)
+			if c == '$' && statement.Len()-len(dollarTag) >= dollarStart && bytes.HasSuffix(statement.Bytes(), []byte(dollarTag)) {
+				dollarTag = ""
+			}
+			continue
+		}
+
+		switch {
+		case c == '-' && r.peekIs('-'):
+			lineComment = true
+			continue
+		case c == '/' && r.peekIs('*'):
+			r.reader.ReadByte()
+			blockDepth = 1
+			continue
+		case c == '\\':
+			command, err := r.metaCommand()
+			if err != nil {
+				return "", err
+			}
+			if started || !ignoredMetaCommands[command] {
+				return "", fmt.Errorf("psql meta-commands such as '%s' aren't supported", command)
+			}
+			continue
+		case !started && isSpace(c):
+			continue
+		}
+
+		started = true
+		statement.WriteByte(c)
+		switch c {
+		case ';':
+			return statement.String(), nil
+		case '\'':
+			previous := statement.Bytes()[:statement.Len()-1]
+			escapes = len(previous) > 0 && (previous[len(previous)-1] == 'E' || previous[len(previous)-1] == 'e') &&
+				(len(previous) == 1 || !isIdentifierByte(previous[len(previous)-2]))
+			quote = c
+		case '"':
+			escapes = false
+			quote = c
+		case '$':
+			previous := statement.Bytes()[:statement.Len()-1]
+			if len(previous) == 0 || !isIdentifierByte(previous[len(previous)-1]) {
+				dollarTag = r.dollarQuoteTag()
+				statement.WriteString(strings.TrimPrefix(dollarTag, "$"))
+				dollarStart = statement.Len()
+			}
+		}
+	}
+}
+
+// copyRow reads the next row of data of a COPY ... FROM stdin statement,
+// in its text format, returning false after the last row. Its values are
+// strings, or nil for NULL.
+func (r *sqlDumpReader) copyRow() ([]interface{}, bool, error) {
+	line, err := r.reader.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return nil, false, nil
+	}
+	if err != nil && err != io.EOF {
+		return nil, false, err
+	}
+	line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	if line == `\.` {
+		return nil, false, nil
+	}
+
+	fields := strings.Split(line, "\t")
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		if field != `\N` {
+			values[i] = unescapeCopyText(field)
+		}
+	}
+	return values, true, nil
+}
+
+// skipRestOfLine reads past the end of the line a COPY ... FROM stdin
+// statement ends on, as its data starts on the next line.
+func (r *sqlDumpReader) skipRestOfLine() error {
+	rest, err := r.reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if strings.TrimSpace(rest) != "" {
+		return fmt.Errorf("Unexpected '%s' after COPY statement", strings.TrimSpace(rest))
+	}
+	return nil
+}
+
+// metaCommand reads the rest of the line of a psql meta-command, returning
+// the name of the command.
+func (r *sqlDumpReader) metaCommand() (string, error) {
+	line, err := r.reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return `\`, nil
+	}
+	return `\` + fields[0], nil
+}
+
+// dollarQuoteTag reads the rest of the tag of a dollar quote, returning the
+// whole tag, or nothing if the $ doesn't start one.
+func (r *sqlDumpReader) dollarQuoteTag() string {
+	peeked, _ := r.reader.Peek(64)
+	for i, c := range peeked {
+		if c == '$' {
+			r.reader.Discard(i + 1)
+			return "$" + string(peeked[:i+1])
+		}
+		if !isIdentifierByte(c) || (i == 0 && c >= '0' && c <= '9') {
+			return ""
+		}
+	}
+	return ""
+}
+
+func (r *sqlDumpReader) peekIs(c byte) bool {
+	peeked, err := r.reader.Peek(1)
+	return err == nil && peeked[0] == c
+}
+
+// unescapeCopyText decodes a value of the text format of COPY.
+func unescapeCopyText(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+
+	var value strings.Builder
+	for i := 0; i < len(field); i++ {
+		if field[i] != '\\' || i+1 == len(field) {
+			value.WriteByte(field[i])
+			continue
+		}
+		i++
+		switch c := field[i]; c {
+		case 'b':
+			value.WriteByte('\b')
+		case 'f':
+			value.WriteByte('\f')
+		case 'n':
+			value.WriteByte('\n')
+		case 'r':
+			value.WriteByte('\r')
+		case 't':
+			value.WriteByte('\t')
+		case 'v':
+			value.WriteByte('\v')
+		case 'x':
+			digits := 0
+			var b byte
+			for ; digits < 2 && i+1 < len(field) && isHexDigit(field[i+1]); digits++ {
+				i++
+				b = b*16 + hexValue(field[i])
+			}
+			if digits == 0 {
+				value.WriteByte('x')
+			} else {
+				value.WriteByte(b)
+			}
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			b := c - '0'
+			for digits := 1; digits < 3 && i+1 < len(field) && field[i+1] >= '0' && field[i+1] <= '7'; digits++ {
+				i++
+				b = b*8 + field[i] - '0'
+			}
+			value.WriteByte(b)
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return value.String()
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isIdentifierByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func hexValue(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	}
+	return c - '0'
+}
@@ -0,0 +1,91 @@
+package sqlengine
+
+import (
+	"io"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("sqlDumpReader", func() {
+	statements := func(dump string) ([]string, error) {
+		reader := newSQLDumpReader(strings.NewReader(dump))
+		statements := []string{}
+		for {
+			statement, err := reader.nextStatement()
+			if err == io.EOF {
+				return statements, nil
+			}
+			if err != nil {
+				return statements, err
+			}
+			statements = append(statements, statement)
+		}
+	}
+
+	It("splits a dump into its statements, leaving out comments", func() {
+		Expect(statements("-- a comment\nSET client_encoding = 'UTF8';\n/* another\n comment */\nCREATE TABLE gadgets (id integer, -- the id\n name text);\nSELECT 1")).To(Equal([]string{
+			"SET client_encoding = 'UTF8';",
+			"CREATE TABLE gadgets (id integer, \n name text);",
+			"SELECT 1",
+		}))
+	})
+
+	It("doesn't split statements on what is quoted", func() {
+		Expect(statements(`INSERT INTO "a;b" VALUES ('it''s; \', E'\'; \\', $$ ; \! $$, $body$ $$; $body$);SELECT 2;`)).To(Equal([]string{
+			`INSERT INTO "a;b" VALUES ('it''s; \', E'\'; \\', $$ ; \! $$, $body$ $$; $body$);`,
+			"SELECT 2;",
+		}))
+	})
+
+	It("refuses psql meta-commands", func() {
+		_, err := statements("CREATE TABLE gadgets (id integer);\n\\! touch /tmp/owned\nSELECT 1;")
+		Expect(err).To(MatchError(`psql meta-commands such as '\!' aren't supported`))
+
+		_, err = statements("SELECT 1 \\g |sh\n")
+		Expect(err).To(MatchError(`psql meta-commands such as '\g' aren't supported`))
+
+		_, err = statements("\\copy gadgets from program 'sh'\n")
+		Expect(err).To(MatchError(`psql meta-commands such as '\copy' aren't supported`))
+	})
+
+	It("ignores the meta-commands pg_dump writes", func() {
+		Expect(statements("\\restrict abc123\nSELECT 1;\n\\unrestrict abc123\n")).To(Equal([]string{"SELECT 1;"}))
+	})
+
+	It("reads the rows of data after COPY statements", func() {
+		reader := newSQLDumpReader(strings.NewReader("COPY public.gadgets (id, name) FROM stdin;\n1\tsprocket\n2\t\\N\n3\ttab\\there\\\\\\101\\x42\n\\.\nSELECT 1;\n"))
+
+		statement, err := reader.nextStatement()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(copyFromStdinPattern.MatchString(statement)).To(BeTrue())
+		Expect(reader.skipRestOfLine()).To(Succeed())
+
+		rows := [][]interface{}{}
+		for {
+			values, ok, err := reader.copyRow()
+			Expect(err).ToNot(HaveOccurred())
+			if !ok {
+				break
+			}
+			rows = append(rows, values)
+		}
+		Expect(rows).To(Equal([][]interface{}{
+			{"1", "sprocket"},
+			{"2", nil},
+			{"3", "tab\there\\AB"},
+		}))
+
+		statement, err = reader.nextStatement()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(statement).To(Equal("SELECT 1;"))
+	})
+
+	It("only takes COPY statements from stdin to have data after them", func() {
+		Expect(copyFromStdinPattern.MatchString("COPY public.gadgets (id, name) FROM stdin;")).To(BeTrue())
+		Expect(copyFromStdinPattern.MatchString("copy gadgets from STDIN with (format text);")).To(BeTrue())
+		Expect(copyFromStdinPattern.MatchString("COPY gadgets TO stdout;")).To(BeFalse())
+		Expect(copyFromStdinPattern.MatchString("SELECT 'COPY x FROM stdin';")).To(BeFalse())
+	})
+})
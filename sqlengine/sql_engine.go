@@ -2,7 +2,10 @@ package sqlengine
 
 import (
 	"errors"
+	"io"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/alphagov/paas-rds-broker/utils"
 )
@@ -12,20 +15,70 @@ const (
 	passwordLength = 32
 )
 
+//go:generate counterfeiter -o fakes/fake_sql_engine.go . SQLEngine
 type SQLEngine interface {
 	Open(address string, port int64, dbname string, username string, password string) error
 	Close()
 	CreateUser(bindingID, dbname string, readOnly bool) (string, string, error)
 	DropUser(bindingID string) error
+	SetUserExpiry(bindingID, dbname string, expiresAt time.Time) error
+	SetUserStatementTimeout(bindingID string, timeout time.Duration) error
+	DropExpiredUsers() ([]string, error)
 	ResetState() error
+	TerminateConnections() error
 	URI(address string, port int64, dbname string, username string, password string) string
 	JDBCURI(address string, port int64, dbname string, username string, password string) string
 	CreateExtensions(extensions []string) error
-	DropExtensions(extensions []string) error
+	DropExtensions(extensions []string, cascade bool) error
+	ExtensionDependents(extensions []string) (map[string][]string, error)
+	UpdateExtension(extension string) error
+	SetBinlogRetentionHours(hours int64) error
+	ListOtherDatabases() ([]string, error)
+	CreateDatabase(dbname string) error
+	DropDatabase(dbname string) error
+	CreateSchema(dbname, schema string) error
+	CheckConnection() error
+	DatabaseUsage() (DatabaseUsage, error)
+	TransactionIDAges() (map[string]int64, error)
+	ConnectionUsage() (ConnectionUsage, error)
+	CopySchemaFrom(address string, port int64, dbname string, username string, password string) error
+	ImportData(format string, objects []ImportObject) error
+	ExportData(w io.Writer) error
+}
+
+// The formats of data which ImportData can import.
+const (
+	ImportFormatSQL = "sql"
+	ImportFormatCSV = "csv"
+)
+
+// ImportObject is a file of data to import into a database, fetched from
+// its URL. A CSV file is imported into the table Name.
+type ImportObject struct {
+	Name string
+	URL  string
+}
+
+// DatabaseUsage is how much the database the engine is connected to holds.
+type DatabaseUsage struct {
+	SizeBytes  int64
+	TableCount int64
+}
+
+// ConnectionUsage is how many client connections the server has open, how
+// many of those are idle, and how many it allows.
+type ConnectionUsage struct {
+	Connections     int64
+	IdleConnections int64
+	MaxConnections  int64
 }
 
 var LoginFailedError = errors.New("Login failed")
 
+// brokerUsernamePattern matches the usernames made by generateUsername, so
+// that users the broker didn't create are left alone when pruning.
+var brokerUsernamePattern = regexp.MustCompile(`^u[a-z0-9_]{15}$`)
+
 func generateUsername(seed string) string {
 	usernameString := strings.ToLower(utils.GenerateHash(seed, usernameLength-1))
 	return "u" + strings.Replace(usernameString, "-", "_", -1)
@@ -0,0 +1,26 @@
+package statestore
+
+// migrations are applied in order, each exactly once, so existing entries
+// must never be changed or reordered: add a new one instead.
+var migrations = []string{
+	`CREATE TABLE bindings (
+		instance_id text NOT NULL,
+		binding_id text NOT NULL,
+		app_guid text NOT NULL DEFAULT '',
+		read_only boolean NOT NULL DEFAULT false,
+		created_at timestamptz NOT NULL,
+		PRIMARY KEY (instance_id, binding_id)
+	)`,
+	`CREATE TABLE pending_tag_writes (
+		resource_arn text PRIMARY KEY,
+		instance_id text NOT NULL,
+		tags jsonb NOT NULL,
+		attempts integer NOT NULL DEFAULT 0,
+		next_attempt_at timestamptz NOT NULL
+	)`,
+	`CREATE TABLE operation_leases (
+		instance_id text PRIMARY KEY,
+		holder text NOT NULL,
+		expires_at timestamptz NOT NULL
+	)`,
+}
@@ -0,0 +1,212 @@
+package statestore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	_ "github.com/lib/pq"
+)
+
+// migrationsLockID is the advisory lock held while migrating, so that
+// brokers starting at the same time don't apply the same migration twice.
+const migrationsLockID = 3517120171
+
+type PostgresStateStore struct {
+	db     *sql.DB
+	logger lager.Logger
+}
+
+// NewPostgresStateStore connects to the state store database and brings its
+// schema up to date.
+func NewPostgresStateStore(url string, logger lager.Logger) (*PostgresStateStore, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &PostgresStateStore{
+		db:     db,
+		logger: logger,
+	}
+	if err := store.Migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Migrate applies the migrations which haven't been applied to the database
+// yet, all in one transaction.
+func (s *PostgresStateStore) Migrate() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", migrationsLockID); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version integer PRIMARY KEY,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return err
+	}
+
+	var version int
+	if err := tx.QueryRow("SELECT coalesce(max(version), 0) FROM schema_migrations").Scan(&version); err != nil {
+		return err
+	}
+
+	for ; version < len(migrations); version++ {
+		s.logger.Info("migrate", lager.Data{"version": version + 1})
+		if _, err := tx.Exec(migrations[version]); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("INSERT INTO schema_migrations (version) VALUES ($1)", version+1); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *PostgresStateStore) RecordBinding(binding Binding) error {
+	_, err := s.db.Exec(`
+		INSERT INTO bindings (instance_id, binding_id, app_guid, read_only, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (instance_id, binding_id) DO UPDATE
+		SET app_guid = excluded.app_guid, read_only = excluded.read_only, created_at = excluded.created_at`,
+		binding.InstanceID,
+		binding.BindingID,
+		binding.AppGUID,
+		binding.ReadOnly,
+		binding.CreatedAt,
+	)
+	return err
+}
+
+func (s *PostgresStateStore) ForgetBinding(instanceID, bindingID string) error {
+	_, err := s.db.Exec("DELETE FROM bindings WHERE instance_id = $1 AND binding_id = $2", instanceID, bindingID)
+	return err
+}
+
+// ListBindings returns the recorded bindings of the instance, oldest first.
+func (s *PostgresStateStore) ListBindings(instanceID string) ([]Binding, error) {
+	rows, err := s.db.Query(`
+		SELECT binding_id, app_guid, read_only, created_at
+		FROM bindings
+		WHERE instance_id = $1
+		ORDER BY created_at, binding_id`,
+		instanceID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bindings := []Binding{}
+	for rows.Next() {
+		binding := Binding{InstanceID: instanceID}
+		if err := rows.Scan(&binding.BindingID, &binding.AppGUID, &binding.ReadOnly, &binding.CreatedAt); err != nil {
+			return nil, err
+		}
+		binding.CreatedAt = binding.CreatedAt.UTC()
+		bindings = append(bindings, binding)
+	}
+	return bindings, rows.Err()
+}
+
+// RecordPendingTagWrite records a failed tag write, replacing any recorded
+// for the same resource.
+func (s *PostgresStateStore) RecordPendingTagWrite(tagWrite PendingTagWrite) error {
+	tags, err := json.Marshal(tagWrite.Tags)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`
+		INSERT INTO pending_tag_writes (resource_arn, instance_id, tags, attempts, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (resource_arn) DO UPDATE
+		SET instance_id = excluded.instance_id, tags = excluded.tags, attempts = excluded.attempts, next_attempt_at = excluded.next_attempt_at`,
+		tagWrite.ResourceARN,
+		tagWrite.InstanceID,
+		string(tags),
+		tagWrite.Attempts,
+		tagWrite.NextAttemptAt,
+	)
+	return err
+}
+
+func (s *PostgresStateStore) ForgetPendingTagWrite(resourceARN string) error {
+	_, err := s.db.Exec("DELETE FROM pending_tag_writes WHERE resource_arn = $1", resourceARN)
+	return err
+}
+
+// ListPendingTagWrites returns every recorded tag write, soonest due first.
+func (s *PostgresStateStore) ListPendingTagWrites() ([]PendingTagWrite, error) {
+	rows, err := s.db.Query(`
+		SELECT resource_arn, instance_id, tags, attempts, next_attempt_at
+		FROM pending_tag_writes
+		ORDER BY next_attempt_at, resource_arn`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tagWrites := []PendingTagWrite{}
+	for rows.Next() {
+		var tagWrite PendingTagWrite
+		var tags string
+		if err := rows.Scan(&tagWrite.ResourceARN, &tagWrite.InstanceID, &tags, &tagWrite.Attempts, &tagWrite.NextAttemptAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(tags), &tagWrite.Tags); err != nil {
+			return nil, err
+		}
+		tagWrite.NextAttemptAt = tagWrite.NextAttemptAt.UTC()
+		tagWrites = append(tagWrites, tagWrite)
+	}
+	return tagWrites, rows.Err()
+}
+
+// AcquireOperationLease takes the lease on the instance for holder until
+// expiresAt, unless another holder has an unexpired lease on it. It reports
+// whether the lease was taken. A holder can extend a lease it already has.
+func (s *PostgresStateStore) AcquireOperationLease(instanceID, holder string, expiresAt time.Time) (bool, error) {
+	result, err := s.db.Exec(`
+		INSERT INTO operation_leases (instance_id, holder, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (instance_id) DO UPDATE
+		SET holder = excluded.holder, expires_at = excluded.expires_at
+		WHERE operation_leases.holder = excluded.holder OR operation_leases.expires_at < now()`,
+		instanceID,
+		holder,
+		expiresAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// ReleaseOperationLease gives up the lease on the instance if holder has it.
+func (s *PostgresStateStore) ReleaseOperationLease(instanceID, holder string) error {
+	_, err := s.db.Exec("DELETE FROM operation_leases WHERE instance_id = $1 AND holder = $2", instanceID, holder)
+	return err
+}
+
+func (s *PostgresStateStore) Close() error {
+	return s.db.Close()
+}
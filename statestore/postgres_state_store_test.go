@@ -0,0 +1,189 @@
+package statestore_test
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/alphagov/paas-rds-broker/statestore"
+	"github.com/alphagov/paas-rds-broker/utils"
+)
+
+func getEnvOrDefault(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+func postgresURL(dbName string) string {
+	u := url.URL{
+		Scheme: "postgres",
+		User: url.UserPassword(
+			getEnvOrDefault("POSTGRESQL_USERNAME", "postgres"),
+			getEnvOrDefault("POSTGRESQL_PASSWORD", ""),
+		),
+		Host:     fmt.Sprintf("%s:%s", getEnvOrDefault("POSTGRESQL_HOSTNAME", "localhost"), getEnvOrDefault("POSTGRESQL_PORT", "5432")),
+		Path:     dbName,
+		RawQuery: "sslmode=disable",
+	}
+	return u.String()
+}
+
+func execOnTemplate1(statement string) {
+	db, err := sql.Open("postgres", postgresURL("template1"))
+	Expect(err).ToNot(HaveOccurred())
+	defer db.Close()
+
+	_, err = db.Exec(statement)
+	Expect(err).ToNot(HaveOccurred())
+}
+
+var _ = Describe("PostgresStateStore", func() {
+	var (
+		dbName string
+		logger lager.Logger
+		store  *PostgresStateStore
+	)
+
+	BeforeEach(func() {
+		logger = lager.NewLogger("postgres_state_store_test")
+		logger.RegisterSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG))
+
+		dbName = "state_store_" + utils.RandomLowerAlphaNum(6)
+		execOnTemplate1("CREATE DATABASE " + dbName)
+
+		var err error
+		store, err = NewPostgresStateStore(postgresURL(dbName), logger)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		store.Close()
+		execOnTemplate1("DROP DATABASE " + dbName)
+	})
+
+	It("can be migrated again without changes", func() {
+		Expect(store.Migrate()).To(Succeed())
+
+		other, err := NewPostgresStateStore(postgresURL(dbName), logger)
+		Expect(err).ToNot(HaveOccurred())
+		defer other.Close()
+	})
+
+	It("records and forgets bindings", func() {
+		createdAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		Expect(store.RecordBinding(Binding{
+			InstanceID: "instance-1",
+			BindingID:  "binding-2",
+			CreatedAt:  createdAt.Add(time.Hour),
+			ReadOnly:   true,
+		})).To(Succeed())
+		Expect(store.RecordBinding(Binding{
+			InstanceID: "instance-1",
+			BindingID:  "binding-1",
+			AppGUID:    "app-1",
+			CreatedAt:  createdAt,
+		})).To(Succeed())
+		Expect(store.RecordBinding(Binding{
+			InstanceID: "instance-2",
+			BindingID:  "binding-3",
+			CreatedAt:  createdAt,
+		})).To(Succeed())
+
+		bindings, err := store.ListBindings("instance-1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bindings).To(Equal([]Binding{
+			{InstanceID: "instance-1", BindingID: "binding-1", AppGUID: "app-1", CreatedAt: createdAt},
+			{InstanceID: "instance-1", BindingID: "binding-2", CreatedAt: createdAt.Add(time.Hour), ReadOnly: true},
+		}))
+
+		Expect(store.ForgetBinding("instance-1", "binding-1")).To(Succeed())
+
+		bindings, err = store.ListBindings("instance-1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bindings).To(HaveLen(1))
+		Expect(bindings[0].BindingID).To(Equal("binding-2"))
+	})
+
+	It("updates a binding recorded again", func() {
+		createdAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		binding := Binding{InstanceID: "instance-1", BindingID: "binding-1", CreatedAt: createdAt}
+		Expect(store.RecordBinding(binding)).To(Succeed())
+
+		binding.AppGUID = "app-1"
+		Expect(store.RecordBinding(binding)).To(Succeed())
+
+		bindings, err := store.ListBindings("instance-1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bindings).To(Equal([]Binding{binding}))
+	})
+
+	It("forgets bindings it doesn't have without error", func() {
+		Expect(store.ForgetBinding("instance-1", "unknown")).To(Succeed())
+	})
+
+	It("records, replaces and forgets pending tag writes", func() {
+		nextAttemptAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		tagWrite := PendingTagWrite{
+			InstanceID:    "instance-1",
+			ResourceARN:   "arn:aws:rds:eu-west-1:123456789012:db:cf-instance-1",
+			Tags:          map[string]string{"Plan ID": "plan-1"},
+			NextAttemptAt: nextAttemptAt.Add(time.Hour),
+		}
+		Expect(store.RecordPendingTagWrite(tagWrite)).To(Succeed())
+		Expect(store.RecordPendingTagWrite(PendingTagWrite{
+			InstanceID:    "instance-2",
+			ResourceARN:   "arn:aws:rds:eu-west-1:123456789012:db:cf-instance-2",
+			Tags:          map[string]string{"Plan ID": "plan-2"},
+			NextAttemptAt: nextAttemptAt,
+		})).To(Succeed())
+
+		tagWrite.Attempts = 1
+		tagWrite.Tags["Space ID"] = "space-1"
+		Expect(store.RecordPendingTagWrite(tagWrite)).To(Succeed())
+
+		tagWrites, err := store.ListPendingTagWrites()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tagWrites).To(HaveLen(2))
+		Expect(tagWrites[0].InstanceID).To(Equal("instance-2"))
+		Expect(tagWrites[1]).To(Equal(tagWrite))
+
+		Expect(store.ForgetPendingTagWrite(tagWrite.ResourceARN)).To(Succeed())
+
+		tagWrites, err = store.ListPendingTagWrites()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tagWrites).To(HaveLen(1))
+		Expect(tagWrites[0].InstanceID).To(Equal("instance-2"))
+	})
+
+	It("only gives an operation lease to one holder until it expires or is released", func() {
+		expiresAt := time.Now().Add(time.Minute)
+
+		Expect(store.AcquireOperationLease("instance-1", "broker-1", expiresAt)).To(BeTrue())
+		Expect(store.AcquireOperationLease("instance-1", "broker-2", expiresAt)).To(BeFalse())
+		Expect(store.AcquireOperationLease("instance-2", "broker-2", expiresAt)).To(BeTrue())
+
+		By("letting the holder extend its lease")
+		Expect(store.AcquireOperationLease("instance-1", "broker-1", expiresAt.Add(time.Minute))).To(BeTrue())
+
+		By("ignoring releases by others")
+		Expect(store.ReleaseOperationLease("instance-1", "broker-2")).To(Succeed())
+		Expect(store.AcquireOperationLease("instance-1", "broker-2", expiresAt)).To(BeFalse())
+
+		Expect(store.ReleaseOperationLease("instance-1", "broker-1")).To(Succeed())
+		Expect(store.AcquireOperationLease("instance-1", "broker-2", expiresAt)).To(BeTrue())
+	})
+
+	It("gives out an expired operation lease", func() {
+		Expect(store.AcquireOperationLease("instance-1", "broker-1", time.Now().Add(-time.Minute))).To(BeTrue())
+		Expect(store.AcquireOperationLease("instance-1", "broker-2", time.Now().Add(time.Minute))).To(BeTrue())
+	})
+})
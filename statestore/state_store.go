@@ -0,0 +1,39 @@
+package statestore
+
+import "time"
+
+// Binding is a binding of a service instance, as recorded by the broker.
+type Binding struct {
+	InstanceID string
+	BindingID  string
+	AppGUID    string
+	CreatedAt  time.Time
+	ReadOnly   bool
+}
+
+// PendingTagWrite is a write of tags to a DB instance which failed, waiting
+// to be retried.
+type PendingTagWrite struct {
+	InstanceID    string
+	ResourceARN   string
+	Tags          map[string]string
+	Attempts      int
+	NextAttemptAt time.Time
+}
+
+// StateStore holds the broker's own state which doesn't belong in the tags
+// of the DB instances, either because there is too much of it or because it
+// must outlive them.
+//
+//go:generate counterfeiter -o fakes/fake_state_store.go . StateStore
+type StateStore interface {
+	RecordBinding(binding Binding) error
+	ForgetBinding(instanceID, bindingID string) error
+	ListBindings(instanceID string) ([]Binding, error)
+	RecordPendingTagWrite(tagWrite PendingTagWrite) error
+	ForgetPendingTagWrite(resourceARN string) error
+	ListPendingTagWrites() ([]PendingTagWrite, error)
+	AcquireOperationLease(instanceID, holder string, expiresAt time.Time) (bool, error)
+	ReleaseOperationLease(instanceID, holder string) error
+	Close() error
+}
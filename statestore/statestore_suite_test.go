@@ -0,0 +1,13 @@
+package statestore_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestStateStore(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "State Store Suite")
+}
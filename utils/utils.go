@@ -5,7 +5,10 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"fmt"
 	"io"
+
+	"golang.org/x/crypto/hkdf"
 )
 
 var alpha = []byte("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz")
@@ -64,3 +67,53 @@ func GetMD5B64(text string, maxLength int) string {
 		return encoded
 	}
 }
+
+// DeriveString deterministically derives a string of the given length from
+// text, using only the given characters. Like GenerateHash, it is used for
+// master passwords, so changing it changes the passwords of DB instances.
+func DeriveString(text string, length int, characters string) string {
+	return sampleCharacters(func(block int) []byte {
+		hash := sha256.Sum256([]byte(fmt.Sprintf("%s:%d", text, block)))
+		return hash[:]
+	}, length, characters)
+}
+
+// DeriveKeyString derives a string of the given length from a secret with
+// HKDF-SHA256, using only the given characters. Unlike a hash of the secret
+// and salt joined together, the output can't be found from the salt and
+// other outputs without the secret. It is used for master passwords, so
+// changing it changes the passwords of DB instances.
+func DeriveKeyString(secret, salt, info string, length int, characters string) string {
+	keyMaterial := hkdf.New(sha256.New, []byte(secret), []byte(salt), []byte(info))
+	return sampleCharacters(func(block int) []byte {
+		bytes := make([]byte, sha256.Size)
+		if _, err := io.ReadFull(keyMaterial, bytes); err != nil {
+			panic(err)
+		}
+		return bytes
+	}, length, characters)
+}
+
+// sampleCharacters picks length characters using the bytes of the blocks
+// next returns, skipping bytes which would make some characters more likely
+// than others. Each byte picks one character, so there can be at most 256
+// of them.
+func sampleCharacters(next func(block int) []byte, length int, characters string) string {
+	sampled := make([]byte, 0, length)
+	clen := len(characters)
+	if clen == 0 || clen > 256 {
+		panic(fmt.Sprintf("can't sample from %d characters", clen))
+	}
+	maxrb := 256 - (256 % clen)
+	for block := 0; ; block++ {
+		for _, c := range next(block) {
+			if int(c) >= maxrb {
+				continue
+			}
+			sampled = append(sampled, characters[int(c)%clen])
+			if len(sampled) == length {
+				return string(sampled)
+			}
+		}
+	}
+}
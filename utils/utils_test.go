@@ -1,6 +1,9 @@
 package utils_test
 
 import (
+	"encoding/hex"
+	"strings"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -34,3 +37,73 @@ var _ = Describe("GenerateHash", func() {
 		Expect(hash).To(Equal("180NZHG1Cx3N4mkrcboPAzOeJlXlYth4mKxjtzGhXMI="))
 	})
 })
+
+var _ = Describe("DeriveString", func() {
+	It("derives a string of the given length from the given characters", func() {
+		derived := DeriveString("ce71b484-d542-40f7-9dd4-5526e38c81ba", 100, "abc!#")
+		Expect(derived).To(HaveLen(100))
+		Expect(derived).To(MatchRegexp(`^[abc!#]+$`))
+	})
+
+	It("always derives the same string from the same text", func() {
+		Expect(DeriveString("1123456678", 40, "ABCDEFGH0123")).To(Equal(DeriveString("1123456678", 40, "ABCDEFGH0123")))
+		Expect(DeriveString("1123456678", 40, "ABCDEFGH0123")).NotTo(Equal(DeriveString("1123456679", 40, "ABCDEFGH0123")))
+	})
+
+	It("refuses more characters than a byte can pick from rather than never finishing", func() {
+		Expect(func() { DeriveString("1123456678", 40, strings.Repeat("ABCDEFGH", 33)) }).To(Panic())
+	})
+})
+
+var _ = Describe("DeriveKeyString", func() {
+	// with every byte as a character, each byte of key material picks itself
+	var allBytes string
+
+	BeforeEach(func() {
+		bytes := make([]byte, 256)
+		for i := range bytes {
+			bytes[i] = byte(i)
+		}
+		allBytes = string(bytes)
+	})
+
+	It("derives the key material of RFC 5869's first test case", func() {
+		secret, _ := hex.DecodeString("0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b")
+		salt, _ := hex.DecodeString("000102030405060708090a0b0c")
+		info, _ := hex.DecodeString("f0f1f2f3f4f5f6f7f8f9")
+
+		derived := DeriveKeyString(string(secret), string(salt), string(info), 42, allBytes)
+		Expect(hex.EncodeToString([]byte(derived))).To(Equal("3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865"))
+	})
+
+	It("derives the key material of RFC 5869's second test case, with longer inputs and outputs", func() {
+		secret, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f404142434445464748494a4b4c4d4e4f")
+		salt, _ := hex.DecodeString("606162636465666768696a6b6c6d6e6f707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9fa0a1a2a3a4a5a6a7a8a9aaabacadaeaf")
+		info, _ := hex.DecodeString("b0b1b2b3b4b5b6b7b8b9babbbcbdbebfc0c1c2c3c4c5c6c7c8c9cacbcccdcecfd0d1d2d3d4d5d6d7d8d9dadbdcdddedfe0e1e2e3e4e5e6e7e8e9eaebecedeeeff0f1f2f3f4f5f6f7f8f9fafbfcfdfeff")
+
+		derived := DeriveKeyString(string(secret), string(salt), string(info), 82, allBytes)
+		Expect(hex.EncodeToString([]byte(derived))).To(Equal("b11e398dc80327a1c8e7f78c596a49344f012eda2d4efad8a050cc4c19afa97c59045a99cac7827271cb41c65e590e09da3275600c2f09b8367793a9aca3db71cc30c58179ec3e87c14c01d5c1f3434f1d87"))
+	})
+
+	It("derives the key material of RFC 5869's third test case, without a salt or info", func() {
+		secret, _ := hex.DecodeString("0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b")
+
+		derived := DeriveKeyString(string(secret), "", "", 42, allBytes)
+		Expect(hex.EncodeToString([]byte(derived))).To(Equal("8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8"))
+	})
+
+	It("derives the same master passwords as before", func() {
+		Expect(DeriveKeyString("something-secret", "instance-id", "rds-broker master password:0", 32, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_")).To(Equal("UD6wzPMB71md_fuiVBrJmn8vHHDFT3aa"))
+	})
+
+	It("derives a string of the given length from the given characters", func() {
+		derived := DeriveKeyString("secret", "instance-id", "master password", 100, "abc!#")
+		Expect(derived).To(HaveLen(100))
+		Expect(derived).To(MatchRegexp(`^[abc!#]+$`))
+	})
+
+	It("derives different strings for different salts", func() {
+		Expect(DeriveKeyString("secret", "instance-1", "master password", 32, "ABCDEFGH0123")).To(Equal(DeriveKeyString("secret", "instance-1", "master password", 32, "ABCDEFGH0123")))
+		Expect(DeriveKeyString("secret", "instance-1", "master password", 32, "ABCDEFGH0123")).NotTo(Equal(DeriveKeyString("secret", "instance-2", "master password", 32, "ABCDEFGH0123")))
+	})
+})